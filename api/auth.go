@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"nofx/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey gin上下文中保存调用方的键
+const principalKey = "auth.principal"
+
+// SetAuthenticator 设置API Key认证（nil表示不认证，所有接口开放）
+func (s *Server) SetAuthenticator(a *auth.Authenticator) {
+	s.authenticator = a
+}
+
+// requireRole 认证中间件：API Key来自Authorization: Bearer <key>或X-API-Key头，
+// 缺少/无效的Key返回401，角色权限不足返回403
+func (s *Server) requireRole(required auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.authenticator == nil {
+			c.Next()
+			return
+		}
+		key := auth.BearerToken(c.GetHeader("Authorization"), c.GetHeader("X-API-Key"))
+		principal, err := s.authenticator.Authenticate(key)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if !principal.Role.Allows(required) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "需要" + string(required) + "权限"})
+			return
+		}
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// actor 审计日志中的操作来源（API Key名称@客户端地址）
+func (s *Server) actor(c *gin.Context) string {
+	if value, ok := c.Get(principalKey); ok {
+		return value.(auth.Principal).Name + "@" + c.ClientIP()
+	}
+	return c.ClientIP()
+}
@@ -2,9 +2,21 @@ package api
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"nofx/audit"
+	"nofx/auth"
+	"nofx/events"
+	"nofx/leader"
 	"nofx/manager"
+	"nofx/market"
+	"nofx/monitor"
+	"nofx/risk"
+	"nofx/run"
+	"nofx/strategy"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +26,10 @@ type Server struct {
 	router        *gin.Engine
 	traderManager *manager.TraderManager
 	port          int
+	depegMonitor  *monitor.DepegMonitor // 稳定币脱锚监控（可选）
+	webhookSecret string                // 外部信号webhook密钥（为空表示关闭）
+	alertMonitor  *monitor.AlertMonitor // 阈值告警（可选）
+	authenticator *auth.Authenticator   // API Key认证（为nil表示不认证）
 }
 
 // NewServer 创建API服务器
@@ -38,12 +54,22 @@ func NewServer(traderManager *manager.TraderManager, port int) *Server {
 	return s
 }
 
+// SetDepegMonitor 设置稳定币脱锚监控（用于风控状态展示）
+func (s *Server) SetDepegMonitor(m *monitor.DepegMonitor) {
+	s.depegMonitor = m
+}
+
+// SetAlertMonitor 设置阈值告警（用于风控状态展示）
+func (s *Server) SetAlertMonitor(m *monitor.AlertMonitor) {
+	s.alertMonitor = m
+}
+
 // corsMiddleware CORS中间件
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusOK)
@@ -59,8 +85,12 @@ func (s *Server) setupRoutes() {
 	// 健康检查
 	s.router.Any("/health", s.handleHealth)
 
-	// API路由组
-	api := s.router.Group("/api")
+	// 外部信号（使用webhook密钥认证，不需要API Key）
+	s.router.POST("/api/webhook/tradingview", s.handleTradingViewWebhook)
+
+	// API路由组（启用认证时需要observer权限，运行时操作需要operator权限）
+	operator := s.requireRole(auth.RoleOperator)
+	api := s.router.Group("/api", s.requireRole(auth.RoleObserver))
 	{
 		// 竞赛总览
 		api.GET("/competition", s.handleCompetition)
@@ -72,19 +102,55 @@ func (s *Server) setupRoutes() {
 		api.GET("/status", s.handleStatus)
 		api.GET("/account", s.handleAccount)
 		api.GET("/positions", s.handlePositions)
+		api.POST("/positions/close", operator, s.handleClosePosition)
+		api.POST("/positions/flatten", operator, s.handleFlattenAll)
 		api.GET("/decisions", s.handleDecisions)
 		api.GET("/decisions/latest", s.handleLatestDecisions)
+		api.GET("/decisions/snapshot", s.handleDecisionSnapshot)
 		api.GET("/statistics", s.handleStatistics)
 		api.GET("/equity-history", s.handleEquityHistory)
 		api.GET("/performance", s.handlePerformance)
+		api.GET("/shadow", s.handleShadow)
+		api.GET("/inspect", s.handleInspect)
+		api.GET("/fees", s.handleFees)
+		api.GET("/strategies", s.handleStrategies)
+		api.POST("/strategies/:strategy_id/:action", operator, s.handleStrategyAction)
+		api.GET("/allocation", s.handleAllocation)
+		api.GET("/funding-harvest", s.handleFundingHarvest)
+		api.GET("/regime", s.handleRegime)
+		api.GET("/plans", s.handleTradePlans)
+		api.GET("/open-risk", s.handleOpenRisk)
+		api.GET("/run", s.handleRun)
+		api.GET("/audit", s.handleAudit)
+
+		// 全局风控（熔断状态、稳定币监控、最近事件）
+		api.GET("/risk", s.handleRisk)
+		api.GET("/events/stream", s.handleEventStream)
+		api.POST("/risk/trip", operator, s.handleRiskTrip)
+		api.POST("/risk/reset", operator, s.handleRiskReset)
 	}
 }
 
 // handleHealth 健康检查
 func (s *Server) handleHealth(c *gin.Context) {
+	status := "ok"
+	exchanges := monitor.ExchangeStatuses()
+	for _, exchange := range exchanges {
+		if exchange.Paused() {
+			status = "degraded"
+		}
+	}
+	clock := monitor.ClockDrift()
+	if clock != nil && clock.Breached {
+		status = "degraded"
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"status": "ok",
-		"time":   c.Request.Context().Value("time"),
+		"status":    status,
+		"time":      c.Request.Context().Value("time"),
+		"exchanges": exchanges,
+		"clock":     clock,
+		"leader":    leader.Current(),
 	})
 }
 
@@ -263,6 +329,29 @@ func (s *Server) handleLatestDecisions(c *gin.Context) {
 	c.JSON(http.StatusOK, records)
 }
 
+// handleDecisionSnapshot 决策快照（file为决策记录中的snapshot_file）
+func (s *Server) handleDecisionSnapshot(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshot, err := trader.GetDecisionLogger().GetSnapshot(c.Query("file"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
 // handleStatistics 统计信息
 func (s *Server) handleStatistics(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
@@ -401,6 +490,395 @@ func (s *Server) handlePerformance(c *gin.Context) {
 	c.JSON(http.StatusOK, performance)
 }
 
+// handleShadow 影子策略与实盘的收益对比
+func (s *Server) handleShadow(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, ok := trader.GetShadowReport()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该trader未启用影子策略"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleInspect trader状态汇总（持仓、挂单、最近行情快照、最近决策、风控使用情况）
+func (s *Server) handleInspect(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := trader.Inspect()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取trader状态失败: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleFees 手续费核算（交易所收益流水按单笔交易、币种、策略汇总），days为统计天数（默认7，最多90）
+func (s *Server) handleFees(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	days := 7
+	if value := c.Query("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days <= 0 || days > 90 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days必须为1-90"})
+			return
+		}
+	}
+
+	to := time.Now()
+	report, err := trader.FeeReport(to.AddDate(0, 0, -days), to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("手续费核算失败: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleRun 当前运行清单（运行ID、随机种子、代码版本、配置哈希）
+func (s *Server) handleRun(c *gin.Context) {
+	manifest := run.Current()
+	if manifest == nil {
+		c.JSON(http.StatusOK, gin.H{"run_id": run.ID(), "seed": run.Seed()})
+		return
+	}
+	c.JSON(http.StatusOK, manifest)
+}
+
+// handleAudit 校验审计日志的哈希链（记录数、最后一条哈希、第一条校验失败的记录）
+func (s *Server) handleAudit(c *gin.Context) {
+	result, err := audit.Status()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if result == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// handleRisk 全局风控状态
+func (s *Server) handleRisk(c *gin.Context) {
+	result := gin.H{
+		"breaker":  risk.Breaker.Status(),
+		"circuits": market.BinanceCircuits.Status(),
+		"events":   events.Default.Recent(),
+	}
+	if s.depegMonitor != nil {
+		result["stablecoins"] = s.depegMonitor.Status()
+	}
+	if s.alertMonitor != nil {
+		result["alerts"] = s.alertMonitor.Active()
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// handleEventStream 实时事件流（Server-Sent Events：先发送最近的事件，再推送新事件；不含行情快照）
+func (s *Server) handleEventStream(c *gin.Context) {
+	ch := events.Subscribe("")
+	defer events.Unsubscribe(ch)
+
+	for _, event := range events.Default.Recent() {
+		c.SSEvent(event.Type, event)
+	}
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-ch:
+			if event.Type != events.TypeMarketSnapshot {
+				c.SSEvent(event.Type, event)
+			}
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// handleStrategies 用户策略运行状态
+func (s *Server) handleStrategies(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, trader.StrategyStatuses())
+}
+
+// handleAllocation 各策略资金预算
+func (s *Server) handleAllocation(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	report, ok := trader.AllocationReport()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "该trader未配置资金分配"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleFundingHarvest 资金费率套利持仓和净收益
+func (s *Server) handleFundingHarvest(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := trader.FundingHarvestReport()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleRegime 市场状态切换器的状态
+func (s *Server) handleRegime(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	status, err := trader.RegimeStatus()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleTradePlans 交易计划（信号及其订单、止损止盈和平仓）
+func (s *Server) handleTradePlans(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, trader.TradePlans())
+}
+
+// handleOpenRisk 组合开放风险（各持仓止损距离×数量及其汇总占净值的比例）
+func (s *Server) handleOpenRisk(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, trader.OpenRisk())
+}
+
+// handleStrategyAction 运行时启用/停用/重新加载用户策略（action: enable、disable、reload）
+func (s *Server) handleStrategyAction(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	strategyID := c.Param("strategy_id")
+	var status strategy.Status
+	switch c.Param("action") {
+	case "enable":
+		status, err = trader.EnableStrategy(strategyID)
+	case "disable":
+		status, err = trader.DisableStrategy(strategyID)
+	case "reload":
+		status, err = trader.ReloadStrategy(strategyID)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action必须是 enable、disable 或 reload"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": status})
+		return
+	}
+	audit.Record(audit.KindControl, traderID, s.actor(c), map[string]interface{}{
+		"action":      "strategy." + c.Param("action"),
+		"strategy_id": strategyID,
+	})
+	c.JSON(http.StatusOK, status)
+}
+
+// handleClosePosition 手动平仓（?symbol=BTCUSDT&fraction=0.5，fraction默认1即全部平仓），在交易主循环中执行
+func (s *Server) handleClosePosition(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	symbol := market.Normalize(c.Query("symbol"))
+	fraction := 1.0
+	if raw := c.Query("fraction"); raw != "" {
+		if fraction, err = strconv.ParseFloat(raw, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "fraction必须是数字"})
+			return
+		}
+	}
+	audit.Record(audit.KindControl, traderID, s.actor(c), map[string]interface{}{
+		"action":   "positions.close",
+		"symbol":   symbol,
+		"fraction": fraction,
+	})
+	orders, err := at.ClosePosition(symbol, fraction)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "orders": orders})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+// handleFlattenAll 紧急平掉trader的全部持仓（在交易主循环中执行）
+func (s *Server) handleFlattenAll(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	audit.Record(audit.KindControl, traderID, s.actor(c), map[string]interface{}{
+		"action": "positions.flatten",
+	})
+	orders, err := at.FlattenAll()
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "orders": orders})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+// handleRiskTrip 手动触发全局熔断（紧急停止：所有trader暂停开仓决策），
+// 请求体可选 {"reason": "...", "minutes": 60}，minutes为0表示直到手动解除
+func (s *Server) handleRiskTrip(c *gin.Context) {
+	var req struct {
+		Reason  string `json:"reason"`
+		Minutes int    `json:"minutes"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("解析请求失败: %v", err)})
+			return
+		}
+	}
+	if req.Minutes < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minutes不能为负数"})
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "手动紧急停止"
+	}
+	actor := s.actor(c)
+	risk.Breaker.Trip("manual:"+actor, req.Reason, time.Duration(req.Minutes)*time.Minute)
+	audit.Record(audit.KindControl, "", actor, map[string]interface{}{
+		"action":  "risk.trip",
+		"reason":  req.Reason,
+		"minutes": req.Minutes,
+	})
+	c.JSON(http.StatusOK, gin.H{"breaker": risk.Breaker.Status()})
+}
+
+// handleRiskReset 手动解除全局熔断
+func (s *Server) handleRiskReset(c *gin.Context) {
+	risk.Breaker.Reset()
+	audit.Record(audit.KindControl, "", s.actor(c), map[string]interface{}{"action": "risk.reset"})
+	c.JSON(http.StatusOK, gin.H{"breaker": risk.Breaker.Status()})
+}
+
 // Start 启动服务器
 func (s *Server) Start() error {
 	addr := fmt.Sprintf(":%d", s.port)
@@ -413,9 +891,25 @@ func (s *Server) Start() error {
 	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
 	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
 	log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
+	log.Printf("  • GET  /api/decisions/snapshot?trader_id=xxx&file=xxx - 决策时的完整输入快照")
 	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
 	log.Printf("  • GET  /api/equity-history?trader_id=xxx - 指定trader的收益率历史数据")
 	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
+	log.Printf("  • GET  /api/shadow?trader_id=xxx - 指定trader的影子策略收益对比")
+	log.Printf("  • GET  /api/inspect?trader_id=xxx - 指定trader的状态汇总（持仓、挂单、行情快照、最近决策、风控）")
+	log.Printf("  • GET  /api/fees?trader_id=xxx&days=7 - 指定trader的手续费核算（手续费、返佣、资金费按交易/币种/策略汇总）")
+	log.Printf("  • GET  /api/strategies?trader_id=xxx - 指定trader的用户策略运行状态")
+	log.Printf("  • POST /api/strategies/:id/enable|disable|reload?trader_id=xxx - 运行时启用/停用/重新加载用户策略")
+	log.Printf("  • GET  /api/allocation?trader_id=xxx - 指定trader的各策略资金预算")
+	log.Printf("  • GET  /api/funding-harvest?trader_id=xxx - 指定trader的资金费率套利持仓和净收益")
+	log.Printf("  • GET  /api/regime?trader_id=xxx - 指定trader的市场状态和按状态启用的策略")
+	log.Printf("  • GET  /api/run              - 当前运行清单（运行ID、随机种子、代码版本）")
+	log.Printf("  • GET  /api/audit            - 审计日志哈希链校验")
+	log.Printf("  • POST /api/webhook/tradingview - TradingView告警信号（需配置webhook）")
+	log.Printf("  • GET  /api/risk             - 全局风控状态（熔断、行情接口熔断、稳定币监控、阈值告警、事件）")
+	log.Printf("  • POST /api/risk/trip        - 手动触发全局熔断（紧急停止）")
+	log.Printf("  • POST /api/risk/reset       - 手动解除全局熔断")
+	log.Printf("  • GET  /api/events/stream    - 实时事件流（SSE）")
 	log.Printf("  • GET  /health               - 健康检查")
 	log.Println()
 
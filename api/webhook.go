@@ -0,0 +1,148 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"nofx/decision"
+	"nofx/market"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tradingViewAlert TradingView告警消息（在告警的Message中填写JSON，支持占位符）
+//
+//	{"secret": "...", "trader_id": "binance_qwen", "ticker": "{{ticker}}",
+//	 "action": "{{strategy.order.action}}", "market_position": "{{strategy.market_position}}",
+//	 "size_usd": 200, "leverage": 3, "stop_loss": 95000, "take_profit": 110000, "comment": "{{strategy.order.comment}}",
+//	 "strategy_id": "tv_breakout", "tags": ["breakout", "4h"],
+//	 "invalidations": [{"interval": "4h", "close": "below", "ma": 21}]}
+type tradingViewAlert struct {
+	Secret         string   `json:"secret"`
+	TraderID       string   `json:"trader_id"`       // 为空时使用URL参数trader_id或第一个trader
+	Ticker         string   `json:"ticker"`          // 如 BTCUSDT、BTCUSDT.P、BINANCE:BTCUSDT.P
+	Action         string   `json:"action"`          // buy/sell，或 open_long/open_short/close_long/close_short
+	MarketPosition string   `json:"market_position"` // 下单后的仓位方向 long/short/flat（flat表示buy/sell为平仓）
+	SizeUSD        float64  `json:"size_usd"`        // 开仓仓位价值（USDT）
+	Leverage       int      `json:"leverage"`
+	StopLoss       float64  `json:"stop_loss"`
+	TakeProfit     float64  `json:"take_profit"`
+	Confidence     int      `json:"confidence"`
+	Comment        string   `json:"comment"`     // 信号说明（记录为决策理由）
+	StrategyID     string   `json:"strategy_id"` // 策略ID（为空时为"tradingview"）
+	Tags           []string `json:"tags"`        // 策略标签
+
+	Invalidations []decision.Invalidation `json:"invalidations"` // 失效条件（开仓单成交前满足任一条件时取消）
+}
+
+// toDecision 转换为交易决策
+func (a *tradingViewAlert) toDecision() (decision.Decision, error) {
+	ticker := a.Ticker
+	if i := strings.LastIndex(ticker, ":"); i >= 0 {
+		ticker = ticker[i+1:] // 去掉交易所前缀
+	}
+	ticker = strings.TrimSuffix(strings.ToUpper(ticker), ".P") // 永续合约后缀
+	if ticker == "" {
+		return decision.Decision{}, fmt.Errorf("ticker不能为空")
+	}
+
+	d := decision.Decision{
+		Symbol:          market.Normalize(ticker),
+		PositionSizeUSD: a.SizeUSD,
+		Leverage:        a.Leverage,
+		StopLoss:        a.StopLoss,
+		TakeProfit:      a.TakeProfit,
+		Confidence:      a.Confidence,
+		Reasoning:       a.Comment,
+		StrategyID:      a.StrategyID,
+		Tags:            a.Tags,
+		Invalidations:   a.Invalidations,
+	}
+	if d.Reasoning == "" {
+		d.Reasoning = "TradingView告警"
+	}
+
+	action := strings.ToLower(a.Action)
+	flat := strings.EqualFold(a.MarketPosition, "flat")
+	switch {
+	case action == "buy" && flat:
+		d.Action = "close_short"
+	case action == "sell" && flat:
+		d.Action = "close_long"
+	case action == "buy" || action == "long":
+		d.Action = "open_long"
+	case action == "sell" || action == "short":
+		d.Action = "open_short"
+	case action == "open_long", action == "open_short", action == "close_long", action == "close_short":
+		d.Action = action
+	default:
+		return d, fmt.Errorf("无法识别的action: %s", a.Action)
+	}
+
+	if d.Action == "open_long" || d.Action == "open_short" {
+		if d.PositionSizeUSD <= 0 || d.Leverage <= 0 || d.StopLoss <= 0 || d.TakeProfit <= 0 {
+			return d, fmt.Errorf("开仓信号必须提供size_usd、leverage、stop_loss和take_profit")
+		}
+	}
+	return d, nil
+}
+
+// SetWebhookSecret 设置外部信号webhook的密钥（为空时webhook关闭）
+func (s *Server) SetWebhookSecret(secret string) {
+	s.webhookSecret = secret
+}
+
+// handleTradingViewWebhook 接收TradingView告警，转换为交易决策后交给trader执行（与AI决策相同的校验和风控）
+func (s *Server) handleTradingViewWebhook(c *gin.Context) {
+	if s.webhookSecret == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook未启用"})
+		return
+	}
+
+	var alert tradingViewAlert
+	if err := c.ShouldBindJSON(&alert); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("解析告警消息失败: %v", err)})
+		return
+	}
+	secret := alert.Secret
+	if secret == "" {
+		secret = c.Query("secret")
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(s.webhookSecret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "密钥错误"})
+		return
+	}
+
+	d, err := alert.toDecision()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	traderID := alert.TraderID
+	if traderID == "" {
+		_, traderID, err = s.getTraderFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := at.SubmitSignal(trader.ExternalSignal{Decision: d, Source: "tradingview"}); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":    "queued",
+		"trader_id": traderID,
+		"decision":  d,
+	})
+}
@@ -0,0 +1,327 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"nofx/run"
+)
+
+// DefaultPath 审计日志默认路径
+const DefaultPath = "audit/audit.jsonl"
+
+// 审计记录类型
+const (
+	KindConfig   = "config"   // 启动时加载的配置（与上次运行不同时记录变化的字段）
+	KindDecision = "decision" // 决策周期（含决策日志文件的哈希）
+	KindOrder    = "order"    // 下单结果
+	KindControl  = "control"  // 运行时操作（启停策略、解除熔断等）
+)
+
+// Entry 审计记录：每条记录的哈希覆盖记录内容和上一条记录的哈希，修改或删除任意一条都会使之后的链校验失败
+type Entry struct {
+	Seq      uint64          `json:"seq"`
+	Time     time.Time       `json:"time"`
+	RunID    string          `json:"run_id"`
+	Kind     string          `json:"kind"`
+	TraderID string          `json:"trader_id,omitempty"`
+	Actor    string          `json:"actor,omitempty"` // 操作来源（API请求的客户端地址等）
+	Data     json.RawMessage `json:"data,omitempty"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+}
+
+// Log 只追加的审计日志（JSON Lines，每行一条记录）
+type Log struct {
+	path string
+	key  []byte // 非空时使用HMAC-SHA256（没有密钥无法重新计算整条链）
+
+	mu       sync.Mutex
+	file     *os.File
+	seq      uint64
+	lastHash string
+}
+
+// VerifyResult 审计日志校验结果
+type VerifyResult struct {
+	Path     string `json:"path"`
+	Entries  uint64 `json:"entries"`
+	Head     string `json:"head"` // 最后一条记录的哈希（可另行保存，用于发现整条链被重写）
+	Valid    bool   `json:"valid"`
+	BrokenAt uint64 `json:"broken_at,omitempty"` // 第一条校验失败的记录序号
+	Error    string `json:"error,omitempty"`
+}
+
+// Open 打开（或创建）审计日志，从最后一条记录继续追加；已有记录校验失败时只输出警告（保留证据，不截断）
+func Open(path string, key []byte) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建审计日志目录失败: %w", err)
+	}
+
+	result, last, err := verify(path, key)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if !result.Valid && result.Entries > 0 {
+		log.Printf("🚨 审计日志 %s 校验失败（第%d条）: %s", path, result.BrokenAt, result.Error)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志失败: %w", err)
+	}
+	l := &Log{path: path, key: key, file: file}
+	if last != nil {
+		l.seq, l.lastHash = last.Seq, last.Hash
+	}
+	return l, nil
+}
+
+// Append 追加一条记录（写入后立即落盘）
+func (l *Log) Append(kind, traderID, actor string, data interface{}) (*Entry, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("序列化审计数据失败: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := &Entry{
+		Seq:      l.seq + 1,
+		Time:     time.Now().UTC(),
+		RunID:    run.ID(),
+		Kind:     kind,
+		TraderID: traderID,
+		Actor:    actor,
+		Data:     raw,
+		PrevHash: l.lastHash,
+	}
+	if entry.Hash, err = entryHash(entry, l.key); err != nil {
+		return nil, err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("序列化审计记录失败: %w", err)
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("写入审计日志失败: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return nil, fmt.Errorf("写入审计日志失败: %w", err)
+	}
+	l.seq, l.lastHash = entry.Seq, entry.Hash
+	return entry, nil
+}
+
+// Close 关闭审计日志
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Verify 校验审计日志的哈希链
+func Verify(path string, key []byte) (VerifyResult, error) {
+	result, _, err := verify(path, key)
+	return result, err
+}
+
+// verify 逐条校验序号、前一条哈希和本条哈希，返回结果和最后一条可解析的记录
+func verify(path string, key []byte) (VerifyResult, *Entry, error) {
+	result := VerifyResult{Path: path, Valid: true}
+	file, err := os.Open(path)
+	if err != nil {
+		return result, nil, err
+	}
+	defer file.Close()
+
+	var last *Entry
+	fail := func(seq uint64, format string, args ...interface{}) {
+		if result.Valid {
+			result.Valid, result.BrokenAt, result.Error = false, seq, fmt.Sprintf(format, args...)
+		}
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		expectedSeq := uint64(1)
+		prevHash := ""
+		if last != nil {
+			expectedSeq, prevHash = last.Seq+1, last.Hash
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			fail(expectedSeq, "无法解析: %v", err)
+			continue
+		}
+		result.Entries++
+		switch {
+		case entry.Seq != expectedSeq:
+			fail(expectedSeq, "序号不连续（期望%d，实际%d）", expectedSeq, entry.Seq)
+		case entry.PrevHash != prevHash:
+			fail(entry.Seq, "prev_hash与上一条记录不一致")
+		default:
+			if hash, err := entryHash(&entry, key); err != nil || hash != entry.Hash {
+				fail(entry.Seq, "哈希不匹配（记录被修改或密钥不一致）")
+			}
+		}
+		last = &entry
+	}
+	if err := scanner.Err(); err != nil {
+		return result, last, fmt.Errorf("读取审计日志失败: %w", err)
+	}
+	if last != nil {
+		result.Head = last.Hash
+	}
+	return result, last, nil
+}
+
+// entryHash 记录的哈希：对Hash置空后的JSON计算SHA-256（有密钥时为HMAC-SHA256）
+func entryHash(entry *Entry, key []byte) (string, error) {
+	unsigned := *entry
+	unsigned.Hash = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", fmt.Errorf("序列化审计记录失败: %w", err)
+	}
+	if len(key) > 0 {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// 全局审计日志（未启用时为nil，Record为空操作）
+var (
+	defaultLog   *Log
+	defaultMutex sync.RWMutex
+)
+
+// Start 打开审计日志并设为全局实例
+func Start(path string, key []byte) error {
+	l, err := Open(path, key)
+	if err != nil {
+		return err
+	}
+	defaultMutex.Lock()
+	defaultLog = l
+	defaultMutex.Unlock()
+	return nil
+}
+
+// Record 向全局审计日志追加一条记录（未启用时忽略，写入失败只输出警告）
+func Record(kind, traderID, actor string, data interface{}) {
+	defaultMutex.RLock()
+	l := defaultLog
+	defaultMutex.RUnlock()
+	if l == nil {
+		return
+	}
+	if _, err := l.Append(kind, traderID, actor, data); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// Status 校验全局审计日志（未启用时返回nil）
+func Status() (*VerifyResult, error) {
+	defaultMutex.RLock()
+	l := defaultLog
+	defaultMutex.RUnlock()
+	if l == nil {
+		return nil, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result, err := Verify(l.path, l.key)
+	return &result, err
+}
+
+// RecordConfig 记录本次运行加载的配置（密钥已脱敏），并与审计日志中上一次记录的配置比较，列出变化的顶层字段
+func RecordConfig(manifest *run.Manifest) {
+	defaultMutex.RLock()
+	l := defaultLog
+	defaultMutex.RUnlock()
+	if l == nil || manifest == nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"config_file":   manifest.ConfigFile,
+		"config_sha256": manifest.ConfigSHA256,
+		"revision":      manifest.Revision,
+		"config":        manifest.Config,
+	}
+	if previous := l.lastConfig(); previous != nil && previous.SHA256 != manifest.ConfigSHA256 {
+		data["previous_sha256"] = previous.SHA256
+		data["changed"] = changedKeys(previous.Config, manifest.Config)
+	}
+	Record(KindConfig, "", "", data)
+}
+
+// configData 审计记录中的配置内容
+type configData struct {
+	SHA256 string                 `json:"config_sha256"`
+	Config map[string]interface{} `json:"config"`
+}
+
+// lastConfig 审计日志中最后一条配置记录
+func (l *Log) lastConfig() *configData {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var last *configData
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.Kind != KindConfig {
+			continue
+		}
+		var data configData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			last = &data
+		}
+	}
+	return last
+}
+
+// changedKeys 两份配置中值不同的顶层字段（按名称排序）
+func changedKeys(previous, current map[string]interface{}) []string {
+	changed := []string{}
+	for key, value := range current {
+		before, _ := json.Marshal(previous[key])
+		after, _ := json.Marshal(value)
+		if _, ok := previous[key]; !ok || string(before) != string(after) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
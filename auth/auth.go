@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+)
+
+// Role API角色
+type Role string
+
+const (
+	RoleObserver Role = "observer" // 只读（状态、持仓、决策日志、行情、事件流）
+	RoleOperator Role = "operator" // 只读 + 运行时操作（全局熔断/解除、启停策略）
+)
+
+// ParseRole 解析角色名称
+func ParseRole(name string) (Role, error) {
+	switch Role(strings.ToLower(name)) {
+	case RoleObserver:
+		return RoleObserver, nil
+	case RoleOperator:
+		return RoleOperator, nil
+	}
+	return "", fmt.Errorf("未知的API角色: %s（支持 observer 或 operator）", name)
+}
+
+// Allows 该角色是否具有required角色的权限（operator包含observer的全部权限）
+func (r Role) Allows(required Role) bool {
+	return r == RoleOperator || r == required
+}
+
+// Principal 通过认证的调用方
+type Principal struct {
+	Name string // API Key名称（匿名访问为"anonymous"）
+	Role Role
+}
+
+// apiKey 已配置的API Key（只保存哈希）
+type apiKey struct {
+	name string
+	role Role
+	hash [sha256.Size]byte
+}
+
+// Authenticator HTTP与gRPC共用的API Key认证
+type Authenticator struct {
+	keys      []apiKey
+	anonymous Role // 未提供API Key时的角色（为空表示拒绝）
+}
+
+// NewAuthenticator 创建认证器（anonymous为未携带API Key的请求的角色，空表示必须认证）
+func NewAuthenticator(anonymous Role) *Authenticator {
+	return &Authenticator{anonymous: anonymous}
+}
+
+// AddKey 添加API Key
+func (a *Authenticator) AddKey(name, key string, role Role) error {
+	if key == "" {
+		return fmt.Errorf("API Key %s 为空", name)
+	}
+	hash := sha256.Sum256([]byte(key))
+	for _, existing := range a.keys {
+		if existing.hash == hash {
+			return fmt.Errorf("API Key %s 与 %s 相同", name, existing.name)
+		}
+	}
+	a.keys = append(a.keys, apiKey{name: name, role: role, hash: hash})
+	return nil
+}
+
+// Authenticate 校验API Key（空字符串表示未提供），返回调用方
+func (a *Authenticator) Authenticate(key string) (Principal, error) {
+	if key == "" {
+		if a.anonymous == "" {
+			return Principal{}, fmt.Errorf("缺少API Key")
+		}
+		return Principal{Name: "anonymous", Role: a.anonymous}, nil
+	}
+	hash := sha256.Sum256([]byte(key))
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], k.hash[:]) == 1 {
+			return Principal{Name: k.name, Role: k.role}, nil
+		}
+	}
+	return Principal{}, fmt.Errorf("无效的API Key")
+}
+
+// BearerToken 从Authorization头（Bearer <key>）或X-API-Key头的值中取出API Key
+func BearerToken(authorization, apiKeyHeader string) string {
+	if apiKeyHeader != "" {
+		return apiKeyHeader
+	}
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "Bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testConfig 最小可用配置，memorySize为trader的memory_size字段（空字符串表示不设置）
+func testConfig(memorySize string) []byte {
+	field := ""
+	if memorySize != "" {
+		field = fmt.Sprintf(`"memory_size": %s,`, memorySize)
+	}
+	return []byte(fmt.Sprintf(`{
+  "traders": [{
+    "id": "test",
+    "name": "Test",
+    "enabled": true,
+    "ai_model": "deepseek",
+    "exchange": "binance",
+    "binance_api_key": "key",
+    "binance_secret_key": "secret",
+    "deepseek_key": "sk-test",
+    %s
+    "initial_balance": 1000,
+    "scan_interval_minutes": 3
+  }],
+  "use_default_coins": true,
+  "api_server_port": 8080
+}`, field))
+}
+
+// TestMemorySize memory_size未设置时为默认5条，0表示关闭交易记忆，超过上限时拒绝
+func TestMemorySize(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{"unset", "", defaultMemorySize},
+		{"disabled", "0", 0},
+		{"custom", "8", 8},
+		{"max", "10", maxMemorySize},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := parseConfig(testConfig(tc.value))
+			if err != nil {
+				t.Fatalf("解析配置失败: %v", err)
+			}
+			if got := cfg.Traders[0].GetMemorySize(); got != tc.want {
+				t.Fatalf("memory_size=%q 应为 %d，实际 %d", tc.value, tc.want, got)
+			}
+		})
+	}
+
+	for _, value := range []string{"-1", "11"} {
+		t.Run("invalid_"+value, func(t *testing.T) {
+			_, err := parseConfig(testConfig(value))
+			if err == nil || !strings.Contains(err.Error(), "memory_size") {
+				t.Fatalf("memory_size=%s 应被拒绝，实际错误: %v", value, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"nofx/secret"
+)
+
+// decryptSecrets 解密配置中enc:前缀的字符串（用 nofx encrypt-secret 生成），
+// 密钥从encryption.key_env指定的环境变量读取；没有加密字符串时原样返回
+func decryptSecrets(data []byte) ([]byte, error) {
+	var raw interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // 保持整数精度（如seed）
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	if !hasEncrypted(raw) {
+		return data, nil
+	}
+
+	keyEnv := ""
+	if root, ok := raw.(map[string]interface{}); ok {
+		if enc, ok := root["encryption"].(map[string]interface{}); ok {
+			keyEnv, _ = enc["key_env"].(string)
+		}
+	}
+	key, err := secret.Key(keyEnv)
+	if err != nil {
+		return nil, fmt.Errorf("配置中包含加密的密钥: %w", err)
+	}
+	if raw, err = decryptValue(key, "", raw); err != nil {
+		return nil, err
+	}
+	return json.Marshal(raw)
+}
+
+// hasEncrypted 配置中是否有enc:前缀的字符串
+func hasEncrypted(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, item := range v {
+			if hasEncrypted(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if hasEncrypted(item) {
+				return true
+			}
+		}
+	case string:
+		return secret.IsEncryptedString(v)
+	}
+	return false
+}
+
+// decryptValue 递归解密所有enc:前缀的字符串，path用于错误信息中指出字段
+func decryptValue(key []byte, path string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, item := range v {
+			decrypted, err := decryptValue(key, joinPath(path, name), item)
+			if err != nil {
+				return nil, err
+			}
+			v[name] = decrypted
+		}
+	case []interface{}:
+		for i, item := range v {
+			decrypted, err := decryptValue(key, fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = decrypted
+		}
+	case string:
+		plaintext, err := secret.DecryptString(key, v)
+		if err != nil {
+			return nil, fmt.Errorf("解密配置字段 %s 失败: %w", path, err)
+		}
+		return plaintext, nil
+	}
+	return value, nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvSource 从环境变量加载配置时的配置来源名（nofx run --config env）
+const EnvSource = "env"
+
+// 环境变量配置
+const (
+	EnvPrefix     = "NOFX_"            // 配置字段环境变量前缀（NOFX_<JSON路径大写，以_连接>）
+	EnvConfigJSON = "NOFX_CONFIG_JSON" // 完整配置JSON（可选，作为基础配置，其他环境变量覆盖其中的字段）
+	EnvConfig     = "NOFX_CONFIG"      // 配置来源（文件路径或env），nofx run未指定--config时使用
+)
+
+// LoadConfigFromEnv 从环境变量加载配置（不读取配置文件），处理流程与LoadConfig相同
+func LoadConfigFromEnv() (*Config, error) {
+	data, err := EnvJSON()
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data)
+}
+
+// EnvJSON 由环境变量生成配置JSON：以NOFX_CONFIG_JSON为基础，按名称顺序应用NOFX_<字段路径>变量。
+// 字段路径为JSON字段名大写后以_连接，数组元素用下标（如NOFX_TRADERS_0_BINANCE_API_KEY）；
+// 数字/布尔按类型解析，字符串和数字数组用逗号分隔，对象、map和对象数组的值为JSON。
+// 不对应任何配置字段的NOFX_变量（如NOFX_ENCRYPTION_KEY）被忽略
+func EnvJSON() ([]byte, error) {
+	root := map[string]interface{}{}
+	if base := os.Getenv(EnvConfigJSON); base != "" {
+		decoder := json.NewDecoder(strings.NewReader(base))
+		decoder.UseNumber() // 保持整数精度（如seed）
+		if err := decoder.Decode(&root); err != nil {
+			return nil, fmt.Errorf("解析%s失败: %w", EnvConfigJSON, err)
+		}
+	}
+
+	var names []string
+	values := make(map[string]string)
+	for _, env := range os.Environ() {
+		name, value, ok := strings.Cut(env, "=")
+		// 空值视为未设置（便于compose中用${VAR:-}透传可选变量）
+		if !ok || value == "" || !strings.HasPrefix(name, EnvPrefix) || name == EnvConfig || name == EnvConfigJSON {
+			continue
+		}
+		names = append(names, name)
+		values[name] = value
+	}
+	// 按名称排序：整体JSON（如NOFX_TRADERS）先于其中的字段（如NOFX_TRADERS_0_ID）应用
+	sort.Strings(names)
+
+	for _, name := range names {
+		path, leaf, ok := envPath(reflect.TypeOf(Config{}), strings.TrimPrefix(name, EnvPrefix))
+		if !ok {
+			continue
+		}
+		value, err := envValue(leaf, values[name])
+		if err != nil {
+			return nil, fmt.Errorf("环境变量 %s: %w", name, err)
+		}
+		if err := setPath(root, path, value); err != nil {
+			return nil, fmt.Errorf("环境变量 %s: %w", name, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+// envPath 将变量名（去掉前缀）解析为JSON路径（字段名或数组下标）和目标字段类型
+func envPath(t reflect.Type, name string) ([]interface{}, reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if name == "" {
+		return nil, t, true
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := strings.Split(field.Tag.Get("json"), ",")[0]
+			if tag == "" || tag == "-" {
+				continue
+			}
+			upper := strings.ToUpper(tag)
+			var rest string
+			switch {
+			case name == upper:
+			case strings.HasPrefix(name, upper+"_"):
+				rest = strings.TrimPrefix(name, upper+"_")
+			default:
+				continue
+			}
+			if path, leaf, ok := envPath(field.Type, rest); ok {
+				return append([]interface{}{tag}, path...), leaf, true
+			}
+		}
+	case reflect.Slice:
+		// 只有对象数组支持按下标设置元素字段，其他数组整体设置
+		elem := t.Elem()
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return nil, nil, false
+		}
+		indexStr, rest, _ := strings.Cut(name, "_")
+		index, err := strconv.Atoi(indexStr)
+		if err != nil || index < 0 {
+			return nil, nil, false
+		}
+		if path, leaf, ok := envPath(elem, rest); ok {
+			return append([]interface{}{index}, path...), leaf, true
+		}
+	}
+	return nil, nil, false
+}
+
+// envValue 按字段类型解析环境变量的值
+func envValue(t reflect.Type, value string) (interface{}, error) {
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		if !json.Valid([]byte(value)) {
+			return nil, fmt.Errorf("需要JSON值")
+		}
+		return json.RawMessage(value), nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		return strconv.ParseBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(value, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(value, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(value, 64)
+	case reflect.Slice:
+		if elem := t.Elem(); elem.Kind() != reflect.Struct && elem.Kind() != reflect.Ptr && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			var items []interface{}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				parsed, err := envValue(elem, item)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, parsed)
+			}
+			return items, nil
+		}
+	}
+	// 对象、map、对象数组：JSON
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("需要JSON值: %w", err)
+	}
+	return parsed, nil
+}
+
+// setPath 在配置JSON树中设置路径上的值（按需创建中间对象和数组元素）
+func setPath(node interface{}, path []interface{}, value interface{}) error {
+	for i, key := range path {
+		last := i == len(path)-1
+		switch k := key.(type) {
+		case string:
+			object, ok := node.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s 不是对象", k)
+			}
+			if last {
+				object[k] = value
+				return nil
+			}
+			next := object[k]
+			if next == nil {
+				if _, isIndex := path[i+1].(int); isIndex {
+					next = []interface{}{}
+				} else {
+					next = map[string]interface{}{}
+				}
+			}
+			// 数组扩容后需要写回父对象
+			if array, ok := next.([]interface{}); ok {
+				index, isIndex := path[i+1].(int)
+				if !isIndex {
+					return fmt.Errorf("%s 是数组", k)
+				}
+				for len(array) <= index {
+					array = append(array, map[string]interface{}{})
+				}
+				next = array
+			}
+			object[k] = next
+			node = next
+		case int:
+			array, ok := node.([]interface{})
+			if !ok || k >= len(array) {
+				return fmt.Errorf("下标 %d 无效", k)
+			}
+			if last {
+				array[k] = value
+				return nil
+			}
+			if array[k] == nil {
+				array[k] = map[string]interface{}{}
+			}
+			node = array[k]
+		}
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package decision
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"nofx/market"
 	"nofx/mcp"
 	"nofx/pool"
@@ -24,6 +26,8 @@ type PositionInfo struct {
 	LiquidationPrice float64 `json:"liquidation_price"`
 	MarginUsed       float64 `json:"margin_used"`
 	UpdateTime       int64   `json:"update_time"` // 持仓更新时间戳（毫秒）
+	StopLoss         float64 `json:"stop_loss"`   // 当前止损价（0表示未知）
+	TakeProfit       float64 `json:"take_profit"` // 当前止盈价（0表示未知）
 }
 
 // AccountInfo 账户信息
@@ -53,19 +57,98 @@ type OITopData struct {
 	NetShort          float64 // 净空仓
 }
 
+// SymbolOverride 单个币种的策略覆盖配置（BTC与小市值山寨币需要完全不同的处理）
+type SymbolOverride struct {
+	Prompt           string  // 该币种的专属策略提示（追加在其市场数据之后）
+	Leverage         int     // 杠杆上限（0表示使用全局杠杆配置）
+	MaxPositionRatio float64 // 单币仓位价值上限（账户净值倍数，0表示使用默认值）
+	TrendInterval    string  // 趋势判断K线周期（空表示默认4h）
+	EntryInterval    string  // 入场信号K线周期（空表示默认15m）
+	DisableOpen      bool    // 禁止开新仓（只管理已有持仓）
+}
+
 // Context 交易上下文（传递给AI的完整信息）
 type Context struct {
-	CurrentTime     string                  `json:"current_time"`
-	RuntimeMinutes  int                     `json:"runtime_minutes"`
-	CallCount       int                     `json:"call_count"`
-	Account         AccountInfo             `json:"account"`
-	Positions       []PositionInfo          `json:"positions"`
-	CandidateCoins  []CandidateCoin         `json:"candidate_coins"`
-	MarketDataMap   map[string]*market.Data `json:"-"` // 不序列化，但内部使用
-	OITopDataMap    map[string]*OITopData   `json:"-"` // OI Top数据映射
-	Performance     interface{}             `json:"-"` // 历史表现分析（logger.PerformanceAnalysis）
-	BTCETHLeverage  int                     `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
-	AltcoinLeverage int                     `json:"-"` // 山寨币杠杆倍数（从配置读取）
+	CurrentTime     string                    `json:"current_time"`
+	RuntimeMinutes  int                       `json:"runtime_minutes"`
+	CallCount       int                       `json:"call_count"`
+	Account         AccountInfo               `json:"account"`
+	Positions       []PositionInfo            `json:"positions"`
+	CandidateCoins  []CandidateCoin           `json:"candidate_coins"`
+	MarketDataMap   map[string]*market.Data   `json:"-"` // 不序列化，但内部使用
+	PrevMarketData  map[string]*market.Data   `json:"-"` // 上一周期的市场数据（用于生成变化摘要）
+	OITopDataMap    map[string]*OITopData     `json:"-"` // OI Top数据映射
+	Performance     interface{}               `json:"-"` // 历史表现分析（logger.PerformanceAnalysis）
+	MemorySize      int                       `json:"-"` // 决策记忆条数（最近N笔交易写入prompt）
+	BTCETHLeverage  int                       `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
+	AltcoinLeverage int                       `json:"-"` // 山寨币杠杆倍数（从配置读取）
+	SymbolOverrides map[string]SymbolOverride `json:"-"` // 按币种的策略覆盖配置（key为标准化symbol）
+	ExtraPrompt     string                    `json:"-"` // 追加到系统prompt的策略说明（影子策略等）
+	Pairs           []PairInfo                `json:"-"` // 配对交易的价差数据（未配置配对时为空）
+	EntryBlocked    string                    `json:"-"` // 禁止开新仓的原因（交易时段过滤等，空表示允许开仓）
+	FundingBlackout time.Duration             `json:"-"` // 资金费结算前该时间内禁止开仓（0表示不限制）
+}
+
+// PairInfo 配对交易的价差数据和当前持仓
+type PairInfo struct {
+	Spread   *market.Spread
+	Position string  // 当前持仓方向："long"（多base空quote）、"short"（空base多quote），空表示无持仓
+	EntryZ   float64 // 开仓时的z-score
+}
+
+// 配对交易动作（symbol为 "BASE/QUOTE"，两条腿同时下单，position_size_usd为每条腿的名义价值）
+const (
+	ActionOpenPairLong  = "open_pair_long"  // 做多价差：多base、空quote（价格比偏低时）
+	ActionOpenPairShort = "open_pair_short" // 做空价差：空base、多quote（价格比偏高时）
+	ActionClosePair     = "close_pair"      // 同时平掉两条腿
+)
+
+// IsPairAction 是否为配对交易动作
+func IsPairAction(action string) bool {
+	return action == ActionOpenPairLong || action == ActionOpenPairShort || action == ActionClosePair
+}
+
+// IsOpenAction 是否为开仓动作（含配对开仓）
+func IsOpenAction(action string) bool {
+	switch action {
+	case "open_long", "open_short", ActionOpenPairLong, ActionOpenPairShort:
+		return true
+	}
+	return false
+}
+
+// checkFundingBlackout 资金费结算前的禁止开仓窗口（结算前开仓往往一开始就要支付资金费）
+func (ctx *Context) checkFundingBlackout(symbol string) error {
+	if ctx.FundingBlackout <= 0 {
+		return nil
+	}
+	data, ok := ctx.MarketDataMap[symbol]
+	if !ok || !data.FundingWithin(ctx.FundingBlackout) {
+		return nil
+	}
+	return fmt.Errorf("%s 将在 %.0f 分钟后结算资金费，结算前 %.0f 分钟内禁止开仓",
+		symbol, data.NextFundingTime.Sub(market.Clock.Now()).Minutes(), ctx.FundingBlackout.Minutes())
+}
+
+// getOverride 获取指定币种的覆盖配置
+func (ctx *Context) getOverride(symbol string) (SymbolOverride, bool) {
+	if ctx.SymbolOverrides == nil {
+		return SymbolOverride{}, false
+	}
+	override, ok := ctx.SymbolOverrides[market.Normalize(symbol)]
+	return override, ok
+}
+
+// TradeMemory 交易记忆（最近已平仓交易的结果，用于避免重复犯错）
+type TradeMemory struct {
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"`
+	OpenPrice  float64   `json:"open_price"`
+	ClosePrice float64   `json:"close_price"`
+	PnL        float64   `json:"pn_l"`
+	PnLPct     float64   `json:"pn_l_pct"`
+	Duration   string    `json:"duration"`
+	CloseTime  time.Time `json:"close_time"`
 }
 
 // Decision AI的交易决策
@@ -79,6 +162,16 @@ type Decision struct {
 	Confidence      int     `json:"confidence,omitempty"` // 信心度 (0-100)
 	RiskUSD         float64 `json:"risk_usd,omitempty"`   // 最大美元风险
 	Reasoning       string  `json:"reasoning"`
+
+	// 策略归因（多策略部署时用于区分各策略的成交和表现；为空时由trader按来源填充）
+	StrategyID string   `json:"strategy_id,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+
+	// 开仓信号的失效条件（任一满足时取消尚未成交的开仓单）
+	Invalidations []Invalidation `json:"invalidations,omitempty"`
+
+	// 信号依据（开平仓时由引擎根据市场数据附加的关键指标读数）
+	Explanation *market.Explanation `json:"explanation,omitempty"`
 }
 
 // FullDecision AI的完整决策（包含思维链）
@@ -87,36 +180,92 @@ type FullDecision struct {
 	CoTTrace   string     `json:"cot_trace"`   // 思维链分析（AI输出）
 	Decisions  []Decision `json:"decisions"`   // 具体决策列表
 	Timestamp  time.Time  `json:"timestamp"`
+
+	// 验证前剔除的决策（禁止开新仓期间的开仓），其余决策照常验证执行
+	Rejected []RejectedDecision `json:"rejected,omitempty"`
+
+	// 各阶段耗时（用于周期延迟统计）
+	FetchDuration   time.Duration `json:"-"` // 获取行情数据
+	ComputeDuration time.Duration `json:"-"` // 构建prompt和解析响应
+	LLMDuration     time.Duration `json:"-"` // AI调用
+}
+
+// RejectedDecision 被剔除的决策及原因
+type RejectedDecision struct {
+	Decision Decision `json:"decision"`
+	Reason   string   `json:"reason"`
 }
 
 // GetFullDecision 获取AI的完整交易决策（批量分析所有币种和持仓）
 func GetFullDecision(ctx *Context, mcpClient *mcp.Client) (*FullDecision, error) {
-	// 1. 为所有币种获取市场数据
-	if err := fetchMarketDataForContext(ctx); err != nil {
-		return nil, fmt.Errorf("获取市场数据失败: %w", err)
+	// 1. 为所有币种获取市场数据（已提供MarketDataMap时直接使用，如影子策略复用实盘数据）
+	fetchStart := time.Now()
+	if ctx.MarketDataMap == nil {
+		if err := fetchMarketDataForContext(ctx); err != nil {
+			return nil, fmt.Errorf("获取市场数据失败: %w", err)
+		}
 	}
+	fetchDuration := time.Since(fetchStart)
 
 	// 2. 构建 System Prompt（固定规则）和 User Prompt（动态数据）
+	computeStart := time.Now()
 	systemPrompt := buildSystemPrompt(ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+	if len(ctx.Pairs) > 0 {
+		systemPrompt += buildPairPrompt()
+	}
+	if ctx.ExtraPrompt != "" {
+		systemPrompt += "\n# 策略补充说明\n\n" + ctx.ExtraPrompt + "\n"
+	}
 	userPrompt := buildUserPrompt(ctx)
+	computeDuration := time.Since(computeStart)
 
 	// 3. 调用AI API（使用 system + user prompt）
+	llmStart := time.Now()
 	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("调用AI API失败: %w", err)
 	}
+	llmDuration := time.Since(llmStart)
 
 	// 4. 解析AI响应
-	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+	parseStart := time.Now()
+	decision, err := parseFullDecisionResponse(aiResponse, ctx)
 	if err != nil {
 		return nil, fmt.Errorf("解析AI响应失败: %w", err)
 	}
 
+	attachExplanations(decision, ctx)
+
 	decision.Timestamp = time.Now()
 	decision.UserPrompt = userPrompt // 保存输入prompt
+	decision.FetchDuration = fetchDuration
+	decision.ComputeDuration = computeDuration + time.Since(parseStart)
+	decision.LLMDuration = llmDuration
 	return decision, nil
 }
 
+// attachExplanations 为开平仓决策附加信号依据（决策时的关键指标读数）
+func attachExplanations(fd *FullDecision, ctx *Context) {
+	for i := range fd.Decisions {
+		d := &fd.Decisions[i]
+		data, ok := ctx.MarketDataMap[d.Symbol]
+		if !ok {
+			continue
+		}
+		switch d.Action {
+		case "open_long":
+			d.Explanation = market.Explain(data, "long")
+		case "open_short":
+			d.Explanation = market.Explain(data, "short")
+		case "close_long", "close_short":
+			d.Explanation = market.Explain(data, "")
+		}
+	}
+}
+
+// delistingBlockWindow 计划下架前多长时间禁止开仓
+const delistingBlockWindow = 24 * time.Hour
+
 // fetchMarketDataForContext 为上下文中的所有币种获取市场数据和OI数据
 func fetchMarketDataForContext(ctx *Context) error {
 	ctx.MarketDataMap = make(map[string]*market.Data)
@@ -136,6 +285,10 @@ func fetchMarketDataForContext(ctx *Context) error {
 		if i >= maxCandidates {
 			break
 		}
+		// 禁止开新仓的币种不作为候选（已有持仓仍然会获取数据）
+		if override, ok := ctx.getOverride(coin.Symbol); ok && override.DisableOpen {
+			continue
+		}
 		symbolSet[coin.Symbol] = true
 	}
 
@@ -147,9 +300,15 @@ func fetchMarketDataForContext(ctx *Context) error {
 	}
 
 	for symbol := range symbolSet {
-		data, err := market.Get(symbol)
+		override, _ := ctx.getOverride(symbol)
+		data, err := market.GetWithIntervals(symbol, override.TrendInterval, override.EntryInterval)
 		if err != nil {
 			// 单个币种失败不影响整体，只记录错误
+			if errors.Is(err, market.ErrStaleData) {
+				log.Printf("⚠️  %v，跳过此币种", err)
+			} else {
+				log.Printf("⚠️  获取%s市场数据失败，跳过此币种: %v", symbol, err)
+			}
 			continue
 		}
 
@@ -157,6 +316,13 @@ func fetchMarketDataForContext(ctx *Context) error {
 		// 持仓价值 = 持仓量 × 当前价格
 		// 但现有持仓必须保留（需要决策是否平仓）
 		isExistingPosition := positionSymbols[symbol]
+
+		// 已下架或即将下架的币种不作为候选（现有持仓保留，需要决策平仓）
+		if !isExistingPosition && data.DelistingWithin(delistingBlockWindow) {
+			log.Printf("⚠️  %s 已下架或即将下架，跳过此币种", symbol)
+			continue
+		}
+
 		if !isExistingPosition && data.OpenInterest != nil && data.CurrentPrice > 0 {
 			// 计算持仓价值（USD）= 持仓量 × 当前价格
 			oiValue := data.OpenInterest.Latest * data.CurrentPrice
@@ -224,7 +390,8 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	sb.WriteString("2. **最多持仓**: 3个币种（质量>数量）\n")
 	sb.WriteString(fmt.Sprintf("3. **单币仓位**: 山寨%.0f-%.0f U(%dx杠杆) | BTC/ETH %.0f-%.0f U(%dx杠杆)\n",
 		accountEquity*0.8, accountEquity*1.5, altcoinLeverage, accountEquity*5, accountEquity*10, btcEthLeverage))
-	sb.WriteString("4. **保证金**: 总使用率 ≤ 90%\n\n")
+	sb.WriteString("4. **保证金**: 总使用率 ≤ 90%\n")
+	sb.WriteString("5. **币种专属规则**: 如果某个币种标注了「专属策略」或专属杠杆/仓位上限，以该币种的规则为准\n\n")
 
 	// === 趋势判断规则（MA21+MA15策略）===
 	sb.WriteString("# 📈 趋势判断规则（MA21+MA15策略）\n\n")
@@ -278,7 +445,8 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	sb.WriteString("- 单一维度（只看一个指标）\n")
 	sb.WriteString("- 相互矛盾（涨但量萎缩）\n")
 	sb.WriteString("- 横盘震荡\n")
-	sb.WriteString("- 刚平仓不久（<15分钟）\n\n")
+	sb.WriteString("- 刚平仓不久（<15分钟）\n")
+	sb.WriteString("- 重复交易记忆中刚亏损过的同币种同方向仓位\n\n")
 
 	// === 夏普比率自我进化 ===
 	sb.WriteString("# 🧬 夏普比率自我进化\n\n")
@@ -319,7 +487,8 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	sb.WriteString("**字段说明**:\n")
 	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | hold | wait\n")
 	sb.WriteString("- `confidence`: 0-100（开仓建议≥75）\n")
-	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n\n")
+	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n")
+	sb.WriteString("- 开仓可选 `invalidations`: 开仓单成交前的失效条件，如 [{\"interval\": \"4h\", \"close\": \"below\", \"ma\": 21}]（4h收盘价低于MA21时取消），参考值用ma或price\n\n")
 
 	// === 关键提醒 ===
 	sb.WriteString("---\n\n")
@@ -332,6 +501,20 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	return sb.String()
 }
 
+// fundingBlackoutSymbols 处于资金费结算前禁止开仓窗口的候选币种
+func fundingBlackoutSymbols(ctx *Context) []string {
+	if ctx.FundingBlackout <= 0 {
+		return nil
+	}
+	var symbols []string
+	for _, coin := range ctx.CandidateCoins {
+		if data, ok := ctx.MarketDataMap[coin.Symbol]; ok && data.FundingWithin(ctx.FundingBlackout) {
+			symbols = append(symbols, coin.Symbol)
+		}
+	}
+	return symbols
+}
+
 // buildUserPrompt 构建 User Prompt（动态数据）
 func buildUserPrompt(ctx *Context) string {
 	var sb strings.Builder
@@ -339,19 +522,25 @@ func buildUserPrompt(ctx *Context) string {
 	// 系统状态
 	sb.WriteString(fmt.Sprintf("**时间**: %s | **周期**: #%d | **运行**: %d分钟\n\n",
 		ctx.CurrentTime, ctx.CallCount, ctx.RuntimeMinutes))
+	if ctx.EntryBlocked != "" {
+		sb.WriteString(fmt.Sprintf("⛔ **当前禁止开新仓**: %s（只能平仓或观望）\n\n", ctx.EntryBlocked))
+	}
+	if blackout := fundingBlackoutSymbols(ctx); len(blackout) > 0 {
+		sb.WriteString(fmt.Sprintf("⏳ **资金费结算前%.0f分钟内禁止开仓**: %s\n\n", ctx.FundingBlackout.Minutes(), strings.Join(blackout, ", ")))
+	}
 
 	// BTC 市场
 	if btcData, hasBTC := ctx.MarketDataMap["BTCUSDT"]; hasBTC {
 		// 判断趋势
 		trend := "横盘"
-		if len(btcData.MA21_4hSeries) >= 3 {
+		if len(btcData.TrendMASeries) >= 3 {
 			rising := true
 			falling := true
-			for i := 1; i < len(btcData.MA21_4hSeries); i++ {
-				if btcData.MA21_4hSeries[i] <= btcData.MA21_4hSeries[i-1] {
+			for i := 1; i < len(btcData.TrendMASeries); i++ {
+				if btcData.TrendMASeries[i] <= btcData.TrendMASeries[i-1] {
 					rising = false
 				}
-				if btcData.MA21_4hSeries[i] >= btcData.MA21_4hSeries[i-1] {
+				if btcData.TrendMASeries[i] >= btcData.TrendMASeries[i-1] {
 					falling = false
 				}
 			}
@@ -364,8 +553,9 @@ func buildUserPrompt(ctx *Context) string {
 
 		sb.WriteString(fmt.Sprintf("**BTC**: %.2f (1h: %+.2f%%, 4h: %+.2f%%)\n",
 			btcData.CurrentPrice, btcData.PriceChange1h, btcData.PriceChange4h))
-		sb.WriteString(fmt.Sprintf("MA21_4h: %.2f | 趋势: %s | MA15_15m: %.2f\n\n",
-			btcData.MA21_4h, trend, btcData.MA15_15m))
+		sb.WriteString(fmt.Sprintf("MA%d_%s: %.2f | 趋势: %s | MA%d_%s: %.2f\n\n",
+			btcData.Indicators.TrendMA, btcData.TrendInterval, btcData.TrendMA, trend,
+			btcData.Indicators.EntryMA, btcData.EntryInterval, btcData.EntryMA))
 	}
 
 	// 账户
@@ -381,29 +571,17 @@ func buildUserPrompt(ctx *Context) string {
 	if len(ctx.Positions) > 0 {
 		sb.WriteString("## 当前持仓\n")
 		for i, pos := range ctx.Positions {
-			// 计算持仓时长
-			holdingDuration := ""
-			if pos.UpdateTime > 0 {
-				durationMs := time.Now().UnixMilli() - pos.UpdateTime
-				durationMin := durationMs / (1000 * 60) // 转换为分钟
-				if durationMin < 60 {
-					holdingDuration = fmt.Sprintf(" | 持仓时长%d分钟", durationMin)
-				} else {
-					durationHour := durationMin / 60
-					durationMinRemainder := durationMin % 60
-					holdingDuration = fmt.Sprintf(" | 持仓时长%d小时%d分钟", durationHour, durationMinRemainder)
-				}
-			}
+			sb.WriteString(fmt.Sprintf("%d. %s %s | 保证金%.0f\n\n", i+1, pos.Symbol, strings.ToUpper(pos.Side), pos.MarginUsed))
 
-			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场价%.4f 当前价%.4f | 盈亏%+.2f%% | 杠杆%dx | 保证金%.0f | 强平价%.4f%s\n\n",
-				i+1, pos.Symbol, strings.ToUpper(pos.Side),
-				pos.EntryPrice, pos.MarkPrice, pos.UnrealizedPnLPct,
-				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration))
-
-			// 使用FormatMarketData输出完整市场数据
+			// 输出完整市场数据，持仓信息（入场价、盈亏、止损止盈、持仓时长）附在下方
+			view := positionView(pos)
 			if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
-				sb.WriteString(market.Format(marketData))
+				sb.WriteString(market.FormatWithPosition(marketData, view))
+				sb.WriteString(formatMarketDiff(ctx, marketData))
+				sb.WriteString(formatSymbolOverride(ctx, pos.Symbol))
 				sb.WriteString("\n")
+			} else {
+				sb.WriteString(market.FormatPosition(view))
 			}
 		}
 	} else {
@@ -430,20 +608,41 @@ func buildUserPrompt(ctx *Context) string {
 		// 使用FormatMarketData输出完整市场数据
 		sb.WriteString(fmt.Sprintf("### %d. %s%s\n\n", displayedCount, coin.Symbol, sourceTags))
 		sb.WriteString(market.Format(marketData))
+		sb.WriteString(formatMarketDiff(ctx, marketData))
+		sb.WriteString(formatSymbolOverride(ctx, coin.Symbol))
 		sb.WriteString("\n")
 	}
 	sb.WriteString("\n")
 
-	// 夏普比率（直接传值，不要复杂格式化）
+	// 配对价差
+	if len(ctx.Pairs) > 0 {
+		sb.WriteString(fmt.Sprintf("## 配对价差 (%d个)\n\n", len(ctx.Pairs)))
+		for _, pair := range ctx.Pairs {
+			sb.WriteString(fmt.Sprintf("### %s", pair.Spread.Symbol()))
+			switch pair.Position {
+			case "long":
+				sb.WriteString(fmt.Sprintf("（持仓: 做多价差，开仓z-score %+.2f）", pair.EntryZ))
+			case "short":
+				sb.WriteString(fmt.Sprintf("（持仓: 做空价差，开仓z-score %+.2f）", pair.EntryZ))
+			}
+			sb.WriteString("\n\n")
+			sb.WriteString(market.FormatSpread(pair.Spread))
+			sb.WriteString("\n")
+		}
+	}
+
+	// 夏普比率和交易记忆（直接传值，不要复杂格式化）
 	if ctx.Performance != nil {
-		// 直接从interface{}中提取SharpeRatio
+		// 直接从interface{}中提取SharpeRatio和最近交易
 		type PerformanceData struct {
-			SharpeRatio float64 `json:"sharpe_ratio"`
+			SharpeRatio  float64       `json:"sharpe_ratio"`
+			RecentTrades []TradeMemory `json:"recent_trades"`
 		}
 		var perfData PerformanceData
 		if jsonData, err := json.Marshal(ctx.Performance); err == nil {
 			if err := json.Unmarshal(jsonData, &perfData); err == nil {
 				sb.WriteString(fmt.Sprintf("## 📊 夏普比率: %.2f\n\n", perfData.SharpeRatio))
+				sb.WriteString(formatTradeMemory(perfData.RecentTrades, ctx.MemorySize))
 			}
 		}
 	}
@@ -454,8 +653,125 @@ func buildUserPrompt(ctx *Context) string {
 	return sb.String()
 }
 
+// buildPairPrompt 配对交易规则（配置了配对时追加到系统prompt）
+func buildPairPrompt() string {
+	var sb strings.Builder
+	sb.WriteString("\n# 📐 配对交易\n\n")
+	sb.WriteString("用户数据中的「配对价差」给出两个币种的价格比 base/quote 及其z-score，可做均值回归交易：\n")
+	sb.WriteString("- `open_pair_long`: 做多价差（多base、空quote），适合z-score显著为负（如≤-2）\n")
+	sb.WriteString("- `open_pair_short`: 做空价差（空base、多quote），适合z-score显著为正（如≥2）\n")
+	sb.WriteString("- `close_pair`: 同时平掉两条腿\n")
+	sb.WriteString("- symbol填配对代码（如 \"ETHUSDT/BTCUSDT\"），position_size_usd为每条腿的名义价值，两条腿同时下单\n")
+	sb.WriteString("- 开仓必填: leverage, position_size_usd, confidence, reasoning；不需要stop_loss/take_profit，z-score回归或继续偏离时系统自动平仓\n")
+	sb.WriteString("- 收益相关系数低于0.5的配对不适合做均值回归\n")
+	return sb.String()
+}
+
+// positionView 转换为市场数据格式化使用的持仓信息
+func positionView(pos PositionInfo) *market.PositionView {
+	view := &market.PositionView{
+		Side:             pos.Side,
+		EntryPrice:       pos.EntryPrice,
+		MarkPrice:        pos.MarkPrice,
+		Quantity:         pos.Quantity,
+		Leverage:         pos.Leverage,
+		UnrealizedPnL:    pos.UnrealizedPnL,
+		UnrealizedPnLPct: pos.UnrealizedPnLPct,
+		StopLoss:         pos.StopLoss,
+		TakeProfit:       pos.TakeProfit,
+		LiquidationPrice: pos.LiquidationPrice,
+	}
+	if pos.UpdateTime > 0 {
+		view.OpenedAt = time.UnixMilli(pos.UpdateTime)
+	}
+	return view
+}
+
+// formatMarketDiff 格式化相对上一周期的变化摘要（引导AI关注变化而非重复分析）
+func formatMarketDiff(ctx *Context, data *market.Data) string {
+	diff := data.Diff(ctx.PrevMarketData[data.Symbol])
+	if diff == nil {
+		return ""
+	}
+	return fmt.Sprintf("较上周期变化: %s\n\n", diff)
+}
+
+// formatSymbolOverride 格式化币种专属策略（杠杆/仓位上限/专属提示）
+func formatSymbolOverride(ctx *Context, symbol string) string {
+	override, ok := ctx.getOverride(symbol)
+	if !ok {
+		return ""
+	}
+
+	var parts []string
+	if override.Leverage > 0 {
+		parts = append(parts, fmt.Sprintf("杠杆上限%dx", override.Leverage))
+	}
+	if override.MaxPositionRatio > 0 {
+		parts = append(parts, fmt.Sprintf("仓位上限%.0f U", ctx.Account.TotalEquity*override.MaxPositionRatio))
+	}
+	if override.DisableOpen {
+		parts = append(parts, "禁止开新仓")
+	}
+	if override.Prompt != "" {
+		parts = append(parts, override.Prompt)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("**%s 专属策略**: %s\n\n", symbol, strings.Join(parts, " | "))
+}
+
+// formatTradeMemory 格式化最近N笔交易记忆（最新的在前）
+// 同币种同方向连续亏损会额外标注，提醒AI不要重复进入同一笔失败交易
+func formatTradeMemory(trades []TradeMemory, memorySize int) string {
+	if memorySize <= 0 || len(trades) == 0 {
+		return ""
+	}
+	if len(trades) > memorySize {
+		trades = trades[:memorySize]
+	}
+
+	// 统计同币种同方向的亏损次数
+	lossCount := make(map[string]int)
+	for _, t := range trades {
+		if t.PnL < 0 {
+			lossCount[t.Symbol+"_"+t.Side]++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## 🧠 交易记忆（最近%d笔）\n\n", len(trades)))
+	for i, t := range trades {
+		result := "盈利"
+		if t.PnL < 0 {
+			result = "亏损"
+		} else if t.PnL == 0 {
+			result = "持平"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s %s | 入场%.4f → 出场%.4f | %s %+.2f USDT (%+.2f%%) | 持仓%s | 平仓于%s\n",
+			i+1, t.Symbol, strings.ToUpper(t.Side), t.OpenPrice, t.ClosePrice,
+			result, t.PnL, t.PnLPct, t.Duration, t.CloseTime.Format("01-02 15:04")))
+	}
+	sb.WriteString("\n")
+
+	// 按交易顺序输出警告（保证prompt稳定）
+	warned := make(map[string]bool)
+	for _, t := range trades {
+		key := t.Symbol + "_" + t.Side
+		if lossCount[key] >= 2 && !warned[key] {
+			warned[key] = true
+			sb.WriteString(fmt.Sprintf("⚠️ %s %s 最近已亏损%d次，除非出现全新的强信号，否则不要再次同方向开仓\n",
+				t.Symbol, strings.ToUpper(t.Side), lossCount[key]))
+		}
+	}
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
 // parseFullDecisionResponse 解析AI的完整决策响应
-func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
+func parseFullDecisionResponse(aiResponse string, ctx *Context) (*FullDecision, error) {
 	// 1. 提取思维链
 	cotTrace := extractCoTTrace(aiResponse)
 
@@ -468,20 +784,42 @@ func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthL
 		}, fmt.Errorf("提取决策失败: %w\n\n=== AI思维链分析 ===\n%s", err, cotTrace)
 	}
 
-	// 3. 验证决策
-	if err := validateDecisions(decisions, accountEquity, btcEthLeverage, altcoinLeverage); err != nil {
+	// 3. 禁止开新仓期间剔除开仓决策（熔断、交易时段等），同一批返回的平仓必须照常执行
+	decisions, rejected := withoutBlockedOpens(decisions, ctx)
+
+	// 4. 验证决策
+	if err := validateDecisions(decisions, ctx); err != nil {
 		return &FullDecision{
 			CoTTrace:  cotTrace,
 			Decisions: decisions,
+			Rejected:  rejected,
 		}, fmt.Errorf("决策验证失败: %w\n\n=== AI思维链分析 ===\n%s", err, cotTrace)
 	}
 
 	return &FullDecision{
 		CoTTrace:  cotTrace,
 		Decisions: decisions,
+		Rejected:  rejected,
 	}, nil
 }
 
+// withoutBlockedOpens 禁止开新仓时去掉开仓决策并记录原因，保留平仓和观望
+func withoutBlockedOpens(decisions []Decision, ctx *Context) ([]Decision, []RejectedDecision) {
+	if ctx.EntryBlocked == "" {
+		return decisions, nil
+	}
+	kept := make([]Decision, 0, len(decisions))
+	var rejected []RejectedDecision
+	for _, d := range decisions {
+		if IsOpenAction(d.Action) {
+			rejected = append(rejected, RejectedDecision{Decision: d, Reason: "当前禁止开新仓: " + ctx.EntryBlocked})
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept, rejected
+}
+
 // extractCoTTrace 提取思维链分析
 func extractCoTTrace(response string) string {
 	// 查找JSON数组的开始位置
@@ -536,16 +874,109 @@ func fixMissingQuotes(jsonStr string) string {
 	return jsonStr
 }
 
-// validateDecisions 验证所有决策（需要账户信息和杠杆配置）
-func validateDecisions(decisions []Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
+// MaxPositionValue 单币种仓位价值上限：BTC/ETH最多10倍账户净值，山寨币最多1.5倍，币种专属配置优先
+func MaxPositionValue(symbol string, accountEquity float64, override SymbolOverride) float64 {
+	if override.MaxPositionRatio > 0 {
+		return accountEquity * override.MaxPositionRatio
+	}
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		return accountEquity * 10
+	}
+	return accountEquity * 1.5
+}
+
+// ValidateDecisions 验证外部来源的决策（与AI决策使用相同的规则：杠杆/仓位上限、止损止盈、风险回报比、下架禁开）
+func ValidateDecisions(decisions []Decision, ctx *Context) error {
+	return validateDecisions(decisions, ctx)
+}
+
+// validateDecisions 验证所有决策（需要账户信息、杠杆配置和币种覆盖配置）
+func validateDecisions(decisions []Decision, ctx *Context) error {
 	for i, decision := range decisions {
-		if err := validateDecision(&decision, accountEquity, btcEthLeverage, altcoinLeverage); err != nil {
+		if IsPairAction(decision.Action) {
+			if err := validatePairDecision(&decision, ctx); err != nil {
+				return fmt.Errorf("决策 #%d 验证失败: %w", i+1, err)
+			}
+			continue
+		}
+		override, _ := ctx.getOverride(decision.Symbol)
+
+		// 禁止开新仓的时段，以及已下架或即将下架的币种禁止开仓
+		if decision.Action == "open_long" || decision.Action == "open_short" {
+			if ctx.EntryBlocked != "" {
+				return fmt.Errorf("决策 #%d 验证失败: 当前禁止开新仓: %s", i+1, ctx.EntryBlocked)
+			}
+			if err := ctx.checkFundingBlackout(decision.Symbol); err != nil {
+				return fmt.Errorf("决策 #%d 验证失败: %w", i+1, err)
+			}
+			if data, ok := ctx.MarketDataMap[decision.Symbol]; ok && data.DelistingWithin(delistingBlockWindow) {
+				return fmt.Errorf("决策 #%d 验证失败: %s 已下架或即将下架，禁止开仓", i+1, decision.Symbol)
+			}
+		}
+
+		if err := validateDecision(&decision, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, override); err != nil {
 			return fmt.Errorf("决策 #%d 验证失败: %w", i+1, err)
 		}
 	}
 	return nil
 }
 
+// validatePairDecision 验证配对交易决策：配对已配置，杠杆和每条腿的仓位不超过两个币种中较严格的上限
+func validatePairDecision(d *Decision, ctx *Context) error {
+	var pair *PairInfo
+	for i := range ctx.Pairs {
+		if ctx.Pairs[i].Spread.Symbol() == d.Symbol {
+			pair = &ctx.Pairs[i]
+			break
+		}
+	}
+	if pair == nil {
+		return fmt.Errorf("%s 不是已配置的配对", d.Symbol)
+	}
+	if d.Action == ActionClosePair {
+		return nil
+	}
+	if ctx.EntryBlocked != "" {
+		return fmt.Errorf("当前禁止开新仓: %s", ctx.EntryBlocked)
+	}
+
+	maxLeverage := 0
+	maxPositionValue := math.MaxFloat64
+	for _, symbol := range []string{pair.Spread.Base, pair.Spread.Quote} {
+		override, _ := ctx.getOverride(symbol)
+		if override.DisableOpen {
+			return fmt.Errorf("%s 已配置为禁止开新仓", symbol)
+		}
+		if data, ok := ctx.MarketDataMap[symbol]; ok && data.DelistingWithin(delistingBlockWindow) {
+			return fmt.Errorf("%s 已下架或即将下架，禁止开仓", symbol)
+		}
+		if err := ctx.checkFundingBlackout(symbol); err != nil {
+			return err
+		}
+		leverage := ctx.AltcoinLeverage
+		if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+			leverage = ctx.BTCETHLeverage
+		}
+		if override.Leverage > 0 {
+			leverage = override.Leverage
+		}
+		if maxLeverage == 0 || leverage < maxLeverage {
+			maxLeverage = leverage
+		}
+		maxPositionValue = math.Min(maxPositionValue, MaxPositionValue(symbol, ctx.Account.TotalEquity, override))
+	}
+	if d.Leverage <= 0 || d.Leverage > maxLeverage {
+		return fmt.Errorf("杠杆必须在1-%d之间（%s）: %d", maxLeverage, d.Symbol, d.Leverage)
+	}
+	if d.PositionSizeUSD <= 0 {
+		return fmt.Errorf("仓位大小必须大于0: %.2f", d.PositionSizeUSD)
+	}
+	if d.PositionSizeUSD > maxPositionValue*1.01 {
+		return fmt.Errorf("%s 每条腿的仓位价值不能超过%.0f USDT，实际: %.0f", d.Symbol, maxPositionValue, d.PositionSizeUSD)
+	}
+	return nil
+}
+
 // findMatchingBracket 查找匹配的右括号
 func findMatchingBracket(s string, start int) int {
 	if start >= len(s) || s[start] != '[' {
@@ -569,7 +1000,7 @@ func findMatchingBracket(s string, start int) int {
 }
 
 // validateDecision 验证单个决策的有效性
-func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
+func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, override SymbolOverride) error {
 	// 验证action
 	validActions := map[string]bool{
 		"open_long":   true,
@@ -587,11 +1018,18 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 	// 开仓操作必须提供完整参数
 	if d.Action == "open_long" || d.Action == "open_short" {
 		// 根据币种使用配置的杠杆上限
-		maxLeverage := altcoinLeverage          // 山寨币使用配置的杠杆
-		maxPositionValue := accountEquity * 1.5 // 山寨币最多1.5倍账户净值
+		maxLeverage := altcoinLeverage // 山寨币使用配置的杠杆
 		if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-			maxLeverage = btcEthLeverage          // BTC和ETH使用配置的杠杆
-			maxPositionValue = accountEquity * 10 // BTC/ETH最多10倍账户净值
+			maxLeverage = btcEthLeverage // BTC和ETH使用配置的杠杆
+		}
+		maxPositionValue := MaxPositionValue(d.Symbol, accountEquity, override)
+
+		// 币种专属配置优先
+		if override.DisableOpen {
+			return fmt.Errorf("%s 已配置为禁止开新仓", d.Symbol)
+		}
+		if override.Leverage > 0 {
+			maxLeverage = override.Leverage
 		}
 
 		if d.Leverage <= 0 || d.Leverage > maxLeverage {
@@ -603,7 +1041,9 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		// 验证仓位价值上限（加1%容差以避免浮点数精度问题）
 		tolerance := maxPositionValue * 0.01 // 1%容差
 		if d.PositionSizeUSD > maxPositionValue+tolerance {
-			if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
+			if override.MaxPositionRatio > 0 {
+				return fmt.Errorf("%s 单币种仓位价值不能超过%.0f USDT（专属配置%.1f倍账户净值），实际: %.0f", d.Symbol, maxPositionValue, override.MaxPositionRatio, d.PositionSizeUSD)
+			} else if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
 				return fmt.Errorf("BTC/ETH单币种仓位价值不能超过%.0f USDT（10倍账户净值），实际: %.0f", maxPositionValue, d.PositionSizeUSD)
 			} else {
 				return fmt.Errorf("山寨币单币种仓位价值不能超过%.0f USDT（1.5倍账户净值），实际: %.0f", maxPositionValue, d.PositionSizeUSD)
@@ -612,6 +1052,11 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		if d.StopLoss <= 0 || d.TakeProfit <= 0 {
 			return fmt.Errorf("止损和止盈必须大于0")
 		}
+		for _, inv := range d.Invalidations {
+			if err := inv.Validate(); err != nil {
+				return err
+			}
+		}
 
 		// 验证止损止盈的合理性
 		if d.Action == "open_long" {
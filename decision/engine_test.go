@@ -0,0 +1,50 @@
+package decision
+
+import "testing"
+
+// TestBlockedOpensKeepCloses 禁止开新仓（熔断）期间同一批返回的开仓被剔除，平仓照常通过验证
+func TestBlockedOpensKeepCloses(t *testing.T) {
+	ctx := &Context{
+		Account:         AccountInfo{TotalEquity: 1000},
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 5,
+		EntryBlocked:    "全局熔断: 稳定币脱锚",
+	}
+	response := `市场剧烈波动，平掉ETH多仓，同时BTC出现做空信号
+[
+  {"symbol": "ETHUSDT", "action": "close_long", "reasoning": "止损"},
+  {"symbol": "BTCUSDT", "action": "open_short", "leverage": 5, "position_size_usd": 2000, "stop_loss": 70000, "take_profit": 60000, "confidence": 80, "reasoning": "破位"}
+]`
+
+	full, err := parseFullDecisionResponse(response, ctx)
+	if err != nil {
+		t.Fatalf("平仓不应因开仓被拒绝而失败: %v", err)
+	}
+	if len(full.Decisions) != 1 || full.Decisions[0].Action != "close_long" || full.Decisions[0].Symbol != "ETHUSDT" {
+		t.Fatalf("应只保留ETHUSDT平仓决策，实际: %+v", full.Decisions)
+	}
+	if len(full.Rejected) != 1 || full.Rejected[0].Decision.Action != "open_short" {
+		t.Fatalf("开仓决策应被剔除并记录原因，实际: %+v", full.Rejected)
+	}
+	if full.Rejected[0].Reason == "" {
+		t.Fatalf("被剔除的决策缺少原因")
+	}
+}
+
+// TestOpensAllowedWithoutBlock 未禁止开新仓时开仓照常验证
+func TestOpensAllowedWithoutBlock(t *testing.T) {
+	ctx := &Context{
+		Account:         AccountInfo{TotalEquity: 1000},
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 5,
+	}
+	response := `[{"symbol": "BTCUSDT", "action": "open_short", "leverage": 5, "position_size_usd": 2000, "stop_loss": 70000, "take_profit": 60000, "confidence": 80, "reasoning": "破位"}]`
+
+	full, err := parseFullDecisionResponse(response, ctx)
+	if err != nil {
+		t.Fatalf("开仓决策验证失败: %v", err)
+	}
+	if len(full.Decisions) != 1 || len(full.Rejected) != 0 {
+		t.Fatalf("开仓决策不应被剔除，实际: decisions=%+v rejected=%+v", full.Decisions, full.Rejected)
+	}
+}
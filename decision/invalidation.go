@@ -0,0 +1,67 @@
+package decision
+
+import (
+	"fmt"
+
+	"nofx/market"
+)
+
+// 失效条件的比较方向
+const (
+	InvalidateCloseBelow = "below" // 收盘价低于参考值
+	InvalidateCloseAbove = "above" // 收盘价高于参考值
+)
+
+// maxInvalidationMA 失效条件可引用的最长MA周期
+const maxInvalidationMA = 500
+
+// Invalidation 开仓信号的失效条件：指定周期的K线收盘价越过参考值（MA或固定价格）时，信号的前提不再成立，
+// 尚未成交的开仓单（限价单、网格未成交的层）自动取消。例如 {"interval": "4h", "close": "below", "ma": 21}
+// 表示"4小时K线收盘价低于MA21时取消"
+type Invalidation struct {
+	Interval string  `json:"interval"`        // K线周期（如"4h"）
+	Close    string  `json:"close"`           // below 或 above
+	MA       int     `json:"ma,omitempty"`    // 参考值为该周期的MA（与price二选一）
+	Price    float64 `json:"price,omitempty"` // 参考值为固定价格
+}
+
+// Validate 检查失效条件的配置
+func (inv Invalidation) Validate() error {
+	if _, err := market.IntervalDuration(inv.Interval); err != nil {
+		return fmt.Errorf("失效条件的K线周期无效: %w", err)
+	}
+	if inv.Close != InvalidateCloseBelow && inv.Close != InvalidateCloseAbove {
+		return fmt.Errorf("失效条件的close必须为below或above: %q", inv.Close)
+	}
+	if (inv.MA > 0) == (inv.Price > 0) {
+		return fmt.Errorf("失效条件必须且只能指定ma或price之一")
+	}
+	if inv.MA < 0 || inv.MA == 1 || inv.MA > maxInvalidationMA {
+		return fmt.Errorf("失效条件的MA周期必须在2-%d之间: %d", maxInvalidationMA, inv.MA)
+	}
+	if inv.Price < 0 {
+		return fmt.Errorf("失效条件的价格不能为负: %.4f", inv.Price)
+	}
+	return nil
+}
+
+// Triggered 收盘价close相对参考值reference是否满足失效条件
+func (inv Invalidation) Triggered(close, reference float64) bool {
+	if inv.Close == InvalidateCloseBelow {
+		return close < reference
+	}
+	return close > reference
+}
+
+// String 失效条件的描述（如"4h收盘价低于MA21"）
+func (inv Invalidation) String() string {
+	direction := "低于"
+	if inv.Close == InvalidateCloseAbove {
+		direction = "高于"
+	}
+	reference := fmt.Sprintf("%.4f", inv.Price)
+	if inv.MA > 0 {
+		reference = fmt.Sprintf("MA%d", inv.MA)
+	}
+	return fmt.Sprintf("%s收盘价%s%s", inv.Interval, direction, reference)
+}
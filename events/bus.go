@@ -0,0 +1,141 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	TypeDepegAlert           = "stablecoin.depeg"           // 稳定币脱锚告警
+	TypeBreakerTripped       = "risk.breaker_trip"          // 熔断触发
+	TypeBreakerReset         = "risk.breaker_reset"         // 熔断解除
+	TypeExchangeStatus       = "exchange.status"            // 交易所状态变化（维护/恢复）
+	TypeEndpointFailover     = "exchange.endpoint_failover" // API基础地址故障切换
+	TypeCircuitBreaker       = "exchange.circuit_breaker"   // 单个行情接口连续失败熔断/探测恢复
+	TypeReconcileDiscrepancy = "trader.reconcile"           // 对账发现本地与交易所持仓/挂单不一致
+	TypeTradeSignal          = "trader.signal"              // 开平仓信号已执行（附信号依据）
+	TypeOrderFill            = "trader.fill"                // 订单成交（成交均价、滑点）
+	TypeTradePlan            = "trader.plan"                // 交易计划状态变化（pending/active/scaling/closed/invalidated）
+	TypeMarketSnapshot       = "market.snapshot"            // 每个决策周期的行情快照
+	TypeOpenInterestShift    = "market.open_interest"       // 持仓量较上次观测变化超过阈值
+	TypeFundingShift         = "market.funding"             // 资金费率较上次观测变化超过阈值
+	TypeThresholdAlert       = "alert.threshold"            // 阈值告警触发/恢复（资金费率、回撤、WebSocket断开）
+	TypeStrategyDisabled     = "strategy.disabled"          // 用户策略连续失败（报错/panic/超时）被停用
+	TypeRegimeSwitch         = "strategy.regime"            // 市场状态切换，按状态启用/停用用户策略
+	TypeLeadership           = "cluster.leader"             // 主备选举：成为主节点/失去主节点身份
+)
+
+// unrecordedTypes 高频事件，不保留在最近事件中（避免挤掉告警）
+var unrecordedTypes = map[string]bool{
+	TypeMarketSnapshot: true,
+}
+
+// 事件级别
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Event 系统事件
+type Event struct {
+	Type     string                 `json:"type"`
+	Severity string                 `json:"severity"`
+	Message  string                 `json:"message"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Time     time.Time              `json:"time"`
+}
+
+// Bus 进程内事件总线（发布/订阅）
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]chan Event // 事件类型 -> 订阅者（""表示订阅全部）
+	recent      []Event                 // 最近的事件（供API查询）
+}
+
+// maxRecentEvents 保留的最近事件数量
+const maxRecentEvents = 100
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[string][]chan Event)}
+}
+
+// Default 全局事件总线
+var Default = NewBus()
+
+// Subscribe 订阅指定类型的事件（eventType为空表示订阅全部）
+// 订阅者处理过慢时事件会被丢弃，不会阻塞发布者
+func (b *Bus) Subscribe(eventType string) <-chan Event {
+	ch := make(chan Event, 32)
+
+	b.mu.Lock()
+	b.subscribers[eventType] = append(b.subscribers[eventType], ch)
+	b.mu.Unlock()
+
+	return ch
+}
+
+// Unsubscribe 取消订阅（通道不会被关闭，取消后不再收到新事件）
+func (b *Bus) Unsubscribe(ch <-chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for eventType, subscribers := range b.subscribers {
+		for i, subscriber := range subscribers {
+			if subscriber == ch {
+				b.subscribers[eventType] = append(subscribers[:i:i], subscribers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish 发布事件
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	if !unrecordedTypes[event.Type] {
+		b.recent = append(b.recent, event)
+		if len(b.recent) > maxRecentEvents {
+			b.recent = b.recent[len(b.recent)-maxRecentEvents:]
+		}
+	}
+	targets := append(append([]chan Event(nil), b.subscribers[event.Type]...), b.subscribers[""]...)
+	b.mu.Unlock()
+
+	for _, ch := range targets {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("⚠️  事件订阅者处理过慢，丢弃事件: %s", event.Type)
+		}
+	}
+}
+
+// Recent 获取最近的事件（按时间升序）
+func (b *Bus) Recent() []Event {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]Event(nil), b.recent...)
+}
+
+// Publish 向全局事件总线发布事件
+func Publish(event Event) {
+	Default.Publish(event)
+}
+
+// Subscribe 订阅全局事件总线
+func Subscribe(eventType string) <-chan Event {
+	return Default.Subscribe(eventType)
+}
+
+// Unsubscribe 取消全局事件总线的订阅
+func Unsubscribe(ch <-chan Event) {
+	Default.Unsubscribe(ch)
+}
@@ -0,0 +1,196 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/redis/go-redis/v9"
+)
+
+// mirrorPublishTimeout 单条事件外部发布的超时时间
+const mirrorPublishTimeout = 5 * time.Second
+
+// MirrorConfig 事件外部发布配置
+type MirrorConfig struct {
+	Type        string   // "redis"（pub/sub）或 "mqtt"
+	URL         string   // redis://[:password@]host:6379/0 或 tcp://host:1883
+	TopicPrefix string   // 主题前缀（默认"nofx"）
+	Events      []string // 转发的事件类型（空表示全部）
+	ClientID    string   // MQTT客户端ID（默认"nofx"）
+	Username    string   // MQTT用户名（可选）
+	Password    string   // MQTT密码（可选）
+	QoS         byte     // MQTT QoS（0或1）
+}
+
+// sink 外部消息系统
+type sink interface {
+	publish(topic string, payload []byte) error
+	close()
+}
+
+// Mirror 将事件总线上的事件（行情快照、信号、成交、告警等）以JSON转发到Redis pub/sub或MQTT
+// Redis频道为 <prefix>.<事件类型>（如 nofx.trader.signal），MQTT主题为 <prefix>/<事件类型按.分级>（如 nofx/trader/signal）
+type Mirror struct {
+	config MirrorConfig
+	sink   sink
+	ch     <-chan Event
+	filter map[string]bool
+	done   chan struct{}
+}
+
+// NewMirror 创建事件转发器并连接外部消息系统
+func NewMirror(config MirrorConfig) (*Mirror, error) {
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = "nofx"
+	}
+
+	var s sink
+	var err error
+	switch config.Type {
+	case "redis":
+		s, err = newRedisSink(config)
+	case "mqtt":
+		s, err = newMQTTSink(config)
+	default:
+		return nil, fmt.Errorf("不支持的事件发布类型: %s（支持 redis 或 mqtt）", config.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mirror{config: config, sink: s, done: make(chan struct{})}
+	if len(config.Events) > 0 {
+		m.filter = make(map[string]bool)
+		for _, eventType := range config.Events {
+			m.filter[eventType] = true
+		}
+	}
+	return m, nil
+}
+
+// Start 开始转发事件
+func (m *Mirror) Start() {
+	m.ch = Subscribe("")
+	log.Printf("📡 事件转发已启动: %s %s（主题前缀 %s）", m.config.Type, m.config.URL, m.config.TopicPrefix)
+	go m.loop()
+}
+
+// Stop 停止转发并断开连接
+func (m *Mirror) Stop() {
+	Unsubscribe(m.ch)
+	close(m.done)
+	m.sink.close()
+}
+
+// loop 转发循环
+func (m *Mirror) loop() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case event := <-m.ch:
+			if m.filter != nil && !m.filter[event.Type] {
+				continue
+			}
+			payload, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("⚠️  事件序列化失败 (%s): %v", event.Type, err)
+				continue
+			}
+			if err := m.sink.publish(m.topic(event.Type), payload); err != nil {
+				log.Printf("⚠️  事件转发失败 (%s): %v", event.Type, err)
+			}
+		}
+	}
+}
+
+// topic 事件类型对应的频道/主题
+func (m *Mirror) topic(eventType string) string {
+	if m.config.Type == "mqtt" {
+		return m.config.TopicPrefix + "/" + strings.ReplaceAll(eventType, ".", "/")
+	}
+	return m.config.TopicPrefix + "." + eventType
+}
+
+// redisSink Redis pub/sub
+type redisSink struct {
+	client *redis.Client
+}
+
+func newRedisSink(config MirrorConfig) (*redisSink, error) {
+	options, err := redis.ParseURL(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("解析Redis地址失败: %w", err)
+	}
+	client := redis.NewClient(options)
+
+	ctx, cancel := context.WithTimeout(context.Background(), mirrorPublishTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("连接Redis失败: %w", err)
+	}
+	return &redisSink{client: client}, nil
+}
+
+func (s *redisSink) publish(topic string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mirrorPublishTimeout)
+	defer cancel()
+	return s.client.Publish(ctx, topic, payload).Err()
+}
+
+func (s *redisSink) close() {
+	s.client.Close()
+}
+
+// mqttSink MQTT（断线自动重连）
+type mqttSink struct {
+	client mqtt.Client
+	qos    byte
+}
+
+func newMQTTSink(config MirrorConfig) (*mqttSink, error) {
+	if config.QoS > 1 {
+		return nil, fmt.Errorf("MQTT QoS只支持0或1")
+	}
+	clientID := config.ClientID
+	if clientID == "" {
+		clientID = "nofx"
+	}
+	options := mqtt.NewClientOptions().
+		AddBroker(config.URL).
+		SetClientID(clientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetAutoReconnect(true).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("⚠️  MQTT连接断开，正在重连: %v", err)
+		})
+	client := mqtt.NewClient(options)
+
+	token := client.Connect()
+	if !token.WaitTimeout(mirrorPublishTimeout) {
+		return nil, fmt.Errorf("连接MQTT超时: %s", config.URL)
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("连接MQTT失败: %w", err)
+	}
+	return &mqttSink{client: client, qos: config.QoS}, nil
+}
+
+func (s *mqttSink) publish(topic string, payload []byte) error {
+	token := s.client.Publish(topic, s.qos, false, payload)
+	if !token.WaitTimeout(mirrorPublishTimeout) {
+		return fmt.Errorf("发布超时")
+	}
+	return token.Error()
+}
+
+func (s *mqttSink) close() {
+	s.client.Disconnect(250)
+}
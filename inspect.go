@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"nofx/trader"
+	"nofx/utils"
+)
+
+// inspect 连接运行中的实例（通过其API）并打印每个trader的状态: nofx inspect [API地址] [trader_id]
+func inspect(args []string) {
+	baseURL := "http://localhost:8080"
+	if len(args) > 0 {
+		baseURL = strings.TrimRight(args[0], "/")
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	var traderIDs []string
+	if len(args) > 1 {
+		traderIDs = args[1:]
+	} else {
+		var traders []struct {
+			TraderID string `json:"trader_id"`
+		}
+		if err := getJSON(client, baseURL+"/api/traders", &traders); err != nil {
+			log.Fatalf("❌ 连接 %s 失败: %v", baseURL, err)
+		}
+		for _, t := range traders {
+			traderIDs = append(traderIDs, t.TraderID)
+		}
+	}
+	if len(traderIDs) == 0 {
+		log.Fatalf("❌ %s 没有运行中的trader", baseURL)
+	}
+
+	for _, traderID := range traderIDs {
+		var report trader.InspectReport
+		if err := getJSON(client, baseURL+"/api/inspect?trader_id="+url.QueryEscape(traderID), &report); err != nil {
+			log.Printf("❌ [%s] 获取状态失败: %v", traderID, err)
+			continue
+		}
+		printInspectReport(&report)
+	}
+}
+
+// apiGet 发送GET请求（环境变量NOFX_API_KEY不为空时携带API Key）
+func apiGet(client *http.Client, endpoint string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if key := os.Getenv("NOFX_API_KEY"); key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	return client.Do(req)
+}
+
+// getJSON GET请求并解析JSON响应（非200时返回API的错误信息）
+func getJSON(client *http.Client, endpoint string, v interface{}) error {
+	resp, err := apiGet(client, endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		utils.DecodeJSON(resp.Body, &apiErr)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, apiErr.Error)
+	}
+	return utils.DecodeJSON(resp.Body, v)
+}
+
+// printInspectReport 以表格形式打印trader状态
+func printInspectReport(r *trader.InspectReport) {
+	status := "运行中"
+	if !r.Running {
+		status = "已停止"
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("🤖 %s (%s) | %s | %s | 周期 #%d\n", r.Name, r.TraderID, r.Exchange, status, r.CallCount)
+	fmt.Println(strings.Repeat("=", 70))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	// 风控使用情况
+	risk := r.Risk
+	fmt.Println("\n🛡️  风控")
+	fmt.Fprintf(w, "  净值\t%.2f USDT\t保证金使用率\t%.1f%%\n", risk.Equity, risk.MarginUsedPct)
+	fmt.Fprintf(w, "  日亏损\t%.2f%% / %s\t回撤\t%.2f%% / %s\n",
+		risk.DailyLossPct, limitText(risk.MaxDailyLoss), risk.DrawdownPct, limitText(risk.MaxDrawdown))
+	fmt.Fprintf(w, "  开放风险\t%.2f USDT\t占净值\t%.2f%% / %s\n", risk.OpenRiskUSD, risk.OpenRiskPct, limitText(risk.MaxOpenRiskPct))
+	if !risk.StopUntil.IsZero() {
+		fmt.Fprintf(w, "  ⏸ 风控暂停至\t%s\t\t\n", risk.StopUntil.Local().Format("2006-01-02 15:04:05"))
+	}
+	if risk.BreakerTripped {
+		fmt.Fprintf(w, "  🚨 全局熔断\t%s\t\t\n", risk.BreakerReason)
+	}
+	w.Flush()
+
+	// 持仓
+	fmt.Printf("\n📊 持仓 (%d)\n", len(r.Positions))
+	if len(r.Positions) > 0 {
+		fmt.Fprintln(w, "  币种\t方向\t数量\t开仓价\t标记价\t杠杆\t未实现盈亏\t强平价")
+		for _, pos := range r.Positions {
+			fmt.Fprintf(w, "  %v\t%v\t%.4f\t%.4f\t%.4f\t%vx\t%+.2f (%+.2f%%)\t%.4f\n",
+				pos["symbol"], pos["side"], pos["quantity"], pos["entry_price"], pos["mark_price"],
+				pos["leverage"], pos["unrealized_pnl"], pos["unrealized_pnl_pct"], pos["liquidation_price"])
+		}
+		w.Flush()
+	}
+
+	// 挂单
+	fmt.Printf("\n📋 挂单 (%d)\n", len(r.OpenOrders))
+	if len(r.OpenOrders) > 0 {
+		fmt.Fprintln(w, "  币种\t类别\t类型\t方向\t持仓方向\t触发价\t数量")
+		for _, order := range r.OpenOrders {
+			fmt.Fprintf(w, "  %v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+				order["symbol"], order["kind"], order["type"], order["side"], order["positionSide"], order["stopPrice"], order["quantity"])
+		}
+		w.Flush()
+	}
+
+	// 最近行情快照
+	fmt.Printf("\n📈 最近行情快照 (%d)\n", len(r.Snapshots))
+	if len(r.Snapshots) > 0 {
+		fmt.Fprintln(w, "  币种\t价格\t1h\t4h\t资金费率\tRSI14\t强度")
+		for _, s := range r.Snapshots {
+			fmt.Fprintf(w, "  %s\t%.4f\t%+.2f%%\t%+.2f%%\t%+.4f%%\t%.1f\t%.0f\n",
+				s.Symbol, s.Price, s.PriceChange1h, s.PriceChange4h, s.FundingRate*100, s.RSI14, s.StrengthScore)
+		}
+		w.Flush()
+	}
+
+	// 最近决策
+	fmt.Println("\n🧠 最近决策")
+	if d := r.LastDecision; d != nil {
+		result := "✓ 成功"
+		if !d.Success {
+			result = "✗ " + d.ErrorMessage
+		}
+		source := d.Source
+		if source == "" {
+			source = "AI"
+		}
+		fmt.Printf("  周期 #%d | %s | 来源 %s | %s\n", d.CycleNumber, d.Timestamp.Local().Format("2006-01-02 15:04:05"), source, result)
+		for _, action := range d.Decisions {
+			mark := "✓"
+			if !action.Success {
+				mark = "✗"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t数量 %.4f\t价格 %.4f\t%s\n", mark, action.Symbol, action.Action, action.Quantity, action.Price, action.Explanation)
+		}
+		w.Flush()
+	} else {
+		fmt.Println("  （暂无）")
+	}
+	fmt.Println()
+}
+
+// limitText 风控上限的显示文本（0表示不限制）
+func limitText(limit float64) string {
+	if limit <= 0 {
+		return "不限"
+	}
+	return fmt.Sprintf("%.1f%%", limit)
+}
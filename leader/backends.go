@@ -0,0 +1,136 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+
+	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisRenewScript 仍持有锁时延长租约
+var redisRenewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// redisReleaseScript 仍持有锁时删除（避免删掉其他实例在租约过期后获得的锁）
+var redisReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// redisLocker Redis租约锁：SET key identity NX PX ttl，持有者定期续约
+type redisLocker struct {
+	client *redis.Client
+	config Config
+}
+
+func newRedisLocker(config Config) (*redisLocker, error) {
+	options, err := redis.ParseURL(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("解析Redis地址失败: %w", err)
+	}
+	client := redis.NewClient(options)
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.TTL)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("连接Redis失败: %w", err)
+	}
+	return &redisLocker{client: client, config: config}, nil
+}
+
+func (l *redisLocker) tryAcquire(ctx context.Context) (bool, error) {
+	return l.client.SetNX(ctx, l.config.Key, l.config.Identity, l.config.TTL).Result()
+}
+
+func (l *redisLocker) renew(ctx context.Context) (bool, error) {
+	result, err := redisRenewScript.Run(ctx, l.client, []string{l.config.Key}, l.config.Identity, l.config.TTL.Milliseconds()).Int()
+	if err != nil {
+		return true, err // 无法确认：租约未过期前仍可能持有
+	}
+	return result == 1, nil
+}
+
+func (l *redisLocker) release(ctx context.Context) error {
+	return redisReleaseScript.Run(ctx, l.client, []string{l.config.Key}, l.config.Identity).Err()
+}
+
+func (l *redisLocker) close() {
+	l.client.Close()
+}
+
+// postgresLocker PostgreSQL会话级advisory lock：锁随持有连接存在，连接断开时由数据库释放
+type postgresLocker struct {
+	db     *sql.DB
+	conn   *sql.Conn // 持有（或尝试获取）锁的专用连接
+	lockID int64
+}
+
+func newPostgresLocker(config Config) (*postgresLocker, error) {
+	db, err := sql.Open("postgres", config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("解析PostgreSQL地址失败: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.TTL)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接PostgreSQL失败: %w", err)
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(config.Key))
+	return &postgresLocker{db: db, lockID: int64(hash.Sum64())}, nil
+}
+
+func (l *postgresLocker) tryAcquire(ctx context.Context) (bool, error) {
+	if l.conn == nil {
+		conn, err := l.db.Conn(ctx)
+		if err != nil {
+			return false, err
+		}
+		l.conn = conn
+	}
+	var acquired bool
+	if err := l.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.lockID).Scan(&acquired); err != nil {
+		l.resetConn()
+		return false, err
+	}
+	return acquired, nil
+}
+
+func (l *postgresLocker) renew(ctx context.Context) (bool, error) {
+	// 锁属于连接：连接出错时锁可能已被数据库释放，不能依赖宽限期
+	if err := l.conn.PingContext(ctx); err != nil {
+		l.resetConn()
+		return false, err
+	}
+	return true, nil
+}
+
+func (l *postgresLocker) release(ctx context.Context) error {
+	if l.conn == nil {
+		return nil
+	}
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.lockID)
+	return err
+}
+
+func (l *postgresLocker) resetConn() {
+	if l.conn != nil {
+		l.conn.Close()
+		l.conn = nil
+	}
+}
+
+func (l *postgresLocker) close() {
+	l.resetConn()
+	l.db.Close()
+}
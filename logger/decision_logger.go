@@ -1,29 +1,77 @@
 package logger
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
 	"time"
+
+	"nofx/audit"
+	"nofx/run"
+	"nofx/secret"
 )
 
 // DecisionRecord 决策记录
 type DecisionRecord struct {
-	Timestamp      time.Time          `json:"timestamp"`       // 决策时间
-	CycleNumber    int                `json:"cycle_number"`    // 周期编号
-	InputPrompt    string             `json:"input_prompt"`    // 发送给AI的输入prompt
-	CoTTrace       string             `json:"cot_trace"`       // AI思维链（输出）
-	DecisionJSON   string             `json:"decision_json"`   // 决策JSON
-	AccountState   AccountSnapshot    `json:"account_state"`   // 账户状态快照
-	Positions      []PositionSnapshot `json:"positions"`       // 持仓快照
-	CandidateCoins []string           `json:"candidate_coins"` // 候选币种列表
-	Decisions      []DecisionAction   `json:"decisions"`       // 执行的决策
-	ExecutionLog   []string           `json:"execution_log"`   // 执行日志
-	Success        bool               `json:"success"`         // 是否成功
-	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
+	RunID          string             `json:"run_id,omitempty"`  // 运行ID（对应运行清单）
+	Source         string             `json:"source,omitempty"`  // 决策来源（空表示AI，外部信号为来源名称如"tradingview"）
+	Timestamp      time.Time          `json:"timestamp"`         // 决策时间
+	CycleNumber    int                `json:"cycle_number"`      // 周期编号
+	InputPrompt    string             `json:"input_prompt"`      // 发送给AI的输入prompt
+	CoTTrace       string             `json:"cot_trace"`         // AI思维链（输出）
+	DecisionJSON   string             `json:"decision_json"`     // 决策JSON
+	AccountState   AccountSnapshot    `json:"account_state"`     // 账户状态快照
+	Positions      []PositionSnapshot `json:"positions"`         // 持仓快照
+	CandidateCoins []string           `json:"candidate_coins"`   // 候选币种列表
+	Decisions      []DecisionAction   `json:"decisions"`         // 执行的决策
+	ExecutionLog   []string           `json:"execution_log"`     // 执行日志
+	Success        bool               `json:"success"`           // 是否成功
+	ErrorMessage   string             `json:"error_message"`     // 错误信息（如果有）
+	Latency        *CycleLatency      `json:"latency,omitempty"` // 各阶段耗时
+
+	Snapshot     *DecisionSnapshot `json:"-"`                       // 决策输入快照（启用快照时单独保存）
+	SnapshotFile string            `json:"snapshot_file,omitempty"` // 快照文件名（snapshots目录下）
+}
+
+// CycleLatency 决策周期各阶段耗时（毫秒）
+type CycleLatency struct {
+	FetchMs     int64 `json:"fetch_ms"`            // 获取账户、持仓和行情数据
+	ComputeMs   int64 `json:"compute_ms"`          // 构建prompt、解析AI响应
+	LLMMs       int64 `json:"llm_ms"`              // AI调用
+	RiskCheckMs int64 `json:"risk_check_ms"`       // 风控检查
+	OrderMs     int64 `json:"order_ms"`            // 下单执行
+	TotalMs     int64 `json:"total_ms"`            // 周期总耗时
+	BudgetMs    int64 `json:"budget_ms,omitempty"` // 延迟预算（0表示不限制）
+	Aborted     bool  `json:"aborted,omitempty"`   // 是否因超出预算放弃开仓
+
+	startedAt time.Time
+}
+
+// NewCycleLatency 开始统计一个周期的耗时
+func NewCycleLatency(budget time.Duration) *CycleLatency {
+	return &CycleLatency{
+		BudgetMs:  budget.Milliseconds(),
+		startedAt: time.Now(),
+	}
+}
+
+// Elapsed 周期开始至今的耗时
+func (c *CycleLatency) Elapsed() time.Duration {
+	return time.Since(c.startedAt)
+}
+
+// OverBudget 是否已超出延迟预算
+func (c *CycleLatency) OverBudget() bool {
+	return c.BudgetMs > 0 && c.Elapsed().Milliseconds() > c.BudgetMs
+}
+
+// Finish 记录周期总耗时
+func (c *CycleLatency) Finish() {
+	c.TotalMs = c.Elapsed().Milliseconds()
 }
 
 // AccountSnapshot 账户状态快照
@@ -49,21 +97,50 @@ type PositionSnapshot struct {
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short
-	Symbol    string    `json:"symbol"`    // 币种
-	Quantity  float64   `json:"quantity"`  // 数量
-	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
-	OrderID   int64     `json:"order_id"`  // 订单ID
+	Action   string  `json:"action"`   // open_long, open_short, close_long, close_short, add_long, add_short（金字塔加仓）
+	Symbol   string  `json:"symbol"`   // 币种
+	Quantity float64 `json:"quantity"` // 数量
+	Leverage int     `json:"leverage"` // 杠杆（开仓时）
+	Price    float64 `json:"price"`    // 执行价格
+	OrderID  int64   `json:"order_id"` // 订单ID
+
+	// 策略归因
+	StrategyID string   `json:"strategy_id,omitempty"` // 策略ID（旧记录为空，统计时归入DefaultStrategyID）
+	Tags       []string `json:"tags,omitempty"`        // 策略标签
+
+	// 执行质量（决策时价格与实际成交均价的偏差）
+	DecisionPrice float64 `json:"decision_price,omitempty"` // 决策时价格（AI看到的价格）
+	FillPrice     float64 `json:"fill_price,omitempty"`     // 实际成交均价（0表示未知）
+	OrderType     string  `json:"order_type,omitempty"`     // market 或 limit
+
+	// 信号依据（决策时的关键指标读数）
+	Explanation string             `json:"explanation,omitempty"` // 如"EMA20>EMA50, RSI 61.0, funding -0.0100%"
+	Readings    map[string]float64 `json:"readings,omitempty"`    // 指标名 -> 数值
+
 	Timestamp time.Time `json:"timestamp"` // 执行时间
 	Success   bool      `json:"success"`   // 是否成功
 	Error     string    `json:"error"`     // 错误信息
 }
 
+// DefaultStrategyID 未指定策略ID的决策归入的策略
+const DefaultStrategyID = "default"
+
+// Strategy 决策动作所属策略（未指定时为DefaultStrategyID）
+func (a *DecisionAction) Strategy() string {
+	if a.StrategyID == "" {
+		return DefaultStrategyID
+	}
+	return a.StrategyID
+}
+
 // DecisionLogger 决策日志记录器
 type DecisionLogger struct {
 	logDir      string
 	cycleNumber int
+
+	snapshotDir       string        // 决策快照目录（为空表示未启用）
+	snapshotRetention time.Duration // 快照保留时长（0表示不清理）
+	lastSnapshotClean time.Time
 }
 
 // NewDecisionLogger 创建决策日志记录器
@@ -87,7 +164,11 @@ func NewDecisionLogger(logDir string) *DecisionLogger {
 func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 	l.cycleNumber++
 	record.CycleNumber = l.cycleNumber
+	record.RunID = run.ID()
 	record.Timestamp = time.Now()
+	if record.Latency != nil {
+		record.Latency.Finish()
+	}
 
 	// 生成文件名：decision_YYYYMMDD_HHMMSS_cycleN.json
 	filename := fmt.Sprintf("decision_%s_cycle%d.json",
@@ -96,6 +177,15 @@ func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 
 	filepath := filepath.Join(l.logDir, filename)
 
+	// 决策快照单独保存（体积较大），记录中只保存文件名
+	if record.Snapshot != nil && l.snapshotDir != "" {
+		if name, err := l.saveSnapshot(record); err != nil {
+			fmt.Printf("⚠ 保存决策快照失败: %v\n", err)
+		} else {
+			record.SnapshotFile = name
+		}
+	}
+
 	// 序列化为JSON（带缩进，方便阅读）
 	data, err := json.MarshalIndent(record, "", "  ")
 	if err != nil {
@@ -103,17 +193,49 @@ func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 	}
 
 	// 写入文件
-	if err := ioutil.WriteFile(filepath, data, 0644); err != nil {
+	if err := secret.WriteFile(filepath, data, 0644); err != nil {
 		return fmt.Errorf("写入决策记录失败: %w", err)
 	}
+	l.audit(record, filename, data)
 
 	fmt.Printf("📝 决策记录已保存: %s\n", filename)
 	return nil
 }
 
+// audit 向审计日志追加决策记录（含决策日志文件的哈希，文件被修改后可发现）和每个下单结果
+func (l *DecisionLogger) audit(record *DecisionRecord, filename string, data []byte) {
+	traderID := filepath.Base(l.logDir)
+	sum := sha256.Sum256(data)
+	audit.Record(audit.KindDecision, traderID, record.Source, map[string]interface{}{
+		"cycle_number":  record.CycleNumber,
+		"success":       record.Success,
+		"error_message": record.ErrorMessage,
+		"record_file":   filename,
+		"record_sha256": hex.EncodeToString(sum[:]),
+		"snapshot_file": record.SnapshotFile,
+		"actions":       len(record.Decisions),
+	})
+	for _, action := range record.Decisions {
+		audit.Record(audit.KindOrder, traderID, record.Source, map[string]interface{}{
+			"cycle_number": record.CycleNumber,
+			"action":       action.Action,
+			"symbol":       action.Symbol,
+			"quantity":     action.Quantity,
+			"leverage":     action.Leverage,
+			"price":        action.Price,
+			"order_id":     action.OrderID,
+			"order_type":   action.OrderType,
+			"strategy_id":  action.StrategyID,
+			"success":      action.Success,
+			"error":        action.Error,
+			"time":         action.Timestamp,
+		})
+	}
+}
+
 // GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
 func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
-	files, err := ioutil.ReadDir(l.logDir)
+	files, err := os.ReadDir(l.logDir)
 	if err != nil {
 		return nil, fmt.Errorf("读取日志目录失败: %w", err)
 	}
@@ -128,7 +250,7 @@ func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 		}
 
 		filepath := filepath.Join(l.logDir, file.Name())
-		data, err := ioutil.ReadFile(filepath)
+		data, err := secret.ReadFile(filepath)
 		if err != nil {
 			continue
 		}
@@ -162,7 +284,7 @@ func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, err
 
 	var records []*DecisionRecord
 	for _, filepath := range files {
-		data, err := ioutil.ReadFile(filepath)
+		data, err := secret.ReadFile(filepath)
 		if err != nil {
 			continue
 		}
@@ -182,7 +304,7 @@ func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, err
 func (l *DecisionLogger) CleanOldRecords(days int) error {
 	cutoffTime := time.Now().AddDate(0, 0, -days)
 
-	files, err := ioutil.ReadDir(l.logDir)
+	files, err := os.ReadDir(l.logDir)
 	if err != nil {
 		return fmt.Errorf("读取日志目录失败: %w", err)
 	}
@@ -193,7 +315,11 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 			continue
 		}
 
-		if file.ModTime().Before(cutoffTime) {
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Before(cutoffTime) {
 			filepath := filepath.Join(l.logDir, file.Name())
 			if err := os.Remove(filepath); err != nil {
 				fmt.Printf("⚠ 删除旧记录失败 %s: %v\n", file.Name(), err)
@@ -212,7 +338,7 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 
 // GetStatistics 获取统计信息
 func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
-	files, err := ioutil.ReadDir(l.logDir)
+	files, err := os.ReadDir(l.logDir)
 	if err != nil {
 		return nil, fmt.Errorf("读取日志目录失败: %w", err)
 	}
@@ -225,7 +351,7 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 		}
 
 		filepath := filepath.Join(l.logDir, file.Name())
-		data, err := ioutil.ReadFile(filepath)
+		data, err := secret.ReadFile(filepath)
 		if err != nil {
 			continue
 		}
@@ -283,6 +409,7 @@ type TradeOutcome struct {
 	OpenTime      time.Time `json:"open_time"`      // 开仓时间
 	CloseTime     time.Time `json:"close_time"`     // 平仓时间
 	WasStopLoss   bool      `json:"was_stop_loss"`  // 是否止损
+	StrategyID    string    `json:"strategy_id"`    // 开仓策略ID
 }
 
 // PerformanceAnalysis 交易表现分析
@@ -299,6 +426,51 @@ type PerformanceAnalysis struct {
 	SymbolStats   map[string]*SymbolPerformance `json:"symbol_stats"`   // 各币种表现
 	BestSymbol    string                        `json:"best_symbol"`    // 表现最好的币种
 	WorstSymbol   string                        `json:"worst_symbol"`   // 表现最差的币种
+
+	StrategyStats map[string]*StrategyPerformance `json:"strategy_stats"` // 各策略表现（按开仓策略归因）
+
+	// 执行质量（滑点统计）
+	Execution            *ExecutionStats            `json:"execution"`               // 整体
+	ExecutionBySymbol    map[string]*ExecutionStats `json:"execution_by_symbol"`     // 按币种
+	ExecutionByOrderType map[string]*ExecutionStats `json:"execution_by_order_type"` // 按订单类型
+}
+
+// ExecutionStats 执行质量统计
+// 滑点以基点(bps)表示，正数表示成交价比决策时价格更差（买得更贵/卖得更便宜）
+type ExecutionStats struct {
+	Fills          int     `json:"fills"`            // 有成交均价的订单数
+	AvgSlippageBps float64 `json:"avg_slippage_bps"` // 平均滑点
+	MaxSlippageBps float64 `json:"max_slippage_bps"` // 最大不利滑点
+	SlippageCost   float64 `json:"slippage_cost"`    // 滑点成本合计（USDT，负数表示价格改善）
+}
+
+// add 累加一笔成交
+func (s *ExecutionStats) add(slippageBps, cost float64) {
+	if s.Fills == 0 || slippageBps > s.MaxSlippageBps {
+		s.MaxSlippageBps = slippageBps
+	}
+	s.Fills++
+	s.AvgSlippageBps += slippageBps // 先累加，finish时求平均
+	s.SlippageCost += cost
+}
+
+// finish 计算平均值
+func (s *ExecutionStats) finish() {
+	if s.Fills > 0 {
+		s.AvgSlippageBps /= float64(s.Fills)
+	}
+}
+
+// Slippage 计算滑点（bps）和滑点成本（USDT），缺少价格时ok为false
+func (a *DecisionAction) Slippage() (bps, cost float64, ok bool) {
+	if a.DecisionPrice <= 0 || a.FillPrice <= 0 {
+		return 0, 0, false
+	}
+	diff := a.FillPrice - a.DecisionPrice
+	if a.Action == "open_short" || a.Action == "add_short" || a.Action == "close_long" {
+		diff = -diff // 卖出：成交价低于决策价为不利
+	}
+	return diff / a.DecisionPrice * 10000, diff * a.Quantity, true
 }
 
 // SymbolPerformance 币种表现统计
@@ -312,6 +484,18 @@ type SymbolPerformance struct {
 	AvgPnL        float64 `json:"avg_pn_l"`       // 平均盈亏
 }
 
+// StrategyPerformance 策略表现统计
+type StrategyPerformance struct {
+	StrategyID    string  `json:"strategy_id"`    // 策略ID
+	TotalTrades   int     `json:"total_trades"`   // 交易次数
+	WinningTrades int     `json:"winning_trades"` // 盈利次数
+	LosingTrades  int     `json:"losing_trades"`  // 亏损次数
+	WinRate       float64 `json:"win_rate"`       // 胜率
+	TotalPnL      float64 `json:"total_pn_l"`     // 总盈亏
+	AvgPnL        float64 `json:"avg_pn_l"`       // 平均盈亏
+	Fills         int     `json:"fills"`          // 成功执行的订单数（开仓+平仓）
+}
+
 // AnalyzePerformance 分析最近N个周期的交易表现
 func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAnalysis, error) {
 	records, err := l.GetLatestRecords(lookbackCycles)
@@ -319,16 +503,16 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		return nil, fmt.Errorf("读取历史记录失败: %w", err)
 	}
 
-	if len(records) == 0 {
-		return &PerformanceAnalysis{
-			RecentTrades: []TradeOutcome{},
-			SymbolStats:  make(map[string]*SymbolPerformance),
-		}, nil
-	}
-
 	analysis := &PerformanceAnalysis{
-		RecentTrades: []TradeOutcome{},
-		SymbolStats:  make(map[string]*SymbolPerformance),
+		RecentTrades:         []TradeOutcome{},
+		SymbolStats:          make(map[string]*SymbolPerformance),
+		StrategyStats:        make(map[string]*StrategyPerformance),
+		Execution:            &ExecutionStats{},
+		ExecutionBySymbol:    make(map[string]*ExecutionStats),
+		ExecutionByOrderType: make(map[string]*ExecutionStats),
+	}
+	if len(records) == 0 {
+		return analysis, nil
 	}
 
 	// 追踪持仓状态：symbol_side -> {side, openPrice, openTime, quantity, leverage}
@@ -347,9 +531,9 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 
 				symbol := action.Symbol
 				side := ""
-				if action.Action == "open_long" || action.Action == "close_long" {
+				if action.Action == "open_long" || action.Action == "add_long" || action.Action == "close_long" {
 					side = "long"
-				} else if action.Action == "open_short" || action.Action == "close_short" {
+				} else if action.Action == "open_short" || action.Action == "add_short" || action.Action == "close_short" {
 					side = "short"
 				}
 				posKey := symbol + "_" + side
@@ -363,6 +547,11 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						"openTime":  action.Timestamp,
 						"quantity":  action.Quantity,
 						"leverage":  action.Leverage,
+						"strategy":  action.Strategy(),
+					}
+				case "add_long", "add_short":
+					if openPos, exists := openPositions[posKey]; exists {
+						mergeScaleIn(openPos, action)
 					}
 				case "close_long", "close_short":
 					// 移除已平仓记录
@@ -381,13 +570,35 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 
 			symbol := action.Symbol
 			side := ""
-			if action.Action == "open_long" || action.Action == "close_long" {
+			if action.Action == "open_long" || action.Action == "add_long" || action.Action == "close_long" {
 				side = "long"
-			} else if action.Action == "open_short" || action.Action == "close_short" {
+			} else if action.Action == "open_short" || action.Action == "add_short" || action.Action == "close_short" {
 				side = "short"
 			}
 			posKey := symbol + "_" + side // 使用symbol_side作为key，区分多空持仓
 
+			// 执行质量统计
+			if bps, cost, ok := action.Slippage(); ok {
+				orderType := action.OrderType
+				if orderType == "" {
+					orderType = "market"
+				}
+				if analysis.ExecutionBySymbol[symbol] == nil {
+					analysis.ExecutionBySymbol[symbol] = &ExecutionStats{}
+				}
+				if analysis.ExecutionByOrderType[orderType] == nil {
+					analysis.ExecutionByOrderType[orderType] = &ExecutionStats{}
+				}
+				analysis.Execution.add(bps, cost)
+				analysis.ExecutionBySymbol[symbol].add(bps, cost)
+				analysis.ExecutionByOrderType[orderType].add(bps, cost)
+			}
+
+			// 策略成交统计
+			if action.Action != "hold" && action.Action != "wait" {
+				strategyStats(analysis, action.Strategy()).Fills++
+			}
+
 			switch action.Action {
 			case "open_long", "open_short":
 				// 更新开仓记录（可能已经在预填充时记录过了）
@@ -397,6 +608,13 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					"openTime":  action.Timestamp,
 					"quantity":  action.Quantity,
 					"leverage":  action.Leverage,
+					"strategy":  action.Strategy(),
+				}
+
+			case "add_long", "add_short":
+				// 金字塔加仓：并入同一笔交易
+				if openPos, exists := openPositions[posKey]; exists {
+					mergeScaleIn(openPos, action)
 				}
 
 			case "close_long", "close_short":
@@ -407,6 +625,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					side := openPos["side"].(string)
 					quantity := openPos["quantity"].(float64)
 					leverage := openPos["leverage"].(int)
+					strategy := openPos["strategy"].(string)
 
 					// 计算实际盈亏（USDT）
 					// 合约交易 PnL 计算：quantity × 价格差
@@ -441,6 +660,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						Duration:      action.Timestamp.Sub(openTime).String(),
 						OpenTime:      openTime,
 						CloseTime:     action.Timestamp,
+						StrategyID:    strategy,
 					}
 
 					analysis.RecentTrades = append(analysis.RecentTrades, outcome)
@@ -471,6 +691,16 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						stats.LosingTrades++
 					}
 
+					// 更新策略统计（归因到开仓策略）
+					sstats := strategyStats(analysis, strategy)
+					sstats.TotalTrades++
+					sstats.TotalPnL += pnl
+					if pnl > 0 {
+						sstats.WinningTrades++
+					} else if pnl < 0 {
+						sstats.LosingTrades++
+					}
+
 					// 移除已平仓记录
 					delete(openPositions, posKey)
 				}
@@ -522,6 +752,14 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}
 	}
 
+	// 计算各策略胜率和平均盈亏
+	for _, stats := range analysis.StrategyStats {
+		if stats.TotalTrades > 0 {
+			stats.WinRate = (float64(stats.WinningTrades) / float64(stats.TotalTrades)) * 100
+			stats.AvgPnL = stats.TotalPnL / float64(stats.TotalTrades)
+		}
+	}
+
 	// 只保留最近的交易（倒序：最新的在前）
 	if len(analysis.RecentTrades) > 10 {
 		// 反转数组，让最新的在前
@@ -536,12 +774,54 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}
 	}
 
+	// 计算平均滑点
+	analysis.Execution.finish()
+	for _, stats := range analysis.ExecutionBySymbol {
+		stats.finish()
+	}
+	for _, stats := range analysis.ExecutionByOrderType {
+		stats.finish()
+	}
+
 	// 计算夏普比率（需要至少2个数据点）
 	analysis.SharpeRatio = l.calculateSharpeRatio(records)
 
 	return analysis, nil
 }
 
+// mergeScaleIn 把加仓（add_long/add_short）并入未平仓的开仓记录：数量累加，开仓价按数量加权
+// 开仓记录中保存已合并的加仓，预填充和分析窗口重复遍历同一条记录时不会重复计入
+func mergeScaleIn(openPos map[string]interface{}, action DecisionAction) {
+	merged, _ := openPos["scaleIns"].(map[string]bool)
+	if merged == nil {
+		merged = make(map[string]bool)
+		openPos["scaleIns"] = merged
+	}
+	key := fmt.Sprintf("%d_%d", action.OrderID, action.Timestamp.UnixNano())
+	if merged[key] {
+		return
+	}
+	merged[key] = true
+
+	quantity := openPos["quantity"].(float64)
+	total := quantity + action.Quantity
+	if total <= 0 {
+		return
+	}
+	openPos["openPrice"] = (openPos["openPrice"].(float64)*quantity + action.Price*action.Quantity) / total
+	openPos["quantity"] = total
+}
+
+// strategyStats 获取（不存在时创建）策略统计
+func strategyStats(analysis *PerformanceAnalysis, strategyID string) *StrategyPerformance {
+	stats, ok := analysis.StrategyStats[strategyID]
+	if !ok {
+		stats = &StrategyPerformance{StrategyID: strategyID}
+		analysis.StrategyStats[strategyID] = stats
+	}
+	return stats
+}
+
 // calculateSharpeRatio 计算夏普比率
 // 基于账户净值的变化计算风险调整后收益
 func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64 {
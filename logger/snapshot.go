@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nofx/market"
+	"nofx/secret"
+)
+
+// snapshotCleanInterval 清理过期快照的最短间隔
+const snapshotCleanInterval = time.Hour
+
+// DecisionSnapshot 决策时的完整输入（事后复盘还原AI决策时“看到”的数据）
+type DecisionSnapshot struct {
+	RunID       string                   `json:"run_id,omitempty"`
+	CycleNumber int                      `json:"cycle_number"`
+	Timestamp   time.Time                `json:"timestamp"`
+	CycleStart  time.Time                `json:"cycle_start"`         // 周期开始时间（Payloads从此时开始记录）
+	Context     interface{}              `json:"context"`             // 交易上下文（账户、持仓、候选币种）
+	MarketData  map[string]*market.Data  `json:"market_data"`         // 各币种的完整行情数据（指标、持仓量、资金费率等）
+	Balance     map[string]interface{}   `json:"balance,omitempty"`   // 交易所返回的账户余额
+	Positions   []map[string]interface{} `json:"positions,omitempty"` // 交易所返回的持仓
+	Payloads    []market.CapturedPayload `json:"payloads,omitempty"`  // 周期内收到的币安REST原始响应（含同一进程其他trader的请求）
+}
+
+// EnableSnapshots 启用决策快照：带Snapshot的决策记录同时写入snapshots子目录（gzip压缩），
+// retention>0时定期删除超过该时长的快照
+func (l *DecisionLogger) EnableSnapshots(retention time.Duration) {
+	l.snapshotDir = filepath.Join(l.logDir, "snapshots")
+	l.snapshotRetention = retention
+	if err := os.MkdirAll(l.snapshotDir, 0755); err != nil {
+		fmt.Printf("⚠ 创建快照目录失败: %v\n", err)
+	}
+}
+
+// saveSnapshot 写入决策快照，返回文件名（相对snapshots目录）
+func (l *DecisionLogger) saveSnapshot(record *DecisionRecord) (string, error) {
+	snapshot := record.Snapshot
+	snapshot.RunID = record.RunID
+	snapshot.CycleNumber = record.CycleNumber
+	snapshot.Timestamp = record.Timestamp
+
+	filename := fmt.Sprintf("snapshot_%s_cycle%d.json.gz",
+		record.Timestamp.Format("20060102_150405"), record.CycleNumber)
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(writer).Encode(snapshot); err != nil {
+		return "", fmt.Errorf("序列化决策快照失败: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("压缩决策快照失败: %w", err)
+	}
+	// 先压缩再加密（启用加密时）
+	if err := secret.WriteFile(filepath.Join(l.snapshotDir, filename), buf.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("写入决策快照失败: %w", err)
+	}
+
+	if l.snapshotRetention > 0 && time.Since(l.lastSnapshotClean) >= snapshotCleanInterval {
+		l.lastSnapshotClean = time.Now()
+		l.cleanSnapshots()
+	}
+	return filename, nil
+}
+
+// cleanSnapshots 删除超过保留期的快照
+func (l *DecisionLogger) cleanSnapshots() {
+	files, err := os.ReadDir(l.snapshotDir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-l.snapshotRetention)
+	removed := 0
+	for _, entry := range files {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(l.snapshotDir, entry.Name())); err == nil {
+			removed++
+		}
+	}
+	if removed > 0 {
+		fmt.Printf("🗑️ 已清理 %d 个过期决策快照\n", removed)
+	}
+}
+
+// GetSnapshot 读取决策记录中snapshot_file指向的快照
+func (l *DecisionLogger) GetSnapshot(filename string) (*DecisionSnapshot, error) {
+	if filename != filepath.Base(filename) || !strings.HasPrefix(filename, "snapshot_") {
+		return nil, fmt.Errorf("无效的快照文件名: %s", filename)
+	}
+	data, err := secret.ReadFile(filepath.Join(l.logDir, "snapshots", filename))
+	if err != nil {
+		return nil, fmt.Errorf("读取决策快照失败: %w", err)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解压决策快照失败: %w", err)
+	}
+	defer reader.Close()
+
+	var snapshot DecisionSnapshot
+	if err := json.NewDecoder(reader).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("解析决策快照失败: %w", err)
+	}
+	return &snapshot, nil
+}
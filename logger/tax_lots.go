@@ -0,0 +1,163 @@
+package logger
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// taxLotHeader 税务批次CSV表头（常见加密货币报税工具的通用导入格式）
+var taxLotHeader = []string{
+	"date_acquired", "date_sold", "asset", "side", "quantity",
+	"proceeds", "cost_basis", "fee", "gain", "currency", "strategy_id",
+}
+
+// TaxLot 一笔已平仓的税务批次（永续合约：多单先买后卖，空单先卖后买）
+type TaxLot struct {
+	DateAcquired time.Time // 开仓时间
+	DateSold     time.Time // 平仓时间
+	Asset        string    // 标的资产（如BTC）
+	Currency     string    // 计价货币（如USDT）
+	Side         string    // long 或 short
+	Quantity     float64
+	Proceeds     float64 // 卖出所得（多单为平仓价值，空单为开仓价值）
+	CostBasis    float64 // 买入成本（多单为开仓价值，空单为平仓价值）
+	Fee          float64 // 开平仓手续费（按费率估算）
+	StrategyID   string
+}
+
+// Gain 应税盈亏（卖出所得 - 买入成本 - 手续费）
+func (lot TaxLot) Gain() float64 {
+	return lot.Proceeds - lot.CostBasis - lot.Fee
+}
+
+// TaxLotSummary 导出结果汇总
+type TaxLotSummary struct {
+	Lots     []TaxLot
+	Unclosed int // 决策日志中找不到平仓记录的开仓（由止损/止盈平仓或仍未平仓），未导出
+}
+
+// TaxLots 从决策日志中按开平仓配对出平仓时间在[from, to]内的税务批次
+// 会扫描全部日志（开仓可早于from）；同一持仓不会叠加开仓，再次开仓时上一笔视为已被止损/止盈平仓（无平仓价，不导出）。
+// feeRate为单边手续费率（如0.0004表示0.04%），用于估算手续费
+func (l *DecisionLogger) TaxLots(from, to time.Time, feeRate float64) (*TaxLotSummary, error) {
+	records, err := l.GetLatestRecords(math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &TaxLotSummary{}
+	open := make(map[string]DecisionAction) // symbol_side -> 未平仓的开仓
+	for _, record := range records {
+		for _, action := range record.Decisions {
+			if !action.Success || action.Price <= 0 {
+				continue
+			}
+			switch action.Action {
+			case "open_long", "open_short":
+				key := action.Symbol + "_" + strings.TrimPrefix(action.Action, "open_")
+				if _, exists := open[key]; exists {
+					summary.Unclosed++
+				}
+				open[key] = action
+			case "add_long", "add_short":
+				// 金字塔加仓并入同一批次：数量累加，成本价按数量加权
+				key := action.Symbol + "_" + strings.TrimPrefix(action.Action, "add_")
+				entry, exists := open[key]
+				if !exists || entry.Quantity+action.Quantity <= 0 {
+					continue
+				}
+				total := entry.Quantity + action.Quantity
+				entry.Price = (entry.Price*entry.Quantity + action.Price*action.Quantity) / total
+				entry.Quantity = total
+				open[key] = entry
+			case "close_long", "close_short":
+				side := strings.TrimPrefix(action.Action, "close_")
+				key := action.Symbol + "_" + side
+				entry, exists := open[key]
+				if !exists {
+					continue
+				}
+				delete(open, key)
+
+				if action.Timestamp.Before(from) || action.Timestamp.After(to) {
+					continue
+				}
+				summary.Lots = append(summary.Lots, newTaxLot(entry, action, side, feeRate))
+			}
+		}
+	}
+	summary.Unclosed += len(open)
+	return summary, nil
+}
+
+// newTaxLot 由开仓和平仓动作生成税务批次
+func newTaxLot(entry, exit DecisionAction, side string, feeRate float64) TaxLot {
+	quantity := entry.Quantity
+	if exit.Quantity > 0 && exit.Quantity < quantity {
+		quantity = exit.Quantity
+	}
+	openValue := quantity * entry.Price
+	closeValue := quantity * exit.Price
+
+	asset, currency := entry.Symbol, ""
+	for _, quote := range []string{"USDT", "USDC", "USD"} {
+		if strings.HasSuffix(entry.Symbol, quote) {
+			asset, currency = strings.TrimSuffix(entry.Symbol, quote), quote
+			break
+		}
+	}
+
+	lot := TaxLot{
+		DateAcquired: entry.Timestamp,
+		DateSold:     exit.Timestamp,
+		Asset:        asset,
+		Currency:     currency,
+		Side:         side,
+		Quantity:     quantity,
+		Fee:          (openValue + closeValue) * feeRate,
+		StrategyID:   entry.Strategy(),
+	}
+	if side == "long" {
+		lot.Proceeds, lot.CostBasis = closeValue, openValue
+	} else {
+		lot.Proceeds, lot.CostBasis = openValue, closeValue
+	}
+	return lot
+}
+
+// WriteTaxLotsCSV 写出税务批次CSV（时间为UTC，金额保留8位小数）
+func WriteTaxLotsCSV(w io.Writer, lots []TaxLot) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(taxLotHeader); err != nil {
+		return err
+	}
+	amount := func(v float64) string { return strconv.FormatFloat(v, 'f', 8, 64) }
+	for _, lot := range lots {
+		row := []string{
+			lot.DateAcquired.UTC().Format("2006-01-02 15:04:05"),
+			lot.DateSold.UTC().Format("2006-01-02 15:04:05"),
+			lot.Asset,
+			lot.Side,
+			amount(lot.Quantity),
+			amount(lot.Proceeds),
+			amount(lot.CostBasis),
+			amount(lot.Fee),
+			amount(lot.Gain()),
+			lot.Currency,
+			lot.StrategyID,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("写入CSV失败: %w", err)
+	}
+	return nil
+}
@@ -1,16 +1,32 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
 	"log"
 	"nofx/api"
+	"nofx/audit"
+	"nofx/auth"
 	"nofx/config"
+	"nofx/events"
+	"nofx/leader"
+	"nofx/logger"
 	"nofx/manager"
+	"nofx/market"
+	"nofx/monitor"
 	"nofx/pool"
+	"nofx/rpc"
+	"nofx/run"
+	"nofx/secret"
+	"nofx/trader"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -19,19 +35,94 @@ func main() {
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
 	fmt.Println()
 
-	// 加载配置文件
+	// 状态查看子命令: nofx inspect [API地址] [trader_id...]
+	if len(os.Args) > 1 && os.Args[1] == "inspect" {
+		inspect(os.Args[2:])
+		return
+	}
+	// 终端监控子命令: nofx tui [API地址] [trader_id...]
+	if len(os.Args) > 1 && os.Args[1] == "tui" {
+		runTUI(os.Args[2:])
+		return
+	}
+	// 历史回放子命令: nofx replay CONFIG 开始日期 结束日期 [基础周期] [目录]
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		replay(os.Args[2:])
+		return
+	}
+	// 历史K线同步子命令: nofx sync-history SYMBOL INTERVAL [起始日期YYYY-MM-DD] [目录]
+	if len(os.Args) > 1 && os.Args[1] == "sync-history" {
+		syncHistory(os.Args[2:], false)
+		return
+	}
+	// 从币安公开数据归档批量导入历史K线（再用REST补齐最新部分）: nofx import-history SYMBOL INTERVAL [起始日期] [目录]
+	if len(os.Args) > 1 && os.Args[1] == "import-history" {
+		syncHistory(os.Args[2:], true)
+		return
+	}
+
+	// 税务批次导出子命令: nofx export-tax 决策日志目录 开始日期 结束日期 [输出文件] [单边手续费率%]
+	if len(os.Args) > 1 && os.Args[1] == "export-tax" {
+		exportTax(os.Args[2:])
+		return
+	}
+	// 审计日志校验子命令: nofx verify-audit [审计日志文件] [HMAC密钥环境变量名]
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		verifyAudit(os.Args[2:])
+		return
+	}
+	// 生成静态加密密钥: nofx gen-encryption-key
+	if len(os.Args) > 1 && os.Args[1] == "gen-encryption-key" {
+		key, err := secret.GenerateKey()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Println(key)
+		return
+	}
+	// 加密配置中的密钥（从标准输入读取明文）: nofx encrypt-secret [密钥环境变量名]
+	if len(os.Args) > 1 && os.Args[1] == "encrypt-secret" {
+		encryptSecret(os.Args[2:])
+		return
+	}
+
+	// 加载配置: nofx [配置文件] 或 nofx run [--config 配置文件|env] [--api-port 端口]
 	configFile := "config.json"
-	if len(os.Args) > 1 {
+	apiPort := 0
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		configFile, apiPort = runFlags(os.Args[2:])
+	} else if len(os.Args) > 1 {
 		configFile = os.Args[1]
 	}
 
-	log.Printf("📋 加载配置文件: %s", configFile)
-	cfg, err := config.LoadConfig(configFile)
+	cfg, err := loadConfig(configFile)
 	if err != nil {
 		log.Fatalf("❌ 加载配置失败: %v", err)
 	}
+	if apiPort > 0 {
+		cfg.APIServerPort = apiPort
+	}
 
 	log.Printf("✓ 配置加载成功，共%d个trader参赛", len(cfg.Traders))
+	configureEncryption(cfg.Encryption)
+	startRun("live", configFile, cfg.Seed, nil)
+
+	// 启动防篡改审计日志（可选），记录本次运行的配置
+	if a := cfg.AuditLog; a != nil && a.Enabled {
+		path := a.Path
+		if path == "" {
+			path = audit.DefaultPath
+		}
+		key, err := auditKey(a.KeyEnv)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if err := audit.Start(path, key); err != nil {
+			log.Fatalf("❌ 启动审计日志失败: %v", err)
+		}
+		audit.RecordConfig(run.Current())
+		log.Printf("✓ 审计日志: %s（HMAC: %v）", path, key != nil)
+	}
 	fmt.Println()
 
 	// 设置默认主流币种列表
@@ -53,6 +144,83 @@ func main() {
 		log.Printf("✓ 已配置OI Top API")
 	}
 
+	// 设置币安合约API基础地址（可选镜像/代理，支持故障切换）
+	if cfg.BinanceFuturesURL != "" || len(cfg.BinanceFuturesFallbackURLs) > 0 {
+		market.BinanceFutures.SetURLs(cfg.BinanceFuturesURL, cfg.BinanceFuturesFallbackURLs...)
+		log.Printf("✓ 币安合约API地址: %v", market.BinanceFutures.URLs())
+	}
+
+	// 设置币安合约请求权重上限（可选，-1关闭限速）
+	if cfg.BinanceWeightPerMinute != 0 {
+		market.BinanceLimiter.SetLimit(cfg.BinanceWeightPerMinute)
+		log.Printf("✓ 币安合约请求权重上限: %d/分钟", cfg.BinanceWeightPerMinute)
+	}
+
+	// 设置私有接口recvWindow（可选）
+	if cfg.RecvWindowMs > 0 {
+		trader.SetRecvWindow(cfg.RecvWindowMs)
+	}
+
+	// 加载币种规格（精度、上线时间）到注册表，之后每小时刷新（下架计划、状态和精度变化）
+	if err := market.LoadSymbolSpecs(); err != nil {
+		log.Printf("⚠️  %v（将按命名规则推导交易对）", err)
+	}
+	market.StartSymbolSpecsRefresh()
+
+	// 设置行情数据源（默认币安永续）
+	if cfg.MarketDataSource != "" {
+		if err := market.SetProvider(cfg.MarketDataSource); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+
+	// 启动实时行情中心（可选，下单前检查使用WebSocket最新价格）
+	if cfg.WebSocketStream {
+		market.Hub.Start()
+		defer market.Hub.Stop()
+	}
+
+	// K线缓存保留策略（可选，长时间运行时限制内存）
+	if k := cfg.KlineCache; k != nil {
+		if err := market.Hub.SetKlineRetention(market.KlineRetention{
+			Recent:           k.Recent,
+			DownsampleFactor: k.DownsampleFactor,
+			Archive:          k.Archive,
+			IdleTimeout:      time.Duration(k.IdleMinutes) * time.Minute,
+		}); err != nil {
+			log.Fatalf("❌ kline_cache配置无效: %v", err)
+		}
+	}
+
+	// 按币种活跃度自适应轮询持仓量/资金费率（可选）
+	if p := cfg.AdaptivePolling; p != nil {
+		if err := market.Hub.SetPollConfig(market.PollConfig{
+			MinInterval:     time.Duration(p.MinIntervalSeconds) * time.Second,
+			MaxInterval:     time.Duration(p.MaxIntervalSeconds) * time.Second,
+			BudgetPerMinute: p.BudgetPerMinute,
+		}); err != nil {
+			log.Fatalf("❌ adaptive_polling配置无效: %v", err)
+		}
+	}
+
+	// 持仓量/资金费率变化事件（可选）
+	if p := cfg.PositioningAlerts; p != nil {
+		if err := market.Hub.SetPositioningAlerts(market.PositioningAlerts{
+			OIChangePct:      p.OIChangePct,
+			FundingChangeBps: p.FundingChangeBps,
+		}); err != nil {
+			log.Fatalf("❌ positioning_alerts配置无效: %v", err)
+		}
+	}
+
+	// 设置指标与行情新鲜度（可选）
+	configureAnalysis(cfg)
+
+	// 设置期权数据来源（可选）
+	if cfg.OptionsSource != "" {
+		market.SetOptionsSource(cfg.OptionsSource)
+	}
+
 	// 创建TraderManager
 	traderManager := manager.NewTraderManager()
 
@@ -75,7 +243,8 @@ func main() {
 			cfg.MaxDailyLoss,
 			cfg.MaxDrawdown,
 			cfg.StopTradingMinutes,
-			cfg.Leverage, // 传递杠杆配置
+			cfg.Leverage,        // 传递杠杆配置
+			cfg.SymbolOverrides, // 传递币种覆盖配置
 		)
 		if err != nil {
 			log.Fatalf("❌ 初始化trader失败: %v", err)
@@ -103,6 +272,9 @@ func main() {
 	fmt.Printf("  • AI将自主决定每笔交易的杠杆倍数（山寨币最高%d倍，BTC/ETH最高%d倍）\n",
 		cfg.Leverage.AltcoinLeverage, cfg.Leverage.BTCETHLeverage)
 	fmt.Println("  • AI将自主决定每笔交易的仓位大小")
+	if len(cfg.SymbolOverrides) > 0 {
+		fmt.Printf("  • 已为%d个币种配置专属策略（杠杆/仓位/时间框架/提示）\n", len(cfg.SymbolOverrides))
+	}
 	fmt.Println("  • AI将自主设置止损和止盈价格")
 	fmt.Println("  • AI将基于市场数据、技术指标、账户状态做出全面分析")
 	fmt.Println()
@@ -112,23 +284,167 @@ func main() {
 	fmt.Println(strings.Repeat("=", 60))
 	fmt.Println()
 
+	// API Key认证（HTTP和gRPC共用，可选）
+	authenticator, err := newAuthenticator(cfg.APIAuth)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
 	// 创建并启动API服务器
 	apiServer := api.NewServer(traderManager, cfg.APIServerPort)
+	if cfg.Webhook != nil && cfg.Webhook.Enabled {
+		apiServer.SetWebhookSecret(cfg.Webhook.Secret)
+	}
+	if authenticator != nil {
+		apiServer.SetAuthenticator(authenticator)
+	} else {
+		log.Printf("⚠️  未启用api_auth：所有接口（包括熔断、策略启停、平仓等运行时操作）无需API Key即可访问")
+	}
+
+	// 启动交易所状态监控（可选，维护期间暂停交易）
+	if cfg.ExchangeStatus != nil && cfg.ExchangeStatus.Enabled {
+		exchangeSet := make(map[string]bool)
+		for _, traderCfg := range cfg.Traders {
+			if traderCfg.Enabled {
+				exchangeSet[traderCfg.Exchange] = true
+			}
+		}
+		// 行情数据源同样需要监控（未配置时默认为币安，模拟交易也依赖它）
+		marketSource := cfg.MarketDataSource
+		if marketSource == "" {
+			marketSource = "binance"
+		}
+		exchangeSet[marketSource] = true
+		exchanges := make([]string, 0, len(exchangeSet))
+		for exchange := range exchangeSet {
+			exchanges = append(exchanges, exchange)
+		}
+
+		statusMonitor := monitor.NewExchangeStatusMonitor(exchanges, time.Duration(cfg.ExchangeStatus.IntervalSeconds)*time.Second)
+		statusMonitor.Start()
+		defer statusMonitor.Stop()
+	}
+
+	// 启动时钟偏差监控（可选，偏差超过recvWindow时告警，可暂停签名请求）
+	if w := cfg.ClockWatchdog; w != nil && w.Enabled {
+		maxDrift := w.MaxDriftMs
+		if maxDrift == 0 {
+			maxDrift = cfg.RecvWindowMs
+		}
+		clockMonitor := monitor.NewClockDriftMonitor(monitor.ClockDriftConfig{
+			Interval:    time.Duration(w.IntervalSeconds) * time.Second,
+			MaxDrift:    time.Duration(maxDrift) * time.Millisecond,
+			NTPServer:   w.NTPServer,
+			BlockSigned: w.BlockSignedRequests,
+		})
+		clockMonitor.Start()
+		defer clockMonitor.Stop()
+	}
+
+	// 启动稳定币脱锚监控（可选）
+	if cfg.DepegMonitor != nil && cfg.DepegMonitor.Enabled {
+		depegMonitor := monitor.NewDepegMonitor(monitor.DepegConfig{
+			ThresholdPct:  cfg.DepegMonitor.ThresholdPct,
+			BasisPct:      cfg.DepegMonitor.BasisPct,
+			Interval:      time.Duration(cfg.DepegMonitor.IntervalSeconds) * time.Second,
+			TripBreaker:   cfg.DepegMonitor.TripBreaker,
+			PauseDuration: time.Duration(cfg.DepegMonitor.PauseMinutes) * time.Minute,
+		})
+		depegMonitor.Start()
+		defer depegMonitor.Stop()
+		apiServer.SetDepegMonitor(depegMonitor)
+	}
 	go func() {
 		if err := apiServer.Start(); err != nil {
 			log.Printf("❌ API服务器错误: %v", err)
 		}
 	}()
 
+	// 启动阈值告警（可选）
+	if cfg.Alerts != nil {
+		alertMonitor := monitor.NewAlertMonitor(monitor.AlertThresholds{
+			FundingRatePct:       cfg.Alerts.FundingRatePct,
+			DrawdownPct:          cfg.Alerts.DrawdownPct,
+			WebSocketDownSeconds: cfg.Alerts.WebSocketDownSeconds,
+		})
+		alertMonitor.Start()
+		defer alertMonitor.Stop()
+		apiServer.SetAlertMonitor(alertMonitor)
+	}
+
+	// 启动事件转发（可选，镜像到Redis pub/sub或MQTT）
+	if p := cfg.EventPublisher; p != nil && p.Enabled {
+		mirror, err := events.NewMirror(events.MirrorConfig{
+			Type:        p.Type,
+			URL:         p.URL,
+			TopicPrefix: p.TopicPrefix,
+			Events:      p.Events,
+			ClientID:    p.ClientID,
+			Username:    p.Username,
+			Password:    p.Password,
+			QoS:         byte(p.QoS),
+		})
+		if err != nil {
+			log.Printf("❌ 事件转发启动失败: %v", err)
+		} else {
+			mirror.Start()
+			defer mirror.Stop()
+		}
+	}
+
+	// 启动gRPC服务（可选）
+	if cfg.GRPCPort > 0 {
+		grpcServer := rpc.NewServer(traderManager, cfg.GRPCPort, authenticator)
+		go func() {
+			if err := grpcServer.Start(); err != nil {
+				log.Printf("❌ gRPC服务错误: %v", err)
+			}
+		}()
+		defer grpcServer.Stop()
+	}
+
 	// 设置优雅退出
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	// 主备选举（可选）：备用节点只提供API/行情，成为主节点后才启动交易循环
+	var leaderLost <-chan struct{}
+	if l := cfg.LeaderElection; l != nil && l.Enabled {
+		elector, err := leader.New(leader.Config{
+			Backend:  l.Backend,
+			URL:      l.URL,
+			Key:      l.Key,
+			TTL:      time.Duration(l.TTLSeconds) * time.Second,
+			Identity: l.Identity,
+		})
+		if err != nil {
+			log.Fatalf("❌ 主备选举启动失败: %v", err)
+		}
+		elector.Start()
+		defer elector.Stop()
+
+		log.Printf("⏳ 备用节点：等待成为主节点后启动交易...")
+		select {
+		case <-elector.Elected():
+		case <-sigChan:
+			log.Println("📛 收到退出信号（备用节点，未启动交易）")
+			return
+		}
+		leaderLost = elector.Lost()
+	}
+
 	// 启动所有trader
 	traderManager.StartAll()
 
-	// 等待退出信号
-	<-sigChan
+	// 等待退出信号（或失去主节点身份）
+	select {
+	case <-sigChan:
+	case <-leaderLost:
+		// 不再重新竞选：停止交易并以非零状态退出，由进程管理器重启为备用节点
+		log.Println("🚨 已失去主节点身份，停止所有trader并退出")
+		traderManager.StopAll()
+		os.Exit(1)
+	}
 	fmt.Println()
 	fmt.Println()
 	log.Println("📛 收到退出信号，正在停止所有trader...")
@@ -137,3 +453,376 @@ func main() {
 	fmt.Println()
 	fmt.Println("👋 感谢使用AI交易竞赛系统！")
 }
+
+// syncHistory 下载/增量同步历史K线到本地存储（archive为true时先从data.binance.vision导入归档）
+func syncHistory(args []string, archive bool) {
+	if len(args) < 2 {
+		log.Fatalf("❌ 用法: nofx sync-history|import-history SYMBOL INTERVAL [起始日期YYYY-MM-DD] [目录]")
+	}
+	since := time.Now().AddDate(-1, 0, 0)
+	if len(args) > 2 {
+		t, err := time.Parse("2006-01-02", args[2])
+		if err != nil {
+			log.Fatalf("❌ 无效的起始日期: %s", args[2])
+		}
+		since = t
+	}
+	dir := market.DefaultHistoryDir
+	if len(args) > 3 {
+		dir = args[3]
+	}
+
+	store := market.NewHistoryStore(dir)
+	if archive {
+		if _, err := store.ImportBinanceVision(args[0], args[1], since); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+	if _, err := store.Sync(args[0], args[1], since); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+// exportTax 把决策日志中的已平仓交易导出为税务批次CSV（按平仓日期筛选）
+func exportTax(args []string) {
+	if len(args) < 3 {
+		log.Fatalf("❌ 用法: nofx export-tax 决策日志目录 开始日期YYYY-MM-DD 结束日期YYYY-MM-DD [输出文件，默认tax_lots.csv] [单边手续费率%%，默认0.04]")
+	}
+	from, err := time.Parse("2006-01-02", args[1])
+	if err != nil {
+		log.Fatalf("❌ 无效的开始日期: %s", args[1])
+	}
+	to, err := time.Parse("2006-01-02", args[2])
+	if err != nil {
+		log.Fatalf("❌ 无效的结束日期: %s", args[2])
+	}
+	output := "tax_lots.csv"
+	if len(args) > 3 {
+		output = args[3]
+	}
+	feeRatePct := 0.04
+	if len(args) > 4 {
+		if feeRatePct, err = strconv.ParseFloat(args[4], 64); err != nil || feeRatePct < 0 {
+			log.Fatalf("❌ 无效的手续费率: %s", args[4])
+		}
+	}
+
+	configureEncryption(nil)
+	summary, err := logger.NewDecisionLogger(args[0]).TaxLots(from, to.AddDate(0, 0, 1), feeRatePct/100)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		log.Fatalf("❌ 创建输出文件失败: %v", err)
+	}
+	defer f.Close()
+	if err := logger.WriteTaxLotsCSV(f, summary.Lots); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	log.Printf("✓ 已导出 %d 笔已平仓交易到 %s", len(summary.Lots), output)
+	if summary.Unclosed > 0 {
+		log.Printf("⚠️  %d 笔开仓在决策日志中没有平仓记录（由止损/止盈平仓或仍持仓），未导出", summary.Unclosed)
+	}
+}
+
+// verifyAudit 校验审计日志的哈希链，输出记录数和最后一条记录的哈希
+func verifyAudit(args []string) {
+	path := audit.DefaultPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+	keyEnv := ""
+	if len(args) > 1 {
+		keyEnv = args[1]
+	}
+	key, err := auditKey(keyEnv)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	result, err := audit.Verify(path, key)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if !result.Valid {
+		log.Fatalf("❌ 审计日志校验失败: 第%d条记录 %s（共%d条）", result.BrokenAt, result.Error, result.Entries)
+	}
+	log.Printf("✓ 审计日志完整: %d条记录，最后一条哈希 %s", result.Entries, result.Head)
+}
+
+// indicatorConfig 配置中的核心指标周期（未设置的字段为0，使用默认周期）
+func indicatorConfig(cfg *config.Config) market.IndicatorConfig {
+	ind := cfg.Indicators
+	if ind == nil {
+		return market.IndicatorConfig{}
+	}
+	return market.IndicatorConfig{
+		TrendMA:  ind.TrendMA,
+		EntryMA:  ind.EntryMA,
+		RSI:      ind.RSI,
+		EMAFast:  ind.EMAFast,
+		EMASlow:  ind.EMASlow,
+		ATRFast:  ind.ATRFast,
+		ATRSlow:  ind.ATRSlow,
+		MACDFast: ind.MACDFast,
+		MACDSlow: ind.MACDSlow,
+		Warmup:   ind.Warmup,
+	}
+}
+
+// runFlags 解析nofx run的参数（容器部署入口），--config默认取环境变量NOFX_CONFIG，未设置时为config.json
+func runFlags(args []string) (configFile string, apiPort int) {
+	defaultConfig := os.Getenv(config.EnvConfig)
+	if defaultConfig == "" {
+		defaultConfig = "config.json"
+	}
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	flags.StringVar(&configFile, "config", defaultConfig, "配置文件路径，env表示从NOFX_*环境变量加载（不需要配置文件）")
+	flags.IntVar(&apiPort, "api-port", 0, "API服务端口（覆盖api_server_port）")
+	flags.Parse(args)
+	return configFile, apiPort
+}
+
+// loadConfig 从配置文件或环境变量（source为env）加载配置
+func loadConfig(source string) (*config.Config, error) {
+	if source == config.EnvSource {
+		log.Printf("📋 从环境变量加载配置（%s*）", config.EnvPrefix)
+		return config.LoadConfigFromEnv()
+	}
+	log.Printf("📋 加载配置文件: %s", source)
+	return config.LoadConfig(source)
+}
+
+// encryptSecret 用静态加密密钥加密标准输入的一行明文，输出可直接写入配置文件的enc:密文
+func encryptSecret(args []string) {
+	keyEnv := ""
+	if len(args) > 0 {
+		keyEnv = args[0]
+	}
+	key, err := secret.Key(keyEnv)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Fprintln(os.Stderr, "请输入要加密的内容（回车结束）:")
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	plaintext := strings.TrimRight(line, "\r\n")
+	if plaintext == "" {
+		log.Fatalf("❌ 未读取到要加密的内容")
+	}
+	encrypted, err := secret.EncryptString(key, plaintext)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Println(encrypted)
+}
+
+// configureEncryption 按配置设置决策日志/快照的静态加密；
+// 未配置时如果设置了NOFX_ENCRYPTION_KEY，仍可读取已加密的日志（新日志不加密）
+func configureEncryption(cfg *config.EncryptionConfig) {
+	if cfg == nil {
+		if os.Getenv(secret.DefaultKeyEnv) == "" {
+			return
+		}
+		cfg = &config.EncryptionConfig{}
+	}
+	key, err := secret.Key(cfg.KeyEnv)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	secret.Configure(key, cfg.DecisionLogs)
+	if cfg.DecisionLogs {
+		log.Printf("🔒 决策日志和决策快照将加密保存（AES-256-GCM）")
+	}
+}
+
+// newAuthenticator 按配置创建API Key认证（未启用时返回nil）
+func newAuthenticator(cfg *config.APIAuthConfig) (*auth.Authenticator, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+	authenticator := auth.NewAuthenticator(auth.Role(cfg.AnonymousRole))
+	for _, k := range cfg.Keys {
+		role, err := auth.ParseRole(k.Role)
+		if err != nil {
+			return nil, err
+		}
+		key := k.Key
+		if k.KeyEnv != "" {
+			if key = os.Getenv(k.KeyEnv); key == "" {
+				return nil, fmt.Errorf("API Key %s 的环境变量 %s 未设置", k.Name, k.KeyEnv)
+			}
+		}
+		if err := authenticator.AddKey(k.Name, key, role); err != nil {
+			return nil, err
+		}
+	}
+	anonymous := "拒绝"
+	if cfg.AnonymousRole != "" {
+		anonymous = cfg.AnonymousRole
+	}
+	log.Printf("✓ API认证已启用: %d个API Key，未认证请求: %s", len(cfg.Keys), anonymous)
+	return authenticator, nil
+}
+
+// auditKey 从环境变量读取审计日志的HMAC密钥（envName为空表示不使用密钥）
+func auditKey(envName string) ([]byte, error) {
+	if envName == "" {
+		return nil, nil
+	}
+	key := os.Getenv(envName)
+	if key == "" {
+		return nil, fmt.Errorf("审计日志密钥环境变量 %s 未设置", envName)
+	}
+	return []byte(key), nil
+}
+
+// configureAnalysis 按配置设置指标周期、评分权重和行情新鲜度检查（实盘和回放共用）
+func configureAnalysis(cfg *config.Config) {
+	// 设置变化率/动量指标周期（可选）
+	if len(cfg.MomentumPeriods) > 0 {
+		market.SetMomentumPeriods(cfg.MomentumPeriods)
+	}
+
+	// 设置综合强度评分权重（可选）
+	if w := cfg.StrengthWeights; w != nil {
+		market.SetStrengthWeights(market.StrengthWeights{Trend: w.Trend, Momentum: w.Momentum, Volume: w.Volume, OI: w.OI})
+	}
+
+	// 设置核心指标周期（可选）
+	if cfg.Indicators != nil {
+		market.SetIndicatorConfig(indicatorConfig(cfg))
+	}
+
+	// 设置行情新鲜度检查（可选）
+	if cfg.MaxDataAgeSeconds > 0 {
+		market.SetFreshnessGuard(time.Duration(cfg.MaxDataAgeSeconds)*time.Second, cfg.RefetchStaleData)
+	}
+}
+
+// replay 用本地历史K线回放完整的实盘交易流程（模拟成交，AI决策仍会真实调用）
+func replay(args []string) {
+	if len(args) < 3 {
+		log.Fatalf("❌ 用法: nofx replay CONFIG 开始日期YYYY-MM-DD 结束日期YYYY-MM-DD [基础周期，默认3m] [目录]")
+	}
+	cfg, err := config.LoadConfig(args[0])
+	if err != nil {
+		log.Fatalf("❌ 加载配置失败: %v", err)
+	}
+	configureEncryption(cfg.Encryption)
+	from, err := time.Parse("2006-01-02", args[1])
+	if err != nil {
+		log.Fatalf("❌ 无效的开始日期: %s", args[1])
+	}
+	to, err := time.Parse("2006-01-02", args[2])
+	if err != nil {
+		log.Fatalf("❌ 无效的结束日期: %s", args[2])
+	}
+	baseInterval := "3m"
+	if len(args) > 3 {
+		baseInterval = args[3]
+	}
+	dir := market.DefaultHistoryDir
+	if len(args) > 4 {
+		dir = args[4]
+	}
+
+	provider, err := market.NewReplayProvider(market.NewHistoryStore(dir), baseInterval)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	startRun("replay", args[0], cfg.Seed, func(m *run.Manifest) {
+		for _, symbol := range cfg.DefaultCoins {
+			m.AddDataRange(market.Normalize(symbol), baseInterval, from, to)
+		}
+	})
+	configureAnalysis(cfg)
+
+	// 回放只使用默认币种列表（币种池/OI Top接口只有当前数据）
+	pool.SetDefaultCoins(cfg.DefaultCoins)
+	pool.SetUseDefaultCoins(true)
+	pool.SetOITopAPI("")
+
+	traderManager := manager.NewTraderManager()
+	var ids []string
+	runID := run.ID()
+	for _, traderCfg := range cfg.Traders {
+		if !traderCfg.Enabled {
+			continue
+		}
+		traderCfg.Exchange = "paper"
+		traderCfg.ID = fmt.Sprintf("%s_replay_%s", traderCfg.ID, runID) // 独立的决策日志目录
+		err := traderManager.AddTrader(traderCfg, "", cfg.MaxDailyLoss, cfg.MaxDrawdown,
+			cfg.StopTradingMinutes, cfg.Leverage, cfg.SymbolOverrides)
+		if err != nil {
+			log.Fatalf("❌ 初始化trader失败: %v", err)
+		}
+		ids = append(ids, traderCfg.ID)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Println("📛 收到退出信号，停止回放...")
+		traderManager.StopAll()
+	}()
+
+	for _, id := range ids {
+		at, _ := traderManager.GetTrader(id)
+		result, err := at.Replay(provider, trader.ReplayConfig{Symbols: cfg.DefaultCoins, From: from, To: to})
+		if err != nil {
+			log.Fatalf("❌ 回放失败: %v", err)
+		}
+		fmt.Printf("  • %s: 净值 %.2f (%+.2f%%) | 最大回撤 %.2f%% | %d个周期 | %d笔交易（胜%d） | 手续费 %.2f\n",
+			at.GetName(), result.FinalEquity, result.PnLPct, result.MaxDrawdownPct,
+			result.Cycles, result.Stats.Trades, result.Stats.Wins, result.Stats.Fees)
+
+		// HTML报告（K线图周期按回放时长选择）
+		interval := trader.ReportInterval(result.From, result.To)
+		candles := make(map[string][]market.Kline)
+		for _, symbol := range cfg.DefaultCoins {
+			symbol = market.Normalize(symbol)
+			klines, err := provider.Range(symbol, interval, result.From, result.To)
+			if err != nil {
+				log.Printf("⚠️  %s K线图跳过: %v", symbol, err)
+				continue
+			}
+			candles[symbol] = klines
+		}
+		path := filepath.Join(run.DefaultManifestDir, runID, fmt.Sprintf("report_%s.html", id))
+		if err := trader.WriteReplayReport(path, result, candles, interval); err != nil {
+			log.Printf("⚠️  %v", err)
+			continue
+		}
+		fmt.Printf("    报告: %s\n", path)
+	}
+}
+
+// startRun 开始一次运行（设置随机种子）并保存运行清单，便于按相同配置、代码版本、种子和数据范围复现
+func startRun(mode, configFile string, seed int64, describe func(*run.Manifest)) {
+	run.Start(seed)
+	var manifest *run.Manifest
+	var err error
+	if configFile == config.EnvSource {
+		var data []byte
+		if data, err = config.EnvJSON(); err == nil {
+			manifest, err = run.NewManifestFromConfig(mode, configFile, data)
+		}
+	} else {
+		manifest, err = run.NewManifest(mode, configFile)
+	}
+	if err != nil {
+		log.Printf("⚠️  创建运行清单失败: %v", err)
+		return
+	}
+	if describe != nil {
+		describe(manifest)
+	}
+	path, err := manifest.Save("")
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+		return
+	}
+	log.Printf("✓ 运行清单: %s（代码版本 %s）", path, manifest.Revision)
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/market"
+	"nofx/risk"
 	"nofx/trader"
 	"sync"
 	"time"
@@ -23,7 +26,7 @@ func NewTraderManager() *TraderManager {
 }
 
 // AddTrader 添加一个trader
-func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, leverage config.LeverageConfig) error {
+func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, leverage config.LeverageConfig, symbolOverrides map[string]config.SymbolOverride) error {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
@@ -31,6 +34,14 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		return fmt.Errorf("trader ID '%s' 已存在", cfg.ID)
 	}
 
+	// 账户级别的风控配置优先于全局配置
+	if cfg.MaxDailyLoss > 0 {
+		maxDailyLoss = cfg.MaxDailyLoss
+	}
+	if cfg.MaxDrawdown > 0 {
+		maxDrawdown = cfg.MaxDrawdown
+	}
+
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
 		ID:                    cfg.ID,
@@ -39,6 +50,7 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		Exchange:              cfg.Exchange,
 		BinanceAPIKey:         cfg.BinanceAPIKey,
 		BinanceSecretKey:      cfg.BinanceSecretKey,
+		BinanceCoinMContract:  cfg.BinanceCoinMContract,
 		HyperliquidPrivateKey: cfg.HyperliquidPrivateKey,
 		HyperliquidWalletAddr: cfg.HyperliquidWalletAddr,
 		HyperliquidTestnet:    cfg.HyperliquidTestnet,
@@ -53,12 +65,323 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		CustomAPIKey:          cfg.CustomAPIKey,
 		CustomModelName:       cfg.CustomModelName,
 		ScanInterval:          cfg.GetScanInterval(),
+		MemorySize:            cfg.GetMemorySize(),
 		InitialBalance:        cfg.InitialBalance,
 		BTCETHLeverage:        leverage.BTCETHLeverage,  // 使用配置的杠杆倍数
 		AltcoinLeverage:       leverage.AltcoinLeverage, // 使用配置的杠杆倍数
 		MaxDailyLoss:          maxDailyLoss,
 		MaxDrawdown:           maxDrawdown,
+		EnforceRiskLimits:     cfg.EnforceRiskLimits != nil && *cfg.EnforceRiskLimits,
+		MaxOpenRiskPct:        cfg.MaxOpenRiskPct,
 		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
+		ReconcileInterval:     cfg.GetReconcileInterval(),
+		LatencyBudget:         cfg.GetLatencyBudget(),
+		TrailingStopMode:      cfg.TrailingStopMode,
+		EntryOrderType:        cfg.EntryOrderType,
+		EntryTimeInForce:      cfg.EntryTimeInForce,
+		PostOnly:              cfg.PostOnly,
+		StrategyID:            cfg.StrategyID,
+	}
+
+	// 影子策略
+	if cfg.Shadow != nil {
+		traderConfig.Shadow = &trader.ShadowConfig{
+			Enabled:         cfg.Shadow.Enabled,
+			AIModel:         cfg.Shadow.AIModel,
+			CustomAPIURL:    cfg.Shadow.CustomAPIURL,
+			CustomAPIKey:    cfg.Shadow.CustomAPIKey,
+			CustomModelName: cfg.Shadow.CustomModelName,
+			ExtraPrompt:     cfg.Shadow.ExtraPrompt,
+		}
+	}
+
+	// 用户策略
+	for _, s := range cfg.Strategies {
+		traderConfig.Strategies = append(traderConfig.Strategies, trader.StrategyConfig{
+			ID:           s.ID,
+			Plugin:       s.Plugin,
+			Script:       s.Script,
+			Builtin:      s.Builtin,
+			Params:       s.Params,
+			Timeout:      time.Duration(s.TimeoutMs) * time.Millisecond,
+			MaxDecisions: s.MaxDecisions,
+			MaxSteps:     s.MaxSteps,
+		})
+	}
+
+	// 策略资金分配
+	if cfg.Allocation != nil {
+		rebalanceHours, lookbackDays := cfg.Allocation.RebalanceHours, cfg.Allocation.LookbackDays
+		if rebalanceHours == 0 {
+			rebalanceHours = 24
+		}
+		if lookbackDays == 0 {
+			lookbackDays = 30
+		}
+		traderConfig.Allocation = trader.AllocationConfig{
+			Mode:              cfg.Allocation.Mode,
+			Budgets:           cfg.Allocation.Budgets,
+			RebalanceInterval: time.Duration(rebalanceHours) * time.Hour,
+			Lookback:          time.Duration(lookbackDays) * 24 * time.Hour,
+		}
+	}
+
+	// 策略仓位计算模式
+	for id, s := range cfg.Sizing {
+		sizing := trader.SizingConfig{
+			Mode:          s.Mode,
+			RiskPct:       s.RiskPct,
+			KellyFraction: s.KellyFraction,
+			MaxRiskPct:    s.MaxRiskPct,
+			MinTrades:     s.MinTrades,
+			Lookback:      time.Duration(s.LookbackDays) * 24 * time.Hour,
+			TargetVolPct:  s.TargetVolPct,
+		}
+		if sizing.RiskPct == 0 {
+			sizing.RiskPct = 1
+		}
+		if sizing.KellyFraction == 0 {
+			sizing.KellyFraction = 0.5
+		}
+		if sizing.MaxRiskPct == 0 {
+			sizing.MaxRiskPct = 2
+		}
+		if sizing.MinTrades == 0 {
+			sizing.MinTrades = 10
+		}
+		if sizing.Lookback == 0 {
+			sizing.Lookback = 30 * 24 * time.Hour
+		}
+		if traderConfig.Sizing == nil {
+			traderConfig.Sizing = make(map[string]trader.SizingConfig)
+		}
+		traderConfig.Sizing[id] = sizing
+	}
+
+	// 各策略的最长持仓时间
+	for id, h := range cfg.MaxHolding {
+		if traderConfig.MaxHolding == nil {
+			traderConfig.MaxHolding = make(map[string]trader.MaxHoldingConfig)
+		}
+		traderConfig.MaxHolding[id] = trader.MaxHoldingConfig{Candles: h.Candles, Interval: h.Interval, Hours: h.Hours}
+	}
+
+	// 各策略的金字塔加仓规则
+	for id, p := range cfg.Pyramiding {
+		pyramid := trader.PyramidConfig{
+			ThresholdsR: p.ThresholdsR,
+			SizeScale:   p.SizeScale,
+			StopLagR:    p.StopLagR,
+			Caps:        risk.PyramidCaps{MaxAdds: p.MaxAdds, MaxSizeMultiple: p.MaxSizeMultiple},
+		}
+		if pyramid.SizeScale == 0 {
+			pyramid.SizeScale = 0.5
+		}
+		if pyramid.StopLagR == 0 {
+			pyramid.StopLagR = 1
+		}
+		if pyramid.Caps.MaxAdds == 0 {
+			pyramid.Caps.MaxAdds = len(p.ThresholdsR)
+		}
+		if pyramid.Caps.MaxSizeMultiple == 0 {
+			pyramid.Caps.MaxSizeMultiple = 2
+		}
+		if traderConfig.Pyramiding == nil {
+			traderConfig.Pyramiding = make(map[string]trader.PyramidConfig)
+		}
+		traderConfig.Pyramiding[id] = pyramid
+	}
+
+	// 网格/DCA开仓
+	if cfg.Grid != nil {
+		traderConfig.Grid = trader.GridConfig{
+			Levels:         cfg.Grid.Levels,
+			SpacingPct:     cfg.Grid.SpacingPct,
+			SizeMultiplier: cfg.Grid.SizeMultiplier,
+		}
+	}
+
+	// 配对交易
+	for _, p := range cfg.Pairs {
+		pair := trader.PairConfig{
+			Base:     market.Normalize(p.Base),
+			Quote:    market.Normalize(p.Quote),
+			Interval: p.Interval,
+			Lookback: p.Lookback,
+			ExitZ:    p.ExitZ,
+			StopZ:    p.StopZ,
+		}
+		if pair.Interval == "" {
+			pair.Interval = "1h"
+		}
+		if pair.Lookback == 0 {
+			pair.Lookback = 100
+		}
+		if pair.ExitZ == 0 {
+			pair.ExitZ = 0.5
+		}
+		if pair.StopZ == 0 {
+			pair.StopZ = 4
+		}
+		traderConfig.Pairs = append(traderConfig.Pairs, pair)
+	}
+
+	// 资金费率套利
+	if h := cfg.FundingHarvest; h != nil {
+		harvest := trader.FundingHarvestConfig{
+			EntryRatePct: h.EntryRatePct,
+			ExitRatePct:  h.ExitRatePct,
+			SizeUSD:      h.SizeUSD,
+			Leverage:     h.Leverage,
+			MaxPositions: h.MaxPositions,
+			Reverse:      h.Reverse,
+		}
+		for _, symbol := range h.Symbols {
+			harvest.Symbols = append(harvest.Symbols, market.Normalize(symbol))
+		}
+		if harvest.EntryRatePct == 0 {
+			harvest.EntryRatePct = 0.05
+		}
+		if harvest.ExitRatePct == 0 {
+			harvest.ExitRatePct = 0.01
+		}
+		if harvest.Leverage == 0 {
+			harvest.Leverage = 2
+		}
+		if harvest.MaxPositions == 0 {
+			harvest.MaxPositions = 3
+		}
+		traderConfig.FundingHarvest = harvest
+	}
+
+	// 信号组合
+	if e := cfg.Ensemble; e != nil {
+		traderConfig.Ensemble = trader.EnsembleConfig{
+			ID:        e.ID,
+			Members:   e.Members,
+			Rule:      e.Rule,
+			Weights:   e.Weights,
+			Threshold: e.Threshold,
+		}
+		if traderConfig.Ensemble.ID == "" {
+			traderConfig.Ensemble.ID = "ensemble"
+		}
+		if traderConfig.Ensemble.Threshold == 0 {
+			traderConfig.Ensemble.Threshold = 0.5
+		}
+	}
+
+	// 按市场状态切换策略
+	if r := cfg.Regime; r != nil {
+		regime := trader.RegimeConfig{
+			Trending:      r.Trending,
+			Ranging:       r.Ranging,
+			ConfirmCycles: r.ConfirmCycles,
+		}
+		for _, symbol := range r.Symbols {
+			regime.Symbols = append(regime.Symbols, market.Normalize(symbol))
+		}
+		if len(regime.Symbols) == 0 {
+			regime.Symbols = []string{"BTCUSDT"}
+		}
+		if regime.ConfirmCycles == 0 {
+			regime.ConfirmCycles = 3
+		}
+		traderConfig.Regime = regime
+	}
+
+	// 交易时段过滤
+	if h := cfg.TradingHours; h != nil {
+		hours, err := risk.NewTradingHours(h.Timezone, h.NoEntryWindows, h.NoEntryDays)
+		if err != nil {
+			return fmt.Errorf("trader %s 的trading_hours配置无效: %w", cfg.ID, err)
+		}
+		traderConfig.TradingHours = hours
+	}
+
+	// 币种分级的杠杆和名义价值上限（币种统一标准化为USDT交易对）
+	if len(leverage.Tiers) > 0 {
+		tiers := make([]risk.SymbolTier, 0, len(leverage.Tiers))
+		for _, t := range leverage.Tiers {
+			tier := risk.SymbolTier{Name: t.Name, MaxLeverage: t.MaxLeverage, MaxNotional: t.MaxNotionalUSD}
+			for _, symbol := range t.Symbols {
+				tier.Symbols = append(tier.Symbols, market.Normalize(symbol))
+			}
+			tiers = append(tiers, tier)
+		}
+		symbolTiers, err := risk.NewSymbolTiers(tiers)
+		if err != nil {
+			return fmt.Errorf("leverage.tiers配置无效: %w", err)
+		}
+		traderConfig.SymbolTiers = symbolTiers
+	}
+
+	// 盘口流动性检查
+	if g := cfg.LiquidityGuard; g != nil {
+		guard := trader.LiquidityGuardConfig{
+			MaxDepthPct: g.MaxDepthPct,
+			WithinBps:   g.WithinBps,
+			Reject:      g.Action == "reject",
+			MinScalePct: g.MinScalePct,
+		}
+		if guard.WithinBps == 0 {
+			guard.WithinBps = 50
+		}
+		if guard.MinScalePct == 0 {
+			guard.MinScalePct = 25
+		}
+		traderConfig.LiquidityGuard = guard
+	}
+
+	// 买卖价差检查（币种统一标准化为USDT交易对）
+	if g := cfg.SpreadGuard; g != nil {
+		guard := trader.SpreadGuardConfig{
+			MaxSpreadBps: g.MaxSpreadBps,
+			MaxDelay:     time.Duration(g.MaxDelaySeconds) * time.Second,
+		}
+		for symbol, limit := range g.Symbols {
+			if guard.Symbols == nil {
+				guard.Symbols = make(map[string]float64, len(g.Symbols))
+			}
+			guard.Symbols[market.Normalize(symbol)] = limit
+		}
+		traderConfig.SpreadGuard = guard
+	}
+
+	// 资金费结算前禁止开仓
+	traderConfig.FundingBlackout = time.Duration(cfg.FundingBlackoutMinutes) * time.Minute
+
+	// 决策快照
+	if s := cfg.Snapshots; s != nil {
+		traderConfig.Snapshots = trader.SnapshotConfig{
+			Enabled:     s.Enabled,
+			RawPayloads: s.RawPayloads,
+			Retention:   time.Duration(s.RetentionDays) * 24 * time.Hour,
+		}
+	}
+
+	// 模拟交易执行模型
+	if cfg.Paper != nil {
+		traderConfig.Paper = trader.PaperConfig{
+			Latency:        time.Duration(cfg.Paper.LatencyMs) * time.Millisecond,
+			SlippageBps:    cfg.Paper.SlippageBps,
+			PartialFillPct: cfg.Paper.PartialFillPct,
+		}
+	}
+
+	// 转换币种覆盖配置（key统一标准化为USDT交易对）
+	if len(symbolOverrides) > 0 {
+		traderConfig.SymbolOverrides = make(map[string]decision.SymbolOverride, len(symbolOverrides))
+		for symbol, o := range symbolOverrides {
+			traderConfig.SymbolOverrides[market.Normalize(symbol)] = decision.SymbolOverride{
+				Prompt:           o.Prompt,
+				Leverage:         o.Leverage,
+				MaxPositionRatio: o.MaxPositionRatio,
+				TrendInterval:    o.TrendInterval,
+				EntryInterval:    o.EntryInterval,
+				DisableOpen:      o.DisableOpen,
+			}
+		}
 	}
 
 	// 创建trader实例
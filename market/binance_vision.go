@@ -0,0 +1,167 @@
+package market
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nofx/utils"
+)
+
+// binanceVisionURL 币安公开数据下载地址（USDⓈ-M永续K线归档）
+const binanceVisionURL = "https://data.binance.vision/data/futures/um"
+
+// ImportBinanceVision 从币安公开数据归档（data.binance.vision）批量导入[from, 昨天]的K线，返回新增数量
+// 已结束的月份使用月度ZIP，本月使用日度ZIP；只追加晚于本地最后一根的K线，尚未发布的归档会被跳过
+// 导入后可调用Sync通过REST补齐最新的K线
+func (s *HistoryStore) ImportBinanceVision(symbol, interval string, from time.Time) (int, error) {
+	symbol = Normalize(symbol)
+	if _, err := IntervalDuration(interval); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.load(symbol, interval)
+	if err != nil {
+		return 0, err
+	}
+	var last int64 = -1
+	if len(existing) > 0 {
+		last = existing[len(existing)-1].OpenTime
+		if t := time.UnixMilli(last).UTC(); t.After(from) {
+			from = t
+		}
+	}
+
+	added := 0
+	for _, url := range binanceVisionArchives(symbol, interval, from.UTC(), time.Now().UTC()) {
+		klines, err := downloadBinanceVision(url)
+		if err != nil {
+			return added, err
+		}
+		if klines == nil {
+			log.Printf("  ⚠ 归档尚未发布，跳过: %s", url)
+			continue
+		}
+
+		fresh := klines[:0]
+		for _, k := range klines {
+			if k.OpenTime > last {
+				fresh = append(fresh, k)
+				last = k.OpenTime
+			}
+		}
+		if err := s.append(symbol, interval, fresh); err != nil {
+			return added, err
+		}
+		added += len(fresh)
+	}
+
+	log.Printf("✓ 公开数据导入完成: %s %s 新增%d根（共%d根）", symbol, interval, added, len(existing)+added)
+	return added, nil
+}
+
+// binanceVisionArchives 生成[from, now)需要下载的归档地址（按时间顺序）
+func binanceVisionArchives(symbol, interval string, from, now time.Time) []string {
+	base := fmt.Sprintf("%s/%%s/klines/%s/%s/%s-%s-%%s.zip", binanceVisionURL, symbol, interval, symbol, interval)
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var urls []string
+	month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for ; month.Before(thisMonth); month = month.AddDate(0, 1, 0) {
+		urls = append(urls, fmt.Sprintf(base, "monthly", month.Format("2006-01")))
+	}
+
+	day := month
+	if from.After(day) {
+		day = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	for ; day.Before(today); day = day.AddDate(0, 0, 1) {
+		urls = append(urls, fmt.Sprintf(base, "daily", day.Format("2006-01-02")))
+	}
+	return urls
+}
+
+// downloadBinanceVision 下载并解析单个K线ZIP归档（404时返回nil, nil）
+func downloadBinanceVision(url string) ([]Kline, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("下载%s失败: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载%s失败: HTTP %d", url, resp.StatusCode)
+	}
+
+	body, err := utils.ReadBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("下载%s失败: %w", url, err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("解压%s失败: %w", url, err)
+	}
+
+	var klines []Kline
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("解压%s失败: %w", url, err)
+		}
+		parsed, err := parseBinanceVisionCSV(utils.LimitBody(rc)) // 限制解压后的大小，防止压缩炸弹
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析%s失败: %w", url, err)
+		}
+		klines = append(klines, parsed...)
+	}
+	return klines, nil
+}
+
+// parseBinanceVisionCSV 解析归档CSV（open_time,open,high,low,close,volume,close_time,...）
+// 较新的归档带表头行；时间戳为微秒的归档会被转换为毫秒
+func parseBinanceVisionCSV(r io.Reader) ([]Kline, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	var klines []Kline
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 7 {
+			return nil, fmt.Errorf("字段数量不足: %v", record)
+		}
+		if _, err := strconv.ParseInt(record[0], 10, 64); err != nil {
+			continue // 表头
+		}
+
+		k, err := parseKlineRecord(record[:7])
+		if err != nil {
+			return nil, err
+		}
+		if k.OpenTime > 1e14 {
+			k.OpenTime /= 1000
+			k.CloseTime /= 1000
+		}
+		klines = append(klines, k)
+	}
+	return klines, nil
+}
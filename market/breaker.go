@@ -0,0 +1,190 @@
+package market
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"nofx/events"
+)
+
+// 接口熔断参数
+const (
+	circuitFailureThreshold = 5                // 同一接口连续失败多少次后熔断
+	circuitBaseCooldown     = 30 * time.Second // 熔断后首次探测前的等待时间
+	circuitMaxCooldown      = 5 * time.Minute  // 探测连续失败时等待时间翻倍的上限
+)
+
+// 熔断状态
+const (
+	CircuitClosed   = "closed"    // 正常
+	CircuitOpen     = "open"      // 熔断中，请求直接失败
+	CircuitHalfOpen = "half-open" // 探测中，只放行一个请求
+)
+
+// ErrCircuitOpen 接口熔断中（请求未发送）
+var ErrCircuitOpen = errors.New("接口熔断中")
+
+// circuitBreaker 单个接口的熔断器：连续失败达到阈值后熔断，冷却结束后放行一个探测请求，
+// 探测成功恢复正常，失败则冷却时间翻倍后再探测
+type circuitBreaker struct {
+	state     string
+	failures  int
+	cooldown  time.Duration
+	openedAt  time.Time
+	probing   bool
+	lastError string
+}
+
+// CircuitStatus 接口熔断器的状态
+type CircuitStatus struct {
+	Endpoint  string     `json:"endpoint"`
+	State     string     `json:"state"`
+	Failures  int        `json:"failures"`
+	RetryAt   *time.Time `json:"retry_at,omitempty"` // 下次探测时间（熔断中）
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// CircuitBreakers 按接口（如openInterest、premiumIndex、klines）熔断，避免持续请求故障接口拖慢整个数据流程
+type CircuitBreakers struct {
+	name string
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// BinanceCircuits 币安合约行情接口的熔断器（交易客户端不熔断，订单请求始终发送）
+var BinanceCircuits = NewCircuitBreakers("币安合约")
+
+// NewCircuitBreakers 创建按接口熔断的熔断器组
+func NewCircuitBreakers(name string) *CircuitBreakers {
+	return &CircuitBreakers{name: name, breakers: make(map[string]*circuitBreaker)}
+}
+
+// allow 是否放行该接口的请求，熔断冷却结束时转为探测状态并放行一个请求
+func (c *CircuitBreakers) allow(endpoint string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.breakers[endpoint]
+	if b == nil || b.state == CircuitClosed {
+		return nil
+	}
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.cooldown {
+		b.state = CircuitHalfOpen
+		b.probing = false
+	}
+	if b.state == CircuitHalfOpen && !b.probing {
+		b.probing = true
+		log.Printf("🔌 %s %s 熔断冷却结束，发送探测请求", c.name, endpoint)
+		return nil
+	}
+	return fmt.Errorf("%w: %s %s（连续失败%d次，最后错误: %s）", ErrCircuitOpen, c.name, endpoint, b.failures, b.lastError)
+}
+
+// record 记录请求结果：正常状态连续失败达到阈值时熔断，探测失败时延长冷却，成功时恢复
+func (c *CircuitBreakers) record(endpoint string, err error) {
+	c.mu.Lock()
+	b := c.breakers[endpoint]
+	if b == nil {
+		if err == nil {
+			c.mu.Unlock()
+			return
+		}
+		b = &circuitBreaker{state: CircuitClosed}
+		c.breakers[endpoint] = b
+	}
+
+	var message, severity, state string
+	switch {
+	case err == nil && b.state == CircuitClosed:
+		b.failures = 0
+	case err == nil:
+		message = fmt.Sprintf("%s %s 探测成功，解除熔断", c.name, endpoint)
+		severity, state = events.SeverityInfo, CircuitClosed
+		*b = circuitBreaker{state: CircuitClosed}
+	case b.state == CircuitHalfOpen:
+		b.failures++
+		b.lastError = err.Error()
+		b.cooldown = min(b.cooldown*2, circuitMaxCooldown)
+		b.state, b.openedAt, b.probing = CircuitOpen, time.Now(), false
+		log.Printf("🔌 %s %s 探测失败，%v后再次探测: %v", c.name, endpoint, b.cooldown, err)
+	default:
+		b.failures++
+		b.lastError = err.Error()
+		if b.state == CircuitClosed && b.failures >= circuitFailureThreshold {
+			b.cooldown = circuitBaseCooldown
+			b.state, b.openedAt = CircuitOpen, time.Now()
+			message = fmt.Sprintf("%s %s 连续%d次请求失败，熔断%v（期间使用缓存数据）: %v", c.name, endpoint, b.failures, b.cooldown, err)
+			severity, state = events.SeverityWarning, CircuitOpen
+		}
+	}
+	c.mu.Unlock()
+
+	if message == "" {
+		return
+	}
+	log.Printf("🔌 %s", message)
+	events.Publish(events.Event{
+		Type:     events.TypeCircuitBreaker,
+		Severity: severity,
+		Message:  message,
+		Data: map[string]interface{}{
+			"service":  c.name,
+			"endpoint": endpoint,
+			"state":    state,
+		},
+	})
+}
+
+// Status 全部出现过失败的接口的熔断状态
+func (c *CircuitBreakers) Status() []CircuitStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make([]CircuitStatus, 0, len(c.breakers))
+	for endpoint, b := range c.breakers {
+		status := CircuitStatus{Endpoint: endpoint, State: b.state, Failures: b.failures, LastError: b.lastError}
+		if b.state == CircuitOpen {
+			retryAt := b.openedAt.Add(b.cooldown)
+			status.RetryAt = &retryAt
+		}
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Endpoint < result[j].Endpoint })
+	return result
+}
+
+// Transport 返回熔断中间件：熔断中的接口直接返回ErrCircuitOpen（不消耗请求权重），
+// 网络错误和5xx响应计为失败。base为nil时使用http.DefaultTransport
+func (c *CircuitBreakers) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &circuitTransport{breakers: c, base: base}
+}
+
+// circuitTransport 按接口熔断
+type circuitTransport struct {
+	breakers *CircuitBreakers
+	base     http.RoundTripper
+}
+
+func (t *circuitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointName(req.URL.Path)
+	if err := t.breakers.allow(endpoint); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil:
+		t.breakers.record(endpoint, err)
+	case resp.StatusCode >= 500:
+		t.breakers.record(endpoint, fmt.Errorf("HTTP %d", resp.StatusCode))
+	default:
+		t.breakers.record(endpoint, nil)
+	}
+	return resp, err
+}
@@ -0,0 +1,133 @@
+package market
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"nofx/utils"
+)
+
+// 原始响应记录上限
+const (
+	payloadMaxBody  = 2 << 20  // 单个响应超过该大小时只记录大小（不保存内容）
+	payloadMaxTotal = 64 << 20 // 缓冲区保存的响应总大小，超出时丢弃最早的记录
+)
+
+// CapturedPayload 记录的原始REST响应
+type CapturedPayload struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"` // 签名请求去掉signature参数
+	Status    int       `json:"status"`
+	Size      int       `json:"size"`
+	Body      string    `json:"body,omitempty"`
+	Truncated bool      `json:"truncated,omitempty"` // 响应过大未保存内容
+}
+
+// PayloadRecorder 原始REST响应的内存缓冲区（用于决策快照复盘“决策时收到了什么数据”），默认关闭
+type PayloadRecorder struct {
+	mu       sync.Mutex
+	enabled  bool
+	payloads []CapturedPayload
+	total    int
+}
+
+// BinancePayloads 币安合约REST响应记录（行情请求和交易客户端共用）
+var BinancePayloads = &PayloadRecorder{}
+
+// Enable 开始记录响应
+func (r *PayloadRecorder) Enable() {
+	r.mu.Lock()
+	r.enabled = true
+	r.mu.Unlock()
+}
+
+// Since 返回from之后（含）收到的响应，按时间从旧到新
+func (r *PayloadRecorder) Since(from time.Time) []CapturedPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []CapturedPayload
+	for i := len(r.payloads) - 1; i >= 0 && !r.payloads[i].Time.Before(from); i-- {
+		result = append(result, r.payloads[i])
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+func (r *PayloadRecorder) add(p CapturedPayload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloads = append(r.payloads, p)
+	r.total += len(p.Body)
+	drop := 0
+	for r.total > payloadMaxTotal && drop < len(r.payloads)-1 {
+		r.total -= len(r.payloads[drop].Body)
+		drop++
+	}
+	if drop > 0 {
+		r.payloads = append(r.payloads[:0:0], r.payloads[drop:]...)
+	}
+}
+
+func (r *PayloadRecorder) isEnabled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enabled
+}
+
+// Transport 返回记录中间件，应放在最外层（记录调用方实际收到的响应，包括缓存命中）
+// 未启用时直接转发。base为nil时使用http.DefaultTransport
+func (r *PayloadRecorder) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &payloadTransport{recorder: r, base: base}
+}
+
+// payloadTransport 记录响应内容
+type payloadTransport struct {
+	recorder *PayloadRecorder
+	base     http.RoundTripper
+}
+
+func (t *payloadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.recorder.isEnabled() {
+		return t.base.RoundTrip(req)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := utils.ReadBody(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	url := *req.URL
+	if query := url.Query(); query.Has("signature") {
+		query.Del("signature")
+		url.RawQuery = query.Encode()
+	}
+	payload := CapturedPayload{
+		Time:   time.Now(),
+		Method: req.Method,
+		URL:    url.String(),
+		Status: resp.StatusCode,
+		Size:   len(body),
+	}
+	if len(body) > payloadMaxBody {
+		payload.Truncated = true
+	} else {
+		payload.Body = string(body)
+	}
+	t.recorder.add(payload)
+	return resp, nil
+}
@@ -0,0 +1,85 @@
+package market
+
+import (
+	"sync"
+	"time"
+)
+
+// klineSettleDelay K线收盘后等待交易所生成最终K线的时间
+const klineSettleDelay = 2 * time.Second
+
+// KlineClock 按交易所时间计算K线边界
+// 币安等交易所的K线边界按UTC对齐（15m K线在UTC整点+0/15/30/45分收盘），
+// 与本机时区无关；Offset用于校正本机时钟与交易所服务器时间的偏差
+type KlineClock struct {
+	mu       sync.RWMutex
+	location *time.Location   // K线边界对齐的时区（默认UTC）
+	offset   time.Duration    // 交易所时间 - 本机时间
+	now      func() time.Time // 当前时间（可注入，默认time.Now）
+}
+
+// Clock 全局K线时钟
+var Clock = NewKlineClock(time.UTC)
+
+// NewKlineClock 创建K线时钟（location为nil时使用UTC）
+func NewKlineClock(location *time.Location) *KlineClock {
+	if location == nil {
+		location = time.UTC
+	}
+	return &KlineClock{location: location, now: time.Now}
+}
+
+// SetLocation 设置K线边界对齐的时区（交易所按非UTC时区切分日线等周期时使用）
+func (c *KlineClock) SetLocation(location *time.Location) {
+	if location == nil {
+		location = time.UTC
+	}
+	c.mu.Lock()
+	c.location = location
+	c.mu.Unlock()
+}
+
+// SetOffset 设置交易所时间相对本机时间的偏差（交易所时间 - 本机时间）
+func (c *KlineClock) SetOffset(offset time.Duration) {
+	c.mu.Lock()
+	c.offset = offset
+	c.mu.Unlock()
+}
+
+// SetNow 注入当前时间函数（nil恢复为time.Now）
+func (c *KlineClock) SetNow(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	c.mu.Lock()
+	c.now = now
+	c.mu.Unlock()
+}
+
+// Now 当前交易所时间（位于K线对齐时区）
+func (c *KlineClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now().Add(c.offset).In(c.location)
+}
+
+// CandleStart 计算t所在K线的开盘时间（按对齐时区的午夜起算，interval需能整除一天）
+func (c *KlineClock) CandleStart(t time.Time, interval time.Duration) time.Time {
+	c.mu.RLock()
+	location := c.location
+	c.mu.RUnlock()
+
+	t = t.In(location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+	return midnight.Add(t.Sub(midnight) / interval * interval)
+}
+
+// LastClose 最近一根已收盘K线的收盘时间（即当前K线的开盘时间）
+func (c *KlineClock) LastClose(interval time.Duration) time.Time {
+	return c.CandleStart(c.Now(), interval)
+}
+
+// CandleCompleted 最近一根K线收盘后是否已过settle时间（交易所已生成最终K线）
+func (c *KlineClock) CandleCompleted(interval time.Duration, settle time.Duration) bool {
+	return c.Now().Sub(c.LastClose(interval)) >= settle
+}
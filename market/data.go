@@ -1,14 +1,14 @@
 package market
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"math"
-	"net/http"
+	"log"
 	"strconv"
 	"strings"
 	"time"
+
+	"nofx/symbols"
+	"nofx/utils"
 )
 
 // Data 市场数据结构
@@ -19,28 +19,126 @@ type Data struct {
 	PriceChange4h     float64 // 4小时价格变化百分比
 	OpenInterest      *OIData
 	FundingRate       float64
+	FundingInterval   int       // 资金费结算周期（小时，币安8h，Hyperliquid 1h）
+	NextFundingTime   time.Time // 下次资金费结算时间（现货数据源为零值）
 	LongerTermContext *LongerTermData
-	MA21_4h           float64   // 4小时MA21
-	MA21_4hSeries     []float64 // 4小时MA21序列（最近3个，用于趋势判断）
-	MA15_15m          float64   // 15分钟MA15
+	Options           *OptionsData    // 期权概要（可选，未启用或无期权市场时为nil）
+	TrendMA           float64         // 趋势周期均线（默认4小时MA21，周期见TrendInterval和Indicators.TrendMA）
+	TrendMASeries     []float64       // 趋势周期均线序列（最近3个，用于趋势判断）
+	EntryMA           float64         // 入场周期均线（默认15分钟MA15，周期见EntryInterval和Indicators.EntryMA）
+	TrendInterval     string          // 趋势判断K线周期（默认4h）
+	EntryInterval     string          // 入场信号K线周期（默认15m）
+	Indicators        IndicatorConfig // 计算指标使用的周期
+
+	// 综合强度评分（0~100，50为中性，越高越偏多头强势；权重可通过SetStrengthWeights配置）
+	StrengthScore float64
+
+	// 趋势周期最近的收盘价（最多correlationBars+1个，用于计算币种间相关性）
+	TrendCloses []float64
+
+	// 趋势周期最近10根K线内的指标交叉（EMA20/EMA50、价格/MA21、MACD/信号线），按时间从旧到新
+	Crossovers []CrossoverEvent
+
+	// 上市/下架状态
+	HistoryAvailableBars int       // 可用的已完成趋势周期K线数量（不足MinHistoryBars视为新上市）
+	ListedAt             time.Time // 上线时间（未知为零值）
+	DelistAt             time.Time // 计划下架时间（无计划为零值）
+	Delisted             bool      // 已下架/停止交易
+
+	// 可用K线不足预热需求的指标（这些指标的值为0或尚未收敛，见IndicatorConfig.Warmup）
+	Warnings []string
+
+	// 数据不足、无法计算的均线（"trend_ma"、"entry_ma"），对应字段的值为0；长期指标见LongerTermData.Unavailable
+	Unavailable map[string]bool
+}
+
+// Available 均线是否计算出了有效值（"trend_ma"对应TrendMA，"entry_ma"对应EntryMA）
+func (d *Data) Available(indicator string) bool {
+	return !d.Unavailable[indicator]
+}
+
+// MinHistoryBars 指标可靠所需的最少趋势周期K线数（EMA50需要50根）
+const MinHistoryBars = 50
+
+// IsNewListing 是否为新上市币种（历史K线不足，长期指标不可靠）
+func (d *Data) IsNewListing() bool {
+	return d.HistoryAvailableBars < MinHistoryBars
+}
+
+// FundingWithin 下次资金费结算是否在指定时间内（未知结算时间时返回false）
+func (d *Data) FundingWithin(window time.Duration) bool {
+	if d.NextFundingTime.IsZero() {
+		return false
+	}
+	until := d.NextFundingTime.Sub(Clock.Now())
+	return until >= 0 && until < window
+}
+
+// DelistingWithin 是否已下架或将在指定时间内下架
+func (d *Data) DelistingWithin(window time.Duration) bool {
+	if d.Delisted {
+		return true
+	}
+	return !d.DelistAt.IsZero() && time.Until(d.DelistAt) < window
 }
 
 // OIData Open Interest数据
 type OIData struct {
 	Latest  float64
-	Average float64
+	Average float64 // 0表示数据源不提供（如Hyperliquid）
 }
 
 // LongerTermData 长期数据(4小时时间框架)
+// EMA20/EMA50/ATR3/ATR14/MACDValues/RSI14Values按IndicatorConfig配置的周期计算，字段名为默认周期
 type LongerTermData struct {
-	EMA20         float64
-	EMA50         float64
-	ATR3          float64
-	ATR14         float64
+	EMA20         float64 // 快速EMA
+	EMA50         float64 // 慢速EMA
+	ATR3          float64 // 短期ATR
+	ATR14         float64 // 长期ATR
+	ATR3Pct       float64 // ATR占价格百分比（跨币种可比）
+	ATR14Pct      float64
 	CurrentVolume float64
 	AverageVolume float64
 	MACDValues    []float64
 	RSI14Values   []float64
+
+	// 通道指标
+	KeltnerUpper   float64 // Keltner上轨（EMA20 + 1.5×ATR10）
+	KeltnerMiddle  float64
+	KeltnerLower   float64
+	DonchianUpper  float64 // Donchian上轨（20根K线最高价）
+	DonchianLower  float64 // Donchian下轨（20根K线最低价）
+	BollingerUpper float64 // 布林带上轨（SMA20 + 2σ）
+	BollingerLower float64
+	Squeeze        bool // 布林带收窄至Keltner通道内（波动率压缩，常见突破前兆）
+
+	WilliamsR14 float64 // 威廉指标（-100~0，高于-20超买，低于-80超卖）
+	CCI20       float64 // 顺势指标（高于+100超买，低于-100超卖）
+
+	ParabolicSAR float64 // 抛物线转向指标（下一根K线的SAR值）
+	SARUptrend   bool    // SAR位于价格下方（多头趋势）
+
+	MFI14 float64 // 资金流量指标（成交量加权RSI，高于80超买，低于20超卖）
+	CMF20 float64 // 蔡金资金流（-1~1，正值买盘主导）
+
+	Momentum []MomentumSeries // 各周期变化率/动量序列（周期可通过SetMomentumPeriods配置）
+
+	Regression *RegressionChannel // 最近20根收盘价的线性回归（斜率、R²、通道），数据不足为nil
+
+	Hurst         float64 // Hurst指数（>0.55趋势，<0.45均值回归）
+	VarianceRatio float64 // 方差比VR(4)（>1趋势，<1均值回归）
+
+	RealizedVolDaily  float64 // 收盘价已实现波动率（日，%）
+	RealizedVolAnnual float64 // 收盘价已实现波动率（年化，%）
+
+	// 数据不足或无定义、无法计算的指标（键同IndicatorConfig.Warmup，另有parabolic_sar、realized_vol），
+	// 这些字段的值为0，不是真实读数，使用前应先检查Available
+	Unavailable map[string]bool
+}
+
+// Available 指标是否计算出了有效值（键如"ema_slow"、"hurst"，见Unavailable）
+func (d *LongerTermData) Available(indicator string) bool {
+	return !d.Unavailable[indicator]
 }
 
 // Kline K线数据
@@ -60,88 +158,203 @@ type BinanceError struct {
 	Msg  string `json:"msg"`
 }
 
-// Get 获取指定代币的市场数据
+const (
+	// DefaultTrendInterval 默认趋势判断K线周期
+	DefaultTrendInterval = "4h"
+	// DefaultEntryInterval 默认入场信号K线周期
+	DefaultEntryInterval = "15m"
+)
+
+// Get 获取指定代币的市场数据（4小时趋势 + 15分钟入场）
 func Get(symbol string) (*Data, error) {
+	return GetWithIntervals(symbol, DefaultTrendInterval, DefaultEntryInterval)
+}
+
+// GetWithIntervals 使用指定的趋势/入场K线周期获取市场数据
+// 用于按币种覆盖时间框架（例如小市值山寨币使用1h趋势 + 5m入场）
+func GetWithIntervals(symbol, trendInterval, entryInterval string) (*Data, error) {
+	return GetWithConfig(symbol, trendInterval, entryInterval, indicatorConfig)
+}
+
+// GetWithConfig 使用指定的K线周期和指标周期获取市场数据（cfg未设置的字段使用默认周期）
+func GetWithConfig(symbol, trendInterval, entryInterval string, cfg IndicatorConfig) (*Data, error) {
 	// 标准化symbol
 	symbol = Normalize(symbol)
 
-	// 获取4小时K线数据 (最近10个)
-	klines4h, err := getKlines(symbol, "4h", 60) // 多获取用于计算指标
+	if trendInterval == "" {
+		trendInterval = DefaultTrendInterval
+	}
+	if entryInterval == "" {
+		entryInterval = DefaultEntryInterval
+	}
+	cfg = cfg.withDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	trendDuration, err := IntervalDuration(trendInterval)
+	if err != nil {
+		return nil, err
+	}
+	entryDuration, err := IntervalDuration(entryInterval)
 	if err != nil {
-		return nil, fmt.Errorf("获取4小时K线失败: %v", err)
+		return nil, err
 	}
-	// 过滤掉未走完的4小时K线
-	klines4h = filterCompletedKlines(klines4h)
 
-	// 获取15分钟K线数据 (用于计算MA15和当前价格)
-	klines15m, err := getKlines(symbol, "15m", 40)
+	provider := GetProvider()
+
+	// 获取趋势周期K线数据
+	trendKlines, err := provider.GetKlines(symbol, trendInterval, cfg.trendBars()) // 多获取用于计算指标
 	if err != nil {
-		return nil, fmt.Errorf("获取15分钟K线失败: %v", err)
+		return nil, fmt.Errorf("获取%s K线失败: %v", trendInterval, err)
 	}
-	// 过滤掉未走完的15分钟K线
-	klines15m = filterCompletedKlines(klines15m)
+	// 过滤掉未走完的趋势周期K线
+	trendKlines = filterCompletedKlines(trendKlines)
 
-	// 计算当前指标 (基于15分钟最新数据)
-	currentPrice := klines15m[len(klines15m)-1].Close
+	// 获取入场周期K线数据 (用于计算MA15和当前价格)
+	entryKlines, err := provider.GetKlines(symbol, entryInterval, cfg.entryBars())
+	if err != nil {
+		return nil, fmt.Errorf("获取%s K线失败: %v", entryInterval, err)
+	}
+	// 过滤掉未走完的入场周期K线
+	entryKlines = filterCompletedKlines(entryKlines)
+	if isStale(entryKlines) && freshnessRefetch {
+		log.Printf("⚠️  %s 最新%s K线过旧（%v），重新获取", symbol, entryInterval, klinesAge(entryKlines).Round(time.Second))
+		if refetched, err := provider.GetKlines(symbol, entryInterval, cfg.entryBars()); err == nil {
+			entryKlines = filterCompletedKlines(refetched)
+		}
+	}
+	if isStale(entryKlines) {
+		return nil, fmt.Errorf("%w: %s 最新已完成%s K线收盘于%v前（上限%v）",
+			ErrStaleData, symbol, entryInterval, klinesAge(entryKlines).Round(time.Second), freshnessMaxAge)
+	}
+	if len(entryKlines) == 0 {
+		return nil, fmt.Errorf("%s 没有已完成的%s K线", symbol, entryInterval)
+	}
+
+	// 计算当前指标 (基于入场周期最新数据)
+	currentPrice := entryKlines[len(entryKlines)-1].Close
 
 	// 计算价格变化百分比
-	// 1小时价格变化 = 4个15分钟K线前的价格
+	// 1小时价格变化 = 1小时前（默认4个15分钟K线前）的价格
 	priceChange1h := 0.0
-	if len(klines15m) >= 5 { // 至少需要5根K线 (当前 + 4根前)
-		price1hAgo := klines15m[len(klines15m)-5].Close
-		if price1hAgo > 0 {
+	if barsAgo := barsInDuration(time.Hour, entryDuration); barsAgo > 0 {
+		if price1hAgo, ok := closes(entryKlines).Ago(barsAgo); ok && price1hAgo > 0 {
 			priceChange1h = ((currentPrice - price1hAgo) / price1hAgo) * 100
 		}
 	}
 
-	// 4小时价格变化 = 1个4小时K线前的价格
+	// 4小时价格变化 = 4小时前（默认1个4小时K线前）的价格
 	priceChange4h := 0.0
-	if len(klines4h) >= 2 {
-		price4hAgo := klines4h[len(klines4h)-2].Close
-		if price4hAgo > 0 {
+	if barsAgo := barsInDuration(4*time.Hour, trendDuration); barsAgo > 0 {
+		if price4hAgo, ok := closes(trendKlines).Ago(barsAgo); ok && price4hAgo > 0 {
 			priceChange4h = ((currentPrice - price4hAgo) / price4hAgo) * 100
 		}
 	}
 
-	// 获取OI数据
-	oiData, err := getOpenInterestData(symbol)
-	if err != nil {
-		// OI失败不影响整体,使用默认值
-		oiData = &OIData{Latest: 0, Average: 0}
+	// 获取OI和Funding Rate（仅永续合约数据源提供）
+	var oiData *OIData
+	var fundingRate float64
+	var fundingIntervalHours int
+	var nextFunding time.Time
+	if derivatives, ok := provider.(DerivativesProvider); ok {
+		// 启用自适应轮询时按币种活跃度刷新，未到刷新时间返回缓存
+		oiData, fundingRate, fundingIntervalHours, nextFunding = Hub.pollDerivatives(provider, derivatives, symbol)
+		Hub.observePositioning(symbol, oiData, fundingRate)
 	}
 
-	// 获取Funding Rate
-	fundingRate, _ := getFundingRate(symbol)
+	// 获取期权概要（可选，失败不影响整体）
+	optionsData, err := GetOptionsData(symbol)
+	if err != nil {
+		log.Printf("⚠️  获取%s期权数据失败: %v", symbol, err)
+		optionsData = nil
+	}
 
 	// 计算长期数据
-	longerTermData := calculateLongerTermData(klines4h)
-
-	// 计算MA21_4h (4小时21期简单移动平均线)
-	ma21_4h := calculateSMA(klines4h, 21)
+	longerTermData := calculateLongerTermData(trendKlines, cfg)
+	Hub.ReportActivity(symbol, activityScore(entryKlines, longerTermData))
+
+	// 计算趋势均线 (默认4小时21期简单移动平均线)
+	unavailable := make(map[string]bool)
+	trendMA, ok := calculateSMA(trendKlines, cfg.TrendMA)
+	if !ok {
+		unavailable["trend_ma"] = true
+	}
+
+	// 计算趋势均线序列（最近3个值，用于趋势判断）
+	trendMASeries := make([]float64, 0, 3)
+	if ma := Window(closes(trendKlines), cfg.TrendMA, Mean); len(ma) >= 3 {
+		trendMASeries = append(trendMASeries, ma.Tail(3)...)
+	}
+
+	// 计算入场均线 (默认15分钟15期简单移动平均线)
+	entryMA, ok := calculateSMA(entryKlines, cfg.EntryMA)
+	if !ok {
+		unavailable["entry_ma"] = true
+	}
+
+	// 上市/下架信息（来自币种注册表）
+	instrument, _ := symbols.Get(symbols.Canonical(symbol), provider.Name())
+
+	data := &Data{
+		Symbol:               symbol,
+		CurrentPrice:         currentPrice,
+		PriceChange1h:        priceChange1h,
+		PriceChange4h:        priceChange4h,
+		OpenInterest:         oiData,
+		FundingRate:          fundingRate,
+		FundingInterval:      fundingIntervalHours,
+		NextFundingTime:      nextFunding,
+		LongerTermContext:    longerTermData,
+		Options:              optionsData,
+		TrendMA:              trendMA,
+		TrendMASeries:        trendMASeries,
+		EntryMA:              entryMA,
+		TrendInterval:        trendInterval,
+		EntryInterval:        entryInterval,
+		Indicators:           cfg,
+		TrendCloses:          recentCloses(trendKlines, correlationBars+1),
+		Crossovers:           detectCrossovers(trendKlines, crossoverLookback, cfg),
+		HistoryAvailableBars: len(trendKlines),
+		ListedAt:             instrument.ListedAt,
+		DelistAt:             instrument.DelistAt,
+		Delisted:             instrument.Delisted(),
+		Warnings:             cfg.warmupWarnings(len(trendKlines), len(entryKlines), trendInterval, entryInterval),
+		Unavailable:          unavailable,
+	}
+	data.StrengthScore = calculateStrengthScore(data, strengthWeights)
+
+	return data, nil
+}
 
-	// 计算MA21_4h序列（最近3个值，用于趋势判断）
-	ma21_4hSeries := make([]float64, 0, 3)
-	if len(klines4h) >= 23 { // 至少需要23根K线来计算3个MA21值
-		for i := len(klines4h) - 3; i < len(klines4h); i++ {
-			ma21_4hSeries = append(ma21_4hSeries, calculateSMA(klines4h[:i+1], 21))
-		}
+// IntervalDuration 将K线周期字符串（如"15m"、"4h"、"1d"）转换为时长
+func IntervalDuration(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("无效的K线周期: %s", interval)
+	}
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("无效的K线周期: %s", interval)
+	}
+	switch interval[len(interval)-1] {
+	case 'm':
+		return time.Duration(n) * time.Minute, nil
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("无效的K线周期: %s", interval)
 	}
+}
 
-	// 计算MA15_15m (15分钟15期简单移动平均线)
-	ma15_15m := calculateSMA(klines15m, 15)
-
-	return &Data{
-		Symbol:            symbol,
-		CurrentPrice:      currentPrice,
-		PriceChange1h:     priceChange1h,
-		PriceChange4h:     priceChange4h,
-		OpenInterest:      oiData,
-		FundingRate:       fundingRate,
-		LongerTermContext: longerTermData,
-		MA21_4h:           ma21_4h,
-		MA21_4hSeries:     ma21_4hSeries,
-		MA15_15m:          ma15_15m,
-	}, nil
+// barsInDuration 计算一段时长包含多少根指定周期的K线（不能整除时返回0）
+func barsInDuration(window, interval time.Duration) int {
+	if interval <= 0 || window < interval || window%interval != 0 {
+		return 0
+	}
+	return int(window / interval)
 }
 
 // getKlines 从Binance获取K线数据
@@ -149,194 +362,113 @@ func getKlines(symbol, interval string, limit int) ([]Kline, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&limit=%d",
 		symbol, interval, limit)
 
-	resp, err := http.Get(url)
+	resp, err := binanceHTTPClient.Get(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	return decodeKlines(utils.LimitBody(resp.Body), limit)
+}
+
+// getKlinesRange 从Binance获取startTime（毫秒）开始的K线数据（用于分页下载历史）
+func getKlinesRange(symbol, interval string, startTime int64, limit int) ([]Kline, error) {
+	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&startTime=%d&limit=%d",
+		symbol, interval, startTime, limit)
+
+	resp, err := binanceHTTPClient.Get(url)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	// Check if response is an error object first
-	var binanceErr BinanceError
-	if err := json.Unmarshal(body, &binanceErr); err == nil && binanceErr.Code != 0 {
-		return nil, fmt.Errorf("Binance API Error %d: %s", binanceErr.Code, binanceErr.Msg)
-	}
-
-	// Parse klines data if not an error
-	var rawData [][]interface{}
-	if err := json.Unmarshal(body, &rawData); err != nil {
-		return nil, fmt.Errorf("Failed to parse klines data: %v", err)
-	}
-
-	klines := make([]Kline, len(rawData))
-	for i, item := range rawData {
-		openTime := int64(item[0].(float64))
-		open, _ := parseFloat(item[1])
-		high, _ := parseFloat(item[2])
-		low, _ := parseFloat(item[3])
-		close, _ := parseFloat(item[4])
-		volume, _ := parseFloat(item[5])
-		closeTime := int64(item[6].(float64))
-
-		klines[i] = Kline{
-			OpenTime:  openTime,
-			Open:      open,
-			High:      high,
-			Low:       low,
-			Close:     close,
-			Volume:    volume,
-			CloseTime: closeTime,
-		}
-	}
-
-	return klines, nil
+	return decodeKlines(utils.LimitBody(resp.Body), limit)
 }
 
-// calculateEMA 计算EMA
-func calculateEMA(klines []Kline, period int) float64 {
-	if len(klines) < period {
-		return 0
-	}
-
-	// 计算SMA作为初始EMA
-	sum := 0.0
-	for i := 0; i < period; i++ {
-		sum += klines[i].Close
-	}
-	ema := sum / float64(period)
+// 指标计算函数在数据不足（或结果无定义）时返回ok=false，不用0表示"无法计算"
 
-	// 计算EMA
-	multiplier := 2.0 / float64(period+1)
-	for i := period; i < len(klines); i++ {
-		ema = (klines[i].Close-ema)*multiplier + ema
+// calculateEMA 计算EMA（以前period根K线的SMA作为初始EMA）
+func calculateEMA(klines []Kline, period int) (float64, bool) {
+	if period <= 0 || len(klines) < period {
+		return 0, false
 	}
-
-	return ema
+	return emaValues(closes(klines), period).Ago(0)
 }
 
 // calculateSMA 计算简单移动平均线(Simple Moving Average)
-func calculateSMA(klines []Kline, period int) float64 {
-	if len(klines) < period {
-		return 0
-	}
-
-	sum := 0.0
-	for i := len(klines) - period; i < len(klines); i++ {
-		sum += klines[i].Close
+func calculateSMA(klines []Kline, period int) (float64, bool) {
+	if period <= 0 || len(klines) < period {
+		return 0, false
 	}
-	return sum / float64(period)
+	return Mean(closes(klines).Tail(period)), true
 }
 
-// calculateMACD 计算MACD
-func calculateMACD(klines []Kline) float64 {
-	if len(klines) < 26 {
-		return 0
+// calculateMACD 计算MACD（默认12/26期）
+func calculateMACD(klines []Kline, fast, slow int) (float64, bool) {
+	// 计算快线和慢线EMA
+	emaFast, fastOK := calculateEMA(klines, fast)
+	emaSlow, slowOK := calculateEMA(klines, slow)
+	if !fastOK || !slowOK {
+		return 0, false
 	}
 
-	// 计算12期和26期EMA
-	ema12 := calculateEMA(klines, 12)
-	ema26 := calculateEMA(klines, 26)
-
-	// MACD = EMA12 - EMA26
-	return ema12 - ema26
+	// MACD = 快线EMA - 慢线EMA
+	return emaFast - emaSlow, true
 }
 
-// calculateRSI 计算RSI
-func calculateRSI(klines []Kline, period int) float64 {
-	if len(klines) <= period {
-		return 0
-	}
-
-	gains := 0.0
-	losses := 0.0
-
-	// 计算初始平均涨跌幅
-	for i := 1; i <= period; i++ {
-		change := klines[i].Close - klines[i-1].Close
-		if change > 0 {
-			gains += change
-		} else {
-			losses += -change
-		}
-	}
-
-	avgGain := gains / float64(period)
-	avgLoss := losses / float64(period)
-
-	// 使用Wilder平滑方法计算后续RSI
-	for i := period + 1; i < len(klines); i++ {
-		change := klines[i].Close - klines[i-1].Close
-		if change > 0 {
-			avgGain = (avgGain*float64(period-1) + change) / float64(period)
-			avgLoss = (avgLoss * float64(period-1)) / float64(period)
-		} else {
-			avgGain = (avgGain * float64(period-1)) / float64(period)
-			avgLoss = (avgLoss*float64(period-1) + (-change)) / float64(period)
-		}
-	}
-
-	if avgLoss == 0 {
-		return 100
+// calculateRSI 计算RSI（Wilder平滑）
+func calculateRSI(klines []Kline, period int) (float64, bool) {
+	if period <= 0 || len(klines) <= period {
+		return 0, false
 	}
-
-	rs := avgGain / avgLoss
-	rsi := 100 - (100 / (1 + rs))
-
-	return rsi
+	return rsiValues(closes(klines), period).Ago(0)
 }
 
-// calculateATR 计算ATR
-func calculateATR(klines []Kline, period int) float64 {
-	if len(klines) <= period {
-		return 0
-	}
-
-	trs := make([]float64, len(klines))
-	for i := 1; i < len(klines); i++ {
-		high := klines[i].High
-		low := klines[i].Low
-		prevClose := klines[i-1].Close
-
-		tr1 := high - low
-		tr2 := math.Abs(high - prevClose)
-		tr3 := math.Abs(low - prevClose)
-
-		trs[i] = math.Max(tr1, math.Max(tr2, tr3))
-	}
-
-	// 计算初始ATR
-	sum := 0.0
-	for i := 1; i <= period; i++ {
-		sum += trs[i]
-	}
-	atr := sum / float64(period)
-
-	// Wilder平滑
-	for i := period + 1; i < len(klines); i++ {
-		atr = (atr*float64(period-1) + trs[i]) / float64(period)
+// calculateATR 计算ATR（真实波幅的Wilder平滑）
+func calculateATR(klines []Kline, period int) (float64, bool) {
+	if period <= 0 || len(klines) <= period {
+		return 0, false
 	}
+	return wilderSmooth(trueRanges(klines), period).Ago(0)
+}
 
-	return atr
+// Compute 使用指定指标周期从K线计算长期指标（用于回测/离线分析，cfg未设置的字段使用默认周期）
+func Compute(klines []Kline, cfg IndicatorConfig) *LongerTermData {
+	return calculateLongerTermData(klines, cfg.withDefaults())
 }
 
 // calculateLongerTermData 计算长期数据
-func calculateLongerTermData(klines []Kline) *LongerTermData {
+func calculateLongerTermData(klines []Kline, cfg IndicatorConfig) *LongerTermData {
 	data := &LongerTermData{
 		MACDValues:  make([]float64, 0, 10),
 		RSI14Values: make([]float64, 0, 10),
+		Unavailable: make(map[string]bool),
+	}
+	var ok bool
+	mark := func(indicator string, ok bool) {
+		if !ok {
+			data.Unavailable[indicator] = true
+		}
 	}
 
 	// 计算EMA
-	data.EMA20 = calculateEMA(klines, 20)
-	data.EMA50 = calculateEMA(klines, 50)
+	data.EMA20, ok = calculateEMA(klines, cfg.EMAFast)
+	mark("ema_fast", ok)
+	data.EMA50, ok = calculateEMA(klines, cfg.EMASlow)
+	mark("ema_slow", ok)
 
 	// 计算ATR
-	data.ATR3 = calculateATR(klines, 3)
-	data.ATR14 = calculateATR(klines, 14)
+	data.ATR3, ok = calculateATR(klines, cfg.ATRFast)
+	mark("atr_fast", ok)
+	data.ATR14, ok = calculateATR(klines, cfg.ATRSlow)
+	mark("atr_slow", ok)
+	if len(klines) > 0 && klines[len(klines)-1].Close > 0 {
+		price := klines[len(klines)-1].Close
+		data.ATR3Pct = data.ATR3 / price * 100
+		data.ATR14Pct = data.ATR14 / price * 100
+	}
+	data.RealizedVolDaily, data.RealizedVolAnnual, ok = calculateRealizedVolatility(klines)
+	mark("realized_vol", ok)
 
 	// 计算成交量
 	if len(klines) > 0 {
@@ -349,22 +481,50 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 		data.AverageVolume = sum / float64(len(klines))
 	}
 
-	// 计算MACD和RSI序列
-	start := len(klines) - 10
-	if start < 0 {
-		start = 0
-	}
-
-	for i := start; i < len(klines); i++ {
-		if i >= 25 {
-			macd := calculateMACD(klines[:i+1])
-			data.MACDValues = append(data.MACDValues, macd)
-		}
-		if i >= 14 {
-			rsi14 := calculateRSI(klines[:i+1], 14)
-			data.RSI14Values = append(data.RSI14Values, rsi14)
-		}
-	}
+	// 计算通道指标和挤压状态
+	data.KeltnerUpper, data.KeltnerMiddle, data.KeltnerLower, ok = calculateKeltner(klines)
+	mark("keltner", ok)
+	data.DonchianUpper, data.DonchianLower, ok = calculateDonchian(klines, channelPeriod)
+	mark("donchian", ok)
+	data.BollingerUpper, _, data.BollingerLower, ok = calculateBollinger(klines, channelPeriod, bollingerStdDev)
+	mark("bollinger", ok)
+	data.Squeeze = data.Available("keltner") && data.Available("bollinger") &&
+		data.BollingerUpper < data.KeltnerUpper && data.BollingerLower > data.KeltnerLower
+
+	// 计算震荡指标
+	data.WilliamsR14, ok = calculateWilliamsR(klines, 14)
+	mark("williams_r", ok)
+	data.CCI20, ok = calculateCCI(klines, 20)
+	mark("cci", ok)
+	data.ParabolicSAR, data.SARUptrend, ok = calculateParabolicSAR(klines)
+	mark("parabolic_sar", ok)
+
+	// 计算成交量加权动量指标
+	data.MFI14, ok = calculateMFI(klines, 14)
+	mark("mfi", ok)
+	data.CMF20, ok = calculateCMF(klines, 20)
+	mark("cmf", ok)
+
+	// 计算变化率/动量序列
+	data.Momentum = calculateMomentumSeries(klines, momentumPeriods, momentumSeriesLength)
+	mark("momentum", len(data.Momentum) > 0)
+
+	// 计算线性回归通道（量化趋势方向和强度）
+	data.Regression = calculateRegression(klines, regressionPeriod)
+	mark("regression", data.Regression != nil)
+
+	// 计算趋势性指标（区分趋势与均值回归行情）
+	data.Hurst, ok = calculateHurst(klines)
+	mark("hurst", ok)
+	data.VarianceRatio, ok = calculateVarianceRatio(klines, 4)
+	mark("variance_ratio", ok)
+
+	// 计算最近10个MACD和RSI值（单次遍历整段K线，结果与对每个前缀调用calculateMACD/calculateRSI一致）
+	values := closes(klines)
+	data.MACDValues = append(data.MACDValues, macdValues(values, cfg.MACDFast, cfg.MACDSlow).Tail(10)...)
+	data.RSI14Values = append(data.RSI14Values, rsiValues(values, cfg.RSI).Tail(10)...)
+	mark("macd", len(data.MACDValues) > 0)
+	mark("rsi", len(data.RSI14Values) > 0)
 
 	return data
 }
@@ -373,66 +533,59 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 func getOpenInterestData(symbol string) (*OIData, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/openInterest?symbol=%s", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := binanceHTTPClient.Get(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := utils.ReadBody(resp.Body)
 	if err != nil {
 		return nil, err
 	}
+	return parseOpenInterest(body)
+}
 
-	var result struct {
-		OpenInterest string `json:"openInterest"`
-		Symbol       string `json:"symbol"`
-		Time         int64  `json:"time"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
-	}
-
-	oi, _ := strconv.ParseFloat(result.OpenInterest, 64)
-
-	return &OIData{
-		Latest:  oi,
-		Average: oi * 0.999, // 近似平均值
-	}, nil
+// premiumIndex 币安永续的标记价格和资金费率
+type premiumIndex struct {
+	Symbol          string `json:"symbol"`
+	MarkPrice       string `json:"markPrice"`
+	IndexPrice      string `json:"indexPrice"`
+	LastFundingRate string `json:"lastFundingRate"`
+	NextFundingTime int64  `json:"nextFundingTime"`
+	InterestRate    string `json:"interestRate"`
+	Time            int64  `json:"time"`
 }
 
-// getFundingRate 获取资金费率
-func getFundingRate(symbol string) (float64, error) {
+// getPremiumIndex 获取标记价格和资金费率
+func getPremiumIndex(symbol string) (*premiumIndex, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/premiumIndex?symbol=%s", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := binanceHTTPClient.Get(url)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := utils.ReadBody(resp.Body)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	return parsePremiumIndex(body)
+}
 
-	var result struct {
-		Symbol          string `json:"symbol"`
-		MarkPrice       string `json:"markPrice"`
-		IndexPrice      string `json:"indexPrice"`
-		LastFundingRate string `json:"lastFundingRate"`
-		NextFundingTime int64  `json:"nextFundingTime"`
-		InterestRate    string `json:"interestRate"`
-		Time            int64  `json:"time"`
+// nextFundingTime 下次资金费结算时间：数据源提供结算时间时直接使用，否则按结算周期从UTC零点对齐推算
+func nextFundingTime(provider Provider, symbol string, intervalHours int) time.Time {
+	if schedule, ok := provider.(FundingScheduleProvider); ok {
+		if next, err := schedule.NextFundingTime(symbol); err == nil && !next.IsZero() {
+			return next
+		}
 	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, err
+	if intervalHours <= 0 {
+		return time.Time{}
 	}
-
-	rate, _ := strconv.ParseFloat(result.LastFundingRate, 64)
-	return rate, nil
+	interval := time.Duration(intervalHours) * time.Hour
+	return Clock.Now().UTC().Truncate(interval).Add(interval)
 }
 
 // Format 格式化输出市场数据
@@ -440,58 +593,177 @@ func Format(data *Data) string {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("current_price = %.2f\n\n", data.CurrentPrice))
+	sb.WriteString(fmt.Sprintf("strength_score = %.1f/100 (50 neutral, higher = stronger bullish trend/momentum/volume/OI)\n\n", data.StrengthScore))
 
-	// 添加MA21_4h和趋势信息
-	sb.WriteString(fmt.Sprintf("MA21_4h: %.2f\n", data.MA21_4h))
-	if len(data.MA21_4hSeries) >= 3 {
+	// 上市/下架提示
+	if data.Delisted {
+		sb.WriteString("⚠️ 该币种已下架/停止交易，只能平仓\n\n")
+	} else if !data.DelistAt.IsZero() {
+		sb.WriteString(fmt.Sprintf("⚠️ 该币种计划于 %s 下架\n\n", data.DelistAt.UTC().Format("2006-01-02 15:04 UTC")))
+	}
+	if data.IsNewListing() {
+		sb.WriteString(fmt.Sprintf("⚠️ 新上市币种：仅有%d根已完成K线（少于%d根），长期指标不可靠\n\n",
+			data.HistoryAvailableBars, MinHistoryBars))
+	}
+	if len(data.Warnings) > 0 {
+		sb.WriteString(fmt.Sprintf("⚠️ 指标预热不足（以下指标不可用或尚未收敛）: %s\n\n", strings.Join(data.Warnings, "; ")))
+	}
+
+	trendInterval := data.TrendInterval
+	if trendInterval == "" {
+		trendInterval = DefaultTrendInterval
+	}
+	entryInterval := data.EntryInterval
+	if entryInterval == "" {
+		entryInterval = DefaultEntryInterval
+	}
+
+	periods := data.Indicators.withDefaults()
+
+	// 添加趋势均线和趋势信息
+	sb.WriteString(fmt.Sprintf("MA%d_%s: %s\n", periods.TrendMA, trendInterval, formatIndicator(data.TrendMA, data.Available("trend_ma"), "%.2f")))
+	if len(data.TrendMASeries) >= 3 {
 		trend := "横盘"
-		if isRising(data.MA21_4hSeries) {
+		if isRising(data.TrendMASeries) {
 			trend = "上涨"
-		} else if isFalling(data.MA21_4hSeries) {
+		} else if isFalling(data.TrendMASeries) {
 			trend = "下跌"
 		}
-		sb.WriteString(fmt.Sprintf("4小时趋势(MA21连续3): %s (序列: %s)\n", trend, formatFloatSlice(data.MA21_4hSeries)))
+		sb.WriteString(fmt.Sprintf("%s趋势(MA%d连续3): %s (序列: %s)\n", trendInterval, periods.TrendMA, trend, formatFloatSlice(data.TrendMASeries)))
 	}
 
-	// 添加MA15_15m和价格距离
-	sb.WriteString(fmt.Sprintf("MA15_15m: %.2f\n", data.MA15_15m))
-	priceToMA15Dist := ((data.CurrentPrice - data.MA15_15m) / data.MA15_15m) * 100
-	sb.WriteString(fmt.Sprintf("价格与MA15_15m距离: %.2f%%\n\n", priceToMA15Dist))
+	if len(data.Crossovers) > 0 {
+		sb.WriteString(fmt.Sprintf("%s指标交叉: %s\n", trendInterval, formatCrossovers(data.Crossovers, trendInterval)))
+	}
 
-	sb.WriteString(fmt.Sprintf("In addition, here is the latest %s open interest and funding rate for perps:\n\n",
-		data.Symbol))
+	// 添加入场均线和价格距离
+	entryMAOK := data.Available("entry_ma") && data.EntryMA != 0
+	sb.WriteString(fmt.Sprintf("MA%d_%s: %s\n", periods.EntryMA, entryInterval, formatIndicator(data.EntryMA, entryMAOK, "%.2f")))
+	priceToMA15Dist := 0.0
+	if entryMAOK {
+		priceToMA15Dist = ((data.CurrentPrice - data.EntryMA) / data.EntryMA) * 100
+	}
+	sb.WriteString(fmt.Sprintf("价格与MA%d_%s距离: %s\n\n", periods.EntryMA, entryInterval, formatIndicator(priceToMA15Dist, entryMAOK, "%.2f%%")))
 
+	// 永续合约数据（现货数据源没有OI和资金费率）
 	if data.OpenInterest != nil {
-		sb.WriteString(fmt.Sprintf("Open Interest: Latest: %.2f Average: %.2f\n\n",
-			data.OpenInterest.Latest, data.OpenInterest.Average))
+		sb.WriteString(fmt.Sprintf("In addition, here is the latest %s open interest and funding rate for perps:\n\n",
+			data.Symbol))
+
+		sb.WriteString(fmt.Sprintf("Open Interest: Latest: %.2f Average: %s\n\n",
+			data.OpenInterest.Latest, formatIndicator(data.OpenInterest.Average, data.OpenInterest.Average > 0, "%.2f")))
+
+		if data.FundingInterval > 0 && data.FundingInterval != 8 {
+			sb.WriteString(fmt.Sprintf("Funding Rate: %.2e (per %dh, ≈%.2e per 8h)\n\n",
+				data.FundingRate, data.FundingInterval, data.FundingRate*8/float64(data.FundingInterval)))
+		} else {
+			sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
+		}
+		if until := data.NextFundingTime.Sub(Clock.Now()); !data.NextFundingTime.IsZero() && until >= 0 {
+			sb.WriteString(fmt.Sprintf("Next Funding: in %.0f min\n\n", until.Minutes()))
+		}
 	}
 
-	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
+	if data.Options != nil {
+		sb.WriteString(FormatOptions(data.Options))
+	}
 
-	if data.LongerTermContext != nil {
-		sb.WriteString("Longer‑term context (4‑hour timeframe):\n\n")
+	if ltd := data.LongerTermContext; ltd != nil {
+		sb.WriteString(fmt.Sprintf("Longer‑term context (%s timeframe):\n\n", trendInterval))
 
-		sb.WriteString(fmt.Sprintf("20‑Period EMA: %.3f vs. 50‑Period EMA: %.3f\n\n",
-			data.LongerTermContext.EMA20, data.LongerTermContext.EMA50))
+		sb.WriteString(fmt.Sprintf("%d‑Period EMA: %s vs. %d‑Period EMA: %s\n\n",
+			periods.EMAFast, formatIndicator(ltd.EMA20, ltd.Available("ema_fast"), "%.3f"),
+			periods.EMASlow, formatIndicator(ltd.EMA50, ltd.Available("ema_slow"), "%.3f")))
+
+		atrFast, atrSlow := "n/a", "n/a"
+		if ltd.Available("atr_fast") {
+			atrFast = fmt.Sprintf("%.3f (%.2f%%)", ltd.ATR3, ltd.ATR3Pct)
+		}
+		if ltd.Available("atr_slow") {
+			atrSlow = fmt.Sprintf("%.3f (%.2f%%)", ltd.ATR14, ltd.ATR14Pct)
+		}
+		sb.WriteString(fmt.Sprintf("%d‑Period ATR: %s vs. %d‑Period ATR: %s\n\n", periods.ATRFast, atrFast, periods.ATRSlow, atrSlow))
 
-		sb.WriteString(fmt.Sprintf("3‑Period ATR: %.3f vs. 14‑Period ATR: %.3f\n\n",
-			data.LongerTermContext.ATR3, data.LongerTermContext.ATR14))
+		if ltd.Available("realized_vol") {
+			sb.WriteString(fmt.Sprintf("Realized Volatility: %.2f%% daily / %.1f%% annualized\n\n",
+				ltd.RealizedVolDaily, ltd.RealizedVolAnnual))
+		} else {
+			sb.WriteString("Realized Volatility: n/a\n\n")
+		}
 
 		sb.WriteString(fmt.Sprintf("Current Volume: %.3f vs. Average Volume: %.3f\n\n",
-			data.LongerTermContext.CurrentVolume, data.LongerTermContext.AverageVolume))
+			ltd.CurrentVolume, ltd.AverageVolume))
+
+		keltner := "n/a"
+		if ltd.Available("keltner") {
+			keltner = fmt.Sprintf("%.3f / %.3f / %.3f", ltd.KeltnerUpper, ltd.KeltnerMiddle, ltd.KeltnerLower)
+		}
+		donchian := "n/a"
+		if ltd.Available("donchian") {
+			donchian = fmt.Sprintf("%.3f / %.3f", ltd.DonchianUpper, ltd.DonchianLower)
+		}
+		sb.WriteString(fmt.Sprintf("Keltner Channel: %s | Donchian(20): %s\n\n", keltner, donchian))
+		if ltd.Squeeze {
+			sb.WriteString(fmt.Sprintf("⚠️ Squeeze: Bollinger Bands (%.3f / %.3f) inside Keltner Channel, volatility compressed\n\n",
+				ltd.BollingerUpper, ltd.BollingerLower))
+		}
+
+		macd := "n/a"
+		if len(ltd.MACDValues) > 0 {
+			macd = formatFloatSlice(ltd.MACDValues)
+		}
+		sb.WriteString(fmt.Sprintf("MACD indicators (%d/%d): %s\n\n", periods.MACDFast, periods.MACDSlow, macd))
+
+		rsi := "n/a"
+		if len(ltd.RSI14Values) > 0 {
+			rsi = formatFloatSlice(ltd.RSI14Values)
+		}
+		sb.WriteString(fmt.Sprintf("RSI indicators (%d‑Period): %s\n\n", periods.RSI, rsi))
+
+		sb.WriteString(fmt.Sprintf("Williams %%R (14‑Period): %s | CCI (20‑Period): %s\n\n",
+			formatIndicator(ltd.WilliamsR14, ltd.Available("williams_r"), "%.2f"),
+			formatIndicator(ltd.CCI20, ltd.Available("cci"), "%.2f")))
+		sb.WriteString(fmt.Sprintf("MFI (14‑Period): %s | CMF (20‑Period): %s\n\n",
+			formatIndicator(ltd.MFI14, ltd.Available("mfi"), "%.2f"),
+			formatIndicator(ltd.CMF20, ltd.Available("cmf"), "%.3f")))
 
-		if len(data.LongerTermContext.MACDValues) > 0 {
-			sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(data.LongerTermContext.MACDValues)))
+		for _, series := range ltd.Momentum {
+			sb.WriteString(fmt.Sprintf("ROC (%d‑Period, %%): %s\n\n", series.Period, formatFloatSlice(series.ROC)))
+		}
+
+		if reg := ltd.Regression; reg != nil {
+			sb.WriteString(fmt.Sprintf("Linear Regression (%d‑Period): slope %+.3f%%/bar, R² %.2f, channel %.3f / %.3f / %.3f\n\n",
+				reg.Period, reg.SlopePct, reg.R2, reg.Upper, reg.Middle, reg.Lower))
+		} else {
+			sb.WriteString(fmt.Sprintf("Linear Regression (%d‑Period): n/a\n\n", regressionPeriod))
 		}
 
-		if len(data.LongerTermContext.RSI14Values) > 0 {
-			sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(data.LongerTermContext.RSI14Values)))
+		sb.WriteString(fmt.Sprintf("Hurst Exponent: %s | Variance Ratio (4): %s | Regime: %s\n\n",
+			formatIndicator(ltd.Hurst, ltd.Available("hurst"), "%.2f"),
+			formatIndicator(ltd.VarianceRatio, ltd.Available("variance_ratio"), "%.2f"), ltd.Regime()))
+
+		if ltd.Available("parabolic_sar") {
+			sarSide := "below price (bullish)"
+			if !ltd.SARUptrend {
+				sarSide = "above price (bearish)"
+			}
+			sb.WriteString(fmt.Sprintf("Parabolic SAR: %.3f, %s\n\n", ltd.ParabolicSAR, sarSide))
+		} else {
+			sb.WriteString("Parabolic SAR: n/a\n\n")
 		}
 	}
 
 	return sb.String()
 }
 
+// formatIndicator 格式化指标值，无法计算时输出"n/a"（避免0被当作真实读数）
+func formatIndicator(value float64, ok bool, format string) string {
+	if !ok {
+		return "n/a"
+	}
+	return fmt.Sprintf(format, value)
+}
+
 // formatFloatSlice 格式化float64切片为字符串
 func formatFloatSlice(values []float64) string {
 	strValues := make([]string, len(values))
@@ -501,20 +773,16 @@ func formatFloatSlice(values []float64) string {
 	return "[" + strings.Join(strValues, ", ") + "]"
 }
 
-// Normalize 标准化symbol,确保是USDT交易对
+// Normalize 标准化symbol为系统格式（USDT永续交易对），通过币种注册表解析各交易所写法
 func Normalize(symbol string) string {
-	symbol = strings.ToUpper(symbol)
-	if strings.HasSuffix(symbol, "USDT") {
-		return symbol
-	}
-	return symbol + "USDT"
+	return symbols.System(symbol)
 }
 
 // parseFloat 解析float值
 func parseFloat(v interface{}) (float64, error) {
 	switch val := v.(type) {
 	case string:
-		return strconv.ParseFloat(val, 64)
+		return parseDecimal("value", val)
 	case float64:
 		return val, nil
 	case int:
@@ -552,25 +820,12 @@ func isFalling(series []float64) bool {
 	return true
 }
 
-// CheckKlineCompleteness 检查15分钟K线是否走完
+// CheckKlineCompleteness 检查最近一根15分钟K线是否走完
 // 返回true表示K线已完成，可以用于决策
+// K线边界按交易所时间（Clock，默认UTC并校正本机时钟偏差）计算，与本机时区无关；
+// 例如现在是10:30:01，10:15-10:30的K线刚收盘，需等待交易所生成最终K线后才返回true
 func CheckKlineCompleteness() bool {
-	// 获取当前时间
-	now := time.Now()
-
-	// 当前分钟数（0-59）
-	currentMinute := now.Minute()
-
-	// 计算当前15分钟周期的开始时间
-	// 例如：如果现在是10:37，当前周期是10:30-10:45
-	klineStartMinute := (currentMinute / 15) * 15
-	klineStartTime := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), klineStartMinute, 0, 0, now.Location())
-
-	// 计算K线结束时间
-	klineEndTime := klineStartTime.Add(15 * time.Minute)
-
-	// 如果当前时间已经达到或超过K线结束时间，说明K线已完成
-	return now.Equal(klineEndTime) || now.After(klineEndTime)
+	return Clock.CandleCompleted(15*time.Minute, klineSettleDelay)
 }
 
 // filterCompletedKlines 过滤掉未走完的K线
@@ -580,8 +835,8 @@ func filterCompletedKlines(klines []Kline) []Kline {
 		return klines
 	}
 
-	// 获取当前时间戳（毫秒）
-	now := time.Now().UnixMilli()
+	// 获取当前交易所时间戳（毫秒）
+	now := Clock.Now().UnixMilli()
 
 	// 过滤掉 CloseTime > now 的K线（未走完的K线）
 	completed := make([]Kline, 0, len(klines))
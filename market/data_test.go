@@ -0,0 +1,20 @@
+package market
+
+import (
+	"fmt"
+	"testing"
+)
+
+// BenchmarkCalculateLongerTermData 趋势周期指标计算（每个币种每根K线一次，筛选200个币种时的CPU热点）
+func BenchmarkCalculateLongerTermData(b *testing.B) {
+	cfg := IndicatorConfig{}.withDefaults()
+	for _, bars := range []int{cfg.trendBars(), 500, 2000} {
+		klines := randomKlineSamples(bars)[0]
+		b.Run(fmt.Sprintf("bars=%d", bars), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				calculateLongerTermData(klines, cfg)
+			}
+		})
+	}
+}
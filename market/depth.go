@@ -0,0 +1,93 @@
+package market
+
+import (
+	"fmt"
+	"time"
+)
+
+// depthLimits 币安深度接口支持的档位数
+var depthLimits = []int{5, 10, 20, 50, 100, 500, 1000}
+
+// BookLevel 盘口的一档
+type BookLevel struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// OrderBook 盘口深度快照（买盘按价格降序，卖盘按价格升序）
+type OrderBook struct {
+	Symbol string      `json:"symbol"`
+	Bids   []BookLevel `json:"bids"`
+	Asks   []BookLevel `json:"asks"`
+	Time   time.Time   `json:"time"`
+}
+
+// DepthLimit 不小于limit的最近支持档位（5-1000，币安及兼容接口通用）
+func DepthLimit(limit int) int {
+	for _, l := range depthLimits {
+		if l >= limit {
+			return l
+		}
+	}
+	return depthLimits[len(depthLimits)-1]
+}
+
+// GetDepth 从币安USDT永续深度接口（/fapi/v1/depth）获取盘口，limit取不小于该值的最近支持档位（5-1000）
+func GetDepth(symbol string, limit int) (*OrderBook, error) {
+	symbol = Normalize(symbol)
+	return fetchDepth(symbol, fmt.Sprintf("https://fapi.binance.com/fapi/v1/depth?symbol=%s&limit=%d", symbol, DepthLimit(limit)))
+}
+
+// GetCoinMDepth 从币安币本位永续深度接口（/dapi/v1/depth）获取盘口（contract如"BTCUSD_PERP"，数量为合约张数）
+func GetCoinMDepth(contract string, limit int) (*OrderBook, error) {
+	return fetchDepth(contract, fmt.Sprintf("https://dapi.binance.com/dapi/v1/depth?symbol=%s&limit=%d", contract, DepthLimit(limit)))
+}
+
+// fetchDepth 请求并解析币安格式的深度接口
+func fetchDepth(symbol, url string) (*OrderBook, error) {
+	body, err := binanceGetBody(url)
+	if err != nil {
+		return nil, err
+	}
+	book, err := ParseDepth(body)
+	if err != nil {
+		return nil, err
+	}
+	book.Symbol = symbol
+	return book, nil
+}
+
+// Mid 买一卖一的中间价（任一侧为空时返回0）
+func (b *OrderBook) Mid() float64 {
+	if len(b.Bids) == 0 || len(b.Asks) == 0 {
+		return 0
+	}
+	return (b.Bids[0].Price + b.Asks[0].Price) / 2
+}
+
+// DepthWithin 中间价上下bps以内的可见深度（USDT名义价值）：buy为可买入的卖盘，否则为可卖出的买盘
+func (b *OrderBook) DepthWithin(buy bool, bps float64) float64 {
+	mid := b.Mid()
+	if mid <= 0 {
+		return 0
+	}
+	total := 0.0
+	if buy {
+		limit := mid * (1 + bps/10000)
+		for _, level := range b.Asks {
+			if level.Price > limit {
+				break
+			}
+			total += level.Price * level.Quantity
+		}
+		return total
+	}
+	limit := mid * (1 - bps/10000)
+	for _, level := range b.Bids {
+		if level.Price < limit {
+			break
+		}
+		total += level.Price * level.Quantity
+	}
+	return total
+}
@@ -0,0 +1,82 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DataDiff 两个周期之间市场数据的变化
+type DataDiff struct {
+	Symbol         string
+	PriceChangePct float64  // 价格变化百分比
+	OIChangePct    float64  // 持仓量变化百分比（HasOI为false时无意义）
+	HasOI          bool     // 两个快照都有持仓量数据
+	FundingDelta   float64  // 资金费率变化
+	RSIDelta       float64  // RSI14变化
+	Crossovers     []string // 指标交叉（如"EMA20上穿EMA50"）
+}
+
+// Diff 计算相对上一周期快照的变化（prev为nil或币种不同时返回nil）
+func (d *Data) Diff(prev *Data) *DataDiff {
+	if prev == nil || prev.Symbol != d.Symbol {
+		return nil
+	}
+
+	diff := &DataDiff{Symbol: d.Symbol}
+	if prev.CurrentPrice > 0 {
+		diff.PriceChangePct = (d.CurrentPrice - prev.CurrentPrice) / prev.CurrentPrice * 100
+	}
+	if d.OpenInterest != nil && prev.OpenInterest != nil && prev.OpenInterest.Latest > 0 {
+		diff.HasOI = true
+		diff.OIChangePct = (d.OpenInterest.Latest - prev.OpenInterest.Latest) / prev.OpenInterest.Latest * 100
+	}
+	diff.FundingDelta = d.FundingRate - prev.FundingRate
+
+	periods := d.Indicators.withDefaults()
+	cur, old := d.LongerTermContext, prev.LongerTermContext
+	if cur != nil && old != nil {
+		if len(cur.RSI14Values) > 0 && len(old.RSI14Values) > 0 {
+			diff.RSIDelta = cur.RSI14Values[len(cur.RSI14Values)-1] - old.RSI14Values[len(old.RSI14Values)-1]
+		}
+		if cur.Available("ema_fast") && cur.Available("ema_slow") && old.Available("ema_fast") && old.Available("ema_slow") {
+			diff.addCrossover(old.EMA20-old.EMA50, cur.EMA20-cur.EMA50,
+				fmt.Sprintf("EMA%d上穿EMA%d", periods.EMAFast, periods.EMASlow),
+				fmt.Sprintf("EMA%d下穿EMA%d", periods.EMAFast, periods.EMASlow))
+		}
+		if len(cur.MACDValues) > 0 && len(old.MACDValues) > 0 {
+			diff.addCrossover(old.MACDValues[len(old.MACDValues)-1], cur.MACDValues[len(cur.MACDValues)-1],
+				"MACD上穿零轴", "MACD下穿零轴")
+		}
+	}
+	if d.Available("trend_ma") && prev.Available("trend_ma") {
+		diff.addCrossover(prev.CurrentPrice-prev.TrendMA, d.CurrentPrice-d.TrendMA,
+			fmt.Sprintf("价格上穿MA%d", periods.TrendMA), fmt.Sprintf("价格下穿MA%d", periods.TrendMA))
+	}
+
+	return diff
+}
+
+// addCrossover 根据差值符号变化记录交叉
+func (diff *DataDiff) addCrossover(before, after float64, up, down string) {
+	if before <= 0 && after > 0 {
+		diff.Crossovers = append(diff.Crossovers, up)
+	} else if before >= 0 && after < 0 {
+		diff.Crossovers = append(diff.Crossovers, down)
+	}
+}
+
+// String 单行变化摘要（用于追加到AI prompt）
+func (diff *DataDiff) String() string {
+	parts := []string{fmt.Sprintf("价格 %+.2f%%", diff.PriceChangePct)}
+	if diff.HasOI {
+		parts = append(parts, fmt.Sprintf("OI %+.2f%%", diff.OIChangePct))
+	}
+	if diff.FundingDelta != 0 {
+		parts = append(parts, fmt.Sprintf("资金费率 %+.2e", diff.FundingDelta))
+	}
+	if diff.RSIDelta != 0 {
+		parts = append(parts, fmt.Sprintf("RSI14 %+.1f", diff.RSIDelta))
+	}
+	parts = append(parts, diff.Crossovers...)
+	return strings.Join(parts, " | ")
+}
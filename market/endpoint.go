@@ -0,0 +1,210 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/events"
+	"nofx/utils"
+)
+
+// 故障切换参数
+const (
+	endpointFailureThreshold = 3                // 连续失败多少次切换到下一个地址
+	endpointRetryPrimary     = 10 * time.Minute // 切换后多久重新尝试主地址
+)
+
+// Endpoint 带故障切换的API基础地址
+// 第一个地址为主地址，其余为备用地址（如fapi1/fapi2镜像、区域域名、自建代理）
+type Endpoint struct {
+	name string
+
+	mu         sync.Mutex
+	urls       []string
+	current    int
+	failures   int
+	switchedAt time.Time
+}
+
+// BinanceFutures 币安USDT永续（fapi）基础地址，行情请求和交易客户端共用
+var BinanceFutures = NewEndpoint("币安合约", "https://fapi.binance.com")
+
+// NewEndpoint 创建基础地址
+func NewEndpoint(name, primary string, fallbacks ...string) *Endpoint {
+	e := &Endpoint{name: name}
+	e.SetURLs(primary, fallbacks...)
+	return e
+}
+
+// SetURLs 设置主地址和备用地址（primary为空时保留当前主地址）
+func (e *Endpoint) SetURLs(primary string, fallbacks ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if primary == "" && len(e.urls) > 0 {
+		primary = e.urls[0]
+	}
+	urls := []string{strings.TrimSuffix(primary, "/")}
+	for _, fallback := range fallbacks {
+		if fallback = strings.TrimSuffix(fallback, "/"); fallback != "" && fallback != urls[0] {
+			urls = append(urls, fallback)
+		}
+	}
+
+	e.urls = urls
+	e.current = 0
+	e.failures = 0
+}
+
+// URL 获取当前使用的基础地址
+func (e *Endpoint) URL() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// 备用地址使用一段时间后重新尝试主地址
+	if e.current != 0 && time.Since(e.switchedAt) > endpointRetryPrimary {
+		log.Printf("🔁 %s 重新尝试主地址 %s", e.name, e.urls[0])
+		e.current = 0
+		e.failures = 0
+	}
+	return e.urls[e.current]
+}
+
+// URLs 获取全部地址（主地址在前）
+func (e *Endpoint) URLs() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.urls...)
+}
+
+// ReportSuccess 记录请求成功
+func (e *Endpoint) ReportSuccess() {
+	e.mu.Lock()
+	e.failures = 0
+	e.mu.Unlock()
+}
+
+// ReportFailure 记录请求失败，连续失败达到阈值时切换到下一个地址
+func (e *Endpoint) ReportFailure(err error) {
+	e.mu.Lock()
+	e.failures++
+	if e.failures < endpointFailureThreshold || len(e.urls) < 2 {
+		e.mu.Unlock()
+		return
+	}
+	from := e.urls[e.current]
+	e.current = (e.current + 1) % len(e.urls)
+	e.failures = 0
+	e.switchedAt = time.Now()
+	to := e.urls[e.current]
+	e.mu.Unlock()
+
+	message := fmt.Sprintf("%s 连续%d次请求失败，切换地址 %s -> %s（最后错误: %v）", e.name, endpointFailureThreshold, from, to, err)
+	log.Printf("⚠️  %s", message)
+	events.Publish(events.Event{
+		Type:     events.TypeEndpointFailover,
+		Severity: events.SeverityWarning,
+		Message:  message,
+		Data: map[string]interface{}{
+			"endpoint": e.name,
+			"from":     from,
+			"to":       to,
+		},
+	})
+}
+
+// Transport 返回将请求改写到当前基础地址的RoundTripper，并根据响应记录成功/失败
+// 网络错误和5xx响应计为失败；base为nil时使用http.DefaultTransport
+func (e *Endpoint) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &endpointTransport{endpoint: e, base: base}
+}
+
+// endpointTransport 基础地址改写与故障统计
+type endpointTransport struct {
+	endpoint *Endpoint
+	base     http.RoundTripper
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, err := url.Parse(t.endpoint.URL())
+	if err != nil {
+		return nil, fmt.Errorf("无效的基础地址: %w", err)
+	}
+
+	// 保留原请求的路径和参数，只替换协议、主机和路径前缀（自建代理可带前缀）
+	rewritten := req.Clone(req.Context())
+	rewritten.URL.Scheme = target.Scheme
+	rewritten.URL.Host = target.Host
+	rewritten.URL.Path = strings.TrimSuffix(target.Path, "/") + req.URL.Path
+	rewritten.URL.RawPath = ""
+	rewritten.Host = target.Host
+
+	resp, err := t.base.RoundTrip(rewritten)
+	if err != nil {
+		t.endpoint.ReportFailure(err)
+		return nil, err
+	}
+	if resp.StatusCode >= 500 {
+		t.endpoint.ReportFailure(fmt.Errorf("HTTP %d", resp.StatusCode))
+	} else {
+		t.endpoint.ReportSuccess()
+	}
+	return resp, nil
+}
+
+// binanceHTTPClient 币安行情请求客户端（经由BinancePayloads记录响应、BinanceResponseCache缓存慢变接口、
+// BinanceCircuits按接口熔断、BinanceLimiter限速和BinanceFutures故障切换）
+var binanceHTTPClient = &http.Client{
+	Timeout:   30 * time.Second,
+	Transport: BinancePayloads.Transport(BinanceResponseCache.Transport(BinanceCircuits.Transport(BinanceLimiter.Transport(BinanceFutures.Transport(sharedTransport))))),
+}
+
+// binanceGetBody 通过BinanceFutures发送GET请求并返回响应体
+func binanceGetBody(url string) ([]byte, error) {
+	resp, err := binanceHTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return utils.ReadBody(resp.Body)
+}
+
+// binanceGetJSON 通过BinanceFutures发送GET请求并边读边解析JSON响应（exchangeInfo等大响应不整体读入内存）
+func binanceGetJSON(url string, v interface{}) error {
+	resp, err := binanceHTTPClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return utils.DecodeJSON(resp.Body, v)
+}
+
+// BinanceServerOffset 币安合约服务器时间相对本地时间的偏差（服务器时间 - 本地时间），以请求往返的中点估算本地时间
+func BinanceServerOffset() (time.Duration, error) {
+	start := time.Now()
+	body, err := binanceGetBody("https://fapi.binance.com/fapi/v1/time")
+	if err != nil {
+		return 0, err
+	}
+	end := time.Now()
+
+	var result struct {
+		ServerTime int64 `json:"serverTime"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.ServerTime == 0 {
+		return 0, fmt.Errorf("解析服务器时间失败: %s", string(body))
+	}
+	local := start.Add(end.Sub(start) / 2)
+	return time.UnixMilli(result.ServerTime).Sub(local), nil
+}
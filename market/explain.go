@@ -0,0 +1,95 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reading 支撑交易信号的单项指标读数
+type Reading struct {
+	Name     string  `json:"name"`     // 指标名（如"ema_trend"、"rsi"）
+	Value    float64 `json:"value"`    // 数值
+	Text     string  `json:"text"`     // 可读描述（如"EMA20>EMA50"）
+	Supports bool    `json:"supports"` // 是否支持信号方向（平仓等无方向信号为false）
+}
+
+// Explanation 交易信号的结构化依据（信号产生时的关键指标读数）
+type Explanation struct {
+	Direction string    `json:"direction,omitempty"` // "long" / "short"，无方向为空
+	Readings  []Reading `json:"readings"`
+	Summary   string    `json:"summary"` // 如"EMA20>EMA50, RSI 61.0, funding -0.0100%"
+}
+
+// Explain 根据市场数据生成信号依据（direction为"long"/"short"时标记各读数是否支持该方向）
+func Explain(data *Data, direction string) *Explanation {
+	if data == nil {
+		return nil
+	}
+	exp := &Explanation{Direction: direction}
+	periods := data.Indicators.withDefaults()
+	bullish := func(up bool) bool {
+		return (direction == "long" && up) || (direction == "short" && !up)
+	}
+
+	if ltd := data.LongerTermContext; ltd != nil {
+		if ltd.Available("ema_fast") && ltd.Available("ema_slow") && ltd.EMA50 > 0 {
+			relation := ">"
+			if ltd.EMA20 < ltd.EMA50 {
+				relation = "<"
+			}
+			exp.add("ema_trend", (ltd.EMA20-ltd.EMA50)/ltd.EMA50*100,
+				fmt.Sprintf("EMA%d%sEMA%d", periods.EMAFast, relation, periods.EMASlow), bullish(ltd.EMA20 > ltd.EMA50))
+		}
+		if n := len(ltd.RSI14Values); n > 0 {
+			rsi := ltd.RSI14Values[n-1]
+			exp.add("rsi", rsi, fmt.Sprintf("RSI %.1f", rsi), bullish(rsi > 50))
+		}
+		if n := len(ltd.MACDValues); n > 0 {
+			macd := ltd.MACDValues[n-1]
+			exp.add("macd", macd, fmt.Sprintf("MACD %+.4g", macd), bullish(macd > 0))
+		}
+		if ltd.Available("hurst") {
+			exp.add("hurst", ltd.Hurst, fmt.Sprintf("regime %s (Hurst %.2f)", ltd.Regime(), ltd.Hurst), false)
+		}
+	}
+
+	if data.Available("trend_ma") && data.TrendMA > 0 {
+		relation := "above"
+		if data.CurrentPrice < data.TrendMA {
+			relation = "below"
+		}
+		exp.add("price_vs_ma", (data.CurrentPrice-data.TrendMA)/data.TrendMA*100,
+			fmt.Sprintf("price %s MA%d", relation, periods.TrendMA), bullish(data.CurrentPrice > data.TrendMA))
+	}
+	if data.OpenInterest != nil {
+		// 资金费率为负（空头付费）时做多有利
+		exp.add("funding", data.FundingRate*100, fmt.Sprintf("funding %+.4f%%", data.FundingRate*100), bullish(data.FundingRate < 0))
+	}
+	exp.add("strength", data.StrengthScore, fmt.Sprintf("strength %.0f", data.StrengthScore), bullish(data.StrengthScore > 50))
+
+	texts := make([]string, len(exp.Readings))
+	for i, r := range exp.Readings {
+		texts[i] = r.Text
+	}
+	exp.Summary = strings.Join(texts, ", ")
+	return exp
+}
+
+// add 添加一项读数（无方向时Supports恒为false）
+func (e *Explanation) add(name string, value float64, text string, supports bool) {
+	e.Readings = append(e.Readings, Reading{
+		Name:     name,
+		Value:    value,
+		Text:     text,
+		Supports: e.Direction != "" && supports,
+	})
+}
+
+// Values 读数名称到数值的映射（用于日志/通知）
+func (e *Explanation) Values() map[string]float64 {
+	values := make(map[string]float64, len(e.Readings))
+	for _, r := range e.Readings {
+		values[r.Name] = r.Value
+	}
+	return values
+}
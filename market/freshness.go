@@ -0,0 +1,40 @@
+package market
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrStaleData 最新已完成入场周期K线过旧（交易所延迟或过滤后剩余K线不足）
+var ErrStaleData = errors.New("行情数据过旧")
+
+// freshnessMaxAge 最新已完成入场周期K线收盘时间距今的最大允许时长（0表示不检查）
+var freshnessMaxAge time.Duration
+
+// freshnessRefetch 数据过旧时是否重新获取一次（仍然过旧才拒绝）
+var freshnessRefetch bool
+
+// SetFreshnessGuard 设置行情新鲜度检查
+// maxAge: 最新已完成入场K线的最大年龄（应大于入场周期，例如15m入场设置20分钟）；refetch: 过旧时先重新获取一次
+func SetFreshnessGuard(maxAge time.Duration, refetch bool) {
+	freshnessMaxAge = maxAge
+	freshnessRefetch = refetch
+	if maxAge > 0 {
+		log.Printf("✓ 行情新鲜度检查: 最新入场K线不超过%v（过旧时重新获取: %v）", maxAge, refetch)
+	}
+}
+
+// klinesAge 最新一根K线收盘至今的时长（无K线时返回-1）
+func klinesAge(klines []Kline) time.Duration {
+	if len(klines) == 0 {
+		return -1
+	}
+	closeTime := time.UnixMilli(klines[len(klines)-1].CloseTime)
+	return Clock.Now().Sub(closeTime)
+}
+
+// isStale 按新鲜度设置判断K线是否过旧（未启用时总是false）
+func isStale(klines []Kline) bool {
+	return freshnessMaxAge > 0 && (len(klines) == 0 || klinesAge(klines) > freshnessMaxAge)
+}
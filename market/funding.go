@@ -0,0 +1,49 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+)
+
+// FundingRate 单个币种的当前资金费率
+type FundingRate struct {
+	Symbol   string  `json:"symbol"`
+	Rate     float64 `json:"rate"`     // 每个结算周期的资金费率
+	Interval int     `json:"interval"` // 结算周期（小时）
+	Rate8h   float64 `json:"rate_8h"`  // 折算为8小时的资金费率（便于比较不同结算周期）
+	APR      float64 `json:"apr"`      // 年化收益（%，按当前费率持续计算）
+}
+
+// ScanFunding 扫描各币种的资金费率，按8小时费率绝对值从高到低排序
+// 获取失败的币种跳过；当前数据源不提供资金费率（现货数据源）时返回错误
+func ScanFunding(symbols []string) ([]FundingRate, error) {
+	derivatives, ok := GetProvider().(DerivativesProvider)
+	if !ok {
+		return nil, fmt.Errorf("行情数据源 %s 不提供资金费率", GetProvider().Name())
+	}
+
+	var rates []FundingRate
+	for _, symbol := range symbols {
+		rate, interval, err := derivatives.GetFundingRate(symbol)
+		if err != nil {
+			log.Printf("⚠️  获取 %s 资金费率失败: %v", symbol, err)
+			continue
+		}
+		if interval <= 0 {
+			interval = 8
+		}
+		rates = append(rates, FundingRate{
+			Symbol:   symbol,
+			Rate:     rate,
+			Interval: interval,
+			Rate8h:   rate * 8 / float64(interval),
+			APR:      rate * 24 / float64(interval) * 365 * 100,
+		})
+	}
+	sort.SliceStable(rates, func(i, j int) bool {
+		return math.Abs(rates[i].Rate8h) > math.Abs(rates[j].Rate8h)
+	})
+	return rates, nil
+}
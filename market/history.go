@@ -0,0 +1,198 @@
+package market
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultHistoryDir 历史K线本地存储默认目录
+const DefaultHistoryDir = "history"
+
+// historyPageSize 每次向币安请求的K线数量（接口上限1500）
+const historyPageSize = 1500
+
+// HistoryStore 本地历史K线存储（每个币种/周期一个CSV文件：<dir>/<SYMBOL>/<interval>.csv）
+// Sync只下载本地最后一根K线之后缺失的部分，回测和预热无需每次重新下载全部历史
+type HistoryStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewHistoryStore 创建历史K线存储（dir为空时使用DefaultHistoryDir）
+func NewHistoryStore(dir string) *HistoryStore {
+	if dir == "" {
+		dir = DefaultHistoryDir
+	}
+	return &HistoryStore{dir: dir}
+}
+
+// path 币种/周期对应的文件路径
+func (s *HistoryStore) path(symbol, interval string) string {
+	return filepath.Join(s.dir, Normalize(symbol), interval+".csv")
+}
+
+// Load 读取本地保存的全部K线（按开盘时间升序，文件不存在时返回空）
+func (s *HistoryStore) Load(symbol, interval string) ([]Kline, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(symbol, interval)
+}
+
+// Range 读取[from, to)区间内的K线
+func (s *HistoryStore) Range(symbol, interval string, from, to time.Time) ([]Kline, error) {
+	klines, err := s.Load(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	var result []Kline
+	for _, k := range klines {
+		if k.OpenTime >= from.UnixMilli() && k.OpenTime < to.UnixMilli() {
+			result = append(result, k)
+		}
+	}
+	return result, nil
+}
+
+// Sync 下载本地缺失的已完成K线并追加到文件，返回新增数量
+// 本地没有数据时从since开始下载；已有数据时从最后一根K线之后继续
+func (s *HistoryStore) Sync(symbol, interval string, since time.Time) (int, error) {
+	symbol = Normalize(symbol)
+	duration, err := IntervalDuration(interval)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.load(symbol, interval)
+	if err != nil {
+		return 0, err
+	}
+	start := since.UnixMilli()
+	if len(existing) > 0 {
+		start = existing[len(existing)-1].OpenTime + duration.Milliseconds()
+	}
+
+	added := 0
+	for {
+		page, err := getKlinesRange(symbol, interval, start, historyPageSize)
+		if err != nil {
+			return added, fmt.Errorf("下载%s %s K线失败: %w", symbol, interval, err)
+		}
+		completed := filterCompletedKlines(page)
+		if err := s.append(symbol, interval, completed); err != nil {
+			return added, err
+		}
+		added += len(completed)
+
+		// 最后一页（不足一页或包含未完成K线）说明已同步到最新
+		if len(page) < historyPageSize || len(completed) < len(page) {
+			break
+		}
+		start = page[len(page)-1].OpenTime + duration.Milliseconds()
+	}
+
+	log.Printf("✓ 历史K线同步完成: %s %s 新增%d根（共%d根）", symbol, interval, added, len(existing)+added)
+	return added, nil
+}
+
+// load 读取文件中的K线（调用方持有锁）
+func (s *HistoryStore) load(symbol, interval string) ([]Kline, error) {
+	file, err := os.Open(s.path(symbol, interval))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开历史数据文件失败: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(bufio.NewReader(file))
+	reader.FieldsPerRecord = 7
+	var klines []Kline
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析历史数据失败: %w", err)
+		}
+		k, err := parseKlineRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("解析历史数据失败: %w", err)
+		}
+		klines = append(klines, k)
+	}
+	return klines, nil
+}
+
+// append 将K线追加到文件末尾（调用方持有锁，并保证K线晚于文件中最后一根）
+func (s *HistoryStore) append(symbol, interval string, klines []Kline) error {
+	if len(klines) == 0 {
+		return nil
+	}
+
+	path := s.path(symbol, interval)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建历史数据目录失败: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开历史数据文件失败: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	for _, k := range klines {
+		if err := writer.Write(klineRecord(k)); err != nil {
+			return fmt.Errorf("写入历史数据失败: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("写入历史数据失败: %w", err)
+	}
+	return nil
+}
+
+// klineRecord K线转换为CSV行（openTime,open,high,low,close,volume,closeTime）
+func klineRecord(k Kline) []string {
+	return []string{
+		strconv.FormatInt(k.OpenTime, 10),
+		strconv.FormatFloat(k.Open, 'f', -1, 64),
+		strconv.FormatFloat(k.High, 'f', -1, 64),
+		strconv.FormatFloat(k.Low, 'f', -1, 64),
+		strconv.FormatFloat(k.Close, 'f', -1, 64),
+		strconv.FormatFloat(k.Volume, 'f', -1, 64),
+		strconv.FormatInt(k.CloseTime, 10),
+	}
+}
+
+// parseKlineRecord 解析CSV行
+func parseKlineRecord(record []string) (Kline, error) {
+	var k Kline
+	var err error
+	if k.OpenTime, err = strconv.ParseInt(record[0], 10, 64); err != nil {
+		return k, err
+	}
+	values := []*float64{&k.Open, &k.High, &k.Low, &k.Close, &k.Volume}
+	for i, value := range values {
+		if *value, err = strconv.ParseFloat(record[i+1], 64); err != nil {
+			return k, err
+		}
+	}
+	if k.CloseTime, err = strconv.ParseInt(record[6], 10, 64); err != nil {
+		return k, err
+	}
+	return k, nil
+}
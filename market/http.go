@@ -0,0 +1,30 @@
+package market
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// sharedTransport 行情REST请求共用的连接池
+// 保持长连接并放宽每个主机的空闲连接数（默认只有2个，批量下载/多币种并发时会反复建连）；
+// 未手动设置Accept-Encoding时Transport会自动请求gzip并透明解压
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   32,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// httpClient 非币安行情请求（Coinbase、Kraken、Hyperliquid、期权）共用的客户端
+var httpClient = &http.Client{
+	Timeout:   30 * time.Second,
+	Transport: sharedTransport,
+}
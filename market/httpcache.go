@@ -0,0 +1,135 @@
+package market
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"nofx/utils"
+)
+
+// ResponseCache 慢变接口（交易规则、资金费结算周期）的GET响应缓存
+// 有效期内直接返回缓存（不发送请求、不消耗权重）；过期后带If-None-Match/If-Modified-Since条件请求，
+// 服务器返回304时沿用缓存并重新计时（服务器未提供ETag/Last-Modified时重新下载）
+type ResponseCache struct {
+	ttls map[string]time.Duration // 接口名 -> 缓存有效期
+
+	mu      sync.Mutex
+	entries map[string]*cachedResponse // 路径+参数 -> 响应
+}
+
+// cachedResponse 缓存的200响应
+type cachedResponse struct {
+	header       http.Header
+	body         []byte
+	etag         string
+	lastModified string
+	storedAt     time.Time
+}
+
+// BinanceResponseCache 币安合约慢变接口的响应缓存（行情请求和交易客户端共用）
+var BinanceResponseCache = NewResponseCache(map[string]time.Duration{
+	"exchangeInfo": 10 * time.Minute,
+	"fundingInfo":  10 * time.Minute,
+})
+
+// NewResponseCache 创建响应缓存（ttls为接口名到有效期的映射，未列出的接口不缓存）
+func NewResponseCache(ttls map[string]time.Duration) *ResponseCache {
+	return &ResponseCache{ttls: ttls, entries: make(map[string]*cachedResponse)}
+}
+
+// Transport 返回缓存中间件，应放在限速和熔断外层（命中缓存的请求不排队、不计入权重）
+// 只缓存无签名的GET请求。base为nil时使用http.DefaultTransport
+func (c *ResponseCache) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &cacheTransport{cache: c, base: base}
+}
+
+// cacheTransport 响应缓存与条件请求
+type cacheTransport struct {
+	cache *ResponseCache
+	base  http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ttl, ok := t.cache.ttls[endpointName(req.URL.Path)]
+	if !ok || req.Method != http.MethodGet || req.URL.Query().Has("signature") {
+		return t.base.RoundTrip(req)
+	}
+	key := req.URL.Path + "?" + req.URL.RawQuery
+
+	t.cache.mu.Lock()
+	entry := t.cache.entries[key]
+	fresh := entry != nil && time.Since(entry.storedAt) < ttl
+	t.cache.mu.Unlock()
+	if fresh {
+		return entry.response(req), nil
+	}
+
+	conditional := req
+	if entry != nil && (entry.etag != "" || entry.lastModified != "") {
+		conditional = req.Clone(req.Context())
+		if entry.etag != "" {
+			conditional.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			conditional.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(conditional)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+		t.cache.mu.Lock()
+		entry.storedAt = time.Now()
+		t.cache.mu.Unlock()
+		return entry.response(req), nil
+	case resp.StatusCode != http.StatusOK:
+		return resp, nil
+	}
+
+	body, err := utils.ReadBody(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	stored := &cachedResponse{
+		header:       resp.Header.Clone(),
+		body:         body,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		storedAt:     time.Now(),
+	}
+	t.cache.mu.Lock()
+	t.cache.entries[key] = stored
+	t.cache.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// response 用缓存内容构造200响应
+func (e *cachedResponse) response(req *http.Request) *http.Response {
+	header := e.header.Clone()
+	header.Set("Content-Length", strconv.Itoa(len(e.body)))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
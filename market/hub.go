@@ -0,0 +1,283 @@
+package market
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// Ticker 实时行情快照（标记价格 + 最优买卖价）
+type Ticker struct {
+	Symbol          string
+	MarkPrice       float64
+	IndexPrice      float64
+	FundingRate     float64
+	NextFundingTime time.Time
+	MarkUpdatedAt   time.Time
+
+	BidPrice      float64
+	BidQty        float64
+	AskPrice      float64
+	AskQty        float64
+	BookUpdatedAt time.Time
+}
+
+// DataHub 实时行情中心
+// 通过币安WebSocket接收全市场标记价格（1秒推送）和关注币种的bookTicker，
+// 供止损管理和下单前检查使用比最近收盘K线更新的价格；所有流复用streamManager管理的组合流连接
+type DataHub struct {
+	mu       sync.RWMutex
+	tickers  map[string]*Ticker
+	watched  map[string]int             // 订阅bookTicker的币种 -> 关注方数量
+	watchers map[string]map[string]bool // 关注方（如trader ID）-> 关注的币种
+	klines   map[string]*klineSeries    // K线缓存（key: SYMBOL_interval）
+
+	retention KlineRetention // K线缓存保留策略
+
+	running     bool
+	startedAt   time.Time // 启动时间
+	lastMessage time.Time // 最近一次收到标记价格推送的时间（用于判断连接是否中断）
+	closeOnce   sync.Once
+	quit        chan struct{}
+	streams     *streamManager // 组合流订阅（标记价格、bookTicker、K线）
+
+	poll        pollScheduler      // 衍生品数据（持仓量、资金费率）的自适应轮询
+	positioning positioningTracker // 持仓量/资金费率变化事件
+}
+
+// Hub 全局实时行情中心（未启动时查询返回无数据，调用方应回退到REST/K线价格）
+var Hub = NewDataHub()
+
+// NewDataHub 创建实时行情中心
+func NewDataHub() *DataHub {
+	quit := make(chan struct{})
+	return &DataHub{
+		tickers:   make(map[string]*Ticker),
+		watched:   make(map[string]int),
+		watchers:  make(map[string]map[string]bool),
+		klines:    make(map[string]*klineSeries),
+		retention: defaultKlineRetention,
+		quit:      quit,
+		streams:   newStreamManager(binanceCombinedStreamURL, quit),
+	}
+}
+
+// Start 启动WebSocket订阅（断线自动重连）
+func (h *DataHub) Start() {
+	h.mu.Lock()
+	if h.running {
+		h.mu.Unlock()
+		return
+	}
+	h.running = true
+	h.startedAt = time.Now()
+	h.mu.Unlock()
+
+	log.Printf("📡 实时行情中心已启动（标记价格 + bookTicker WebSocket）")
+	if err := h.streams.subscribe([]string{"!markPrice@arr@1s"}, h.handleMarkPrices, nil); err != nil {
+		log.Printf("⚠️  标记价格订阅失败: %v", err)
+	}
+	go h.releaseLoop()
+}
+
+// releaseLoop 定期释放闲置的K线缓存
+func (h *DataHub) releaseLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.releaseIdleKlines()
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// Stop 停止所有订阅（关闭全部连接）
+func (h *DataHub) Stop() {
+	h.closeOnce.Do(func() {
+		close(h.quit)
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.running = false
+		h.closeSubscribers()
+	})
+}
+
+// Running 是否已启动
+func (h *DataHub) Running() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.running
+}
+
+// Silence 距最近一次收到标记价格推送的时长（未收到过时从启动算起；未启动返回0）
+// 标记价格每秒推送一次，持续数秒无推送即表示WebSocket已断开或卡住
+func (h *DataHub) Silence() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if !h.running {
+		return 0
+	}
+	if h.lastMessage.IsZero() {
+		return time.Since(h.startedAt)
+	}
+	return time.Since(h.lastMessage)
+}
+
+// Watch 设置关注方（如trader ID）关注的币种最优买卖价，替换该关注方之前的集合
+// 按全部关注方的引用计数计算变化：新增的币种批量订阅bookTicker，不再有任何关注方的币种批量取消订阅，不影响其它流
+func (h *DataHub) Watch(owner string, symbols ...string) {
+	next := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		if symbol = Normalize(symbol); symbol != "" {
+			next[symbol] = true
+		}
+	}
+
+	h.mu.Lock()
+	if !h.running {
+		h.mu.Unlock()
+		return
+	}
+	previous := h.watchers[owner]
+	var added, removed []string
+	for symbol := range next {
+		if previous[symbol] {
+			continue
+		}
+		h.watched[symbol]++
+		if h.watched[symbol] == 1 {
+			added = append(added, strings.ToLower(symbol)+"@bookTicker")
+		}
+	}
+	for symbol := range previous {
+		if next[symbol] {
+			continue
+		}
+		if h.watched[symbol]--; h.watched[symbol] <= 0 {
+			delete(h.watched, symbol)
+			removed = append(removed, strings.ToLower(symbol)+"@bookTicker")
+		}
+	}
+	if len(next) == 0 {
+		delete(h.watchers, owner)
+	} else {
+		h.watchers[owner] = next
+	}
+	h.mu.Unlock()
+
+	if len(removed) > 0 {
+		sort.Strings(removed)
+		h.streams.unsubscribe(removed)
+	}
+	if len(added) > 0 {
+		sort.Strings(added)
+		if err := h.streams.subscribe(added, h.handleBookTicker, nil); err != nil {
+			log.Printf("⚠️  bookTicker订阅失败: %v", err)
+		}
+	}
+}
+
+// Unwatch 取消关注方关注的全部币种（没有其它关注方的币种取消订阅）
+func (h *DataHub) Unwatch(owner string) {
+	h.Watch(owner)
+}
+
+// handleMarkPrices 处理标记价格推送
+func (h *DataHub) handleMarkPrices(data []byte) {
+	var events futures.WsAllMarkPriceEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		log.Printf("⚠️  解析标记价格推送失败: %v", err)
+		return
+	}
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, event := range events {
+		ticker := h.ticker(event.Symbol)
+		ticker.MarkPrice, _ = strconv.ParseFloat(event.MarkPrice, 64)
+		ticker.IndexPrice, _ = strconv.ParseFloat(event.IndexPrice, 64)
+		ticker.FundingRate, _ = strconv.ParseFloat(event.FundingRate, 64)
+		ticker.NextFundingTime = time.UnixMilli(event.NextFundingTime)
+		ticker.MarkUpdatedAt = now
+	}
+	h.lastMessage = now
+}
+
+// handleBookTicker 处理最优买卖价推送
+func (h *DataHub) handleBookTicker(data []byte) {
+	var event futures.WsBookTickerEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Printf("⚠️  解析bookTicker推送失败: %v", err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ticker := h.ticker(event.Symbol)
+	ticker.BidPrice, _ = strconv.ParseFloat(event.BestBidPrice, 64)
+	ticker.BidQty, _ = strconv.ParseFloat(event.BestBidQty, 64)
+	ticker.AskPrice, _ = strconv.ParseFloat(event.BestAskPrice, 64)
+	ticker.AskQty, _ = strconv.ParseFloat(event.BestAskQty, 64)
+	ticker.BookUpdatedAt = time.Now()
+}
+
+// ticker 获取或创建币种快照（调用方需持有写锁）
+func (h *DataHub) ticker(symbol string) *Ticker {
+	ticker, ok := h.tickers[symbol]
+	if !ok {
+		ticker = &Ticker{Symbol: symbol}
+		h.tickers[symbol] = ticker
+	}
+	return ticker
+}
+
+// Ticker 获取币种的实时行情快照
+func (h *DataHub) Ticker(symbol string) (Ticker, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ticker, ok := h.tickers[Normalize(symbol)]
+	if !ok {
+		return Ticker{}, false
+	}
+	return *ticker, true
+}
+
+// MarkPrice 获取不超过maxAge的标记价格
+func (h *DataHub) MarkPrice(symbol string, maxAge time.Duration) (float64, bool) {
+	ticker, ok := h.Ticker(symbol)
+	if !ok || ticker.MarkPrice <= 0 || time.Since(ticker.MarkUpdatedAt) > maxAge {
+		return 0, false
+	}
+	return ticker.MarkPrice, true
+}
+
+// BestBidAsk 获取不超过maxAge的最优买卖价
+func (h *DataHub) BestBidAsk(symbol string, maxAge time.Duration) (float64, float64, bool) {
+	ticker, ok := h.Ticker(symbol)
+	if !ok || ticker.BidPrice <= 0 || ticker.AskPrice <= 0 || time.Since(ticker.BookUpdatedAt) > maxAge {
+		return 0, 0, false
+	}
+	return ticker.BidPrice, ticker.AskPrice, true
+}
+
+// SpreadBps 获取不超过maxAge的买卖价差（相对中间价，bps）
+func (h *DataHub) SpreadBps(symbol string, maxAge time.Duration) (float64, bool) {
+	bid, ask, ok := h.BestBidAsk(symbol, maxAge)
+	if !ok {
+		return 0, false
+	}
+	return (ask - bid) / ((ask + bid) / 2) * 10000, true
+}
@@ -0,0 +1,268 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// KlineRetention 实时行情中心K线缓存的保留策略（长时间运行时限制内存占用）
+type KlineRetention struct {
+	Recent           int           // 每个币种/周期保留的完整精度已完成K线数量（默认500）
+	DownsampleFactor int           // 移出近期缓存的K线每N根合并为一根归档（默认4）
+	Archive          int           // 每个币种/周期保留的合并后K线数量（0表示不归档，移出即丢弃）
+	IdleTimeout      time.Duration // 超过该时长未被读取的币种/周期取消订阅并释放缓存（0表示一直保留）
+}
+
+// defaultKlineRetention 默认只保留最近500根K线，不归档、不释放
+var defaultKlineRetention = KlineRetention{Recent: 500, DownsampleFactor: 4}
+
+// klineSeries 实时行情中心缓存的K线序列（只保存已完成K线，按开盘时间升序）
+type klineSeries struct {
+	klines    []Kline
+	confirmed map[int64]bool    // 已由REST确认的K线（按OpenTime）
+	archive   *klineDownsampler // 移出近期缓存的K线降采样归档（未启用时为nil）
+	stream    string
+	lastRead  atomic.Int64 // 最近一次读取（或订阅）的时间（Unix毫秒），用于释放不再使用的序列
+
+	subscribers map[*klineSubscriber]bool // Subscribe 的外部订阅者
+}
+
+// SetKlineRetention 设置K线缓存保留策略（应在订阅K线之前调用，未设置的字段使用默认值）
+func (h *DataHub) SetKlineRetention(retention KlineRetention) error {
+	if retention.Recent < 0 || retention.DownsampleFactor < 0 || retention.Archive < 0 || retention.IdleTimeout < 0 {
+		return fmt.Errorf("K线缓存参数不能为负数")
+	}
+	if retention.Recent == 0 {
+		retention.Recent = defaultKlineRetention.Recent
+	}
+	if retention.DownsampleFactor == 0 {
+		retention.DownsampleFactor = defaultKlineRetention.DownsampleFactor
+	}
+
+	h.mu.Lock()
+	h.retention = retention
+	h.mu.Unlock()
+	log.Printf("✓ K线缓存: 最近%d根，归档%d根（每%d根合并），闲置释放 %v",
+		retention.Recent, retention.Archive, retention.DownsampleFactor, retention.IdleTimeout)
+	return nil
+}
+
+// WatchKlines 订阅币种/周期的K线流（首次订阅及每次重连后通过REST回补缺口）
+func (h *DataHub) WatchKlines(symbol, interval string) {
+	symbol = Normalize(symbol)
+	key := symbol + "_" + interval
+
+	stream := strings.ToLower(symbol) + "@kline_" + interval
+
+	h.mu.Lock()
+	if !h.running || h.klines[key] != nil {
+		h.mu.Unlock()
+		return
+	}
+	series := &klineSeries{confirmed: make(map[int64]bool), stream: stream}
+	series.lastRead.Store(time.Now().UnixMilli())
+	if h.retention.Archive > 0 {
+		if duration, err := IntervalDuration(interval); err == nil {
+			series.archive = newKlineDownsampler(duration.Milliseconds(), h.retention.DownsampleFactor, h.retention.Archive)
+		}
+	}
+	h.klines[key] = series
+	h.mu.Unlock()
+	handler := func(data []byte) { h.handleKline(symbol, interval, data) }
+	backfill := func() { h.backfillKlines(symbol, interval) }
+	if err := h.streams.subscribe([]string{stream}, handler, backfill); err != nil {
+		// 未订阅时缓存保持为空，Klines返回false，调用方回退到REST
+		log.Printf("⚠️  %s %s K线订阅失败: %v", symbol, interval, err)
+	}
+}
+
+// Klines 获取缓存的最近limit根已完成K线
+// 缓存不足limit根或缺少最近一根已完成K线（推送中断）时返回false，调用方应回退到REST
+func (h *DataHub) Klines(symbol, interval string, limit int) ([]Kline, bool) {
+	duration, err := IntervalDuration(interval)
+	if err != nil {
+		return nil, false
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	series := h.klines[Normalize(symbol)+"_"+interval]
+	if series == nil {
+		return nil, false
+	}
+	series.lastRead.Store(time.Now().UnixMilli())
+	if len(series.klines) < limit || len(series.klines) == 0 {
+		return nil, false
+	}
+	last := series.klines[len(series.klines)-1]
+	if Clock.Now().UnixMilli()-last.CloseTime > duration.Milliseconds() {
+		return nil, false
+	}
+
+	result := make([]Kline, limit)
+	copy(result, series.klines[len(series.klines)-limit:])
+	return result, true
+}
+
+// backfillKlines 通过REST获取最近的已完成K线并合并（REST数据为准）
+func (h *DataHub) backfillKlines(symbol, interval string) {
+	h.mu.RLock()
+	limit := h.retention.Recent
+	h.mu.RUnlock()
+	klines, err := getKlines(symbol, interval, limit)
+	if err != nil {
+		log.Printf("⚠️  %s %s K线回补失败: %v", symbol, interval, err)
+		return
+	}
+	h.mergeKlines(symbol, interval, filterCompletedKlines(klines), true)
+}
+
+// handleKline 处理K线推送（只合并已收盘的K线）
+func (h *DataHub) handleKline(symbol, interval string, data []byte) {
+	var event futures.WsKlineEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Printf("⚠️  解析%s %s K线推送失败: %v", symbol, interval, err)
+		return
+	}
+	if !event.Kline.IsFinal {
+		return
+	}
+	k := Kline{OpenTime: event.Kline.StartTime, CloseTime: event.Kline.EndTime}
+	k.Open, _ = strconv.ParseFloat(event.Kline.Open, 64)
+	k.High, _ = strconv.ParseFloat(event.Kline.High, 64)
+	k.Low, _ = strconv.ParseFloat(event.Kline.Low, 64)
+	k.Close, _ = strconv.ParseFloat(event.Kline.Close, 64)
+	k.Volume, _ = strconv.ParseFloat(event.Kline.Volume, 64)
+	h.mergeKlines(symbol, interval, []Kline{k}, false)
+}
+
+// mergeKlines 将K线合并到缓存：按OpenTime去重，丢弃OHLC不一致的K线
+// 同一根K线冲突时以REST确认的值为准（fromREST覆盖推送值，推送值不覆盖已确认的值）
+func (h *DataHub) mergeKlines(symbol, interval string, incoming []Kline, fromREST bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	series := h.klines[symbol+"_"+interval]
+	if series == nil {
+		return
+	}
+
+	index := make(map[int64]int, len(series.klines))
+	for i, k := range series.klines {
+		index[k.OpenTime] = i
+	}
+
+	appended := false
+	for _, k := range incoming {
+		if err := validateKline(k); err != nil {
+			log.Printf("⚠️  %s %s 丢弃异常K线 @ %s: %v", symbol, interval,
+				time.UnixMilli(k.OpenTime).UTC().Format("01-02 15:04"), err)
+			continue
+		}
+
+		i, exists := index[k.OpenTime]
+		switch {
+		case !exists:
+			index[k.OpenTime] = len(series.klines)
+			series.klines = append(series.klines, k)
+			appended = true
+		case fromREST:
+			if series.klines[i] != k && !series.confirmed[k.OpenTime] {
+				log.Printf("⚠️  %s %s K线 @ %s 推送值与REST不一致，以REST为准", symbol, interval,
+					time.UnixMilli(k.OpenTime).UTC().Format("01-02 15:04"))
+			}
+			series.klines[i] = k
+		case !series.confirmed[k.OpenTime]:
+			series.klines[i] = k
+		}
+		if fromREST {
+			series.confirmed[k.OpenTime] = true
+		}
+	}
+
+	if appended {
+		sort.Slice(series.klines, func(i, j int) bool {
+			return series.klines[i].OpenTime < series.klines[j].OpenTime
+		})
+		h.publishKlines(symbol, interval, series)
+	}
+	// 超出保留数量的最旧K线移入降采样归档（原地移动，复用底层数组）
+	if excess := len(series.klines) - h.retention.Recent; excess > 0 {
+		for _, k := range series.klines[:excess] {
+			delete(series.confirmed, k.OpenTime)
+			if series.archive != nil {
+				series.archive.add(k)
+			}
+		}
+		n := copy(series.klines, series.klines[excess:])
+		series.klines = series.klines[:n]
+	}
+}
+
+// KlineHistory 缓存的K线：archive为降采样归档（较旧，粗粒度），recent为近期完整精度K线，均按时间升序
+// 未订阅该币种/周期时返回false
+func (h *DataHub) KlineHistory(symbol, interval string) (archive, recent []Kline, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	series := h.klines[Normalize(symbol)+"_"+interval]
+	if series == nil {
+		return nil, nil, false
+	}
+	if series.archive != nil {
+		archive = series.archive.klines()
+	}
+	return archive, append([]Kline(nil), series.klines...), true
+}
+
+// releaseIdleKlines 取消订阅并释放超过IdleTimeout未被读取且没有外部订阅者的K线序列（候选币种轮换后不再使用的币种）
+func (h *DataHub) releaseIdleKlines() {
+	h.mu.Lock()
+	timeout := h.retention.IdleTimeout
+	if timeout <= 0 {
+		h.mu.Unlock()
+		return
+	}
+	cutoff := time.Now().Add(-timeout).UnixMilli()
+	var streams, keys []string
+	for key, series := range h.klines {
+		if series.lastRead.Load() < cutoff && !series.subscribed() {
+			streams = append(streams, series.stream)
+			keys = append(keys, key)
+			delete(h.klines, key)
+		}
+	}
+	h.mu.Unlock()
+
+	if len(streams) > 0 {
+		h.streams.unsubscribe(streams)
+		log.Printf("🗑️ 已释放 %d 个闲置K线缓存: %v", len(keys), keys)
+	}
+}
+
+// validateKline 校验K线OHLC一致性
+func validateKline(k Kline) error {
+	switch {
+	case k.OpenTime <= 0 || k.CloseTime <= k.OpenTime:
+		return fmt.Errorf("时间无效 (open=%d close=%d)", k.OpenTime, k.CloseTime)
+	case k.Low <= 0 || k.Open <= 0 || k.Close <= 0:
+		return fmt.Errorf("价格无效 (O=%g H=%g L=%g C=%g)", k.Open, k.High, k.Low, k.Close)
+	case k.High < k.Low:
+		return fmt.Errorf("最高价低于最低价 (H=%g L=%g)", k.High, k.Low)
+	case k.High < math.Max(k.Open, k.Close) || k.Low > math.Min(k.Open, k.Close):
+		return fmt.Errorf("开盘/收盘价超出高低区间 (O=%g H=%g L=%g C=%g)", k.Open, k.High, k.Low, k.Close)
+	case k.Volume < 0 || math.IsNaN(k.Volume):
+		return fmt.Errorf("成交量无效 (%g)", k.Volume)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	pollActiveThreshold = 1.5  // 活跃度达到该值视为活跃币种（可使用全部轮询预算）
+	pollQuietReserve    = 0.25 // 剩余预算低于该比例时只刷新活跃币种
+	pollMarkMove        = 0.01 // 标记价格较上次刷新变化超过1%时提前刷新
+)
+
+// PollConfig 按币种自适应轮询REST衍生品数据（持仓量、资金费率）的配置
+// 平静币种按MaxInterval刷新，成交量/波动率放大的币种缩短到MinInterval，所有币种共享每分钟的请求预算
+type PollConfig struct {
+	MinInterval     time.Duration // 活跃币种的最短刷新间隔（默认30秒）
+	MaxInterval     time.Duration // 平静币种的最长刷新间隔（默认5分钟）
+	BudgetPerMinute int           // 全部币种每分钟最多的轮询次数（0表示不限制）
+}
+
+// interval 按活跃度计算刷新间隔：活跃度≤1使用最长间隔，之后按活跃度的平方缩短
+func (c PollConfig) interval(activity float64) time.Duration {
+	if activity <= 1 {
+		return c.MaxInterval
+	}
+	return max(time.Duration(float64(c.MaxInterval)/(activity*activity)), c.MinInterval)
+}
+
+// pollState 单个币种的衍生品数据缓存和活跃度
+type pollState struct {
+	oi           *OIData
+	fundingRate  float64
+	fundingHours int
+	nextFunding  time.Time
+	fetchedAt    time.Time
+	fetchMark    float64 // 刷新时的标记价格（用于检测价格异动）
+	activity     float64 // 最近一次计算的活跃度（1为正常）
+}
+
+// pollScheduler 衍生品数据的自适应轮询调度（令牌桶限制全局请求速率）
+type pollScheduler struct {
+	mu      sync.Mutex
+	enabled bool
+	config  PollConfig
+	states  map[string]*pollState
+	tokens  float64
+	refill  time.Time
+}
+
+// SetPollConfig 启用衍生品数据的自适应轮询（未调用时每次获取市场数据都请求REST）
+func (h *DataHub) SetPollConfig(config PollConfig) error {
+	if config.MinInterval <= 0 {
+		config.MinInterval = 30 * time.Second
+	}
+	if config.MaxInterval <= 0 {
+		config.MaxInterval = 5 * time.Minute
+	}
+	if config.MaxInterval < config.MinInterval {
+		return fmt.Errorf("轮询最长间隔 %v 小于最短间隔 %v", config.MaxInterval, config.MinInterval)
+	}
+	if config.BudgetPerMinute < 0 {
+		return fmt.Errorf("轮询预算不能为负数")
+	}
+
+	p := &h.poll
+	p.mu.Lock()
+	p.enabled = true
+	p.config = config
+	p.tokens = float64(config.BudgetPerMinute)
+	p.refill = time.Now()
+	if p.states == nil {
+		p.states = make(map[string]*pollState)
+	}
+	p.mu.Unlock()
+	log.Printf("⏱ 衍生品数据自适应轮询: 间隔 %v~%v，预算 %d次/分钟", config.MinInterval, config.MaxInterval, config.BudgetPerMinute)
+	return nil
+}
+
+// ReportActivity 记录币种的活跃度（成交量/波动率相对均值的倍数），用于决定下次刷新间隔
+func (h *DataHub) ReportActivity(symbol string, activity float64) {
+	p := &h.poll
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.enabled {
+		return
+	}
+	if state := p.states[Normalize(symbol)]; state != nil {
+		state.activity = activity
+	}
+}
+
+// pollDerivatives 获取持仓量和资金费率：未到刷新时间或预算不足时返回缓存
+// 首次获取总是请求REST；请求失败时保留上次的缓存
+func (h *DataHub) pollDerivatives(provider Provider, derivatives DerivativesProvider, symbol string) (*OIData, float64, int, time.Time) {
+	p := &h.poll
+	p.mu.Lock()
+	state := p.states[symbol]
+	if !p.enabled || state == nil || p.due(h, symbol, state) {
+		p.mu.Unlock()
+		return h.fetchDerivatives(provider, derivatives, symbol)
+	}
+	oi, rate, hours, next := state.oi, state.fundingRate, state.fundingHours, state.nextFunding
+	p.mu.Unlock()
+	return oi, rate, hours, next
+}
+
+// due 是否需要刷新（调用方持有p.mu）：到达按活跃度计算的间隔或标记价格异动，且预算允许
+func (p *pollScheduler) due(h *DataHub, symbol string, state *pollState) bool {
+	now := time.Now()
+	active := state.activity >= pollActiveThreshold
+	if now.Sub(state.fetchedAt) < p.config.interval(state.activity) {
+		mark, ok := h.MarkPrice(symbol, 10*time.Second)
+		if !ok || state.fetchMark <= 0 || math.Abs(mark/state.fetchMark-1) < pollMarkMove {
+			return false
+		}
+		active = true // 价格异动按活跃币种处理
+	}
+	return p.take(now, active)
+}
+
+// take 从令牌桶取一次请求额度，平静币种不能使用最后的保留额度（调用方持有p.mu）
+func (p *pollScheduler) take(now time.Time, active bool) bool {
+	budget := float64(p.config.BudgetPerMinute)
+	if budget <= 0 {
+		return true
+	}
+	p.tokens = math.Min(budget, p.tokens+now.Sub(p.refill).Minutes()*budget)
+	p.refill = now
+	reserve := budget * pollQuietReserve
+	if active {
+		reserve = 0
+	}
+	if p.tokens-1 < reserve {
+		return false
+	}
+	p.tokens--
+	return true
+}
+
+// fetchDerivatives 请求REST获取持仓量和资金费率，启用轮询时更新缓存
+func (h *DataHub) fetchDerivatives(provider Provider, derivatives DerivativesProvider, symbol string) (*OIData, float64, int, time.Time) {
+	oi, oiErr := derivatives.GetOpenInterest(symbol)
+	rate, hours, rateErr := derivatives.GetFundingRate(symbol)
+	next := nextFundingTime(provider, symbol, hours)
+
+	p := &h.poll
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.enabled {
+		if oiErr != nil {
+			// OI失败不影响整体,使用默认值
+			oi = &OIData{Latest: 0, Average: 0}
+		}
+		return oi, rate, hours, next
+	}
+
+	state := p.states[symbol]
+	if state == nil {
+		state = &pollState{activity: 1}
+		p.states[symbol] = state
+	}
+	if oiErr == nil {
+		state.oi = oi
+	} else if state.oi == nil {
+		state.oi = &OIData{Latest: 0, Average: 0}
+	}
+	if rateErr == nil {
+		state.fundingRate, state.fundingHours, state.nextFunding = rate, hours, next
+	}
+	state.fetchedAt = time.Now()
+	if mark, ok := h.MarkPrice(symbol, 10*time.Second); ok {
+		state.fetchMark = mark
+	}
+	return state.oi, state.fundingRate, state.fundingHours, state.nextFunding
+}
+
+// activityScore 币种活跃度：最新入场K线成交量相对前20根均量的倍数，与趋势周期ATR3/ATR14取较大值
+func activityScore(entryKlines []Kline, longerTerm *LongerTermData) float64 {
+	activity := 1.0
+	if n := len(entryKlines); n > 20 {
+		sum := 0.0
+		for _, k := range entryKlines[n-21 : n-1] {
+			sum += k.Volume
+		}
+		if sum > 0 {
+			activity = entryKlines[n-1].Volume / (sum / 20)
+		}
+	}
+	if longerTerm != nil && longerTerm.Available("atr_fast") && longerTerm.Available("atr_slow") && longerTerm.ATR14 > 0 {
+		activity = math.Max(activity, longerTerm.ATR3/longerTerm.ATR14)
+	}
+	return activity
+}
@@ -0,0 +1,316 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 组合流订阅参数
+const (
+	binanceCombinedStreamURL = "wss://fstream.binance.com/stream"
+
+	streamsPerConnection  = 200                           // 单个连接最多订阅的流数量（币安限制每个连接的流数量）
+	maxStreamConnections  = 5                             // 最多同时保持的连接数
+	streamRotateAfter     = 23*time.Hour + 30*time.Minute // 币安连接24小时后强制断开，提前轮换连接并重新订阅
+	streamReadTimeout     = 5 * time.Minute               // 超过该时间未收到任何消息（含ping）视为连接卡住
+	streamWriteInterval   = 150 * time.Millisecond        // 订阅消息的最小发送间隔（币安限制每个连接每秒10条）
+	streamSubscribeBatch  = 50                            // 每条SUBSCRIBE消息最多包含的流
+	streamRetryDelay      = 5 * time.Second               // 连接失败或刚建立就断开时的重连等待
+	streamStableConnected = time.Minute                   // 连接保持超过该时长后断开立即重连（如24小时强制断开）
+)
+
+// streamSubscription 单个流的订阅
+type streamSubscription struct {
+	handler   func(data []byte)
+	onConnect func() // 每次（重新）订阅成功后调用（如REST回补断线期间的K线），可为nil
+	conn      *streamConn
+}
+
+// streamManager 币安组合流订阅管理：把多个币种的流复用到有限数量的连接上（每个连接最多streamsPerConnection个流），
+// 断线或24小时强制断开后自动重连并重新订阅，调用方无需感知连接变化
+type streamManager struct {
+	url  string
+	quit chan struct{}
+
+	mu     sync.RWMutex
+	subs   map[string]*streamSubscription // 流名称（如btcusdt@kline_15m）-> 订阅
+	conns  []*streamConn
+	nextID int
+}
+
+// streamConn 一个组合流连接及其承载的流
+type streamConn struct {
+	id      int
+	manager *streamManager
+	streams map[string]bool // 由manager.mu保护
+	ws      *websocket.Conn // 由manager.mu保护，断线期间为nil
+
+	writeMu   sync.Mutex
+	lastWrite time.Time
+	requestID int64
+}
+
+func newStreamManager(url string, quit chan struct{}) *streamManager {
+	return &streamManager{url: url, quit: quit, subs: make(map[string]*streamSubscription)}
+}
+
+// subscribe 订阅流（已订阅的流只更新处理函数）：新流分配到未满的连接，全部连接已满时新建连接，
+// 达到连接上限时返回错误（调用方应回退到REST）
+func (m *streamManager) subscribe(streams []string, handler func(data []byte), onConnect func()) error {
+	m.mu.Lock()
+	pending := make(map[*streamConn][]string)
+	var err error
+	for _, stream := range streams {
+		if sub := m.subs[stream]; sub != nil {
+			sub.handler, sub.onConnect = handler, onConnect
+			continue
+		}
+		conn := m.connWithRoom()
+		if conn == nil {
+			err = fmt.Errorf("WebSocket订阅已达上限（%d个连接×%d个流），%s 未订阅", maxStreamConnections, streamsPerConnection, stream)
+			break
+		}
+		m.subs[stream] = &streamSubscription{handler: handler, onConnect: onConnect, conn: conn}
+		conn.streams[stream] = true
+		if conn.ws != nil {
+			pending[conn] = append(pending[conn], stream)
+		}
+	}
+	m.mu.Unlock()
+
+	// 已连接的连接直接发送SUBSCRIBE；未连接的在连接建立后统一订阅
+	for conn, added := range pending {
+		if sendErr := conn.sendBatched("SUBSCRIBE", added); sendErr != nil {
+			log.Printf("⚠️  WebSocket连接#%d 订阅失败（重连后重新订阅）: %v", conn.id, sendErr)
+			continue
+		}
+		if onConnect != nil {
+			go onConnect()
+		}
+	}
+	return err
+}
+
+// unsubscribe 取消订阅（未订阅的流忽略），连接上的空位可分配给新的流
+func (m *streamManager) unsubscribe(streams []string) {
+	m.mu.Lock()
+	pending := make(map[*streamConn][]string)
+	for _, stream := range streams {
+		sub := m.subs[stream]
+		if sub == nil {
+			continue
+		}
+		delete(m.subs, stream)
+		delete(sub.conn.streams, stream)
+		if sub.conn.ws != nil {
+			pending[sub.conn] = append(pending[sub.conn], stream)
+		}
+	}
+	m.mu.Unlock()
+
+	for conn, removed := range pending {
+		if err := conn.sendBatched("UNSUBSCRIBE", removed); err != nil {
+			log.Printf("⚠️  WebSocket连接#%d 取消订阅失败: %v", conn.id, err)
+		}
+	}
+}
+
+// connWithRoom 返回未满的连接，需要时新建连接（调用方持有m.mu）
+func (m *streamManager) connWithRoom() *streamConn {
+	for _, conn := range m.conns {
+		if len(conn.streams) < streamsPerConnection {
+			return conn
+		}
+	}
+	if len(m.conns) >= maxStreamConnections {
+		return nil
+	}
+	m.nextID++
+	conn := &streamConn{id: m.nextID, manager: m, streams: make(map[string]bool)}
+	m.conns = append(m.conns, conn)
+	go conn.run()
+	return conn
+}
+
+// run 保持连接：建立连接后重新订阅全部流，断线后重连（刚建立就断开时等待streamRetryDelay）
+func (c *streamConn) run() {
+	m := c.manager
+	dialer := websocket.Dialer{
+		Proxy:             http.ProxyFromEnvironment,
+		HandshakeTimeout:  45 * time.Second,
+		EnableCompression: true,
+	}
+	for {
+		ws, _, err := dialer.Dial(m.url, nil)
+		if err != nil {
+			log.Printf("⚠️  WebSocket连接#%d 建立失败: %v，%v后重试", c.id, err, streamRetryDelay)
+			if !m.sleep(streamRetryDelay) {
+				return
+			}
+			continue
+		}
+		connectedAt := time.Now()
+
+		m.mu.Lock()
+		c.ws = ws
+		streams := make([]string, 0, len(c.streams))
+		for stream := range c.streams {
+			streams = append(streams, stream)
+		}
+		m.mu.Unlock()
+		sort.Strings(streams)
+		log.Printf("📡 WebSocket连接#%d 已连接，订阅%d个流", c.id, len(streams))
+
+		err = c.resubscribe(streams)
+		if err == nil {
+			err = c.read(ws)
+		}
+
+		m.mu.Lock()
+		c.ws = nil
+		m.mu.Unlock()
+		ws.Close()
+
+		select {
+		case <-m.quit:
+			return
+		default:
+		}
+		lived := time.Since(connectedAt)
+		if lived >= streamRotateAfter {
+			log.Printf("🔄 WebSocket连接#%d 已使用%v，轮换连接并重新订阅%d个流", c.id, lived.Round(time.Minute), len(streams))
+			continue
+		}
+		log.Printf("⚠️  WebSocket连接#%d 断开（%d个流）: %v", c.id, len(streams), err)
+		if lived < streamStableConnected && !m.sleep(streamRetryDelay) {
+			return
+		}
+	}
+}
+
+// resubscribe 连接建立后分批订阅全部流，并调用各订阅的onConnect
+func (c *streamConn) resubscribe(streams []string) error {
+	if err := c.sendBatched("SUBSCRIBE", streams); err != nil {
+		return err
+	}
+
+	m := c.manager
+	m.mu.RLock()
+	var hooks []func()
+	for _, stream := range streams {
+		if sub := m.subs[stream]; sub != nil && sub.onConnect != nil {
+			hooks = append(hooks, sub.onConnect)
+		}
+	}
+	m.mu.RUnlock()
+	for _, hook := range hooks {
+		go hook()
+	}
+	return nil
+}
+
+// read 读取消息并分发到订阅的处理函数，连接出错、超时、到达轮换时间或停止时返回
+func (c *streamConn) read(ws *websocket.Conn) error {
+	m := c.manager
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-m.quit:
+			ws.Close()
+		case <-stop:
+		}
+	}()
+	rotate := time.AfterFunc(streamRotateAfter, func() { ws.Close() })
+	defer rotate.Stop()
+
+	ws.SetPingHandler(func(data string) error {
+		ws.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		return ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(10*time.Second))
+	})
+
+	for {
+		ws.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		var envelope struct {
+			Stream string          `json:"stream"`
+			Data   json.RawMessage `json:"data"`
+			Error  *struct {
+				Code int    `json:"code"`
+				Msg  string `json:"msg"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(message, &envelope); err != nil {
+			continue
+		}
+		if envelope.Error != nil {
+			log.Printf("⚠️  WebSocket连接#%d 订阅请求出错: %d %s", c.id, envelope.Error.Code, envelope.Error.Msg)
+			continue
+		}
+		if envelope.Stream == "" {
+			continue // SUBSCRIBE的确认消息
+		}
+
+		var handler func(data []byte)
+		m.mu.RLock()
+		if sub := m.subs[envelope.Stream]; sub != nil {
+			handler = sub.handler
+		}
+		m.mu.RUnlock()
+		if handler != nil {
+			handler(envelope.Data)
+		}
+	}
+}
+
+// sendBatched 分批发送订阅/取消订阅请求（每条消息最多streamSubscribeBatch个流）
+func (c *streamConn) sendBatched(method string, streams []string) error {
+	for start := 0; start < len(streams); start += streamSubscribeBatch {
+		end := min(start+streamSubscribeBatch, len(streams))
+		if err := c.send(method, streams[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send 发送订阅请求（遵守发送频率限制；未连接时跳过，连接建立后统一订阅）
+func (c *streamConn) send(method string, streams []string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.manager.mu.RLock()
+	ws := c.ws
+	c.manager.mu.RUnlock()
+	if ws == nil {
+		return nil
+	}
+	if wait := streamWriteInterval - time.Since(c.lastWrite); wait > 0 {
+		time.Sleep(wait)
+	}
+	c.requestID++
+	err := ws.WriteJSON(map[string]interface{}{"method": method, "params": streams, "id": c.requestID})
+	c.lastWrite = time.Now()
+	return err
+}
+
+// sleep 等待d，停止时返回false
+func (m *streamManager) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-m.quit:
+		return false
+	}
+}
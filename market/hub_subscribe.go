@@ -0,0 +1,101 @@
+package market
+
+import (
+	"log"
+	"sync"
+)
+
+// klineSubscriberBuffer 每个订阅者通道的缓冲K线数
+const klineSubscriberBuffer = 64
+
+// klineSubscriber 外部订阅者（由DataHub.mu保护）
+type klineSubscriber struct {
+	ch       chan Kline
+	since    int64 // 订阅时间（Unix毫秒），只发送之后收盘的K线
+	lastSent int64 // 最近发送的K线开盘时间（保证按时间递增、不重复）
+	dropped  int   // 消费过慢丢弃的K线数
+	once     sync.Once
+}
+
+// close 关闭通道（取消订阅与停止实时行情中心可能同时发生，只关闭一次）
+func (s *klineSubscriber) close() {
+	s.once.Do(func() { close(s.ch) })
+}
+
+// Subscribe 订阅币种/周期的已完成K线，复用实时行情中心的WebSocket连接（与内部K线缓存共享同一个流）
+// 订阅之后收盘的每根K线经过校验、去重后按开盘时间递增发送到返回的通道；断线期间缺失的K线在重连回补后补发
+// cancel 取消订阅并关闭通道，实时行情中心停止时通道也会关闭；实时行情中心未启动时返回已关闭的通道
+// 订阅者处理过慢（通道缓冲已满）时丢弃K线，不会阻塞行情处理和其他订阅者，需要完整序列时可用 Klines/KlineHistory 补齐
+func (h *DataHub) Subscribe(symbol, interval string) (<-chan Kline, func()) {
+	symbol = Normalize(symbol)
+	key := symbol + "_" + interval
+	sub := &klineSubscriber{ch: make(chan Kline, klineSubscriberBuffer), since: Clock.Now().UnixMilli()}
+
+	if _, err := IntervalDuration(interval); err != nil || !h.Running() {
+		sub.close()
+		return sub.ch, func() {}
+	}
+	h.WatchKlines(symbol, interval)
+
+	h.mu.Lock()
+	series := h.klines[key]
+	if series == nil {
+		h.mu.Unlock()
+		sub.close()
+		return sub.ch, func() {}
+	}
+	if series.subscribers == nil {
+		series.subscribers = make(map[*klineSubscriber]bool)
+	}
+	series.subscribers[sub] = true
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		if series := h.klines[key]; series != nil {
+			delete(series.subscribers, sub)
+		}
+		h.mu.Unlock()
+		sub.close()
+	}
+	return sub.ch, cancel
+}
+
+// publishKlines 把新收盘的K线发送给订阅者（调用方持有h.mu写锁，series.klines已按开盘时间排序）
+func (h *DataHub) publishKlines(symbol, interval string, series *klineSeries) {
+	for sub := range series.subscribers {
+		start := len(series.klines)
+		for start > 0 && series.klines[start-1].OpenTime > sub.lastSent {
+			start--
+		}
+		for _, k := range series.klines[start:] {
+			if k.CloseTime < sub.since {
+				continue
+			}
+			sub.lastSent = k.OpenTime
+			select {
+			case sub.ch <- k:
+			default:
+				sub.dropped++
+				if sub.dropped == 1 || sub.dropped%100 == 0 {
+					log.Printf("⚠️  %s %s K线订阅者处理过慢，已丢弃%d根K线", symbol, interval, sub.dropped)
+				}
+			}
+		}
+	}
+}
+
+// closeSubscribers 关闭全部K线订阅者的通道（实时行情中心停止时调用，调用方持有h.mu写锁）
+func (h *DataHub) closeSubscribers() {
+	for _, series := range h.klines {
+		for sub := range series.subscribers {
+			sub.close()
+		}
+		series.subscribers = nil
+	}
+}
+
+// subscribed 序列是否有外部订阅者（有订阅者的序列视为正在使用，不会被闲置释放）
+func (s *klineSeries) subscribed() bool {
+	return len(s.subscribers) > 0
+}
@@ -0,0 +1,176 @@
+package market
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"nofx/symbols"
+	"nofx/utils"
+)
+
+// hyperliquidInfoURL Hyperliquid公共信息接口
+const hyperliquidInfoURL = "https://api.hyperliquid.xyz/info"
+
+// hyperliquidAssetCtx 单个永续资产的实时上下文
+type hyperliquidAssetCtx struct {
+	Funding      string `json:"funding"`      // 每小时资金费率
+	OpenInterest string `json:"openInterest"` // 持仓量（以币计）
+	MarkPx       string `json:"markPx"`
+	OraclePx     string `json:"oraclePx"`
+}
+
+// hyperliquidProvider Hyperliquid永续行情（去中心化永续，资金费每小时结算）
+type hyperliquidProvider struct {
+	// metaAndAssetCtxs 缓存（OI和资金费率共用一次请求）
+	assetCtxs      map[string]hyperliquidAssetCtx
+	assetCtxsTime  time.Time
+	assetCtxsMutex sync.Mutex
+}
+
+func newHyperliquidProvider() *hyperliquidProvider {
+	return &hyperliquidProvider{}
+}
+
+func (p *hyperliquidProvider) Name() string { return "hyperliquid" }
+
+// postInfo 调用Hyperliquid info接口
+func (p *hyperliquidProvider) postInfo(request interface{}, result interface{}) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Post(hyperliquidInfoURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := utils.ReadBody(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Hyperliquid API错误 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+func (p *hyperliquidProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	duration, err := IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	end := time.Now()
+	start := end.Add(-duration * time.Duration(limit+1))
+
+	request := map[string]interface{}{
+		"type": "candleSnapshot",
+		"req": map[string]interface{}{
+			"coin":      symbols.ToExchange(symbol, "hyperliquid"),
+			"interval":  interval,
+			"startTime": start.UnixMilli(),
+			"endTime":   end.UnixMilli(),
+		},
+	}
+
+	var candles []hyperliquidCandle
+	if err := p.postInfo(request, &candles); err != nil {
+		return nil, fmt.Errorf("获取Hyperliquid K线失败: %w", err)
+	}
+	klines, err := parseHyperliquidKlines(candles)
+	if err != nil {
+		return nil, err
+	}
+	return lastKlines(klines, limit), nil
+}
+
+func (p *hyperliquidProvider) GetPrice(symbol string) (float64, error) {
+	var mids map[string]string
+	if err := p.postInfo(map[string]string{"type": "allMids"}, &mids); err != nil {
+		return 0, fmt.Errorf("获取Hyperliquid价格失败: %w", err)
+	}
+
+	mid, ok := mids[symbols.ToExchange(symbol, "hyperliquid")]
+	if !ok {
+		return 0, fmt.Errorf("Hyperliquid没有 %s 的价格", symbol)
+	}
+	return parseDecimal(symbol, mid)
+}
+
+// getAssetCtx 获取资产上下文（缓存10秒）
+func (p *hyperliquidProvider) getAssetCtx(symbol string) (*hyperliquidAssetCtx, error) {
+	p.assetCtxsMutex.Lock()
+	defer p.assetCtxsMutex.Unlock()
+
+	if p.assetCtxs == nil || time.Since(p.assetCtxsTime) > 10*time.Second {
+		// 返回格式: [{"universe": [{"name": "BTC", ...}, ...]}, [{assetCtx}, ...]]（两个数组按下标对应）
+		var response []json.RawMessage
+		if err := p.postInfo(map[string]string{"type": "metaAndAssetCtxs"}, &response); err != nil {
+			return nil, fmt.Errorf("获取Hyperliquid资产信息失败: %w", err)
+		}
+		if len(response) != 2 {
+			return nil, fmt.Errorf("Hyperliquid资产信息格式错误")
+		}
+
+		var meta struct {
+			Universe []struct {
+				Name string `json:"name"`
+			} `json:"universe"`
+		}
+		var ctxs []hyperliquidAssetCtx
+		if err := json.Unmarshal(response[0], &meta); err != nil {
+			return nil, fmt.Errorf("解析Hyperliquid meta失败: %w", err)
+		}
+		if err := json.Unmarshal(response[1], &ctxs); err != nil {
+			return nil, fmt.Errorf("解析Hyperliquid资产上下文失败: %w", err)
+		}
+
+		assetCtxs := make(map[string]hyperliquidAssetCtx, len(ctxs))
+		for i, asset := range meta.Universe {
+			if i < len(ctxs) {
+				assetCtxs[asset.Name] = ctxs[i]
+			}
+		}
+		p.assetCtxs = assetCtxs
+		p.assetCtxsTime = time.Now()
+	}
+
+	ctx, ok := p.assetCtxs[symbols.ToExchange(symbol, "hyperliquid")]
+	if !ok {
+		return nil, fmt.Errorf("Hyperliquid没有 %s 永续合约", symbol)
+	}
+	return &ctx, nil
+}
+
+// GetOpenInterest 获取持仓量（以币计，与币安口径一致）
+func (p *hyperliquidProvider) GetOpenInterest(symbol string) (*OIData, error) {
+	ctx, err := p.getAssetCtx(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	oi, err := parseDecimal("openInterest", ctx.OpenInterest)
+	if err != nil {
+		return nil, fmt.Errorf("解析Hyperliquid持仓量失败: %w", err)
+	}
+	// Hyperliquid只提供当前持仓量，没有历史可计算平均值，Average保持为0（输出时显示为不可用）
+	return &OIData{Latest: oi}, nil
+}
+
+// GetFundingRate Hyperliquid资金费每小时结算一次
+func (p *hyperliquidProvider) GetFundingRate(symbol string) (float64, int, error) {
+	ctx, err := p.getAssetCtx(symbol)
+	if err != nil {
+		return 0, 1, err
+	}
+
+	rate, err := parseDecimal("funding", ctx.Funding)
+	return rate, 1, err
+}
@@ -0,0 +1,117 @@
+package market
+
+import (
+	"fmt"
+	"log"
+)
+
+// IndicatorConfig 核心指标周期配置（字段为0时使用默认周期）
+// 字段名沿用默认周期命名的Data/LongerTermData字段（EMA20、RSI14Values等），其值按此处配置的周期计算
+type IndicatorConfig struct {
+	TrendMA  int // 趋势周期简单均线（默认21）
+	EntryMA  int // 入场周期简单均线（默认15）
+	RSI      int // RSI周期（默认14）
+	EMAFast  int // 快速EMA（默认20）
+	EMASlow  int // 慢速EMA（默认50）
+	ATRFast  int // 短期ATR（默认3）
+	ATRSlow  int // 长期ATR（默认14）
+	MACDFast int // MACD快线EMA（默认12）
+	MACDSlow int // MACD慢线EMA（默认26）
+
+	Warmup map[string]int // 按指标覆盖预热所需的最少K线数（键如"ema_slow"，只能高于计算所需的最少K线数），不足时写入Data.Warnings
+}
+
+// DefaultIndicatorConfig 默认指标周期
+func DefaultIndicatorConfig() IndicatorConfig {
+	return IndicatorConfig{
+		TrendMA:  21,
+		EntryMA:  15,
+		RSI:      14,
+		EMAFast:  20,
+		EMASlow:  50,
+		ATRFast:  3,
+		ATRSlow:  14,
+		MACDFast: 12,
+		MACDSlow: 26,
+	}
+}
+
+// indicatorConfig 当前使用的指标周期（Get/GetWithIntervals使用）
+var indicatorConfig = DefaultIndicatorConfig()
+
+// SetIndicatorConfig 设置默认指标周期（未设置的字段保留默认值，配置无效时不生效）
+func SetIndicatorConfig(cfg IndicatorConfig) {
+	cfg = cfg.withDefaults()
+	if err := cfg.Validate(); err != nil {
+		log.Printf("⚠️  指标周期配置无效，使用默认周期: %v", err)
+		return
+	}
+	indicatorConfig = cfg
+	log.Printf("✓ 指标周期: MA%d/MA%d RSI%d EMA%d/%d ATR%d/%d MACD%d/%d",
+		cfg.TrendMA, cfg.EntryMA, cfg.RSI, cfg.EMAFast, cfg.EMASlow,
+		cfg.ATRFast, cfg.ATRSlow, cfg.MACDFast, cfg.MACDSlow)
+}
+
+// withDefaults 用默认周期填充未设置（<=0）的字段
+func (c IndicatorConfig) withDefaults() IndicatorConfig {
+	def := DefaultIndicatorConfig()
+	fill := func(value *int, fallback int) {
+		if *value <= 0 {
+			*value = fallback
+		}
+	}
+	fill(&c.TrendMA, def.TrendMA)
+	fill(&c.EntryMA, def.EntryMA)
+	fill(&c.RSI, def.RSI)
+	fill(&c.EMAFast, def.EMAFast)
+	fill(&c.EMASlow, def.EMASlow)
+	fill(&c.ATRFast, def.ATRFast)
+	fill(&c.ATRSlow, def.ATRSlow)
+	fill(&c.MACDFast, def.MACDFast)
+	fill(&c.MACDSlow, def.MACDSlow)
+	return c
+}
+
+// Validate 校验周期设置（快线周期必须小于慢线周期）和预热配置
+func (c IndicatorConfig) Validate() error {
+	if c.EMAFast >= c.EMASlow {
+		return fmt.Errorf("EMA快线周期(%d)必须小于慢线周期(%d)", c.EMAFast, c.EMASlow)
+	}
+	if c.ATRFast >= c.ATRSlow {
+		return fmt.Errorf("ATR短期周期(%d)必须小于长期周期(%d)", c.ATRFast, c.ATRSlow)
+	}
+	if c.MACDFast >= c.MACDSlow {
+		return fmt.Errorf("MACD快线周期(%d)必须小于慢线周期(%d)", c.MACDFast, c.MACDSlow)
+	}
+	return c.validateWarmup()
+}
+
+// trendBars 趋势周期需要获取的K线数量（默认60根，慢速指标周期较长或预热要求更高时相应增加）
+func (c IndicatorConfig) trendBars() int {
+	bars := 60
+	for _, period := range []int{c.EMASlow, c.TrendMA + 2, c.MACDSlow + macdSignalPeriod, c.RSI, c.ATRSlow} {
+		if period+10 > bars {
+			bars = period + 10
+		}
+	}
+	for _, r := range c.warmupRequirements() {
+		if !r.entry && r.bars+1 > bars {
+			bars = r.bars + 1 // 多获取一根（最新一根未走完的K线会被过滤）
+		}
+	}
+	return bars
+}
+
+// entryBars 入场周期需要获取的K线数量（默认40根，预热要求更高时相应增加）
+func (c IndicatorConfig) entryBars() int {
+	bars := 40
+	if c.EntryMA+10 > bars {
+		bars = c.EntryMA + 10
+	}
+	for _, r := range c.warmupRequirements() {
+		if r.entry && r.bars+1 > bars {
+			bars = r.bars + 1
+		}
+	}
+	return bars
+}
@@ -0,0 +1,352 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"testing/quick"
+)
+
+// indicatorCheck 指标校验结果
+type indicatorCheck struct {
+	Name   string
+	Kind   string // "golden" 参考向量 / "property" 性质检验
+	Passed bool
+	Detail string // 失败原因
+}
+
+// 性质检验使用的随机K线（固定种子，结果可复现）
+const (
+	propertySeed    = 20240101
+	propertySamples = 50
+)
+
+// rsiReference Wilder《New Concepts in Technical Trading Systems》RSI示例数据（StockCharts RSI教程同一组数据）
+// 及其14期RSI（四舍五入到2位小数，TA-Lib RSI同样以前14个涨跌幅的简单平均为初始值）
+var rsiReference = struct {
+	closes, rsi14 []float64
+}{
+	closes: []float64{
+		44.3389, 44.0902, 44.1497, 43.6124, 44.3278, 44.8264, 45.0955, 45.4245, 45.8433, 46.0826, 45.8931,
+		46.0328, 45.6140, 46.2820, 46.2820, 46.0028, 46.0328, 46.4116, 46.2222, 45.6439, 46.2122, 46.2521,
+		45.7137, 46.4515, 45.7835, 45.3548, 44.0288, 44.1783, 44.2181, 44.5672, 43.4205, 42.6628, 43.1314,
+	},
+	rsi14: []float64{
+		70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+		54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77,
+	},
+}
+
+// emaReference StockCharts EMA教程的示例数据及其10期EMA（以前10个值的SMA为初始值，与TA-Lib EMA相同，四舍五入到2位小数）
+var emaReference = struct {
+	closes, ema10 []float64
+}{
+	closes: []float64{
+		22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29, 22.15, 22.39, 22.38, 22.61, 23.36,
+		24.05, 23.75, 23.83, 23.95, 23.63, 23.82, 23.87, 23.65, 23.19, 23.10, 23.33, 22.68, 23.10, 22.40, 22.17,
+	},
+	ema10: []float64{
+		22.22, 22.21, 22.24, 22.27, 22.33, 22.52, 22.80, 22.97, 23.13, 23.28, 23.34,
+		23.43, 23.51, 23.53, 23.47, 23.40, 23.39, 23.26, 23.23, 23.08, 22.92,
+	},
+}
+
+// atrReference 手工计算的3期ATR：真实波幅依次为 2, 4, 6（跳空高开，取|最高价-前收盘|）, 8（跳空低开，取|最低价-前收盘|）, 2, 10
+// 初始值 (2+4+6)/3 = 4，之后 Wilder平滑 (ATR×2 + TR)/3（与TA-Lib ATR定义相同）
+var atrReference = struct {
+	klines []Kline
+	atr3   []float64
+}{
+	klines: []Kline{
+		{Open: 100, High: 101, Low: 99, Close: 100},
+		{Open: 100, High: 101, Low: 99, Close: 100},
+		{Open: 100, High: 102, Low: 98, Close: 100},
+		{Open: 104, High: 106, Low: 104, Close: 105},
+		{Open: 100, High: 101, Low: 97, Close: 99},
+		{Open: 99, High: 100, Low: 98, Close: 99},
+		{Open: 99, High: 104, Low: 94, Close: 100},
+	},
+	atr3: []float64{4, 16.0 / 3, 38.0 / 9, 166.0 / 27},
+}
+
+// verifyIndicators 校验指标实现：
+// 参考向量（RSI/EMA为公开发布的示例数据，ATR/MACD为可手工验算的数据）确认计算方法与TA-Lib一致；
+// 性质检验按cfg配置的周期（未设置的字段使用默认周期）在随机K线上检查取值范围、单调性、缩放/平移不变性等，
+// 用于确认自定义周期配置下指标仍然有意义
+func verifyIndicators(cfg IndicatorConfig) ([]indicatorCheck, error) {
+	cfg = cfg.withDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var checks []indicatorCheck
+	add := func(kind, name string, check func() error) {
+		result := indicatorCheck{Name: name, Kind: kind, Passed: true}
+		if err := check(); err != nil {
+			result.Passed, result.Detail = false, err.Error()
+		}
+		checks = append(checks, result)
+	}
+
+	add("golden", "RSI14 (Wilder)", func() error {
+		return compareVector(rsiValues(rsiReference.closes, 14), rsiReference.rsi14, 0.005)
+	})
+	add("golden", "EMA10 (StockCharts)", func() error {
+		return compareVector(emaValues(emaReference.closes, 10), emaReference.ema10, 0.005)
+	})
+	add("golden", "ATR3 (hand-computed)", func() error {
+		return compareVector(wilderSmooth(trueRanges(atrReference.klines), 3), atrReference.atr3, 1e-9)
+	})
+	add("golden", "MACD12/26 on a ramp", func() error {
+		// 线性序列 x_i = a + b·i：SMA初始值的滞后等于EMA稳态滞后 b(p-1)/2，与初始化方式无关，
+		// 因此步长b=1时 MACD = b(slow-fast)/2 = 14/2 = 7（共100-26+1个值），信号线同样为7
+		macd := macdValues(rampSeries(100, 100, 1), 12, 26)
+		if err := compareVector(macd, rampSeries(75, 7, 0), 1e-9); err != nil {
+			return err
+		}
+		return compareVector(emaValues(macd, macdSignalPeriod), rampSeries(75-macdSignalPeriod+1, 7, 0), 1e-9)
+	})
+
+	samples := randomKlineSamples(cfg.trendBars() + 50)
+	property := func(name string, check func(klines []Kline) error) {
+		add("property", name, func() error {
+			for i, klines := range samples {
+				if err := check(klines); err != nil {
+					return fmt.Errorf("样本#%d: %w", i, err)
+				}
+			}
+			return nil
+		})
+	}
+
+	property(fmt.Sprintf("RSI%d in [0, 100]", cfg.RSI), func(klines []Kline) error {
+		for i, v := range rsiValues(closes(klines), cfg.RSI) {
+			if math.IsNaN(v) || v < 0 || v > 100 {
+				return fmt.Errorf("第%d个值 %.6f 超出范围", i, v)
+			}
+		}
+		return nil
+	})
+	property(fmt.Sprintf("RSI%d scale invariant", cfg.RSI), func(klines []Kline) error {
+		return compareVector(rsiValues(closes(scaleKlines(klines, 3.7, 0)), cfg.RSI), rsiValues(closes(klines), cfg.RSI), 1e-6)
+	})
+	add("property", fmt.Sprintf("RSI%d extremes", cfg.RSI), func() error {
+		if rsi := rsiValues(rampSeries(cfg.RSI*3, 100, 1), cfg.RSI).Last(); rsi != 100 {
+			return fmt.Errorf("持续上涨的RSI应为100，实际 %.6f", rsi)
+		}
+		if rsi := rsiValues(rampSeries(cfg.RSI*3, float64(cfg.RSI*3)+100, -1), cfg.RSI).Last(); rsi != 0 {
+			return fmt.Errorf("持续下跌的RSI应为0，实际 %.6f", rsi)
+		}
+		return nil
+	})
+
+	for _, period := range []int{cfg.EMAFast, cfg.EMASlow, cfg.MACDFast, cfg.MACDSlow} {
+		period := period
+		property(fmt.Sprintf("EMA%d within price range", period), func(klines []Kline) error {
+			values := closes(klines)
+			for i, ema := range emaValues(values, period) {
+				window := values.Head(period + i)
+				low, high := window[0], window[0]
+				for _, v := range window {
+					low, high = math.Min(low, v), math.Max(high, v)
+				}
+				if ema < low-1e-9 || ema > high+1e-9 {
+					return fmt.Errorf("第%d个值 %.6f 超出价格范围 [%.6f, %.6f]", i, ema, low, high)
+				}
+			}
+			return nil
+		})
+		property(fmt.Sprintf("EMA%d monotonic in latest close", period), func(klines []Kline) error {
+			values := closes(klines)
+			raised := append(Series[float64](nil), values...)
+			raised[len(raised)-1] *= 1.01
+			if before, after := emaValues(values, period).Last(), emaValues(raised, period).Last(); after <= before {
+				return fmt.Errorf("最新收盘价上涨后EMA未上升: %.6f -> %.6f", before, after)
+			}
+			return nil
+		})
+		add("property", fmt.Sprintf("EMA%d ramp lag = (period-1)/2", period), func() error {
+			ramp := rampSeries(period*4, 100, 1)
+			expected := Map(ramp.Drop(period-1), func(v float64) float64 { return v - float64(period-1)/2 })
+			return compareVector(emaValues(ramp, period), expected, 1e-9)
+		})
+	}
+
+	for _, period := range []int{cfg.ATRFast, cfg.ATRSlow} {
+		period := period
+		property(fmt.Sprintf("ATR%d non-negative", period), func(klines []Kline) error {
+			for i, v := range wilderSmooth(trueRanges(klines), period) {
+				if math.IsNaN(v) || v < 0 {
+					return fmt.Errorf("第%d个值 %.6f 为负数", i, v)
+				}
+			}
+			return nil
+		})
+		property(fmt.Sprintf("ATR%d scales with price, ignores offset", period), func(klines []Kline) error {
+			atr, ok := calculateATR(klines, period)
+			if !ok {
+				return fmt.Errorf("%d根K线无法计算ATR%d", len(klines), period)
+			}
+			if scaled, _ := calculateATR(scaleKlines(klines, 2.5, 0), period); math.Abs(scaled-2.5*atr) > 1e-9*math.Max(1, atr) {
+				return fmt.Errorf("价格×2.5后ATR应为 %.6f，实际 %.6f", 2.5*atr, scaled)
+			}
+			if shifted, _ := calculateATR(scaleKlines(klines, 1, 1000), period); math.Abs(shifted-atr) > 1e-6 {
+				return fmt.Errorf("价格+1000后ATR应不变 %.6f，实际 %.6f", atr, shifted)
+			}
+			return nil
+		})
+	}
+
+	macdName := fmt.Sprintf("MACD%d/%d", cfg.MACDFast, cfg.MACDSlow)
+	add("property", macdName+" zero on flat prices", func() error {
+		for i, v := range macdValues(rampSeries(cfg.MACDSlow*3, 100, 0), cfg.MACDFast, cfg.MACDSlow) {
+			if math.Abs(v) > 1e-9 {
+				return fmt.Errorf("第%d个值 %.9f 不为0", i, v)
+			}
+		}
+		return nil
+	})
+	add("property", macdName+" sign follows trend", func() error {
+		want := float64(cfg.MACDSlow-cfg.MACDFast) / 2
+		if v := macdValues(rampSeries(cfg.MACDSlow*3, 100, 1), cfg.MACDFast, cfg.MACDSlow).Last(); math.Abs(v-want) > 1e-9 {
+			return fmt.Errorf("上涨序列MACD应为 %.4f，实际 %.6f", want, v)
+		}
+		if v := macdValues(rampSeries(cfg.MACDSlow*3, float64(cfg.MACDSlow*3)+100, -1), cfg.MACDFast, cfg.MACDSlow).Last(); math.Abs(v+want) > 1e-9 {
+			return fmt.Errorf("下跌序列MACD应为 %.4f，实际 %.6f", -want, v)
+		}
+		return nil
+	})
+	property(macdName+" ignores price offset", func(klines []Kline) error {
+		return compareVector(macdValues(closes(scaleKlines(klines, 1, 1000)), cfg.MACDFast, cfg.MACDSlow),
+			macdValues(closes(klines), cfg.MACDFast, cfg.MACDSlow), 1e-6)
+	})
+
+	return checks, nil
+}
+
+// compareVector 逐个比较计算结果与参考值（结果按末尾对齐，长度必须一致）
+func compareVector(actual, expected Series[float64], tolerance float64) error {
+	if len(actual) != len(expected) {
+		return fmt.Errorf("长度 %d，参考值长度 %d", len(actual), len(expected))
+	}
+	for i := range expected {
+		if math.IsNaN(actual[i]) || math.Abs(actual[i]-expected[i]) > tolerance {
+			return fmt.Errorf("第%d个值 %.6f，参考值 %.6f", i, actual[i], expected[i])
+		}
+	}
+	return nil
+}
+
+// rampSeries 线性序列 start + step·i
+func rampSeries(length int, start, step float64) Series[float64] {
+	values := make(Series[float64], length)
+	for i := range values {
+		values[i] = start + step*float64(i)
+	}
+	return values
+}
+
+// randomKlineSamples 生成固定种子的随机游走K线（含跳空和平盘），每组length根
+func randomKlineSamples(length int) [][]Kline {
+	random := rand.New(rand.NewSource(propertySeed))
+	samples := make([][]Kline, propertySamples)
+	for s := range samples {
+		klines := make([]Kline, length)
+		price := 10 + random.Float64()*1000
+		for i := range klines {
+			open := price
+			if random.Intn(10) == 0 {
+				open *= 1 + random.NormFloat64()*0.02 // 跳空
+			}
+			if random.Intn(8) != 0 {
+				price = open * (1 + random.NormFloat64()*0.01)
+			}
+			klines[i] = Kline{
+				OpenTime: int64(i) * 60000,
+				Open:     open,
+				High:     math.Max(open, price) * (1 + random.Float64()*0.005),
+				Low:      math.Min(open, price) * (1 - random.Float64()*0.005),
+				Close:    price,
+				Volume:   random.Float64() * 1000,
+			}
+		}
+		samples[s] = klines
+	}
+	return samples
+}
+
+// scaleKlines 价格 × factor + offset
+func scaleKlines(klines []Kline, factor, offset float64) []Kline {
+	return Map(Series[Kline](klines), func(k Kline) Kline {
+		k.Open, k.High, k.Low, k.Close = k.Open*factor+offset, k.High*factor+offset, k.Low*factor+offset, k.Close*factor+offset
+		return k
+	})
+}
+
+// TestIndicatorGoldenVectors RSI/EMA/ATR/MACD与参考向量（TA-Lib同一计算方法）一致
+func TestIndicatorGoldenVectors(t *testing.T) {
+	checks, err := verifyIndicators(IndicatorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range checks {
+		if check.Kind != "golden" {
+			continue
+		}
+		t.Run(check.Name, func(t *testing.T) {
+			if !check.Passed {
+				t.Error(check.Detail)
+			}
+		})
+	}
+}
+
+// TestIndicatorProperties 默认周期下的性质检验（取值范围、单调性、缩放/平移不变性）
+func TestIndicatorProperties(t *testing.T) {
+	checks, err := verifyIndicators(IndicatorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range checks {
+		if check.Kind != "property" {
+			continue
+		}
+		t.Run(check.Name, func(t *testing.T) {
+			if !check.Passed {
+				t.Error(check.Detail)
+			}
+		})
+	}
+}
+
+// TestIndicatorPropertiesRandomPeriods 随机生成的有效周期配置下性质检验同样成立
+func TestIndicatorPropertiesRandomPeriods(t *testing.T) {
+	property := func(rsi, emaFast, emaSlow, atrFast, atrSlow, macdFast, macdSlow uint8) bool {
+		cfg := IndicatorConfig{
+			RSI:      2 + int(rsi%30),
+			EMAFast:  2 + int(emaFast%30),
+			ATRFast:  1 + int(atrFast%10),
+			MACDFast: 2 + int(macdFast%20),
+		}
+		cfg.EMASlow = cfg.EMAFast + 1 + int(emaSlow%60)
+		cfg.ATRSlow = cfg.ATRFast + 1 + int(atrSlow%30)
+		cfg.MACDSlow = cfg.MACDFast + 1 + int(macdSlow%30)
+
+		checks, err := verifyIndicators(cfg)
+		if err != nil {
+			t.Logf("%+v: %v", cfg, err)
+			return false
+		}
+		for _, check := range checks {
+			if !check.Passed {
+				t.Logf("%+v: %s: %s", cfg, check.Name, check.Detail)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 20}); err != nil {
+		t.Error(err)
+	}
+}
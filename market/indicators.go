@@ -0,0 +1,703 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// 指标交叉类型
+const (
+	CrossEMA20EMA50 = "ema20_ema50" // EMA20与EMA50交叉
+	CrossPriceMA21  = "price_ma21"  // 收盘价与MA21交叉
+	CrossMACDSignal = "macd_signal" // MACD与信号线(EMA9)交叉
+)
+
+// crossoverLookback 检测交叉的K线根数（趋势周期）
+const crossoverLookback = 10
+
+// macdSignalPeriod MACD信号线EMA周期
+const macdSignalPeriod = 9
+
+// CrossoverEvent 指标交叉事件
+type CrossoverEvent struct {
+	Kind      string    // 交叉类型（CrossEMA20EMA50等）
+	Direction string    // "up" 上穿 / "down" 下穿
+	Time      time.Time // 发生交叉的K线收盘时间
+	BarsAgo   int       // 距最新K线的根数（0表示最新一根）
+	Fast      int       // 快线周期（EMA交叉为快速EMA，均线交叉为均线周期）
+	Slow      int       // 慢线周期（仅EMA交叉）
+}
+
+// Description 交叉描述（如"EMA20上穿EMA50"）
+func (e CrossoverEvent) Description() string {
+	direction := "上穿"
+	if e.Direction == "down" {
+		direction = "下穿"
+	}
+	switch e.Kind {
+	case CrossEMA20EMA50:
+		return fmt.Sprintf("EMA%d%sEMA%d", e.Fast, direction, e.Slow)
+	case CrossPriceMA21:
+		return fmt.Sprintf("价格%sMA%d", direction, e.Fast)
+	case CrossMACDSignal:
+		return "MACD" + direction + "信号线"
+	default:
+		return e.Kind + " " + e.Direction
+	}
+}
+
+// closes 提取收盘价序列
+func closes(klines []Kline) Series[float64] {
+	return Map(Series[Kline](klines), func(k Kline) float64 { return k.Close })
+}
+
+// typicalPrice 典型价格 (最高价 + 最低价 + 收盘价) / 3
+func typicalPrice(k Kline) float64 {
+	return (k.High + k.Low + k.Close) / 3
+}
+
+// changes 相邻值的变化量序列（与values[1:]对齐）
+func changes(values Series[float64]) Series[float64] {
+	return Zip(values, values.Shift(1), func(current, previous float64) float64 { return current - previous })
+}
+
+// trueRanges 真实波幅序列（与klines[1:]对齐）
+func trueRanges(klines []Kline) Series[float64] {
+	bars := Series[Kline](klines)
+	return Zip(bars, bars.Shift(1), func(k, previous Kline) float64 {
+		return math.Max(k.High-k.Low, math.Max(math.Abs(k.High-previous.Close), math.Abs(k.Low-previous.Close)))
+	})
+}
+
+// emaValues EMA：以前period个值的SMA为初始值，结果与values末尾对齐（长度 len-period+1，数据不足时为空）
+func emaValues(values Series[float64], period int) Series[float64] {
+	if period <= 0 || len(values) < period {
+		return nil
+	}
+	multiplier := 2.0 / float64(period+1)
+	return Scan(values.Drop(period), Mean(values.Head(period)), func(ema, v float64) float64 {
+		return (v-ema)*multiplier + ema
+	})
+}
+
+// wilderSmooth Wilder平滑：以前period个值的均值为初始值，之后 avg = (avg×(period-1) + v) / period，
+// 结果与values末尾对齐（长度 len-period+1，数据不足时为空）
+func wilderSmooth(values Series[float64], period int) Series[float64] {
+	if period <= 0 || len(values) < period {
+		return nil
+	}
+	return Scan(values.Drop(period), Mean(values.Head(period)), func(avg, v float64) float64 {
+		return (avg*float64(period-1) + v) / float64(period)
+	})
+}
+
+// rsiValues RSI（Wilder平滑），结果与values末尾对齐（长度 len-period，数据不足时为空）
+func rsiValues(values Series[float64], period int) Series[float64] {
+	deltas := changes(values)
+	gains := wilderSmooth(Map(deltas, func(change float64) float64 { return math.Max(change, 0) }), period)
+	losses := wilderSmooth(Map(deltas, func(change float64) float64 { return math.Max(-change, 0) }), period)
+	return Zip(gains, losses, func(avgGain, avgLoss float64) float64 {
+		if avgLoss == 0 {
+			return 100
+		}
+		return 100 - 100/(1+avgGain/avgLoss)
+	})
+}
+
+// macdValues MACD = 快线EMA - 慢线EMA，结果与values末尾对齐（长度 len-slow+1，数据不足时为空）
+func macdValues(values Series[float64], fast, slow int) Series[float64] {
+	return Zip(emaValues(values, fast), emaValues(values, slow), func(f, s float64) float64 { return f - s })
+}
+
+// emaSeries 计算EMA序列（与calculateEMA一致：以前period个值的SMA为初始值），数据不足的位置为NaN
+func emaSeries(values []float64, period int) []float64 {
+	// 跳过开头的NaN（如MACD序列）
+	valid := Series[float64](values)
+	for len(valid) > 0 && math.IsNaN(valid[0]) {
+		valid = valid.Drop(1)
+	}
+	return emaValues(valid, period).Pad(len(values), math.NaN())
+}
+
+// smaSeries 计算SMA序列，数据不足的位置为NaN
+func smaSeries(values []float64, period int) []float64 {
+	return Window(Series[float64](values), period, Mean).Pad(len(values), math.NaN())
+}
+
+// rsiSeries 计算RSI序列（Wilder平滑，与calculateRSI一致），数据不足的位置为NaN
+func rsiSeries(values []float64, period int) []float64 {
+	return rsiValues(values, period).Pad(len(values), math.NaN())
+}
+
+// EMASeries 计算EMA序列（供脚本策略使用），数据不足的位置为NaN
+func EMASeries(values []float64, period int) []float64 {
+	return emaSeries(values, period)
+}
+
+// SMASeries 计算SMA序列，数据不足的位置为NaN
+func SMASeries(values []float64, period int) []float64 {
+	return smaSeries(values, period)
+}
+
+// RSISeries 计算RSI序列（Wilder平滑），数据不足的位置为NaN
+func RSISeries(values []float64, period int) []float64 {
+	return rsiSeries(values, period)
+}
+
+// ATR 计算K线的ATR（Wilder平滑），K线数不足时ok为false
+func ATR(klines []Kline, period int) (float64, bool) {
+	return calculateATR(klines, period)
+}
+
+// detectCrossovers 检测最近lookback根K线内的指标交叉（按时间从旧到新）
+func detectCrossovers(klines []Kline, lookback int, cfg IndicatorConfig) []CrossoverEvent {
+	values := closes(klines)
+	ema20 := emaSeries(values, cfg.EMAFast)
+	ema50 := emaSeries(values, cfg.EMASlow)
+	ma21 := smaSeries(values, cfg.TrendMA)
+
+	// 任一EMA为NaN时MACD为NaN
+	macd := Zip(emaSeries(values, cfg.MACDFast), emaSeries(values, cfg.MACDSlow), func(fast, slow float64) float64 {
+		return fast - slow
+	})
+	signal := emaSeries(macd, macdSignalPeriod)
+
+	crosses := []struct {
+		kind       string
+		fast, slow int
+		directions Series[string]
+	}{
+		{CrossEMA20EMA50, cfg.EMAFast, cfg.EMASlow, crossDirections(ema20, ema50)},
+		{CrossPriceMA21, cfg.TrendMA, 0, crossDirections(values, ma21)},
+		{CrossMACDSignal, cfg.MACDFast, cfg.MACDSlow, crossDirections(macd, signal)},
+	}
+
+	bars := Series[Kline](klines)
+	var events []CrossoverEvent
+	for barsAgo := min(lookback, len(klines)-1) - 1; barsAgo >= 0; barsAgo-- {
+		bar, _ := bars.Ago(barsAgo)
+		for _, cross := range crosses {
+			if direction, _ := cross.directions.Ago(barsAgo); direction != "" {
+				events = append(events, CrossoverEvent{
+					Kind:      cross.kind,
+					Direction: direction,
+					Time:      time.UnixMilli(bar.CloseTime),
+					BarsAgo:   barsAgo,
+					Fast:      cross.fast,
+					Slow:      cross.slow,
+				})
+			}
+		}
+	}
+	return events
+}
+
+// crossDirections 快线相对慢线的穿越方向序列（"up"上穿、"down"下穿、""未穿越或数据不足），与输入的[1:]对齐
+func crossDirections(fast, slow Series[float64]) Series[string] {
+	spread := Zip(fast, slow, func(f, s float64) float64 { return f - s })
+	return Zip(spread, spread.Shift(1), func(after, before float64) string {
+		switch {
+		case math.IsNaN(before) || math.IsNaN(after):
+			return ""
+		case before <= 0 && after > 0:
+			return "up"
+		case before >= 0 && after < 0:
+			return "down"
+		}
+		return ""
+	})
+}
+
+// formatCrossovers 格式化交叉事件
+func formatCrossovers(events []CrossoverEvent, interval string) string {
+	parts := make([]string, len(events))
+	for i, e := range events {
+		parts[i] = fmt.Sprintf("%s @ %s (%d根%s K线前)",
+			e.Description(), e.Time.UTC().Format("01-02 15:04 UTC"), e.BarsAgo, interval)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// 通道参数
+const (
+	channelPeriod     = 20  // Keltner/Donchian/布林带周期
+	keltnerATRPeriod  = 10  // Keltner通道ATR周期
+	keltnerMultiplier = 1.5 // Keltner通道ATR倍数（TTM Squeeze惯例）
+	bollingerStdDev   = 2.0 // 布林带标准差倍数
+)
+
+// calculateKeltner 计算Keltner通道（EMA20 ± 1.5×ATR10）
+func calculateKeltner(klines []Kline) (upper, middle, lower float64, ok bool) {
+	middle, emaOK := calculateEMA(klines, channelPeriod)
+	atr, atrOK := calculateATR(klines, keltnerATRPeriod)
+	if !emaOK || !atrOK {
+		return 0, 0, 0, false
+	}
+	return middle + keltnerMultiplier*atr, middle, middle - keltnerMultiplier*atr, true
+}
+
+// calculateDonchian 计算Donchian通道（最近N根K线的最高价/最低价）
+func calculateDonchian(klines []Kline, period int) (upper, lower float64, ok bool) {
+	if period <= 0 || len(klines) < period {
+		return 0, 0, false
+	}
+	window := Series[Kline](klines).Tail(period)
+	upper, lower = window[0].High, window[0].Low
+	for _, k := range window {
+		upper = math.Max(upper, k.High)
+		lower = math.Min(lower, k.Low)
+	}
+	return upper, lower, true
+}
+
+// calculateBollinger 计算布林带（SMA20 ± 2σ）
+func calculateBollinger(klines []Kline, period int, stdDevs float64) (upper, middle, lower float64, ok bool) {
+	middle, ok = calculateSMA(klines, period)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	variance := Mean(Map(closes(klines).Tail(period), func(c float64) float64 { return (c - middle) * (c - middle) }))
+	stdDev := math.Sqrt(variance)
+	return middle + stdDevs*stdDev, middle, middle - stdDevs*stdDev, true
+}
+
+// calculateWilliamsR 计算威廉指标 %R = (最高价 - 收盘价) / (最高价 - 最低价) × -100，取值[-100, 0]
+func calculateWilliamsR(klines []Kline, period int) (float64, bool) {
+	high, low, ok := calculateDonchian(klines, period)
+	if !ok {
+		return 0, false
+	}
+	if high == low {
+		return -50, true
+	}
+	return (high - klines[len(klines)-1].Close) / (high - low) * -100, true
+}
+
+// calculateCCI 计算顺势指标 CCI = (典型价格 - 典型价格SMA) / (0.015 × 平均绝对偏差)
+// 窗口内典型价格全部相同（平均绝对偏差为0）时价格就在均值上，CCI为0
+func calculateCCI(klines []Kline, period int) (float64, bool) {
+	if period <= 0 || len(klines) < period {
+		return 0, false
+	}
+	typical := Map(Series[Kline](klines).Tail(period), typicalPrice)
+	mean := Mean(typical)
+	deviation := Mean(Map(typical, func(tp float64) float64 { return math.Abs(tp - mean) }))
+	if deviation == 0 {
+		return 0, true
+	}
+	return (typical.Last() - mean) / (0.015 * deviation), true
+}
+
+// Parabolic SAR参数
+const (
+	sarStep    = 0.02 // 加速因子步长
+	sarMaxStep = 0.2  // 加速因子上限
+)
+
+// calculateParabolicSAR 计算抛物线转向指标（下一根K线的SAR值）
+// uptrend为true时SAR位于价格下方（多头），可作为多仓移动止损；反之可作为空仓移动止损
+func calculateParabolicSAR(klines []Kline) (sar float64, uptrend, ok bool) {
+	if len(klines) < 2 {
+		return 0, false, false
+	}
+
+	// 以前两根K线确定初始趋势
+	uptrend = klines[1].Close >= klines[0].Close
+	sar, extreme := klines[0].Low, klines[1].High
+	if !uptrend {
+		sar, extreme = klines[0].High, klines[1].Low
+	}
+	af := sarStep
+
+	for i := 2; i < len(klines); i++ {
+		k := klines[i]
+		sar += af * (extreme - sar)
+
+		if uptrend {
+			// SAR不能高于前两根K线的最低价
+			sar = math.Min(sar, math.Min(klines[i-1].Low, klines[i-2].Low))
+			if k.Low < sar {
+				// 反转为空头
+				uptrend, sar, extreme, af = false, extreme, k.Low, sarStep
+				continue
+			}
+			if k.High > extreme {
+				extreme = k.High
+				af = math.Min(af+sarStep, sarMaxStep)
+			}
+		} else {
+			// SAR不能低于前两根K线的最高价
+			sar = math.Max(sar, math.Max(klines[i-1].High, klines[i-2].High))
+			if k.High > sar {
+				// 反转为多头
+				uptrend, sar, extreme, af = true, extreme, k.High, sarStep
+				continue
+			}
+			if k.Low < extreme {
+				extreme = k.Low
+				af = math.Min(af+sarStep, sarMaxStep)
+			}
+		}
+	}
+
+	// 推算下一根K线的SAR
+	sar += af * (extreme - sar)
+	last, prev := klines[len(klines)-1], klines[len(klines)-2]
+	if uptrend {
+		sar = math.Min(sar, math.Min(last.Low, prev.Low))
+	} else {
+		sar = math.Max(sar, math.Max(last.High, prev.High))
+	}
+	return sar, uptrend, true
+}
+
+// calculateMFI 计算资金流量指标（成交量加权的RSI，取值0~100）
+func calculateMFI(klines []Kline, period int) (float64, bool) {
+	if period <= 0 || len(klines) <= period {
+		return 0, false
+	}
+
+	// 典型价格上涨的K线计入正资金流，下跌的计入负资金流（两个序列按末尾对齐）
+	bars := Series[Kline](klines)
+	typical := Map(bars, typicalPrice)
+	moneyFlow := Zip(bars, typical, func(k Kline, tp float64) float64 { return tp * k.Volume }).Tail(period)
+	direction := changes(typical).Tail(period)
+
+	positive, negative := 0.0, 0.0
+	for i, flow := range moneyFlow {
+		if direction[i] > 0 {
+			positive += flow
+		} else if direction[i] < 0 {
+			negative += flow
+		}
+	}
+
+	if negative == 0 {
+		return 100, true
+	}
+	return 100 - 100/(1+positive/negative), true
+}
+
+// calculateCMF 计算蔡金资金流（取值-1~1，正值表示买盘主导）
+// 窗口内没有成交量时无法计算
+func calculateCMF(klines []Kline, period int) (float64, bool) {
+	if period <= 0 || len(klines) < period {
+		return 0, false
+	}
+
+	flowVolume, totalVolume := 0.0, 0.0
+	for _, k := range Series[Kline](klines).Tail(period) {
+		if k.High > k.Low {
+			multiplier := ((k.Close - k.Low) - (k.High - k.Close)) / (k.High - k.Low)
+			flowVolume += multiplier * k.Volume
+		}
+		totalVolume += k.Volume
+	}
+
+	if totalVolume == 0 {
+		return 0, false
+	}
+	return flowVolume / totalVolume, true
+}
+
+// momentumPeriods 变化率/动量的计算周期（趋势周期K线根数）
+var momentumPeriods = []int{5, 10, 20}
+
+// momentumSeriesLength 变化率/动量序列长度
+const momentumSeriesLength = 10
+
+// SetMomentumPeriods 设置变化率/动量的计算周期
+func SetMomentumPeriods(periods []int) {
+	valid := make([]int, 0, len(periods))
+	for _, period := range periods {
+		if period > 0 {
+			valid = append(valid, period)
+		}
+	}
+	if len(valid) == 0 {
+		return
+	}
+	momentumPeriods = valid
+	log.Printf("✓ 变化率/动量周期: %v", valid)
+}
+
+// MomentumSeries 指定周期的变化率和动量序列（从旧到新）
+type MomentumSeries struct {
+	Period   int
+	ROC      []float64 // 变化率 = (收盘价 - N期前收盘价) / N期前收盘价 × 100（%）
+	Momentum []float64 // 动量 = 收盘价 / N期前收盘价 × 100（100为持平）
+}
+
+// ROC 计算最新一根K线的N期变化率（%），数据不足时ok为false
+func ROC(klines []Kline, period int) (float64, bool) {
+	if period <= 0 {
+		return 0, false
+	}
+	return rocValues(closes(klines), period).Ago(0)
+}
+
+// rocValues N期变化率序列（%，N期前收盘价为0时为0），与values[period:]对齐
+func rocValues(values Series[float64], period int) Series[float64] {
+	return Zip(values, values.Shift(period), func(current, base float64) float64 {
+		if base == 0 {
+			return 0
+		}
+		return (current - base) / base * 100
+	})
+}
+
+// calculateMomentumSeries 计算各周期最近length个变化率/动量值
+func calculateMomentumSeries(klines []Kline, periods []int, length int) []MomentumSeries {
+	result := make([]MomentumSeries, 0, len(periods))
+	for _, period := range periods {
+		roc := rocValues(closes(klines), period).Tail(length)
+		if len(roc) == 0 {
+			continue
+		}
+		result = append(result, MomentumSeries{
+			Period:   period,
+			ROC:      roc,
+			Momentum: Map(roc, func(v float64) float64 { return 100 + v }),
+		})
+	}
+	return result
+}
+
+// regressionPeriod 线性回归通道的K线根数
+const regressionPeriod = 20
+
+// RegressionChannel 最近N根收盘价的线性回归
+type RegressionChannel struct {
+	Period   int
+	Slope    float64 // 斜率（每根K线的价格变化）
+	SlopePct float64 // 斜率占当前回归值的百分比（%/根K线，便于跨币种比较）
+	R2       float64 // 拟合优度（0~1，越接近1趋势越线性）
+	Middle   float64 // 最新一根K线的回归值
+	Upper    float64 // 上轨（回归值 + 2倍残差标准差）
+	Lower    float64 // 下轨（回归值 - 2倍残差标准差）
+}
+
+// calculateRegression 计算最近period根收盘价的线性回归通道（数据不足返回nil）
+func calculateRegression(klines []Kline, period int) *RegressionChannel {
+	if len(klines) < period || period < 3 {
+		return nil
+	}
+	values := closes(klines).Tail(period)
+
+	// 最小二乘：x为0..period-1
+	n := float64(period)
+	sumX, sumY, sumXY, sumXX := 0.0, 0.0, 0.0, 0.0
+	for i, y := range values {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return nil
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator
+	intercept := (sumY - slope*sumX) / n
+
+	// 残差和R²
+	meanY := sumY / n
+	ssRes, ssTot := 0.0, 0.0
+	for i, y := range values {
+		fitted := intercept + slope*float64(i)
+		ssRes += (y - fitted) * (y - fitted)
+		ssTot += (y - meanY) * (y - meanY)
+	}
+	r2 := 0.0
+	if ssTot > 0 {
+		r2 = 1 - ssRes/ssTot
+	}
+	stdDev := math.Sqrt(ssRes / n)
+
+	middle := intercept + slope*(n-1)
+	channel := &RegressionChannel{
+		Period: period,
+		Slope:  slope,
+		R2:     r2,
+		Middle: middle,
+		Upper:  middle + 2*stdDev,
+		Lower:  middle - 2*stdDev,
+	}
+	if middle != 0 {
+		channel.SlopePct = slope / middle * 100
+	}
+	return channel
+}
+
+// logReturns 计算对数收益率序列
+func logReturns(klines []Kline) []float64 {
+	returns := make([]float64, 0, len(klines))
+	for i := 1; i < len(klines); i++ {
+		if klines[i-1].Close > 0 && klines[i].Close > 0 {
+			returns = append(returns, math.Log(klines[i].Close/klines[i-1].Close))
+		}
+	}
+	return returns
+}
+
+// calculateHurst 用重标极差(R/S)法估计Hurst指数
+// H > 0.5 趋势延续，H < 0.5 均值回归，约0.5为随机游走；数据不足（少于32个收益率）或价格没有波动时ok为false
+func calculateHurst(klines []Kline) (float64, bool) {
+	returns := logReturns(klines)
+	if len(returns) < 32 {
+		return 0, false
+	}
+
+	// 按窗口大小(8,16,32,...)计算平均R/S，对log(n)与log(R/S)做线性回归，斜率即H
+	var logN, logRS []float64
+	for size := 8; size <= len(returns)/2; size *= 2 {
+		total, count := 0.0, 0
+		for start := 0; start+size <= len(returns); start += size {
+			if rs := rescaledRange(returns[start : start+size]); rs > 0 {
+				total += rs
+				count++
+			}
+		}
+		if count > 0 {
+			logN = append(logN, math.Log(float64(size)))
+			logRS = append(logRS, math.Log(total/float64(count)))
+		}
+	}
+	if len(logN) < 2 {
+		return 0, false
+	}
+
+	n := float64(len(logN))
+	sumX, sumY, sumXY, sumXX := 0.0, 0.0, 0.0, 0.0
+	for i := range logN {
+		sumX += logN[i]
+		sumY += logRS[i]
+		sumXY += logN[i] * logRS[i]
+		sumXX += logN[i] * logN[i]
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
+
+// rescaledRange 计算一段收益率的R/S值
+func rescaledRange(returns []float64) float64 {
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	cumulative, maxDev, minDev, variance := 0.0, 0.0, 0.0, 0.0
+	for _, r := range returns {
+		cumulative += r - mean
+		maxDev = math.Max(maxDev, cumulative)
+		minDev = math.Min(minDev, cumulative)
+		variance += (r - mean) * (r - mean)
+	}
+	stdDev := math.Sqrt(variance / float64(len(returns)))
+	if stdDev == 0 {
+		return 0
+	}
+	return (maxDev - minDev) / stdDev
+}
+
+// calculateVarianceRatio 计算方差比 VR(q) = Var(q期收益) / (q × Var(1期收益))
+// VR > 1 趋势（收益正相关），VR < 1 均值回归；数据不足或价格没有波动时ok为false
+func calculateVarianceRatio(klines []Kline, q int) (float64, bool) {
+	returns := logReturns(klines)
+	if q < 2 || len(returns) < q*4 {
+		return 0, false
+	}
+
+	variance := func(values []float64) float64 {
+		mean := 0.0
+		for _, v := range values {
+			mean += v
+		}
+		mean /= float64(len(values))
+		sum := 0.0
+		for _, v := range values {
+			sum += (v - mean) * (v - mean)
+		}
+		return sum / float64(len(values))
+	}
+
+	// q期重叠收益
+	aggregated := make([]float64, 0, len(returns)-q+1)
+	for i := 0; i+q <= len(returns); i++ {
+		sum := 0.0
+		for _, r := range returns[i : i+q] {
+			sum += r
+		}
+		aggregated = append(aggregated, sum)
+	}
+
+	single := variance(returns)
+	if single == 0 {
+		return 0, false
+	}
+	return variance(aggregated) / (float64(q) * single), true
+}
+
+// Regime 根据Hurst指数判断市场状态
+func (d *LongerTermData) Regime() string {
+	switch {
+	case !d.Available("hurst"):
+		return "unknown"
+	case d.Hurst > 0.55:
+		return "trending"
+	case d.Hurst < 0.45:
+		return "mean-reverting"
+	default:
+		return "random-walk"
+	}
+}
+
+// VolatilityRegime 根据短期/长期ATR之比和布林带收窄判断波动率状态
+func (d *LongerTermData) VolatilityRegime() string {
+	switch {
+	case !d.Available("atr_fast") || !d.Available("atr_slow") || d.ATR14 == 0:
+		return "unknown"
+	case d.ATR3 > 1.3*d.ATR14:
+		return "expanding"
+	case d.Squeeze || d.ATR3 < 0.8*d.ATR14:
+		return "contracting"
+	default:
+		return "normal"
+	}
+}
+
+// calculateRealizedVolatility 计算收盘价对数收益率的已实现波动率（%）
+// 按K线周期换算为日波动率和年化波动率（加密货币全年交易，按365天年化）
+func calculateRealizedVolatility(klines []Kline) (daily, annualized float64, ok bool) {
+	returns := logReturns(klines)
+	if len(returns) < 2 || len(klines) < 2 {
+		return 0, 0, false
+	}
+	barDuration := time.Duration(klines[1].OpenTime-klines[0].OpenTime) * time.Millisecond
+	if barDuration <= 0 {
+		return 0, 0, false
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	perBar := math.Sqrt(variance / float64(len(returns)-1))
+
+	barsPerDay := float64(24*time.Hour) / float64(barDuration)
+	daily = perBar * math.Sqrt(barsPerDay) * 100
+	return daily, daily * math.Sqrt(365), true
+}
@@ -0,0 +1,86 @@
+package market
+
+// klineRing 固定容量的K线环形缓冲区（写满后覆盖最旧的K线，内存占用不随运行时间增长）
+type klineRing struct {
+	buf   []Kline
+	start int // 最旧K线的位置
+	count int
+}
+
+func newKlineRing(capacity int) *klineRing {
+	return &klineRing{buf: make([]Kline, capacity)}
+}
+
+// push 追加K线（已满时覆盖最旧的）
+func (r *klineRing) push(k Kline) {
+	if len(r.buf) == 0 {
+		return
+	}
+	if r.count < len(r.buf) {
+		r.buf[(r.start+r.count)%len(r.buf)] = k
+		r.count++
+		return
+	}
+	r.buf[r.start] = k
+	r.start = (r.start + 1) % len(r.buf)
+}
+
+// slice 按时间顺序复制全部K线
+func (r *klineRing) slice() []Kline {
+	result := make([]Kline, r.count)
+	for i := range result {
+		result[i] = r.buf[(r.start+i)%len(r.buf)]
+	}
+	return result
+}
+
+// klineDownsampler 将移出近期缓存的K线按factor根合并为一根粗粒度K线（按开盘时间对齐到factor倍周期），
+// 写入固定容量的归档环形缓冲区
+type klineDownsampler struct {
+	bucketMs int64 // 粗粒度K线的周期（毫秒）
+	archive  *klineRing
+	pending  *Kline // 正在合并的粗粒度K线
+	lastOpen int64  // 最近合并的K线开盘时间（更早的K线被忽略，保证归档按时间递增）
+}
+
+func newKlineDownsampler(intervalMs int64, factor, capacity int) *klineDownsampler {
+	return &klineDownsampler{bucketMs: intervalMs * int64(factor), archive: newKlineRing(capacity)}
+}
+
+// add 合并一根K线，进入新周期时把已完成的粗粒度K线写入归档
+func (d *klineDownsampler) add(k Kline) {
+	if k.OpenTime <= d.lastOpen {
+		return
+	}
+	d.lastOpen = k.OpenTime
+
+	bucket := k.OpenTime - k.OpenTime%d.bucketMs
+	if d.pending != nil && d.pending.OpenTime != bucket {
+		d.archive.push(*d.pending)
+		d.pending = nil
+	}
+	if d.pending == nil {
+		merged := k
+		merged.OpenTime = bucket
+		d.pending = &merged
+		return
+	}
+	if k.High > d.pending.High {
+		d.pending.High = k.High
+	}
+	if k.Low < d.pending.Low {
+		d.pending.Low = k.Low
+	}
+	d.pending.Close = k.Close
+	d.pending.Volume += k.Volume
+	d.pending.CloseTime = k.CloseTime
+}
+
+// klines 归档的粗粒度K线（含正在合并的一根），按时间升序
+func (d *klineDownsampler) klines() []Kline {
+	result := d.archive.slice()
+	if d.pending != nil {
+		result = append(result, *d.pending)
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package market
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// decodeKlines 流式解析币安K线响应（[[openTime,"open","high","low","close","volume",closeTime,...],...]）
+// 逐个token读取，不构造[][]interface{}中间结构；响应为错误对象时返回Binance API错误
+func decodeKlines(r io.Reader, sizeHint int) ([]Kline, error) {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse klines data: %v", err)
+	}
+	if first == '{' {
+		var binanceErr BinanceError
+		if err := json.NewDecoder(br).Decode(&binanceErr); err != nil {
+			return nil, fmt.Errorf("Failed to parse klines data: %v", err)
+		}
+		return nil, fmt.Errorf("Binance API Error %d: %s", binanceErr.Code, binanceErr.Msg)
+	}
+
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+	if err := expectDelim(dec, '['); err != nil {
+		return nil, err
+	}
+
+	klines := make([]Kline, 0, sizeHint)
+	for dec.More() {
+		k, err := decodeKline(dec)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse klines data: %v", err)
+		}
+		klines = append(klines, k)
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		return nil, err
+	}
+	return klines, nil
+}
+
+// decodeKline 解析单根K线数组，忽略第7个字段之后的成交额/成交笔数等字段
+// 字段不足7个、类型不符（对象/数组/布尔/null）或不是有限数值时返回错误
+func decodeKline(dec *json.Decoder) (Kline, error) {
+	var k Kline
+	if err := expectDelim(dec, '['); err != nil {
+		return k, err
+	}
+
+	fields := 0
+	for ; dec.More(); fields++ {
+		if fields > 6 {
+			// 整体跳过多余字段（可能是任意JSON值，逐token读取会把嵌套数组的元素当作字段）
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return k, err
+			}
+			continue
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return k, err
+		}
+		value, err := tokenFloat(tok)
+		if err != nil {
+			return k, fmt.Errorf("字段%d: %v", fields, err)
+		}
+		switch fields {
+		case 0:
+			if k.OpenTime, err = finiteMillis("openTime", value); err != nil {
+				return k, err
+			}
+		case 1:
+			k.Open = value
+		case 2:
+			k.High = value
+		case 3:
+			k.Low = value
+		case 4:
+			k.Close = value
+		case 5:
+			k.Volume = value
+		case 6:
+			if k.CloseTime, err = finiteMillis("closeTime", value); err != nil {
+				return k, err
+			}
+		}
+	}
+	if fields < 7 {
+		return k, fmt.Errorf("只有%d个字段", fields)
+	}
+
+	return k, expectDelim(dec, ']')
+}
+
+// tokenFloat 将数字或字符串token转换为有限的float64
+func tokenFloat(tok json.Token) (float64, error) {
+	switch v := tok.(type) {
+	case json.Number:
+		return parseDecimal("value", string(v))
+	case string:
+		return parseDecimal("value", v)
+	default:
+		return 0, fmt.Errorf("unsupported type: %T", tok)
+	}
+}
+
+// expectDelim 读取下一个token并确认是指定的分隔符
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("Failed to parse klines data: %v", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("Failed to parse klines data: expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// peekNonSpace 跳过空白并返回下一个字节（不消费）
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
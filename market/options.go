@@ -0,0 +1,414 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/symbols"
+	"nofx/utils"
+)
+
+// OptionsData 期权市场概要（隐含波动率、偏度、多空比）
+type OptionsData struct {
+	Source       string    // 数据来源: "deribit" 或 "binance"
+	Expiry       time.Time // 用于计算ATM IV和偏度的到期日（最接近30天）
+	ATMIV        float64   // 平值隐含波动率（%）
+	Skew25Delta  float64   // 25-delta偏度 = 25Δ看跌IV - 25Δ看涨IV（%，正值表示下行保护更贵）
+	PutCallRatio float64   // 看跌/看涨比（Deribit按持仓量，Binance按24h成交量）
+	UpdatedAt    time.Time
+}
+
+// optionQuote 单个期权合约报价
+type optionQuote struct {
+	Expiry time.Time
+	Strike float64
+	IsCall bool
+	IV     float64 // 隐含波动率（%）
+	Delta  float64 // NaN表示交易所未提供，需要自行计算
+	OI     float64
+	Volume float64
+}
+
+// optionsCacheEntry 期权数据缓存（data为nil表示没有期权市场，err非nil表示获取失败）
+type optionsCacheEntry struct {
+	data      *OptionsData
+	err       error
+	fetchedAt time.Time
+}
+
+// 期权数据源配置
+var (
+	optionsSource     string // ""表示关闭，"deribit" 或 "binance"
+	optionsCache      = make(map[string]optionsCacheEntry)
+	optionsCacheMutex sync.Mutex
+)
+
+const (
+	// optionsCacheDuration 期权数据变化较慢，缓存5分钟避免频繁请求（没有期权市场的结果同样缓存）
+	optionsCacheDuration = 5 * time.Minute
+	// optionsFailureCacheDuration 获取失败后的短暂缓存，避免每个周期都重试拖慢交易循环
+	optionsFailureCacheDuration = time.Minute
+)
+
+// optionsHTTPClient 期权数据请求的客户端（期权只是辅助数据，超时比行情请求更短）
+var optionsHTTPClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: sharedTransport,
+}
+
+// SetOptionsSource 设置期权数据来源（""关闭、"deribit" 或 "binance"）
+func SetOptionsSource(source string) {
+	optionsSource = strings.ToLower(source)
+	log.Printf("✓ 期权数据来源: %s", source)
+}
+
+// GetOptionsData 获取标的币种的期权概要（未启用或无期权市场时返回nil）
+func GetOptionsData(symbol string) (*OptionsData, error) {
+	if optionsSource == "" {
+		return nil, nil
+	}
+
+	underlying := symbols.Canonical(symbol)
+
+	optionsCacheMutex.Lock()
+	cached, ok := optionsCache[underlying]
+	optionsCacheMutex.Unlock()
+	if ok {
+		ttl := optionsCacheDuration
+		if cached.err != nil {
+			ttl = optionsFailureCacheDuration
+		}
+		if time.Since(cached.fetchedAt) < ttl {
+			return cached.data, cached.err
+		}
+	}
+
+	data, err := fetchOptionsData(underlying)
+	optionsCacheMutex.Lock()
+	optionsCache[underlying] = optionsCacheEntry{data: data, err: err, fetchedAt: time.Now()}
+	optionsCacheMutex.Unlock()
+	return data, err
+}
+
+// fetchOptionsData 从配置的数据来源获取期权概要（没有期权市场时返回nil）
+func fetchOptionsData(underlying string) (*OptionsData, error) {
+	var quotes []optionQuote
+	var underlyingPrice float64
+	var err error
+	switch optionsSource {
+	case "deribit":
+		quotes, underlyingPrice, err = fetchDeribitOptions(underlying)
+	case "binance":
+		quotes, underlyingPrice, err = fetchBinanceOptions(underlying)
+	default:
+		return nil, fmt.Errorf("不支持的期权数据来源: %s", optionsSource)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(quotes) == 0 || underlyingPrice <= 0 {
+		return nil, nil // 该币种没有期权市场
+	}
+
+	data := summarizeOptions(quotes, underlyingPrice, time.Now())
+	if data == nil {
+		return nil, nil
+	}
+	data.Source = optionsSource
+	return data, nil
+}
+
+// fetchDeribitOptions 从Deribit获取期权概要（仅BTC/ETH等有期权的币种）
+func fetchDeribitOptions(underlying string) ([]optionQuote, float64, error) {
+	url := fmt.Sprintf("https://www.deribit.com/api/v2/public/get_book_summary_by_currency?currency=%s&kind=option", underlying)
+
+	body, err := httpGetBodyWith(optionsHTTPClient, url)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var result struct {
+		Result []struct {
+			InstrumentName  string  `json:"instrument_name"`
+			MarkIV          float64 `json:"mark_iv"`
+			UnderlyingPrice float64 `json:"underlying_price"`
+			OpenInterest    float64 `json:"open_interest"`
+			Volume          float64 `json:"volume"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, 0, fmt.Errorf("解析Deribit期权数据失败: %w", err)
+	}
+	if result.Error != nil {
+		// 不支持的币种直接视为没有期权市场
+		return nil, 0, nil
+	}
+
+	var quotes []optionQuote
+	var underlyingPrice float64
+	for _, item := range result.Result {
+		// 合约格式: BTC-27DEC24-60000-C
+		parts := strings.Split(item.InstrumentName, "-")
+		if len(parts) != 4 {
+			continue
+		}
+		expiry, err := time.Parse("2Jan06", parts[1])
+		if err != nil {
+			continue
+		}
+		strike, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			continue
+		}
+
+		quotes = append(quotes, optionQuote{
+			Expiry: expiry.Add(8 * time.Hour), // Deribit期权在UTC 08:00到期
+			Strike: strike,
+			IsCall: parts[3] == "C",
+			IV:     item.MarkIV,
+			Delta:  math.NaN(),
+			OI:     item.OpenInterest,
+			Volume: item.Volume,
+		})
+		if item.UnderlyingPrice > 0 {
+			underlyingPrice = item.UnderlyingPrice
+		}
+	}
+
+	return quotes, underlyingPrice, nil
+}
+
+// fetchBinanceOptions 从币安期权（eapi）获取期权概要
+func fetchBinanceOptions(underlying string) ([]optionQuote, float64, error) {
+	indexBody, err := httpGetBodyWith(optionsHTTPClient, fmt.Sprintf("https://eapi.binance.com/eapi/v1/index?underlying=%sUSDT", underlying))
+	if err != nil {
+		return nil, 0, err
+	}
+	var index struct {
+		IndexPrice string `json:"indexPrice"`
+	}
+	if err := json.Unmarshal(indexBody, &index); err != nil || index.IndexPrice == "" {
+		return nil, 0, nil // 该币种没有期权市场
+	}
+	underlyingPrice, _ := strconv.ParseFloat(index.IndexPrice, 64)
+
+	var marks []struct {
+		Symbol string `json:"symbol"`
+		MarkIV string `json:"markIV"`
+		Delta  string `json:"delta"`
+	}
+	if err := httpGetJSON(optionsHTTPClient, "https://eapi.binance.com/eapi/v1/mark", &marks); err != nil {
+		return nil, 0, fmt.Errorf("解析币安期权标记价格失败: %w", err)
+	}
+
+	var tickers []struct {
+		Symbol string `json:"symbol"`
+		Volume string `json:"volume"`
+	}
+	if err := httpGetJSON(optionsHTTPClient, "https://eapi.binance.com/eapi/v1/ticker", &tickers); err != nil {
+		return nil, 0, fmt.Errorf("解析币安期权行情失败: %w", err)
+	}
+	volumes := make(map[string]float64, len(tickers))
+	for _, t := range tickers {
+		volumes[t.Symbol], _ = strconv.ParseFloat(t.Volume, 64)
+	}
+
+	var quotes []optionQuote
+	for _, m := range marks {
+		// 合约格式: BTC-251226-60000-C
+		parts := strings.Split(m.Symbol, "-")
+		if len(parts) != 4 || parts[0] != underlying {
+			continue
+		}
+		expiry, err := time.Parse("060102", parts[1])
+		if err != nil {
+			continue
+		}
+		strike, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			continue
+		}
+		iv, _ := strconv.ParseFloat(m.MarkIV, 64)
+		delta, err := strconv.ParseFloat(m.Delta, 64)
+		if err != nil {
+			delta = math.NaN()
+		}
+
+		quotes = append(quotes, optionQuote{
+			Expiry: expiry.Add(8 * time.Hour), // 币安期权在UTC 08:00到期
+			Strike: strike,
+			IsCall: parts[3] == "C",
+			IV:     iv * 100, // 币安返回小数形式
+			Delta:  delta,
+			Volume: volumes[m.Symbol],
+		})
+	}
+
+	return quotes, underlyingPrice, nil
+}
+
+// summarizeOptions 计算ATM IV、25-delta偏度和看跌/看涨比
+func summarizeOptions(quotes []optionQuote, underlyingPrice float64, now time.Time) *OptionsData {
+	// 选择最接近30天的到期日（至少剩余1天）
+	const targetDays = 30.0
+	var expiry time.Time
+	bestDiff := math.MaxFloat64
+	for _, q := range quotes {
+		days := q.Expiry.Sub(now).Hours() / 24
+		if days < 1 {
+			continue
+		}
+		if diff := math.Abs(days - targetDays); diff < bestDiff {
+			bestDiff = diff
+			expiry = q.Expiry
+		}
+	}
+	if expiry.IsZero() {
+		return nil
+	}
+	years := expiry.Sub(now).Hours() / 24 / 365
+
+	// ATM IV: 最接近标的价格的行权价（看涨/看跌平均）
+	atmStrike := 0.0
+	bestDist := math.MaxFloat64
+	for _, q := range quotes {
+		if !q.Expiry.Equal(expiry) || q.IV <= 0 {
+			continue
+		}
+		if dist := math.Abs(q.Strike - underlyingPrice); dist < bestDist {
+			bestDist = dist
+			atmStrike = q.Strike
+		}
+	}
+	var atmSum float64
+	var atmCount int
+	for _, q := range quotes {
+		if q.Expiry.Equal(expiry) && q.Strike == atmStrike && q.IV > 0 {
+			atmSum += q.IV
+			atmCount++
+		}
+	}
+	if atmCount == 0 {
+		return nil
+	}
+
+	// 25-delta偏度
+	var callIV, putIV float64
+	callDist, putDist := math.MaxFloat64, math.MaxFloat64
+	for _, q := range quotes {
+		if !q.Expiry.Equal(expiry) || q.IV <= 0 {
+			continue
+		}
+		delta := q.Delta
+		if math.IsNaN(delta) {
+			delta = blackScholesDelta(underlyingPrice, q.Strike, q.IV/100, years, q.IsCall)
+		}
+		if q.IsCall {
+			if dist := math.Abs(delta - 0.25); dist < callDist {
+				callDist = dist
+				callIV = q.IV
+			}
+		} else {
+			if dist := math.Abs(delta + 0.25); dist < putDist {
+				putDist = dist
+				putIV = q.IV
+			}
+		}
+	}
+
+	// 看跌/看涨比（优先使用持仓量，没有则使用成交量）
+	var putOI, callOI, putVol, callVol float64
+	for _, q := range quotes {
+		if q.IsCall {
+			callOI += q.OI
+			callVol += q.Volume
+		} else {
+			putOI += q.OI
+			putVol += q.Volume
+		}
+	}
+	putCallRatio := 0.0
+	if callOI > 0 {
+		putCallRatio = putOI / callOI
+	} else if callVol > 0 {
+		putCallRatio = putVol / callVol
+	}
+
+	data := &OptionsData{
+		Expiry:       expiry,
+		ATMIV:        atmSum / float64(atmCount),
+		PutCallRatio: putCallRatio,
+		UpdatedAt:    now,
+	}
+	if callIV > 0 && putIV > 0 {
+		data.Skew25Delta = putIV - callIV
+	}
+	return data
+}
+
+// blackScholesDelta 计算期权delta（无风险利率取0）
+func blackScholesDelta(spot, strike, vol, years float64, isCall bool) float64 {
+	if spot <= 0 || strike <= 0 || vol <= 0 || years <= 0 {
+		return math.NaN()
+	}
+	d1 := (math.Log(spot/strike) + 0.5*vol*vol*years) / (vol * math.Sqrt(years))
+	nd1 := 0.5 * math.Erfc(-d1/math.Sqrt2)
+	if isCall {
+		return nd1
+	}
+	return nd1 - 1
+}
+
+// FormatOptions 格式化期权概要
+func FormatOptions(data *OptionsData) string {
+	if data == nil {
+		return ""
+	}
+
+	sentiment := "中性"
+	if data.Skew25Delta > 2 {
+		sentiment = "偏空（看跌期权溢价）"
+	} else if data.Skew25Delta < -2 {
+		sentiment = "偏多（看涨期权溢价）"
+	}
+
+	return fmt.Sprintf("Options (%s, expiry %s): ATM IV: %.1f%% | 25Δ Skew: %+.1f%% (%s) | Put/Call Ratio: %.2f\n\n",
+		data.Source, data.Expiry.Format("2006-01-02"), data.ATMIV, data.Skew25Delta, sentiment, data.PutCallRatio)
+}
+
+// httpGetBody 发送GET请求并返回响应体
+func httpGetBody(url string) ([]byte, error) {
+	return httpGetBodyWith(httpClient, url)
+}
+
+// httpGetBodyWith 使用指定客户端发送GET请求并返回响应体
+func httpGetBodyWith(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return utils.ReadBody(resp.Body)
+}
+
+// httpGetJSON 发送GET请求并边读边解析JSON响应（全市场列表等大响应不整体读入内存）
+func httpGetJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return utils.DecodeJSON(resp.Body, v)
+}
@@ -0,0 +1,244 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// 交易所REST响应解析：只依赖响应字节，任何输入（字段缺失、类型不符、非数字、NaN/Inf、错误对象）都返回错误而不会panic，
+// 由 FuzzParsers 以变异的响应持续验证
+
+// parseDecimal 解析数值字符串，拒绝空值和NaN/Inf
+func parseDecimal(field, value string) (float64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("缺少字段%s", field)
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("字段%s不是数字: %q", field, value)
+	}
+	if math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return 0, fmt.Errorf("字段%s不是有限数值: %q", field, value)
+	}
+	return parsed, nil
+}
+
+// finiteMillis 数值转换为毫秒时间戳（拒绝负数、NaN/Inf和超出int64范围的值）
+func finiteMillis(field string, value float64) (int64, error) {
+	if math.IsNaN(value) || value < 0 || value > math.MaxInt64/2 {
+		return 0, fmt.Errorf("字段%s不是有效时间戳: %v", field, value)
+	}
+	return int64(value), nil
+}
+
+// binanceErrorIn 响应为币安错误对象（{"code":-1121,"msg":"Invalid symbol."}）时返回对应错误
+func binanceErrorIn(body []byte) error {
+	var binanceErr BinanceError
+	if json.Unmarshal(body, &binanceErr) == nil && binanceErr.Code != 0 {
+		return fmt.Errorf("Binance API Error %d: %s", binanceErr.Code, binanceErr.Msg)
+	}
+	return nil
+}
+
+// parseOpenInterest 解析币安 /fapi/v1/openInterest 响应
+func parseOpenInterest(body []byte) (*OIData, error) {
+	if err := binanceErrorIn(body); err != nil {
+		return nil, err
+	}
+	var result struct {
+		OpenInterest string `json:"openInterest"`
+		Symbol       string `json:"symbol"`
+		Time         int64  `json:"time"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析持仓量失败: %w", err)
+	}
+	oi, err := parseDecimal("openInterest", result.OpenInterest)
+	if err != nil {
+		return nil, fmt.Errorf("解析持仓量失败: %w", err)
+	}
+	if oi < 0 {
+		return nil, fmt.Errorf("解析持仓量失败: 持仓量为负数 %g", oi)
+	}
+	return &OIData{
+		Latest:  oi,
+		Average: oi * 0.999, // 近似平均值
+	}, nil
+}
+
+// parsePremiumIndex 解析币安 /fapi/v1/premiumIndex 响应（单个币种），确认资金费率和标记价格为有效数值
+func parsePremiumIndex(body []byte) (*premiumIndex, error) {
+	if err := binanceErrorIn(body); err != nil {
+		return nil, err
+	}
+	var result premiumIndex
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析资金费率失败: %w", err)
+	}
+	if _, err := parseDecimal("lastFundingRate", result.LastFundingRate); err != nil {
+		return nil, fmt.Errorf("解析资金费率失败: %w", err)
+	}
+	if _, err := parseDecimal("markPrice", result.MarkPrice); err != nil {
+		return nil, fmt.Errorf("解析资金费率失败: %w", err)
+	}
+	return &result, nil
+}
+
+// ParseDepth 解析币安 /fapi/v1/depth 及兼容接口（币本位、Aster）的响应（价格和数量为字符串）
+func ParseDepth(body []byte) (*OrderBook, error) {
+	if err := binanceErrorIn(body); err != nil {
+		return nil, err
+	}
+	var result struct {
+		Time int64      `json:"T"`
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析盘口深度失败: %w", err)
+	}
+	book := &OrderBook{Time: time.UnixMilli(result.Time)}
+	for _, side := range []struct {
+		name   string
+		raw    [][]string
+		levels *[]BookLevel
+	}{{"bids", result.Bids, &book.Bids}, {"asks", result.Asks, &book.Asks}} {
+		for i, item := range side.raw {
+			if len(item) < 2 {
+				return nil, fmt.Errorf("解析盘口深度失败: %s第%d档只有%d个字段", side.name, i, len(item))
+			}
+			price, err := parseDecimal("price", item[0])
+			if err != nil {
+				return nil, fmt.Errorf("解析盘口深度失败: %w", err)
+			}
+			quantity, err := parseDecimal("quantity", item[1])
+			if err != nil {
+				return nil, fmt.Errorf("解析盘口深度失败: %w", err)
+			}
+			if price <= 0 || quantity < 0 {
+				return nil, fmt.Errorf("解析盘口深度失败: %s第%d档价格或数量无效（%g, %g）", side.name, i, price, quantity)
+			}
+			*side.levels = append(*side.levels, BookLevel{Price: price, Quantity: quantity})
+		}
+	}
+	return book, nil
+}
+
+// parseCoinbaseKlines 解析Coinbase K线响应 [[time, low, high, open, close, volume], ...]（按时间降序），返回按时间升序的K线
+func parseCoinbaseKlines(body []byte, baseSeconds int) ([]Kline, error) {
+	var rawData [][]float64
+	if err := json.Unmarshal(body, &rawData); err != nil {
+		return nil, fmt.Errorf("解析Coinbase K线失败: %v (%s)", err, truncateBody(body))
+	}
+
+	klines := make([]Kline, 0, len(rawData))
+	for i := len(rawData) - 1; i >= 0; i-- {
+		item := rawData[i]
+		if len(item) < 6 {
+			return nil, fmt.Errorf("解析Coinbase K线失败: 第%d根K线只有%d个字段", i, len(item))
+		}
+		openTime, err := finiteMillis("time", item[0]*1000)
+		if err != nil {
+			return nil, fmt.Errorf("解析Coinbase K线失败: %w", err)
+		}
+		klines = append(klines, Kline{
+			OpenTime:  openTime,
+			Low:       item[1],
+			High:      item[2],
+			Open:      item[3],
+			Close:     item[4],
+			Volume:    item[5],
+			CloseTime: openTime + int64(baseSeconds)*1000 - 1,
+		})
+	}
+	return klines, nil
+}
+
+// parseKrakenKlines 解析Kraken OHLC结果 [[time, "open", "high", "low", "close", "vwap", "volume", count], ...]（按时间升序）
+func parseKrakenKlines(raw []byte, baseSeconds int) ([]Kline, error) {
+	var rawData [][]interface{}
+	if err := json.Unmarshal(raw, &rawData); err != nil {
+		return nil, fmt.Errorf("解析Kraken K线失败: %w", err)
+	}
+
+	klines := make([]Kline, 0, len(rawData))
+	for i, item := range rawData {
+		if len(item) < 7 {
+			return nil, fmt.Errorf("解析Kraken K线失败: 第%d根K线只有%d个字段", i, len(item))
+		}
+		var values [6]float64
+		for field, j := range []int{0, 1, 2, 3, 4, 6} {
+			value, err := parseFloat(item[j])
+			if err != nil {
+				return nil, fmt.Errorf("解析Kraken K线失败: 第%d根K线字段%d: %w", i, j, err)
+			}
+			values[field] = value
+		}
+		openTime, err := finiteMillis("time", values[0]*1000)
+		if err != nil {
+			return nil, fmt.Errorf("解析Kraken K线失败: %w", err)
+		}
+		klines = append(klines, Kline{
+			OpenTime:  openTime,
+			Open:      values[1],
+			High:      values[2],
+			Low:       values[3],
+			Close:     values[4],
+			Volume:    values[5],
+			CloseTime: openTime + int64(baseSeconds)*1000 - 1,
+		})
+	}
+	return klines, nil
+}
+
+// hyperliquidCandle Hyperliquid candleSnapshot 响应中的K线
+type hyperliquidCandle struct {
+	OpenTime  int64  `json:"t"`
+	CloseTime int64  `json:"T"`
+	Open      string `json:"o"`
+	High      string `json:"h"`
+	Low       string `json:"l"`
+	Close     string `json:"c"`
+	Volume    string `json:"v"`
+}
+
+// parseHyperliquidKlines 转换Hyperliquid K线（价格和成交量为字符串）
+func parseHyperliquidKlines(candles []hyperliquidCandle) ([]Kline, error) {
+	klines := make([]Kline, 0, len(candles))
+	for i, c := range candles {
+		if c.OpenTime < 0 || c.CloseTime < 0 {
+			return nil, fmt.Errorf("解析Hyperliquid K线失败: 第%d根K线时间戳为负 (t=%d T=%d)", i, c.OpenTime, c.CloseTime)
+		}
+		k := Kline{OpenTime: c.OpenTime, CloseTime: c.CloseTime}
+		for _, field := range []struct {
+			name  string
+			value string
+			dest  *float64
+		}{
+			{"o", c.Open, &k.Open},
+			{"h", c.High, &k.High},
+			{"l", c.Low, &k.Low},
+			{"c", c.Close, &k.Close},
+			{"v", c.Volume, &k.Volume},
+		} {
+			value, err := parseDecimal(field.name, field.value)
+			if err != nil {
+				return nil, fmt.Errorf("解析Hyperliquid K线失败: 第%d根K线: %w", i, err)
+			}
+			*field.dest = value
+		}
+		klines = append(klines, k)
+	}
+	return klines, nil
+}
+
+// truncateBody 错误信息中最多显示响应的前200字节
+func truncateBody(body []byte) string {
+	if len(body) > 200 {
+		return string(body[:200]) + "..."
+	}
+	return string(body)
+}
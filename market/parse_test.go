@@ -0,0 +1,144 @@
+package market
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// 交易所REST响应解析器的模糊测试：从真实响应格式出发变异，任何输入都只能返回错误，不能panic；
+// 解析成功时价格、成交量、时间戳都必须是有限的有效值
+// 运行: go test ./market -run '^$' -fuzz FuzzBinanceKlines -fuzztime 60s
+
+// fuzzValues 类型不符、非有限数值和边界值（与种子语料一起作为变异的起点）
+var fuzzValues = []string{
+	`null`, `[]`, `{}`, `[[]]`, `"NaN"`, `"Inf"`, `"1e999"`, `1e999`, `-1`, `18446744073709551616`, `"abc"`,
+}
+
+func addSeeds(f *testing.F, seeds ...string) {
+	for _, seed := range append(seeds, fuzzValues...) {
+		f.Add([]byte(seed))
+	}
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// checkKlines 解析成功的K线必须是有限数值、时间戳非负
+func checkKlines(t *testing.T, klines []Kline) {
+	t.Helper()
+	for i, k := range klines {
+		for _, v := range []float64{k.Open, k.High, k.Low, k.Close, k.Volume} {
+			if !isFinite(v) {
+				t.Fatalf("第%d根K线包含非有限数值: %+v", i, k)
+			}
+		}
+		if k.OpenTime < 0 || k.CloseTime < 0 {
+			t.Fatalf("第%d根K线时间戳为负: %+v", i, k)
+		}
+	}
+}
+
+func FuzzBinanceKlines(f *testing.F) {
+	addSeeds(f,
+		`[[1499040000000,"0.01634790","0.80000000","0.01575800","0.01577100","148976.11427815",1499644799999,"2434.19055334",308,"1756.87402397","28.46694368","0"]]`,
+		`[[1700000000000,"37000.1","37100.5","36950.0","37050.2","1234.5",1700000899999,"0",10,"0","0","0"],[1700000900000,"37050.2","37080.0","37000.0","37010.0","800.1",1700001799999,"0",8,"0","0","0"]]`,
+		`{"code":-1121,"msg":"Invalid symbol."}`,
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		klines, err := decodeKlines(bytes.NewReader(data), 0)
+		if err == nil {
+			checkKlines(t, klines)
+		}
+	})
+}
+
+func FuzzOpenInterest(f *testing.F) {
+	addSeeds(f,
+		`{"openInterest":"10659.509","symbol":"BTCUSDT","time":1589437530011}`,
+		`{"code":-1121,"msg":"Invalid symbol."}`,
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		oi, err := parseOpenInterest(data)
+		if err == nil && (!isFinite(oi.Latest) || oi.Latest < 0) {
+			t.Fatalf("持仓量无效: %v", oi.Latest)
+		}
+	})
+}
+
+func FuzzPremiumIndex(f *testing.F) {
+	addSeeds(f,
+		`{"symbol":"BTCUSDT","markPrice":"11793.63104562","indexPrice":"11781.80495970","estimatedSettlePrice":"11781.16138815","lastFundingRate":"0.00038246","interestRate":"0.00010000","nextFundingTime":1597392000000,"time":1597370495002}`,
+		`{"code":-1003,"msg":"Too many requests."}`,
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		index, err := parsePremiumIndex(data)
+		if err != nil {
+			return
+		}
+		if rate, err := parseDecimal("lastFundingRate", index.LastFundingRate); err != nil || !isFinite(rate) {
+			t.Fatalf("资金费率无效: %q", index.LastFundingRate)
+		}
+	})
+}
+
+func FuzzBinanceDepth(f *testing.F) {
+	addSeeds(f,
+		`{"lastUpdateId":1027024,"E":1589436922972,"T":1589436922959,"bids":[["4.00000000","431.00000000"]],"asks":[["4.00000200","12.00000000"]]}`,
+		`{"code":-1121,"msg":"Invalid symbol."}`,
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		book, err := ParseDepth(data)
+		if err != nil {
+			return
+		}
+		for _, level := range append(book.Bids, book.Asks...) {
+			if !isFinite(level.Price) || level.Price <= 0 || !isFinite(level.Quantity) || level.Quantity < 0 {
+				t.Fatalf("盘口档位无效: %+v", level)
+			}
+		}
+	})
+}
+
+func FuzzCoinbaseKlines(f *testing.F) {
+	addSeeds(f,
+		`[[1700000100,36950.0,37100.5,37000.1,37050.2,12.5],[1700000040,36900,37000,36950,36990,3.25]]`,
+		`{"message":"NotFound"}`,
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		klines, err := parseCoinbaseKlines(data, 60)
+		if err == nil {
+			checkKlines(t, klines)
+		}
+	})
+}
+
+func FuzzKrakenKlines(f *testing.F) {
+	addSeeds(f,
+		`[[1700000040,"36950.0","37000.0","36900.0","36990.0","36960.1","3.25000000",42],[1700000100,"37000.1","37100.5","36950.0","37050.2","37020.0","12.5",99]]`,
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		klines, err := parseKrakenKlines(data, 60)
+		if err == nil {
+			checkKlines(t, klines)
+		}
+	})
+}
+
+func FuzzHyperliquidKlines(f *testing.F) {
+	addSeeds(f,
+		`[{"t":1700000000000,"T":1700000899999,"s":"BTC","i":"15m","o":"37000.1","c":"37050.2","h":"37100.5","l":"36950.0","v":"12.5","n":42}]`,
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var candles []hyperliquidCandle
+		if err := json.Unmarshal(data, &candles); err != nil {
+			return
+		}
+		klines, err := parseHyperliquidKlines(candles)
+		if err == nil {
+			checkKlines(t, klines)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// correlationBars 计算与BTC相关性使用的趋势周期收益率数量
+const correlationBars = 30
+
+// benchmarkSymbol 组合上下文的基准币种
+const benchmarkSymbol = "BTCUSDT"
+
+// recentCloses 最近n根K线的收盘价
+func recentCloses(klines []Kline, n int) []float64 {
+	if len(klines) > n {
+		klines = klines[len(klines)-n:]
+	}
+	return closes(klines)
+}
+
+// Correlation 计算两个币种趋势周期收益率的相关系数和beta（a相对b）
+// 两者趋势周期不同或数据不足10个收益率时ok为false
+func Correlation(a, b *Data) (corr, beta float64, ok bool) {
+	if a == nil || b == nil || a.TrendInterval != b.TrendInterval {
+		return 0, 0, false
+	}
+	ra, rb := closeReturns(a.TrendCloses), closeReturns(b.TrendCloses)
+	n := len(ra)
+	if len(rb) < n {
+		n = len(rb)
+	}
+	if n < 10 {
+		return 0, 0, false
+	}
+	ra, rb = ra[len(ra)-n:], rb[len(rb)-n:]
+
+	meanA, meanB := 0.0, 0.0
+	for i := 0; i < n; i++ {
+		meanA += ra[i]
+		meanB += rb[i]
+	}
+	meanA /= float64(n)
+	meanB /= float64(n)
+
+	cov, varA, varB := 0.0, 0.0, 0.0
+	for i := 0; i < n; i++ {
+		da, db := ra[i]-meanA, rb[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0, 0, false
+	}
+	return cov / math.Sqrt(varA*varB), cov / varB, true
+}
+
+// closeReturns 收盘价简单收益率序列
+func closeReturns(values []float64) []float64 {
+	if len(values) < 2 {
+		return nil
+	}
+	returns := make([]float64, 0, len(values)-1)
+	for i := 1; i < len(values); i++ {
+		if values[i-1] <= 0 {
+			return nil
+		}
+		returns = append(returns, values[i]/values[i-1]-1)
+	}
+	return returns
+}
+
+// FormatMulti 将多个币种的快照合并为一个prompt段落（用于组合层面的AI决策）
+// 开头为BTC基准和各币种与BTC的相关性/beta概览，之后每个币种的完整数据以BEGIN/END分隔符包裹；
+// weights为各币种权重（如当前仓位占比或分配比例，可为nil），有权重时按权重降序排列，否则保持传入顺序
+func FormatMulti(list []*Data, weights map[string]float64) string {
+	var sb strings.Builder
+
+	ordered := make([]*Data, 0, len(list))
+	var btc *Data
+	for _, data := range list {
+		if data == nil {
+			continue
+		}
+		if data.Symbol == benchmarkSymbol {
+			btc = data
+		}
+		ordered = append(ordered, data)
+	}
+	if len(weights) > 0 {
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return weights[ordered[i].Symbol] > weights[ordered[j].Symbol]
+		})
+	}
+
+	sb.WriteString(fmt.Sprintf("=== PORTFOLIO CONTEXT (%d symbols) ===\n\n", len(ordered)))
+
+	if btc != nil {
+		sb.WriteString(fmt.Sprintf("Benchmark BTC: %.2f (1h: %+.2f%%, 4h: %+.2f%%) | strength_score %.1f\n\n",
+			btc.CurrentPrice, btc.PriceChange1h, btc.PriceChange4h, btc.StrengthScore))
+	}
+
+	sb.WriteString(fmt.Sprintf("corr_btc / beta_btc use the last %d trend-timeframe returns\n", correlationBars))
+	sb.WriteString("symbol | weight | 1h | 4h | strength | corr_btc | beta_btc\n")
+	for _, data := range ordered {
+		weight := "-"
+		if w, ok := weights[data.Symbol]; ok {
+			weight = fmt.Sprintf("%.1f%%", w*100)
+		}
+		corr, beta := "-", "-"
+		if btc != nil && data != btc {
+			if c, b, ok := Correlation(data, btc); ok {
+				corr, beta = fmt.Sprintf("%.2f", c), fmt.Sprintf("%.2f", b)
+			}
+		}
+		sb.WriteString(fmt.Sprintf("%s | %s | %+.2f%% | %+.2f%% | %.1f | %s | %s\n",
+			data.Symbol, weight, data.PriceChange1h, data.PriceChange4h, data.StrengthScore, corr, beta))
+	}
+	sb.WriteString("\n")
+
+	for _, data := range ordered {
+		sb.WriteString(fmt.Sprintf("=== BEGIN %s ===\n", data.Symbol))
+		sb.WriteString(Format(data))
+		sb.WriteString(fmt.Sprintf("=== END %s ===\n\n", data.Symbol))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,89 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PositionView 调用方当前持仓（用于在市场数据下方渲染持仓段落）
+type PositionView struct {
+	Side             string // "long" 或 "short"
+	EntryPrice       float64
+	MarkPrice        float64 // 为0时使用市场数据的当前价格
+	Quantity         float64
+	Leverage         int
+	UnrealizedPnL    float64
+	UnrealizedPnLPct float64 // 保证金收益率（%）
+	StopLoss         float64 // 0表示未设置/未知
+	TakeProfit       float64
+	LiquidationPrice float64
+	OpenedAt         time.Time // 零值表示未知
+}
+
+// FormatWithPosition 格式化市场数据，并在下方附加调用方当前持仓（pos为nil时等同于Format）
+func FormatWithPosition(data *Data, pos *PositionView) string {
+	if pos == nil {
+		return Format(data)
+	}
+	view := *pos
+	if view.MarkPrice <= 0 {
+		view.MarkPrice = data.CurrentPrice
+	}
+	return Format(data) + FormatPosition(&view)
+}
+
+// FormatPosition 格式化持仓段落（方向、入场价、数量、未实现盈亏、止损止盈及距离、持仓时长）
+func FormatPosition(pos *PositionView) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Current position: %s %.4f @ %.4f", strings.ToUpper(pos.Side), pos.Quantity, pos.EntryPrice))
+	if pos.Leverage > 0 {
+		sb.WriteString(fmt.Sprintf(" (%dx)", pos.Leverage))
+	}
+	if pos.MarkPrice > 0 {
+		sb.WriteString(fmt.Sprintf(" | mark %.4f", pos.MarkPrice))
+	}
+	sb.WriteString(fmt.Sprintf(" | unrealized PnL %+.2f (%+.2f%%)\n", pos.UnrealizedPnL, pos.UnrealizedPnLPct))
+
+	var parts []string
+	if pos.StopLoss > 0 {
+		parts = append(parts, fmt.Sprintf("stop_loss %.4f%s", pos.StopLoss, distanceFrom(pos.MarkPrice, pos.StopLoss)))
+	} else {
+		parts = append(parts, "stop_loss none")
+	}
+	if pos.TakeProfit > 0 {
+		parts = append(parts, fmt.Sprintf("take_profit %.4f%s", pos.TakeProfit, distanceFrom(pos.MarkPrice, pos.TakeProfit)))
+	}
+	if pos.LiquidationPrice > 0 {
+		parts = append(parts, fmt.Sprintf("liquidation %.4f%s", pos.LiquidationPrice, distanceFrom(pos.MarkPrice, pos.LiquidationPrice)))
+	}
+	if !pos.OpenedAt.IsZero() {
+		parts = append(parts, "time in trade "+formatHolding(time.Since(pos.OpenedAt)))
+	}
+	sb.WriteString(strings.Join(parts, " | "))
+	sb.WriteString("\n\n")
+
+	return sb.String()
+}
+
+// distanceFrom 目标价相对参考价的距离（参考价未知时为空）
+func distanceFrom(reference, target float64) string {
+	if reference <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%+.2f%%)", (target-reference)/reference*100)
+}
+
+// formatHolding 格式化持仓时长（如"45m"、"3h12m"、"2d5h"）
+func formatHolding(d time.Duration) string {
+	minutes := int(d.Minutes())
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes < 24*60:
+		return fmt.Sprintf("%dh%dm", minutes/60, minutes%60)
+	default:
+		return fmt.Sprintf("%dd%dh", minutes/(24*60), minutes%(24*60)/60)
+	}
+}
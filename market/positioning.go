@@ -0,0 +1,120 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"nofx/events"
+)
+
+// PositioningAlerts 持仓量/资金费率变化事件的阈值（0表示不检测该项）
+// 每次获取市场数据时与该币种上次观测的值比较，变化超过阈值时发布事件，让告警和策略能对持仓结构的变化做出反应
+type PositioningAlerts struct {
+	OIChangePct      float64 // 持仓量较上次观测变化超过该百分比
+	FundingChangeBps float64 // 资金费率较上次观测变化超过该基点数（1bp = 0.01%）
+}
+
+// positioningState 单个币种上次观测的持仓量和资金费率
+type positioningState struct {
+	oi         float64
+	funding    float64
+	observedAt time.Time
+}
+
+// positioningTracker 持仓量/资金费率变化检测
+type positioningTracker struct {
+	mu      sync.Mutex
+	enabled bool
+	config  PositioningAlerts
+	states  map[string]*positioningState
+}
+
+// SetPositioningAlerts 启用持仓量/资金费率变化事件（未调用时不检测）
+func (h *DataHub) SetPositioningAlerts(config PositioningAlerts) error {
+	if config.OIChangePct < 0 || config.FundingChangeBps < 0 {
+		return fmt.Errorf("持仓量/资金费率变化阈值不能为负数")
+	}
+
+	p := &h.positioning
+	p.mu.Lock()
+	p.enabled = config.OIChangePct > 0 || config.FundingChangeBps > 0
+	p.config = config
+	if p.states == nil {
+		p.states = make(map[string]*positioningState)
+	}
+	p.mu.Unlock()
+	log.Printf("📣 持仓结构变化事件: 持仓量变化 > %.2f%%，资金费率变化 > %.2fbp", config.OIChangePct, config.FundingChangeBps)
+	return nil
+}
+
+// observePositioning 记录币种最新的持仓量和资金费率，与上次观测相比变化超过阈值时发布事件
+// 多个trader在同一周期获取同一币种时，后到的调用与刚记录的值比较，不会重复发布
+func (h *DataHub) observePositioning(symbol string, oi *OIData, funding float64) {
+	p := &h.positioning
+	p.mu.Lock()
+	if !p.enabled {
+		p.mu.Unlock()
+		return
+	}
+	config := p.config
+	previous := p.states[symbol]
+	current := &positioningState{funding: funding, observedAt: time.Now()}
+	if oi != nil {
+		current.oi = oi.Latest
+	}
+	if previous != nil && current.oi <= 0 {
+		current.oi = previous.oi // 持仓量获取失败时保留上次的值作为比较基准
+	}
+	p.states[symbol] = current
+	p.mu.Unlock()
+
+	if previous == nil {
+		return
+	}
+	elapsed := current.observedAt.Sub(previous.observedAt)
+
+	if config.OIChangePct > 0 && previous.oi > 0 && current.oi > 0 {
+		changePct := (current.oi - previous.oi) / previous.oi * 100
+		if math.Abs(changePct) >= config.OIChangePct {
+			events.Publish(events.Event{
+				Type:     events.TypeOpenInterestShift,
+				Severity: events.SeverityInfo,
+				Message:  fmt.Sprintf("%s 持仓量 %+.2f%%（%.0f → %.0f，%v内）", symbol, changePct, previous.oi, current.oi, elapsed.Round(time.Second)),
+				Data: map[string]interface{}{
+					"symbol":          symbol,
+					"previous":        previous.oi,
+					"current":         current.oi,
+					"change_pct":      changePct,
+					"elapsed_seconds": elapsed.Seconds(),
+				},
+			})
+		}
+	}
+
+	if config.FundingChangeBps > 0 {
+		changeBps := (current.funding - previous.funding) * 10000
+		if math.Abs(changeBps) >= config.FundingChangeBps {
+			flipped := current.funding*previous.funding < 0
+			message := fmt.Sprintf("%s 资金费率 %+.2fbp（%.4f%% → %.4f%%，%v内）", symbol, changeBps, previous.funding*100, current.funding*100, elapsed.Round(time.Second))
+			if flipped {
+				message += "，方向翻转"
+			}
+			events.Publish(events.Event{
+				Type:     events.TypeFundingShift,
+				Severity: events.SeverityInfo,
+				Message:  message,
+				Data: map[string]interface{}{
+					"symbol":          symbol,
+					"previous":        previous.funding,
+					"current":         current.funding,
+					"change_bps":      changeBps,
+					"flipped":         flipped,
+					"elapsed_seconds": elapsed.Seconds(),
+				},
+			})
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/symbols"
+)
+
+// Provider 行情数据源接口（K线和最新价格）
+// 默认使用币安USDT永续；可切换为现货交易所（Coinbase、Kraken）或去中心化永续（Hyperliquid）获取信号
+type Provider interface {
+	// Name 数据源名称
+	Name() string
+
+	// GetKlines 获取K线（按时间升序，包含未走完的最后一根）
+	GetKlines(symbol, interval string, limit int) ([]Kline, error)
+
+	// GetPrice 获取最新成交价
+	GetPrice(symbol string) (float64, error)
+}
+
+// DerivativesProvider 提供永续合约数据（持仓量、资金费率）的数据源
+// 现货数据源不实现该接口，Data中的OI和资金费率留空
+type DerivativesProvider interface {
+	// GetOpenInterest 获取持仓量
+	GetOpenInterest(symbol string) (*OIData, error)
+
+	// GetFundingRate 获取资金费率及其结算周期（小时）
+	GetFundingRate(symbol string) (float64, int, error)
+}
+
+// FundingScheduleProvider 提供下次资金费结算时间的数据源（未实现时按结算周期从UTC零点推算）
+type FundingScheduleProvider interface {
+	NextFundingTime(symbol string) (time.Time, error)
+}
+
+var (
+	providers = map[string]Provider{
+		"binance":     &binanceProvider{},
+		"coinbase":    &coinbaseProvider{},
+		"kraken":      &krakenProvider{},
+		"hyperliquid": newHyperliquidProvider(),
+	}
+	currentProvider Provider = providers["binance"]
+	providerMutex   sync.RWMutex
+)
+
+// SetProvider 设置行情数据源（"binance"、"coinbase"、"kraken" 或 "hyperliquid"）
+func SetProvider(name string) error {
+	provider, ok := providers[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("不支持的行情数据源: %s", name)
+	}
+
+	providerMutex.Lock()
+	currentProvider = provider
+	providerMutex.Unlock()
+
+	log.Printf("✓ 行情数据源: %s", provider.Name())
+	return nil
+}
+
+// RegisterProvider 注册自定义行情数据源
+func RegisterProvider(provider Provider) {
+	providerMutex.Lock()
+	providers[provider.Name()] = provider
+	providerMutex.Unlock()
+}
+
+// GetProvider 获取当前行情数据源
+func GetProvider() Provider {
+	providerMutex.RLock()
+	defer providerMutex.RUnlock()
+	return currentProvider
+}
+
+// binanceProvider 币安USDT永续行情
+type binanceProvider struct {
+	nextFunding sync.Map // symbol -> 最近一次查询资金费率时返回的下次结算时间
+	lastOI      sync.Map // symbol -> 最近一次成功获取的持仓量（cachedValue）
+	lastFunding sync.Map // symbol -> 最近一次成功获取的资金费率（cachedValue）
+}
+
+// derivativesCacheMaxAge 接口失败或熔断时可使用的持仓量/资金费率缓存的最长年龄
+const derivativesCacheMaxAge = 30 * time.Minute
+
+// cachedValue 带获取时间的缓存值
+type cachedValue struct {
+	value     interface{}
+	fetchedAt time.Time
+}
+
+// loadCached 读取未超过derivativesCacheMaxAge的缓存
+func loadCached(cache *sync.Map, symbol string) (interface{}, bool) {
+	v, ok := cache.Load(symbol)
+	if !ok || time.Since(v.(cachedValue).fetchedAt) > derivativesCacheMaxAge {
+		return nil, false
+	}
+	return v.(cachedValue).value, true
+}
+
+func (p *binanceProvider) Name() string { return "binance" }
+
+// GetOpenInterest 请求失败（含接口熔断）时返回最近一次成功获取的持仓量
+func (p *binanceProvider) GetOpenInterest(symbol string) (*OIData, error) {
+	oi, err := getOpenInterestData(symbol)
+	if err != nil {
+		if cached, ok := loadCached(&p.lastOI, symbol); ok {
+			return cached.(*OIData), nil
+		}
+		return nil, err
+	}
+	p.lastOI.Store(symbol, cachedValue{value: oi, fetchedAt: time.Now()})
+	return oi, nil
+}
+
+// GetFundingRate 币安永续默认每8小时结算一次资金费（部分币种为4小时，见fundingInfo）；
+// 请求失败（含接口熔断）时返回最近一次成功获取的费率
+func (p *binanceProvider) GetFundingRate(symbol string) (float64, int, error) {
+	hours := binanceFundingInterval(symbol)
+	index, err := getPremiumIndex(symbol)
+	if err != nil {
+		if cached, ok := loadCached(&p.lastFunding, symbol); ok {
+			return cached.(float64), hours, nil
+		}
+		return 0, hours, err
+	}
+	p.nextFunding.Store(symbol, time.UnixMilli(index.NextFundingTime))
+	rate, _ := parseDecimal("lastFundingRate", index.LastFundingRate) // 已由parsePremiumIndex校验
+	p.lastFunding.Store(symbol, cachedValue{value: rate, fetchedAt: time.Now()})
+	return rate, hours, nil
+}
+
+// binanceFundingInterval 币种的资金费结算周期（小时）：fundingInfo只列出调整过结算周期的币种，其余为8小时
+// fundingInfo经由响应缓存获取，每个周期逐币种调用不会重复下载
+func binanceFundingInterval(symbol string) int {
+	body, err := binanceGetBody("https://fapi.binance.com/fapi/v1/fundingInfo")
+	if err != nil {
+		return 8
+	}
+	var infos []struct {
+		Symbol               string `json:"symbol"`
+		FundingIntervalHours int    `json:"fundingIntervalHours"`
+	}
+	if err := json.Unmarshal(body, &infos); err != nil {
+		return 8
+	}
+	for _, info := range infos {
+		if info.Symbol == symbol && info.FundingIntervalHours > 0 {
+			return info.FundingIntervalHours
+		}
+	}
+	return 8
+}
+
+// NextFundingTime 币安部分币种按4小时结算，优先使用实时行情中心推送或最近一次查询资金费率返回的结算时间，否则查询premiumIndex
+func (p *binanceProvider) NextFundingTime(symbol string) (time.Time, error) {
+	if ticker, ok := Hub.Ticker(symbol); ok && ticker.NextFundingTime.After(Clock.Now()) {
+		return ticker.NextFundingTime, nil
+	}
+	if next, ok := p.nextFunding.Load(symbol); ok && next.(time.Time).After(Clock.Now()) {
+		return next.(time.Time), nil
+	}
+	index, err := getPremiumIndex(symbol)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(index.NextFundingTime), nil
+}
+
+// GetKlines 实时行情中心运行时优先使用WebSocket推送+REST回补的K线缓存（只含已完成K线）
+func (p *binanceProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	if Hub.Running() {
+		Hub.WatchKlines(symbol, interval)
+		if klines, ok := Hub.Klines(symbol, interval, limit); ok {
+			return klines, nil
+		}
+	}
+	return getKlines(symbol, interval, limit)
+}
+
+func (p *binanceProvider) GetPrice(symbol string) (float64, error) {
+	body, err := binanceGetBody(fmt.Sprintf("https://fapi.binance.com/fapi/v1/ticker/price?symbol=%s", symbol))
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Price string `json:"price"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("解析币安价格失败: %w", err)
+	}
+	return strconv.ParseFloat(result.Price, 64)
+}
+
+// pickBaseInterval 从交易所支持的周期中选择能整除目标周期的最大周期
+// 返回基础周期（秒）和聚合倍数，例如目标4h、支持1h -> (3600, 4)
+func pickBaseInterval(interval string, supportedSeconds []int) (int, int, error) {
+	duration, err := IntervalDuration(interval)
+	if err != nil {
+		return 0, 0, err
+	}
+	target := int(duration.Seconds())
+
+	sorted := append([]int(nil), supportedSeconds...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	for _, base := range sorted {
+		if base <= target && target%base == 0 {
+			return base, target / base, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("不支持的K线周期: %s", interval)
+}
+
+// aggregateKlines 将基础周期K线聚合为更大周期（按目标周期对齐分组）
+func aggregateKlines(klines []Kline, baseSeconds, factor int) []Kline {
+	if factor <= 1 {
+		return klines
+	}
+
+	bucketMs := int64(baseSeconds*factor) * 1000
+	var result []Kline
+	for _, k := range klines {
+		bucket := k.OpenTime - k.OpenTime%bucketMs
+		if n := len(result); n > 0 && result[n-1].OpenTime == bucket {
+			last := &result[n-1]
+			if k.High > last.High {
+				last.High = k.High
+			}
+			if k.Low < last.Low {
+				last.Low = k.Low
+			}
+			last.Close = k.Close
+			last.Volume += k.Volume
+			continue
+		}
+		result = append(result, Kline{
+			OpenTime:  bucket,
+			Open:      k.Open,
+			High:      k.High,
+			Low:       k.Low,
+			Close:     k.Close,
+			Volume:    k.Volume,
+			CloseTime: bucket + bucketMs - 1,
+		})
+	}
+	return result
+}
+
+// lastKlines 取最后limit根K线
+func lastKlines(klines []Kline, limit int) []Kline {
+	if limit > 0 && len(klines) > limit {
+		return klines[len(klines)-limit:]
+	}
+	return klines
+}
+
+// coinbaseProvider Coinbase Exchange现货行情（BTC-USD等）
+type coinbaseProvider struct{}
+
+// coinbaseGranularities Coinbase支持的K线周期（秒）
+var coinbaseGranularities = []int{60, 300, 900, 3600, 21600, 86400}
+
+func (p *coinbaseProvider) Name() string { return "coinbase" }
+
+func (p *coinbaseProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	base, factor, err := pickBaseInterval(interval, coinbaseGranularities)
+	if err != nil {
+		return nil, err
+	}
+
+	// Coinbase单次最多返回300根K线
+	count := (limit + 1) * factor
+	if count > 300 {
+		count = 300
+	}
+	end := time.Now().UTC()
+	start := end.Add(-time.Duration(count*base) * time.Second)
+
+	url := fmt.Sprintf("https://api.exchange.coinbase.com/products/%s/candles?granularity=%d&start=%s&end=%s",
+		symbols.ToExchange(symbol, "coinbase"), base, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	body, err := httpGetBody(url)
+	if err != nil {
+		return nil, err
+	}
+
+	klines, err := parseCoinbaseKlines(body, base)
+	if err != nil {
+		return nil, err
+	}
+	return lastKlines(aggregateKlines(klines, base, factor), limit), nil
+}
+
+func (p *coinbaseProvider) GetPrice(symbol string) (float64, error) {
+	body, err := httpGetBody(fmt.Sprintf("https://api.exchange.coinbase.com/products/%s/ticker", symbols.ToExchange(symbol, "coinbase")))
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Price   string `json:"price"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("解析Coinbase价格失败: %w", err)
+	}
+	if result.Price == "" {
+		return 0, fmt.Errorf("Coinbase API错误: %s", result.Message)
+	}
+	return strconv.ParseFloat(result.Price, 64)
+}
+
+// krakenProvider Kraken现货行情（XBTUSD等）
+type krakenProvider struct{}
+
+// krakenIntervals Kraken支持的K线周期（秒）
+var krakenIntervals = []int{60, 300, 900, 1800, 3600, 14400, 86400, 604800}
+
+func (p *krakenProvider) Name() string { return "kraken" }
+
+// krakenResult 解析Kraken响应（result中以交易对为key，忽略"last"字段）
+func krakenResult(body []byte) (json.RawMessage, error) {
+	var response struct {
+		Error  []string                   `json:"error"`
+		Result map[string]json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析Kraken响应失败: %w", err)
+	}
+	if len(response.Error) > 0 {
+		return nil, fmt.Errorf("Kraken API错误: %s", strings.Join(response.Error, "; "))
+	}
+	for key, raw := range response.Result {
+		if key != "last" {
+			return raw, nil
+		}
+	}
+	return nil, fmt.Errorf("Kraken响应为空")
+}
+
+func (p *krakenProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	base, factor, err := pickBaseInterval(interval, krakenIntervals)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("https://api.kraken.com/0/public/OHLC?pair=%s&interval=%d", symbols.ToExchange(symbol, "kraken"), base/60)
+	body, err := httpGetBody(url)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := krakenResult(body)
+	if err != nil {
+		return nil, err
+	}
+
+	klines, err := parseKrakenKlines(raw, base)
+	if err != nil {
+		return nil, err
+	}
+	return lastKlines(aggregateKlines(klines, base, factor), limit), nil
+}
+
+func (p *krakenProvider) GetPrice(symbol string) (float64, error) {
+	body, err := httpGetBody(fmt.Sprintf("https://api.kraken.com/0/public/Ticker?pair=%s", symbols.ToExchange(symbol, "kraken")))
+	if err != nil {
+		return 0, err
+	}
+	raw, err := krakenResult(body)
+	if err != nil {
+		return 0, err
+	}
+
+	var ticker struct {
+		C []string `json:"c"` // 最新成交 [价格, 数量]
+	}
+	if err := json.Unmarshal(raw, &ticker); err != nil || len(ticker.C) == 0 {
+		return 0, fmt.Errorf("解析Kraken价格失败")
+	}
+	return strconv.ParseFloat(ticker.C[0], 64)
+}
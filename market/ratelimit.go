@@ -0,0 +1,358 @@
+package market
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestPriority 币安REST请求的优先级：请求权重用尽时按优先级排队，高优先级先执行
+type RequestPriority int
+
+const (
+	PriorityScreening RequestPriority = iota // 筛选/批量数据（全市场行情、交易规则、历史K线）
+	PrioritySnapshot                         // 行情快照（K线、标记价格、持仓量）
+	PriorityReconcile                        // 持仓/账户核对
+	PriorityOrder                            // 订单管理（下单、撤单、杠杆、签名校时）
+)
+
+func (p RequestPriority) String() string {
+	switch p {
+	case PriorityOrder:
+		return "order"
+	case PriorityReconcile:
+		return "reconcile"
+	case PrioritySnapshot:
+		return "snapshot"
+	}
+	return "screening"
+}
+
+// priorityReserve 各优先级不能使用的最后一部分权重（为更高优先级保留，保证订单请求不被批量数据饿死）
+var priorityReserve = [...]float64{
+	PriorityScreening: 0.2,
+	PrioritySnapshot:  0.1,
+	PriorityReconcile: 0.05,
+	PriorityOrder:     0,
+}
+
+// rateLimitWarnInterval 权重用尽提示的最短间隔
+const rateLimitWarnInterval = time.Minute
+
+// BinanceLimiter 币安合约请求的IP权重限速器（行情请求和交易客户端共用，默认币安上限2400/分钟）
+var BinanceLimiter = NewRateLimiter("币安合约", 2400)
+
+// RateLimiter 按请求权重限速的令牌桶，权重不足时请求按优先级排队（同优先级先到先得）
+// 收到交易所返回的已用权重时同步剩余额度，收到429/418时暂停全部请求直到Retry-After
+type RateLimiter struct {
+	name string
+
+	mu          sync.Mutex
+	limit       float64 // 每分钟权重上限（<=0不限速）
+	tokens      float64
+	refill      time.Time
+	pausedUntil time.Time
+	queue       rateQueue
+	seq         uint64
+	timer       *time.Timer
+	lastWarn    time.Time
+}
+
+// NewRateLimiter 创建限速器（weightPerMinute<=0表示不限速）
+func NewRateLimiter(name string, weightPerMinute int) *RateLimiter {
+	l := &RateLimiter{name: name}
+	l.SetLimit(weightPerMinute)
+	return l
+}
+
+// SetLimit 设置每分钟权重上限（<=0关闭限速，排队中的请求立即放行）
+func (l *RateLimiter) SetLimit(weightPerMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = float64(weightPerMinute)
+	l.tokens = l.limit
+	l.refill = time.Now()
+	l.dispatch()
+}
+
+// Wait 等待weight权重的额度，ctx取消时退出排队并返回错误
+func (l *RateLimiter) Wait(ctx context.Context, priority RequestPriority, weight int) error {
+	l.mu.Lock()
+	w := &rateWaiter{priority: priority, weight: float64(weight), seq: l.seq, ready: make(chan struct{})}
+	l.seq++
+	heap.Push(&l.queue, w)
+	l.dispatch()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if w.index < 0 {
+			return nil // 取消的同时已放行
+		}
+		heap.Remove(&l.queue, w.index)
+		l.dispatch()
+		return fmt.Errorf("%s 请求排队等待权重超时（%s）: %w", l.name, priority, ctx.Err())
+	}
+}
+
+// dispatch 按优先级放行排队的请求，额度不足时定时重试（调用方持有l.mu）
+func (l *RateLimiter) dispatch() {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	now := time.Now()
+	if l.limit > 0 {
+		l.tokens = math.Min(l.limit, l.tokens+now.Sub(l.refill).Minutes()*l.limit)
+	}
+	l.refill = now
+
+	for len(l.queue) > 0 {
+		w := l.queue[0]
+		var wait time.Duration
+		if l.limit > 0 && now.Before(l.pausedUntil) {
+			wait = l.pausedUntil.Sub(now)
+		} else if l.limit > 0 {
+			need := math.Min(w.weight+l.limit*priorityReserve[w.priority], l.limit)
+			if l.tokens < need {
+				wait = time.Duration((need - l.tokens) / l.limit * float64(time.Minute))
+			}
+		}
+		if wait <= 0 {
+			heap.Pop(&l.queue)
+			if l.limit > 0 {
+				l.tokens -= w.weight
+			}
+			close(w.ready)
+			continue
+		}
+
+		if now.Sub(l.lastWarn) >= rateLimitWarnInterval {
+			l.lastWarn = now
+			log.Printf("⏳ %s 请求权重已用尽，%d个请求按优先级排队（最高 %s，约%.1f秒后继续）",
+				l.name, len(l.queue), w.priority, wait.Seconds())
+		}
+		l.timer = time.AfterFunc(wait, l.wake)
+		return
+	}
+}
+
+func (l *RateLimiter) wake() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.dispatch()
+}
+
+// observe 根据响应同步额度：已用权重（X-MBX-USED-WEIGHT-1M）高于本地估计时扣减剩余额度，
+// 429/418（超限/封禁IP）时按Retry-After暂停全部请求
+func (l *RateLimiter) observe(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit <= 0 {
+		return
+	}
+	if used, err := strconv.ParseFloat(resp.Header.Get("X-MBX-USED-WEIGHT-1M"), 64); err == nil {
+		l.tokens = math.Min(l.tokens, l.limit-used)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusTeapot {
+		return
+	}
+	retry := time.Minute
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retry = time.Duration(seconds) * time.Second
+	}
+	if until := time.Now().Add(retry); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+		l.tokens = 0
+		log.Printf("⚠️  %s 请求超出限速（HTTP %d），暂停%.0f秒", l.name, resp.StatusCode, retry.Seconds())
+	}
+	l.dispatch()
+}
+
+// Transport 返回限速中间件：请求按路径估算权重和优先级（可用WithPriority覆盖）后排队发送
+// 应放在签名中间件外层，避免排队时间消耗recvWindow。base为nil时使用http.DefaultTransport
+func (l *RateLimiter) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &limiterTransport{limiter: l, base: base}
+}
+
+// limiterTransport 请求权重限速
+type limiterTransport struct {
+	limiter *RateLimiter
+	base    http.RoundTripper
+}
+
+func (t *limiterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context(), requestPriority(req), requestWeight(req)); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.limiter.observe(resp)
+	}
+	return resp, err
+}
+
+// rateWaiter 排队中的请求
+type rateWaiter struct {
+	priority RequestPriority
+	weight   float64
+	seq      uint64
+	index    int
+	ready    chan struct{}
+}
+
+// rateQueue 排队请求的优先队列（优先级高的在前，同优先级按到达顺序）
+type rateQueue []*rateWaiter
+
+func (q rateQueue) Len() int { return len(q) }
+
+func (q rateQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q rateQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *rateQueue) Push(x interface{}) {
+	w := x.(*rateWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *rateQueue) Pop() interface{} {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
+
+type priorityKey struct{}
+
+// WithPriority 为请求指定优先级（覆盖按路径的默认分类）
+func WithPriority(ctx context.Context, priority RequestPriority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// fapiVersionPath 匹配接口路径中的版本前缀（/fapi/v1/、/fapi/v2/…）
+var fapiVersionPath = regexp.MustCompile(`/fapi/v\d+/`)
+
+// endpointName 去掉版本前缀的接口名（如order、ticker/24hr、futures/data/openInterestHist）
+func endpointName(path string) string {
+	if loc := fapiVersionPath.FindStringIndex(path); loc != nil {
+		return path[loc[1]:]
+	}
+	return strings.TrimPrefix(path, "/")
+}
+
+// requestPriority 请求的优先级：WithPriority指定的优先，否则按接口分类
+// 未知接口中GET按行情快照处理，其他方法按订单管理处理
+func requestPriority(req *http.Request) RequestPriority {
+	if priority, ok := req.Context().Value(priorityKey{}).(RequestPriority); ok {
+		return priority
+	}
+	query := req.URL.Query()
+	name := endpointName(req.URL.Path)
+	switch name {
+	case "order", "batchOrders", "allOpenOrders", "countdownCancelAll", "leverage", "marginType",
+		"positionMargin", "positionSide/dual", "listenKey", "time":
+		return PriorityOrder
+	case "positionRisk", "account", "balance", "openOrders", "openOrder", "userTrades", "allOrders",
+		"income", "commissionRate", "leverageBracket":
+		return PriorityReconcile
+	case "ticker/24hr", "exchangeInfo", "fundingRate", "fundingInfo":
+		return PriorityScreening
+	case "klines", "continuousKlines", "markPriceKlines":
+		if query.Has("startTime") || query.Has("endTime") {
+			return PriorityScreening // 分页下载历史K线
+		}
+		return PrioritySnapshot
+	case "premiumIndex", "ticker/price", "ticker/bookTicker":
+		if !query.Has("symbol") {
+			return PriorityScreening // 全市场
+		}
+		return PrioritySnapshot
+	}
+	if strings.HasPrefix(name, "futures/data/") {
+		return PriorityScreening
+	}
+	if req.Method != http.MethodGet {
+		return PriorityOrder
+	}
+	return PrioritySnapshot
+}
+
+// requestWeight 按币安文档估算请求权重（未列出的接口按1计）
+func requestWeight(req *http.Request) int {
+	query := req.URL.Query()
+	symbol := query.Has("symbol")
+	switch endpointName(req.URL.Path) {
+	case "klines", "continuousKlines", "markPriceKlines":
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil {
+			limit = 500
+		}
+		switch {
+		case limit < 100:
+			return 1
+		case limit < 500:
+			return 2
+		case limit <= 1000:
+			return 5
+		}
+		return 10
+	case "depth":
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		switch {
+		case limit <= 50:
+			return 2
+		case limit <= 100:
+			return 5
+		case limit <= 500:
+			return 10
+		}
+		return 20
+	case "ticker/24hr", "openOrders":
+		if symbol {
+			return 1
+		}
+		return 40
+	case "premiumIndex":
+		if symbol {
+			return 1
+		}
+		return 10
+	case "ticker/price", "ticker/bookTicker":
+		if symbol {
+			return 1
+		}
+		return 2
+	case "positionRisk", "account", "balance", "userTrades", "allOrders", "batchOrders":
+		return 5
+	case "income":
+		return 30
+	}
+	return 1
+}
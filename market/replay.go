@@ -0,0 +1,161 @@
+package market
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReplayProvider 历史回放数据源：从本地历史K线存储按Clock当前时间返回“截至此刻”的K线
+// 配合Clock.SetNow推进模拟时间，实盘的行情/指标/决策代码无需任何修改即可在历史数据上运行
+// 存储中缺少某个周期时，由基础周期（baseInterval）K线聚合得到
+type ReplayProvider struct {
+	store        *HistoryStore
+	baseInterval string // 基础周期（价格推进粒度，也用于聚合其他周期）
+
+	mu     sync.Mutex
+	series map[string][]Kline // symbol_interval -> 全部历史K线
+}
+
+// NewReplayProvider 创建历史回放数据源
+func NewReplayProvider(store *HistoryStore, baseInterval string) (*ReplayProvider, error) {
+	if _, err := IntervalDuration(baseInterval); err != nil {
+		return nil, err
+	}
+	return &ReplayProvider{
+		store:        store,
+		baseInterval: baseInterval,
+		series:       make(map[string][]Kline),
+	}, nil
+}
+
+func (p *ReplayProvider) Name() string { return "replay" }
+
+// BaseInterval 基础周期
+func (p *ReplayProvider) BaseInterval() string { return p.baseInterval }
+
+// GetKlines 返回截至Clock当前时间的最近limit根K线（由基础周期聚合时最后一根可能未走完，与实盘一致）
+func (p *ReplayProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	symbol = Normalize(symbol)
+	klines, err := p.load(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(klines) > 0 {
+		visible := p.visible(klines)
+		if len(visible) == 0 {
+			return nil, fmt.Errorf("回放时间点之前没有%s %s历史K线", symbol, interval)
+		}
+		return append([]Kline(nil), lastKlines(visible, limit)...), nil
+	}
+
+	// 存储中没有该周期，由基础周期聚合
+	base, err := p.load(symbol, p.baseInterval)
+	if err != nil {
+		return nil, err
+	}
+	baseDuration, _ := IntervalDuration(p.baseInterval)
+	duration, err := IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+	if duration < baseDuration || duration%baseDuration != 0 {
+		return nil, fmt.Errorf("没有%s %s历史K线，且无法由%s聚合（请先运行 sync-history）", symbol, interval, p.baseInterval)
+	}
+	factor := int(duration / baseDuration)
+
+	visible := p.visible(base)
+	if len(visible) == 0 {
+		return nil, fmt.Errorf("回放时间点之前没有%s %s历史K线", symbol, p.baseInterval)
+	}
+	tail := lastKlines(visible, (limit+1)*factor)
+	return lastKlines(aggregateKlines(tail, int(baseDuration.Seconds()), factor), limit), nil
+}
+
+// Range 返回[from, to)时间范围内的全部K线（不受Clock限制，用于生成回放报告；存储中没有该周期时由基础周期聚合）
+func (p *ReplayProvider) Range(symbol, interval string, from, to time.Time) ([]Kline, error) {
+	symbol = Normalize(symbol)
+	klines, err := p.load(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	if len(klines) == 0 {
+		base, err := p.load(symbol, p.baseInterval)
+		if err != nil {
+			return nil, err
+		}
+		baseDuration, _ := IntervalDuration(p.baseInterval)
+		duration, err := IntervalDuration(interval)
+		if err != nil {
+			return nil, err
+		}
+		if duration < baseDuration || duration%baseDuration != 0 {
+			return nil, fmt.Errorf("没有%s %s历史K线，且无法由%s聚合", symbol, interval, p.baseInterval)
+		}
+		klines = aggregateKlines(base, int(baseDuration.Seconds()), int(duration/baseDuration))
+	}
+
+	start := sort.Search(len(klines), func(i int) bool { return klines[i].OpenTime >= from.UnixMilli() })
+	end := sort.Search(len(klines), func(i int) bool { return klines[i].OpenTime >= to.UnixMilli() })
+	return append([]Kline(nil), klines[start:end]...), nil
+}
+
+// GetPrice 最近一根已完成基础周期K线的收盘价
+func (p *ReplayProvider) GetPrice(symbol string) (float64, error) {
+	bar, ok := p.LastBar(symbol)
+	if !ok {
+		return 0, fmt.Errorf("回放时间点之前没有%s价格", Normalize(symbol))
+	}
+	return bar.Close, nil
+}
+
+// LastBar 最近一根已完成的基础周期K线（模拟撮合用其高低价判断止损/止盈是否触发）
+func (p *ReplayProvider) LastBar(symbol string) (Kline, bool) {
+	klines, err := p.load(Normalize(symbol), p.baseInterval)
+	if err != nil {
+		return Kline{}, false
+	}
+	visible := p.visible(klines)
+	if len(visible) == 0 {
+		return Kline{}, false
+	}
+	return visible[len(visible)-1], true
+}
+
+// Span 基础周期历史数据的时间范围（开盘时间，毫秒）
+func (p *ReplayProvider) Span(symbol string) (int64, int64, error) {
+	klines, err := p.load(Normalize(symbol), p.baseInterval)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(klines) == 0 {
+		return 0, 0, fmt.Errorf("没有%s %s历史K线（请先运行 sync-history）", Normalize(symbol), p.baseInterval)
+	}
+	return klines[0].OpenTime, klines[len(klines)-1].OpenTime, nil
+}
+
+// visible 截至Clock当前时间已收盘的K线
+func (p *ReplayProvider) visible(klines []Kline) []Kline {
+	now := Clock.Now().UnixMilli()
+	n := sort.Search(len(klines), func(i int) bool { return klines[i].CloseTime > now })
+	return klines[:n]
+}
+
+// load 读取并缓存币种/周期的全部历史K线
+func (p *ReplayProvider) load(symbol, interval string) ([]Kline, error) {
+	key := symbol + "_" + interval
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if klines, ok := p.series[key]; ok {
+		return klines, nil
+	}
+	klines, err := p.store.Load(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	p.series[key] = klines
+	return klines, nil
+}
@@ -0,0 +1,153 @@
+package market
+
+import "math"
+
+// Series 按时间升序的序列（最旧在前，最新在末尾）
+// 不同长度的序列按末尾（最新值）对齐：Window/Shift/Zip的结果都以最新值为准，
+// 无需手写 len-1-i、i-period+1 之类的下标计算
+type Series[T any] []T
+
+// Len 序列长度
+func (s Series[T]) Len() int {
+	return len(s)
+}
+
+// Last 最新值（序列为空时返回零值）
+func (s Series[T]) Last() T {
+	var zero T
+	if len(s) == 0 {
+		return zero
+	}
+	return s[len(s)-1]
+}
+
+// Ago n根之前的值（Ago(0)为最新值），超出范围时返回零值和false
+func (s Series[T]) Ago(n int) (T, bool) {
+	var zero T
+	if n < 0 || n >= len(s) {
+		return zero, false
+	}
+	return s[len(s)-1-n], true
+}
+
+// Head 最早的n个值（不足n个时返回全部）
+func (s Series[T]) Head(n int) Series[T] {
+	if n < 0 {
+		n = 0
+	}
+	if n > len(s) {
+		n = len(s)
+	}
+	return s[:n]
+}
+
+// Tail 最近的n个值（不足n个时返回全部）
+func (s Series[T]) Tail(n int) Series[T] {
+	if n < 0 {
+		n = 0
+	}
+	if n > len(s) {
+		n = len(s)
+	}
+	return s[len(s)-n:]
+}
+
+// Drop 去掉最早的n个值
+func (s Series[T]) Drop(n int) Series[T] {
+	if n < 0 {
+		n = 0
+	}
+	if n > len(s) {
+		n = len(s)
+	}
+	return s[n:]
+}
+
+// Shift 滞后n根的序列：按末尾对齐后，每个位置对应n根之前的值（去掉最近的n个值）
+// 例如 Zip(s, s.Shift(1), f) 依次得到 f(当前值, 前一根的值)
+func (s Series[T]) Shift(n int) Series[T] {
+	if n < 0 {
+		n = 0
+	}
+	if n > len(s) {
+		n = len(s)
+	}
+	return s[:len(s)-n]
+}
+
+// Pad 在开头补fill直到长度为length（用于还原与输入等长、数据不足处为NaN的序列）
+func (s Series[T]) Pad(length int, fill T) Series[T] {
+	if len(s) >= length {
+		return s
+	}
+	result := make(Series[T], length)
+	offset := length - len(s)
+	for i := 0; i < offset; i++ {
+		result[i] = fill
+	}
+	copy(result[offset:], s)
+	return result
+}
+
+// Map 逐个转换
+func Map[T, U any](s Series[T], f func(T) U) Series[U] {
+	result := make(Series[U], len(s))
+	for i, v := range s {
+		result[i] = f(v)
+	}
+	return result
+}
+
+// Zip 将两个序列按末尾对齐后逐个组合，结果长度为两者中较短的
+func Zip[A, B, C any](a Series[A], b Series[B], f func(A, B) C) Series[C] {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	a, b = a.Tail(n), b.Tail(n)
+	result := make(Series[C], n)
+	for i := range result {
+		result[i] = f(a[i], b[i])
+	}
+	return result
+}
+
+// Window 对每个长度为period的滑动窗口求值，结果与s按末尾对齐（长度 len(s)-period+1，数据不足时为空）
+func Window[T, U any](s Series[T], period int, f func(Series[T]) U) Series[U] {
+	if period <= 0 || len(s) < period {
+		return nil
+	}
+	result := make(Series[U], len(s)-period+1)
+	for i := range result {
+		result[i] = f(s[i : i+period])
+	}
+	return result
+}
+
+// Scan 从seed开始依次累积（如EMA、Wilder平滑），结果为seed及之后每一步的值（长度 len(s)+1）
+func Scan[T, U any](s Series[T], seed U, f func(U, T) U) Series[U] {
+	result := make(Series[U], 0, len(s)+1)
+	result = append(result, seed)
+	for _, v := range s {
+		seed = f(seed, v)
+		result = append(result, seed)
+	}
+	return result
+}
+
+// Sum 求和
+func Sum(s Series[float64]) float64 {
+	sum := 0.0
+	for _, v := range s {
+		sum += v
+	}
+	return sum
+}
+
+// Mean 平均值（空序列返回NaN）
+func Mean(s Series[float64]) float64 {
+	if len(s) == 0 {
+		return math.NaN()
+	}
+	return Sum(s) / float64(len(s))
+}
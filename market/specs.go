@@ -0,0 +1,116 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"nofx/symbols"
+)
+
+// symbolSpecsRefreshInterval 定期重新加载合约规格的间隔（exchangeInfo有缓存和条件请求，未变化时不消耗权重）
+const symbolSpecsRefreshInterval = time.Hour
+
+// statusRemoved 已从exchangeInfo中移除的合约的交易状态（视为已下架）
+const statusRemoved = "REMOVED"
+
+// LoadSymbolSpecs 从币安USDT永续加载合约规格（价格/数量精度、上线/下架时间、交易状态）到币种注册表
+// 启动时调用，之后由StartSymbolSpecsRefresh定期刷新；失败不影响运行，Normalize会按命名规则推导
+// 刷新时记录状态和精度的变化，已加载过但不再出现在exchangeInfo中的合约标记为已下架
+func LoadSymbolSpecs() error {
+	var info struct {
+		Symbols []struct {
+			Symbol       string                   `json:"symbol"`
+			Status       string                   `json:"status"`
+			DeliveryDate int64                    `json:"deliveryDate"`
+			BaseAsset    string                   `json:"baseAsset"`
+			QuoteAsset   string                   `json:"quoteAsset"`
+			ContractType string                   `json:"contractType"`
+			OnboardDate  int64                    `json:"onboardDate"`
+			Filters      []map[string]interface{} `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := binanceGetJSON("https://fapi.binance.com/fapi/v1/exchangeInfo", &info); err != nil {
+		return fmt.Errorf("获取币安合约规格失败: %w", err)
+	}
+
+	previous := symbols.Exchange("binance")
+	seen := make(map[string]bool, len(info.Symbols))
+	count := 0
+	for _, s := range info.Symbols {
+		if s.ContractType != "PERPETUAL" || s.QuoteAsset != "USDT" {
+			continue
+		}
+
+		instrument := symbols.Instrument{
+			Exchange: "binance",
+			ID:       s.Symbol,
+			ListedAt: time.UnixMilli(s.OnboardDate),
+			Status:   s.Status,
+		}
+		// 永续合约的交割日期默认是2100年，早于该日期说明已公布下架计划
+		if deliveryAt := time.UnixMilli(s.DeliveryDate); s.DeliveryDate > 0 && deliveryAt.Year() < 2100 {
+			instrument.DelistAt = deliveryAt
+		}
+		for _, filter := range s.Filters {
+			switch filter["filterType"] {
+			case "PRICE_FILTER":
+				instrument.TickSize, _ = strconv.ParseFloat(fmt.Sprint(filter["tickSize"]), 64)
+			case "LOT_SIZE":
+				instrument.StepSize, _ = strconv.ParseFloat(fmt.Sprint(filter["stepSize"]), 64)
+			}
+		}
+
+		// 使用交易对前缀作为规范币种（1000PEPEUSDT -> 1000PEPE），保证与合约代码一一对应
+		canonical := symbols.Canonical(s.Symbol)
+		if old, ok := previous[canonical]; ok && specChanged(old, instrument) {
+			log.Printf("📋 %s 合约规格变化: 状态 %s→%s 价格精度 %g→%g 数量精度 %g→%g 下架时间 %s",
+				s.Symbol, old.Status, instrument.Status, old.TickSize, instrument.TickSize, old.StepSize, instrument.StepSize, formatDelist(instrument.DelistAt))
+		}
+		symbols.Register(canonical, instrument)
+		seen[canonical] = true
+		count++
+	}
+
+	for canonical, old := range previous {
+		if seen[canonical] || old.Status == statusRemoved {
+			continue
+		}
+		log.Printf("📋 %s 已从币安合约列表中移除，标记为已下架", old.ID)
+		old.Status = statusRemoved
+		symbols.Register(canonical, old)
+	}
+
+	if len(previous) == 0 {
+		log.Printf("✓ 已加载 %d 个币安永续合约规格", count)
+	}
+	return nil
+}
+
+// StartSymbolSpecsRefresh 定期重新加载合约规格（下架计划、状态和精度变化），进程退出前一直运行
+func StartSymbolSpecsRefresh() {
+	go func() {
+		ticker := time.NewTicker(symbolSpecsRefreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := LoadSymbolSpecs(); err != nil {
+				log.Printf("⚠️  刷新合约规格失败（沿用上次的规格）: %v", err)
+			}
+		}
+	}()
+}
+
+// specChanged 合约的交易状态、精度或下架时间是否变化
+func specChanged(old, current symbols.Instrument) bool {
+	return old.Status != current.Status || old.TickSize != current.TickSize ||
+		old.StepSize != current.StepSize || !old.DelistAt.Equal(current.DelistAt)
+}
+
+// formatDelist 下架时间的描述
+func formatDelist(t time.Time) string {
+	if t.IsZero() {
+		return "无"
+	}
+	return t.Format("2006-01-02 15:04")
+}
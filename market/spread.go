@@ -0,0 +1,139 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Spread 两个币种的价差数据：价格比 base/quote 及其在回看期内的z-score（用于配对交易/统计套利）
+type Spread struct {
+	Base        string    `json:"base"`
+	Quote       string    `json:"quote"`
+	Interval    string    `json:"interval"`
+	Lookback    int       `json:"lookback"`     // 计算均值/标准差的K线数
+	Ratio       float64   `json:"ratio"`        // 当前价格比 base/quote
+	Mean        float64   `json:"mean"`         // 回看期价格比均值
+	StdDev      float64   `json:"std_dev"`      // 回看期价格比标准差
+	ZScore      float64   `json:"z_score"`      // (Ratio - Mean) / StdDev
+	Correlation float64   `json:"correlation"`  // 两个币种收益率的相关系数
+	RatioSeries []float64 `json:"ratio_series"` // 最近10根K线的价格比
+}
+
+// Symbol 配对代码（如 ETHUSDT/BTCUSDT）
+func (s *Spread) Symbol() string {
+	return PairSymbol(s.Base, s.Quote)
+}
+
+// PairSymbol 由两个币种组成配对代码
+func PairSymbol(base, quote string) string {
+	return base + "/" + quote
+}
+
+// SplitPair 拆分配对代码为两个标准化币种
+func SplitPair(symbol string) (string, string, bool) {
+	parts := strings.Split(symbol, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return Normalize(parts[0]), Normalize(parts[1]), true
+}
+
+// GetSpread 从当前行情数据源获取两个币种的价差数据
+func GetSpread(base, quote, interval string, lookback int) (*Spread, error) {
+	baseKlines, err := GetProvider().GetKlines(base, interval, lookback+1)
+	if err != nil {
+		return nil, fmt.Errorf("获取%s K线失败: %w", base, err)
+	}
+	quoteKlines, err := GetProvider().GetKlines(quote, interval, lookback+1)
+	if err != nil {
+		return nil, fmt.Errorf("获取%s K线失败: %w", quote, err)
+	}
+	spread, err := ComputeSpread(baseKlines, quoteKlines, lookback)
+	if err != nil {
+		return nil, err
+	}
+	spread.Base, spread.Quote, spread.Interval = base, quote, interval
+	return spread, nil
+}
+
+// ComputeSpread 按开盘时间对齐两组K线，计算价格比的均值、标准差、z-score和收益率相关系数
+func ComputeSpread(base, quote []Kline, lookback int) (*Spread, error) {
+	quoteClose := make(map[int64]float64, len(quote))
+	for _, k := range quote {
+		quoteClose[k.OpenTime] = k.Close
+	}
+	var baseCloses, quoteCloses, ratios []float64
+	for _, k := range base {
+		q, ok := quoteClose[k.OpenTime]
+		if !ok || q <= 0 || k.Close <= 0 {
+			continue
+		}
+		baseCloses = append(baseCloses, k.Close)
+		quoteCloses = append(quoteCloses, q)
+		ratios = append(ratios, k.Close/q)
+	}
+	if len(ratios) > lookback {
+		offset := len(ratios) - lookback
+		baseCloses, quoteCloses, ratios = baseCloses[offset:], quoteCloses[offset:], ratios[offset:]
+	}
+	if len(ratios) < 20 {
+		return nil, fmt.Errorf("对齐后的K线数量不足（%d根）", len(ratios))
+	}
+
+	spread := &Spread{Lookback: len(ratios), Ratio: ratios[len(ratios)-1]}
+	for _, r := range ratios {
+		spread.Mean += r
+	}
+	spread.Mean /= float64(len(ratios))
+	for _, r := range ratios {
+		spread.StdDev += (r - spread.Mean) * (r - spread.Mean)
+	}
+	spread.StdDev = math.Sqrt(spread.StdDev / float64(len(ratios)))
+	if spread.StdDev > 0 {
+		spread.ZScore = (spread.Ratio - spread.Mean) / spread.StdDev
+	}
+	spread.Correlation = returnCorrelation(baseCloses, quoteCloses)
+	spread.RatioSeries = ratios[max(0, len(ratios)-10):]
+	return spread, nil
+}
+
+// returnCorrelation 两组收盘价的收益率相关系数
+func returnCorrelation(a, b []float64) float64 {
+	n := len(a) - 1
+	if n < 2 {
+		return 0
+	}
+	var sumA, sumB float64
+	ra, rb := make([]float64, n), make([]float64, n)
+	for i := 0; i < n; i++ {
+		ra[i] = a[i+1]/a[i] - 1
+		rb[i] = b[i+1]/b[i] - 1
+		sumA += ra[i]
+		sumB += rb[i]
+	}
+	meanA, meanB := sumA/float64(n), sumB/float64(n)
+	var cov, varA, varB float64
+	for i := 0; i < n; i++ {
+		cov += (ra[i] - meanA) * (rb[i] - meanB)
+		varA += (ra[i] - meanA) * (ra[i] - meanA)
+		varB += (rb[i] - meanB) * (rb[i] - meanB)
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
+
+// FormatSpread 格式化价差数据（用于AI prompt）
+func FormatSpread(s *Spread) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("价格比 %.6f | 均值 %.6f | 标准差 %.6f | z-score %+.2f | 收益相关系数 %.2f（%s × %d）\n",
+		s.Ratio, s.Mean, s.StdDev, s.ZScore, s.Correlation, s.Interval, s.Lookback))
+	series := make([]string, len(s.RatioSeries))
+	for i, r := range s.RatioSeries {
+		series[i] = fmt.Sprintf("%.6f", r)
+	}
+	sb.WriteString(fmt.Sprintf("价格比序列（旧→新）: [%s]\n", strings.Join(series, ", ")))
+	return sb.String()
+}
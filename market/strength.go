@@ -0,0 +1,96 @@
+package market
+
+import (
+	"log"
+	"math"
+	"sort"
+)
+
+// StrengthWeights 综合强度评分各分项权重（按比例归一化，无需相加为1）
+type StrengthWeights struct {
+	Trend    float64 // 趋势：EMA20/EMA50排列 + 线性回归斜率
+	Momentum float64 // 动量：RSI14 + 10周期变化率
+	Volume   float64 // 成交量：当前成交量相对均量
+	OI       float64 // 持仓量：最新持仓量相对均值的变化
+}
+
+// strengthWeights 当前使用的权重
+var strengthWeights = StrengthWeights{Trend: 0.35, Momentum: 0.30, Volume: 0.15, OI: 0.20}
+
+// SetStrengthWeights 设置综合强度评分权重（全部为0时保留默认值）
+func SetStrengthWeights(weights StrengthWeights) {
+	if weights.Trend < 0 || weights.Momentum < 0 || weights.Volume < 0 || weights.OI < 0 {
+		log.Printf("⚠️  强度评分权重不能为负数，使用默认权重")
+		return
+	}
+	if weights.Trend+weights.Momentum+weights.Volume+weights.OI == 0 {
+		return
+	}
+	strengthWeights = weights
+	log.Printf("✓ 强度评分权重: 趋势%.2f 动量%.2f 成交量%.2f 持仓量%.2f",
+		weights.Trend, weights.Momentum, weights.Volume, weights.OI)
+}
+
+// calculateStrengthScore 计算0~100的综合强度评分（50为中性，越高越偏多头强势）
+// 缺少的分项（如现货数据源没有持仓量）不参与计算，其余分项按权重重新归一化
+func calculateStrengthScore(data *Data, weights StrengthWeights) float64 {
+	total, weightSum := 0.0, 0.0
+	add := func(score, weight float64, ok bool) {
+		if ok && weight > 0 {
+			total += clamp(score, 0, 100) * weight
+			weightSum += weight
+		}
+	}
+
+	if ltd := data.LongerTermContext; ltd != nil {
+		// 趋势：均线多空排列±25，回归斜率（按R²折算）±25
+		if ltd.Available("ema_fast") && ltd.Available("ema_slow") {
+			trend := 50.0
+			if ltd.EMA20 > ltd.EMA50 {
+				trend += 25
+			} else if ltd.EMA20 < ltd.EMA50 {
+				trend -= 25
+			}
+			if ltd.Regression != nil {
+				trend += 25 * clamp(ltd.Regression.SlopePct*ltd.Regression.R2/0.5, -1, 1)
+			}
+			add(trend, weights.Trend, true)
+		}
+
+		// 动量：RSI14与10周期变化率（每1%计5分）的平均
+		if len(ltd.RSI14Values) > 0 {
+			momentum := ltd.RSI14Values[len(ltd.RSI14Values)-1]
+			for _, series := range ltd.Momentum {
+				if series.Period == 10 && len(series.ROC) > 0 {
+					momentum = (momentum + 50 + series.ROC[len(series.ROC)-1]*5) / 2
+				}
+			}
+			add(momentum, weights.Momentum, true)
+		}
+
+		// 成交量：与均量持平为50分，放量一倍为100分
+		add(ltd.CurrentVolume/ltd.AverageVolume*50, weights.Volume, ltd.AverageVolume > 0)
+	}
+
+	// 持仓量：相对均值每增加1%计10分
+	if oi := data.OpenInterest; oi != nil && oi.Average > 0 {
+		add(50+(oi.Latest-oi.Average)/oi.Average*100*10, weights.OI, true)
+	}
+
+	if weightSum == 0 {
+		return 50
+	}
+	return total / weightSum
+}
+
+// SortByStrength 按综合强度评分降序排序（可用于筛选候选币种）
+func SortByStrength(list []*Data) {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].StrengthScore > list[j].StrengthScore
+	})
+}
+
+// clamp 将值限制在[lo, hi]区间
+func clamp(value, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, value))
+}
@@ -0,0 +1,96 @@
+package market
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// warmupRequirement 单个指标计算所需的最少已完成K线数（预热）
+type warmupRequirement struct {
+	indicator string // indicators.warmup 配置的键
+	label     string // 显示名称（含周期）
+	entry     bool   // 入场周期指标（否则为趋势周期）
+	bars      int
+}
+
+// warmupRequirements 各指标的预热需求：默认为能计算出数值的最少K线数（不足时指标函数返回0），
+// IndicatorConfig.Warmup 可为需要收敛的指标（如EMA以SMA为初值，通常需要数倍周期才稳定）设置更高的要求
+func (c IndicatorConfig) warmupRequirements() []warmupRequirement {
+	maxMomentum := 0
+	for _, period := range momentumPeriods {
+		maxMomentum = max(maxMomentum, period)
+	}
+	requirements := []warmupRequirement{
+		{"trend_ma", fmt.Sprintf("MA%d", c.TrendMA), false, c.TrendMA + 2}, // 趋势判断需要最近3个MA值
+		{"entry_ma", fmt.Sprintf("MA%d", c.EntryMA), true, c.EntryMA},
+		{"ema_fast", fmt.Sprintf("EMA%d", c.EMAFast), false, c.EMAFast},
+		{"ema_slow", fmt.Sprintf("EMA%d", c.EMASlow), false, c.EMASlow},
+		{"rsi", fmt.Sprintf("RSI%d", c.RSI), false, c.RSI + 1},
+		{"atr_fast", fmt.Sprintf("ATR%d", c.ATRFast), false, c.ATRFast + 1},
+		{"atr_slow", fmt.Sprintf("ATR%d", c.ATRSlow), false, c.ATRSlow + 1},
+		{"macd", fmt.Sprintf("MACD %d/%d", c.MACDFast, c.MACDSlow), false, c.MACDSlow},
+		{"keltner", "Keltner", false, max(channelPeriod, keltnerATRPeriod+1)},
+		{"donchian", fmt.Sprintf("Donchian(%d)", channelPeriod), false, channelPeriod},
+		{"bollinger", fmt.Sprintf("Bollinger(%d)", channelPeriod), false, channelPeriod},
+		{"williams_r", "Williams %R(14)", false, 14},
+		{"cci", "CCI(20)", false, 20},
+		{"mfi", "MFI(14)", false, 15},
+		{"cmf", "CMF(20)", false, 20},
+		{"momentum", fmt.Sprintf("ROC(%d)", maxMomentum), false, maxMomentum + 1},
+		{"regression", fmt.Sprintf("Linear Regression(%d)", regressionPeriod), false, regressionPeriod},
+		{"hurst", "Hurst", false, 33},                      // 至少32个对数收益
+		{"variance_ratio", "Variance Ratio(4)", false, 17}, // 至少4×4个对数收益
+	}
+	for i, r := range requirements {
+		if bars, ok := c.Warmup[r.indicator]; ok && bars > r.bars {
+			requirements[i].bars = bars
+		}
+	}
+	return requirements
+}
+
+// validateWarmup 校验预热配置：只能使用已知的指标名，且不能低于计算所需的最少K线数
+func (c IndicatorConfig) validateWarmup() error {
+	if len(c.Warmup) == 0 {
+		return nil
+	}
+	base := c
+	base.Warmup = nil
+	minimum := base.warmupRequirements()
+	known := make([]string, 0, len(minimum))
+	for _, r := range minimum {
+		known = append(known, r.indicator)
+	}
+	names := make([]string, 0, len(c.Warmup))
+	for name := range c.Warmup {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		i := slices.Index(known, name)
+		if i < 0 {
+			return fmt.Errorf("未知的预热指标%q（可用: %s）", name, strings.Join(known, ", "))
+		}
+		if c.Warmup[name] < minimum[i].bars {
+			return fmt.Errorf("%s预热K线数%d低于计算所需的%d根", name, c.Warmup[name], minimum[i].bars)
+		}
+	}
+	return nil
+}
+
+// warmupWarnings 可用K线不足预热需求的指标（这些指标的值为0或尚未收敛，不应当作真实数值使用）
+func (c IndicatorConfig) warmupWarnings(trendBars, entryBars int, trendInterval, entryInterval string) []string {
+	var warnings []string
+	for _, r := range c.warmupRequirements() {
+		available, interval := trendBars, trendInterval
+		if r.entry {
+			available, interval = entryBars, entryInterval
+		}
+		if available < r.bars {
+			warnings = append(warnings, fmt.Sprintf("%s需要%d根%s K线，仅有%d根", r.label, r.bars, interval, available))
+		}
+	}
+	return warnings
+}
@@ -4,11 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"nofx/run"
 	"nofx/utils"
 )
 
@@ -117,7 +117,7 @@ func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, er
 
 		// 重试前等待
 		if attempt < maxRetries {
-			waitTime := time.Duration(attempt) * 2 * time.Second
+			waitTime := run.Jitter("mcp.retry", time.Duration(attempt)*2*time.Second, 0.2) // 加入抖动，避免多个trader同时重试
 			fmt.Printf("⏳ 等待%v后重试...\n", waitTime)
 			time.Sleep(waitTime)
 		}
@@ -198,7 +198,7 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 	defer resp.Body.Close()
 
 	// 读取响应
-	body, err := io.ReadAll(resp.Body)
+	body, err := utils.ReadBody(resp.Body)
 	if err != nil {
 		return "", fmt.Errorf("读取响应失败: %w", err)
 	}
@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"nofx/events"
+	"nofx/market"
+)
+
+// AlertThresholds 阈值告警规则（0表示不检查该项）
+type AlertThresholds struct {
+	FundingRatePct       float64 // 资金费率绝对值超过该值（%/结算周期）时告警
+	DrawdownPct          float64 // trader净值回撤超过该值（%）时告警
+	WebSocketDownSeconds int     // 实时行情WebSocket超过该秒数无推送时告警
+}
+
+// AlertState 一条告警规则的当前状态
+type AlertState struct {
+	Rule      string    `json:"rule"` // funding_rate、drawdown、websocket_down
+	Key       string    `json:"key"`  // 规则作用对象（币种、trader ID或"hub"）
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Since     time.Time `json:"since"`
+}
+
+// alertCheckInterval WebSocket连接状态的检查间隔
+const alertCheckInterval = 5 * time.Second
+
+// AlertMonitor 阈值告警：根据行情快照事件和实时行情连接状态评估规则，
+// 规则触发和恢复时各发布一次告警事件（供通知、事件转发使用），不需要外部Prometheus/Alertmanager
+type AlertMonitor struct {
+	thresholds AlertThresholds
+
+	mu     sync.RWMutex
+	active map[string]*AlertState // rule/key -> 状态
+
+	events <-chan events.Event
+	stopCh chan struct{}
+}
+
+// NewAlertMonitor 创建阈值告警
+func NewAlertMonitor(thresholds AlertThresholds) *AlertMonitor {
+	return &AlertMonitor{
+		thresholds: thresholds,
+		active:     make(map[string]*AlertState),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Start 启动后台评估
+func (m *AlertMonitor) Start() {
+	log.Printf("🔔 阈值告警已启动（资金费率 %.4f%%，回撤 %.1f%%，WebSocket断开 %ds）",
+		m.thresholds.FundingRatePct, m.thresholds.DrawdownPct, m.thresholds.WebSocketDownSeconds)
+
+	m.events = events.Subscribe(events.TypeMarketSnapshot)
+	go func() {
+		ticker := time.NewTicker(alertCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case event := <-m.events:
+				m.checkSnapshot(event)
+			case <-ticker.C:
+				m.checkWebSocket()
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止评估
+func (m *AlertMonitor) Stop() {
+	events.Unsubscribe(m.events)
+	close(m.stopCh)
+}
+
+// Active 当前处于触发状态的告警（按触发时间排序）
+func (m *AlertMonitor) Active() []AlertState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	states := make([]AlertState, 0, len(m.active))
+	for _, state := range m.active {
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Since.Before(states[j].Since) })
+	return states
+}
+
+// checkSnapshot 评估行情快照中的资金费率和回撤
+func (m *AlertMonitor) checkSnapshot(event events.Event) {
+	if threshold := m.thresholds.DrawdownPct; threshold > 0 {
+		traderID, _ := event.Data["trader_id"].(string)
+		if drawdown, ok := event.Data["drawdown_pct"].(float64); ok {
+			m.evaluate("drawdown", traderID, drawdown, threshold,
+				fmt.Sprintf("[%s] 净值回撤 %.2f%% 超过阈值 %.2f%%", traderID, drawdown, threshold))
+		}
+	}
+
+	if threshold := m.thresholds.FundingRatePct; threshold > 0 {
+		symbols, _ := event.Data["symbols"].(map[string]interface{})
+		for symbol, item := range symbols {
+			snapshot, _ := item.(map[string]interface{})
+			rate, ok := snapshot["funding_rate"].(float64)
+			if !ok {
+				continue
+			}
+			ratePct := math.Abs(rate * 100)
+			m.evaluate("funding_rate", symbol, ratePct, threshold,
+				fmt.Sprintf("%s 资金费率 %+.4f%% 超过阈值 %.4f%%", symbol, rate*100, threshold))
+		}
+	}
+}
+
+// checkWebSocket 评估实时行情WebSocket连接状态
+func (m *AlertMonitor) checkWebSocket() {
+	threshold := m.thresholds.WebSocketDownSeconds
+	if threshold <= 0 || !market.Hub.Running() {
+		return
+	}
+	silence := market.Hub.Silence().Seconds()
+	m.evaluate("websocket_down", "hub", silence, float64(threshold),
+		fmt.Sprintf("实时行情WebSocket已 %.0f 秒无推送（阈值 %ds）", silence, threshold))
+}
+
+// evaluate 更新规则状态，超过阈值时告警（同一规则持续超过阈值只告警一次），回落后发布恢复事件
+func (m *AlertMonitor) evaluate(rule, key string, value, threshold float64, message string) {
+	id := rule + "/" + key
+
+	m.mu.Lock()
+	state, alerting := m.active[id]
+	breached := value >= threshold
+	switch {
+	case breached && alerting:
+		state.Value = value
+		m.mu.Unlock()
+		return
+	case breached:
+		m.active[id] = &AlertState{Rule: rule, Key: key, Value: value, Threshold: threshold, Since: time.Now()}
+	case alerting:
+		delete(m.active, id)
+	default:
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
+	data := map[string]interface{}{
+		"rule":      rule,
+		"key":       key,
+		"value":     value,
+		"threshold": threshold,
+		"resolved":  !breached,
+	}
+	if breached {
+		log.Printf("🔔 %s", message)
+		events.Publish(events.Event{
+			Type:     events.TypeThresholdAlert,
+			Severity: events.SeverityWarning,
+			Message:  message,
+			Data:     data,
+		})
+		return
+	}
+
+	message = fmt.Sprintf("告警已恢复: %s %s（当前 %.4f，阈值 %.4f）", rule, key, value, threshold)
+	log.Printf("✅ %s", message)
+	events.Publish(events.Event{
+		Type:     events.TypeThresholdAlert,
+		Severity: events.SeverityInfo,
+		Message:  message,
+		Data:     data,
+	})
+}
@@ -0,0 +1,276 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"nofx/events"
+	"nofx/market"
+)
+
+// ntpEpochOffset NTP时间（1900年起）与Unix时间（1970年起）相差的秒数
+const ntpEpochOffset = 2208988800
+
+// ClockDriftConfig 时钟偏差监控配置
+type ClockDriftConfig struct {
+	Interval    time.Duration // 检查间隔（默认1分钟）
+	MaxDrift    time.Duration // 本地时钟与交易所/NTP时间的最大偏差（默认5秒，即币安默认recvWindow）
+	NTPServer   string        // NTP服务器（如pool.ntp.org，空表示只与交易所服务器时间比较）
+	BlockSigned bool          // 偏差超过上限时暂停签名请求（避免连续-1021错误）
+}
+
+// ClockDriftStatus 时钟偏差监控状态
+type ClockDriftStatus struct {
+	ExchangeDriftMs *int64    `json:"exchange_drift_ms,omitempty"` // 本地时间 - 交易所服务器时间（毫秒，正数表示本地时钟偏快）
+	NTPDriftMs      *int64    `json:"ntp_drift_ms,omitempty"`      // 本地时间 - NTP时间（毫秒）
+	MaxDriftMs      int64     `json:"max_drift_ms"`
+	Breached        bool      `json:"breached"`
+	Blocking        bool      `json:"blocking"` // 是否正在暂停签名请求
+	LastError       string    `json:"last_error,omitempty"`
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+// ClockDriftMonitor 时钟偏差看门狗：定期比较本地时间与交易所服务器时间（可选NTP），
+// 偏差超过recvWindow时告警（签名请求会被交易所以-1021拒绝），可选暂停签名请求直到恢复
+type ClockDriftMonitor struct {
+	config ClockDriftConfig
+
+	mu     sync.RWMutex
+	status ClockDriftStatus
+
+	stopCh chan struct{}
+}
+
+// clockDriftMonitor 全局实例（未启动时为nil，签名请求不受限制）
+var (
+	clockDriftMonitor      *ClockDriftMonitor
+	clockDriftMonitorMutex sync.RWMutex
+)
+
+// NewClockDriftMonitor 创建时钟偏差监控
+func NewClockDriftMonitor(config ClockDriftConfig) *ClockDriftMonitor {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.MaxDrift <= 0 {
+		config.MaxDrift = 5 * time.Second
+	}
+	return &ClockDriftMonitor{
+		config: config,
+		status: ClockDriftStatus{MaxDriftMs: config.MaxDrift.Milliseconds()},
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start 启动后台检查，并设置为全局实例
+func (m *ClockDriftMonitor) Start() {
+	clockDriftMonitorMutex.Lock()
+	clockDriftMonitor = m
+	clockDriftMonitorMutex.Unlock()
+
+	ntp := m.config.NTPServer
+	if ntp == "" {
+		ntp = "未启用"
+	}
+	log.Printf("🕒 时钟偏差监控已启动（上限 %v，NTP %s，间隔%v，超限暂停签名请求: %v）",
+		m.config.MaxDrift, ntp, m.config.Interval, m.config.BlockSigned)
+
+	go func() {
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+
+		m.check()
+		for {
+			select {
+			case <-ticker.C:
+				m.check()
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止检查
+func (m *ClockDriftMonitor) Stop() {
+	close(m.stopCh)
+
+	clockDriftMonitorMutex.Lock()
+	if clockDriftMonitor == m {
+		clockDriftMonitor = nil
+	}
+	clockDriftMonitorMutex.Unlock()
+}
+
+// Status 最近一次检查的结果
+func (m *ClockDriftMonitor) Status() ClockDriftStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// check 测量本地时钟与交易所/NTP的偏差，超限和恢复时各发布一次告警事件
+func (m *ClockDriftMonitor) check() {
+	status := ClockDriftStatus{MaxDriftMs: m.config.MaxDrift.Milliseconds(), CheckedAt: time.Now()}
+	var errs []string
+	worst := time.Duration(0)
+	source := ""
+
+	if offset, err := market.BinanceServerOffset(); err != nil {
+		errs = append(errs, fmt.Sprintf("交易所: %v", err))
+	} else {
+		drift := -offset
+		ms := drift.Milliseconds()
+		status.ExchangeDriftMs = &ms
+		worst, source = drift, "交易所服务器"
+	}
+	if m.config.NTPServer != "" {
+		if offset, err := queryNTP(m.config.NTPServer, 5*time.Second); err != nil {
+			errs = append(errs, fmt.Sprintf("NTP %s: %v", m.config.NTPServer, err))
+		} else {
+			drift := -offset
+			ms := drift.Milliseconds()
+			status.NTPDriftMs = &ms
+			if absDuration(drift) > absDuration(worst) {
+				worst, source = drift, "NTP "+m.config.NTPServer
+			}
+		}
+	}
+	if len(errs) > 0 {
+		status.LastError = fmt.Sprint(errs)
+	}
+
+	// 两个时间源都无法获取时保持原状态（不因网络故障解除或触发告警）
+	m.mu.Lock()
+	previous := m.status
+	if status.ExchangeDriftMs == nil && status.NTPDriftMs == nil {
+		previous.LastError, previous.CheckedAt = status.LastError, status.CheckedAt
+		m.status = previous
+		m.mu.Unlock()
+		log.Printf("⚠️  时钟偏差检查失败: %s", status.LastError)
+		return
+	}
+	status.Breached = absDuration(worst) > m.config.MaxDrift
+	status.Blocking = status.Breached && m.config.BlockSigned
+	m.status = status
+	m.mu.Unlock()
+
+	data := map[string]interface{}{
+		"rule":      "clock_drift",
+		"key":       source,
+		"value":     float64(worst.Milliseconds()),
+		"threshold": float64(m.config.MaxDrift.Milliseconds()),
+		"resolved":  !status.Breached,
+	}
+	switch {
+	case status.Breached && !previous.Breached:
+		message := fmt.Sprintf("本地时钟与%s偏差 %dms，超过上限 %dms（签名请求将被拒绝: -1021）",
+			source, worst.Milliseconds(), m.config.MaxDrift.Milliseconds())
+		if m.config.BlockSigned {
+			message += "，已暂停签名请求"
+		}
+		log.Printf("🕒 %s", message)
+		events.Publish(events.Event{
+			Type:     events.TypeThresholdAlert,
+			Severity: events.SeverityCritical,
+			Message:  message,
+			Data:     data,
+		})
+	case !status.Breached && previous.Breached:
+		message := fmt.Sprintf("时钟偏差已恢复: 与%s偏差 %dms（上限 %dms）", source, worst.Milliseconds(), m.config.MaxDrift.Milliseconds())
+		log.Printf("✅ %s", message)
+		events.Publish(events.Event{
+			Type:     events.TypeThresholdAlert,
+			Severity: events.SeverityInfo,
+			Message:  message,
+			Data:     data,
+		})
+	}
+}
+
+// SignedRequestsAllowed 是否允许发送签名请求（监控未启动或未开启暂停时始终返回true）
+func SignedRequestsAllowed() (bool, string) {
+	clockDriftMonitorMutex.RLock()
+	m := clockDriftMonitor
+	clockDriftMonitorMutex.RUnlock()
+	if m == nil {
+		return true, ""
+	}
+
+	status := m.Status()
+	if !status.Blocking {
+		return true, ""
+	}
+	drift := status.ExchangeDriftMs
+	if drift == nil || (status.NTPDriftMs != nil && abs64(*status.NTPDriftMs) > abs64(*drift)) {
+		drift = status.NTPDriftMs
+	}
+	return false, fmt.Sprintf("本地时钟偏差 %dms 超过上限 %dms", *drift, status.MaxDriftMs)
+}
+
+// ClockDrift 全局时钟偏差监控的状态（监控未启动时返回nil）
+func ClockDrift() *ClockDriftStatus {
+	clockDriftMonitorMutex.RLock()
+	m := clockDriftMonitor
+	clockDriftMonitorMutex.RUnlock()
+	if m == nil {
+		return nil
+	}
+	status := m.Status()
+	return &status
+}
+
+// queryNTP 通过SNTP查询服务器时间，返回 NTP时间 - 本地时间
+func queryNTP(server string, timeout time.Duration) (time.Duration, error) {
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(server, "123"), timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	request := make([]byte, 48)
+	request[0] = 0x1B // LI=0, VN=3, Mode=3（客户端）
+	sent := time.Now()
+	if _, err := conn.Write(request); err != nil {
+		return 0, err
+	}
+	response := make([]byte, 48)
+	if _, err := conn.Read(response); err != nil {
+		return 0, err
+	}
+	received := time.Now()
+	if response[1] == 0 {
+		return 0, fmt.Errorf("NTP服务器拒绝请求（stratum 0）")
+	}
+
+	// 时钟偏差 = ((T2 - T1) + (T3 - T4)) / 2
+	serverReceive := ntpTimestamp(response[32:40])
+	serverTransmit := ntpTimestamp(response[40:48])
+	return (serverReceive.Sub(sent) + serverTransmit.Sub(received)) / 2, nil
+}
+
+// ntpTimestamp 解析64位NTP时间戳（32位秒 + 32位小数）
+func ntpTimestamp(b []byte) time.Time {
+	seconds := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+	fraction := int64(binary.BigEndian.Uint32(b[4:8]))
+	return time.Unix(seconds, fraction*1e9>>32)
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,259 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"nofx/events"
+	"nofx/risk"
+	"nofx/utils"
+)
+
+// DepegConfig 稳定币脱锚监控配置
+type DepegConfig struct {
+	ThresholdPct  float64       // 偏离$1的告警阈值（%）
+	BasisPct      float64       // USDT计价与USD计价BTC价差的告警阈值（%）
+	Interval      time.Duration // 检查间隔
+	TripBreaker   bool          // 触发时是否熔断
+	PauseDuration time.Duration // 熔断时长
+}
+
+// StablecoinQuote 稳定币报价
+type StablecoinQuote struct {
+	Symbol    string    `json:"symbol"`
+	PriceUSD  float64   `json:"price_usd"`
+	Deviation float64   `json:"deviation_pct"` // 偏离$1的百分比
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DepegMonitor 稳定币脱锚监控
+// 监控USDT/USDC相对美元的偏离，以及USDT计价与USD计价BTC的价差（基差爆裂）
+type DepegMonitor struct {
+	config DepegConfig
+	client *http.Client
+
+	mu       sync.RWMutex
+	quotes   map[string]StablecoinQuote
+	basisPct float64
+	alerting bool // 当前是否处于告警状态（避免重复告警）
+
+	stopCh chan struct{}
+}
+
+// NewDepegMonitor 创建稳定币脱锚监控
+func NewDepegMonitor(config DepegConfig) *DepegMonitor {
+	if config.ThresholdPct <= 0 {
+		config.ThresholdPct = 0.5
+	}
+	if config.BasisPct <= 0 {
+		config.BasisPct = 1.0
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.PauseDuration <= 0 {
+		config.PauseDuration = time.Hour
+	}
+
+	return &DepegMonitor{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		quotes: make(map[string]StablecoinQuote),
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start 启动后台监控
+func (m *DepegMonitor) Start() {
+	log.Printf("🛡️  稳定币脱锚监控已启动（阈值%.2f%%，基差阈值%.2f%%，间隔%v，熔断: %v）",
+		m.config.ThresholdPct, m.config.BasisPct, m.config.Interval, m.config.TripBreaker)
+
+	go func() {
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+
+		m.check()
+		for {
+			select {
+			case <-ticker.C:
+				m.check()
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止监控
+func (m *DepegMonitor) Stop() {
+	close(m.stopCh)
+}
+
+// check 执行一次检查
+func (m *DepegMonitor) check() {
+	var alerts []string
+
+	for _, pair := range []struct{ symbol, krakenPair string }{
+		{"USDT", "USDTZUSD"},
+		{"USDC", "USDCUSD"},
+	} {
+		price, err := m.krakenPrice(pair.krakenPair)
+		if err != nil {
+			log.Printf("⚠️  获取%s/USD价格失败: %v", pair.symbol, err)
+			continue
+		}
+
+		deviation := (price - 1) * 100
+		m.mu.Lock()
+		m.quotes[pair.symbol] = StablecoinQuote{
+			Symbol:    pair.symbol,
+			PriceUSD:  price,
+			Deviation: deviation,
+			UpdatedAt: time.Now(),
+		}
+		m.mu.Unlock()
+
+		if math.Abs(deviation) >= m.config.ThresholdPct {
+			alerts = append(alerts, fmt.Sprintf("%s=$%.4f（偏离%+.2f%%）", pair.symbol, price, deviation))
+		}
+	}
+
+	// 基差检查：币安BTC/USDT现货 vs Coinbase BTC/USD
+	if basis, err := m.usdtBasis(); err == nil {
+		m.mu.Lock()
+		m.basisPct = basis
+		m.mu.Unlock()
+
+		if math.Abs(basis) >= m.config.BasisPct {
+			alerts = append(alerts, fmt.Sprintf("BTC USDT/USD基差%+.2f%%", basis))
+		}
+	} else {
+		log.Printf("⚠️  计算USDT基差失败: %v", err)
+	}
+
+	m.mu.Lock()
+	wasAlerting := m.alerting
+	m.alerting = len(alerts) > 0
+	m.mu.Unlock()
+
+	if len(alerts) == 0 {
+		if wasAlerting {
+			log.Printf("✅ 稳定币价格已恢复正常")
+		}
+		return
+	}
+
+	message := fmt.Sprintf("稳定币脱锚告警: %v", alerts)
+	if !wasAlerting {
+		log.Printf("🚨 %s", message)
+		events.Publish(events.Event{
+			Type:     events.TypeDepegAlert,
+			Severity: events.SeverityCritical,
+			Message:  message,
+			Data:     map[string]interface{}{"alerts": alerts},
+		})
+	}
+
+	// 脱锚持续期间每次检查都维持熔断（持续时间超过pause_minutes时不会到期恢复交易）
+	if m.config.TripBreaker {
+		risk.Breaker.Hold("depeg", message, m.config.PauseDuration)
+	}
+}
+
+// krakenPrice 获取Kraken交易对最新价格
+func (m *DepegMonitor) krakenPrice(pair string) (float64, error) {
+	body, err := m.get(fmt.Sprintf("https://api.kraken.com/0/public/Ticker?pair=%s", pair))
+	if err != nil {
+		return 0, err
+	}
+
+	var response struct {
+		Error  []string `json:"error"`
+		Result map[string]struct {
+			C []string `json:"c"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, err
+	}
+	if len(response.Error) > 0 {
+		return 0, fmt.Errorf("Kraken API错误: %v", response.Error)
+	}
+	for _, ticker := range response.Result {
+		if len(ticker.C) > 0 {
+			return strconv.ParseFloat(ticker.C[0], 64)
+		}
+	}
+	return 0, fmt.Errorf("Kraken返回为空")
+}
+
+// usdtBasis 计算USDT计价BTC相对USD计价BTC的价差（%）
+// 正值表示USDT折价（同样的BTC需要更多USDT）
+func (m *DepegMonitor) usdtBasis() (float64, error) {
+	body, err := m.get("https://api.binance.com/api/v3/ticker/price?symbol=BTCUSDT")
+	if err != nil {
+		return 0, err
+	}
+	var binance struct {
+		Price string `json:"price"`
+	}
+	if err := json.Unmarshal(body, &binance); err != nil {
+		return 0, err
+	}
+	usdtPrice, _ := strconv.ParseFloat(binance.Price, 64)
+
+	body, err = m.get("https://api.exchange.coinbase.com/products/BTC-USD/ticker")
+	if err != nil {
+		return 0, err
+	}
+	var coinbase struct {
+		Price string `json:"price"`
+	}
+	if err := json.Unmarshal(body, &coinbase); err != nil {
+		return 0, err
+	}
+	usdPrice, _ := strconv.ParseFloat(coinbase.Price, 64)
+
+	if usdtPrice <= 0 || usdPrice <= 0 {
+		return 0, fmt.Errorf("价格无效")
+	}
+	return (usdtPrice/usdPrice - 1) * 100, nil
+}
+
+// get 发送GET请求
+func (m *DepegMonitor) get(url string) ([]byte, error) {
+	resp, err := m.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return utils.ReadBody(resp.Body)
+}
+
+// Status 监控状态（供API展示）
+func (m *DepegMonitor) Status() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	quotes := make([]StablecoinQuote, 0, len(m.quotes))
+	for _, symbol := range []string{"USDT", "USDC"} {
+		if quote, ok := m.quotes[symbol]; ok {
+			quotes = append(quotes, quote)
+		}
+	}
+
+	return map[string]interface{}{
+		"quotes":        quotes,
+		"btc_basis_pct": m.basisPct,
+		"alerting":      m.alerting,
+		"threshold_pct": m.config.ThresholdPct,
+		"basis_pct":     m.config.BasisPct,
+		"trips_breaker": m.config.TripBreaker,
+	}
+}
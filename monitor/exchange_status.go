@@ -0,0 +1,364 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"nofx/events"
+	"nofx/market"
+	"nofx/utils"
+)
+
+// 交易所状态
+const (
+	StatusNormal      = "normal"
+	StatusMaintenance = "maintenance" // 系统维护（暂停交易）
+	StatusUnreachable = "unreachable" // 连续请求失败（暂停交易）
+	StatusUnknown     = "unknown"     // 尚未检查
+)
+
+// unreachableThreshold 连续失败多少次视为不可用
+const unreachableThreshold = 3
+
+// ExchangeStatus 单个交易所的状态
+type ExchangeStatus struct {
+	Exchange         string    `json:"exchange"`
+	Status           string    `json:"status"`
+	Message          string    `json:"message,omitempty"`
+	MaintenanceStart time.Time `json:"maintenance_start,omitempty"` // 计划维护开始时间
+	MaintenanceEnd   time.Time `json:"maintenance_end,omitempty"`   // 计划维护结束时间
+	CheckedAt        time.Time `json:"checked_at"`
+	failures         int
+}
+
+// Paused 该状态下是否应暂停交易
+func (s ExchangeStatus) Paused() bool {
+	return s.Status == StatusMaintenance || s.Status == StatusUnreachable
+}
+
+// ExchangeStatusMonitor 交易所状态与维护窗口监控
+type ExchangeStatusMonitor struct {
+	interval time.Duration
+	client   *http.Client
+
+	mu       sync.RWMutex
+	statuses map[string]*ExchangeStatus
+
+	stopCh chan struct{}
+}
+
+// exchangeStatusMonitor 全局实例（未启动时为nil，视为所有交易所可用）
+var (
+	exchangeStatusMonitor      *ExchangeStatusMonitor
+	exchangeStatusMonitorMutex sync.RWMutex
+)
+
+// NewExchangeStatusMonitor 创建交易所状态监控
+// exchanges: 需要监控的交易所（"binance", "binance_coinm", "aster", "hyperliquid", "coinbase", "kraken"）
+func NewExchangeStatusMonitor(exchanges []string, interval time.Duration) *ExchangeStatusMonitor {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	statuses := make(map[string]*ExchangeStatus)
+	for _, exchange := range exchanges {
+		statuses[exchange] = &ExchangeStatus{Exchange: exchange, Status: StatusUnknown}
+	}
+
+	return &ExchangeStatusMonitor{
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		statuses: statuses,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start 启动后台监控，并设置为全局实例
+func (m *ExchangeStatusMonitor) Start() {
+	exchangeStatusMonitorMutex.Lock()
+	exchangeStatusMonitor = m
+	exchangeStatusMonitorMutex.Unlock()
+
+	log.Printf("🛰️  交易所状态监控已启动（%d个交易所，间隔%v）", len(m.statuses), m.interval)
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		m.checkAll()
+		for {
+			select {
+			case <-ticker.C:
+				m.checkAll()
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止监控
+func (m *ExchangeStatusMonitor) Stop() {
+	close(m.stopCh)
+
+	exchangeStatusMonitorMutex.Lock()
+	if exchangeStatusMonitor == m {
+		exchangeStatusMonitor = nil
+	}
+	exchangeStatusMonitorMutex.Unlock()
+}
+
+// checkAll 检查所有交易所
+func (m *ExchangeStatusMonitor) checkAll() {
+	m.mu.RLock()
+	exchanges := make([]string, 0, len(m.statuses))
+	for exchange := range m.statuses {
+		exchanges = append(exchanges, exchange)
+	}
+	m.mu.RUnlock()
+
+	for _, exchange := range exchanges {
+		status, message, window, err := m.check(exchange)
+
+		m.mu.Lock()
+		current := m.statuses[exchange]
+		previous := current.Status
+		current.CheckedAt = time.Now()
+		if err != nil {
+			current.failures++
+			if current.failures >= unreachableThreshold {
+				current.Status = StatusUnreachable
+				current.Message = err.Error()
+			}
+		} else {
+			current.failures = 0
+			current.Status = status
+			current.Message = message
+			current.MaintenanceStart = window[0]
+			current.MaintenanceEnd = window[1]
+		}
+		changed := current.Status != previous
+		snapshot := *current
+		m.mu.Unlock()
+
+		// 首次检查只在异常时发布，之后每次状态变化都发布
+		if changed && (previous != StatusUnknown || snapshot.Paused()) {
+			m.publishChange(snapshot)
+		}
+	}
+}
+
+// publishChange 发布状态变化事件
+func (m *ExchangeStatusMonitor) publishChange(status ExchangeStatus) {
+	severity := events.SeverityInfo
+	icon := "✅"
+	if status.Paused() {
+		severity = events.SeverityWarning
+		icon = "🚧"
+	}
+
+	message := fmt.Sprintf("%s 状态: %s", status.Exchange, status.Status)
+	if status.Message != "" {
+		message += " (" + status.Message + ")"
+	}
+	log.Printf("%s %s", icon, message)
+
+	events.Publish(events.Event{
+		Type:     events.TypeExchangeStatus,
+		Severity: severity,
+		Message:  message,
+		Data: map[string]interface{}{
+			"exchange": status.Exchange,
+			"status":   status.Status,
+		},
+	})
+}
+
+// check 检查单个交易所，返回状态、说明和当前/即将到来的维护窗口
+func (m *ExchangeStatusMonitor) check(exchange string) (string, string, [2]time.Time, error) {
+	var window [2]time.Time
+
+	switch exchange {
+	case "binance", "binance_coinm":
+		// 币安系统状态: 0正常 1维护
+		var result struct {
+			Status int    `json:"status"`
+			Msg    string `json:"msg"`
+		}
+		if err := m.getJSON("https://api.binance.com/sapi/v1/system/status", &result); err != nil {
+			return "", "", window, err
+		}
+		// 维护期间合约接口通常也不可用，先判断系统状态，避免维护被记为连续请求失败
+		if result.Status == 1 {
+			return StatusMaintenance, result.Msg, window, nil
+		}
+		pingURL := market.BinanceFutures.URL() + "/fapi/v1/ping"
+		if exchange == "binance_coinm" {
+			pingURL = "https://dapi.binance.com/dapi/v1/ping"
+		}
+		if err := m.getJSON(pingURL, &struct{}{}); err != nil {
+			return "", "", window, err
+		}
+		return StatusNormal, "", window, nil
+
+	case "aster":
+		if err := m.getJSON("https://fapi.asterdex.com/fapi/v1/ping", &struct{}{}); err != nil {
+			return "", "", window, err
+		}
+		return StatusNormal, "", window, nil
+
+	case "hyperliquid":
+		payload := bytes.NewReader([]byte(`{"type":"allMids"}`))
+		resp, err := m.client.Post("https://api.hyperliquid.xyz/info", "application/json", payload)
+		if err != nil {
+			return "", "", window, err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", "", window, fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		return StatusNormal, "", window, nil
+
+	case "kraken":
+		// Kraken系统状态: online, maintenance, cancel_only, post_only
+		var result struct {
+			Error  []string `json:"error"`
+			Result struct {
+				Status string `json:"status"`
+			} `json:"result"`
+		}
+		if err := m.getJSON("https://api.kraken.com/0/public/SystemStatus", &result); err != nil {
+			return "", "", window, err
+		}
+		status, message := StatusNormal, ""
+		if result.Result.Status != "online" {
+			status, message = StatusMaintenance, result.Result.Status
+		}
+		if scheduled, ok := m.scheduledMaintenance("https://status.kraken.com"); ok {
+			window = scheduled
+			if inWindow(window) {
+				status, message = StatusMaintenance, "计划维护中"
+			}
+		}
+		return status, message, window, nil
+
+	case "coinbase":
+		if err := m.getJSON("https://api.exchange.coinbase.com/time", &struct{}{}); err != nil {
+			return "", "", window, err
+		}
+		status, message := StatusNormal, ""
+		if scheduled, ok := m.scheduledMaintenance("https://status.exchange.coinbase.com"); ok {
+			window = scheduled
+			if inWindow(window) {
+				status, message = StatusMaintenance, "计划维护中"
+			}
+		}
+		return status, message, window, nil
+
+	default:
+		return StatusNormal, "未支持状态检查", window, nil
+	}
+}
+
+// scheduledMaintenance 从Statuspage获取最近的计划维护窗口
+func (m *ExchangeStatusMonitor) scheduledMaintenance(statusPageURL string) ([2]time.Time, bool) {
+	var result struct {
+		ScheduledMaintenances []struct {
+			Name           string    `json:"name"`
+			Status         string    `json:"status"`
+			ScheduledFor   time.Time `json:"scheduled_for"`
+			ScheduledUntil time.Time `json:"scheduled_until"`
+		} `json:"scheduled_maintenances"`
+	}
+	if err := m.getJSON(statusPageURL+"/api/v2/scheduled-maintenances/active.json", &result); err != nil || len(result.ScheduledMaintenances) == 0 {
+		if err := m.getJSON(statusPageURL+"/api/v2/scheduled-maintenances/upcoming.json", &result); err != nil {
+			return [2]time.Time{}, false
+		}
+	}
+	if len(result.ScheduledMaintenances) == 0 {
+		return [2]time.Time{}, false
+	}
+
+	sort.Slice(result.ScheduledMaintenances, func(i, j int) bool {
+		return result.ScheduledMaintenances[i].ScheduledFor.Before(result.ScheduledMaintenances[j].ScheduledFor)
+	})
+	next := result.ScheduledMaintenances[0]
+	return [2]time.Time{next.ScheduledFor, next.ScheduledUntil}, true
+}
+
+// inWindow 当前时间是否在维护窗口内
+func inWindow(window [2]time.Time) bool {
+	now := time.Now()
+	return !window[0].IsZero() && !now.Before(window[0]) && (window[1].IsZero() || now.Before(window[1]))
+}
+
+// getJSON 发送GET请求并解析JSON
+func (m *ExchangeStatusMonitor) getJSON(url string, result interface{}) error {
+	resp, err := m.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := utils.ReadBody(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, result)
+}
+
+// Statuses 获取所有交易所状态（按名称排序）
+func (m *ExchangeStatusMonitor) Statuses() []ExchangeStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]ExchangeStatus, 0, len(m.statuses))
+	for _, status := range m.statuses {
+		result = append(result, *status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Exchange < result[j].Exchange })
+	return result
+}
+
+// ExchangeAvailable 交易所当前是否可以交易（监控未启动时始终返回true）
+func ExchangeAvailable(exchange string) (bool, string) {
+	exchangeStatusMonitorMutex.RLock()
+	m := exchangeStatusMonitor
+	exchangeStatusMonitorMutex.RUnlock()
+	if m == nil {
+		return true, ""
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status, ok := m.statuses[exchange]
+	if !ok || !status.Paused() {
+		return true, ""
+	}
+	reason := status.Status
+	if status.Message != "" {
+		reason += ": " + status.Message
+	}
+	return false, reason
+}
+
+// ExchangeStatuses 获取全局监控的交易所状态（监控未启动时返回nil）
+func ExchangeStatuses() []ExchangeStatus {
+	exchangeStatusMonitorMutex.RLock()
+	m := exchangeStatusMonitor
+	exchangeStatusMonitorMutex.RUnlock()
+	if m == nil {
+		return nil
+	}
+	return m.Statuses()
+}
@@ -0,0 +1,122 @@
+package risk
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"nofx/events"
+)
+
+// CircuitBreaker 全局熔断器
+// 触发后所有trader暂停开仓决策，直到到期或手动解除
+type CircuitBreaker struct {
+	mu        sync.RWMutex
+	reason    string
+	source    string
+	trippedAt time.Time
+	until     time.Time
+}
+
+// Breaker 全局熔断器实例
+var Breaker = &CircuitBreaker{}
+
+// Trip 触发熔断（duration<=0表示直到手动解除）
+func (b *CircuitBreaker) Trip(source, reason string, duration time.Duration) {
+	b.mu.Lock()
+	b.source = source
+	b.reason = reason
+	b.trippedAt = time.Now()
+	if duration > 0 {
+		b.until = b.trippedAt.Add(duration)
+	} else {
+		b.until = time.Time{}
+	}
+	b.mu.Unlock()
+
+	log.Printf("🚨 熔断触发 [%s]: %s", source, reason)
+	events.Publish(events.Event{
+		Type:     events.TypeBreakerTripped,
+		Severity: events.SeverityCritical,
+		Message:  fmt.Sprintf("熔断触发 [%s]: %s", source, reason),
+		Data: map[string]interface{}{
+			"source":   source,
+			"reason":   reason,
+			"duration": duration.String(),
+		},
+	})
+}
+
+// Hold 条件持续期间维持熔断：同一来源的熔断生效中时只延长到期时间（不重复告警），
+// 已到期或已被手动解除时重新触发
+func (b *CircuitBreaker) Hold(source, reason string, duration time.Duration) {
+	b.mu.Lock()
+	now := time.Now()
+	active := !b.trippedAt.IsZero() && (b.until.IsZero() || now.Before(b.until))
+	if active && b.source == source {
+		if duration > 0 && !b.until.IsZero() && now.Add(duration).After(b.until) {
+			b.until = now.Add(duration)
+		}
+		b.reason = reason
+		b.mu.Unlock()
+		return
+	}
+	b.mu.Unlock()
+	b.Trip(source, reason, duration)
+}
+
+// Reset 手动解除熔断
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	wasTripped := !b.trippedAt.IsZero()
+	b.reason = ""
+	b.source = ""
+	b.trippedAt = time.Time{}
+	b.until = time.Time{}
+	b.mu.Unlock()
+
+	if wasTripped {
+		log.Printf("✅ 熔断已解除")
+		events.Publish(events.Event{
+			Type:     events.TypeBreakerReset,
+			Severity: events.SeverityInfo,
+			Message:  "熔断已解除",
+		})
+	}
+}
+
+// Tripped 熔断是否生效中，返回触发原因
+func (b *CircuitBreaker) Tripped() (bool, string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.trippedAt.IsZero() {
+		return false, ""
+	}
+	if !b.until.IsZero() && time.Now().After(b.until) {
+		return false, ""
+	}
+	return true, fmt.Sprintf("[%s] %s", b.source, b.reason)
+}
+
+// Status 熔断状态（供API展示）
+func (b *CircuitBreaker) Status() map[string]interface{} {
+	tripped, _ := b.Tripped()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	status := map[string]interface{}{
+		"tripped": tripped,
+	}
+	if tripped {
+		status["source"] = b.source
+		status["reason"] = b.reason
+		status["tripped_at"] = b.trippedAt.Format(time.RFC3339)
+		if !b.until.IsZero() {
+			status["until"] = b.until.Format(time.RFC3339)
+		}
+	}
+	return status
+}
@@ -0,0 +1,101 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TradingHours 交易时段过滤：在指定时区的禁止时段和禁止日期内不开新仓（平仓不受影响）
+type TradingHours struct {
+	location *time.Location
+	windows  []hoursWindow
+	days     map[time.Weekday]bool
+}
+
+// hoursWindow 每天的禁止开仓时段（分钟，from > to 表示跨午夜，如22:00-02:00）
+type hoursWindow struct {
+	from, to int
+	label    string
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// NewTradingHours 创建交易时段过滤
+// timezone为IANA时区名（空表示UTC），windows格式为"HH:MM-HH:MM"，days为星期缩写（mon..sun）
+func NewTradingHours(timezone string, windows, days []string) (*TradingHours, error) {
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("无效的时区 %s: %w", timezone, err)
+		}
+	}
+	h := &TradingHours{location: location, days: make(map[time.Weekday]bool)}
+	for _, window := range windows {
+		from, to, ok := strings.Cut(strings.TrimSpace(window), "-")
+		if !ok {
+			return nil, fmt.Errorf("无效的时段 %s（格式为HH:MM-HH:MM）", window)
+		}
+		fromMinute, err := parseClock(from)
+		if err != nil {
+			return nil, fmt.Errorf("无效的时段 %s: %w", window, err)
+		}
+		toMinute, err := parseClock(to)
+		if err != nil {
+			return nil, fmt.Errorf("无效的时段 %s: %w", window, err)
+		}
+		if fromMinute == toMinute {
+			return nil, fmt.Errorf("无效的时段 %s（开始和结束时间相同）", window)
+		}
+		h.windows = append(h.windows, hoursWindow{from: fromMinute, to: toMinute, label: strings.TrimSpace(window)})
+	}
+	for _, day := range days {
+		name := strings.ToLower(strings.TrimSpace(day))
+		if len(name) > 3 {
+			name = name[:3] // 允许完整名称（monday）
+		}
+		weekday, ok := weekdayNames[name]
+		if !ok {
+			return nil, fmt.Errorf("无效的星期 %s（可选 mon、tue、wed、thu、fri、sat、sun）", day)
+		}
+		h.days[weekday] = true
+	}
+	return h, nil
+}
+
+// parseClock 解析"HH:MM"为当天的分钟数
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("时间 %s 格式应为HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Blocked 指定时刻是否禁止开新仓，返回原因
+func (h *TradingHours) Blocked(t time.Time) (bool, string) {
+	local := t.In(h.location)
+	if h.days[local.Weekday()] {
+		return true, fmt.Sprintf("%s 全天不开新仓（%s）", local.Weekday(), h.location)
+	}
+	minute := local.Hour()*60 + local.Minute()
+	for _, w := range h.windows {
+		inside := minute >= w.from && minute < w.to
+		if w.from > w.to {
+			inside = minute >= w.from || minute < w.to
+		}
+		if inside {
+			return true, fmt.Sprintf("%s 不开新仓（%s）", w.label, h.location)
+		}
+	}
+	return false, ""
+}
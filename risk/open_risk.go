@@ -0,0 +1,82 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// PositionRisk 单个持仓的开放风险：按当前价格计算止损触发时的亏损
+type PositionRisk struct {
+	Symbol      string  `json:"symbol"`
+	Side        string  `json:"side"` // "long" 或 "short"
+	Quantity    float64 `json:"quantity"`
+	Price       float64 `json:"price"`
+	StopLoss    float64 `json:"stop_loss"`
+	RiskUSD     float64 `json:"risk_usd"`              // 止损距离×数量（止损已越过当前价格方向锁定利润时为0）
+	Unprotected bool    `json:"unprotected,omitempty"` // 没有止损，按全部名义价值计入
+	Unknown     bool    `json:"unknown,omitempty"`     // 价格未知，风险无法计算（此时禁止新开仓）
+}
+
+// NewPositionRisk 计算持仓的开放风险（没有止损时按全部名义价值计算，价格未知时标记为未知）
+func NewPositionRisk(symbol, side string, quantity, price, stopLoss float64) PositionRisk {
+	p := PositionRisk{Symbol: symbol, Side: side, Quantity: quantity, Price: price, StopLoss: stopLoss}
+	if price <= 0 {
+		p.Unknown = true
+		return p
+	}
+	if stopLoss <= 0 {
+		p.Unprotected = true
+		p.RiskUSD = quantity * price
+		return p
+	}
+	distance := price - stopLoss
+	if side == "short" {
+		distance = -distance
+	}
+	p.RiskUSD = math.Max(distance, 0) * quantity
+	return p
+}
+
+// OpenRisk 组合开放风险：全部持仓的止损距离×数量之和，及其占净值的比例
+type OpenRisk struct {
+	TotalUSD  float64        `json:"total_usd"`
+	Equity    float64        `json:"equity"`
+	Pct       float64        `json:"pct"`        // 占净值%
+	BudgetPct float64        `json:"budget_pct"` // 预算（占净值%，0表示不限制）
+	Positions []PositionRisk `json:"positions"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// NewOpenRisk 汇总各持仓的开放风险
+func NewOpenRisk(positions []PositionRisk, equity, budgetPct float64, now time.Time) OpenRisk {
+	r := OpenRisk{Equity: equity, BudgetPct: budgetPct, Positions: positions, UpdatedAt: now}
+	for _, p := range positions {
+		r.TotalUSD += p.RiskUSD
+	}
+	if equity > 0 {
+		r.Pct = r.TotalUSD / equity * 100
+	}
+	return r
+}
+
+// Check 新开仓增加added（USDT）的风险后是否超过预算：超过时拒绝开仓（不增加风险的开仓不受限制）
+// 有持仓价格未知时开放风险无法计算，同样拒绝开仓
+func (r OpenRisk) Check(added float64) error {
+	if r.BudgetPct <= 0 || added <= 0 {
+		return nil
+	}
+	if r.Equity <= 0 {
+		return fmt.Errorf("净值未知，无法检查开放风险预算")
+	}
+	for _, p := range r.Positions {
+		if p.Unknown {
+			return fmt.Errorf("%s %s 价格未知，无法计算开放风险", p.Symbol, p.Side)
+		}
+	}
+	budget := r.Equity * r.BudgetPct / 100
+	if total := r.TotalUSD + added; total > budget*(1+riskTolerance) {
+		return fmt.Errorf("开放风险 %.2f + %.2f USDT 超过预算 %.2f USDT（净值的%g%%）", r.TotalUSD, added, budget, r.BudgetPct)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package risk
+
+import "fmt"
+
+// riskTolerance 比较加仓前后风险时允许的相对误差（价格、数量取整）
+const riskTolerance = 1e-6
+
+// PyramidCaps 金字塔加仓的硬性上限：无论加仓规则如何配置，超过上限的加仓一律拒绝
+type PyramidCaps struct {
+	MaxAdds         int     // 单个持仓最多加仓次数
+	MaxSizeMultiple float64 // 加仓后的总数量不超过初始数量的倍数
+}
+
+// PyramidAdd 一次加仓的检查参数
+type PyramidAdd struct {
+	Adds            int     // 已加仓次数
+	InitialQuantity float64 // 初始开仓数量
+	Quantity        float64 // 当前持仓数量
+	AddQuantity     float64 // 本次加仓数量
+	InitialRisk     float64 // 初始风险：初始数量×开仓价到初始止损的距离（USDT）
+	Risk            float64 // 加仓并收紧止损后的风险：总数量×新均价到新止损的距离（≤0表示已锁定利润）
+}
+
+// Check 加仓前的硬性检查：加仓次数、总数量倍数已达上限，或加仓后止损触发的亏损超过初始风险时拒绝
+func (c PyramidCaps) Check(add PyramidAdd) error {
+	if add.Adds >= c.MaxAdds {
+		return fmt.Errorf("已加仓%d次，达到上限%d次", add.Adds, c.MaxAdds)
+	}
+	if add.InitialQuantity <= 0 || add.InitialRisk <= 0 {
+		return fmt.Errorf("缺少初始数量或初始风险，无法加仓")
+	}
+	if total := add.Quantity + add.AddQuantity; total > add.InitialQuantity*c.MaxSizeMultiple*(1+riskTolerance) {
+		return fmt.Errorf("加仓后数量%.4f超过初始数量%.4f的%g倍", total, add.InitialQuantity, c.MaxSizeMultiple)
+	}
+	if add.Risk > add.InitialRisk*(1+riskTolerance) {
+		return fmt.Errorf("加仓后风险%.2f USDT超过初始风险%.2f USDT", add.Risk, add.InitialRisk)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package risk
+
+import "fmt"
+
+// SymbolTier 币种分级的开仓上限（如主流币与小市值山寨币），0表示不限制
+type SymbolTier struct {
+	Name        string
+	Symbols     []string // 该分级的币种（已标准化的交易对），为空表示其他未列出的币种
+	MaxLeverage int      // 最大杠杆
+	MaxNotional float64  // 单个持仓的最大名义价值（USDT）
+}
+
+// SymbolTiers 按币种分级的杠杆和名义价值上限，下单前检查（流动性差的山寨币不能套用主流币的上限）
+type SymbolTiers struct {
+	bySymbol map[string]SymbolTier
+	fallback *SymbolTier
+}
+
+// NewSymbolTiers 创建币种分级上限：同一币种不能属于多个分级，最多一个不列币种的分级作为其他币种的默认分级
+func NewSymbolTiers(tiers []SymbolTier) (*SymbolTiers, error) {
+	t := &SymbolTiers{bySymbol: make(map[string]SymbolTier)}
+	for _, tier := range tiers {
+		if tier.MaxLeverage < 0 || tier.MaxNotional < 0 {
+			return nil, fmt.Errorf("分级 %s 的上限不能为负数", tier.Name)
+		}
+		if len(tier.Symbols) == 0 {
+			if t.fallback != nil {
+				return nil, fmt.Errorf("分级 %s 和 %s 都未列出币种（只能有一个默认分级）", t.fallback.Name, tier.Name)
+			}
+			fallback := tier
+			t.fallback = &fallback
+			continue
+		}
+		for _, symbol := range tier.Symbols {
+			if existing, ok := t.bySymbol[symbol]; ok {
+				return nil, fmt.Errorf("%s 同时属于分级 %s 和 %s", symbol, existing.Name, tier.Name)
+			}
+			t.bySymbol[symbol] = tier
+		}
+	}
+	return t, nil
+}
+
+// Tier 币种所属的分级（未列出且没有默认分级时返回false）
+func (t *SymbolTiers) Tier(symbol string) (SymbolTier, bool) {
+	if tier, ok := t.bySymbol[symbol]; ok {
+		return tier, true
+	}
+	if t.fallback != nil {
+		return *t.fallback, true
+	}
+	return SymbolTier{}, false
+}
+
+// Check 下单前检查杠杆和开仓后持仓的名义价值（USDT）是否超过币种所属分级的上限
+func (t *SymbolTiers) Check(symbol string, leverage int, notional float64) error {
+	tier, ok := t.Tier(symbol)
+	if !ok {
+		return nil
+	}
+	if tier.MaxLeverage > 0 && leverage > tier.MaxLeverage {
+		return fmt.Errorf("%s 杠杆%dx超过分级 %s 的上限%dx", symbol, leverage, tier.Name, tier.MaxLeverage)
+	}
+	if tier.MaxNotional > 0 && notional > tier.MaxNotional*(1+riskTolerance) {
+		return fmt.Errorf("%s 名义价值%.2f USDT超过分级 %s 的上限%.2f USDT", symbol, notional, tier.Name, tier.MaxNotional)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package rpc
+
+import (
+	"context"
+
+	"nofx/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorize 从metadata（authorization: Bearer <key> 或 x-api-key）读取API Key并检查角色
+// （gRPC方法均为只读，需要observer权限）
+func authorize(ctx context.Context, authenticator *auth.Authenticator) error {
+	var authorization, apiKey string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+		if values := md.Get("x-api-key"); len(values) > 0 {
+			apiKey = values[0]
+		}
+	}
+	key := auth.BearerToken(authorization, apiKey)
+	principal, err := authenticator.Authenticate(key)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !principal.Role.Allows(auth.RoleObserver) {
+		return status.Errorf(codes.PermissionDenied, "需要%s权限", auth.RoleObserver)
+	}
+	return nil
+}
+
+// authInterceptors 认证拦截器（普通调用和流式调用）
+func authInterceptors(authenticator *auth.Authenticator) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := authorize(ctx, authenticator); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := authorize(ss.Context(), authenticator); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
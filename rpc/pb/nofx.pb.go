@@ -0,0 +1,1061 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: rpc/pb/nofx.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Kline K线
+type Kline struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OpenTime      int64                  `protobuf:"varint,1,opt,name=open_time,json=openTime,proto3" json:"open_time,omitempty"` // 开盘时间（毫秒）
+	Open          float64                `protobuf:"fixed64,2,opt,name=open,proto3" json:"open,omitempty"`
+	High          float64                `protobuf:"fixed64,3,opt,name=high,proto3" json:"high,omitempty"`
+	Low           float64                `protobuf:"fixed64,4,opt,name=low,proto3" json:"low,omitempty"`
+	Close         float64                `protobuf:"fixed64,5,opt,name=close,proto3" json:"close,omitempty"`
+	Volume        float64                `protobuf:"fixed64,6,opt,name=volume,proto3" json:"volume,omitempty"`
+	CloseTime     int64                  `protobuf:"varint,7,opt,name=close_time,json=closeTime,proto3" json:"close_time,omitempty"` // 收盘时间（毫秒）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Kline) Reset() {
+	*x = Kline{}
+	mi := &file_rpc_pb_nofx_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Kline) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Kline) ProtoMessage() {}
+
+func (x *Kline) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pb_nofx_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Kline.ProtoReflect.Descriptor instead.
+func (*Kline) Descriptor() ([]byte, []int) {
+	return file_rpc_pb_nofx_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Kline) GetOpenTime() int64 {
+	if x != nil {
+		return x.OpenTime
+	}
+	return 0
+}
+
+func (x *Kline) GetOpen() float64 {
+	if x != nil {
+		return x.Open
+	}
+	return 0
+}
+
+func (x *Kline) GetHigh() float64 {
+	if x != nil {
+		return x.High
+	}
+	return 0
+}
+
+func (x *Kline) GetLow() float64 {
+	if x != nil {
+		return x.Low
+	}
+	return 0
+}
+
+func (x *Kline) GetClose() float64 {
+	if x != nil {
+		return x.Close
+	}
+	return 0
+}
+
+func (x *Kline) GetVolume() float64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *Kline) GetCloseTime() int64 {
+	if x != nil {
+		return x.CloseTime
+	}
+	return 0
+}
+
+// Data 币种市场数据快照（指标按配置的周期计算，字段名为默认周期的含义）
+type Data struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Symbol               string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	CurrentPrice         float64                `protobuf:"fixed64,2,opt,name=current_price,json=currentPrice,proto3" json:"current_price,omitempty"`
+	PriceChange_1H       float64                `protobuf:"fixed64,3,opt,name=price_change_1h,json=priceChange1h,proto3" json:"price_change_1h,omitempty"` // 1小时价格变化百分比
+	PriceChange_4H       float64                `protobuf:"fixed64,4,opt,name=price_change_4h,json=priceChange4h,proto3" json:"price_change_4h,omitempty"` // 4小时价格变化百分比
+	FundingRate          float64                `protobuf:"fixed64,5,opt,name=funding_rate,json=fundingRate,proto3" json:"funding_rate,omitempty"`
+	FundingIntervalHours int32                  `protobuf:"varint,6,opt,name=funding_interval_hours,json=fundingIntervalHours,proto3" json:"funding_interval_hours,omitempty"`
+	OpenInterest         float64                `protobuf:"fixed64,7,opt,name=open_interest,json=openInterest,proto3" json:"open_interest,omitempty"`
+	OpenInterestAverage  float64                `protobuf:"fixed64,8,opt,name=open_interest_average,json=openInterestAverage,proto3" json:"open_interest_average,omitempty"`
+	TrendInterval        string                 `protobuf:"bytes,9,opt,name=trend_interval,json=trendInterval,proto3" json:"trend_interval,omitempty"`
+	EntryInterval        string                 `protobuf:"bytes,10,opt,name=entry_interval,json=entryInterval,proto3" json:"entry_interval,omitempty"`
+	TrendMa              float64                `protobuf:"fixed64,11,opt,name=trend_ma,json=trendMa,proto3" json:"trend_ma,omitempty"`                   // 趋势周期均线（默认MA21）
+	EntryMa              float64                `protobuf:"fixed64,12,opt,name=entry_ma,json=entryMa,proto3" json:"entry_ma,omitempty"`                   // 入场周期均线（默认MA15）
+	StrengthScore        float64                `protobuf:"fixed64,13,opt,name=strength_score,json=strengthScore,proto3" json:"strength_score,omitempty"` // 综合强度评分（0~100，50为中性）
+	EmaFast              float64                `protobuf:"fixed64,14,opt,name=ema_fast,json=emaFast,proto3" json:"ema_fast,omitempty"`
+	EmaSlow              float64                `protobuf:"fixed64,15,opt,name=ema_slow,json=emaSlow,proto3" json:"ema_slow,omitempty"`
+	AtrFast              float64                `protobuf:"fixed64,16,opt,name=atr_fast,json=atrFast,proto3" json:"atr_fast,omitempty"`
+	AtrSlow              float64                `protobuf:"fixed64,17,opt,name=atr_slow,json=atrSlow,proto3" json:"atr_slow,omitempty"`
+	Macd                 []float64              `protobuf:"fixed64,18,rep,packed,name=macd,proto3" json:"macd,omitempty"` // 趋势周期MACD序列
+	Rsi                  []float64              `protobuf:"fixed64,19,rep,packed,name=rsi,proto3" json:"rsi,omitempty"`   // 趋势周期RSI序列
+	CurrentVolume        float64                `protobuf:"fixed64,20,opt,name=current_volume,json=currentVolume,proto3" json:"current_volume,omitempty"`
+	AverageVolume        float64                `protobuf:"fixed64,21,opt,name=average_volume,json=averageVolume,proto3" json:"average_volume,omitempty"`
+	NewListing           bool                   `protobuf:"varint,22,opt,name=new_listing,json=newListing,proto3" json:"new_listing,omitempty"` // 历史K线不足，长期指标不可靠
+	Delisted             bool                   `protobuf:"varint,23,opt,name=delisted,proto3" json:"delisted,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *Data) Reset() {
+	*x = Data{}
+	mi := &file_rpc_pb_nofx_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data) ProtoMessage() {}
+
+func (x *Data) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pb_nofx_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data.ProtoReflect.Descriptor instead.
+func (*Data) Descriptor() ([]byte, []int) {
+	return file_rpc_pb_nofx_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Data) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Data) GetCurrentPrice() float64 {
+	if x != nil {
+		return x.CurrentPrice
+	}
+	return 0
+}
+
+func (x *Data) GetPriceChange_1H() float64 {
+	if x != nil {
+		return x.PriceChange_1H
+	}
+	return 0
+}
+
+func (x *Data) GetPriceChange_4H() float64 {
+	if x != nil {
+		return x.PriceChange_4H
+	}
+	return 0
+}
+
+func (x *Data) GetFundingRate() float64 {
+	if x != nil {
+		return x.FundingRate
+	}
+	return 0
+}
+
+func (x *Data) GetFundingIntervalHours() int32 {
+	if x != nil {
+		return x.FundingIntervalHours
+	}
+	return 0
+}
+
+func (x *Data) GetOpenInterest() float64 {
+	if x != nil {
+		return x.OpenInterest
+	}
+	return 0
+}
+
+func (x *Data) GetOpenInterestAverage() float64 {
+	if x != nil {
+		return x.OpenInterestAverage
+	}
+	return 0
+}
+
+func (x *Data) GetTrendInterval() string {
+	if x != nil {
+		return x.TrendInterval
+	}
+	return ""
+}
+
+func (x *Data) GetEntryInterval() string {
+	if x != nil {
+		return x.EntryInterval
+	}
+	return ""
+}
+
+func (x *Data) GetTrendMa() float64 {
+	if x != nil {
+		return x.TrendMa
+	}
+	return 0
+}
+
+func (x *Data) GetEntryMa() float64 {
+	if x != nil {
+		return x.EntryMa
+	}
+	return 0
+}
+
+func (x *Data) GetStrengthScore() float64 {
+	if x != nil {
+		return x.StrengthScore
+	}
+	return 0
+}
+
+func (x *Data) GetEmaFast() float64 {
+	if x != nil {
+		return x.EmaFast
+	}
+	return 0
+}
+
+func (x *Data) GetEmaSlow() float64 {
+	if x != nil {
+		return x.EmaSlow
+	}
+	return 0
+}
+
+func (x *Data) GetAtrFast() float64 {
+	if x != nil {
+		return x.AtrFast
+	}
+	return 0
+}
+
+func (x *Data) GetAtrSlow() float64 {
+	if x != nil {
+		return x.AtrSlow
+	}
+	return 0
+}
+
+func (x *Data) GetMacd() []float64 {
+	if x != nil {
+		return x.Macd
+	}
+	return nil
+}
+
+func (x *Data) GetRsi() []float64 {
+	if x != nil {
+		return x.Rsi
+	}
+	return nil
+}
+
+func (x *Data) GetCurrentVolume() float64 {
+	if x != nil {
+		return x.CurrentVolume
+	}
+	return 0
+}
+
+func (x *Data) GetAverageVolume() float64 {
+	if x != nil {
+		return x.AverageVolume
+	}
+	return 0
+}
+
+func (x *Data) GetNewListing() bool {
+	if x != nil {
+		return x.NewListing
+	}
+	return false
+}
+
+func (x *Data) GetDelisted() bool {
+	if x != nil {
+		return x.Delisted
+	}
+	return false
+}
+
+// Position 持仓
+type Position struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	TraderId         string                 `protobuf:"bytes,1,opt,name=trader_id,json=traderId,proto3" json:"trader_id,omitempty"`
+	Symbol           string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Side             string                 `protobuf:"bytes,3,opt,name=side,proto3" json:"side,omitempty"` // "long" 或 "short"
+	Quantity         float64                `protobuf:"fixed64,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	EntryPrice       float64                `protobuf:"fixed64,5,opt,name=entry_price,json=entryPrice,proto3" json:"entry_price,omitempty"`
+	MarkPrice        float64                `protobuf:"fixed64,6,opt,name=mark_price,json=markPrice,proto3" json:"mark_price,omitempty"`
+	Leverage         int32                  `protobuf:"varint,7,opt,name=leverage,proto3" json:"leverage,omitempty"`
+	UnrealizedPnl    float64                `protobuf:"fixed64,8,opt,name=unrealized_pnl,json=unrealizedPnl,proto3" json:"unrealized_pnl,omitempty"`
+	UnrealizedPnlPct float64                `protobuf:"fixed64,9,opt,name=unrealized_pnl_pct,json=unrealizedPnlPct,proto3" json:"unrealized_pnl_pct,omitempty"`
+	LiquidationPrice float64                `protobuf:"fixed64,10,opt,name=liquidation_price,json=liquidationPrice,proto3" json:"liquidation_price,omitempty"`
+	MarginUsed       float64                `protobuf:"fixed64,11,opt,name=margin_used,json=marginUsed,proto3" json:"margin_used,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Position) Reset() {
+	*x = Position{}
+	mi := &file_rpc_pb_nofx_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Position) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Position) ProtoMessage() {}
+
+func (x *Position) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pb_nofx_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Position.ProtoReflect.Descriptor instead.
+func (*Position) Descriptor() ([]byte, []int) {
+	return file_rpc_pb_nofx_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Position) GetTraderId() string {
+	if x != nil {
+		return x.TraderId
+	}
+	return ""
+}
+
+func (x *Position) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Position) GetSide() string {
+	if x != nil {
+		return x.Side
+	}
+	return ""
+}
+
+func (x *Position) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *Position) GetEntryPrice() float64 {
+	if x != nil {
+		return x.EntryPrice
+	}
+	return 0
+}
+
+func (x *Position) GetMarkPrice() float64 {
+	if x != nil {
+		return x.MarkPrice
+	}
+	return 0
+}
+
+func (x *Position) GetLeverage() int32 {
+	if x != nil {
+		return x.Leverage
+	}
+	return 0
+}
+
+func (x *Position) GetUnrealizedPnl() float64 {
+	if x != nil {
+		return x.UnrealizedPnl
+	}
+	return 0
+}
+
+func (x *Position) GetUnrealizedPnlPct() float64 {
+	if x != nil {
+		return x.UnrealizedPnlPct
+	}
+	return 0
+}
+
+func (x *Position) GetLiquidationPrice() float64 {
+	if x != nil {
+		return x.LiquidationPrice
+	}
+	return 0
+}
+
+func (x *Position) GetMarginUsed() float64 {
+	if x != nil {
+		return x.MarginUsed
+	}
+	return 0
+}
+
+// Signal 已执行的交易信号
+type Signal struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TraderId      string                 `protobuf:"bytes,1,opt,name=trader_id,json=traderId,proto3" json:"trader_id,omitempty"`
+	Symbol        string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Action        string                 `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"` // "open_long"、"open_short"、"close_long" 或 "close_short"
+	Price         float64                `protobuf:"fixed64,4,opt,name=price,proto3" json:"price,omitempty"` // 成交价（未知时为决策价）
+	Quantity      float64                `protobuf:"fixed64,5,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Leverage      int32                  `protobuf:"varint,6,opt,name=leverage,proto3" json:"leverage,omitempty"`
+	StopLoss      float64                `protobuf:"fixed64,7,opt,name=stop_loss,json=stopLoss,proto3" json:"stop_loss,omitempty"`
+	TakeProfit    float64                `protobuf:"fixed64,8,opt,name=take_profit,json=takeProfit,proto3" json:"take_profit,omitempty"`
+	Confidence    int32                  `protobuf:"varint,9,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	Reasoning     string                 `protobuf:"bytes,10,opt,name=reasoning,proto3" json:"reasoning,omitempty"`     // AI给出的理由
+	Explanation   string                 `protobuf:"bytes,11,opt,name=explanation,proto3" json:"explanation,omitempty"` // 关键指标读数摘要
+	Time          int64                  `protobuf:"varint,12,opt,name=time,proto3" json:"time,omitempty"`              // 信号时间（毫秒）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Signal) Reset() {
+	*x = Signal{}
+	mi := &file_rpc_pb_nofx_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Signal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Signal) ProtoMessage() {}
+
+func (x *Signal) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pb_nofx_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Signal.ProtoReflect.Descriptor instead.
+func (*Signal) Descriptor() ([]byte, []int) {
+	return file_rpc_pb_nofx_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Signal) GetTraderId() string {
+	if x != nil {
+		return x.TraderId
+	}
+	return ""
+}
+
+func (x *Signal) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Signal) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Signal) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Signal) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *Signal) GetLeverage() int32 {
+	if x != nil {
+		return x.Leverage
+	}
+	return 0
+}
+
+func (x *Signal) GetStopLoss() float64 {
+	if x != nil {
+		return x.StopLoss
+	}
+	return 0
+}
+
+func (x *Signal) GetTakeProfit() float64 {
+	if x != nil {
+		return x.TakeProfit
+	}
+	return 0
+}
+
+func (x *Signal) GetConfidence() int32 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *Signal) GetReasoning() string {
+	if x != nil {
+		return x.Reasoning
+	}
+	return ""
+}
+
+func (x *Signal) GetExplanation() string {
+	if x != nil {
+		return x.Explanation
+	}
+	return ""
+}
+
+func (x *Signal) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+type GetMarketDataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	TrendInterval string                 `protobuf:"bytes,2,opt,name=trend_interval,json=trendInterval,proto3" json:"trend_interval,omitempty"` // 为空时使用默认周期（4h）
+	EntryInterval string                 `protobuf:"bytes,3,opt,name=entry_interval,json=entryInterval,proto3" json:"entry_interval,omitempty"` // 为空时使用默认周期（15m）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMarketDataRequest) Reset() {
+	*x = GetMarketDataRequest{}
+	mi := &file_rpc_pb_nofx_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMarketDataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMarketDataRequest) ProtoMessage() {}
+
+func (x *GetMarketDataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pb_nofx_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMarketDataRequest.ProtoReflect.Descriptor instead.
+func (*GetMarketDataRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_pb_nofx_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetMarketDataRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *GetMarketDataRequest) GetTrendInterval() string {
+	if x != nil {
+		return x.TrendInterval
+	}
+	return ""
+}
+
+func (x *GetMarketDataRequest) GetEntryInterval() string {
+	if x != nil {
+		return x.EntryInterval
+	}
+	return ""
+}
+
+type GetKlinesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Interval      string                 `protobuf:"bytes,2,opt,name=interval,proto3" json:"interval,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"` // 默认100
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetKlinesRequest) Reset() {
+	*x = GetKlinesRequest{}
+	mi := &file_rpc_pb_nofx_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetKlinesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetKlinesRequest) ProtoMessage() {}
+
+func (x *GetKlinesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pb_nofx_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetKlinesRequest.ProtoReflect.Descriptor instead.
+func (*GetKlinesRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_pb_nofx_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetKlinesRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *GetKlinesRequest) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *GetKlinesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetKlinesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Klines        []*Kline               `protobuf:"bytes,1,rep,name=klines,proto3" json:"klines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetKlinesResponse) Reset() {
+	*x = GetKlinesResponse{}
+	mi := &file_rpc_pb_nofx_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetKlinesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetKlinesResponse) ProtoMessage() {}
+
+func (x *GetKlinesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pb_nofx_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetKlinesResponse.ProtoReflect.Descriptor instead.
+func (*GetKlinesResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_pb_nofx_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetKlinesResponse) GetKlines() []*Kline {
+	if x != nil {
+		return x.Klines
+	}
+	return nil
+}
+
+type GetPositionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TraderId      string                 `protobuf:"bytes,1,opt,name=trader_id,json=traderId,proto3" json:"trader_id,omitempty"` // 为空时使用第一个trader
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPositionsRequest) Reset() {
+	*x = GetPositionsRequest{}
+	mi := &file_rpc_pb_nofx_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPositionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPositionsRequest) ProtoMessage() {}
+
+func (x *GetPositionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pb_nofx_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPositionsRequest.ProtoReflect.Descriptor instead.
+func (*GetPositionsRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_pb_nofx_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetPositionsRequest) GetTraderId() string {
+	if x != nil {
+		return x.TraderId
+	}
+	return ""
+}
+
+type GetPositionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Positions     []*Position            `protobuf:"bytes,1,rep,name=positions,proto3" json:"positions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPositionsResponse) Reset() {
+	*x = GetPositionsResponse{}
+	mi := &file_rpc_pb_nofx_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPositionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPositionsResponse) ProtoMessage() {}
+
+func (x *GetPositionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pb_nofx_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPositionsResponse.ProtoReflect.Descriptor instead.
+func (*GetPositionsResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_pb_nofx_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetPositionsResponse) GetPositions() []*Position {
+	if x != nil {
+		return x.Positions
+	}
+	return nil
+}
+
+type SubscribeSignalsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TraderId      string                 `protobuf:"bytes,1,opt,name=trader_id,json=traderId,proto3" json:"trader_id,omitempty"` // 为空表示所有trader
+	Symbol        string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`                     // 为空表示所有币种
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeSignalsRequest) Reset() {
+	*x = SubscribeSignalsRequest{}
+	mi := &file_rpc_pb_nofx_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeSignalsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeSignalsRequest) ProtoMessage() {}
+
+func (x *SubscribeSignalsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pb_nofx_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeSignalsRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeSignalsRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_pb_nofx_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SubscribeSignalsRequest) GetTraderId() string {
+	if x != nil {
+		return x.TraderId
+	}
+	return ""
+}
+
+func (x *SubscribeSignalsRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+var File_rpc_pb_nofx_proto protoreflect.FileDescriptor
+
+const file_rpc_pb_nofx_proto_rawDesc = "" +
+	"\n" +
+	"\x11rpc/pb/nofx.proto\x12\anofx.v1\"\xab\x01\n" +
+	"\x05Kline\x12\x1b\n" +
+	"\topen_time\x18\x01 \x01(\x03R\bopenTime\x12\x12\n" +
+	"\x04open\x18\x02 \x01(\x01R\x04open\x12\x12\n" +
+	"\x04high\x18\x03 \x01(\x01R\x04high\x12\x10\n" +
+	"\x03low\x18\x04 \x01(\x01R\x03low\x12\x14\n" +
+	"\x05close\x18\x05 \x01(\x01R\x05close\x12\x16\n" +
+	"\x06volume\x18\x06 \x01(\x01R\x06volume\x12\x1d\n" +
+	"\n" +
+	"close_time\x18\a \x01(\x03R\tcloseTime\"\x8d\x06\n" +
+	"\x04Data\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12#\n" +
+	"\rcurrent_price\x18\x02 \x01(\x01R\fcurrentPrice\x12&\n" +
+	"\x0fprice_change_1h\x18\x03 \x01(\x01R\rpriceChange1h\x12&\n" +
+	"\x0fprice_change_4h\x18\x04 \x01(\x01R\rpriceChange4h\x12!\n" +
+	"\ffunding_rate\x18\x05 \x01(\x01R\vfundingRate\x124\n" +
+	"\x16funding_interval_hours\x18\x06 \x01(\x05R\x14fundingIntervalHours\x12#\n" +
+	"\ropen_interest\x18\a \x01(\x01R\fopenInterest\x122\n" +
+	"\x15open_interest_average\x18\b \x01(\x01R\x13openInterestAverage\x12%\n" +
+	"\x0etrend_interval\x18\t \x01(\tR\rtrendInterval\x12%\n" +
+	"\x0eentry_interval\x18\n" +
+	" \x01(\tR\rentryInterval\x12\x19\n" +
+	"\btrend_ma\x18\v \x01(\x01R\atrendMa\x12\x19\n" +
+	"\bentry_ma\x18\f \x01(\x01R\aentryMa\x12%\n" +
+	"\x0estrength_score\x18\r \x01(\x01R\rstrengthScore\x12\x19\n" +
+	"\bema_fast\x18\x0e \x01(\x01R\aemaFast\x12\x19\n" +
+	"\bema_slow\x18\x0f \x01(\x01R\aemaSlow\x12\x19\n" +
+	"\batr_fast\x18\x10 \x01(\x01R\aatrFast\x12\x19\n" +
+	"\batr_slow\x18\x11 \x01(\x01R\aatrSlow\x12\x12\n" +
+	"\x04macd\x18\x12 \x03(\x01R\x04macd\x12\x10\n" +
+	"\x03rsi\x18\x13 \x03(\x01R\x03rsi\x12%\n" +
+	"\x0ecurrent_volume\x18\x14 \x01(\x01R\rcurrentVolume\x12%\n" +
+	"\x0eaverage_volume\x18\x15 \x01(\x01R\raverageVolume\x12\x1f\n" +
+	"\vnew_listing\x18\x16 \x01(\bR\n" +
+	"newListing\x12\x1a\n" +
+	"\bdelisted\x18\x17 \x01(\bR\bdelisted\"\xee\x02\n" +
+	"\bPosition\x12\x1b\n" +
+	"\ttrader_id\x18\x01 \x01(\tR\btraderId\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x12\x12\n" +
+	"\x04side\x18\x03 \x01(\tR\x04side\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x01R\bquantity\x12\x1f\n" +
+	"\ventry_price\x18\x05 \x01(\x01R\n" +
+	"entryPrice\x12\x1d\n" +
+	"\n" +
+	"mark_price\x18\x06 \x01(\x01R\tmarkPrice\x12\x1a\n" +
+	"\bleverage\x18\a \x01(\x05R\bleverage\x12%\n" +
+	"\x0eunrealized_pnl\x18\b \x01(\x01R\runrealizedPnl\x12,\n" +
+	"\x12unrealized_pnl_pct\x18\t \x01(\x01R\x10unrealizedPnlPct\x12+\n" +
+	"\x11liquidation_price\x18\n" +
+	" \x01(\x01R\x10liquidationPrice\x12\x1f\n" +
+	"\vmargin_used\x18\v \x01(\x01R\n" +
+	"marginUsed\"\xd5\x02\n" +
+	"\x06Signal\x12\x1b\n" +
+	"\ttrader_id\x18\x01 \x01(\tR\btraderId\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\x12\x14\n" +
+	"\x05price\x18\x04 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bquantity\x18\x05 \x01(\x01R\bquantity\x12\x1a\n" +
+	"\bleverage\x18\x06 \x01(\x05R\bleverage\x12\x1b\n" +
+	"\tstop_loss\x18\a \x01(\x01R\bstopLoss\x12\x1f\n" +
+	"\vtake_profit\x18\b \x01(\x01R\n" +
+	"takeProfit\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\t \x01(\x05R\n" +
+	"confidence\x12\x1c\n" +
+	"\treasoning\x18\n" +
+	" \x01(\tR\treasoning\x12 \n" +
+	"\vexplanation\x18\v \x01(\tR\vexplanation\x12\x12\n" +
+	"\x04time\x18\f \x01(\x03R\x04time\"|\n" +
+	"\x14GetMarketDataRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12%\n" +
+	"\x0etrend_interval\x18\x02 \x01(\tR\rtrendInterval\x12%\n" +
+	"\x0eentry_interval\x18\x03 \x01(\tR\rentryInterval\"\\\n" +
+	"\x10GetKlinesRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x1a\n" +
+	"\binterval\x18\x02 \x01(\tR\binterval\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\";\n" +
+	"\x11GetKlinesResponse\x12&\n" +
+	"\x06klines\x18\x01 \x03(\v2\x0e.nofx.v1.KlineR\x06klines\"2\n" +
+	"\x13GetPositionsRequest\x12\x1b\n" +
+	"\ttrader_id\x18\x01 \x01(\tR\btraderId\"G\n" +
+	"\x14GetPositionsResponse\x12/\n" +
+	"\tpositions\x18\x01 \x03(\v2\x11.nofx.v1.PositionR\tpositions\"N\n" +
+	"\x17SubscribeSignalsRequest\x12\x1b\n" +
+	"\ttrader_id\x18\x01 \x01(\tR\btraderId\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol2\xa6\x02\n" +
+	"\vNofxService\x12=\n" +
+	"\rGetMarketData\x12\x1d.nofx.v1.GetMarketDataRequest\x1a\r.nofx.v1.Data\x12B\n" +
+	"\tGetKlines\x12\x19.nofx.v1.GetKlinesRequest\x1a\x1a.nofx.v1.GetKlinesResponse\x12K\n" +
+	"\fGetPositions\x12\x1c.nofx.v1.GetPositionsRequest\x1a\x1d.nofx.v1.GetPositionsResponse\x12G\n" +
+	"\x10SubscribeSignals\x12 .nofx.v1.SubscribeSignalsRequest\x1a\x0f.nofx.v1.Signal0\x01B\x10Z\x0enofx/rpc/pb;pbb\x06proto3"
+
+var (
+	file_rpc_pb_nofx_proto_rawDescOnce sync.Once
+	file_rpc_pb_nofx_proto_rawDescData []byte
+)
+
+func file_rpc_pb_nofx_proto_rawDescGZIP() []byte {
+	file_rpc_pb_nofx_proto_rawDescOnce.Do(func() {
+		file_rpc_pb_nofx_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_rpc_pb_nofx_proto_rawDesc), len(file_rpc_pb_nofx_proto_rawDesc)))
+	})
+	return file_rpc_pb_nofx_proto_rawDescData
+}
+
+var file_rpc_pb_nofx_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_rpc_pb_nofx_proto_goTypes = []any{
+	(*Kline)(nil),                   // 0: nofx.v1.Kline
+	(*Data)(nil),                    // 1: nofx.v1.Data
+	(*Position)(nil),                // 2: nofx.v1.Position
+	(*Signal)(nil),                  // 3: nofx.v1.Signal
+	(*GetMarketDataRequest)(nil),    // 4: nofx.v1.GetMarketDataRequest
+	(*GetKlinesRequest)(nil),        // 5: nofx.v1.GetKlinesRequest
+	(*GetKlinesResponse)(nil),       // 6: nofx.v1.GetKlinesResponse
+	(*GetPositionsRequest)(nil),     // 7: nofx.v1.GetPositionsRequest
+	(*GetPositionsResponse)(nil),    // 8: nofx.v1.GetPositionsResponse
+	(*SubscribeSignalsRequest)(nil), // 9: nofx.v1.SubscribeSignalsRequest
+}
+var file_rpc_pb_nofx_proto_depIdxs = []int32{
+	0, // 0: nofx.v1.GetKlinesResponse.klines:type_name -> nofx.v1.Kline
+	2, // 1: nofx.v1.GetPositionsResponse.positions:type_name -> nofx.v1.Position
+	4, // 2: nofx.v1.NofxService.GetMarketData:input_type -> nofx.v1.GetMarketDataRequest
+	5, // 3: nofx.v1.NofxService.GetKlines:input_type -> nofx.v1.GetKlinesRequest
+	7, // 4: nofx.v1.NofxService.GetPositions:input_type -> nofx.v1.GetPositionsRequest
+	9, // 5: nofx.v1.NofxService.SubscribeSignals:input_type -> nofx.v1.SubscribeSignalsRequest
+	1, // 6: nofx.v1.NofxService.GetMarketData:output_type -> nofx.v1.Data
+	6, // 7: nofx.v1.NofxService.GetKlines:output_type -> nofx.v1.GetKlinesResponse
+	8, // 8: nofx.v1.NofxService.GetPositions:output_type -> nofx.v1.GetPositionsResponse
+	3, // 9: nofx.v1.NofxService.SubscribeSignals:output_type -> nofx.v1.Signal
+	6, // [6:10] is the sub-list for method output_type
+	2, // [2:6] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_rpc_pb_nofx_proto_init() }
+func file_rpc_pb_nofx_proto_init() {
+	if File_rpc_pb_nofx_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_rpc_pb_nofx_proto_rawDesc), len(file_rpc_pb_nofx_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_rpc_pb_nofx_proto_goTypes,
+		DependencyIndexes: file_rpc_pb_nofx_proto_depIdxs,
+		MessageInfos:      file_rpc_pb_nofx_proto_msgTypes,
+	}.Build()
+	File_rpc_pb_nofx_proto = out.File
+	file_rpc_pb_nofx_proto_goTypes = nil
+	file_rpc_pb_nofx_proto_depIdxs = nil
+}
@@ -0,0 +1,251 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: rpc/pb/nofx.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	NofxService_GetMarketData_FullMethodName    = "/nofx.v1.NofxService/GetMarketData"
+	NofxService_GetKlines_FullMethodName        = "/nofx.v1.NofxService/GetKlines"
+	NofxService_GetPositions_FullMethodName     = "/nofx.v1.NofxService/GetPositions"
+	NofxService_SubscribeSignals_FullMethodName = "/nofx.v1.NofxService/SubscribeSignals"
+)
+
+// NofxServiceClient is the client API for NofxService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// NofxService 对外提供行情数据、持仓和交易信号（供Python研究环境、看板等非Go组件使用）
+type NofxServiceClient interface {
+	// GetMarketData 获取币种的市场数据快照（价格、指标、资金费率、持仓量）
+	GetMarketData(ctx context.Context, in *GetMarketDataRequest, opts ...grpc.CallOption) (*Data, error)
+	// GetKlines 获取当前行情数据源的K线（按时间升序，包含未走完的最后一根）
+	GetKlines(ctx context.Context, in *GetKlinesRequest, opts ...grpc.CallOption) (*GetKlinesResponse, error)
+	// GetPositions 获取指定trader的当前持仓
+	GetPositions(ctx context.Context, in *GetPositionsRequest, opts ...grpc.CallOption) (*GetPositionsResponse, error)
+	// SubscribeSignals 订阅已执行的开平仓信号（服务端流）
+	SubscribeSignals(ctx context.Context, in *SubscribeSignalsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Signal], error)
+}
+
+type nofxServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNofxServiceClient(cc grpc.ClientConnInterface) NofxServiceClient {
+	return &nofxServiceClient{cc}
+}
+
+func (c *nofxServiceClient) GetMarketData(ctx context.Context, in *GetMarketDataRequest, opts ...grpc.CallOption) (*Data, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Data)
+	err := c.cc.Invoke(ctx, NofxService_GetMarketData_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nofxServiceClient) GetKlines(ctx context.Context, in *GetKlinesRequest, opts ...grpc.CallOption) (*GetKlinesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetKlinesResponse)
+	err := c.cc.Invoke(ctx, NofxService_GetKlines_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nofxServiceClient) GetPositions(ctx context.Context, in *GetPositionsRequest, opts ...grpc.CallOption) (*GetPositionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPositionsResponse)
+	err := c.cc.Invoke(ctx, NofxService_GetPositions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nofxServiceClient) SubscribeSignals(ctx context.Context, in *SubscribeSignalsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Signal], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NofxService_ServiceDesc.Streams[0], NofxService_SubscribeSignals_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeSignalsRequest, Signal]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NofxService_SubscribeSignalsClient = grpc.ServerStreamingClient[Signal]
+
+// NofxServiceServer is the server API for NofxService service.
+// All implementations must embed UnimplementedNofxServiceServer
+// for forward compatibility.
+//
+// NofxService 对外提供行情数据、持仓和交易信号（供Python研究环境、看板等非Go组件使用）
+type NofxServiceServer interface {
+	// GetMarketData 获取币种的市场数据快照（价格、指标、资金费率、持仓量）
+	GetMarketData(context.Context, *GetMarketDataRequest) (*Data, error)
+	// GetKlines 获取当前行情数据源的K线（按时间升序，包含未走完的最后一根）
+	GetKlines(context.Context, *GetKlinesRequest) (*GetKlinesResponse, error)
+	// GetPositions 获取指定trader的当前持仓
+	GetPositions(context.Context, *GetPositionsRequest) (*GetPositionsResponse, error)
+	// SubscribeSignals 订阅已执行的开平仓信号（服务端流）
+	SubscribeSignals(*SubscribeSignalsRequest, grpc.ServerStreamingServer[Signal]) error
+	mustEmbedUnimplementedNofxServiceServer()
+}
+
+// UnimplementedNofxServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedNofxServiceServer struct{}
+
+func (UnimplementedNofxServiceServer) GetMarketData(context.Context, *GetMarketDataRequest) (*Data, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetMarketData not implemented")
+}
+func (UnimplementedNofxServiceServer) GetKlines(context.Context, *GetKlinesRequest) (*GetKlinesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetKlines not implemented")
+}
+func (UnimplementedNofxServiceServer) GetPositions(context.Context, *GetPositionsRequest) (*GetPositionsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetPositions not implemented")
+}
+func (UnimplementedNofxServiceServer) SubscribeSignals(*SubscribeSignalsRequest, grpc.ServerStreamingServer[Signal]) error {
+	return status.Error(codes.Unimplemented, "method SubscribeSignals not implemented")
+}
+func (UnimplementedNofxServiceServer) mustEmbedUnimplementedNofxServiceServer() {}
+func (UnimplementedNofxServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeNofxServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NofxServiceServer will
+// result in compilation errors.
+type UnsafeNofxServiceServer interface {
+	mustEmbedUnimplementedNofxServiceServer()
+}
+
+func RegisterNofxServiceServer(s grpc.ServiceRegistrar, srv NofxServiceServer) {
+	// If the following call panics, it indicates UnimplementedNofxServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&NofxService_ServiceDesc, srv)
+}
+
+func _NofxService_GetMarketData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMarketDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NofxServiceServer).GetMarketData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NofxService_GetMarketData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NofxServiceServer).GetMarketData(ctx, req.(*GetMarketDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NofxService_GetKlines_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetKlinesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NofxServiceServer).GetKlines(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NofxService_GetKlines_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NofxServiceServer).GetKlines(ctx, req.(*GetKlinesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NofxService_GetPositions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPositionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NofxServiceServer).GetPositions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NofxService_GetPositions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NofxServiceServer).GetPositions(ctx, req.(*GetPositionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NofxService_SubscribeSignals_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeSignalsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NofxServiceServer).SubscribeSignals(m, &grpc.GenericServerStream[SubscribeSignalsRequest, Signal]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NofxService_SubscribeSignalsServer = grpc.ServerStreamingServer[Signal]
+
+// NofxService_ServiceDesc is the grpc.ServiceDesc for NofxService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NofxService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "nofx.v1.NofxService",
+	HandlerType: (*NofxServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetMarketData",
+			Handler:    _NofxService_GetMarketData_Handler,
+		},
+		{
+			MethodName: "GetKlines",
+			Handler:    _NofxService_GetKlines_Handler,
+		},
+		{
+			MethodName: "GetPositions",
+			Handler:    _NofxService_GetPositions_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeSignals",
+			Handler:       _NofxService_SubscribeSignals_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rpc/pb/nofx.proto",
+}
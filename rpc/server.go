@@ -0,0 +1,233 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"nofx/auth"
+	"nofx/events"
+	"nofx/manager"
+	"nofx/market"
+	"nofx/rpc/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultKlineLimit GetKlines未指定数量时返回的K线数
+const defaultKlineLimit = 100
+
+// Server gRPC服务（行情数据、持仓、交易信号流），协议定义见 rpc/pb/nofx.proto
+type Server struct {
+	pb.UnimplementedNofxServiceServer
+
+	traderManager *manager.TraderManager
+	port          int
+	grpcServer    *grpc.Server
+}
+
+// NewServer 创建gRPC服务（authenticator为nil表示不认证）
+func NewServer(traderManager *manager.TraderManager, port int, authenticator *auth.Authenticator) *Server {
+	var options []grpc.ServerOption
+	if authenticator != nil {
+		options = authInterceptors(authenticator)
+	}
+	s := &Server{
+		traderManager: traderManager,
+		port:          port,
+		grpcServer:    grpc.NewServer(options...),
+	}
+	pb.RegisterNofxServiceServer(s.grpcServer, s)
+	return s
+}
+
+// Start 启动gRPC服务（阻塞直到停止）
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return fmt.Errorf("监听gRPC端口失败: %w", err)
+	}
+	log.Printf("🔌 gRPC服务启动在 :%d（nofx.v1.NofxService）", s.port)
+	return s.grpcServer.Serve(listener)
+}
+
+// Stop 停止gRPC服务（等待进行中的调用结束，信号流会被中断）
+func (s *Server) Stop() {
+	s.grpcServer.Stop()
+}
+
+// GetMarketData 获取币种的市场数据快照
+func (s *Server) GetMarketData(ctx context.Context, req *pb.GetMarketDataRequest) (*pb.Data, error) {
+	if req.Symbol == "" {
+		return nil, status.Error(codes.InvalidArgument, "symbol不能为空")
+	}
+	trend, entry := req.TrendInterval, req.EntryInterval
+	if trend == "" {
+		trend = "4h"
+	}
+	if entry == "" {
+		entry = "15m"
+	}
+
+	data, err := market.GetWithIntervals(market.Normalize(req.Symbol), trend, entry)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "获取市场数据失败: %v", err)
+	}
+	return toData(data), nil
+}
+
+// GetKlines 获取当前行情数据源的K线
+func (s *Server) GetKlines(ctx context.Context, req *pb.GetKlinesRequest) (*pb.GetKlinesResponse, error) {
+	if req.Symbol == "" || req.Interval == "" {
+		return nil, status.Error(codes.InvalidArgument, "symbol和interval不能为空")
+	}
+	if _, err := market.IntervalDuration(req.Interval); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultKlineLimit
+	}
+
+	klines, err := market.GetProvider().GetKlines(market.Normalize(req.Symbol), req.Interval, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "获取K线失败: %v", err)
+	}
+	resp := &pb.GetKlinesResponse{Klines: make([]*pb.Kline, 0, len(klines))}
+	for _, k := range klines {
+		resp.Klines = append(resp.Klines, &pb.Kline{
+			OpenTime:  k.OpenTime,
+			Open:      k.Open,
+			High:      k.High,
+			Low:       k.Low,
+			Close:     k.Close,
+			Volume:    k.Volume,
+			CloseTime: k.CloseTime,
+		})
+	}
+	return resp, nil
+}
+
+// GetPositions 获取指定trader的持仓
+func (s *Server) GetPositions(ctx context.Context, req *pb.GetPositionsRequest) (*pb.GetPositionsResponse, error) {
+	traderID := req.TraderId
+	if traderID == "" {
+		ids := s.traderManager.GetTraderIDs()
+		if len(ids) == 0 {
+			return nil, status.Error(codes.NotFound, "没有可用的trader")
+		}
+		traderID = ids[0]
+	}
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	positions, err := trader.GetPositions()
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	resp := &pb.GetPositionsResponse{Positions: make([]*pb.Position, 0, len(positions))}
+	for _, pos := range positions {
+		position := &pb.Position{TraderId: traderID}
+		position.Symbol, _ = pos["symbol"].(string)
+		position.Side, _ = pos["side"].(string)
+		position.Quantity, _ = pos["quantity"].(float64)
+		position.EntryPrice, _ = pos["entry_price"].(float64)
+		position.MarkPrice, _ = pos["mark_price"].(float64)
+		position.UnrealizedPnl, _ = pos["unrealized_pnl"].(float64)
+		position.UnrealizedPnlPct, _ = pos["unrealized_pnl_pct"].(float64)
+		position.LiquidationPrice, _ = pos["liquidation_price"].(float64)
+		position.MarginUsed, _ = pos["margin_used"].(float64)
+		if leverage, ok := pos["leverage"].(int); ok {
+			position.Leverage = int32(leverage)
+		}
+		resp.Positions = append(resp.Positions, position)
+	}
+	return resp, nil
+}
+
+// SubscribeSignals 订阅已执行的交易信号，直到客户端断开
+func (s *Server) SubscribeSignals(req *pb.SubscribeSignalsRequest, stream grpc.ServerStreamingServer[pb.Signal]) error {
+	ch := events.Subscribe(events.TypeTradeSignal)
+	defer events.Unsubscribe(ch)
+
+	symbol := ""
+	if req.Symbol != "" {
+		symbol = market.Normalize(req.Symbol)
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-ch:
+			signal := toSignal(event)
+			if (req.TraderId != "" && signal.TraderId != req.TraderId) || (symbol != "" && signal.Symbol != symbol) {
+				continue
+			}
+			if err := stream.Send(signal); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toData 转换市场数据
+func toData(data *market.Data) *pb.Data {
+	result := &pb.Data{
+		Symbol:               data.Symbol,
+		CurrentPrice:         data.CurrentPrice,
+		PriceChange_1H:       data.PriceChange1h,
+		PriceChange_4H:       data.PriceChange4h,
+		FundingRate:          data.FundingRate,
+		FundingIntervalHours: int32(data.FundingInterval),
+		TrendInterval:        data.TrendInterval,
+		EntryInterval:        data.EntryInterval,
+		TrendMa:              data.TrendMA,
+		EntryMa:              data.EntryMA,
+		StrengthScore:        data.StrengthScore,
+		NewListing:           data.IsNewListing(),
+		Delisted:             data.Delisted,
+	}
+	if data.OpenInterest != nil {
+		result.OpenInterest = data.OpenInterest.Latest
+		result.OpenInterestAverage = data.OpenInterest.Average
+	}
+	if ltd := data.LongerTermContext; ltd != nil {
+		result.EmaFast = ltd.EMA20
+		result.EmaSlow = ltd.EMA50
+		result.AtrFast = ltd.ATR3
+		result.AtrSlow = ltd.ATR14
+		result.Macd = ltd.MACDValues
+		result.Rsi = ltd.RSI14Values
+		result.CurrentVolume = ltd.CurrentVolume
+		result.AverageVolume = ltd.AverageVolume
+	}
+	return result
+}
+
+// toSignal 将交易信号事件转换为Signal
+func toSignal(event events.Event) *pb.Signal {
+	signal := &pb.Signal{Time: event.Time.UnixMilli()}
+	signal.TraderId, _ = event.Data["trader_id"].(string)
+	signal.Symbol, _ = event.Data["symbol"].(string)
+	signal.Action, _ = event.Data["action"].(string)
+	signal.Price, _ = event.Data["price"].(float64)
+	signal.Quantity, _ = event.Data["quantity"].(float64)
+	signal.StopLoss, _ = event.Data["stop_loss"].(float64)
+	signal.TakeProfit, _ = event.Data["take_profit"].(float64)
+	signal.Reasoning, _ = event.Data["reasoning"].(string)
+	if leverage, ok := event.Data["leverage"].(int); ok {
+		signal.Leverage = int32(leverage)
+	}
+	if confidence, ok := event.Data["confidence"].(int); ok {
+		signal.Confidence = int32(confidence)
+	}
+	if explanation, ok := event.Data["explanation"].(*market.Explanation); ok && explanation != nil {
+		signal.Explanation = explanation.Summary
+	}
+	return signal
+}
@@ -0,0 +1,157 @@
+package run
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultManifestDir 运行清单默认目录（<dir>/<run_id>/manifest.json）
+const DefaultManifestDir = "runs"
+
+// DataRange 运行使用的历史数据范围
+type DataRange struct {
+	Symbol   string    `json:"symbol"`
+	Interval string    `json:"interval"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+}
+
+// Manifest 运行清单：记录复现一次运行所需的全部输入
+type Manifest struct {
+	RunID        string                 `json:"run_id"`
+	Mode         string                 `json:"mode"` // "live" 或 "replay"
+	Seed         int64                  `json:"seed"`
+	StartedAt    time.Time              `json:"started_at"`
+	Args         []string               `json:"args"`
+	GoVersion    string                 `json:"go_version"`
+	Revision     string                 `json:"revision"`              // 代码版本（git commit）
+	RevisionTime string                 `json:"revision_time"`         // commit时间
+	Modified     bool                   `json:"modified"`              // 构建时工作区是否有未提交的修改
+	ConfigFile   string                 `json:"config_file"`           // 配置文件路径
+	ConfigSHA256 string                 `json:"config_sha256"`         // 配置文件原始内容的哈希
+	Config       map[string]interface{} `json:"config"`                // 配置内容（密钥已脱敏）
+	DataRanges   []DataRange            `json:"data_ranges,omitempty"` // 回放使用的历史数据范围（实盘为空）
+}
+
+var (
+	currentManifest *Manifest
+	manifestMutex   sync.RWMutex
+)
+
+// NewManifest 为当前运行创建清单（读取配置文件计算哈希，并保存脱敏后的配置内容）
+func NewManifest(mode, configFile string) (*Manifest, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	return NewManifestFromConfig(mode, configFile, data)
+}
+
+// NewManifestFromConfig 用配置内容创建清单（source为配置来源，如文件路径或"env"）
+func NewManifestFromConfig(mode, source string, data []byte) (*Manifest, error) {
+	m := &Manifest{
+		RunID:      ID(),
+		Mode:       mode,
+		Seed:       Seed(),
+		StartedAt:  StartedAt(),
+		Args:       os.Args,
+		GoVersion:  runtime.Version(),
+		Revision:   "unknown",
+		ConfigFile: source,
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				m.Revision = setting.Value
+			case "vcs.time":
+				m.RevisionTime = setting.Value
+			case "vcs.modified":
+				m.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	m.ConfigSHA256 = hex.EncodeToString(sum[:])
+	if err := json.Unmarshal(data, &m.Config); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	redact(m.Config)
+
+	return m, nil
+}
+
+// AddDataRange 记录使用的历史数据范围
+func (m *Manifest) AddDataRange(symbol, interval string, from, to time.Time) {
+	m.DataRanges = append(m.DataRanges, DataRange{Symbol: symbol, Interval: interval, From: from, To: to})
+}
+
+// Save 保存清单到<dir>/<run_id>/manifest.json（dir为空时使用DefaultManifestDir），并设为当前清单
+func (m *Manifest) Save(dir string) (string, error) {
+	if dir == "" {
+		dir = DefaultManifestDir
+	}
+	path := filepath.Join(dir, m.RunID, "manifest.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("创建运行清单目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("序列化运行清单失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("写入运行清单失败: %w", err)
+	}
+
+	manifestMutex.Lock()
+	currentManifest = m
+	manifestMutex.Unlock()
+	return path, nil
+}
+
+// Current 当前运行的清单（未保存时返回nil）
+func Current() *Manifest {
+	manifestMutex.RLock()
+	defer manifestMutex.RUnlock()
+	return currentManifest
+}
+
+// redact 脱敏配置中的密钥字段（字段名包含key/secret/private/token/password）
+func redact(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if s, ok := item.(string); ok && s != "" && isSecretKey(key) {
+				v[key] = "***"
+				continue
+			}
+			redact(item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			redact(item)
+		}
+	}
+}
+
+// isSecretKey 字段名是否表示密钥
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"key", "secret", "private", "token", "password"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
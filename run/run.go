@@ -0,0 +1,105 @@
+package run
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 运行标识与随机数种子
+// 所有需要随机性的地方（重试抖动、采样、蒙特卡洛模拟等）都应通过本包获取随机数：
+// 每个用途使用独立的随机数流（按名称由种子派生），互不影响调用顺序，
+// 相同种子下各流产生的序列完全相同，从而可以按清单中记录的种子复现一次运行
+var (
+	mu      sync.Mutex
+	id      string
+	seed    int64
+	started time.Time
+	streams map[string]*rand.Rand
+)
+
+// Start 开始一次运行（seed为0时随机生成），返回运行ID；重复调用会开始新的运行
+func Start(seedValue int64) string {
+	mu.Lock()
+	defer mu.Unlock()
+	start(seedValue)
+	log.Printf("🎲 运行ID: %s（随机种子 %d）", id, seed)
+	return id
+}
+
+// start 初始化运行（调用方持有锁）
+func start(seedValue int64) {
+	started = time.Now()
+	if seedValue == 0 {
+		seedValue = started.UnixNano()
+	}
+	seed = seedValue
+	id = fmt.Sprintf("%s-%04x", started.Format("20060102-150405"), uint64(seed)&0xffff)
+	streams = make(map[string]*rand.Rand)
+}
+
+// ensure 未调用Start时自动开始一次随机种子的运行（调用方持有锁）
+func ensure() {
+	if id == "" {
+		start(0)
+	}
+}
+
+// ID 当前运行ID
+func ID() string {
+	mu.Lock()
+	defer mu.Unlock()
+	ensure()
+	return id
+}
+
+// Seed 当前运行的随机数种子
+func Seed() int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	ensure()
+	return seed
+}
+
+// StartedAt 当前运行的开始时间
+func StartedAt() time.Time {
+	mu.Lock()
+	defer mu.Unlock()
+	ensure()
+	return started
+}
+
+// stream 按名称获取随机数流（调用方持有锁）
+func stream(name string) *rand.Rand {
+	ensure()
+	r, ok := streams[name]
+	if !ok {
+		h := fnv.New64a()
+		h.Write([]byte(name))
+		r = rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+		streams[name] = r
+	}
+	return r
+}
+
+// Float64 从指定随机数流取[0,1)的随机数
+func Float64(name string) float64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return stream(name).Float64()
+}
+
+// Intn 从指定随机数流取[0,n)的随机整数
+func Intn(name string, n int) int {
+	mu.Lock()
+	defer mu.Unlock()
+	return stream(name).Intn(n)
+}
+
+// Jitter 在d的基础上加入±fraction比例的随机抖动（例如fraction=0.2时结果在[0.8d, 1.2d)）
+func Jitter(name string, d time.Duration, fraction float64) time.Duration {
+	return d + time.Duration((Float64(name)*2-1)*fraction*float64(d))
+}
@@ -0,0 +1,179 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultKeyEnv 加密密钥默认环境变量
+const DefaultKeyEnv = "NOFX_ENCRYPTION_KEY"
+
+// Prefix 配置文件中加密字符串的前缀（enc:<base64(nonce+密文)>）
+const Prefix = "enc:"
+
+// fileMagic 加密文件头（之后为nonce和密文），没有该文件头的文件按明文读取
+var fileMagic = []byte("NOFXENC1")
+
+// Key 从环境变量读取AES-256密钥（32字节，base64或hex编码），envName为空时使用DefaultKeyEnv
+func Key(envName string) ([]byte, error) {
+	if envName == "" {
+		envName = DefaultKeyEnv
+	}
+	value := strings.TrimSpace(os.Getenv(envName))
+	if value == "" {
+		return nil, fmt.Errorf("加密密钥环境变量 %s 未设置", envName)
+	}
+	key, err := ParseKey(value)
+	if err != nil {
+		return nil, fmt.Errorf("环境变量 %s: %w", envName, err)
+	}
+	return key, nil
+}
+
+// ParseKey 解析32字节密钥（base64或64位hex）
+func ParseKey(value string) ([]byte, error) {
+	if key, err := hex.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("加密密钥必须是32字节（base64或64位hex编码，可用 nofx gen-encryption-key 生成）")
+}
+
+// GenerateKey 生成随机密钥（base64编码）
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("生成密钥失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// seal AES-256-GCM加密，返回nonce+密文
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("生成nonce失败: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open 解密nonce+密文（密钥错误或内容被修改时返回错误）
+func open(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("密文长度不足")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("解密失败（密钥错误或内容被修改）")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("无效的加密密钥: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptString 加密字符串，返回enc:前缀的密文（用于配置文件中的密钥）
+func EncryptString(key []byte, plaintext string) (string, error) {
+	data, err := seal(key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return Prefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// IsEncryptedString 是否为enc:前缀的密文
+func IsEncryptedString(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// DecryptString 解密enc:前缀的密文（没有前缀时原样返回）
+func DecryptString(key []byte, value string) (string, error) {
+	if !IsEncryptedString(value) {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil {
+		return "", fmt.Errorf("无效的密文: %w", err)
+	}
+	plaintext, err := open(key, data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// 全局文件加密设置（决策日志、决策快照共用）
+var (
+	fileKey     []byte // 解密已加密文件的密钥（为空时无法读取加密文件）
+	encryptFile bool   // 新写入的文件是否加密
+	fileMutex   sync.RWMutex
+)
+
+// Configure 设置文件加密密钥；encrypt为true时之后写入的文件加密保存（已有的明文文件仍可读取）
+func Configure(key []byte, encrypt bool) {
+	fileMutex.Lock()
+	defer fileMutex.Unlock()
+	fileKey = key
+	encryptFile = encrypt && key != nil
+}
+
+// WriteFile 写入文件（已启用加密时写入文件头+nonce+密文，权限收紧为0600）
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	fileMutex.RLock()
+	key, encrypt := fileKey, encryptFile
+	fileMutex.RUnlock()
+	if !encrypt {
+		return os.WriteFile(path, data, perm)
+	}
+	sealed, err := seal(key, data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(append([]byte{}, fileMagic...), sealed...), 0600)
+}
+
+// ReadFile 读取文件（加密文件自动解密，明文文件原样返回）
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(data)
+}
+
+// decrypt 解密WriteFile写入的内容（没有加密文件头时原样返回）
+func decrypt(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, fileMagic) {
+		return data, nil
+	}
+	fileMutex.RLock()
+	key := fileKey
+	fileMutex.RUnlock()
+	if key == nil {
+		return nil, fmt.Errorf("文件已加密，需设置环境变量 %s（或配置encryption.key_env）", DefaultKeyEnv)
+	}
+	return open(key, data[len(fileMagic):])
+}
@@ -0,0 +1,196 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+
+	"nofx/decision"
+	"nofx/market"
+)
+
+// BreakoutConfig 唐奇安通道突破策略参数
+type BreakoutConfig struct {
+	Symbols     []string `json:"symbols"`      // 扫描的币种（为空时使用候选币种）
+	Interval    string   `json:"interval"`     // K线周期（默认4h）
+	EntryPeriod int      `json:"entry_period"` // 入场通道周期：收盘价突破前N根K线的最高/最低价时开仓（默认20）
+	ExitPeriod  int      `json:"exit_period"`  // 出场通道周期：收盘价跌破/升破前N根K线的最低/最高价时平仓（默认10）
+	ATRPeriod   int      `json:"atr_period"`   // ATR周期（默认14）
+	StopATR     float64  `json:"stop_atr"`     // 止损距离（ATR倍数，默认2）
+	TargetATR   float64  `json:"target_atr"`   // 止盈距离（ATR倍数，默认6）
+	TrendPeriod int      `json:"trend_period"` // 趋势过滤EMA周期：只在收盘价位于EMA同侧时顺势开仓（默认100）
+	SizePct     float64  `json:"size_pct"`     // 仓位名义价值（占净值%，默认10；配置sizing时由仓位模块重新计算）
+	Leverage    int      `json:"leverage"`     // 杠杆（默认3，不超过账户配置的杠杆上限）
+	LongOnly    bool     `json:"long_only"`    // 只做多
+}
+
+// BreakoutStrategy 唐奇安通道突破 + ATR止损 + EMA趋势过滤（不调用AI的确定性参考策略）
+// 只使用已收盘的K线，同一根K线内的决策不变；只在最新一根K线刚突破时开仓，避免止损后在同一段行情中反复追单
+type BreakoutStrategy struct {
+	config  BreakoutConfig
+	entries entryTracker
+}
+
+// NewBreakoutStrategy 创建突破策略（零值字段使用默认参数）
+func NewBreakoutStrategy(config BreakoutConfig) *BreakoutStrategy {
+	if config.Interval == "" {
+		config.Interval = "4h"
+	}
+	if config.EntryPeriod <= 0 {
+		config.EntryPeriod = 20
+	}
+	if config.ExitPeriod <= 0 {
+		config.ExitPeriod = 10
+	}
+	if config.ATRPeriod <= 0 {
+		config.ATRPeriod = 14
+	}
+	if config.StopATR <= 0 {
+		config.StopATR = 2
+	}
+	if config.TargetATR <= 0 {
+		config.TargetATR = 6
+	}
+	if config.TrendPeriod <= 0 {
+		config.TrendPeriod = 100
+	}
+	if config.SizePct <= 0 {
+		config.SizePct = 10
+	}
+	if config.Leverage <= 0 {
+		config.Leverage = 3
+	}
+	return &BreakoutStrategy{config: config, entries: newEntryTracker()}
+}
+
+// newBreakoutStrategy 按JSON参数创建突破策略
+func newBreakoutStrategy(params json.RawMessage) (Strategy, error) {
+	var config BreakoutConfig
+	if err := decodeParams(params, &config); err != nil {
+		return nil, err
+	}
+	if config.StopATR < 0 || config.TargetATR < 0 || config.SizePct < 0 || config.Leverage < 0 {
+		return nil, fmt.Errorf("突破策略参数不能为负数")
+	}
+	if config.TargetATR > 0 && config.StopATR > 0 && config.TargetATR < config.StopATR*3 {
+		return nil, fmt.Errorf("突破策略的target_atr至少为stop_atr的3倍（风险回报比≥3:1）")
+	}
+	return NewBreakoutStrategy(config), nil
+}
+
+// Decide 检查各币种的通道突破（开仓）和反向通道突破（平仓）
+func (s *BreakoutStrategy) Decide(ctx *decision.Context) ([]decision.Decision, error) {
+	c := s.config
+	held := positionSides(ctx)
+	limit := max(2*c.TrendPeriod, c.EntryPeriod+c.ATRPeriod) + 2
+
+	var decisions []decision.Decision
+	for _, symbol := range strategySymbols(c.Symbols, ctx) {
+		klines, err := completedKlines(symbol, c.Interval, limit)
+		if err != nil {
+			log.Printf("⚠️  突破策略获取 %s K线失败: %v", symbol, err)
+			continue
+		}
+		n := len(klines)
+		if n < c.EntryPeriod+2 || n <= c.ExitPeriod || n <= c.ATRPeriod || n < c.TrendPeriod {
+			continue
+		}
+		last := klines[n-1]
+
+		// 持仓：本策略开的仓在收盘价反向突破出场通道时平仓
+		if side, ok := held[symbol]; ok {
+			if !s.entries.owns(symbol, side) {
+				continue
+			}
+			exitHigh, exitLow := channel(klines[n-1-c.ExitPeriod : n-1])
+			if side == "long" && last.Close < exitLow {
+				decisions = append(decisions, decision.Decision{
+					Symbol:    symbol,
+					Action:    "close_long",
+					Reasoning: fmt.Sprintf("收盘价 %.4f 跌破%d周期低点 %.4f，多头突破结束", last.Close, c.ExitPeriod, exitLow),
+				})
+			}
+			if side == "short" && last.Close > exitHigh {
+				decisions = append(decisions, decision.Decision{
+					Symbol:    symbol,
+					Action:    "close_short",
+					Reasoning: fmt.Sprintf("收盘价 %.4f 升破%d周期高点 %.4f，空头突破结束", last.Close, c.ExitPeriod, exitHigh),
+				})
+			}
+			continue
+		}
+		s.entries.expire(symbol, last.OpenTime)
+
+		// 空仓：最新一根K线刚突破入场通道，且与趋势EMA同向时开仓
+		upper, lower := channel(klines[n-1-c.EntryPeriod : n-1])
+		prevUpper, prevLower := channel(klines[n-2-c.EntryPeriod : n-2])
+		prevClose := klines[n-2].Close
+		ema := market.EMASeries(closePrices(klines), c.TrendPeriod)[n-1]
+		atr, atrOK := market.ATR(klines, c.ATRPeriod)
+		if !atrOK || atr <= 0 || math.IsNaN(ema) {
+			continue
+		}
+
+		var d *decision.Decision
+		switch {
+		case last.Close > upper && prevClose <= prevUpper && last.Close > ema:
+			d = s.open(ctx, symbol, "open_long", last.Close, last.Close-c.StopATR*atr, last.Close+c.TargetATR*atr)
+			d.Reasoning = fmt.Sprintf("收盘价 %.4f 突破%d周期高点 %.4f，位于EMA%d（%.4f）上方；止损%.1f×ATR",
+				last.Close, c.EntryPeriod, upper, c.TrendPeriod, ema, c.StopATR)
+		case !c.LongOnly && last.Close < lower && prevClose >= prevLower && last.Close < ema:
+			d = s.open(ctx, symbol, "open_short", last.Close, last.Close+c.StopATR*atr, last.Close-c.TargetATR*atr)
+			d.Reasoning = fmt.Sprintf("收盘价 %.4f 跌破%d周期低点 %.4f，位于EMA%d（%.4f）下方；止损%.1f×ATR",
+				last.Close, c.EntryPeriod, lower, c.TrendPeriod, ema, c.StopATR)
+		}
+		if d != nil && s.entries.signal(symbol, last.OpenTime, d.Action) {
+			decisions = append(decisions, *d)
+		}
+	}
+	return decisions, nil
+}
+
+// open 生成开仓决策（仓位按净值百分比，杠杆不超过账户配置的上限）
+func (s *BreakoutStrategy) open(ctx *decision.Context, symbol, action string, price, stopLoss, takeProfit float64) *decision.Decision {
+	return &decision.Decision{
+		Symbol:          symbol,
+		Action:          action,
+		Leverage:        cappedLeverage(ctx, symbol, s.config.Leverage),
+		PositionSizeUSD: ctx.Account.TotalEquity * s.config.SizePct / 100,
+		StopLoss:        stopLoss,
+		TakeProfit:      takeProfit,
+		RiskUSD:         ctx.Account.TotalEquity * s.config.SizePct / 100 * math.Abs(price-stopLoss) / price,
+		Tags:            []string{"breakout"},
+	}
+}
+
+// channel 一组K线的最高价和最低价
+func channel(klines []market.Kline) (float64, float64) {
+	high, low := math.Inf(-1), math.Inf(1)
+	for _, k := range klines {
+		high = math.Max(high, k.High)
+		low = math.Min(low, k.Low)
+	}
+	return high, low
+}
+
+// closePrices K线收盘价序列
+func closePrices(klines []market.Kline) []float64 {
+	values := make([]float64, len(klines))
+	for i, k := range klines {
+		values[i] = k.Close
+	}
+	return values
+}
+
+// cappedLeverage 杠杆不超过账户对该币种配置的上限（BTC/ETH与山寨币分开配置）
+func cappedLeverage(ctx *decision.Context, symbol string, leverage int) int {
+	limit := ctx.AltcoinLeverage
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		limit = ctx.BTCETHLeverage
+	}
+	if limit > 0 && leverage > limit {
+		return limit
+	}
+	return leverage
+}
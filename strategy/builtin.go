@@ -0,0 +1,120 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"nofx/decision"
+	"nofx/market"
+)
+
+// builtins 内置参考策略（名称 -> 构造函数，params为配置中的JSON参数，可为空）
+var builtins = map[string]func(params json.RawMessage) (Strategy, error){
+	"breakout":       newBreakoutStrategy,
+	"mean_reversion": newMeanReversionStrategy,
+}
+
+// LoadBuiltin 按名称创建内置策略
+func LoadBuiltin(name string, params json.RawMessage) (Strategy, error) {
+	newStrategy, ok := builtins[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的内置策略: %s（可选: %v）", name, BuiltinNames())
+	}
+	return newStrategy(params)
+}
+
+// BuiltinNames 全部内置策略名称
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// decodeParams 把JSON参数解码到已填好默认值的配置上
+func decodeParams(params json.RawMessage, config interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, config); err != nil {
+		return fmt.Errorf("解析策略参数失败: %w", err)
+	}
+	return nil
+}
+
+// completedKlines 获取已走完的K线（去掉未收盘的最后一根），保证同一根K线内决策不变
+func completedKlines(symbol, interval string, limit int) ([]market.Kline, error) {
+	klines, err := market.GetProvider().GetKlines(symbol, interval, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(klines); n > 0 && klines[n-1].CloseTime > market.Clock.Now().UnixMilli() {
+		klines = klines[:n-1]
+	}
+	if len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+	return klines, nil
+}
+
+// strategySymbols 策略扫描的币种：配置了symbols时使用配置，否则使用本周期的候选币种
+func strategySymbols(configured []string, ctx *decision.Context) []string {
+	if len(configured) > 0 {
+		symbols := make([]string, len(configured))
+		for i, symbol := range configured {
+			symbols[i] = market.Normalize(symbol)
+		}
+		return symbols
+	}
+	symbols := make([]string, 0, len(ctx.CandidateCoins))
+	for _, coin := range ctx.CandidateCoins {
+		symbols = append(symbols, coin.Symbol)
+	}
+	return symbols
+}
+
+// positionSides 当前持仓（symbol -> 方向）
+func positionSides(ctx *decision.Context) map[string]string {
+	sides := make(map[string]string, len(ctx.Positions))
+	for _, pos := range ctx.Positions {
+		sides[pos.Symbol] = pos.Side
+	}
+	return sides
+}
+
+// entryTracker 记录内置策略自己发出的开仓信号：每根K线只开仓一次，只平自己开的仓（不干预AI和其他策略的持仓）
+type entryTracker struct {
+	signalled map[string]int64  // symbol -> 已发出开仓信号的K线开盘时间
+	opened    map[string]string // symbol -> 开仓信号的方向
+}
+
+// newEntryTracker 创建开仓信号记录
+func newEntryTracker() entryTracker {
+	return entryTracker{signalled: make(map[string]int64), opened: make(map[string]string)}
+}
+
+// owns 该方向的持仓是否由本策略开仓
+func (t *entryTracker) owns(symbol, side string) bool {
+	return t.opened[symbol] == side
+}
+
+// expire 币种无持仓时调用：信号K线已过去仍无持仓，说明持仓已平或开仓信号未成交
+func (t *entryTracker) expire(symbol string, barOpen int64) {
+	if t.signalled[symbol] != barOpen {
+		delete(t.opened, symbol)
+	}
+}
+
+// signal 记录开仓信号，同一根K线已发出过信号时返回false
+func (t *entryTracker) signal(symbol string, barOpen int64, action string) bool {
+	if t.signalled[symbol] == barOpen {
+		return false
+	}
+	t.signalled[symbol] = barOpen
+	t.opened[symbol] = strings.TrimPrefix(action, "open_")
+	return true
+}
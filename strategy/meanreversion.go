@@ -0,0 +1,216 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+
+	"nofx/decision"
+	"nofx/market"
+	"nofx/risk"
+)
+
+// MeanReversionConfig 布林带/RSI均值回归策略参数
+type MeanReversionConfig struct {
+	Symbols    []string `json:"symbols"`    // 扫描的币种（为空时使用候选币种）
+	Interval   string   `json:"interval"`   // 入场K线周期（默认15m）
+	BBPeriod   int      `json:"bb_period"`  // 布林带周期（默认20）
+	BBStdDev   float64  `json:"bb_std_dev"` // 布林带宽度（标准差倍数，默认2）
+	RSIPeriod  int      `json:"rsi_period"` // RSI周期（默认14）
+	Oversold   float64  `json:"oversold"`   // RSI低于该值视为超卖（默认30）
+	Overbought float64  `json:"overbought"` // RSI高于该值视为超买（默认70）
+	ATRPeriod  int      `json:"atr_period"` // 止损ATR周期（入场周期，默认14）
+	StopATR    float64  `json:"stop_atr"`   // 止损距离（ATR倍数，默认1.5）
+	RiskPct    float64  `json:"risk_pct"`   // 每笔风险（占净值%，默认0.5），按止损距离计算仓位
+	Leverage   int      `json:"leverage"`   // 杠杆（默认3，不超过账户配置的杠杆上限）
+	MaxHurst   float64  `json:"max_hurst"`  // 趋势周期Hurst指数高于该值（趋势性强）时不开仓（默认0.6，0不过滤）
+	LongOnly   bool     `json:"long_only"`  // 只做多
+}
+
+// MeanReversionStrategy 入场周期布林带 + RSI均值回归，趋势周期EMA过滤方向（不调用AI的确定性参考策略）
+// 趋势判断使用周期上下文中的市场数据（trader配置的趋势周期，默认4h），入场信号使用入场周期的已收盘K线；
+// 多头趋势中只在超卖时做多、空头趋势中只在超买时做空，止盈为布林带中轨
+type MeanReversionStrategy struct {
+	config  MeanReversionConfig
+	entries entryTracker
+}
+
+// NewMeanReversionStrategy 创建均值回归策略（零值字段使用默认参数）
+func NewMeanReversionStrategy(config MeanReversionConfig) *MeanReversionStrategy {
+	if config.Interval == "" {
+		config.Interval = "15m"
+	}
+	if config.BBPeriod <= 0 {
+		config.BBPeriod = 20
+	}
+	if config.BBStdDev <= 0 {
+		config.BBStdDev = 2
+	}
+	if config.RSIPeriod <= 0 {
+		config.RSIPeriod = 14
+	}
+	if config.Oversold <= 0 {
+		config.Oversold = 30
+	}
+	if config.Overbought <= 0 {
+		config.Overbought = 70
+	}
+	if config.ATRPeriod <= 0 {
+		config.ATRPeriod = 14
+	}
+	if config.StopATR <= 0 {
+		config.StopATR = 1.5
+	}
+	if config.RiskPct <= 0 {
+		config.RiskPct = 0.5
+	}
+	if config.Leverage <= 0 {
+		config.Leverage = 3
+	}
+	if config.MaxHurst == 0 {
+		config.MaxHurst = 0.6
+	}
+	return &MeanReversionStrategy{config: config, entries: newEntryTracker()}
+}
+
+// newMeanReversionStrategy 按JSON参数创建均值回归策略
+func newMeanReversionStrategy(params json.RawMessage) (Strategy, error) {
+	var config MeanReversionConfig
+	if err := decodeParams(params, &config); err != nil {
+		return nil, err
+	}
+	if config.StopATR < 0 || config.RiskPct < 0 || config.Leverage < 0 || config.MaxHurst < 0 {
+		return nil, fmt.Errorf("均值回归策略参数不能为负数")
+	}
+	if config.Oversold != 0 && config.Overbought != 0 && config.Oversold >= config.Overbought {
+		return nil, fmt.Errorf("均值回归策略的oversold必须小于overbought")
+	}
+	return NewMeanReversionStrategy(config), nil
+}
+
+// Decide 趋势周期判断方向，入场周期在布林带外且RSI极值时逆短期走势开仓；趋势反转时平掉本策略的持仓
+func (s *MeanReversionStrategy) Decide(ctx *decision.Context) ([]decision.Decision, error) {
+	c := s.config
+	held := positionSides(ctx)
+	// 全局熔断期间不开新仓（平仓不受影响）
+	tripped, _ := risk.Breaker.Tripped()
+	limit := max(c.BBPeriod, c.RSIPeriod, c.ATRPeriod) * 3
+
+	var decisions []decision.Decision
+	for _, symbol := range strategySymbols(c.Symbols, ctx) {
+		data := ctx.MarketDataMap[symbol]
+		if data == nil {
+			var err error
+			if data, err = market.Get(symbol); err != nil {
+				log.Printf("⚠️  均值回归策略获取 %s 市场数据失败: %v", symbol, err)
+				continue
+			}
+		}
+		trend := data.LongerTermContext
+		if trend == nil || !trend.Available("ema_fast") || !trend.Available("ema_slow") {
+			continue
+		}
+		uptrend := trend.EMA20 > trend.EMA50
+
+		// 持仓：趋势周期反转时平掉本策略的持仓（正常情况下由中轨止盈平仓）
+		if side, ok := held[symbol]; ok {
+			if !s.entries.owns(symbol, side) {
+				continue
+			}
+			if side == "long" && !uptrend {
+				decisions = append(decisions, decision.Decision{
+					Symbol:    symbol,
+					Action:    "close_long",
+					Reasoning: fmt.Sprintf("%s EMA20（%.4f）跌破EMA50（%.4f），多头趋势结束", data.TrendInterval, trend.EMA20, trend.EMA50),
+				})
+			}
+			if side == "short" && uptrend {
+				decisions = append(decisions, decision.Decision{
+					Symbol:    symbol,
+					Action:    "close_short",
+					Reasoning: fmt.Sprintf("%s EMA20（%.4f）升破EMA50（%.4f），空头趋势结束", data.TrendInterval, trend.EMA20, trend.EMA50),
+				})
+			}
+			continue
+		}
+		if tripped {
+			continue
+		}
+		if c.MaxHurst > 0 && trend.Available("hurst") && trend.Hurst > c.MaxHurst {
+			continue // 趋势性过强，均值回归容易被单边行情止损
+		}
+
+		klines, err := completedKlines(symbol, c.Interval, limit)
+		if err != nil {
+			log.Printf("⚠️  均值回归策略获取 %s K线失败: %v", symbol, err)
+			continue
+		}
+		n := len(klines)
+		if n < c.BBPeriod || n <= c.RSIPeriod+1 || n <= c.ATRPeriod {
+			continue
+		}
+		last := klines[n-1]
+		s.entries.expire(symbol, last.OpenTime)
+
+		closes := closePrices(klines)
+		middle, std := meanStd(closes[n-c.BBPeriod:])
+		upper, lower := middle+c.BBStdDev*std, middle-c.BBStdDev*std
+		rsi := market.RSISeries(closes, c.RSIPeriod)[n-1]
+		atr, atrOK := market.ATR(klines, c.ATRPeriod)
+		if !atrOK || atr <= 0 || math.IsNaN(rsi) {
+			continue
+		}
+
+		var d *decision.Decision
+		switch {
+		case uptrend && last.Close < lower && rsi < c.Oversold:
+			d = s.open(ctx, symbol, "open_long", last.Close, last.Close-c.StopATR*atr, middle)
+			d.Reasoning = fmt.Sprintf("%s多头趋势中%s超卖：收盘价 %.4f 低于布林下轨 %.4f，RSI %.1f；止盈中轨 %.4f",
+				data.TrendInterval, c.Interval, last.Close, lower, rsi, middle)
+		case !c.LongOnly && !uptrend && last.Close > upper && rsi > c.Overbought:
+			d = s.open(ctx, symbol, "open_short", last.Close, last.Close+c.StopATR*atr, middle)
+			d.Reasoning = fmt.Sprintf("%s空头趋势中%s超买：收盘价 %.4f 高于布林上轨 %.4f，RSI %.1f；止盈中轨 %.4f",
+				data.TrendInterval, c.Interval, last.Close, upper, rsi, middle)
+		}
+		if d != nil && s.entries.signal(symbol, last.OpenTime, d.Action) {
+			decisions = append(decisions, *d)
+		}
+	}
+	return decisions, nil
+}
+
+// open 生成开仓决策：按每笔风险和止损距离计算仓位，不超过单币种仓位上限
+func (s *MeanReversionStrategy) open(ctx *decision.Context, symbol, action string, price, stopLoss, takeProfit float64) *decision.Decision {
+	equity := ctx.Account.TotalEquity
+	riskUSD := equity * s.config.RiskPct / 100
+	size := riskUSD / (math.Abs(price-stopLoss) / price)
+	if maxSize := decision.MaxPositionValue(symbol, equity, ctx.SymbolOverrides[symbol]); size > maxSize {
+		size = maxSize
+		riskUSD = size * math.Abs(price-stopLoss) / price
+	}
+	return &decision.Decision{
+		Symbol:          symbol,
+		Action:          action,
+		Leverage:        cappedLeverage(ctx, symbol, s.config.Leverage),
+		PositionSizeUSD: size,
+		StopLoss:        stopLoss,
+		TakeProfit:      takeProfit,
+		RiskUSD:         riskUSD,
+		Tags:            []string{"mean_reversion"},
+	}
+}
+
+// meanStd 均值和总体标准差
+func meanStd(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
@@ -0,0 +1,269 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"nofx/decision"
+	"nofx/events"
+)
+
+const (
+	defaultTimeout      = 5 * time.Second // 单次决策默认超时
+	defaultMaxDecisions = 10              // 单次决策默认最多返回的决策数
+	maxFailures         = 3               // 连续失败（报错/panic/超时）次数达到后自动停用
+)
+
+// 策略运行模式
+const (
+	ModeActive     = "active"      // 正常运行
+	ModeManageOnly = "manage_only" // 已停用但仍有持仓：只执行该策略持仓的平仓决策，持仓全部平仓后转为disabled
+	ModeDisabled   = "disabled"    // 不再调用
+)
+
+// Limits 策略执行限制（零值使用默认值）
+type Limits struct {
+	Timeout      time.Duration // 单次决策超时
+	MaxDecisions int           // 单次最多返回的决策数（超出部分丢弃）
+}
+
+// ContextStrategy 可中途取消的策略（如脚本策略）：沙箱超时时取消run，策略应尽快返回
+type ContextStrategy interface {
+	DecideContext(run context.Context, ctx *decision.Context) ([]decision.Decision, error)
+}
+
+// Reloader 可在运行时重新加载的策略（如脚本策略）
+type Reloader interface {
+	Reload() error
+}
+
+// Sandbox 策略沙箱：捕获panic、限制执行时间和返回的决策数，连续失败后自动停用
+// Go无法强制终止goroutine，超时的调用（ContextStrategy除外）会在后台继续运行，返回前不会再次调用该策略
+type Sandbox struct {
+	id       string
+	strategy Strategy
+	limits   Limits
+
+	mu        sync.Mutex
+	mode      string
+	busy      bool // 上一次调用尚未返回（超时后仍在运行）
+	failures  int
+	disabled  string // 停用原因
+	lastError string
+	calls     int
+}
+
+// Status 策略运行状态
+type Status struct {
+	ID        string `json:"id"`
+	Mode      string `json:"mode"`               // active、manage_only 或 disabled
+	Disabled  string `json:"disabled,omitempty"` // 停用原因
+	Busy      bool   `json:"busy"`               // 上一次调用超时后仍在运行
+	Calls     int    `json:"calls"`
+	Failures  int    `json:"failures"` // 连续失败次数
+	LastError string `json:"last_error,omitempty"`
+}
+
+// NewSandbox 创建策略沙箱
+func NewSandbox(id string, strategy Strategy, limits Limits) *Sandbox {
+	if limits.Timeout <= 0 {
+		limits.Timeout = defaultTimeout
+	}
+	if limits.MaxDecisions <= 0 {
+		limits.MaxDecisions = defaultMaxDecisions
+	}
+	return &Sandbox{id: id, strategy: strategy, limits: limits, mode: ModeActive}
+}
+
+// ID 策略ID
+func (s *Sandbox) ID() string {
+	return s.id
+}
+
+// result 一次策略调用的结果
+type result struct {
+	decisions []decision.Decision
+	err       error
+}
+
+// Decide 在沙箱中调用策略，返回的决策已填充策略ID
+func (s *Sandbox) Decide(ctx *decision.Context) ([]decision.Decision, error) {
+	s.mu.Lock()
+	if s.mode == ModeDisabled {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("策略 %s 已停用: %s", s.id, s.disabled)
+	}
+	if s.busy {
+		s.mu.Unlock()
+		return nil, s.fail(fmt.Errorf("上一次调用仍未返回"))
+	}
+	s.busy = true
+	s.calls++
+	s.mu.Unlock()
+
+	// 策略使用上下文的浅拷贝：超时返回后调用方继续使用原上下文，不与后台仍在运行的调用共享
+	snapshot := *ctx
+	run, cancel := context.WithCancelCause(context.Background())
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("💥 策略 %s panic: %v\n%s", s.id, r, debug.Stack())
+				done <- result{err: fmt.Errorf("panic: %v", r)}
+			}
+			s.mu.Lock()
+			s.busy = false
+			s.mu.Unlock()
+		}()
+		var decisions []decision.Decision
+		var err error
+		if cs, ok := s.strategy.(ContextStrategy); ok {
+			decisions, err = cs.DecideContext(run, &snapshot)
+		} else {
+			decisions, err = s.strategy.Decide(&snapshot)
+		}
+		done <- result{decisions: decisions, err: err}
+	}()
+
+	timer := time.NewTimer(s.limits.Timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		cancel(nil)
+		if r.err != nil {
+			return nil, s.fail(r.err)
+		}
+		return s.succeed(r.decisions), nil
+	case <-timer.C:
+		err := fmt.Errorf("执行超时（%v）", s.limits.Timeout)
+		cancel(errors.New("执行超时"))
+		return nil, s.fail(err)
+	}
+}
+
+// succeed 记录成功调用，截断并标注决策
+func (s *Sandbox) succeed(decisions []decision.Decision) []decision.Decision {
+	s.mu.Lock()
+	s.failures = 0
+	s.lastError = ""
+	s.mu.Unlock()
+
+	if len(decisions) > s.limits.MaxDecisions {
+		log.Printf("⚠️  策略 %s 返回 %d 个决策，超出上限 %d，多余部分已丢弃", s.id, len(decisions), s.limits.MaxDecisions)
+		decisions = decisions[:s.limits.MaxDecisions]
+	}
+	for i := range decisions {
+		if decisions[i].StrategyID == "" {
+			decisions[i].StrategyID = s.id
+		}
+	}
+	return decisions
+}
+
+// fail 记录失败调用，连续失败达到上限时停用策略
+func (s *Sandbox) fail(err error) error {
+	s.mu.Lock()
+	s.failures++
+	s.lastError = err.Error()
+	failures := s.failures
+	if failures >= maxFailures {
+		s.mode = ModeDisabled
+		s.disabled = fmt.Sprintf("连续失败%d次，最后一次: %v", failures, err)
+	}
+	s.mu.Unlock()
+
+	err = fmt.Errorf("策略 %s 执行失败: %w", s.id, err)
+	if failures >= maxFailures {
+		log.Printf("🚫 策略 %s 连续失败%d次，已停用: %v", s.id, failures, err)
+		events.Publish(events.Event{
+			Type:     events.TypeStrategyDisabled,
+			Severity: events.SeverityCritical,
+			Message:  fmt.Sprintf("策略 %s 连续失败%d次，已停用", s.id, failures),
+			Data: map[string]interface{}{
+				"strategy_id": s.id,
+				"error":       err.Error(),
+			},
+		})
+	}
+	return err
+}
+
+// Status 当前运行状态
+func (s *Sandbox) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Status{
+		ID:        s.id,
+		Mode:      s.mode,
+		Disabled:  s.disabled,
+		Busy:      s.busy,
+		Calls:     s.calls,
+		Failures:  s.failures,
+		LastError: s.lastError,
+	}
+}
+
+// Mode 当前运行模式
+func (s *Sandbox) Mode() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mode
+}
+
+// Enable 启用策略（清除连续失败计数）
+func (s *Sandbox) Enable() {
+	s.mu.Lock()
+	s.mode = ModeActive
+	s.disabled = ""
+	s.failures = 0
+	s.mu.Unlock()
+	log.Printf("▶️  策略 %s 已启用", s.id)
+}
+
+// Disable 停用策略：转为只管理已有持仓（由trader在持仓全部平仓后调用Retire）
+func (s *Sandbox) Disable(reason string) {
+	s.mu.Lock()
+	if s.mode == ModeActive {
+		s.mode = ModeManageOnly
+		s.disabled = reason
+	}
+	s.mu.Unlock()
+	log.Printf("⏸ 策略 %s 已停用（%s），已有持仓转为只管理模式", s.id, reason)
+}
+
+// Retire 完全停用策略（不再调用）
+func (s *Sandbox) Retire(reason string) {
+	s.mu.Lock()
+	s.mode = ModeDisabled
+	s.disabled = reason
+	s.mu.Unlock()
+	log.Printf("⏹ 策略 %s 已完全停用: %s", s.id, reason)
+}
+
+// Reload 重新加载策略（只支持脚本策略；Go插件无法在运行时卸载，需要重启）
+func (s *Sandbox) Reload() error {
+	reloader, ok := s.strategy.(Reloader)
+	if !ok {
+		return fmt.Errorf("策略 %s 不支持运行时重新加载（Go插件需要重启）", s.id)
+	}
+	s.mu.Lock()
+	busy := s.busy
+	s.mu.Unlock()
+	if busy {
+		return fmt.Errorf("策略 %s 正在执行，请稍后重试", s.id)
+	}
+	if err := reloader.Reload(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.failures = 0
+	s.lastError = ""
+	s.mu.Unlock()
+	log.Printf("🔄 策略 %s 已重新加载", s.id)
+	return nil
+}
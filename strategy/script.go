@@ -0,0 +1,495 @@
+package strategy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"nofx/decision"
+	"nofx/market"
+
+	starlarkmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// defaultMaxSteps 脚本单次执行的默认最大步数（防止死循环占满CPU）
+const defaultMaxSteps = 10_000_000
+
+// signalsKey 线程局部变量：本次决策收集到的信号
+const signalsKey = "signals"
+
+// ScriptStrategy Starlark脚本策略
+// 脚本定义 decide(ctx)，通过 signal.open_long(...) 等函数给出决策（也可以直接返回决策dict列表）；
+// 脚本文件修改后在下一次决策前自动重新加载，无需重新编译或重启
+type ScriptStrategy struct {
+	path     string
+	maxSteps uint64
+
+	mu      sync.Mutex
+	decide  starlark.Callable
+	modTime time.Time
+}
+
+// LoadScript 加载Starlark脚本策略（maxSteps为0时使用默认值）
+func LoadScript(path string, maxSteps uint64) (*ScriptStrategy, error) {
+	if maxSteps == 0 {
+		maxSteps = defaultMaxSteps
+	}
+	s := &ScriptStrategy{path: path, maxSteps: maxSteps}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load 执行脚本顶层代码并取出decide函数
+func (s *ScriptStrategy) load() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("读取策略脚本失败: %w", err)
+	}
+	thread := s.newThread()
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, s.path, nil, scriptPredeclared)
+	if err != nil {
+		return fmt.Errorf("加载策略脚本 %s 失败: %s", s.path, scriptError(err))
+	}
+	decide, ok := globals["decide"].(starlark.Callable)
+	if !ok {
+		return fmt.Errorf("策略脚本 %s 未定义 decide(ctx) 函数", s.path)
+	}
+
+	s.mu.Lock()
+	s.decide = decide
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+	return nil
+}
+
+// reload 脚本文件修改后重新加载（加载失败时继续使用上一版本）
+func (s *ScriptStrategy) reload() {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	changed := !info.ModTime().Equal(s.modTime)
+	s.mu.Unlock()
+	if !changed {
+		return
+	}
+	if err := s.load(); err != nil {
+		log.Printf("⚠️  %v（继续使用上一版本）", err)
+		return
+	}
+	log.Printf("🔄 策略脚本 %s 已重新加载", s.path)
+}
+
+// Reload 立即重新加载脚本（加载失败时继续使用上一版本）
+func (s *ScriptStrategy) Reload() error {
+	return s.load()
+}
+
+// newThread 创建执行线程（限制最大步数，print输出到日志）
+func (s *ScriptStrategy) newThread() *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: s.path,
+		Print: func(_ *starlark.Thread, msg string) {
+			log.Printf("📜 [%s] %s", s.path, msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(s.maxSteps)
+	return thread
+}
+
+// Decide 调用脚本的decide(ctx)
+func (s *ScriptStrategy) Decide(ctx *decision.Context) ([]decision.Decision, error) {
+	return s.DecideContext(context.Background(), ctx)
+}
+
+// DecideContext 调用脚本的decide(ctx)，run取消时（沙箱超时）取消执行线程，脚本在下一步执行时返回
+func (s *ScriptStrategy) DecideContext(run context.Context, ctx *decision.Context) ([]decision.Decision, error) {
+	s.reload()
+
+	var decisions []decision.Decision
+	thread := s.newThread()
+	thread.SetLocal(signalsKey, &decisions)
+	// run在调用前已取消时立即取消线程
+	stop := context.AfterFunc(run, func() {
+		thread.Cancel(context.Cause(run).Error())
+	})
+	defer stop()
+
+	s.mu.Lock()
+	decide := s.decide
+	s.mu.Unlock()
+
+	result, err := starlark.Call(thread, decide, starlark.Tuple{scriptContext(ctx)}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s", scriptError(err))
+	}
+
+	// decide也可以直接返回决策列表
+	if list, ok := result.(*starlark.List); ok {
+		for i := 0; i < list.Len(); i++ {
+			d, err := decisionFromValue(list.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("decide返回的第%d个决策无效: %w", i+1, err)
+			}
+			decisions = append(decisions, d)
+		}
+	}
+	return decisions, nil
+}
+
+// scriptError 脚本错误（包含脚本调用栈）
+func scriptError(err error) string {
+	if evalErr, ok := err.(*starlark.EvalError); ok {
+		return evalErr.Backtrace()
+	}
+	return err.Error()
+}
+
+// scriptPredeclared 脚本可用的内置模块
+var scriptPredeclared = starlark.StringDict{
+	"math": starlarkmath.Module,
+	"signal": &starlarkstruct.Module{
+		Name: "signal",
+		Members: starlark.StringDict{
+			"open_long":       starlark.NewBuiltin("open_long", openSignal("open_long")),
+			"open_short":      starlark.NewBuiltin("open_short", openSignal("open_short")),
+			"close_long":      starlark.NewBuiltin("close_long", closeSignal("close_long")),
+			"close_short":     starlark.NewBuiltin("close_short", closeSignal("close_short")),
+			"open_pair_long":  starlark.NewBuiltin("open_pair_long", openPairSignal(decision.ActionOpenPairLong)),
+			"open_pair_short": starlark.NewBuiltin("open_pair_short", openPairSignal(decision.ActionOpenPairShort)),
+			"close_pair":      starlark.NewBuiltin("close_pair", closeSignal(decision.ActionClosePair)),
+		},
+	},
+	"ta": &starlarkstruct.Module{
+		Name: "ta",
+		Members: starlark.StringDict{
+			"ema":    starlark.NewBuiltin("ema", seriesIndicator(market.EMASeries)),
+			"sma":    starlark.NewBuiltin("sma", seriesIndicator(market.SMASeries)),
+			"rsi":    starlark.NewBuiltin("rsi", seriesIndicator(market.RSISeries)),
+			"klines": starlark.NewBuiltin("klines", scriptKlines),
+			"spread": starlark.NewBuiltin("spread", scriptSpread),
+		},
+	},
+}
+
+// addSignal 记录一个决策信号
+func addSignal(thread *starlark.Thread, d decision.Decision) {
+	if decisions, ok := thread.Local(signalsKey).(*[]decision.Decision); ok {
+		*decisions = append(*decisions, d)
+	}
+}
+
+// openSignal signal.open_long/open_short(symbol, size_usd, leverage, stop_loss, take_profit, confidence=0, risk_usd=0, reason="", tags=[])
+func openSignal(action string) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var d decision.Decision
+		var tags *starlark.List
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs,
+			"symbol", &d.Symbol,
+			"size_usd", &d.PositionSizeUSD,
+			"leverage", &d.Leverage,
+			"stop_loss", &d.StopLoss,
+			"take_profit", &d.TakeProfit,
+			"confidence?", &d.Confidence,
+			"risk_usd?", &d.RiskUSD,
+			"reason?", &d.Reasoning,
+			"tags?", &tags,
+		); err != nil {
+			return nil, err
+		}
+		d.Action = action
+		d.Symbol = market.Normalize(d.Symbol)
+		d.Tags = stringList(tags)
+		addSignal(thread, d)
+		return starlark.None, nil
+	}
+}
+
+// closeSignal signal.close_long/close_short(symbol, reason="", tags=[])
+func closeSignal(action string) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var d decision.Decision
+		var tags *starlark.List
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "symbol", &d.Symbol, "reason?", &d.Reasoning, "tags?", &tags); err != nil {
+			return nil, err
+		}
+		d.Action = action
+		d.Symbol = normalizeSignalSymbol(d.Symbol)
+		d.Tags = stringList(tags)
+		addSignal(thread, d)
+		return starlark.None, nil
+	}
+}
+
+// openPairSignal signal.open_pair_long/open_pair_short(pair, size_usd, leverage, confidence=0, reason="", tags=[])
+// pair为 "BASE/QUOTE"，size_usd为每条腿的名义价值
+func openPairSignal(action string) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var d decision.Decision
+		var tags *starlark.List
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs,
+			"pair", &d.Symbol,
+			"size_usd", &d.PositionSizeUSD,
+			"leverage", &d.Leverage,
+			"confidence?", &d.Confidence,
+			"reason?", &d.Reasoning,
+			"tags?", &tags,
+		); err != nil {
+			return nil, err
+		}
+		d.Action = action
+		d.Symbol = normalizeSignalSymbol(d.Symbol)
+		d.Tags = stringList(tags)
+		addSignal(thread, d)
+		return starlark.None, nil
+	}
+}
+
+// normalizeSignalSymbol 标准化信号中的币种（配对代码分别标准化两个币种）
+func normalizeSignalSymbol(symbol string) string {
+	if base, quote, ok := market.SplitPair(symbol); ok {
+		return market.PairSymbol(base, quote)
+	}
+	return market.Normalize(symbol)
+}
+
+// seriesIndicator ta.ema/sma/rsi(values, period)：返回与输入等长的序列，数据不足的位置为None
+func seriesIndicator(compute func([]float64, int) []float64) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var list *starlark.List
+		var period int
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "values", &list, "period", &period); err != nil {
+			return nil, err
+		}
+		if period <= 0 {
+			return nil, fmt.Errorf("%s: period必须大于0", b.Name())
+		}
+		values := make([]float64, list.Len())
+		for i := range values {
+			v, ok := starlark.AsFloat(list.Index(i))
+			if !ok {
+				return nil, fmt.Errorf("%s: values[%d]不是数字", b.Name(), i)
+			}
+			values[i] = v
+		}
+		return toValue(reflect.ValueOf(compute(values, period))), nil
+	}
+}
+
+// scriptKlines ta.klines(symbol, interval, limit=100)：当前行情数据源的K线（最后一根可能未走完）
+func scriptKlines(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var symbol, interval string
+	limit := 100
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "symbol", &symbol, "interval", &interval, "limit?", &limit); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 1500 {
+		return nil, fmt.Errorf("klines: limit必须在1-1500之间")
+	}
+	klines, err := market.GetProvider().GetKlines(market.Normalize(symbol), interval, limit)
+	if err != nil {
+		return nil, fmt.Errorf("klines: %w", err)
+	}
+	return toValue(reflect.ValueOf(klines)), nil
+}
+
+// scriptSpread ta.spread(base, quote, interval="1h", lookback=100)：价格比 base/quote 及其z-score、收益相关系数
+func scriptSpread(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var base, quote string
+	interval, lookback := "1h", 100
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "base", &base, "quote", &quote, "interval?", &interval, "lookback?", &lookback); err != nil {
+		return nil, err
+	}
+	if lookback < 20 || lookback > 1500 {
+		return nil, fmt.Errorf("spread: lookback必须在20-1500之间")
+	}
+	spread, err := market.GetSpread(market.Normalize(base), market.Normalize(quote), interval, lookback)
+	if err != nil {
+		return nil, fmt.Errorf("spread: %w", err)
+	}
+	return toValue(reflect.ValueOf(spread)), nil
+}
+
+// scriptContext 把决策上下文转换为脚本对象
+// ctx.account、ctx.positions、ctx.candidates（币种列表）、ctx.market（币种 -> market.Data，字段名为snake_case，如current_price、longer_term_context.rsi14_values）
+func scriptContext(ctx *decision.Context) starlark.Value {
+	candidates := make([]string, len(ctx.CandidateCoins))
+	for i, coin := range ctx.CandidateCoins {
+		candidates[i] = coin.Symbol
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"time":            starlark.String(ctx.CurrentTime),
+		"runtime_minutes": starlark.MakeInt(ctx.RuntimeMinutes),
+		"call_count":      starlark.MakeInt(ctx.CallCount),
+		"account":         toValue(reflect.ValueOf(ctx.Account)),
+		"positions":       toValue(reflect.ValueOf(ctx.Positions)),
+		"candidates":      toValue(reflect.ValueOf(candidates)),
+		"market":          toValue(reflect.ValueOf(ctx.MarketDataMap)),
+	})
+}
+
+// toValue 把Go值转换为Starlark值（结构体为struct，时间为Unix秒，NaN为None）
+func toValue(v reflect.Value) starlark.Value {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return starlark.None
+		}
+		return toValue(v.Elem())
+	case reflect.Bool:
+		return starlark.Bool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return starlark.MakeInt64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return starlark.MakeUint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(v.Float()) {
+			return starlark.None
+		}
+		return starlark.Float(v.Float())
+	case reflect.String:
+		return starlark.String(v.String())
+	case reflect.Slice, reflect.Array:
+		elems := make([]starlark.Value, v.Len())
+		for i := range elems {
+			elems[i] = toValue(v.Index(i))
+		}
+		return starlark.NewList(elems)
+	case reflect.Map:
+		dict := starlark.NewDict(v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			dict.SetKey(starlark.String(fmt.Sprint(iter.Key().Interface())), toValue(iter.Value()))
+		}
+		return dict
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			if t.IsZero() {
+				return starlark.None
+			}
+			return starlark.MakeInt64(t.Unix())
+		}
+		fields := make(starlark.StringDict)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := snakeCase(field.Name)
+			if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			fields[name] = toValue(v.Field(i))
+		}
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+	}
+	return starlark.None
+}
+
+// snakeCase 字段名转换为snake_case（CurrentPrice -> current_price，RSI14Values -> rsi14_values）
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var out []rune
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				out = append(out, '_')
+			}
+		}
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out)
+}
+
+// stringList Starlark字符串列表转换为Go切片（忽略非字符串元素）
+func stringList(list *starlark.List) []string {
+	if list == nil {
+		return nil
+	}
+	var result []string
+	for i := 0; i < list.Len(); i++ {
+		if s, ok := starlark.AsString(list.Index(i)); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// decisionFromValue 把decide返回的dict（字段与AI决策JSON相同）转换为决策
+func decisionFromValue(v starlark.Value) (decision.Decision, error) {
+	var d decision.Decision
+	dict, ok := v.(*starlark.Dict)
+	if !ok {
+		return d, fmt.Errorf("应为dict，实际为%s", v.Type())
+	}
+	fields := make(map[string]interface{})
+	for _, item := range dict.Items() {
+		key, ok := starlark.AsString(item[0])
+		if !ok {
+			return d, fmt.Errorf("字段名必须是字符串")
+		}
+		value, err := fromValue(item[1])
+		if err != nil {
+			return d, fmt.Errorf("%s: %w", key, err)
+		}
+		fields[key] = value
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return d, err
+	}
+	d.Symbol = market.Normalize(d.Symbol)
+	return d, nil
+}
+
+// fromValue 把Starlark基本类型转换为Go值
+func fromValue(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		i, ok := v.Int64()
+		if !ok {
+			return nil, fmt.Errorf("整数超出范围")
+		}
+		return i, nil
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.String:
+		return string(v), nil
+	case *starlark.List:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			item, err := fromValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("不支持的类型 %s", v.Type())
+}
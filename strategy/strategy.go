@@ -0,0 +1,41 @@
+package strategy
+
+import (
+	"fmt"
+	"plugin"
+
+	"nofx/decision"
+)
+
+// Strategy 用户策略：每个交易周期根据行情和账户状态给出决策
+// ctx与AI决策共用（只读），返回的决策与AI决策经过相同的校验和风控后执行
+type Strategy interface {
+	Decide(ctx *decision.Context) ([]decision.Decision, error)
+}
+
+// LoadPlugin 加载Go插件（go build -buildmode=plugin）
+// 插件需导出 `func NewStrategy() strategy.Strategy` 或 `var Strategy strategy.Strategy`，
+// 且必须使用与主程序相同的Go版本和依赖版本编译
+func LoadPlugin(path string) (Strategy, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("加载策略插件失败: %w", err)
+	}
+	if symbol, err := p.Lookup("NewStrategy"); err == nil {
+		newStrategy, ok := symbol.(func() Strategy)
+		if !ok {
+			return nil, fmt.Errorf("策略插件 %s 的NewStrategy类型应为 func() strategy.Strategy", path)
+		}
+		return newStrategy(), nil
+	}
+	symbol, err := p.Lookup("Strategy")
+	if err != nil {
+		return nil, fmt.Errorf("策略插件 %s 未导出NewStrategy或Strategy", path)
+	}
+	// 导出变量时Lookup返回变量指针
+	s, ok := symbol.(*Strategy)
+	if !ok || *s == nil {
+		return nil, fmt.Errorf("策略插件 %s 的Strategy类型应为 strategy.Strategy", path)
+	}
+	return *s, nil
+}
@@ -0,0 +1,183 @@
+package symbols
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Instrument 某个交易所上的合约/交易对规格
+type Instrument struct {
+	Exchange     string    // 交易所: "binance", "binance_coinm", "aster", "hyperliquid", "coinbase", "kraken"
+	ID           string    // 交易所合约代码（如 BTCUSDT、BTCUSD_PERP、BTC-USD、XBTUSD、BTC）
+	ContractSize float64   // 合约面值（币本位合约为USD面值，其它为0）
+	TickSize     float64   // 价格最小变动单位（0表示未知）
+	StepSize     float64   // 数量最小变动单位（0表示未知）
+	ListedAt     time.Time // 上线时间（零值表示未知）
+	DelistAt     time.Time // 计划下架时间（零值表示无下架计划）
+	Status       string    // 交易状态（如 TRADING、SETTLING、CLOSE，空表示未知）
+}
+
+// Delisted 是否已下架（状态已知且不再交易）
+func (i Instrument) Delisted() bool {
+	return i.Status != "" && i.Status != "TRADING" && i.Status != "PENDING_TRADING"
+}
+
+// Registry 币种元数据注册表
+// 以规范币种（如 BTC）为键，记录各交易所的合约代码和规格
+type Registry struct {
+	mu          sync.RWMutex
+	instruments map[string]map[string]Instrument // canonical -> exchange -> instrument
+	aliases     map[string]string                // 交易所合约代码/别名 -> canonical
+}
+
+// NewRegistry 创建空注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		instruments: make(map[string]map[string]Instrument),
+		aliases:     make(map[string]string),
+	}
+}
+
+// defaultRegistry 全局注册表（market和各交易器共用）
+var defaultRegistry = NewRegistry()
+
+// 交易所特有的币种代码（规范代码 -> 交易所代码）
+var exchangeBaseAliases = map[string]map[string]string{
+	"kraken": {"BTC": "XBT", "DOGE": "XDG"},
+}
+
+// quoteSuffixes 识别规范币种时去除的计价后缀（按长度优先）
+var quoteSuffixes = []string{"USD_PERP", "-USDT", "-USDC", "-USD", "USDT", "USDC", "USD"}
+
+// Register 注册交易所合约规格
+func (r *Registry) Register(canonical string, instrument Instrument) {
+	canonical = strings.ToUpper(canonical)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.instruments[canonical] == nil {
+		r.instruments[canonical] = make(map[string]Instrument)
+	}
+	r.instruments[canonical][instrument.Exchange] = instrument
+	r.aliases[strings.ToUpper(instrument.ID)] = canonical
+}
+
+// Canonical 将任意格式的币种转换为规范币种（BTCUSDT、BTC-USD、XBTUSD、BTCUSD_PERP -> BTC）
+func (r *Registry) Canonical(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+
+	r.mu.RLock()
+	canonical, ok := r.aliases[symbol]
+	r.mu.RUnlock()
+	if ok {
+		return canonical
+	}
+
+	base := symbol
+	for _, suffix := range quoteSuffixes {
+		if len(base) > len(suffix) && strings.HasSuffix(base, suffix) {
+			base = strings.TrimSuffix(base, suffix)
+			break
+		}
+	}
+
+	// 交易所特有代码还原（XBT -> BTC）
+	for _, aliases := range exchangeBaseAliases {
+		for canonicalBase, exchangeBase := range aliases {
+			if base == exchangeBase {
+				return canonicalBase
+			}
+		}
+	}
+	return base
+}
+
+// Get 获取规范币种在某交易所的合约规格
+func (r *Registry) Get(canonical, exchange string) (Instrument, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	instrument, ok := r.instruments[strings.ToUpper(canonical)][exchange]
+	return instrument, ok
+}
+
+// Exchange 某交易所已注册的全部合约规格（canonical -> instrument）
+func (r *Registry) Exchange(exchange string) map[string]Instrument {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[string]Instrument)
+	for canonical, byExchange := range r.instruments {
+		if instrument, ok := byExchange[exchange]; ok {
+			result[canonical] = instrument
+		}
+	}
+	return result
+}
+
+// InstrumentID 获取规范币种在某交易所的合约代码
+// 已注册的使用注册值，否则按交易所命名规则推导
+func (r *Registry) InstrumentID(canonical, exchange string) string {
+	canonical = strings.ToUpper(canonical)
+	if instrument, ok := r.Get(canonical, exchange); ok && instrument.ID != "" {
+		return instrument.ID
+	}
+
+	base := canonical
+	if alias, ok := exchangeBaseAliases[exchange][canonical]; ok {
+		base = alias
+	}
+
+	switch exchange {
+	case "hyperliquid":
+		return base
+	case "coinbase":
+		return base + "-USD"
+	case "kraken":
+		return base + "USD"
+	case "binance_coinm":
+		return base + "USD_PERP"
+	default: // binance, aster 等USDT永续
+		return base + "USDT"
+	}
+}
+
+// SystemExchange 系统内部统一使用的币种格式（币安USDT永续，如 BTCUSDT）
+const SystemExchange = "binance"
+
+// Canonical 使用全局注册表转换规范币种
+func Canonical(symbol string) string {
+	return defaultRegistry.Canonical(symbol)
+}
+
+// InstrumentID 使用全局注册表获取交易所合约代码
+func InstrumentID(canonical, exchange string) string {
+	return defaultRegistry.InstrumentID(canonical, exchange)
+}
+
+// Get 使用全局注册表获取合约规格
+func Get(canonical, exchange string) (Instrument, bool) {
+	return defaultRegistry.Get(canonical, exchange)
+}
+
+// Exchange 使用全局注册表获取某交易所已注册的全部合约规格
+func Exchange(exchange string) map[string]Instrument {
+	return defaultRegistry.Exchange(exchange)
+}
+
+// Register 向全局注册表注册合约规格
+func Register(canonical string, instrument Instrument) {
+	defaultRegistry.Register(canonical, instrument)
+}
+
+// System 将任意格式的币种转换为系统内部格式（BTC、XBTUSD、BTC-USD -> BTCUSDT）
+func System(symbol string) string {
+	return ToExchange(symbol, SystemExchange)
+}
+
+// ToExchange 将任意格式的币种直接转换为交易所合约代码
+func ToExchange(symbol, exchange string) string {
+	return InstrumentID(Canonical(symbol), exchange)
+}
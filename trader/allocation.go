@@ -0,0 +1,247 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+const (
+	minAllocationTrades = 5   // 按滚动表现调整预算所需的最少已平仓交易数
+	minAllocationFill   = 0.1 // 预算剩余不足请求仓位的该比例时拒绝开仓（避免开出过小的仓位）
+)
+
+// AllocationConfig 各策略的资金分配配置
+type AllocationConfig struct {
+	Mode              string             // "fixed"（按配置比例）或 "volatility"（按收益波动率倒数加权）
+	Budgets           map[string]float64 // 策略ID -> 保证金预算（占净值%），未列出的策略不限制
+	RebalanceInterval time.Duration      // 按滚动表现再平衡的间隔
+	Lookback          time.Duration      // 计算滚动表现的回看期
+}
+
+// StrategyBudget 单个策略的资金预算
+type StrategyBudget struct {
+	StrategyID string  `json:"strategy_id"`
+	BasePct    float64 `json:"base_pct"`    // 配置的预算（占净值%）
+	BudgetPct  float64 `json:"budget_pct"`  // 再平衡后的当前预算（占净值%）
+	Trades     int     `json:"trades"`      // 回看期内已平仓交易数
+	MeanReturn float64 `json:"mean_return"` // 单笔平均收益率（%，按名义价值）
+	Volatility float64 `json:"volatility"`  // 单笔收益率标准差（%）
+}
+
+// AllocationReport 资金分配状态
+type AllocationReport struct {
+	Mode          string            `json:"mode"`
+	RebalancedAt  time.Time         `json:"rebalanced_at"`
+	NextRebalance time.Time         `json:"next_rebalance"`
+	Budgets       []*StrategyBudget `json:"budgets"`
+}
+
+// allocator 策略资金分配器：按配置比例（或波动率倒数）分配预算，再按回看期的单笔收益夏普值调整（0.5~1.5倍），总预算不变
+type allocator struct {
+	mu           sync.Mutex
+	config       AllocationConfig
+	budgets      map[string]*StrategyBudget
+	rebalancedAt time.Time
+}
+
+// newAllocator 创建资金分配器（未配置预算时返回nil）
+func newAllocator(config AllocationConfig) *allocator {
+	if len(config.Budgets) == 0 {
+		return nil
+	}
+	a := &allocator{config: config, budgets: make(map[string]*StrategyBudget)}
+	for id, pct := range config.Budgets {
+		a.budgets[id] = &StrategyBudget{StrategyID: id, BasePct: pct, BudgetPct: pct}
+	}
+	return a
+}
+
+// due 是否需要再平衡
+func (a *allocator) due(now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rebalancedAt.IsZero() || now.Sub(a.rebalancedAt) >= a.config.RebalanceInterval
+}
+
+// rebalance 按回看期内已平仓交易的表现重新计算各策略预算
+func (a *allocator) rebalance(lots []logger.TaxLot, now time.Time) {
+	returns := make(map[string][]float64)
+	for _, lot := range lots {
+		notional := lot.CostBasis // 多单开仓价值
+		if lot.Side == "short" {
+			notional = lot.Proceeds // 空单开仓价值
+		}
+		if notional > 0 {
+			returns[lot.StrategyID] = append(returns[lot.StrategyID], lot.Gain()/notional*100)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	total, volSum, volCount := 0.0, 0.0, 0
+	for id, budget := range a.budgets {
+		total += budget.BasePct
+		budget.Trades = len(returns[id])
+		budget.MeanReturn, budget.Volatility = meanStd(returns[id])
+		if budget.Trades >= minAllocationTrades && budget.Volatility > 0 {
+			volSum += budget.Volatility
+			volCount++
+		}
+	}
+
+	weights := make(map[string]float64)
+	weightSum := 0.0
+	for id, budget := range a.budgets {
+		weight := budget.BasePct
+		enoughData := budget.Trades >= minAllocationTrades && budget.Volatility > 0
+		if a.config.Mode == "volatility" && volCount > 0 {
+			// 数据不足的策略按平均波动率计算
+			vol := volSum / float64(volCount)
+			if enoughData {
+				vol = budget.Volatility
+			}
+			weight /= vol
+		}
+		if enoughData {
+			weight *= math.Max(0.5, math.Min(1.5, 1+budget.MeanReturn/budget.Volatility))
+		}
+		weights[id] = weight
+		weightSum += weight
+	}
+	for id, budget := range a.budgets {
+		if weightSum > 0 {
+			budget.BudgetPct = weights[id] / weightSum * total
+		}
+	}
+	a.rebalancedAt = now
+}
+
+// budget 策略当前的保证金预算（占净值%），未配置的策略返回false
+func (a *allocator) budget(strategyID string) (float64, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	budget, ok := a.budgets[strategyID]
+	if !ok {
+		return 0, false
+	}
+	return budget.BudgetPct, true
+}
+
+// report 当前资金分配状态
+func (a *allocator) report() *AllocationReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	report := &AllocationReport{
+		Mode:          a.config.Mode,
+		RebalancedAt:  a.rebalancedAt,
+		NextRebalance: a.rebalancedAt.Add(a.config.RebalanceInterval),
+	}
+	for _, budget := range a.budgets {
+		copied := *budget
+		report.Budgets = append(report.Budgets, &copied)
+	}
+	sort.Slice(report.Budgets, func(i, j int) bool { return report.Budgets[i].StrategyID < report.Budgets[j].StrategyID })
+	return report
+}
+
+// meanStd 平均值和样本标准差
+func meanStd(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)-1))
+}
+
+// rebalanceAllocation 到达再平衡间隔时按滚动表现重新分配各策略预算
+func (at *AutoTrader) rebalanceAllocation() {
+	now := market.Clock.Now()
+	if at.allocator == nil || !at.allocator.due(now) {
+		return
+	}
+	summary, err := at.decisionLogger.TaxLots(now.Add(-at.allocator.config.Lookback), now, 0)
+	if err != nil {
+		log.Printf("⚠️  [%s] 读取交易记录失败，跳过资金再平衡: %v", at.name, err)
+		return
+	}
+	at.allocator.rebalance(summary.Lots, now)
+	for _, budget := range at.allocator.report().Budgets {
+		log.Printf("💰 [%s] 策略 %s 资金预算 %.1f%%（配置 %.1f%%，%d笔交易，平均收益 %+.2f%%，波动 %.2f%%）",
+			at.name, budget.StrategyID, budget.BudgetPct, budget.BasePct, budget.Trades, budget.MeanReturn, budget.Volatility)
+	}
+}
+
+// applyAllocation 按策略资金预算限制开仓：已用保证金加上新仓位超出预算时缩小仓位，剩余预算过少时拒绝开仓
+func (at *AutoTrader) applyAllocation(d *decision.Decision) error {
+	if at.allocator == nil {
+		return nil
+	}
+	budgetPct, ok := at.allocator.budget(d.StrategyID)
+	if !ok {
+		return nil
+	}
+
+	equity, err := at.accountEquity()
+	if err != nil {
+		return err
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	used := 0.0
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		tracked, ok := at.trackedPositions[symbol+"_"+side]
+		if !ok || tracked.StrategyID != d.StrategyID {
+			continue
+		}
+		amount, _ := pos["positionAmt"].(float64)
+		entry, _ := pos["entryPrice"].(float64)
+		leverage, _ := pos["leverage"].(float64)
+		used += math.Abs(amount) * entry / math.Max(leverage, 1)
+	}
+
+	remaining := equity*budgetPct/100 - used
+	margin := d.PositionSizeUSD / float64(maxInt(d.Leverage, 1))
+	if margin <= remaining {
+		return nil
+	}
+	if remaining < margin*minAllocationFill {
+		return fmt.Errorf("策略 %s 资金预算已用完（预算 %.1f%% 净值，已用保证金 %.2f USDT）", d.StrategyID, budgetPct, used)
+	}
+	size := remaining * float64(maxInt(d.Leverage, 1))
+	log.Printf("  💰 策略 %s 资金预算剩余 %.2f USDT，仓位 %.2f → %.2f USDT", d.StrategyID, remaining, d.PositionSizeUSD, size)
+	d.PositionSizeUSD = size
+	return nil
+}
+
+// AllocationReport 各策略资金预算（未配置资金分配时返回false）
+func (at *AutoTrader) AllocationReport() (*AllocationReport, bool) {
+	if at.allocator == nil {
+		return nil, false
+	}
+	return at.allocator.report(), true
+}
@@ -7,7 +7,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"math"
 	"math/big"
@@ -22,6 +21,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"nofx/market"
 	"nofx/utils"
 )
 
@@ -33,6 +33,7 @@ type AsterTrader struct {
 	privateKey *ecdsa.PrivateKey // API钱包私钥
 	client     *http.Client
 	baseURL    string
+	signing    *RequestSigner // 时间戳校正与recvWindow
 
 	// 缓存交易对精度信息
 	symbolPrecision map[string]SymbolPrecision
@@ -70,6 +71,7 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 				IdleConnTimeout:       90 * time.Second,
 			}),
 		baseURL: "https://fapi.asterdex.com",
+		signing: NewRequestSigner("", "https://fapi.asterdex.com/fapi/v1/time", 50000, nil),
 	}, nil
 }
 
@@ -94,7 +96,7 @@ func (t *AsterTrader) getPrecision(symbol string) (SymbolPrecision, error) {
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	body, _ := utils.ReadBody(resp.Body)
 	var info struct {
 		Symbols []struct {
 			Symbol            string `json:"symbol"`
@@ -260,9 +262,13 @@ func (t *AsterTrader) normalize(v interface{}) (interface{}, error) {
 
 // sign 对请求参数进行签名
 func (t *AsterTrader) sign(params map[string]interface{}, nonce uint64) error {
+	if err := checkClockDrift(); err != nil {
+		return err
+	}
+
 	// 添加时间戳和接收窗口
-	params["recvWindow"] = "50000"
-	params["timestamp"] = strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	params["recvWindow"] = strconv.FormatInt(t.signing.RecvWindow(), 10)
+	params["timestamp"] = strconv.FormatInt(t.signing.Timestamp(), 10)
 
 	// 规范化参数为JSON字符串
 	jsonStr, err := t.normalizeAndStringify(params)
@@ -344,6 +350,12 @@ func (t *AsterTrader) request(method, endpoint string, params map[string]interfa
 
 		lastErr = err
 
+		// 时间戳超出recvWindow：同步服务器时间后重试
+		if isTimestampError(err.Error()) && attempt < maxRetries {
+			t.signing.Resync()
+			continue
+		}
+
 		// 如果是网络超时或临时错误，重试
 		if strings.Contains(err.Error(), "timeout") ||
 			strings.Contains(err.Error(), "connection reset") ||
@@ -386,7 +398,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 		}
 		defer resp.Body.Close()
 
-		body, _ := io.ReadAll(resp.Body)
+		body, _ := utils.ReadBody(resp.Body)
 		if resp.StatusCode != http.StatusOK {
 			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
 		}
@@ -412,7 +424,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 		}
 		defer resp.Body.Close()
 
-		body, _ := io.ReadAll(resp.Body)
+		body, _ := utils.ReadBody(resp.Body)
 		if resp.StatusCode != http.StatusOK {
 			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
 		}
@@ -519,7 +531,7 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 }
 
 // OpenLong 开多单
-func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -571,6 +583,9 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 		"quantity":     qtyStr,
 		"price":        priceStr,
 	}
+	if clientOrderID != "" {
+		params["newClientOrderId"] = clientOrderID // 重试时使用相同ID，交易所拒绝重复订单
+	}
 
 	body, err := t.request("POST", "/fapi/v3/order", params)
 	if err != nil {
@@ -586,7 +601,7 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 }
 
 // OpenShort 开空单
-func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -638,6 +653,9 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 		"quantity":     qtyStr,
 		"price":        priceStr,
 	}
+	if clientOrderID != "" {
+		params["newClientOrderId"] = clientOrderID // 重试时使用相同ID，交易所拒绝重复订单
+	}
 
 	body, err := t.request("POST", "/fapi/v3/order", params)
 	if err != nil {
@@ -837,7 +855,7 @@ func (t *AsterTrader) GetMarketPrice(symbol string) (float64, error) {
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	body, _ := utils.ReadBody(resp.Body)
 	if resp.StatusCode != http.StatusOK {
 		return 0, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
 	}
@@ -937,6 +955,71 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 	return err
 }
 
+// PlaceLimitOrder 下限价单（支持GTC/IOC/FOK/GTX有效期和post-only）
+func (t *AsterTrader) PlaceLimitOrder(order LimitOrder) (map[string]interface{}, error) {
+	tif, err := order.EffectiveTimeInForce()
+	if err != nil {
+		return nil, err
+	}
+
+	side := "BUY"
+	if order.Side == "short" {
+		side = "SELL"
+	}
+	if order.ReduceOnly {
+		if side == "BUY" {
+			side = "SELL"
+		} else {
+			side = "BUY"
+		}
+	} else if err := t.SetLeverage(order.Symbol, order.Leverage); err != nil {
+		return nil, fmt.Errorf("设置杠杆失败: %w", err)
+	}
+
+	// 格式化价格和数量到正确精度
+	formattedPrice, err := t.formatPrice(order.Symbol, order.Price)
+	if err != nil {
+		return nil, err
+	}
+	formattedQty, err := t.formatQuantity(order.Symbol, order.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	prec, err := t.getPrecision(order.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{
+		"symbol":       order.Symbol,
+		"positionSide": "BOTH",
+		"type":         "LIMIT",
+		"side":         side,
+		"timeInForce":  tif,
+		"quantity":     t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision),
+		"price":        t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision),
+		// 返回撮合后的状态（IOC/FOK立即成交时为FILLED）
+		"newOrderRespType": "RESULT",
+	}
+	if order.ReduceOnly {
+		params["reduceOnly"] = "true"
+	}
+	if order.ClientOrderID != "" {
+		params["newClientOrderId"] = order.ClientOrderID
+	}
+
+	body, err := t.request("POST", "/fapi/v3/order", params)
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // CancelAllOrders 取消所有订单
 func (t *AsterTrader) CancelAllOrders(symbol string) error {
 	params := map[string]interface{}{
@@ -947,6 +1030,117 @@ func (t *AsterTrader) CancelAllOrders(symbol string) error {
 	return err
 }
 
+// GetOrderBook 获取Aster盘口深度（公开接口，与币安格式相同）
+func (t *AsterTrader) GetOrderBook(symbol string, limit int) (*market.OrderBook, error) {
+	resp, err := t.client.Get(fmt.Sprintf("%s/fapi/v3/depth?symbol=%s&limit=%d", t.baseURL, symbol, market.DepthLimit(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("获取盘口深度失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := utils.ReadBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	book, err := market.ParseDepth(body)
+	if err != nil {
+		return nil, err
+	}
+	book.Symbol = symbol
+	return book, nil
+}
+
+// CancelOrder 按订单ID取消单个挂单
+func (t *AsterTrader) CancelOrder(symbol string, orderID int64) error {
+	params := map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": orderID,
+	}
+
+	_, err := t.request("DELETE", "/fapi/v3/order", params)
+	return err
+}
+
+// GetOrderByClientID 按客户端订单ID查询订单（订单不存在时返回nil, nil）
+func (t *AsterTrader) GetOrderByClientID(symbol, clientOrderID string) (map[string]interface{}, error) {
+	params := map[string]interface{}{
+		"symbol":            symbol,
+		"origClientOrderId": clientOrderID,
+	}
+
+	body, err := t.request("GET", "/fapi/v3/order", params)
+	if err != nil {
+		if strings.Contains(err.Error(), "-2013") {
+			return nil, nil // 订单不存在
+		}
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetIncomeHistory 获取收益流水（/fapi/v3/income）
+func (t *AsterTrader) GetIncomeHistory(startTime, endTime time.Time) ([]IncomeRecord, error) {
+	fetch := func(start, end int64) ([]binanceIncome, error) {
+		body, err := t.request("GET", "/fapi/v3/income", map[string]interface{}{
+			"startTime": start,
+			"endTime":   end,
+			"limit":     incomePageLimit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var page []binanceIncome
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("解析收益流水失败: %w", err)
+		}
+		return page, nil
+	}
+	return fetchBinanceIncome(startTime, endTime, fetch, func(symbol string) string { return symbol })
+}
+
+// GetOpenOrders 获取挂单（symbol为空表示所有币种）
+func (t *AsterTrader) GetOpenOrders(symbol string) ([]map[string]interface{}, error) {
+	params := map[string]interface{}{}
+	if symbol != "" {
+		params["symbol"] = symbol
+	}
+
+	body, err := t.request("GET", "/fapi/v3/openOrders", params)
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	var orders []struct {
+		OrderID      int64  `json:"orderId"`
+		Symbol       string `json:"symbol"`
+		Type         string `json:"type"`
+		Side         string `json:"side"`
+		PositionSide string `json:"positionSide"`
+		StopPrice    string `json:"stopPrice"`
+		OrigQty      string `json:"origQty"`
+	}
+	if err := json.Unmarshal(body, &orders); err != nil {
+		return nil, fmt.Errorf("解析挂单失败: %w", err)
+	}
+
+	result := make([]map[string]interface{}, 0, len(orders))
+	for _, order := range orders {
+		stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+		quantity, _ := strconv.ParseFloat(order.OrigQty, 64)
+		result = append(result, openOrderMap(order.OrderID, order.Symbol, order.Type,
+			order.Side, order.PositionSide, stopPrice, quantity))
+	}
+	return result, nil
+}
+
 // FormatQuantity 格式化数量（实现Trader接口）
 func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
 	formatted, err := t.formatQuantity(symbol, quantity)
@@ -5,10 +5,15 @@ import (
 	"fmt"
 	"log"
 	"nofx/decision"
+	"nofx/events"
 	"nofx/logger"
 	"nofx/market"
 	"nofx/mcp"
+	"nofx/monitor"
 	"nofx/pool"
+	"nofx/risk"
+	"nofx/strategy"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,12 +26,15 @@ type AutoTraderConfig struct {
 	AIModel string // AI模型: "qwen" 或 "deepseek"
 
 	// 交易平台选择
-	Exchange string // "binance", "hyperliquid" 或 "aster"
+	Exchange string // "binance", "binance_coinm", "hyperliquid" 或 "aster"
 
 	// 币安API配置
 	BinanceAPIKey    string
 	BinanceSecretKey string
 
+	// 币安币本位合约类型: "perpetual"、"current_quarter" 或 "next_quarter"
+	BinanceCoinMContract string
+
 	// Hyperliquid配置
 	HyperliquidPrivateKey string
 	HyperliquidWalletAddr string
@@ -52,6 +60,9 @@ type AutoTraderConfig struct {
 	// 扫描配置
 	ScanInterval time.Duration // 扫描间隔（建议3分钟）
 
+	// 决策记忆配置
+	MemorySize int // 写入prompt的最近交易条数（0表示不写入）
+
 	// 账户配置
 	InitialBalance float64 // 初始金额（用于计算盈亏，需手动设置）
 
@@ -59,10 +70,93 @@ type AutoTraderConfig struct {
 	BTCETHLeverage  int // BTC和ETH的杠杆倍数
 	AltcoinLeverage int // 山寨币的杠杆倍数
 
-	// 风险控制（仅作为提示，AI可自主决定）
-	MaxDailyLoss    float64       // 最大日亏损百分比（提示）
-	MaxDrawdown     float64       // 最大回撤百分比（提示）
-	StopTradingTime time.Duration // 触发风控后暂停时长
+	// 按币种的策略覆盖配置（key为标准化symbol）
+	SymbolOverrides map[string]decision.SymbolOverride
+
+	// 风险控制（按账户独立生效，0表示不限制）
+	MaxDailyLoss      float64       // 最大日亏损百分比（相对当日起始净值）
+	MaxDrawdown       float64       // 最大回撤百分比（相对净值峰值）
+	EnforceRiskLimits bool          // 触发上限时暂停交易（false时只记录警告）
+	MaxOpenRiskPct    float64       // 组合开放风险预算（占净值%，超过时禁止开新仓，0表示不限制）
+	StopTradingTime   time.Duration // 触发风控后暂停时长
+
+	// 对账间隔（本地持仓/挂单与交易所核对，默认5分钟）
+	ReconcileInterval time.Duration
+
+	// 移动止损模式（""不移动，"sar"按Parabolic SAR移动）
+	TrailingStopMode string
+
+	// 周期延迟预算（从周期开始到下单前的最大耗时，超出则放弃本周期交易，0表示不限制）
+	LatencyBudget time.Duration
+
+	// 开仓订单类型（"market"默认，"limit"按EntryTimeInForce挂限价单）
+	EntryOrderType   string
+	EntryTimeInForce string
+	PostOnly         bool // 只做Maker（限价单使用GTX）
+
+	// 影子策略（替代模型/prompt并行决策，只做模拟成交，nil表示不启用）
+	Shadow *ShadowConfig
+
+	// 模拟交易的执行模型（延迟、滑点、部分成交，exchange为"paper"时生效）
+	Paper PaperConfig
+
+	// AI决策的策略ID（多策略部署时用于归因成交和统计表现，为空时为"default"）
+	StrategyID string
+
+	// 用户策略插件（在沙箱中运行，决策与AI决策一起执行）
+	Strategies []StrategyConfig
+
+	// 各策略的资金预算（按策略ID限制开仓保证金，定期按滚动表现再平衡）
+	Allocation AllocationConfig
+
+	// 各策略的仓位计算模式（策略ID -> 配置，未配置的策略使用决策给出的仓位）
+	Sizing map[string]SizingConfig
+
+	// 各策略的最长持仓时间（策略ID -> 配置，超时仍未止损/止盈时平仓，未配置的策略不限制）
+	MaxHolding map[string]MaxHoldingConfig
+
+	// 各策略的金字塔加仓规则（策略ID -> 配置，浮盈达到R倍数阈值时加仓并收紧止损，未配置的策略不加仓）
+	Pyramiding map[string]PyramidConfig
+
+	// 网格/DCA开仓（开仓拆分为多层限价单，Levels≥2时启用）
+	Grid GridConfig
+
+	// 配对交易（价格比的z-score均值回归，两条腿同时下单）
+	Pairs []PairConfig
+
+	// 资金费率套利（资金费率极端时永续与现货反向持仓收取资金费，Symbols为空时不启用）
+	FundingHarvest FundingHarvestConfig
+
+	// 信号组合（成员策略的开仓信号按规则投票后合并，Members为空时不启用）
+	Ensemble EnsembleConfig
+
+	// 按市场状态切换用户策略（趋势状态启用趋势策略，震荡状态启用均值回归策略）
+	Regime RegimeConfig
+
+	// 交易时段过滤（禁止时段/日期内不开新仓，nil表示不限制）
+	TradingHours *risk.TradingHours
+
+	// 按币种分级的杠杆和名义价值上限（下单前检查，nil表示不限制）
+	SymbolTiers *risk.SymbolTiers
+
+	// 开仓前的盘口流动性检查（MaxDepthPct为0时不检查）
+	LiquidityGuard LiquidityGuardConfig
+
+	// 市价开仓前的买卖价差检查（价差上限为0时不检查）
+	SpreadGuard SpreadGuardConfig
+
+	// 资金费结算前该时间内禁止开仓（0表示不限制）
+	FundingBlackout time.Duration
+
+	// 决策快照（保存每个周期的完整行情数据和原始API响应，用于事后复盘）
+	Snapshots SnapshotConfig
+}
+
+// SnapshotConfig 决策快照配置
+type SnapshotConfig struct {
+	Enabled     bool
+	RawPayloads bool          // 同时记录周期内收到的币安REST原始响应
+	Retention   time.Duration // 快照保留时长（0表示不清理）
 }
 
 // AutoTrader 自动交易器
@@ -72,17 +166,40 @@ type AutoTrader struct {
 	aiModel               string // AI模型名称
 	exchange              string // 交易平台名称
 	config                AutoTraderConfig
-	trader                Trader // 使用Trader接口（支持多平台）
+	trader                Trader     // 使用Trader接口（支持多平台）
+	spot                  SpotTrader // 现货交易器（资金费率套利的现货腿，不支持现货的平台为nil）
 	mcpClient             *mcp.Client
 	decisionLogger        *logger.DecisionLogger // 决策日志记录器
 	initialBalance        float64
 	dailyPnL              float64
+	dayStartEquity        float64 // 当日起始净值（用于计算日亏损）
+	peakEquity            float64 // 净值峰值（用于计算回撤）
+	rebasePeak            bool    // 回撤触发暂停，暂停结束后以当时净值重置峰值
 	lastResetTime         time.Time
 	stopUntil             time.Time
 	isRunning             bool
-	startTime             time.Time        // 系统启动时间
-	callCount             int              // AI调用次数
-	positionFirstSeenTime map[string]int64 // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	startTime             time.Time                   // 系统启动时间
+	callCount             int                         // AI调用次数
+	positionFirstSeenTime map[string]int64            // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	trackedPositions      map[string]*trackedPosition // 本地跟踪的持仓 (symbol_side -> 持仓)，用于对账
+	reconciledOnce        bool                        // 是否已完成首次对账（首次对账接管已有持仓）
+	lastLatency           *logger.CycleLatency        // 最近一个周期的耗时统计
+	lastMarketData        map[string]*market.Data     // 上一周期的市场数据
+	rawBalance            map[string]interface{}      // 本周期交易所返回的账户余额（决策快照使用）
+	rawPositions          []map[string]interface{}    // 本周期交易所返回的持仓（决策快照使用）
+	shadow                *shadowRunner               // 影子策略（未启用时为nil）
+	strategies            []*strategy.Sandbox         // 用户策略沙箱
+	allocator             *allocator                  // 策略资金分配器（未配置时为nil）
+	sizingStats           sizingStats                 // 凯利公式所用的交易统计缓存
+	grids                 map[string]*gridEntry       // 网格开仓 (symbol_side -> 网格)
+	pairs                 map[string]*pairPosition    // 配对持仓 (BASE/QUOTE -> 持仓)
+	harvest               fundingHarvest              // 资金费率套利持仓
+	regime                regimeController            // 按市场状态切换用户策略
+	signals               chan ExternalSignal         // 待执行的外部信号（webhook）
+	spreadWaits           []spreadWait                // 等待价差收窄的开仓（只在交易主循环中访问）
+	closes                chan closeRequest           // 待执行的手动平仓请求（API）
+	plans                 *planBook                   // 交易计划（持久化，重启后恢复）
+	openRisk              openRiskMonitor             // 组合开放风险（止损距离×数量之和）
 }
 
 // NewAutoTrader 创建自动交易器
@@ -102,21 +219,13 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		}
 	}
 
-	mcpClient := mcp.New()
+	mcpClient := newMCPClient(config)
 
-	// 初始化AI
-	if config.AIModel == "custom" {
-		// 使用自定义API
-		mcpClient.SetCustomAPI(config.CustomAPIURL, config.CustomAPIKey, config.CustomModelName)
-		log.Printf("🤖 [%s] 使用自定义AI API: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
-	} else if config.UseQwen || config.AIModel == "qwen" {
-		// 使用Qwen
-		mcpClient.SetQwenAPIKey(config.QwenKey, "")
-		log.Printf("🤖 [%s] 使用阿里云Qwen AI", config.Name)
-	} else {
-		// 默认使用DeepSeek
-		mcpClient.SetDeepSeekAPIKey(config.DeepSeekKey)
-		log.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
+	if config.StopTradingTime <= 0 {
+		config.StopTradingTime = 60 * time.Minute
+	}
+	if config.ReconcileInterval <= 0 {
+		config.ReconcileInterval = defaultReconcileInterval
 	}
 
 	// 初始化币种池API
@@ -137,6 +246,12 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	case "binance":
 		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
 		trader = NewFuturesTrader(config.BinanceAPIKey, config.BinanceSecretKey)
+	case "binance_coinm":
+		log.Printf("🏦 [%s] 使用币安币本位合约交易", config.Name)
+		trader, err = NewCoinMTrader(config.BinanceAPIKey, config.BinanceSecretKey, config.BinanceCoinMContract)
+		if err != nil {
+			return nil, fmt.Errorf("初始化币本位交易器失败: %w", err)
+		}
 	case "hyperliquid":
 		log.Printf("🏦 [%s] 使用Hyperliquid交易", config.Name)
 		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.HyperliquidTestnet)
@@ -149,10 +264,32 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		if err != nil {
 			return nil, fmt.Errorf("初始化Aster交易器失败: %w", err)
 		}
+	case "paper":
+		log.Printf("🏦 [%s] 使用模拟交易（不向交易所下单）", config.Name)
+		trader = NewPaperTrader(config.InitialBalance, config.Paper)
 	default:
 		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
 	}
 
+	// 资金费率套利需要现货交易（币安现货与合约共用API Key）
+	var spot SpotTrader
+	if config.FundingHarvest.enabled() {
+		switch t := trader.(type) {
+		case *FuturesTrader:
+			spot = NewBinanceSpotTrader(config.BinanceAPIKey, config.BinanceSecretKey)
+		case SpotTrader:
+			spot = t
+		default:
+			return nil, fmt.Errorf("交易平台 %s 不支持现货交易，无法启用资金费率套利", config.Exchange)
+		}
+	}
+
+	// 主备部署时所有下单路径统一经过主节点检查
+	trader = &leaderGuard{Trader: trader}
+	if spot != nil {
+		spot = &leaderSpotGuard{SpotTrader: spot}
+	}
+
 	// 验证初始金额配置
 	if config.InitialBalance <= 0 {
 		return nil, fmt.Errorf("初始金额必须大于0，请在配置中设置InitialBalance")
@@ -161,6 +298,28 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	// 初始化决策日志记录器（使用trader ID创建独立目录）
 	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
 	decisionLogger := logger.NewDecisionLogger(logDir)
+	if config.Snapshots.Enabled {
+		decisionLogger.EnableSnapshots(config.Snapshots.Retention)
+		if config.Snapshots.RawPayloads {
+			market.BinancePayloads.Enable()
+		}
+		log.Printf("📸 [%s] 已启用决策快照（%s/snapshots，原始响应: %v）", config.Name, logDir, config.Snapshots.RawPayloads)
+	}
+
+	if err := validateMaxHolding(config.MaxHolding); err != nil {
+		return nil, err
+	}
+
+	strategies, err := loadStrategies(config.Strategies)
+	if err != nil {
+		return nil, err
+	}
+
+	var shadow *shadowRunner
+	if config.Shadow != nil && config.Shadow.Enabled {
+		shadow = newShadowRunner(config)
+		log.Printf("👻 [%s] 已启用影子策略（只做模拟成交）", config.Name)
+	}
 
 	return &AutoTrader{
 		id:                    config.ID,
@@ -169,6 +328,7 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		exchange:              config.Exchange,
 		config:                config,
 		trader:                trader,
+		spot:                  spot,
 		mcpClient:             mcpClient,
 		decisionLogger:        decisionLogger,
 		initialBalance:        config.InitialBalance,
@@ -177,12 +337,43 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		callCount:             0,
 		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
+		trackedPositions:      make(map[string]*trackedPosition),
+		grids:                 make(map[string]*gridEntry),
+		pairs:                 make(map[string]*pairPosition),
+		shadow:                shadow,
+		strategies:            strategies,
+		allocator:             newAllocator(config.Allocation),
+		signals:               make(chan ExternalSignal, signalQueueSize),
+		closes:                make(chan closeRequest, closeQueueSize),
+		plans:                 loadPlanBook(logDir),
 	}, nil
 }
 
+// newMCPClient 按配置初始化AI客户端
+func newMCPClient(config AutoTraderConfig) *mcp.Client {
+	mcpClient := mcp.New()
+
+	if config.AIModel == "custom" {
+		// 使用自定义API
+		mcpClient.SetCustomAPI(config.CustomAPIURL, config.CustomAPIKey, config.CustomModelName)
+		log.Printf("🤖 [%s] 使用自定义AI API: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
+	} else if config.UseQwen || config.AIModel == "qwen" {
+		// 使用Qwen
+		mcpClient.SetQwenAPIKey(config.QwenKey, "")
+		log.Printf("🤖 [%s] 使用阿里云Qwen AI", config.Name)
+	} else {
+		// 默认使用DeepSeek
+		mcpClient.SetDeepSeekAPIKey(config.DeepSeekKey)
+		log.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
+	}
+	return mcpClient
+}
+
 // Run 运行自动交易主循环
 func (at *AutoTrader) Run() error {
 	at.isRunning = true
+	defer market.Hub.Unwatch(at.id)
+	defer market.Hub.Unwatch(at.spreadWatcher())
 	log.Println("🚀 AI驱动自动交易系统启动")
 	log.Printf("💰 初始余额: %.2f USDT", at.initialBalance)
 	log.Printf("⚙️  扫描间隔: %v", at.config.ScanInterval)
@@ -191,7 +382,20 @@ func (at *AutoTrader) Run() error {
 	ticker := time.NewTicker(at.config.ScanInterval)
 	defer ticker.Stop()
 
-	// 首次立即执行
+	// 对账、外部信号与交易周期在同一goroutine中执行，避免并发修改本地持仓记录
+	reconcileTicker := time.NewTicker(at.config.ReconcileInterval)
+	defer reconcileTicker.Stop()
+	invalidationTicker := time.NewTicker(invalidationCheckInterval)
+	defer invalidationTicker.Stop()
+	openRiskTicker := time.NewTicker(openRiskInterval)
+	defer openRiskTicker.Stop()
+	maxHoldingTicker := time.NewTicker(maxHoldingCheckInterval)
+	defer maxHoldingTicker.Stop()
+	spreadTicker := time.NewTicker(spreadPollInterval)
+	defer spreadTicker.Stop()
+
+	// 首次立即执行（先对账接管已有持仓）
+	at.reconcile()
 	if err := at.runCycle(); err != nil {
 		log.Printf("❌ 执行失败: %v", err)
 	}
@@ -202,6 +406,20 @@ func (at *AutoTrader) Run() error {
 			if err := at.runCycle(); err != nil {
 				log.Printf("❌ 执行失败: %v", err)
 			}
+		case <-reconcileTicker.C:
+			at.reconcile()
+		case <-invalidationTicker.C:
+			at.checkInvalidations()
+		case <-openRiskTicker.C:
+			at.refreshOpenRisk(0)
+		case <-maxHoldingTicker.C:
+			at.checkMaxHolding()
+		case <-spreadTicker.C:
+			at.checkSpreadWaits()
+		case signal := <-at.signals:
+			at.executeSignal(signal)
+		case req := <-at.closes:
+			at.executeClose(req)
 		}
 	}
 
@@ -218,9 +436,9 @@ func (at *AutoTrader) Stop() {
 func (at *AutoTrader) runCycle() error {
 	at.callCount++
 
-	log.Printf("\n" + strings.Repeat("=", 70))
+	log.Print("\n" + strings.Repeat("=", 70))
 	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
-	log.Printf(strings.Repeat("=", 70))
+	log.Print(strings.Repeat("=", 70))
 
 	// 检查15分钟K线是否走完
 	if !market.CheckKlineCompleteness() {
@@ -228,31 +446,51 @@ func (at *AutoTrader) runCycle() error {
 		return nil
 	}
 
-	// 创建决策记录
+	// 创建决策记录（含各阶段耗时统计）
+	cycleStart := time.Now()
+	latency := logger.NewCycleLatency(at.config.LatencyBudget)
+	at.lastLatency = latency
 	record := &logger.DecisionRecord{
 		ExecutionLog: []string{},
 		Success:      true,
+		Latency:      latency,
 	}
 
-	// 1. 检查是否需要停止交易
-	if time.Now().Before(at.stopUntil) {
-		remaining := at.stopUntil.Sub(time.Now())
-		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
+	// 1. 风控暂停期间只禁止开新仓（通过entryBlock写入交易上下文），平仓和持仓管理照常执行
+	riskStart := time.Now()
+	now := market.Clock.Now()
+	if now.Before(at.stopUntil) {
+		log.Printf("⏸ 风险控制：暂停开仓中，剩余 %.0f 分钟（平仓照常执行）", at.stopUntil.Sub(now).Minutes())
+	}
+
+	// 全局熔断检查（稳定币脱锚等系统性风险）：禁止开新仓，平仓和止损管理照常执行
+	if tripped, reason := risk.Breaker.Tripped(); tripped {
+		log.Printf("🚨 [%s] 全局熔断生效中，本周期只平仓和管理持仓: %s", at.name, reason)
+	}
+
+	// 交易所维护/不可用时暂停（避免在循环中途因HTTP错误失败）
+	if available, reason := monitor.ExchangeAvailable(at.exchange); !available {
+		log.Printf("🚧 [%s] 交易所 %s 暂不可用（%s），跳过本次决策", at.name, at.exchange, reason)
 		record.Success = false
-		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
+		record.ErrorMessage = fmt.Sprintf("交易所暂不可用: %s", reason)
 		at.decisionLogger.LogDecision(record)
 		return nil
 	}
 
 	// 2. 重置日盈亏（每天重置）
-	if time.Since(at.lastResetTime) > 24*time.Hour {
+	if now.Sub(at.lastResetTime) > 24*time.Hour {
 		at.dailyPnL = 0
-		at.lastResetTime = time.Now()
+		at.dayStartEquity = 0
+		at.lastResetTime = now
 		log.Println("📅 日盈亏已重置")
 	}
 
+	latency.RiskCheckMs = time.Since(riskStart).Milliseconds()
+
 	// 3. 收集交易上下文
+	fetchStart := time.Now()
 	ctx, err := at.buildTradingContext()
+	latency.FetchMs = time.Since(fetchStart).Milliseconds()
 	if err != nil {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("构建交易上下文失败: %v", err)
@@ -260,6 +498,17 @@ func (at *AutoTrader) runCycle() error {
 		return fmt.Errorf("构建交易上下文失败: %w", err)
 	}
 
+	// 检查账户风控（日亏损/最大回撤），触发后暂停开仓，本周期的平仓照常执行
+	riskStart = time.Now()
+	reason := at.checkRiskLimits(ctx.Account.TotalEquity)
+	latency.RiskCheckMs += time.Since(riskStart).Milliseconds()
+	if reason != "" && !now.Before(at.stopUntil) {
+		at.stopUntil = now.Add(at.config.StopTradingTime)
+		log.Printf("🛑 [%s] 触发风控: %s，暂停开仓 %.0f 分钟（平仓照常执行）", at.name, reason, at.config.StopTradingTime.Minutes())
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛑 触发风控: %s，暂停开仓 %.0f 分钟", reason, at.config.StopTradingTime.Minutes()))
+		ctx.EntryBlocked = at.entryBlock()
+	}
+
 	// 保存账户状态快照
 	record.AccountState = logger.AccountSnapshot{
 		TotalBalance:          ctx.Account.TotalEquity,
@@ -291,18 +540,40 @@ func (at *AutoTrader) runCycle() error {
 	log.Printf("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
 
+	// 按滚动表现再平衡各策略资金预算；配对持仓按z-score自动平仓；资金费率套利按费率开平仓
+	// （超过最长持仓时间的持仓由独立的定时检查平仓，风控暂停期间也照常执行）
+	at.rebalanceAllocation()
+	at.managePairs(ctx, record)
+	at.manageFundingHarvest(record)
+	at.refreshOpenRisk(ctx.Account.TotalEquity)
+
 	// 4. 调用AI获取完整决策
 	log.Println("🤖 正在请求AI分析并决策...")
 	decision, err := decision.GetFullDecision(ctx, at.mcpClient)
+	if len(ctx.MarketDataMap) > 0 {
+		at.lastMarketData = ctx.MarketDataMap // 下一周期用于生成变化摘要
+		at.runShadow(ctx)                     // 影子策略复用同一份行情
+		at.publishSnapshot(ctx)
+	}
+	if at.config.Snapshots.Enabled {
+		record.Snapshot = at.decisionSnapshot(ctx, cycleStart)
+	}
 
 	// 即使有错误，也保存思维链、决策和输入prompt（用于debug）
 	if decision != nil {
+		latency.FetchMs += decision.FetchDuration.Milliseconds()
+		latency.ComputeMs = decision.ComputeDuration.Milliseconds()
+		latency.LLMMs = decision.LLMDuration.Milliseconds()
 		record.InputPrompt = decision.UserPrompt
 		record.CoTTrace = decision.CoTTrace
 		if len(decision.Decisions) > 0 {
 			decisionJSON, _ := json.MarshalIndent(decision.Decisions, "", "  ")
 			record.DecisionJSON = string(decisionJSON)
 		}
+		for _, r := range decision.Rejected {
+			log.Printf("⛔ [%s] %s %s 已拒绝: %s", at.name, r.Decision.Symbol, r.Decision.Action, r.Reason)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⛔ %s %s 已拒绝: %s", r.Decision.Symbol, r.Decision.Action, r.Reason))
+		}
 	}
 
 	if err != nil {
@@ -311,11 +582,11 @@ func (at *AutoTrader) runCycle() error {
 
 		// 打印AI思维链（即使有错误）
 		if decision != nil && decision.CoTTrace != "" {
-			log.Printf("\n" + strings.Repeat("-", 70))
+			log.Print("\n" + strings.Repeat("-", 70))
 			log.Println("💭 AI思维链分析（错误情况）:")
 			log.Println(strings.Repeat("-", 70))
 			log.Println(decision.CoTTrace)
-			log.Printf(strings.Repeat("-", 70) + "\n")
+			log.Print(strings.Repeat("-", 70) + "\n")
 		}
 
 		at.decisionLogger.LogDecision(record)
@@ -323,11 +594,25 @@ func (at *AutoTrader) runCycle() error {
 	}
 
 	// 5. 打印AI思维链
-	log.Printf("\n" + strings.Repeat("-", 70))
+	log.Print("\n" + strings.Repeat("-", 70))
 	log.Println("💭 AI思维链分析:")
 	log.Println(strings.Repeat("-", 70))
 	log.Println(decision.CoTTrace)
-	log.Printf(strings.Repeat("-", 70) + "\n")
+	log.Print(strings.Repeat("-", 70) + "\n")
+
+	// 按市场状态启用/停用用户策略（在运行用户策略前切换，本周期生效）
+	if at.config.Regime.enabled() {
+		at.updateRegime(ctx, record)
+	}
+
+	// 用户策略（沙箱中执行，决策与AI决策一起排序执行）
+	if len(at.strategies) > 0 {
+		decision.Decisions = append(decision.Decisions, at.runStrategies(ctx, record)...)
+	}
+	// 信号组合：成员策略的开仓信号投票通过后合并为一个决策（在风控和仓位计算之前）
+	if at.config.Ensemble.enabled() {
+		decision.Decisions = at.combineSignals(decision.Decisions, record)
+	}
 
 	// 6. 打印AI决策
 	log.Printf("📋 AI决策列表 (%d 个):\n", len(decision.Decisions))
@@ -349,31 +634,72 @@ func (at *AutoTrader) runCycle() error {
 	}
 	log.Println()
 
+	// 按网格成交情况调整止损/止盈，按最新行情移动已有持仓的止损（在执行新决策前）
+	at.manageGrids()
+	at.updateTrailingStops(ctx.MarketDataMap)
+	at.managePyramids(record)
+
+	// 延迟预算检查：决策基于的行情已过时，放弃本周期开仓（平仓只减少风险，照常执行）
+	if latency.OverBudget() {
+		latency.Aborted = true
+		log.Printf("⏱ [%s] 周期耗时 %.1fs 超出延迟预算 %.1fs（AI调用 %.1fs），放弃本周期开仓，平仓照常执行",
+			at.name, latency.Elapsed().Seconds(), at.config.LatencyBudget.Seconds(), float64(latency.LLMMs)/1000)
+		record.ErrorMessage = fmt.Sprintf("超出延迟预算（%.1fs > %.1fs），放弃开仓", latency.Elapsed().Seconds(), at.config.LatencyBudget.Seconds())
+		sortedDecisions = withoutOpens(sortedDecisions, record)
+	}
+
 	// 执行决策并记录结果
+	orderStart := time.Now()
 	for _, d := range sortedDecisions {
+		at.attributeDecision(&d, "")
+		if isPairAction(d.Action) {
+			legs, err := at.executePair(&d, ctx)
+			if err != nil {
+				log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+			} else {
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+			}
+			record.Decisions = append(record.Decisions, legs...)
+			continue
+		}
 		actionRecord := logger.DecisionAction{
-			Action:    d.Action,
-			Symbol:    d.Symbol,
-			Quantity:  0,
-			Leverage:  d.Leverage,
-			Price:     0,
-			Timestamp: time.Now(),
-			Success:   false,
+			Action:     d.Action,
+			Symbol:     d.Symbol,
+			Quantity:   0,
+			Leverage:   d.Leverage,
+			Price:      0,
+			StrategyID: d.StrategyID,
+			Tags:       d.Tags,
+			Timestamp:  time.Now(),
+			Success:    false,
+		}
+		if d.Explanation != nil {
+			actionRecord.Explanation = d.Explanation.Summary
+			actionRecord.Readings = d.Explanation.Values()
+			log.Printf("  📝 %s %s 依据: %s", d.Symbol, d.Action, d.Explanation.Summary)
 		}
 
 		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
 			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
 			actionRecord.Error = err.Error()
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+			at.deferEntry(d, "", err)
 		} else {
 			actionRecord.Success = true
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+			at.publishSignal(&d, &actionRecord)
 			// 成功执行后短暂延迟
 			time.Sleep(1 * time.Second)
 		}
 
 		record.Decisions = append(record.Decisions, actionRecord)
 	}
+	latency.OrderMs = time.Since(orderStart).Milliseconds()
+	log.Printf("⏱ 周期耗时 %.1fs（数据 %dms | 计算 %dms | AI %dms | 风控 %dms | 下单 %dms）",
+		latency.Elapsed().Seconds(), latency.FetchMs, latency.ComputeMs, latency.LLMMs, latency.RiskCheckMs, latency.OrderMs)
+
+	at.refreshOpenRisk(0)
 
 	// 8. 保存决策记录
 	if err := at.decisionLogger.LogDecision(record); err != nil {
@@ -383,6 +709,58 @@ func (at *AutoTrader) runCycle() error {
 	return nil
 }
 
+// checkRiskLimits 检查账户级别的风控限制，返回需要暂停交易的原因（未触发或未启用enforce_risk_limits时返回空字符串）
+func (at *AutoTrader) checkRiskLimits(totalEquity float64) string {
+	reason, drawdown := at.riskLimitBreach(totalEquity)
+	if reason != "" && !at.config.EnforceRiskLimits {
+		log.Printf("⚠️  [%s] 超出风控上限: %s（未启用enforce_risk_limits，只记录不暂停）", at.name, reason)
+		return ""
+	}
+	if drawdown {
+		at.rebasePeak = true
+	}
+	return reason
+}
+
+// riskLimitBreach 更新当日盈亏和净值峰值，返回超出的风控上限（未超出返回空字符串）以及是否为回撤超限
+func (at *AutoTrader) riskLimitBreach(totalEquity float64) (string, bool) {
+	if totalEquity <= 0 {
+		return "", false
+	}
+
+	if at.dayStartEquity <= 0 {
+		at.dayStartEquity = totalEquity
+	}
+	// 回撤触发的暂停结束后以当前净值作为新峰值，否则净值回到上限以内之前每次暂停结束都会立即再次触发
+	if at.rebasePeak && !market.Clock.Now().Before(at.stopUntil) {
+		log.Printf("📉 [%s] 回撤暂停结束，净值峰值从 %.2f 重置为 %.2f", at.name, at.peakEquity, totalEquity)
+		at.peakEquity = totalEquity
+		at.rebasePeak = false
+	}
+	if totalEquity > at.peakEquity {
+		at.peakEquity = totalEquity
+	}
+	at.dailyPnL = totalEquity - at.dayStartEquity
+
+	// 日亏损检查（当日内持续生效，直到每日重置）
+	if at.config.MaxDailyLoss > 0 {
+		dailyLossPct := -at.dailyPnL / at.dayStartEquity * 100
+		if dailyLossPct >= at.config.MaxDailyLoss {
+			return fmt.Sprintf("日亏损%.2f%% ≥ 上限%.2f%%", dailyLossPct, at.config.MaxDailyLoss), false
+		}
+	}
+
+	// 回撤检查（暂停期间峰值保持不变，暂停结束后重置）
+	if at.config.MaxDrawdown > 0 {
+		drawdownPct := (at.peakEquity - totalEquity) / at.peakEquity * 100
+		if drawdownPct >= at.config.MaxDrawdown {
+			return fmt.Sprintf("回撤%.2f%% ≥ 上限%.2f%%", drawdownPct, at.config.MaxDrawdown), true
+		}
+	}
+
+	return "", false
+}
+
 // buildTradingContext 构建交易上下文
 func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 1. 获取账户信息
@@ -414,6 +792,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
+	at.rawBalance, at.rawPositions = balance, positions
 
 	var positionInfos []decision.PositionInfo
 	totalMarginUsed := 0.0
@@ -454,10 +833,16 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		currentPositionKeys[posKey] = true
 		if _, exists := at.positionFirstSeenTime[posKey]; !exists {
 			// 新持仓，记录当前时间
-			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+			at.positionFirstSeenTime[posKey] = market.Clock.Now().UnixMilli()
 		}
 		updateTime := at.positionFirstSeenTime[posKey]
 
+		// 本地记录的止损止盈价（接管的未记录持仓为0）
+		var stopLoss, takeProfit float64
+		if tracked, ok := at.trackedPositions[posKey]; ok {
+			stopLoss, takeProfit = tracked.StopLoss, tracked.TakeProfit
+		}
+
 		positionInfos = append(positionInfos, decision.PositionInfo{
 			Symbol:           symbol,
 			Side:             side,
@@ -470,6 +855,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			LiquidationPrice: liquidationPrice,
 			MarginUsed:       marginUsed,
 			UpdateTime:       updateTime,
+			StopLoss:         stopLoss,
+			TakeProfit:       takeProfit,
 		})
 	}
 
@@ -504,6 +891,16 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	log.Printf("📋 合并币种池: AI500前%d + OI_Top20 = 总计%d个候选币种",
 		ai500Limit, len(candidateCoins))
 
+	// 订阅持仓和候选币种的实时买卖价（替换上个周期的集合，不再关注的币种取消订阅；实时行情中心未启动时忽略）
+	watchSymbols := make([]string, 0, len(positionInfos)+len(candidateCoins))
+	for _, pos := range positionInfos {
+		watchSymbols = append(watchSymbols, pos.Symbol)
+	}
+	for _, coin := range candidateCoins {
+		watchSymbols = append(watchSymbols, coin.Symbol)
+	}
+	market.Hub.Watch(at.id, watchSymbols...)
+
 	// 4. 计算总盈亏
 	totalPnL := totalEquity - at.initialBalance
 	totalPnLPct := 0.0
@@ -527,8 +924,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 	// 6. 构建上下文
 	ctx := &decision.Context{
-		CurrentTime:     time.Now().Format("2006-01-02 15:04:05"),
-		RuntimeMinutes:  int(time.Since(at.startTime).Minutes()),
+		CurrentTime:     market.Clock.Now().Format("2006-01-02 15:04:05"),
+		RuntimeMinutes:  int(market.Clock.Now().Sub(at.startTime).Minutes()),
 		CallCount:       at.callCount,
 		BTCETHLeverage:  at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
 		AltcoinLeverage: at.config.AltcoinLeverage, // 使用配置的杠杆倍数
@@ -541,14 +938,61 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			MarginUsedPct:    marginUsedPct,
 			PositionCount:    len(positionInfos),
 		},
-		Positions:      positionInfos,
-		CandidateCoins: candidateCoins,
-		Performance:    performance, // 添加历史表现分析
+		Positions:       positionInfos,
+		CandidateCoins:  candidateCoins,
+		Performance:     performance, // 添加历史表现分析
+		MemorySize:      at.config.MemorySize,
+		SymbolOverrides: at.config.SymbolOverrides,
+		PrevMarketData:  at.lastMarketData,
+		Pairs:           at.pairContext(),
+		EntryBlocked:    at.entryBlock(),
+		FundingBlackout: at.config.FundingBlackout,
 	}
 
 	return ctx, nil
 }
 
+// entryBlock 当前禁止开新仓的原因（风控暂停、全局熔断、交易时段过滤），允许开仓时返回空字符串
+func (at *AutoTrader) entryBlock() string {
+	if now := market.Clock.Now(); now.Before(at.stopUntil) {
+		return fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", at.stopUntil.Sub(now).Minutes())
+	}
+	if tripped, reason := risk.Breaker.Tripped(); tripped {
+		return "全局熔断: " + reason
+	}
+	if at.config.TradingHours == nil {
+		return ""
+	}
+	if blocked, reason := at.config.TradingHours.Blocked(market.Clock.Now()); blocked {
+		return reason
+	}
+	return ""
+}
+
+// checkSymbolTier 下单前按币种分级检查杠杆和开仓后持仓的名义价值（未配置分级时不限制）
+func (at *AutoTrader) checkSymbolTier(symbol string, leverage int, notional float64) error {
+	if at.config.SymbolTiers == nil {
+		return nil
+	}
+	if err := at.config.SymbolTiers.Check(symbol, leverage, notional); err != nil {
+		return fmt.Errorf("❌ 拒绝开仓: %w", err)
+	}
+	return nil
+}
+
+// attributeDecision 填充决策的策略ID：决策自带的优先，其次为外部信号来源，最后为trader配置的策略ID
+func (at *AutoTrader) attributeDecision(d *decision.Decision, source string) {
+	switch {
+	case d.StrategyID != "":
+	case source != "":
+		d.StrategyID = source
+	case at.config.StrategyID != "":
+		d.StrategyID = at.config.StrategyID
+	default:
+		d.StrategyID = logger.DefaultStrategyID
+	}
+}
+
 // executeDecisionWithRecord 执行AI决策并记录详细信息
 func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	switch decision.Action {
@@ -582,33 +1026,71 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		}
 	}
 
-	// 获取当前价格
+	// 市价开仓前检查买卖价差
+	if at.entryOrderType() == "market" {
+		if err := at.checkSpread(decision.Symbol); err != nil {
+			return err
+		}
+	}
+
+	// 获取当前价格（优先使用实时行情）
 	marketData, err := market.Get(decision.Symbol)
 	if err != nil {
 		return err
 	}
+	price := livePrice(decision.Symbol, "long", marketData.CurrentPrice)
+
+	// 下单前检查：实时价格已越过止损/止盈时拒绝开仓
+	if err := checkStopsAgainstPrice(decision, "long", price); err != nil {
+		return err
+	}
+
+	// 按策略的仓位模式计算仓位，再按资金预算限制
+	if err := at.applySizing(decision, price); err != nil {
+		return err
+	}
+	if err := at.applyAllocation(decision); err != nil {
+		return err
+	}
+	if err := at.applyLiquidityGuard(decision, "long"); err != nil {
+		return err
+	}
+	if err := at.checkSymbolTier(decision.Symbol, decision.Leverage, decision.PositionSizeUSD); err != nil {
+		return err
+	}
+	if err := at.checkOpenRisk(decision.Symbol, entryRisk(decision.PositionSizeUSD, price, decision.StopLoss)); err != nil {
+		return err
+	}
 
-	// 计算数量
-	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
+	// 计算数量（网格开仓时首层按正常开仓下单）
+	quantity := decision.PositionSizeUSD / price
+	grid, err := at.newGridEntry(decision, "long", quantity, price)
+	if err != nil {
+		return err
+	}
+	if grid != nil {
+		quantity = grid.Levels[0].Quantity
+	}
 	actionRecord.Quantity = quantity
-	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.Price = price
+	actionRecord.DecisionPrice = marketData.CurrentPrice
+	actionRecord.OrderType = at.entryOrderType()
 
-	// 开仓
-	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
+	// 开仓（幂等：同一信号不会重复下单）
+	order, err := at.placeOpenOrder(decision.Symbol, "long", quantity, decision.Leverage, price, decision.StrategyID)
 	if err != nil {
 		return err
 	}
+	at.recordFill(actionRecord, decision.Symbol, "long", order, at.entryFilled(order))
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = orderID(order)
 
 	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_long"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.positionFirstSeenTime[posKey] = market.Clock.Now().UnixMilli()
 
 	// 设置止损止盈
 	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
@@ -617,6 +1099,17 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
 		log.Printf("  ⚠ 设置止盈失败: %v", err)
 	}
+	if at.entryFilled(order) {
+		at.trackPosition(decision.Symbol, "long", quantity, decision.StopLoss, decision.TakeProfit, decision.StrategyID)
+	}
+	if grid != nil {
+		filled := 0.0
+		if at.entryFilled(order) {
+			filled = quantity
+		}
+		at.placeGridLevels(grid, filled)
+	}
+	at.startPlan(decision, "long", order, quantity, price, at.entryFilled(order), grid)
 
 	return nil
 }
@@ -635,33 +1128,71 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		}
 	}
 
-	// 获取当前价格
+	// 市价开仓前检查买卖价差
+	if at.entryOrderType() == "market" {
+		if err := at.checkSpread(decision.Symbol); err != nil {
+			return err
+		}
+	}
+
+	// 获取当前价格（优先使用实时行情）
 	marketData, err := market.Get(decision.Symbol)
 	if err != nil {
 		return err
 	}
+	price := livePrice(decision.Symbol, "short", marketData.CurrentPrice)
+
+	// 下单前检查：实时价格已越过止损/止盈时拒绝开仓
+	if err := checkStopsAgainstPrice(decision, "short", price); err != nil {
+		return err
+	}
+
+	// 按策略的仓位模式计算仓位，再按资金预算限制
+	if err := at.applySizing(decision, price); err != nil {
+		return err
+	}
+	if err := at.applyAllocation(decision); err != nil {
+		return err
+	}
+	if err := at.applyLiquidityGuard(decision, "short"); err != nil {
+		return err
+	}
+	if err := at.checkSymbolTier(decision.Symbol, decision.Leverage, decision.PositionSizeUSD); err != nil {
+		return err
+	}
+	if err := at.checkOpenRisk(decision.Symbol, entryRisk(decision.PositionSizeUSD, price, decision.StopLoss)); err != nil {
+		return err
+	}
 
-	// 计算数量
-	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
+	// 计算数量（网格开仓时首层按正常开仓下单）
+	quantity := decision.PositionSizeUSD / price
+	grid, err := at.newGridEntry(decision, "short", quantity, price)
+	if err != nil {
+		return err
+	}
+	if grid != nil {
+		quantity = grid.Levels[0].Quantity
+	}
 	actionRecord.Quantity = quantity
-	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.Price = price
+	actionRecord.DecisionPrice = marketData.CurrentPrice
+	actionRecord.OrderType = at.entryOrderType()
 
-	// 开仓
-	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
+	// 开仓（幂等：同一信号不会重复下单）
+	order, err := at.placeOpenOrder(decision.Symbol, "short", quantity, decision.Leverage, price, decision.StrategyID)
 	if err != nil {
 		return err
 	}
+	at.recordFill(actionRecord, decision.Symbol, "short", order, at.entryFilled(order))
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = orderID(order)
 
 	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_short"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.positionFirstSeenTime[posKey] = market.Clock.Now().UnixMilli()
 
 	// 设置止损止盈
 	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
@@ -670,7 +1201,219 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
 		log.Printf("  ⚠ 设置止盈失败: %v", err)
 	}
+	if at.entryFilled(order) {
+		at.trackPosition(decision.Symbol, "short", quantity, decision.StopLoss, decision.TakeProfit, decision.StrategyID)
+	}
+	if grid != nil {
+		filled := 0.0
+		if at.entryFilled(order) {
+			filled = quantity
+		}
+		at.placeGridLevels(grid, filled)
+	}
+	at.startPlan(decision, "short", order, quantity, price, at.entryFilled(order), grid)
+
+	return nil
+}
+
+// livePriceMaxAge 实时价格的最大有效期，超过则回退到K线价格
+const livePriceMaxAge = 5 * time.Second
+
+// livePrice 获取下单参考价：优先使用bookTicker（开多取卖一价、开空取买一价），
+// 其次使用标记价格，实时行情不可用时回退到fallback（最近K线收盘价）
+func livePrice(symbol, side string, fallback float64) float64 {
+	if bid, ask, ok := market.Hub.BestBidAsk(symbol, livePriceMaxAge); ok {
+		if side == "long" {
+			return ask
+		}
+		return bid
+	}
+	if mark, ok := market.Hub.MarkPrice(symbol, livePriceMaxAge); ok {
+		return mark
+	}
+	return fallback
+}
+
+// publishSignal 发布已执行的开平仓信号事件（附信号依据，供通知使用）
+func (at *AutoTrader) publishSignal(d *decision.Decision, actionRecord *logger.DecisionAction) {
+	message := fmt.Sprintf("[%s] %s %s", at.name, d.Symbol, d.Action)
+	if actionRecord.Explanation != "" {
+		message += " | " + actionRecord.Explanation
+	}
+	events.Publish(events.Event{
+		Type:     events.TypeTradeSignal,
+		Severity: events.SeverityInfo,
+		Message:  message,
+		Data: map[string]interface{}{
+			"trader_id":   at.id,
+			"symbol":      d.Symbol,
+			"action":      d.Action,
+			"price":       actionRecord.Price,
+			"quantity":    actionRecord.Quantity,
+			"explanation": d.Explanation,
+			"leverage":    d.Leverage,
+			"stop_loss":   d.StopLoss,
+			"take_profit": d.TakeProfit,
+			"confidence":  d.Confidence,
+			"reasoning":   d.Reasoning,
+			"strategy_id": d.StrategyID,
+			"tags":        d.Tags,
+		},
+	})
+}
+
+// decisionSnapshot 本周期决策输入的完整快照（交易上下文、行情数据、交易所原始响应）
+func (at *AutoTrader) decisionSnapshot(ctx *decision.Context, cycleStart time.Time) *logger.DecisionSnapshot {
+	snapshot := &logger.DecisionSnapshot{
+		CycleStart: cycleStart,
+		Context:    ctx,
+		MarketData: ctx.MarketDataMap,
+		Balance:    at.rawBalance,
+		Positions:  at.rawPositions,
+	}
+	if at.config.Snapshots.RawPayloads {
+		snapshot.Payloads = market.BinancePayloads.Since(cycleStart)
+	}
+	return snapshot
+}
+
+// publishSnapshot 发布本周期的行情快照事件（价格、涨跌幅、资金费率、持仓量、强度评分，以及账户净值和回撤）
+func (at *AutoTrader) publishSnapshot(ctx *decision.Context) {
+	symbols := make(map[string]interface{}, len(ctx.MarketDataMap))
+	for symbol, data := range ctx.MarketDataMap {
+		snapshot := map[string]interface{}{
+			"price":           data.CurrentPrice,
+			"price_change_1h": data.PriceChange1h,
+			"price_change_4h": data.PriceChange4h,
+			"funding_rate":    data.FundingRate,
+			"strength_score":  data.StrengthScore,
+		}
+		if data.OpenInterest != nil {
+			snapshot["open_interest"] = data.OpenInterest.Latest
+		}
+		symbols[symbol] = snapshot
+	}
+	drawdownPct := 0.0
+	if at.peakEquity > 0 && ctx.Account.TotalEquity < at.peakEquity {
+		drawdownPct = (at.peakEquity - ctx.Account.TotalEquity) / at.peakEquity * 100
+	}
+	events.Publish(events.Event{
+		Type:     events.TypeMarketSnapshot,
+		Severity: events.SeverityInfo,
+		Message:  fmt.Sprintf("[%s] 行情快照（%d个币种）", at.name, len(ctx.MarketDataMap)),
+		Data: map[string]interface{}{
+			"trader_id":    at.id,
+			"equity":       ctx.Account.TotalEquity,
+			"drawdown_pct": drawdownPct,
+			"symbols":      symbols,
+		},
+	})
+}
+
+// entryOrderType 开仓订单类型（market 或 limit）
+func (at *AutoTrader) entryOrderType() string {
+	if at.config.EntryOrderType == "limit" {
+		return "limit"
+	}
+	return "market"
+}
+
+// recordFill 记录实际成交均价并输出滑点
+// 订单响应不含成交均价时，已成交的开仓单（checkPosition=true）回退到持仓开仓均价
+func (at *AutoTrader) recordFill(actionRecord *logger.DecisionAction, symbol, side string, order map[string]interface{}, checkPosition bool) {
+	fill := orderAvgPrice(order)
+	if fill <= 0 && checkPosition {
+		positions, err := at.trader.GetPositions()
+		if err == nil {
+			for _, pos := range positions {
+				if pos["symbol"] == symbol && pos["side"] == side {
+					fill, _ = pos["entryPrice"].(float64)
+					break
+				}
+			}
+		}
+	}
+	if fill <= 0 {
+		return
+	}
+
+	actionRecord.FillPrice = fill
+	actionRecord.Price = fill
+	data := map[string]interface{}{
+		"trader_id":      at.id,
+		"symbol":         symbol,
+		"side":           side,
+		"action":         actionRecord.Action,
+		"price":          fill,
+		"quantity":       actionRecord.Quantity,
+		"decision_price": actionRecord.DecisionPrice,
+		"strategy_id":    actionRecord.StrategyID,
+		"tags":           actionRecord.Tags,
+	}
+	if bps, _, ok := actionRecord.Slippage(); ok {
+		data["slippage_bps"] = bps
+		log.Printf("  📏 成交均价 %.4f（决策价 %.4f，滑点 %.1f bps）", fill, actionRecord.DecisionPrice, bps)
+	}
+	events.Publish(events.Event{
+		Type:     events.TypeOrderFill,
+		Severity: events.SeverityInfo,
+		Message:  fmt.Sprintf("[%s] %s %s 成交 %.4f", at.name, symbol, actionRecord.Action, fill),
+		Data:     data,
+	})
+}
+
+// orderAvgPrice 从订单响应中读取成交均价（兼容数字和字符串格式）
+func orderAvgPrice(order map[string]interface{}) float64 {
+	switch v := order["avgPrice"].(type) {
+	case float64:
+		return v
+	case string:
+		price, _ := strconv.ParseFloat(v, 64)
+		return price
+	}
+	return 0
+}
+
+// orderID 订单ID（各交易所返回的类型不同：币安为int64，Aster等直接解析JSON的为float64或字符串）
+func orderID(order map[string]interface{}) int64 {
+	switch v := order["orderId"].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case json.Number:
+		id, _ := v.Int64()
+		return id
+	case string:
+		id, _ := strconv.ParseInt(v, 10, 64)
+		return id
+	}
+	return 0
+}
 
+// checkStopsAgainstPrice 检查止损止盈是否仍在当前价格的正确一侧
+// 多仓要求 止损 < 价格 < 止盈，空仓要求 止盈 < 价格 < 止损
+func checkStopsAgainstPrice(d *decision.Decision, side string, price float64) error {
+	if price <= 0 {
+		return nil
+	}
+	if side == "long" {
+		if d.StopLoss > 0 && price <= d.StopLoss {
+			return fmt.Errorf("❌ %s 当前价格 %.4f 已低于止损价 %.4f，拒绝开多", d.Symbol, price, d.StopLoss)
+		}
+		if d.TakeProfit > 0 && price >= d.TakeProfit {
+			return fmt.Errorf("❌ %s 当前价格 %.4f 已高于止盈价 %.4f，拒绝开多", d.Symbol, price, d.TakeProfit)
+		}
+		return nil
+	}
+	if d.StopLoss > 0 && price >= d.StopLoss {
+		return fmt.Errorf("❌ %s 当前价格 %.4f 已高于止损价 %.4f，拒绝开空", d.Symbol, price, d.StopLoss)
+	}
+	if d.TakeProfit > 0 && price <= d.TakeProfit {
+		return fmt.Errorf("❌ %s 当前价格 %.4f 已低于止盈价 %.4f，拒绝开空", d.Symbol, price, d.TakeProfit)
+	}
 	return nil
 }
 
@@ -684,18 +1427,21 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.DecisionPrice = marketData.CurrentPrice
+	actionRecord.OrderType = "market"
 
 	// 平仓
 	order, err := at.trader.CloseLong(decision.Symbol, 0) // 0 = 全部平仓
 	if err != nil {
 		return err
 	}
+	at.recordFill(actionRecord, decision.Symbol, "long", order, false)
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = orderID(order)
 
+	at.untrackPosition(decision.Symbol, "long")
+	at.exitPlan(decision.Symbol, "long", order, 0, "平仓决策")
 	log.Printf("  ✓ 平仓成功")
 	return nil
 }
@@ -710,18 +1456,21 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.DecisionPrice = marketData.CurrentPrice
+	actionRecord.OrderType = "market"
 
 	// 平仓
 	order, err := at.trader.CloseShort(decision.Symbol, 0) // 0 = 全部平仓
 	if err != nil {
 		return err
 	}
+	at.recordFill(actionRecord, decision.Symbol, "short", order, false)
 
 	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	actionRecord.OrderID = orderID(order)
 
+	at.untrackPosition(decision.Symbol, "short")
+	at.exitPlan(decision.Symbol, "short", order, 0, "平仓决策")
 	log.Printf("  ✓ 平仓成功")
 	return nil
 }
@@ -767,6 +1516,7 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"stop_until":      at.stopUntil.Format(time.RFC3339),
 		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
 		"ai_provider":     aiProvider,
+		"last_latency":    at.lastLatency,
 	}
 }
 
@@ -903,6 +1653,19 @@ func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
 	return result, nil
 }
 
+// withoutOpens 去掉开仓决策（记录在执行日志中），保留平仓和观望
+func withoutOpens(decisions []decision.Decision, record *logger.DecisionRecord) []decision.Decision {
+	kept := make([]decision.Decision, 0, len(decisions))
+	for _, d := range decisions {
+		if decision.IsOpenAction(d.Action) {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏱ %s %s 超出延迟预算，已跳过", d.Symbol, d.Action))
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept
+}
+
 // sortDecisionsByPriority 对决策排序：先平仓，再开仓，最后hold/wait
 // 这样可以避免换仓时仓位叠加超限
 func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision {
@@ -913,9 +1676,9 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 	// 定义优先级
 	getActionPriority := func(action string) int {
 		switch action {
-		case "close_long", "close_short":
+		case "close_long", "close_short", decision.ActionClosePair:
 			return 1 // 最高优先级：先平仓
-		case "open_long", "open_short":
+		case "open_long", "open_short", decision.ActionOpenPairLong, decision.ActionOpenPairShort:
 			return 2 // 次优先级：后开仓
 		case "hold", "wait":
 			return 3 // 最低优先级：观望
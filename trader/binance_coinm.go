@@ -0,0 +1,845 @@
+package trader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/market"
+	"nofx/symbols"
+	"nofx/utils"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/delivery"
+)
+
+// COIN-M合约类型
+const (
+	CoinMContractPerpetual      = "PERPETUAL"
+	CoinMContractCurrentQuarter = "CURRENT_QUARTER"
+	CoinMContractNextQuarter    = "NEXT_QUARTER"
+)
+
+// coinMContract 币本位合约信息
+type coinMContract struct {
+	Symbol       string  // 合约代码（如 BTCUSD_PERP、BTCUSD_251226）
+	Pair         string  // 标的交易对（如 BTCUSD）
+	MarginAsset  string  // 保证金币种（如 BTC）
+	ContractSize float64 // 每张合约面值（USD）
+	Precision    int     // 数量精度（张）
+	TickSize     float64 // 价格最小变动单位
+}
+
+// CoinMTrader 币安币本位合约交易器（dapi.binance.com）
+// 对外仍使用 BTCUSDT 形式的币种和以币计的数量，内部换算为合约张数；
+// 余额和盈亏以保证金币种计价，按标记价格折算为USD供AI决策使用
+type CoinMTrader struct {
+	client       *delivery.Client
+	contractType string
+
+	// 合约信息缓存（按USDT形式的币种索引）
+	contracts      map[string]*coinMContract
+	contractsMutex sync.RWMutex
+
+	// 余额缓存
+	cachedBalance     map[string]interface{}
+	balanceCacheTime  time.Time
+	balanceCacheMutex sync.RWMutex
+
+	// 持仓缓存
+	cachedPositions     []map[string]interface{}
+	positionsCacheTime  time.Time
+	positionsCacheMutex sync.RWMutex
+
+	// 缓存有效期（15秒）
+	cacheDuration time.Duration
+}
+
+// NewCoinMTrader 创建币本位合约交易器
+// contractType: "perpetual"（默认）、"current_quarter" 或 "next_quarter"
+func NewCoinMTrader(apiKey, secretKey, contractType string) (*CoinMTrader, error) {
+	normalized := strings.ToUpper(contractType)
+	if normalized == "" {
+		normalized = CoinMContractPerpetual
+	}
+	if normalized != CoinMContractPerpetual && normalized != CoinMContractCurrentQuarter && normalized != CoinMContractNextQuarter {
+		return nil, fmt.Errorf("不支持的币本位合约类型: %s", contractType)
+	}
+
+	// 签名中间件统一校正时间戳和recvWindow
+	client := delivery.NewClient(apiKey, secretKey)
+	signer := NewRequestSigner(secretKey, "https://dapi.binance.com/dapi/v1/time", 5000, nil)
+	client.HTTPClient = &http.Client{Timeout: signedRequestTimeout, Transport: signer.Transport(nil)}
+
+	return &CoinMTrader{
+		client:        client,
+		contractType:  normalized,
+		cacheDuration: 15 * time.Second, // 15秒缓存
+	}, nil
+}
+
+// loadContracts 加载合约信息（仅首次调用时请求交易所）
+func (t *CoinMTrader) loadContracts() error {
+	t.contractsMutex.RLock()
+	loaded := t.contracts != nil
+	t.contractsMutex.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("获取币本位合约信息失败: %w", err)
+	}
+
+	contracts := make(map[string]*coinMContract)
+	for _, s := range exchangeInfo.Symbols {
+		if s.ContractType != t.contractType || s.ContractStatus != "TRADING" {
+			continue
+		}
+
+		precision := s.QuantityPrecision
+		tickSize := 0.0
+		for _, filter := range s.Filters {
+			if filter["filterType"] == "LOT_SIZE" {
+				if stepSize, ok := filter["stepSize"].(string); ok {
+					precision = calculatePrecision(stepSize)
+				}
+			}
+			if filter["filterType"] == "PRICE_FILTER" {
+				if tick, ok := filter["tickSize"].(string); ok {
+					tickSize, _ = strconv.ParseFloat(tick, 64)
+				}
+			}
+		}
+
+		// 注册到币种注册表（合约面值、数量步长、上线时间）
+		canonical := symbols.Canonical(s.Pair)
+		symbols.Register(canonical, symbols.Instrument{
+			Exchange:     "binance_coinm",
+			ID:           s.Symbol,
+			ContractSize: float64(s.ContractSize),
+			StepSize:     math.Pow(10, -float64(precision)),
+			ListedAt:     time.UnixMilli(s.OnboardDate),
+		})
+
+		contracts[symbols.System(canonical)] = &coinMContract{
+			Symbol:       s.Symbol,
+			Pair:         s.Pair,
+			MarginAsset:  s.MarginAsset,
+			ContractSize: float64(s.ContractSize),
+			Precision:    precision,
+			TickSize:     tickSize,
+		}
+	}
+
+	log.Printf("✓ 已加载 %d 个币本位合约（%s）", len(contracts), t.contractType)
+
+	t.contractsMutex.Lock()
+	t.contracts = contracts
+	t.contractsMutex.Unlock()
+	return nil
+}
+
+// getContract 根据系统币种（如 BTCUSDT）查找对应的币本位合约
+func (t *CoinMTrader) getContract(symbol string) (*coinMContract, error) {
+	if err := t.loadContracts(); err != nil {
+		return nil, err
+	}
+
+	t.contractsMutex.RLock()
+	defer t.contractsMutex.RUnlock()
+
+	contract, ok := t.contracts[symbol]
+	if !ok {
+		return nil, fmt.Errorf("%s 没有对应的币本位合约（%s）", symbol, t.contractType)
+	}
+	return contract, nil
+}
+
+// findContractBySymbol 根据合约代码查找合约信息
+func (t *CoinMTrader) findContractBySymbol(contractSymbol string) (string, *coinMContract) {
+	t.contractsMutex.RLock()
+	defer t.contractsMutex.RUnlock()
+
+	for symbol, contract := range t.contracts {
+		if contract.Symbol == contractSymbol {
+			return symbol, contract
+		}
+	}
+	return "", nil
+}
+
+// contractsForQuantity 将以币计的数量换算为合约张数（向下取整到精度）
+// 张数 = 数量 × 价格 / 合约面值
+func (t *CoinMTrader) contractsForQuantity(contract *coinMContract, quantity, price float64) float64 {
+	if contract.ContractSize <= 0 || price <= 0 {
+		return 0
+	}
+	factor := math.Pow(10, float64(contract.Precision))
+	return math.Floor(quantity*price/contract.ContractSize*factor) / factor
+}
+
+// GetBalance 获取账户余额（带缓存，按标记价格折算为USD）
+func (t *CoinMTrader) GetBalance() (map[string]interface{}, error) {
+	// 先检查缓存是否有效
+	t.balanceCacheMutex.RLock()
+	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
+		cacheAge := time.Since(t.balanceCacheTime)
+		t.balanceCacheMutex.RUnlock()
+		log.Printf("✓ 使用缓存的币本位账户余额（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		return t.cachedBalance, nil
+	}
+	t.balanceCacheMutex.RUnlock()
+
+	log.Printf("🔄 缓存过期，正在调用币安币本位API获取账户余额...")
+	account, err := t.client.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		log.Printf("❌ 币安币本位API调用失败: %v", err)
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+
+	var totalWalletUSD, availableUSD, unrealizedUSD float64
+	coinBalances := make(map[string]float64)
+	for _, asset := range account.Assets {
+		walletBalance, _ := strconv.ParseFloat(asset.WalletBalance, 64)
+		unrealized, _ := strconv.ParseFloat(asset.UnrealizedProfit, 64)
+		available, _ := strconv.ParseFloat(asset.AvailableBalance, 64)
+		if walletBalance == 0 && unrealized == 0 {
+			continue
+		}
+
+		price, err := t.GetMarketPrice(symbols.System(asset.Asset))
+		if err != nil {
+			log.Printf("  ⚠ 无法获取 %s 价格，跳过该币种余额: %v", asset.Asset, err)
+			continue
+		}
+
+		coinBalances[asset.Asset] = walletBalance
+		totalWalletUSD += walletBalance * price
+		availableUSD += available * price
+		unrealizedUSD += unrealized * price
+
+		log.Printf("✓ 币本位资产 %s: 余额=%.6f, 可用=%.6f, 未实现盈亏=%.6f (≈$%.2f)",
+			asset.Asset, walletBalance, available, unrealized, walletBalance*price)
+	}
+
+	result := make(map[string]interface{})
+	result["totalWalletBalance"] = totalWalletUSD
+	result["availableBalance"] = availableUSD
+	result["totalUnrealizedProfit"] = unrealizedUSD
+	result["coinBalances"] = coinBalances
+
+	// 更新缓存
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = result
+	t.balanceCacheTime = time.Now()
+	t.balanceCacheMutex.Unlock()
+
+	return result, nil
+}
+
+// GetPositions 获取所有持仓（带缓存）
+// positionAmt 换算为以币计的数量，unRealizedProfit 折算为USD，原始张数和币本位盈亏另行保留
+func (t *CoinMTrader) GetPositions() ([]map[string]interface{}, error) {
+	// 先检查缓存是否有效
+	t.positionsCacheMutex.RLock()
+	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
+		cacheAge := time.Since(t.positionsCacheTime)
+		t.positionsCacheMutex.RUnlock()
+		log.Printf("✓ 使用缓存的币本位持仓信息（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		return t.cachedPositions, nil
+	}
+	t.positionsCacheMutex.RUnlock()
+
+	if err := t.loadContracts(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🔄 缓存过期，正在调用币安币本位API获取持仓信息...")
+	positions, err := t.client.NewGetPositionRiskService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var result []map[string]interface{}
+	for _, pos := range positions {
+		contracts, _ := strconv.ParseFloat(pos.PositionAmt, 64)
+		if contracts == 0 {
+			continue // 跳过无持仓的
+		}
+
+		symbol, contract := t.findContractBySymbol(pos.Symbol)
+		if contract == nil {
+			continue // 非当前合约类型的持仓（如其他季度合约）
+		}
+
+		markPrice, _ := strconv.ParseFloat(pos.MarkPrice, 64)
+		entryPrice, _ := strconv.ParseFloat(pos.EntryPrice, 64)
+		unrealizedCoin, _ := strconv.ParseFloat(pos.UnRealizedProfit, 64)
+		if markPrice <= 0 {
+			continue
+		}
+
+		posMap := make(map[string]interface{})
+		posMap["symbol"] = symbol
+		posMap["contractSymbol"] = pos.Symbol
+		posMap["contracts"] = contracts
+		posMap["contractSize"] = contract.ContractSize
+		posMap["positionAmt"] = contracts * contract.ContractSize / markPrice
+		posMap["entryPrice"] = entryPrice
+		posMap["markPrice"] = markPrice
+		posMap["unRealizedProfit"] = unrealizedCoin * markPrice
+		posMap["unRealizedProfitCoin"] = unrealizedCoin
+		posMap["marginAsset"] = contract.MarginAsset
+		posMap["leverage"], _ = strconv.ParseFloat(pos.Leverage, 64)
+		posMap["liquidationPrice"], _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
+
+		// 判断方向
+		if contracts > 0 {
+			posMap["side"] = "long"
+		} else {
+			posMap["side"] = "short"
+		}
+
+		result = append(result, posMap)
+	}
+
+	// 更新缓存
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = result
+	t.positionsCacheTime = time.Now()
+	t.positionsCacheMutex.Unlock()
+
+	return result, nil
+}
+
+// invalidateCache 清除余额和持仓缓存（下单后持仓和保证金已变化，下一次查询必须读取交易所最新数据）
+func (t *CoinMTrader) invalidateCache() {
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = nil
+	t.balanceCacheMutex.Unlock()
+
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = nil
+	t.positionsCacheMutex.Unlock()
+}
+
+// SetLeverage 设置杠杆
+func (t *CoinMTrader) SetLeverage(symbol string, leverage int) error {
+	contract, err := t.getContract(symbol)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.client.NewChangeLeverageService().
+		Symbol(contract.Symbol).
+		Leverage(leverage).
+		Do(context.Background())
+
+	if err != nil {
+		if contains(err.Error(), "No need to change") {
+			log.Printf("  ✓ %s 杠杆已是 %dx", contract.Symbol, leverage)
+			return nil
+		}
+		return fmt.Errorf("设置杠杆失败: %w", err)
+	}
+
+	log.Printf("  ✓ %s 杠杆已切换为 %dx", contract.Symbol, leverage)
+	return nil
+}
+
+// setIsolatedMargin 设置逐仓模式
+func (t *CoinMTrader) setIsolatedMargin(contract *coinMContract) error {
+	err := t.client.NewChangeMarginTypeService().
+		Symbol(contract.Symbol).
+		MarginType(delivery.MarginTypeIsolated).
+		Do(context.Background())
+
+	if err != nil {
+		if contains(err.Error(), "No need to change") {
+			return nil
+		}
+		return fmt.Errorf("设置保证金模式失败: %w", err)
+	}
+
+	log.Printf("  ✓ %s 保证金模式已切换为 ISOLATED", contract.Symbol)
+	return nil
+}
+
+// openPosition 开仓（数量以币计，换算为合约张数下单）
+func (t *CoinMTrader) openPosition(symbol string, quantity float64, leverage int, clientOrderID string, side delivery.SideType, positionSide delivery.PositionSideType) (map[string]interface{}, error) {
+	contract, err := t.getContract(symbol)
+	if err != nil {
+		return nil, err
+	}
+	// 无论下单成功与否都清除缓存（超时等错误时订单可能已成交）
+	defer t.invalidateCache()
+
+	// 先取消该币种的所有委托单（清理旧的止损止盈单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+	if err := t.setIsolatedMargin(contract); err != nil {
+		return nil, err
+	}
+
+	price, err := t.GetMarketPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	contracts := t.contractsForQuantity(contract, quantity, price)
+	if contracts <= 0 {
+		return nil, fmt.Errorf("仓位价值 $%.2f 不足1张 %s 合约（面值 $%.0f）",
+			quantity*price, contract.Symbol, contract.ContractSize)
+	}
+	contractsStr := strconv.FormatFloat(contracts, 'f', contract.Precision, 64)
+
+	service := t.client.NewCreateOrderService().
+		Symbol(contract.Symbol).
+		Side(side).
+		PositionSide(positionSide).
+		Type(delivery.OrderTypeMarket).
+		Quantity(contractsStr).
+		NewOrderResponseType(delivery.NewOrderRespTypeRESULT)
+	if clientOrderID != "" {
+		service = service.NewClientOrderID(clientOrderID)
+	}
+	order, err := service.Do(context.Background())
+
+	if err != nil {
+		return nil, fmt.Errorf("开仓失败: %w", err)
+	}
+
+	log.Printf("✓ 币本位开仓成功: %s %s %s张 (≈%.6f %s)",
+		contract.Symbol, positionSide, contractsStr, quantity, contract.MarginAsset)
+	log.Printf("  订单ID: %d", order.OrderID)
+
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = symbol
+	result["status"] = order.Status
+	result["avgPrice"], _ = strconv.ParseFloat(order.AvgPrice, 64)
+	result["contracts"] = contracts
+	return result, nil
+}
+
+// OpenLong 开多仓
+func (t *CoinMTrader) OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	return t.openPosition(symbol, quantity, leverage, clientOrderID, delivery.SideTypeBuy, delivery.PositionSideTypeLong)
+}
+
+// OpenShort 开空仓
+func (t *CoinMTrader) OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	return t.openPosition(symbol, quantity, leverage, clientOrderID, delivery.SideTypeSell, delivery.PositionSideTypeShort)
+}
+
+// closePosition 平仓（quantity=0表示全部平仓）
+func (t *CoinMTrader) closePosition(symbol string, quantity float64, positionSide delivery.PositionSideType) (map[string]interface{}, error) {
+	contract, err := t.getContract(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	side := delivery.SideTypeSell
+	sideName := "long"
+	if positionSide == delivery.PositionSideTypeShort {
+		side = delivery.SideTypeBuy
+		sideName = "short"
+	}
+
+	var contracts float64
+	if quantity == 0 {
+		// 全部平仓：直接使用持仓张数
+		positions, err := t.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+		for _, pos := range positions {
+			if pos["symbol"] == symbol && pos["side"] == sideName {
+				contracts = math.Abs(pos["contracts"].(float64))
+				break
+			}
+		}
+		if contracts == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, sideName)
+		}
+	} else {
+		price, err := t.GetMarketPrice(symbol)
+		if err != nil {
+			return nil, err
+		}
+		contracts = t.contractsForQuantity(contract, quantity, price)
+		if contracts <= 0 {
+			return nil, fmt.Errorf("平仓数量不足1张 %s 合约", contract.Symbol)
+		}
+	}
+	contractsStr := strconv.FormatFloat(contracts, 'f', contract.Precision, 64)
+
+	// 无论下单成功与否都清除缓存（超时等错误时订单可能已成交）
+	defer t.invalidateCache()
+	order, err := t.client.NewCreateOrderService().
+		Symbol(contract.Symbol).
+		Side(side).
+		PositionSide(positionSide).
+		Type(delivery.OrderTypeMarket).
+		Quantity(contractsStr).
+		NewOrderResponseType(delivery.NewOrderRespTypeRESULT).
+		Do(context.Background())
+
+	if err != nil {
+		return nil, fmt.Errorf("平仓失败: %w", err)
+	}
+
+	log.Printf("✓ 币本位平仓成功: %s %s %s张", contract.Symbol, positionSide, contractsStr)
+
+	// 平仓后取消该币种的所有挂单（止损止盈单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = symbol
+	result["status"] = order.Status
+	result["avgPrice"], _ = strconv.ParseFloat(order.AvgPrice, 64)
+	return result, nil
+}
+
+// CloseLong 平多仓
+func (t *CoinMTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, quantity, delivery.PositionSideTypeLong)
+}
+
+// CloseShort 平空仓
+func (t *CoinMTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, quantity, delivery.PositionSideTypeShort)
+}
+
+// PlaceLimitOrder 下限价单（数量以币计，按限价换算为合约张数）
+func (t *CoinMTrader) PlaceLimitOrder(order LimitOrder) (map[string]interface{}, error) {
+	tif, err := order.EffectiveTimeInForce()
+	if err != nil {
+		return nil, err
+	}
+	contract, err := t.getContract(order.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	side, posSide := delivery.SideTypeBuy, delivery.PositionSideTypeLong
+	if order.Side == "short" {
+		side, posSide = delivery.SideTypeSell, delivery.PositionSideTypeShort
+	}
+	if order.ReduceOnly {
+		// 双向持仓模式下用反向订单+positionSide表示平仓
+		if side == delivery.SideTypeBuy {
+			side = delivery.SideTypeSell
+		} else {
+			side = delivery.SideTypeBuy
+		}
+	} else {
+		if err := t.SetLeverage(order.Symbol, order.Leverage); err != nil {
+			return nil, err
+		}
+		if err := t.setIsolatedMargin(contract); err != nil {
+			return nil, err
+		}
+	}
+
+	contracts := t.contractsForQuantity(contract, order.Quantity, order.Price)
+	if contracts <= 0 {
+		return nil, fmt.Errorf("仓位价值 $%.2f 不足1张 %s 合约（面值 $%.0f）",
+			order.Quantity*order.Price, contract.Symbol, contract.ContractSize)
+	}
+	contractsStr := strconv.FormatFloat(contracts, 'f', contract.Precision, 64)
+	priceStr := formatPriceToTick(order.Price, contract.TickSize)
+
+	service := t.client.NewCreateOrderService().
+		Symbol(contract.Symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(delivery.OrderTypeLimit).
+		TimeInForce(delivery.TimeInForceType(tif)).
+		Quantity(contractsStr).
+		Price(priceStr).
+		NewOrderResponseType(delivery.NewOrderRespTypeRESULT) // 返回撮合后的状态（IOC/FOK立即成交时为FILLED）
+	if order.ClientOrderID != "" {
+		service = service.NewClientOrderID(order.ClientOrderID)
+	}
+	// IOC/FOK可能立即成交，同样清除缓存
+	defer t.invalidateCache()
+	resp, err := service.Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	log.Printf("✓ 币本位限价单已提交: %s %s %s张 @ %s (%s) 状态: %s",
+		contract.Symbol, side, contractsStr, priceStr, tif, resp.Status)
+
+	result := make(map[string]interface{})
+	result["orderId"] = resp.OrderID
+	result["symbol"] = order.Symbol
+	result["status"] = string(resp.Status)
+	result["contracts"] = contracts
+	result["timeInForce"] = tif
+	return result, nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单
+func (t *CoinMTrader) CancelAllOrders(symbol string) error {
+	contract, err := t.getContract(symbol)
+	if err != nil {
+		return err
+	}
+
+	err = t.client.NewCancelAllOpenOrdersService().
+		Symbol(contract.Symbol).
+		Do(context.Background())
+
+	if err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 的所有挂单", contract.Symbol)
+	return nil
+}
+
+// GetOrderBook 获取币本位永续盘口深度（合约张数按面值换算为币的数量）
+func (t *CoinMTrader) GetOrderBook(symbol string, limit int) (*market.OrderBook, error) {
+	contract, err := t.getContract(symbol)
+	if err != nil {
+		return nil, err
+	}
+	book, err := market.GetCoinMDepth(contract.Symbol, limit)
+	if err != nil {
+		return nil, fmt.Errorf("获取盘口深度失败: %w", err)
+	}
+	for _, levels := range [][]market.BookLevel{book.Bids, book.Asks} {
+		for i := range levels {
+			levels[i].Quantity = levels[i].Quantity * contract.ContractSize / levels[i].Price
+		}
+	}
+	book.Symbol = symbol
+	return book, nil
+}
+
+// CancelOrder 按订单ID取消单个挂单
+func (t *CoinMTrader) CancelOrder(symbol string, orderID int64) error {
+	contract, err := t.getContract(symbol)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.client.NewCancelOrderService().
+		Symbol(contract.Symbol).
+		OrderID(orderID).
+		Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("取消订单失败 (orderId=%d): %w", orderID, err)
+	}
+	return nil
+}
+
+// GetOrderByClientID 按客户端订单ID查询订单（订单不存在时返回nil, nil）
+func (t *CoinMTrader) GetOrderByClientID(symbol, clientOrderID string) (map[string]interface{}, error) {
+	contract, err := t.getContract(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := t.client.NewGetOrderService().
+		Symbol(contract.Symbol).
+		OrigClientOrderID(clientOrderID).
+		Do(context.Background())
+	if err != nil {
+		if apiErr, ok := err.(*common.APIError); ok && apiErr.Code == -2013 {
+			return nil, nil // 订单不存在
+		}
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	executedContracts, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = symbol
+	result["status"] = string(order.Status)
+	result["avgPrice"], _ = strconv.ParseFloat(order.AvgPrice, 64)
+	result["contracts"] = executedContracts
+	return result, nil
+}
+
+// GetOpenOrders 获取挂单（symbol为空表示当前合约类型的所有币种，数量换算为以币计）
+func (t *CoinMTrader) GetOpenOrders(symbol string) ([]map[string]interface{}, error) {
+	if err := t.loadContracts(); err != nil {
+		return nil, err
+	}
+
+	service := t.client.NewListOpenOrdersService()
+	if symbol != "" {
+		contract, err := t.getContract(symbol)
+		if err != nil {
+			return nil, err
+		}
+		service = service.Symbol(contract.Symbol)
+	}
+	orders, err := service.Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	result := make([]map[string]interface{}, 0, len(orders))
+	for _, order := range orders {
+		usdtSymbol, contract := t.findContractBySymbol(order.Symbol)
+		if contract == nil {
+			continue // 其他合约类型（如季度合约）的挂单
+		}
+
+		stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+		contracts, _ := strconv.ParseFloat(order.OrigQuantity, 64)
+		quantity := 0.0
+		if stopPrice > 0 {
+			quantity = contracts * contract.ContractSize / stopPrice
+		}
+		result = append(result, openOrderMap(order.OrderID, usdtSymbol, string(order.Type),
+			string(order.Side), string(order.PositionSide), stopPrice, quantity))
+	}
+	return result, nil
+}
+
+// GetIncomeHistory 获取收益流水（/dapi/v1/income，金额以保证金币种计价；SDK未提供该接口，直接请求并由签名中间件签名）
+func (t *CoinMTrader) GetIncomeHistory(startTime, endTime time.Time) ([]IncomeRecord, error) {
+	if err := t.loadContracts(); err != nil {
+		return nil, err
+	}
+
+	fetch := func(start, end int64) ([]binanceIncome, error) {
+		query := url.Values{}
+		query.Set("startTime", strconv.FormatInt(start, 10))
+		query.Set("endTime", strconv.FormatInt(end, 10))
+		query.Set("limit", strconv.Itoa(incomePageLimit))
+		query.Set("signature", "") // 由签名中间件重新签名
+		req, err := http.NewRequest(http.MethodGet, t.client.BaseURL+"/dapi/v1/income?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-MBX-APIKEY", t.client.APIKey)
+
+		resp, err := t.client.HTTPClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		body, err := utils.ReadBody(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		}
+
+		var page []binanceIncome
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("解析收益流水失败: %w", err)
+		}
+		return page, nil
+	}
+	symbolOf := func(contractSymbol string) string {
+		symbol, _ := t.findContractBySymbol(contractSymbol)
+		return symbol // 其他合约类型的流水跳过
+	}
+	return fetchBinanceIncome(startTime, endTime, fetch, symbolOf)
+}
+
+// GetMarketPrice 获取合约最新价格
+func (t *CoinMTrader) GetMarketPrice(symbol string) (float64, error) {
+	contract, err := t.getContract(symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	prices, err := t.client.NewListPricesService().Symbol(contract.Symbol).Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("未找到价格")
+	}
+
+	return strconv.ParseFloat(prices[0].Price, 64)
+}
+
+// setStopOrder 设置条件平仓单（止损/止盈）
+func (t *CoinMTrader) setStopOrder(symbol string, positionSide string, stopPrice float64, orderType delivery.OrderType) error {
+	contract, err := t.getContract(symbol)
+	if err != nil {
+		return err
+	}
+
+	side := delivery.SideTypeBuy
+	posSide := delivery.PositionSideTypeShort
+	if positionSide == "LONG" {
+		side = delivery.SideTypeSell
+		posSide = delivery.PositionSideTypeLong
+	}
+
+	_, err = t.client.NewCreateOrderService().
+		Symbol(contract.Symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(orderType).
+		StopPrice(fmt.Sprintf("%.8f", stopPrice)).
+		WorkingType(delivery.WorkingTypeContractPrice).
+		ClosePosition(true).
+		Do(context.Background())
+
+	return err
+}
+
+// SetStopLoss 设置止损单（closePosition方式，无需指定张数）
+func (t *CoinMTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := t.setStopOrder(symbol, positionSide, stopPrice, delivery.OrderTypeStopMarket); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单（closePosition方式，无需指定张数）
+func (t *CoinMTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := t.setStopOrder(symbol, positionSide, takeProfitPrice, delivery.OrderTypeTakeProfitMarket); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// FormatQuantity 将以币计的数量格式化为合约张数
+func (t *CoinMTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	contract, err := t.getContract(symbol)
+	if err != nil {
+		return "", err
+	}
+
+	price, err := t.GetMarketPrice(symbol)
+	if err != nil {
+		return "", err
+	}
+
+	contracts := t.contractsForQuantity(contract, quantity, price)
+	return strconv.FormatFloat(contracts, 'f', contract.Precision, 64), nil
+}
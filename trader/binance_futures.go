@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"nofx/market"
+	"nofx/symbols"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/adshao/go-binance/v2/common"
 	"github.com/adshao/go-binance/v2/futures"
 )
 
@@ -32,6 +36,13 @@ type FuturesTrader struct {
 // NewFuturesTrader 创建合约交易器
 func NewFuturesTrader(apiKey, secretKey string) *FuturesTrader {
 	client := futures.NewClient(apiKey, secretKey)
+	// 请求经由可配置的基础地址发送（主地址连续失败时自动切换到备用地址），
+	// 签名中间件统一校正时间戳和recvWindow；与行情请求共用IP权重限速（排队在签名之前，不消耗recvWindow）
+	// 和交易规则缓存（每次下单前查询精度不再重复下载exchangeInfo）；启用决策快照时记录原始响应
+	endpointClient := &http.Client{Timeout: 10 * time.Second, Transport: market.BinanceLimiter.Transport(market.BinanceFutures.Transport(nil))}
+	signer := NewRequestSigner(secretKey, "https://fapi.binance.com/fapi/v1/time", 5000, endpointClient)
+	client.HTTPClient = &http.Client{Timeout: signedRequestTimeout, Transport: market.BinancePayloads.Transport(market.BinanceResponseCache.Transport(
+		market.BinanceLimiter.Transport(signer.Transport(market.BinanceFutures.Transport(nil)))))}
 	return &FuturesTrader{
 		client:        client,
 		cacheDuration: 15 * time.Second, // 15秒缓存
@@ -203,7 +214,7 @@ func (t *FuturesTrader) SetMarginType(symbol string, marginType futures.MarginTy
 }
 
 // OpenLong 开多仓
-func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -226,13 +237,17 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 	}
 
 	// 创建市价买入订单
-	order, err := t.client.NewCreateOrderService().
+	service := t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(futures.SideTypeBuy).
 		PositionSide(futures.PositionSideTypeLong).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
-		Do(context.Background())
+		NewOrderResponseType(futures.NewOrderRespTypeRESULT)
+	if clientOrderID != "" {
+		service = service.NewClientOrderID(clientOrderID)
+	}
+	order, err := service.Do(context.Background())
 
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
@@ -245,11 +260,12 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 	result["orderId"] = order.OrderID
 	result["symbol"] = order.Symbol
 	result["status"] = order.Status
+	result["avgPrice"], _ = strconv.ParseFloat(order.AvgPrice, 64)
 	return result, nil
 }
 
 // OpenShort 开空仓
-func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -272,13 +288,17 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 	}
 
 	// 创建市价卖出订单
-	order, err := t.client.NewCreateOrderService().
+	service := t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(futures.SideTypeSell).
 		PositionSide(futures.PositionSideTypeShort).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
-		Do(context.Background())
+		NewOrderResponseType(futures.NewOrderRespTypeRESULT)
+	if clientOrderID != "" {
+		service = service.NewClientOrderID(clientOrderID)
+	}
+	order, err := service.Do(context.Background())
 
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
@@ -291,6 +311,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 	result["orderId"] = order.OrderID
 	result["symbol"] = order.Symbol
 	result["status"] = order.Status
+	result["avgPrice"], _ = strconv.ParseFloat(order.AvgPrice, 64)
 	return result, nil
 }
 
@@ -328,6 +349,7 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 		PositionSide(futures.PositionSideTypeLong).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
+		NewOrderResponseType(futures.NewOrderRespTypeRESULT).
 		Do(context.Background())
 
 	if err != nil {
@@ -345,6 +367,7 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 	result["orderId"] = order.OrderID
 	result["symbol"] = order.Symbol
 	result["status"] = order.Status
+	result["avgPrice"], _ = strconv.ParseFloat(order.AvgPrice, 64)
 	return result, nil
 }
 
@@ -382,6 +405,7 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 		PositionSide(futures.PositionSideTypeShort).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
+		NewOrderResponseType(futures.NewOrderRespTypeRESULT).
 		Do(context.Background())
 
 	if err != nil {
@@ -399,6 +423,71 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 	result["orderId"] = order.OrderID
 	result["symbol"] = order.Symbol
 	result["status"] = order.Status
+	result["avgPrice"], _ = strconv.ParseFloat(order.AvgPrice, 64)
+	return result, nil
+}
+
+// PlaceLimitOrder 下限价单（支持GTC/IOC/FOK/GTX有效期和post-only）
+func (t *FuturesTrader) PlaceLimitOrder(order LimitOrder) (map[string]interface{}, error) {
+	tif, err := order.EffectiveTimeInForce()
+	if err != nil {
+		return nil, err
+	}
+
+	side, posSide := futures.SideTypeBuy, futures.PositionSideTypeLong
+	if order.Side == "short" {
+		side, posSide = futures.SideTypeSell, futures.PositionSideTypeShort
+	}
+	if order.ReduceOnly {
+		// 双向持仓模式下用反向订单+positionSide表示平仓
+		if side == futures.SideTypeBuy {
+			side = futures.SideTypeSell
+		} else {
+			side = futures.SideTypeBuy
+		}
+	} else {
+		if err := t.SetLeverage(order.Symbol, order.Leverage); err != nil {
+			return nil, err
+		}
+		if err := t.SetMarginType(order.Symbol, futures.MarginTypeIsolated); err != nil {
+			return nil, err
+		}
+	}
+
+	quantityStr, err := t.FormatQuantity(order.Symbol, order.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	tickSize := 0.0
+	if instrument, ok := symbols.Get(symbols.Canonical(order.Symbol), "binance"); ok {
+		tickSize = instrument.TickSize
+	}
+	priceStr := formatPriceToTick(order.Price, tickSize)
+
+	service := t.client.NewCreateOrderService().
+		Symbol(order.Symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeLimit).
+		TimeInForce(futures.TimeInForceType(tif)).
+		Quantity(quantityStr).
+		Price(priceStr).
+		NewOrderResponseType(futures.NewOrderRespTypeRESULT) // 返回撮合后的状态（IOC/FOK立即成交时为FILLED）
+	if order.ClientOrderID != "" {
+		service = service.NewClientOrderID(order.ClientOrderID)
+	}
+	resp, err := service.Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s %s @ %s (%s) 状态: %s", order.Symbol, side, quantityStr, priceStr, tif, resp.Status)
+
+	result := make(map[string]interface{})
+	result["orderId"] = resp.OrderID
+	result["symbol"] = resp.Symbol
+	result["status"] = string(resp.Status)
+	result["timeInForce"] = tif
 	return result, nil
 }
 
@@ -416,6 +505,91 @@ func (t *FuturesTrader) CancelAllOrders(symbol string) error {
 	return nil
 }
 
+// GetOrderBook 获取币安USDT永续盘口深度
+func (t *FuturesTrader) GetOrderBook(symbol string, limit int) (*market.OrderBook, error) {
+	return market.GetDepth(symbol, limit)
+}
+
+// CancelOrder 按订单ID取消单个挂单
+func (t *FuturesTrader) CancelOrder(symbol string, orderID int64) error {
+	_, err := t.client.NewCancelOrderService().
+		Symbol(symbol).
+		OrderID(orderID).
+		Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("取消订单失败 (orderId=%d): %w", orderID, err)
+	}
+	return nil
+}
+
+// GetOrderByClientID 按客户端订单ID查询订单（订单不存在时返回nil, nil）
+func (t *FuturesTrader) GetOrderByClientID(symbol, clientOrderID string) (map[string]interface{}, error) {
+	order, err := t.client.NewGetOrderService().
+		Symbol(symbol).
+		OrigClientOrderID(clientOrderID).
+		Do(context.Background())
+	if err != nil {
+		if apiErr, ok := err.(*common.APIError); ok && apiErr.Code == -2013 {
+			return nil, nil // 订单不存在
+		}
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	executedQty, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = order.Symbol
+	result["status"] = string(order.Status)
+	result["avgPrice"], _ = strconv.ParseFloat(order.AvgPrice, 64)
+	result["executedQty"] = executedQty
+	return result, nil
+}
+
+// GetOpenOrders 获取挂单（symbol为空表示所有币种）
+func (t *FuturesTrader) GetOpenOrders(symbol string) ([]map[string]interface{}, error) {
+	service := t.client.NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+	orders, err := service.Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	result := make([]map[string]interface{}, 0, len(orders))
+	for _, order := range orders {
+		stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+		quantity, _ := strconv.ParseFloat(order.OrigQuantity, 64)
+		result = append(result, openOrderMap(order.OrderID, order.Symbol, string(order.Type),
+			string(order.Side), string(order.PositionSide), stopPrice, quantity))
+	}
+	return result, nil
+}
+
+// GetIncomeHistory 获取收益流水（/fapi/v1/income）
+func (t *FuturesTrader) GetIncomeHistory(startTime, endTime time.Time) ([]IncomeRecord, error) {
+	fetch := func(start, end int64) ([]binanceIncome, error) {
+		items, err := t.client.NewGetIncomeHistoryService().StartTime(start).EndTime(end).Limit(incomePageLimit).Do(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		page := make([]binanceIncome, 0, len(items))
+		for _, item := range items {
+			page = append(page, binanceIncome{
+				Symbol:     item.Symbol,
+				IncomeType: item.IncomeType,
+				Income:     item.Income,
+				Asset:      item.Asset,
+				Time:       item.Time,
+				TranID:     item.TranID,
+				TradeID:    item.TradeID,
+			})
+		}
+		return page, nil
+	}
+	return fetchBinanceIncome(startTime, endTime, fetch, func(symbol string) string { return symbol })
+}
+
 // GetMarketPrice 获取市场价格
 func (t *FuturesTrader) GetMarketPrice(symbol string) (float64, error) {
 	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
@@ -544,6 +718,15 @@ func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
 	return 3, nil // 默认精度为3
 }
 
+// formatPriceToTick 将价格取整到最小价格变动单位并格式化（tickSize未知时保留8位小数）
+func formatPriceToTick(price, tickSize float64) string {
+	if tickSize <= 0 {
+		return strconv.FormatFloat(price, 'f', 8, 64)
+	}
+	precision := calculatePrecision(strconv.FormatFloat(tickSize, 'f', -1, 64))
+	return strconv.FormatFloat(roundToTickSize(price, tickSize), 'f', precision, 64)
+}
+
 // calculatePrecision 从stepSize计算精度
 func calculatePrecision(stepSize string) int {
 	// 去除尾部的0
@@ -0,0 +1,161 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+
+	"nofx/symbols"
+)
+
+// BinanceSpotTrader 币安现货交易器（资金费率套利的现货腿，与合约共用API Key）
+type BinanceSpotTrader struct {
+	client *binance.Client
+
+	// 数量步长缓存（交易规则很少变化）
+	stepMutex sync.Mutex
+	stepSizes map[string]float64
+}
+
+// NewBinanceSpotTrader 创建币安现货交易器
+func NewBinanceSpotTrader(apiKey, secretKey string) *BinanceSpotTrader {
+	client := binance.NewClient(apiKey, secretKey)
+	endpointClient := &http.Client{Timeout: 10 * time.Second}
+	signer := NewRequestSigner(secretKey, "https://api.binance.com/api/v3/time", 5000, endpointClient)
+	client.HTTPClient = &http.Client{Timeout: 10 * time.Second, Transport: signer.Transport(nil)}
+	return &BinanceSpotTrader{
+		client:    client,
+		stepSizes: make(map[string]float64),
+	}
+}
+
+// GetSpotBalance 获取现货资产的可用余额
+func (t *BinanceSpotTrader) GetSpotBalance(asset string) (float64, error) {
+	account, err := t.client.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("获取现货账户失败: %w", err)
+	}
+	for _, balance := range account.Balances {
+		if balance.Asset == asset {
+			free, _ := strconv.ParseFloat(balance.Free, 64)
+			return free, nil
+		}
+	}
+	return 0, nil
+}
+
+// GetSpotPrice 获取现货最新成交价
+func (t *BinanceSpotTrader) GetSpotPrice(symbol string) (float64, error) {
+	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("获取%s现货价格失败: %w", symbol, err)
+	}
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("未找到%s现货价格", symbol)
+	}
+	return strconv.ParseFloat(prices[0].Price, 64)
+}
+
+// SpotBuy 市价买入现货
+func (t *BinanceSpotTrader) SpotBuy(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.marketOrder(symbol, binance.SideTypeBuy, quantity)
+}
+
+// SpotSell 市价卖出现货
+func (t *BinanceSpotTrader) SpotSell(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.marketOrder(symbol, binance.SideTypeSell, quantity)
+}
+
+// marketOrder 下现货市价单，按成交明细汇总均价、到账数量和手续费
+func (t *BinanceSpotTrader) marketOrder(symbol string, side binance.SideType, quantity float64) (map[string]interface{}, error) {
+	quantityStr, err := t.formatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	order, err := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		Type(binance.OrderTypeMarket).
+		Quantity(quantityStr).
+		NewOrderRespType(binance.NewOrderRespTypeFULL).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("现货%s失败: %w", side, err)
+	}
+
+	executed, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+	quote, _ := strconv.ParseFloat(order.CummulativeQuoteQuantity, 64)
+	if executed <= 0 {
+		return nil, fmt.Errorf("现货%s未成交（状态: %s）", side, order.Status)
+	}
+	avgPrice := quote / executed
+
+	base := symbols.Canonical(symbol)
+	fee := 0.0
+	for _, fill := range order.Fills {
+		commission, _ := strconv.ParseFloat(fill.Commission, 64)
+		switch fill.CommissionAsset {
+		case base:
+			// 买入时以币种支付的手续费从到账数量中扣除
+			fee += commission * avgPrice
+			if side == binance.SideTypeBuy {
+				executed -= commission
+			}
+		case "USDT":
+			fee += commission
+		default:
+			log.Printf("  ⚠ 现货手续费以%s支付（%.8f），未计入套利成本", fill.CommissionAsset, commission)
+		}
+	}
+
+	log.Printf("✓ 现货%s成功: %s 数量: %s 均价: %.4f", side, symbol, quantityStr, avgPrice)
+	return map[string]interface{}{
+		"orderId":     order.OrderID,
+		"symbol":      symbol,
+		"status":      string(order.Status),
+		"avgPrice":    avgPrice,
+		"executedQty": executed,
+		"fee":         fee,
+	}, nil
+}
+
+// formatQuantity 按现货LOT_SIZE步长向下取整数量（避免超过可用余额）
+func (t *BinanceSpotTrader) formatQuantity(symbol string, quantity float64) (string, error) {
+	t.stepMutex.Lock()
+	step, ok := t.stepSizes[symbol]
+	t.stepMutex.Unlock()
+	if !ok {
+		info, err := t.client.NewExchangeInfoService().Symbol(symbol).Do(context.Background())
+		if err != nil {
+			return "", fmt.Errorf("获取%s现货交易规则失败: %w", symbol, err)
+		}
+		for _, s := range info.Symbols {
+			if s.Symbol != symbol {
+				continue
+			}
+			if filter := s.LotSizeFilter(); filter != nil {
+				step, _ = strconv.ParseFloat(filter.StepSize, 64)
+			}
+		}
+		if step <= 0 {
+			return "", fmt.Errorf("未找到%s现货数量步长", symbol)
+		}
+		t.stepMutex.Lock()
+		t.stepSizes[symbol] = step
+		t.stepMutex.Unlock()
+	}
+
+	floored := math.Floor(quantity/step+1e-9) * step
+	if floored <= 0 {
+		return "", fmt.Errorf("数量 %.8f 小于%s现货最小步长 %g", quantity, symbol, step)
+	}
+	precision := calculatePrecision(strconv.FormatFloat(step, 'f', -1, 64))
+	return strconv.FormatFloat(floored, 'f', precision, 64), nil
+}
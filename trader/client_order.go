@@ -0,0 +1,130 @@
+package trader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"nofx/logger"
+	"nofx/market"
+)
+
+// clientOrderID 为交易信号生成确定性的客户端订单ID
+// 同一trader、同一策略、同一币种、同一动作在同一扫描周期时间窗内得到相同ID，重试或重启后保持不变。
+// 格式为 "0x"+32位十六进制：同时满足币安/Aster（≤36字符）和Hyperliquid cloid（128位）的要求
+func (at *AutoTrader) clientOrderID(symbol, action, strategyID string) string {
+	window := at.config.ScanInterval
+	if window <= 0 {
+		window = 3 * time.Minute
+	}
+	bucket := market.Clock.Now().Truncate(window).Unix()
+
+	key := fmt.Sprintf("%s|%s|%s|%d", at.id, symbol, action, bucket)
+	if strategyID != "" && strategyID != logger.DefaultStrategyID {
+		key += "|" + strategyID // 默认策略保持原有ID格式
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "0x" + hex.EncodeToString(sum[:16])
+}
+
+// placeOpenOrder 幂等开仓
+// 提交前按客户端订单ID查询，该信号已下过单则不再重复提交；
+// 网络错误（超时等）后先确认订单是否已到达交易所，未到达才使用相同ID重试一次。
+// entry_order_type为limit时以price附近的盘口价挂限价单
+func (at *AutoTrader) placeOpenOrder(symbol, side string, quantity float64, leverage int, price float64, strategyID string) (map[string]interface{}, error) {
+	clientOrderID := at.clientOrderID(symbol, "open_"+side, strategyID)
+
+	existing, err := at.trader.GetOrderByClientID(symbol, clientOrderID)
+	if err != nil {
+		log.Printf("  ⚠ 查询历史订单失败（继续下单）: %v", err)
+	} else if existing != nil {
+		log.Printf("  ♻️ 该信号已下过单（clientOrderId=%s，状态=%v），跳过重复提交", clientOrderID, existing["status"])
+		return existing, nil
+	}
+
+	submit := func() (map[string]interface{}, error) {
+		if at.config.EntryOrderType == "limit" {
+			order := LimitOrder{
+				Symbol:        symbol,
+				Side:          side,
+				Quantity:      quantity,
+				Leverage:      leverage,
+				TimeInForce:   at.config.EntryTimeInForce,
+				PostOnly:      at.config.PostOnly,
+				ClientOrderID: clientOrderID,
+			}
+			tif, err := order.EffectiveTimeInForce()
+			if err != nil {
+				return nil, err
+			}
+			order.Price = entryLimitPrice(symbol, side, tif, price)
+			log.Printf("  📌 限价开仓: %s %s %.4f @ %.4f (%s)", symbol, side, quantity, order.Price, tif)
+			return at.trader.PlaceLimitOrder(order)
+		}
+		if side == "long" {
+			return at.trader.OpenLong(symbol, quantity, leverage, clientOrderID)
+		}
+		return at.trader.OpenShort(symbol, quantity, leverage, clientOrderID)
+	}
+
+	order, err := submit()
+	if err == nil || !isNetworkError(err) {
+		return order, err
+	}
+
+	// 网络错误时订单可能已被交易所接受，直接重试会导致仓位翻倍
+	log.Printf("  ⚠ 下单网络错误，按clientOrderId确认订单状态: %v", err)
+	existing, queryErr := at.trader.GetOrderByClientID(symbol, clientOrderID)
+	if queryErr != nil {
+		return nil, fmt.Errorf("下单结果未知（%v），且查询订单失败，放弃重试: %w", err, queryErr)
+	}
+	if existing != nil {
+		log.Printf("  ✓ 订单已到达交易所（状态=%v），不再重试", existing["status"])
+		return existing, nil
+	}
+
+	log.Printf("  🔁 订单未到达交易所，使用相同clientOrderId重试")
+	return submit()
+}
+
+// entryLimitPrice 限价开仓价格
+// GTC/GTX挂在己方最优价等待成交（开多取买一、开空取卖一），IOC/FOK取对手价立即成交；
+// 盘口不可用时使用fallback
+func entryLimitPrice(symbol, side, tif string, fallback float64) float64 {
+	bid, ask, ok := market.Hub.BestBidAsk(symbol, livePriceMaxAge)
+	if !ok {
+		return fallback
+	}
+	passive := tif == TimeInForceGTC || tif == TimeInForceGTX
+	if (side == "long") == passive {
+		return bid
+	}
+	return ask
+}
+
+// entryFilled 开仓单是否已成交（市价单视为已成交；限价单按交易所返回的撮合结果，IOC/FOK立即成交时为FILLED；
+// 挂单中的限价单成交后由对账纳入跟踪）
+func (at *AutoTrader) entryFilled(order map[string]interface{}) bool {
+	if at.config.EntryOrderType != "limit" {
+		return true
+	}
+	status, _ := order["status"].(string)
+	return status == "FILLED"
+}
+
+// isNetworkError 是否为网络错误（请求结果未知）
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "timeout") ||
+		strings.Contains(message, "connection reset") ||
+		strings.Contains(message, "EOF")
+}
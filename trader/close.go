@@ -0,0 +1,227 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"nofx/logger"
+)
+
+// closeQueueSize 手动平仓请求队列长度
+const closeQueueSize = 4
+
+// closeReplyTimeout 等待交易主循环执行手动平仓的最长时间（主循环可能正在等待AI决策）
+const closeReplyTimeout = 2 * time.Minute
+
+// closeRequest 手动平仓请求（API提交，在交易主循环中执行，避免与交易周期并发修改本地持仓记录）
+type closeRequest struct {
+	symbol   string  // 为空表示平掉全部持仓
+	fraction float64 // 平仓比例（平掉全部持仓时为1）
+	reply    chan closeResult
+}
+
+// closeResult 手动平仓结果
+type closeResult struct {
+	orders []map[string]interface{}
+	err    error
+}
+
+// ClosePosition 提交手动平仓请求并等待结果：按比例平掉某币种的持仓（fraction取值(0,1]，1表示全部平仓）
+func (at *AutoTrader) ClosePosition(symbol string, fraction float64) ([]map[string]interface{}, error) {
+	if fraction <= 0 || fraction > 1 {
+		return nil, fmt.Errorf("平仓比例必须在(0, 1]之间: %.4f", fraction)
+	}
+	if symbol == "" {
+		return nil, fmt.Errorf("币种不能为空")
+	}
+	return at.submitClose(closeRequest{symbol: symbol, fraction: fraction})
+}
+
+// FlattenAll 提交紧急平仓请求并等待结果：平掉所有持仓，并放弃等待价差收窄的开仓
+func (at *AutoTrader) FlattenAll() ([]map[string]interface{}, error) {
+	return at.submitClose(closeRequest{fraction: 1})
+}
+
+// submitClose 把平仓请求交给交易主循环并等待执行结果
+func (at *AutoTrader) submitClose(req closeRequest) ([]map[string]interface{}, error) {
+	if !at.isRunning {
+		return nil, fmt.Errorf("trader %s 未运行", at.id)
+	}
+	req.reply = make(chan closeResult, 1)
+	select {
+	case at.closes <- req:
+	default:
+		return nil, fmt.Errorf("手动平仓队列已满（%d）", closeQueueSize)
+	}
+	select {
+	case result := <-req.reply:
+		return result.orders, result.err
+	case <-time.After(closeReplyTimeout):
+		return nil, fmt.Errorf("等待平仓结果超时（请求已提交，交易主循环空闲后执行，请检查持仓）")
+	}
+}
+
+// executeClose 在交易主循环中执行手动平仓请求，结果写入决策日志
+func (at *AutoTrader) executeClose(req closeRequest) {
+	var result closeResult
+	if req.symbol == "" {
+		at.spreadWaits = nil
+		at.watchSpreadWaits()
+		result.orders, result.err = at.flattenAll()
+	} else {
+		result.orders, result.err = at.closePosition(req.symbol, req.fraction)
+	}
+	at.refreshOpenRisk(0)
+	req.reply <- result
+
+	record := &logger.DecisionRecord{Source: "api", ExecutionLog: []string{}, Success: result.err == nil}
+	if req.symbol == "" {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🧹 手动平掉全部持仓: %d个平仓单", len(result.orders)))
+	} else {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✋ 手动平仓 %s %.0f%%: %d个平仓单", req.symbol, req.fraction*100, len(result.orders)))
+	}
+	if result.err != nil {
+		record.ErrorMessage = result.err.Error()
+	}
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+}
+
+// closePosition 按比例平掉某币种的持仓
+// 双向持仓模式下多空两侧分别按比例平仓；平仓会取消该币种全部挂单，
+// 因此所有平仓单完成后再按本地记录的价格为剩余仓位重新挂止损/止盈
+func (at *AutoTrader) closePosition(symbol string, fraction float64) ([]map[string]interface{}, error) {
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var orders []map[string]interface{}
+	var errs []string
+	found := false
+	for _, pos := range positions {
+		if pos["symbol"] != symbol {
+			continue
+		}
+		side, _ := pos["side"].(string)
+		amount, _ := pos["positionAmt"].(float64)
+		amount = math.Abs(amount)
+		if amount == 0 {
+			continue
+		}
+		found = true
+
+		order, err := at.closeSide(symbol, side, amount, fraction, "手动平仓")
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s %s: %v", symbol, side, err))
+			continue
+		}
+		orders = append(orders, order)
+	}
+	if fraction < 1 {
+		at.restoreStops(symbol)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("没有找到 %s 的持仓", symbol)
+	}
+	if len(errs) > 0 {
+		return orders, fmt.Errorf("平仓失败: %s", strings.Join(errs, "; "))
+	}
+	return orders, nil
+}
+
+// flattenAll 平掉所有持仓（逐个币种全部平仓，单个失败不影响其他币种）
+func (at *AutoTrader) flattenAll() ([]map[string]interface{}, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	log.Printf("🧹 [%s] 平掉全部持仓（%d个）", at.name, len(positions))
+
+	var orders []map[string]interface{}
+	var errs []string
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		amount, _ := pos["positionAmt"].(float64)
+		amount = math.Abs(amount)
+		if amount == 0 {
+			continue
+		}
+
+		order, err := at.closeSide(symbol, side, amount, 1, "全部平仓")
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s %s: %v", symbol, side, err))
+			continue
+		}
+		orders = append(orders, order)
+	}
+
+	if len(errs) > 0 {
+		return orders, fmt.Errorf("部分持仓平仓失败: %s", strings.Join(errs, "; "))
+	}
+	return orders, nil
+}
+
+// closeSide 平掉单侧持仓的fraction部分（按持仓方向下平仓单，只减仓不会反向开仓），reason记录在交易计划中
+func (at *AutoTrader) closeSide(symbol, side string, amount, fraction float64, reason string) (map[string]interface{}, error) {
+	quantity := 0.0 // 0 = 全部平仓，由交易所实现按实际持仓数量平仓
+	if fraction < 1 {
+		quantity = amount * fraction
+	}
+
+	var order map[string]interface{}
+	var err error
+	if side == "long" {
+		order, err = at.trader.CloseLong(symbol, quantity)
+	} else {
+		order, err = at.trader.CloseShort(symbol, quantity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if quantity == 0 {
+		log.Printf("  ✓ %s %s 已全部平仓", symbol, side)
+		at.untrackPosition(symbol, side)
+		delete(at.positionFirstSeenTime, symbol+"_"+side)
+		delete(at.grids, symbol+"_"+side) // 平仓时交易所已取消剩余网格挂单
+		at.exitPlan(symbol, side, order, 0, reason)
+		return order, nil
+	}
+
+	remaining := amount - quantity
+	log.Printf("  ✓ %s %s 已平仓 %.1f%%（%.4f），剩余 %.4f", symbol, side, fraction*100, quantity, remaining)
+	if tracked, ok := at.trackedPositions[symbol+"_"+side]; ok {
+		tracked.Quantity = remaining
+	}
+	at.exitPlan(symbol, side, order, quantity, reason)
+	return order, nil
+}
+
+// restoreStops 为该币种仍在跟踪的持仓按原价格重新挂止损/止盈单
+func (at *AutoTrader) restoreStops(symbol string) {
+	for _, tracked := range at.trackedPositions {
+		if tracked.Symbol != symbol {
+			continue
+		}
+		if tracked.StopLoss > 0 {
+			if err := at.trader.SetStopLoss(symbol, tracked.positionSide(), tracked.Quantity, tracked.StopLoss); err != nil {
+				log.Printf("  ⚠ 重新设置止损失败: %v", err)
+			}
+		}
+		if tracked.TakeProfit > 0 {
+			if err := at.trader.SetTakeProfit(symbol, tracked.positionSide(), tracked.Quantity, tracked.TakeProfit); err != nil {
+				log.Printf("  ⚠ 重新设置止盈失败: %v", err)
+			}
+		}
+	}
+	at.restoreGridLevels(symbol)
+}
@@ -0,0 +1,192 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// 信号组合规则
+const (
+	EnsembleUnanimous = "unanimous" // 全部成员同向开仓
+	EnsembleMajority  = "majority"  // 超过半数成员同向开仓
+	EnsembleWeighted  = "weighted"  // 按权重×信心度加权，净得分占总权重的比例达到阈值
+)
+
+// EnsembleConfig 信号组合配置（Members为空时不启用）
+// 成员策略对同一币种的开仓信号先按规则投票，只有达成一致的信号才合并为一个开仓决策交给风控和执行；
+// 平仓决策和非成员的决策不参与投票，原样执行
+type EnsembleConfig struct {
+	ID        string             // 合并后开仓决策的策略ID（用于归因、资金预算和仓位计算）
+	Members   []string           // 参与投票的策略ID（AI决策为trader的strategy_id）
+	Rule      string             // 组合规则：unanimous / majority / weighted
+	Weights   map[string]float64 // weighted规则下各成员的权重（未配置为1）
+	Threshold float64            // weighted规则下净加权信心占总权重的比例阈值（0-1）
+}
+
+func (c EnsembleConfig) enabled() bool {
+	return len(c.Members) > 0
+}
+
+// weight 成员权重（未配置时为1）
+func (c EnsembleConfig) weight(member string) float64 {
+	if w, ok := c.Weights[member]; ok {
+		return w
+	}
+	return 1
+}
+
+// ensembleVote 成员对一个币种的开仓信号
+type ensembleVote struct {
+	member   string
+	decision decision.Decision
+	score    float64 // 权重×信心度（0-1）
+}
+
+// combineSignals 按组合规则合并成员策略的开仓信号：成员的open_long/open_short按币种分组投票，
+// 通过的方向以得分最高的成员决策为模板（止损、止盈、仓位、杠杆）生成一个组合决策，未通过的信号全部丢弃
+func (at *AutoTrader) combineSignals(decisions []decision.Decision, record *logger.DecisionRecord) []decision.Decision {
+	config := at.config.Ensemble
+	members := make(map[string]bool, len(config.Members))
+	for _, id := range config.Members {
+		members[id] = true
+	}
+
+	var result []decision.Decision
+	var symbols []string
+	votes := make(map[string][]ensembleVote)
+	for _, d := range decisions {
+		at.attributeDecision(&d, "")
+		if !members[d.StrategyID] || (d.Action != "open_long" && d.Action != "open_short") {
+			result = append(result, d)
+			continue
+		}
+		if _, seen := votes[d.Symbol]; !seen {
+			symbols = append(symbols, d.Symbol)
+		}
+		confidence := float64(d.Confidence) / 100
+		if d.Confidence <= 0 {
+			confidence = 1 // 未给出信心度的策略（如内置策略）按满信心计
+		}
+		votes[d.Symbol] = append(votes[d.Symbol], ensembleVote{
+			member:   d.StrategyID,
+			decision: d,
+			score:    config.weight(d.StrategyID) * math.Min(confidence, 1),
+		})
+	}
+
+	for _, symbol := range symbols {
+		combined, summary := at.tallyVotes(symbol, votes[symbol])
+		if combined == nil {
+			log.Printf("🗳 [%s] %s 组合信号未通过: %s", at.name, symbol, summary)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🗳 %s 组合信号未通过: %s", symbol, summary))
+			continue
+		}
+		log.Printf("🗳 [%s] %s 组合信号 %s: %s", at.name, symbol, combined.Action, summary)
+		result = append(result, *combined)
+	}
+	return result
+}
+
+// tallyVotes 统计一个币种的投票，通过时返回组合决策；summary说明各成员的表态和结果
+func (at *AutoTrader) tallyVotes(symbol string, votes []ensembleVote) (*decision.Decision, string) {
+	config := at.config.Ensemble
+	byMember := make(map[string]ensembleVote, len(votes))
+	for _, v := range votes {
+		// 同一成员对同一币种给出多个信号时只取得分最高的一个
+		if prev, ok := byMember[v.member]; !ok || v.score > prev.score {
+			byMember[v.member] = v
+		}
+	}
+
+	counts := map[string]int{}
+	scores := map[string]float64{}
+	totalWeight := 0.0
+	var stances []string
+	for _, member := range config.Members {
+		totalWeight += config.weight(member)
+		v, ok := byMember[member]
+		if !ok {
+			stances = append(stances, member+" 未表态")
+			continue
+		}
+		counts[v.decision.Action]++
+		scores[v.decision.Action] += v.score
+		stances = append(stances, fmt.Sprintf("%s %s", member, v.decision.Action))
+	}
+
+	n := len(config.Members)
+	action := "open_long"
+	if counts["open_short"] > counts["open_long"] || (counts["open_short"] == counts["open_long"] && scores["open_short"] > scores["open_long"]) {
+		action = "open_short"
+	}
+	var passed bool
+	var result string
+	switch config.Rule {
+	case EnsembleUnanimous:
+		passed = counts[action] == n
+		result = fmt.Sprintf("%s %d/%d", config.Rule, counts[action], n)
+	case EnsembleMajority:
+		passed = counts[action]*2 > n
+		result = fmt.Sprintf("%s %d/%d", config.Rule, counts[action], n)
+	case EnsembleWeighted:
+		net := scores["open_long"] - scores["open_short"]
+		if net < 0 {
+			action, net = "open_short", -net
+		} else {
+			action = "open_long"
+		}
+		ratio := 0.0
+		if totalWeight > 0 {
+			ratio = net / totalWeight
+		}
+		passed = net > 0 && ratio >= config.Threshold
+		result = fmt.Sprintf("%s %.2f（阈值 %.2f）", config.Rule, ratio, config.Threshold)
+	}
+	summary := strings.Join(stances, "、") + "；" + result
+	if !passed {
+		return nil, summary
+	}
+
+	// 以得分最高的同向成员决策为模板
+	var template *ensembleVote
+	var voters []string
+	tags := []string{"ensemble"}
+	for _, member := range config.Members {
+		v, ok := byMember[member]
+		if !ok || v.decision.Action != action {
+			continue
+		}
+		voters = append(voters, member)
+		for _, tag := range v.decision.Tags {
+			if !containsString(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if template == nil || v.score > template.score {
+			vote := v
+			template = &vote
+		}
+	}
+
+	combined := template.decision
+	combined.StrategyID = config.ID
+	combined.Tags = tags
+	combined.Confidence = int(math.Round(float64(len(voters)) / float64(n) * 100))
+	combined.Reasoning = fmt.Sprintf("组合信号（%s）：采用 %s 的止损止盈和仓位。%s", summary, template.member, template.decision.Reasoning)
+	return &combined, summary
+}
+
+// containsString 切片中是否包含指定字符串
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
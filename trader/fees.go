@@ -0,0 +1,297 @@
+package trader
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 收益流水类型（各交易所的流水统一为以下类型）
+const (
+	IncomeCommission  = "commission"   // 交易手续费（负数）
+	IncomeRebate      = "rebate"       // 手续费返佣（正数）
+	IncomeFunding     = "funding"      // 资金费
+	IncomeRealizedPnL = "realized_pnl" // 已实现盈亏（不含手续费）
+	IncomeOther       = "other"        // 划转、奖励等其他流水
+)
+
+// incomePageLimit 收益流水每页条数（币安/Aster上限1000）
+const incomePageLimit = 1000
+
+// feeMatchGrace 流水与交易匹配时允许的时间误差（下单与成交、记录决策之间的延迟）
+const feeMatchGrace = time.Minute
+
+// IncomeRecord 交易所收益流水（手续费、返佣、资金费、已实现盈亏）
+type IncomeRecord struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Symbol  string    `json:"symbol"` // 系统币种格式（如BTCUSDT），账户级流水为空
+	Type    string    `json:"type"`   // commission、rebate、funding、realized_pnl、other
+	Amount  float64   `json:"amount"` // 正数为收入，负数为支出
+	Asset   string    `json:"asset"`  // 计价资产（USDT、USDC，币本位合约为保证金币种）
+	TradeID string    `json:"trade_id,omitempty"`
+}
+
+// binanceIncome 币安格式的收益流水（U本位、币本位和Aster通用）
+type binanceIncome struct {
+	Symbol     string `json:"symbol"`
+	IncomeType string `json:"incomeType"`
+	Income     string `json:"income"`
+	Asset      string `json:"asset"`
+	Time       int64  `json:"time"`
+	TranID     int64  `json:"tranId"`
+	TradeID    string `json:"tradeId"`
+}
+
+// binanceIncomeType 币安收益类型转换为统一类型
+func binanceIncomeType(incomeType string) string {
+	switch incomeType {
+	case "COMMISSION":
+		return IncomeCommission
+	case "COMMISSION_REBATE", "API_REBATE", "REFERRAL_KICKBACK":
+		return IncomeRebate
+	case "FUNDING_FEE":
+		return IncomeFunding
+	case "REALIZED_PNL":
+		return IncomeRealizedPnL
+	default:
+		return IncomeOther
+	}
+}
+
+// fetchBinanceIncome 分页拉取币安格式的收益流水并转换为统一格式
+// fetch按[start, end]毫秒时间范围返回最多incomePageLimit条（按时间升序）；symbolOf把交易所合约代码转换为系统币种（返回空表示跳过）
+func fetchBinanceIncome(start, end time.Time, fetch func(start, end int64) ([]binanceIncome, error), symbolOf func(string) string) ([]IncomeRecord, error) {
+	var records []IncomeRecord
+	seen := make(map[string]bool)
+	from := start.UnixMilli()
+	for {
+		page, err := fetch(from, end.UnixMilli())
+		if err != nil {
+			return nil, fmt.Errorf("获取收益流水失败: %w", err)
+		}
+		for _, item := range page {
+			// 分页起点与上一页最后一条同一毫秒，按流水ID去重
+			id := fmt.Sprintf("%d_%s_%s", item.TranID, item.IncomeType, item.TradeID)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			symbol := ""
+			if item.Symbol != "" {
+				if symbol = symbolOf(item.Symbol); symbol == "" {
+					continue
+				}
+			}
+			amount, _ := strconv.ParseFloat(item.Income, 64)
+			records = append(records, IncomeRecord{
+				ID:      id,
+				Time:    time.UnixMilli(item.Time),
+				Symbol:  symbol,
+				Type:    binanceIncomeType(item.IncomeType),
+				Amount:  amount,
+				Asset:   item.Asset,
+				TradeID: item.TradeID,
+			})
+		}
+		if len(page) < incomePageLimit || page[len(page)-1].Time <= from {
+			return records, nil
+		}
+		from = page[len(page)-1].Time
+	}
+}
+
+// FeeTotals 手续费汇总（金额按流水的计价资产，支出为负数）
+type FeeTotals struct {
+	Commission  float64 `json:"commission"`   // 手续费
+	Rebate      float64 `json:"rebate"`       // 返佣
+	Funding     float64 `json:"funding"`      // 资金费
+	NetFees     float64 `json:"net_fees"`     // 手续费+返佣+资金费
+	RealizedPnL float64 `json:"realized_pnl"` // 已实现盈亏（不含手续费）
+	NetPnL      float64 `json:"net_pnl"`      // 已实现盈亏+净费用
+	Records     int     `json:"records"`      // 流水条数
+}
+
+// add 累加一条流水
+func (t *FeeTotals) add(record IncomeRecord) {
+	switch record.Type {
+	case IncomeCommission:
+		t.Commission += record.Amount
+	case IncomeRebate:
+		t.Rebate += record.Amount
+	case IncomeFunding:
+		t.Funding += record.Amount
+	case IncomeRealizedPnL:
+		t.RealizedPnL += record.Amount
+	default:
+		return
+	}
+	t.NetFees = t.Commission + t.Rebate + t.Funding
+	t.NetPnL = t.RealizedPnL + t.NetFees
+	t.Records++
+}
+
+// TradeFees 单笔交易（开仓到平仓）的费用
+type TradeFees struct {
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"`
+	StrategyID string    `json:"strategy_id"`
+	OpenTime   time.Time `json:"open_time"`
+	CloseTime  time.Time `json:"close_time,omitempty"` // 零值表示统计区间结束时仍未平仓（或由止损/止盈平仓，未记录平仓决策）
+	Asset      string    `json:"asset,omitempty"`
+	FeeTotals
+}
+
+// FeeReport 手续费核算报告：交易所流水按币种、策略和单笔交易汇总
+type FeeReport struct {
+	TraderID   string                `json:"trader_id"`
+	From       time.Time             `json:"from"`
+	To         time.Time             `json:"to"`
+	Total      map[string]*FeeTotals `json:"total"`       // 按计价资产
+	BySymbol   map[string]*FeeTotals `json:"by_symbol"`   // 按币种
+	ByStrategy map[string]*FeeTotals `json:"by_strategy"` // 按开仓策略
+	Trades     []*TradeFees          `json:"trades"`      // 按决策日志中的开平仓配对
+	Unmatched  map[string]*FeeTotals `json:"unmatched"`   // 无法对应到交易的流水（按计价资产，如手动交易）
+}
+
+// FeeReport 拉取[from, to]期间的交易所收益流水，与决策日志中的交易对应，核算每笔交易和汇总的手续费、返佣和资金费
+func (at *AutoTrader) FeeReport(from, to time.Time) (*FeeReport, error) {
+	records, err := at.trader.GetIncomeHistory(from, to)
+	if err != nil {
+		return nil, err
+	}
+	trades, err := at.journalTrades(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FeeReport{
+		TraderID:   at.id,
+		From:       from,
+		To:         to,
+		Total:      make(map[string]*FeeTotals),
+		BySymbol:   make(map[string]*FeeTotals),
+		ByStrategy: make(map[string]*FeeTotals),
+		Trades:     trades,
+		Unmatched:  make(map[string]*FeeTotals),
+	}
+	totals := func(m map[string]*FeeTotals, key string) *FeeTotals {
+		if m[key] == nil {
+			m[key] = &FeeTotals{}
+		}
+		return m[key]
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	for _, record := range records {
+		if record.Type == IncomeOther {
+			continue
+		}
+		totals(report.Total, record.Asset).add(record)
+		if record.Symbol != "" {
+			totals(report.BySymbol, record.Symbol).add(record)
+		}
+
+		trade := matchTrade(trades, record, to)
+		if trade == nil {
+			totals(report.Unmatched, record.Asset).add(record)
+			continue
+		}
+		trade.Asset = record.Asset
+		trade.add(record)
+		totals(report.ByStrategy, trade.StrategyID).add(record)
+	}
+	return report, nil
+}
+
+// matchTrade 查找流水所属的交易：同币种、流水时间在开仓到平仓（含误差）之间，
+// 前后两笔交易的时间范围重叠时归入较晚开仓的一笔
+func matchTrade(trades []*TradeFees, record IncomeRecord, to time.Time) *TradeFees {
+	for i := len(trades) - 1; i >= 0; i-- {
+		trade := trades[i]
+		if trade.Symbol != record.Symbol {
+			continue
+		}
+		closeTime := trade.CloseTime
+		if closeTime.IsZero() {
+			closeTime = to
+		}
+		if !record.Time.Before(trade.OpenTime.Add(-feeMatchGrace)) && !record.Time.After(closeTime.Add(feeMatchGrace)) {
+			return trade
+		}
+	}
+	return nil
+}
+
+// journalTrades 从决策日志中按开平仓配对出[from, to]期间的交易
+// 同一持仓再次开仓时上一笔视为已在此之前平仓（由止损/止盈触发，未记录平仓决策）
+func (at *AutoTrader) journalTrades(from, to time.Time) ([]*TradeFees, error) {
+	var trades []*TradeFees
+	open := make(map[string]*TradeFees) // symbol_side -> 未平仓交易
+
+	local := from.Local()
+	for day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local); !day.After(to); day = day.AddDate(0, 0, 1) {
+		records, err := at.decisionLogger.GetRecordByDate(day)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			for _, action := range record.Decisions {
+				if !action.Success || action.Timestamp.Before(from) || action.Timestamp.After(to) {
+					continue
+				}
+				side := strings.TrimPrefix(strings.TrimPrefix(action.Action, "open_"), "close_")
+				key := action.Symbol + "_" + side
+				switch action.Action {
+				case "open_long", "open_short":
+					if previous := open[key]; previous != nil {
+						previous.CloseTime = action.Timestamp
+					}
+					trade := &TradeFees{
+						Symbol:     action.Symbol,
+						Side:       side,
+						StrategyID: action.Strategy(),
+						OpenTime:   action.Timestamp,
+					}
+					trades = append(trades, trade)
+					open[key] = trade
+				case "close_long", "close_short":
+					trade := open[key]
+					if trade == nil {
+						// 开仓在统计区间之前
+						trade = &TradeFees{Symbol: action.Symbol, Side: side, StrategyID: action.Strategy(), OpenTime: from}
+						trades = append(trades, trade)
+					}
+					trade.CloseTime = action.Timestamp
+					delete(open, key)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].OpenTime.Before(trades[j].OpenTime) })
+	return trades, nil
+}
+
+// incomeRecordsFromFills 由成交记录生成手续费和已实现盈亏流水（用于只提供成交明细的交易所和模拟交易）
+func incomeRecordsFromFills(fills []PaperFill, asset string, start, end time.Time) []IncomeRecord {
+	var records []IncomeRecord
+	for i, fill := range fills {
+		if fill.Time.Before(start) || fill.Time.After(end) {
+			continue
+		}
+		id := strconv.Itoa(i)
+		records = append(records, IncomeRecord{
+			ID: id + "_fee", Time: fill.Time, Symbol: fill.Symbol, Type: IncomeCommission, Amount: -fill.Fee, Asset: asset,
+		})
+		if fill.Action == "close" {
+			records = append(records, IncomeRecord{
+				ID: id + "_pnl", Time: fill.Time, Symbol: fill.Symbol, Type: IncomeRealizedPnL, Amount: fill.PnL + fill.Fee, Asset: asset,
+			})
+		}
+	}
+	return records
+}
@@ -0,0 +1,478 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/symbols"
+)
+
+// FundingHarvestStrategyID 资金费率套利的策略ID（用于归因永续腿的成交和资金预算）
+const FundingHarvestStrategyID = "funding_harvest"
+
+// FundingHarvestConfig 资金费率套利配置（Delta中性：永续腿与现货腿数量相同、方向相反）
+type FundingHarvestConfig struct {
+	Symbols      []string // 扫描的币种
+	EntryRatePct float64  // 8小时资金费率绝对值≥该值时开仓（%）
+	ExitRatePct  float64  // 8小时资金费率回落到该值以下或反向时平仓（%）
+	SizeUSD      float64  // 每个币种的名义价值（USDT）
+	Leverage     int      // 永续腿杠杆
+	MaxPositions int      // 同时持有的套利数量上限
+	Reverse      bool     // 负费率时卖出已持有的现货、开多永续（不支持借币卖空现货）
+}
+
+// enabled 是否启用资金费率套利
+func (c FundingHarvestConfig) enabled() bool {
+	return len(c.Symbols) > 0
+}
+
+// HarvestPosition 一笔资金费率套利（正费率：空永续+买现货；负费率：多永续+卖现货）
+type HarvestPosition struct {
+	Symbol           string    `json:"symbol"`
+	PerpSide         string    `json:"perp_side"` // 永续腿方向: "short" 或 "long"
+	Quantity         float64   `json:"quantity"`  // 永续腿数量
+	SpotQuantity     float64   `json:"spot_quantity"`
+	PerpEntry        float64   `json:"perp_entry"`
+	SpotEntry        float64   `json:"spot_entry"`
+	EntryRate8h      float64   `json:"entry_rate_8h"`
+	SpotFees         float64   `json:"spot_fees"`
+	EstimatedFunding float64   `json:"estimated_funding"` // 按每周期资金费率估算的累计资金费
+	OpenedAt         time.Time `json:"opened_at"`
+
+	// 以下字段在生成报告时计算
+	CurrentRate8h    float64 `json:"current_rate_8h"`
+	Funding          float64 `json:"funding"`           // 已收资金费（交易所流水，无流水时为估算值）
+	FundingEstimated bool    `json:"funding_estimated"` // Funding是否为估算值
+	Fees             float64 `json:"fees"`              // 永续手续费 + 现货手续费
+	BasisPnL         float64 `json:"basis_pnl"`         // 两条腿价格变动的盈亏合计（基差变化）
+	NetCarry         float64 `json:"net_carry"`         // Funding - Fees + BasisPnL
+
+	lastAccrual time.Time
+}
+
+// spotAction 现货腿的动作（开仓买入现货的套利平仓时卖出，反之买回）
+func (p *HarvestPosition) spotAction(opening bool) string {
+	if (p.PerpSide == "short") == opening {
+		return "spot_buy"
+	}
+	return "spot_sell"
+}
+
+// FundingHarvestReport 资金费率套利报告
+type FundingHarvestReport struct {
+	Positions []HarvestPosition    `json:"positions"`
+	Rates     []market.FundingRate `json:"rates"` // 最近一次扫描的资金费率（按绝对值排序）
+	NetCarry  float64              `json:"net_carry"`
+}
+
+// fundingHarvest 资金费率套利状态（交易周期和API并发访问）
+type fundingHarvest struct {
+	mu        sync.Mutex
+	positions map[string]*HarvestPosition // symbol -> 套利持仓
+	rates     []market.FundingRate
+}
+
+// harvestAction 套利一条腿的执行记录
+func harvestAction(action, symbol string, quantity float64, leverage int) logger.DecisionAction {
+	return logger.DecisionAction{
+		Action:     action,
+		Symbol:     symbol,
+		Quantity:   quantity,
+		Leverage:   leverage,
+		StrategyID: FundingHarvestStrategyID,
+		Tags:       []string{FundingHarvestStrategyID},
+		OrderType:  "market",
+		Timestamp:  time.Now(),
+	}
+}
+
+// isHarvestLeg 持仓是否为资金费率套利的永续腿
+func (at *AutoTrader) isHarvestLeg(symbol, side string) bool {
+	at.harvest.mu.Lock()
+	defer at.harvest.mu.Unlock()
+	position, ok := at.harvest.positions[symbol]
+	return ok && position.PerpSide == side
+}
+
+// manageFundingHarvest 扫描资金费率：已有套利在费率回落/反向或永续腿被平掉时平掉两条腿，
+// 费率绝对值达到入场阈值的币种开新的套利
+func (at *AutoTrader) manageFundingHarvest(record *logger.DecisionRecord) {
+	config := at.config.FundingHarvest
+	if !config.enabled() || at.spot == nil {
+		return
+	}
+	rates, err := market.ScanFunding(config.Symbols)
+	if err != nil {
+		log.Printf("⚠️  [%s] 资金费率扫描失败: %v", at.name, err)
+		return
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️  [%s] 资金费率套利检查失败: %v", at.name, err)
+		return
+	}
+	open := make(map[string]bool)
+	busy := make(map[string]bool)
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		open[symbol+"_"+side] = true
+		busy[symbol] = true
+	}
+
+	h := &at.harvest
+	h.mu.Lock()
+	h.rates = rates
+	var holdings []*HarvestPosition
+	for _, position := range h.positions {
+		holdings = append(holdings, position)
+	}
+	h.mu.Unlock()
+
+	now := market.Clock.Now()
+	for _, position := range holdings {
+		var rate *market.FundingRate
+		for i := range rates {
+			if rates[i].Symbol == position.Symbol {
+				rate = &rates[i]
+			}
+		}
+		if rate == nil {
+			continue // 本次未获取到费率，下个周期再检查
+		}
+		at.accrueFunding(position, *rate, now)
+
+		// 空永续收取正费率，多永续收取负费率
+		carryPct := rate.Rate8h * 100
+		if position.PerpSide == "long" {
+			carryPct = -carryPct
+		}
+		reason := ""
+		switch {
+		case !open[position.Symbol+"_"+position.PerpSide]:
+			reason = "永续腿已平仓"
+		case carryPct < config.ExitRatePct:
+			reason = fmt.Sprintf("资金费率回落至 %+.4f%%/8h", rate.Rate8h*100)
+		}
+		if reason == "" {
+			continue
+		}
+		log.Printf("💸 [%s] %s %s，平掉资金费率套利", at.name, position.Symbol, reason)
+		actions := at.closeHarvest(position, open[position.Symbol+"_"+position.PerpSide])
+		record.Decisions = append(record.Decisions, actions...)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("💸 %s 平资金费率套利: %s", position.Symbol, reason))
+	}
+
+	h.mu.Lock()
+	count := len(h.positions)
+	h.mu.Unlock()
+	for _, rate := range rates {
+		if count >= config.MaxPositions {
+			break
+		}
+		if math.Abs(rate.Rate8h)*100 < config.EntryRatePct {
+			continue
+		}
+		h.mu.Lock()
+		_, exists := h.positions[rate.Symbol]
+		h.mu.Unlock()
+		if exists {
+			continue
+		}
+		side := "short"
+		if rate.Rate8h < 0 {
+			if !config.Reverse {
+				continue
+			}
+			side = "long"
+		}
+		if busy[rate.Symbol] {
+			log.Printf("💸 [%s] %s 资金费率 %+.4f%%/8h，但已有其他持仓，跳过套利", at.name, rate.Symbol, rate.Rate8h*100)
+			continue
+		}
+
+		if reason := at.entryBlock(); reason != "" {
+			log.Printf("💸 [%s] %s 资金费率 %+.4f%%/8h，但当前禁止开新仓（%s），跳过套利", at.name, rate.Symbol, rate.Rate8h*100, reason)
+			continue
+		}
+
+		actions, err := at.openHarvest(rate, side)
+		record.Decisions = append(record.Decisions, actions...)
+		if err != nil {
+			log.Printf("❌ [%s] %s 资金费率套利开仓失败: %v", at.name, rate.Symbol, err)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s 资金费率套利开仓失败: %v", rate.Symbol, err))
+			continue
+		}
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("💸 %s 开资金费率套利（%s永续，费率 %+.4f%%/8h，年化 %.1f%%）",
+			rate.Symbol, sideName(side), rate.Rate8h*100, math.Abs(rate.APR)))
+		count++
+	}
+}
+
+// accrueFunding 按当前资金费率估算上次检查以来的资金费（交易所未返回资金费流水时使用，如模拟交易）
+func (at *AutoTrader) accrueFunding(position *HarvestPosition, rate market.FundingRate, now time.Time) {
+	at.harvest.mu.Lock()
+	defer at.harvest.mu.Unlock()
+	if elapsed := now.Sub(position.lastAccrual).Hours(); elapsed > 0 {
+		funding := rate.Rate * position.Quantity * position.PerpEntry * elapsed / float64(rate.Interval)
+		if position.PerpSide == "long" {
+			funding = -funding
+		}
+		position.EstimatedFunding += funding
+	}
+	position.lastAccrual = now
+}
+
+// openHarvest 先下现货腿，按现货实际成交数量开永续腿；永续腿失败时反向成交现货，避免留下单边敞口
+func (at *AutoTrader) openHarvest(rate market.FundingRate, side string) ([]logger.DecisionAction, error) {
+	config := at.config.FundingHarvest
+	symbol := rate.Symbol
+	price, err := at.spot.GetSpotPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	d := decision.Decision{
+		Symbol:          symbol,
+		Action:          "open_" + side,
+		Leverage:        config.Leverage,
+		PositionSizeUSD: config.SizeUSD,
+		StrategyID:      FundingHarvestStrategyID,
+	}
+	if err := at.applyAllocation(&d); err != nil {
+		return nil, err
+	}
+	if err := at.checkSymbolTier(symbol, config.Leverage, d.PositionSizeUSD); err != nil {
+		return nil, err
+	}
+	quantity, err := at.formatQuantity(symbol, d.PositionSizeUSD/price)
+	if err != nil {
+		return nil, err
+	}
+	now := market.Clock.Now() // 先于两条腿的成交时间，报告按此时间筛选手续费和资金费流水
+	position := &HarvestPosition{Symbol: symbol, PerpSide: side, EntryRate8h: rate.Rate8h, OpenedAt: now, lastAccrual: now}
+	log.Printf("  💸 开资金费率套利: %s %s永续 + %s现货 %.4f（费率 %+.4f%%/8h）",
+		symbol, sideName(side), position.spotAction(true), quantity, rate.Rate8h*100)
+
+	if side == "long" {
+		held, err := at.spot.GetSpotBalance(symbols.Canonical(symbol))
+		if err != nil {
+			return nil, err
+		}
+		if held < quantity {
+			return nil, fmt.Errorf("现货 %s 持有 %.4f，不足卖出 %.4f（不支持借币卖空）", symbols.Canonical(symbol), held, quantity)
+		}
+	}
+
+	// 现货腿
+	var actions []logger.DecisionAction
+	spotRecord := harvestAction(position.spotAction(true), symbol, quantity, 0)
+	spotRecord.DecisionPrice = price
+	spotOrder, err := at.spotOrder(spotRecord.Action, symbol, quantity)
+	if err != nil {
+		spotRecord.Error = err.Error()
+		return append(actions, spotRecord), err
+	}
+	spotRecord.Success = true
+	position.SpotQuantity, _ = spotOrder["executedQty"].(float64)
+	position.SpotEntry = orderAvgPrice(spotOrder)
+	position.SpotFees, _ = spotOrder["fee"].(float64)
+	spotRecord.Quantity = position.SpotQuantity
+	spotRecord.FillPrice = position.SpotEntry
+	spotRecord.Price = position.SpotEntry
+	actions = append(actions, spotRecord)
+
+	// 永续腿（数量与现货到账数量一致）
+	perpRecord := harvestAction("open_"+side, symbol, 0, config.Leverage)
+	perpRecord.DecisionPrice = price
+	perpQuantity, err := at.formatQuantity(symbol, position.SpotQuantity)
+	var perpOrder map[string]interface{}
+	if err == nil {
+		perpRecord.Quantity = perpQuantity
+		clientOrderID := at.clientOrderID(symbol, perpRecord.Action, FundingHarvestStrategyID)
+		if side == "short" {
+			perpOrder, err = at.trader.OpenShort(symbol, perpQuantity, config.Leverage, clientOrderID)
+		} else {
+			perpOrder, err = at.trader.OpenLong(symbol, perpQuantity, config.Leverage, clientOrderID)
+		}
+	}
+	if err != nil {
+		perpRecord.Error = err.Error()
+		actions = append(actions, perpRecord)
+		log.Printf("  ⚠ %s 永续腿开仓失败，反向成交现货腿", symbol)
+		actions = append(actions, at.closeHarvestSpot(position))
+		return actions, fmt.Errorf("永续腿开仓失败: %w", err)
+	}
+	perpRecord.Success = true
+	at.recordFill(&perpRecord, symbol, side, perpOrder, true)
+	actions = append(actions, perpRecord)
+
+	position.Quantity = perpQuantity
+	position.PerpEntry = perpRecord.Price
+	if position.PerpEntry <= 0 {
+		position.PerpEntry = price
+	}
+	at.positionFirstSeenTime[symbol+"_"+side] = now.UnixMilli()
+	at.trackPosition(symbol, side, perpQuantity, 0, 0, FundingHarvestStrategyID)
+
+	at.harvest.mu.Lock()
+	if at.harvest.positions == nil {
+		at.harvest.positions = make(map[string]*HarvestPosition)
+	}
+	at.harvest.positions[symbol] = position
+	at.harvest.mu.Unlock()
+	log.Printf("  ✓ 资金费率套利开仓成功: %s 数量 %.4f，永续 %.4f / 现货 %.4f", symbol, perpQuantity, position.PerpEntry, position.SpotEntry)
+	return actions, nil
+}
+
+// formatQuantity 按永续合约的数量精度格式化（两条腿使用同一数量）
+func (at *AutoTrader) formatQuantity(symbol string, quantity float64) (float64, error) {
+	formatted, err := at.trader.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseFloat(formatted, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%s 数量 %.8f 低于最小下单精度", symbol, quantity)
+	}
+	return value, nil
+}
+
+// spotOrder 按动作下现货市价单
+func (at *AutoTrader) spotOrder(action, symbol string, quantity float64) (map[string]interface{}, error) {
+	if action == "spot_buy" {
+		return at.spot.SpotBuy(symbol, quantity)
+	}
+	return at.spot.SpotSell(symbol, quantity)
+}
+
+// closeHarvestSpot 反向成交现货腿（卖出买入的现货，或买回卖出的现货）
+func (at *AutoTrader) closeHarvestSpot(position *HarvestPosition) logger.DecisionAction {
+	record := harvestAction(position.spotAction(false), position.Symbol, position.SpotQuantity, 0)
+	order, err := at.spotOrder(record.Action, position.Symbol, position.SpotQuantity)
+	if err != nil {
+		record.Error = err.Error()
+		log.Printf("  ❌ %s 现货腿反向成交失败，请手动处理: %v", position.Symbol, err)
+		return record
+	}
+	record.Success = true
+	record.FillPrice = orderAvgPrice(order)
+	record.Price = record.FillPrice
+	return record
+}
+
+// closeHarvest 平掉套利的两条腿（perpOpen为false时永续腿已不存在，只处理现货腿）
+func (at *AutoTrader) closeHarvest(position *HarvestPosition, perpOpen bool) []logger.DecisionAction {
+	var actions []logger.DecisionAction
+	if perpOpen {
+		record := harvestAction("close_"+position.PerpSide, position.Symbol, position.Quantity, 0)
+		order, err := at.closeSide(position.Symbol, position.PerpSide, position.Quantity, 1, "资金费率套利平仓")
+		if err != nil {
+			// 永续腿平仓失败时保留现货腿，下个周期重试
+			record.Error = err.Error()
+			log.Printf("  ❌ %s 永续腿平仓失败: %v", position.Symbol, err)
+			return append(actions, record)
+		}
+		record.Success = true
+		at.recordFill(&record, position.Symbol, position.PerpSide, order, false)
+		actions = append(actions, record)
+	} else {
+		at.untrackPosition(position.Symbol, position.PerpSide)
+	}
+
+	spot := at.closeHarvestSpot(position)
+	actions = append(actions, spot)
+	if !spot.Success {
+		log.Printf("  ⚠ %s 现货腿 %.4f 未能反向成交，已停止跟踪该套利", position.Symbol, position.SpotQuantity)
+	}
+	at.harvest.mu.Lock()
+	delete(at.harvest.positions, position.Symbol)
+	at.harvest.mu.Unlock()
+	return actions
+}
+
+// FundingHarvestReport 资金费率套利报告：各笔套利的资金费、手续费、基差盈亏和净收益
+func (at *AutoTrader) FundingHarvestReport() (*FundingHarvestReport, error) {
+	if at.spot == nil {
+		return nil, fmt.Errorf("该trader未启用资金费率套利")
+	}
+	at.harvest.mu.Lock()
+	report := &FundingHarvestReport{Rates: append([]market.FundingRate(nil), at.harvest.rates...)}
+	for _, position := range at.harvest.positions {
+		report.Positions = append(report.Positions, *position)
+	}
+	at.harvest.mu.Unlock()
+	sort.Slice(report.Positions, func(i, j int) bool { return report.Positions[i].Symbol < report.Positions[j].Symbol })
+	if len(report.Positions) == 0 {
+		return report, nil
+	}
+
+	since := report.Positions[0].OpenedAt
+	for _, position := range report.Positions {
+		if position.OpenedAt.Before(since) {
+			since = position.OpenedAt
+		}
+	}
+	income, err := at.trader.GetIncomeHistory(since, market.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("获取收益流水失败: %w", err)
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	for i := range report.Positions {
+		p := &report.Positions[i]
+		for _, rate := range report.Rates {
+			if rate.Symbol == p.Symbol {
+				p.CurrentRate8h = rate.Rate8h
+			}
+		}
+
+		hasFunding := false
+		perpFees := 0.0
+		for _, record := range income {
+			if record.Symbol != p.Symbol || record.Time.Before(p.OpenedAt) {
+				continue
+			}
+			switch record.Type {
+			case IncomeFunding:
+				p.Funding += record.Amount
+				hasFunding = true
+			case IncomeCommission, IncomeRebate:
+				perpFees -= record.Amount
+			}
+		}
+		if !hasFunding {
+			p.Funding = p.EstimatedFunding
+			p.FundingEstimated = true
+		}
+		p.Fees = perpFees + p.SpotFees
+
+		for _, pos := range positions {
+			if pos["symbol"] == p.Symbol && pos["side"] == p.PerpSide {
+				pnl, _ := pos["unRealizedProfit"].(float64)
+				p.BasisPnL += pnl
+			}
+		}
+		if spotPrice, err := at.spot.GetSpotPrice(p.Symbol); err == nil {
+			spotPnL := (spotPrice - p.SpotEntry) * p.SpotQuantity
+			if p.PerpSide == "long" {
+				spotPnL = -spotPnL // 已卖出的现货，买回成本上升为亏损
+			}
+			p.BasisPnL += spotPnL
+		}
+		p.NetCarry = p.Funding - p.Fees + p.BasisPnL
+		report.NetCarry += p.NetCarry
+	}
+	return report, nil
+}
@@ -0,0 +1,230 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"nofx/decision"
+)
+
+// GridConfig 网格/DCA开仓配置（Levels≥2时启用）
+type GridConfig struct {
+	Levels         int     // 网格层数（含首层）
+	SpacingPct     float64 // 相邻两层的价格间距（%），多单向下、空单向上
+	SizeMultiplier float64 // 每层数量相对上一层的倍数（1为等量，>1为越低越多）
+}
+
+// gridLevel 网格中的一层
+type gridLevel struct {
+	Price    float64
+	Quantity float64
+}
+
+// gridEntry 一次网格开仓：首层按正常开仓下单，其余各层挂GTC限价单；
+// 止损对整个网格生效，止盈保持开仓时的收益距离、按成交均价重新计算
+type gridEntry struct {
+	Symbol        string
+	Side          string
+	Leverage      int
+	StrategyID    string
+	StopLoss      float64
+	TakeProfitPct float64 // 止盈相对成交均价的距离（%）
+	Levels        []gridLevel
+	Filled        float64 // 已成交数量（上次检查时的持仓数量）
+	ClientOrderID string  // 首层订单的客户端订单ID，各层订单ID由此派生
+	revision      int     // 重新挂单次数（交易所不允许复用已取消订单的ID）
+}
+
+// gridEnabled 是否使用网格开仓
+func (at *AutoTrader) gridEnabled() bool {
+	return at.config.Grid.Levels >= 2
+}
+
+// planGrid 把开仓数量拆分到各层：多单从price向下、空单向上按间距排列，越过止损的层不挂
+func planGrid(config GridConfig, d *decision.Decision, side string, quantity, price float64) ([]gridLevel, error) {
+	multiplier := config.SizeMultiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+
+	var levels []gridLevel
+	weightSum, weight := 0.0, 1.0
+	for i := 0; i < config.Levels; i++ {
+		offset := float64(i) * config.SpacingPct / 100
+		levelPrice := price * (1 - offset)
+		if side == "short" {
+			levelPrice = price * (1 + offset)
+		}
+		if (side == "long" && levelPrice <= d.StopLoss) || (side == "short" && levelPrice >= d.StopLoss) {
+			break
+		}
+		levels = append(levels, gridLevel{Price: levelPrice, Quantity: weight})
+		weightSum += weight
+		weight *= multiplier
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("网格首层已越过止损价")
+	}
+	for i := range levels {
+		levels[i].Quantity = quantity * levels[i].Quantity / weightSum
+	}
+	return levels, nil
+}
+
+// newGridEntry 按配置规划网格开仓（未启用网格时返回nil）
+func (at *AutoTrader) newGridEntry(d *decision.Decision, side string, quantity, price float64) (*gridEntry, error) {
+	if !at.gridEnabled() {
+		return nil, nil
+	}
+	levels, err := planGrid(at.config.Grid, d, side, quantity, price)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("  🪜 网格开仓: %d层，间距 %.2f%%，首层 %.4f", len(levels), at.config.Grid.SpacingPct, levels[0].Quantity)
+	return &gridEntry{
+		Symbol:        d.Symbol,
+		Side:          side,
+		Leverage:      d.Leverage,
+		StrategyID:    d.StrategyID,
+		StopLoss:      d.StopLoss,
+		TakeProfitPct: math.Abs(d.TakeProfit-price) / price * 100,
+		Levels:        levels,
+		ClientOrderID: at.clientOrderID(d.Symbol, "open_"+side, d.StrategyID),
+	}, nil
+}
+
+// placeGridLevels 首层下单后挂出其余各层限价单（filled为首层已成交数量）
+func (at *AutoTrader) placeGridLevels(grid *gridEntry, filled float64) {
+	grid.Filled = filled
+	at.grids[grid.Symbol+"_"+grid.Side] = grid
+	at.placePendingLevels(grid)
+}
+
+// restoreGridLevels 重新挂出该币种网格中未成交的各层（取消该币种全部挂单后调用）
+func (at *AutoTrader) restoreGridLevels(symbol string) {
+	for _, grid := range at.grids {
+		if grid.Symbol == symbol && grid.Filled > 0 {
+			grid.revision++
+			at.placePendingLevels(grid)
+		}
+	}
+}
+
+// placePendingLevels 按已成交数量挂出第2层起未成交的数量（首层由正常开仓下单）
+func (at *AutoTrader) placePendingLevels(grid *gridEntry) {
+	cumulative := 0.0
+	for i, level := range grid.Levels {
+		cumulative += level.Quantity
+		quantity := math.Min(level.Quantity, cumulative-grid.Filled)
+		if i == 0 || quantity <= level.Quantity*quantityTolerance {
+			continue
+		}
+		order := LimitOrder{
+			Symbol:        grid.Symbol,
+			Side:          grid.Side,
+			Quantity:      quantity,
+			Price:         level.Price,
+			Leverage:      grid.Leverage,
+			TimeInForce:   TimeInForceGTC,
+			ClientOrderID: gridOrderID(grid.ClientOrderID, i, grid.revision),
+		}
+		if _, err := at.trader.PlaceLimitOrder(order); err != nil {
+			log.Printf("  ⚠ 网格第%d层挂单失败: %v", i+1, err)
+			continue
+		}
+		log.Printf("  🪜 网格第%d层: %s %s %.4f @ %.4f", i+1, grid.Symbol, grid.Side, quantity, level.Price)
+	}
+}
+
+// gridOrderID 由首层订单ID派生网格各层的客户端订单ID（保持长度，替换末尾4位十六进制）
+func gridOrderID(base string, level, revision int) string {
+	if len(base) < 4 {
+		return base
+	}
+	return fmt.Sprintf("%s%02x%02x", base[:len(base)-4], level&0xff, revision&0xff)
+}
+
+// filledLevels 已成交数量覆盖的层数（各层按价格顺序依次成交）
+func (grid *gridEntry) filledLevels(filled float64) int {
+	cumulative := 0.0
+	for i, level := range grid.Levels {
+		cumulative += level.Quantity
+		if filled < cumulative*(1-quantityTolerance) {
+			return i
+		}
+	}
+	return len(grid.Levels)
+}
+
+// takeProfit 按成交均价计算网格的止盈价
+func (grid *gridEntry) takeProfit(avgEntry float64) float64 {
+	if grid.Side == "long" {
+		return avgEntry * (1 + grid.TakeProfitPct/100)
+	}
+	return avgEntry * (1 - grid.TakeProfitPct/100)
+}
+
+// manageGrids 管理网格持仓：有新的层成交时按总持仓重挂止损、按均价重挂止盈；
+// 持仓已平（止损/止盈或手动）时取消剩余的网格挂单
+func (at *AutoTrader) manageGrids() {
+	if len(at.grids) == 0 {
+		return
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️  [%s] 网格检查失败: %v", at.name, err)
+		return
+	}
+	type position struct{ quantity, entry float64 }
+	current := make(map[string]position)
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		amount, _ := pos["positionAmt"].(float64)
+		entry, _ := pos["entryPrice"].(float64)
+		current[symbol+"_"+side] = position{math.Abs(amount), entry}
+	}
+
+	for key, grid := range at.grids {
+		pos, open := current[key]
+		if (!open || pos.quantity == 0) && grid.Filled == 0 {
+			continue // 首层限价单尚未成交
+		}
+		if !open || pos.quantity == 0 {
+			log.Printf("🪜 [%s] %s %s 网格持仓已平仓，取消剩余网格挂单", at.name, grid.Symbol, grid.Side)
+			delete(at.grids, key)
+			at.exitPlan(grid.Symbol, grid.Side, nil, 0, "网格持仓已平仓（止损/止盈或手动）")
+			if err := at.trader.CancelAllOrders(grid.Symbol); err != nil {
+				log.Printf("  ⚠ 取消网格挂单失败: %v", err)
+				continue
+			}
+			at.restoreStops(grid.Symbol) // 同币种另一方向的持仓及网格
+			continue
+		}
+		if pos.quantity <= grid.Filled*(1+quantityTolerance) {
+			continue
+		}
+
+		grid.Filled = pos.quantity
+		filled := grid.filledLevels(pos.quantity)
+		tracked, ok := at.trackedPositions[key]
+		if !ok || tracked.StrategyID == "" {
+			// 首层限价单成交后才建立持仓（可能已被对账接管为无策略持仓）
+			at.trackPosition(grid.Symbol, grid.Side, pos.quantity, grid.StopLoss, 0, grid.StrategyID)
+			tracked = at.trackedPositions[key]
+		}
+		tracked.Quantity = pos.quantity
+		tracked.TakeProfit = grid.takeProfit(pos.entry)
+		log.Printf("🪜 [%s] %s %s 网格已成交 %d/%d 层，持仓 %.4f，均价 %.4f，止盈调整为 %.4f",
+			at.name, grid.Symbol, grid.Side, filled, len(grid.Levels), pos.quantity, pos.entry, tracked.TakeProfit)
+		at.updatePlan(grid.Symbol, grid.Side, pos.quantity, pos.entry, filled < len(grid.Levels), "网格加仓后按均价调整止盈")
+
+		// 取消全部挂单后按新数量重挂止损/止盈，并挂回未成交的层
+		if err := at.trader.CancelAllOrders(grid.Symbol); err != nil {
+			log.Printf("  ⚠ 取消旧止损/止盈单失败: %v", err)
+			continue
+		}
+		at.restoreStops(grid.Symbol)
+	}
+}
@@ -6,6 +6,11 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"time"
+
+	"nofx/market"
+	"nofx/symbols"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
@@ -145,7 +150,7 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 		posMap := make(map[string]interface{})
 
 		// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"）
-		symbol := position.Coin + "USDT"
+		symbol := symbols.System(position.Coin)
 		posMap["symbol"] = symbol
 
 		// 持仓数量和方向
@@ -203,7 +208,7 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 }
 
 // OpenLong 开多仓
-func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -244,6 +249,9 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 		},
 		ReduceOnly: false,
 	}
+	if clientOrderID != "" {
+		order.ClientOrderID = &clientOrderID // cloid: 重试时使用相同ID，交易所拒绝重复订单
+	}
 
 	_, err = t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
@@ -261,7 +269,7 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 }
 
 // OpenShort 开空仓
-func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -302,6 +310,9 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 		},
 		ReduceOnly: false,
 	}
+	if clientOrderID != "" {
+		order.ClientOrderID = &clientOrderID // cloid: 重试时使用相同ID，交易所拒绝重复订单
+	}
 
 	_, err = t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
@@ -462,6 +473,79 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 	return result, nil
 }
 
+// PlaceLimitOrder 下限价单（GTC→Gtc、IOC→Ioc、GTX/post-only→Alo，不支持FOK）
+func (t *HyperliquidTrader) PlaceLimitOrder(order LimitOrder) (map[string]interface{}, error) {
+	tif, err := order.EffectiveTimeInForce()
+	if err != nil {
+		return nil, err
+	}
+
+	var hlTif hyperliquid.Tif
+	switch tif {
+	case TimeInForceGTC:
+		hlTif = hyperliquid.TifGtc
+	case TimeInForceIOC:
+		hlTif = hyperliquid.TifIoc
+	case TimeInForceGTX:
+		hlTif = hyperliquid.TifAlo // Add Liquidity Only
+	default:
+		return nil, fmt.Errorf("Hyperliquid不支持%s有效期", tif)
+	}
+
+	if !order.ReduceOnly {
+		if err := t.SetLeverage(order.Symbol, order.Leverage); err != nil {
+			return nil, err
+		}
+	}
+
+	coin := convertSymbolToHyperliquid(order.Symbol)
+	isBuy := order.Side == "long"
+	if order.ReduceOnly {
+		isBuy = !isBuy
+	}
+
+	request := hyperliquid.CreateOrderRequest{
+		Coin:  coin,
+		IsBuy: isBuy,
+		Size:  t.roundToSzDecimals(coin, order.Quantity),
+		Price: t.roundPriceToSigfigs(order.Price),
+		OrderType: hyperliquid.OrderType{
+			Limit: &hyperliquid.LimitOrderType{Tif: hlTif},
+		},
+		ReduceOnly: order.ReduceOnly,
+	}
+	if order.ClientOrderID != "" {
+		request.ClientOrderID = &order.ClientOrderID
+	}
+
+	status, err := t.exchange.Order(t.ctx, request, nil)
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+	if status.Error != nil {
+		return nil, fmt.Errorf("下限价单失败: %s", *status.Error)
+	}
+
+	// 按撮合结果返回状态：立即成交为FILLED，挂单为NEW，IOC未成交为EXPIRED
+	result := make(map[string]interface{})
+	result["symbol"] = order.Symbol
+	result["timeInForce"] = tif
+	switch {
+	case status.Filled != nil:
+		result["orderId"] = int64(status.Filled.Oid)
+		result["status"] = "FILLED"
+	case status.Resting != nil:
+		result["orderId"] = status.Resting.Oid
+		result["status"] = "NEW"
+	default:
+		result["orderId"] = 0
+		result["status"] = "EXPIRED"
+	}
+
+	log.Printf("✓ 限价单已提交: %s buy=%v %.4f @ %.4f (%s) 状态: %s", order.Symbol, isBuy, request.Size, request.Price, tif, result["status"])
+	return result, nil
+}
+
 // CancelAllOrders 取消该币种的所有挂单
 func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
 	coin := convertSymbolToHyperliquid(symbol)
@@ -486,6 +570,115 @@ func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
 	return nil
 }
 
+// GetOrderBook 获取Hyperliquid盘口深度（l2Book，每侧最多20档）
+func (t *HyperliquidTrader) GetOrderBook(symbol string, limit int) (*market.OrderBook, error) {
+	snapshot, err := t.exchange.Info().L2Snapshot(t.ctx, convertSymbolToHyperliquid(symbol))
+	if err != nil {
+		return nil, fmt.Errorf("获取盘口深度失败: %w", err)
+	}
+	if len(snapshot.Levels) != 2 {
+		return nil, fmt.Errorf("盘口深度格式错误: %d侧", len(snapshot.Levels))
+	}
+	book := &market.OrderBook{Symbol: symbol, Time: time.UnixMilli(snapshot.Time)}
+	for i, levels := range []*[]market.BookLevel{&book.Bids, &book.Asks} {
+		for j, level := range snapshot.Levels[i] {
+			if j >= limit {
+				break
+			}
+			*levels = append(*levels, market.BookLevel{Price: level.Px, Quantity: level.Sz})
+		}
+	}
+	return book, nil
+}
+
+// CancelOrder 按订单ID（oid）取消单个挂单
+func (t *HyperliquidTrader) CancelOrder(symbol string, orderID int64) error {
+	if _, err := t.exchange.Cancel(t.ctx, convertSymbolToHyperliquid(symbol), orderID); err != nil {
+		return fmt.Errorf("取消订单失败 (oid=%d): %w", orderID, err)
+	}
+	return nil
+}
+
+// GetOrderByClientID 按客户端订单ID（cloid）查询订单（订单不存在时返回nil, nil）
+func (t *HyperliquidTrader) GetOrderByClientID(symbol, clientOrderID string) (map[string]interface{}, error) {
+	queried, err := t.exchange.Info().QueryOrderByCloid(t.ctx, t.walletAddr, clientOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	if queried.Status != hyperliquid.OrderQueryStatusSuccess {
+		return nil, nil // 订单不存在
+	}
+
+	result := make(map[string]interface{})
+	result["orderId"] = queried.Order.Order.Oid
+	result["symbol"] = symbol
+	result["status"] = string(queried.Order.Status)
+	return result, nil
+}
+
+// GetOpenOrders 获取挂单（symbol为空表示所有币种）
+func (t *HyperliquidTrader) GetOpenOrders(symbol string) ([]map[string]interface{}, error) {
+	openOrders, err := t.exchange.Info().FrontendOpenOrders(t.ctx, t.walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	coin := ""
+	if symbol != "" {
+		coin = convertSymbolToHyperliquid(symbol)
+	}
+
+	result := make([]map[string]interface{}, 0, len(openOrders))
+	for _, order := range openOrders {
+		if coin != "" && order.Coin != coin {
+			continue
+		}
+
+		// 订单类型如 "Stop Market"、"Take Profit Market"、"Limit"，统一为币安格式
+		orderType := strings.ToUpper(strings.ReplaceAll(order.OrderType, " ", "_"))
+		side := "BUY"
+		if order.Side == hyperliquid.OrderSideAsk {
+			side = "SELL"
+		}
+		result = append(result, openOrderMap(order.Oid, symbols.System(order.Coin), orderType,
+			side, "BOTH", order.TriggerPx, order.Sz))
+	}
+	return result, nil
+}
+
+// GetIncomeHistory 由成交明细（userFillsByTime）生成手续费/返佣和已实现盈亏流水（资金费暂不包含）
+func (t *HyperliquidTrader) GetIncomeHistory(startTime, endTime time.Time) ([]IncomeRecord, error) {
+	end := endTime.UnixMilli()
+	fills, err := t.exchange.Info().UserFillsByTime(t.ctx, t.walletAddr, startTime.UnixMilli(), &end)
+	if err != nil {
+		return nil, fmt.Errorf("获取成交明细失败: %w", err)
+	}
+
+	var records []IncomeRecord
+	for _, fill := range fills {
+		symbol := symbols.System(fill.Coin)
+		fillTime := time.UnixMilli(fill.Time)
+		tid := strconv.FormatInt(fill.Tid, 10)
+
+		// 手续费为负数时是Maker返佣
+		if fee, _ := strconv.ParseFloat(fill.Fee, 64); fee != 0 {
+			feeType := IncomeCommission
+			if fee < 0 {
+				feeType = IncomeRebate
+			}
+			records = append(records, IncomeRecord{
+				ID: tid + "_fee", Time: fillTime, Symbol: symbol, Type: feeType, Amount: -fee, Asset: fill.FeeToken, TradeID: tid,
+			})
+		}
+		if pnl, _ := strconv.ParseFloat(fill.ClosedPnl, 64); pnl != 0 {
+			records = append(records, IncomeRecord{
+				ID: tid + "_pnl", Time: fillTime, Symbol: symbol, Type: IncomeRealizedPnL, Amount: pnl, Asset: "USDC", TradeID: tid,
+			})
+		}
+	}
+	return records, nil
+}
+
 // GetMarketPrice 获取市场价格
 func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
 	coin := convertSymbolToHyperliquid(symbol)
@@ -665,11 +858,7 @@ func (t *HyperliquidTrader) roundPriceToSigfigs(price float64) float64 {
 // convertSymbolToHyperliquid 将标准symbol转换为Hyperliquid格式
 // 例如: "BTCUSDT" -> "BTC"
 func convertSymbolToHyperliquid(symbol string) string {
-	// 去掉USDT后缀
-	if len(symbol) > 4 && symbol[len(symbol)-4:] == "USDT" {
-		return symbol[:len(symbol)-4]
-	}
-	return symbol
+	return symbols.ToExchange(symbol, "hyperliquid")
 }
 
 // absFloat 返回浮点数的绝对值
@@ -0,0 +1,128 @@
+package trader
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"nofx/logger"
+	"nofx/market"
+	"nofx/risk"
+)
+
+// SymbolSnapshot 最近一个周期的币种行情快照
+type SymbolSnapshot struct {
+	Symbol        string  `json:"symbol"`
+	Price         float64 `json:"price"`
+	LivePrice     float64 `json:"live_price,omitempty"` // 实时标记价格（实时行情中心未启动时为0）
+	PriceChange1h float64 `json:"price_change_1h"`
+	PriceChange4h float64 `json:"price_change_4h"`
+	FundingRate   float64 `json:"funding_rate"`
+	StrengthScore float64 `json:"strength_score"`
+	RSI14         float64 `json:"rsi14,omitempty"` // 趋势周期最新RSI14
+}
+
+// RiskUtilization 风控限额使用情况
+type RiskUtilization struct {
+	Equity         float64   `json:"equity"`
+	DayStartEquity float64   `json:"day_start_equity"`
+	DailyLossPct   float64   `json:"daily_loss_pct"` // 当日亏损（%，盈利时为0）
+	MaxDailyLoss   float64   `json:"max_daily_loss"` // 日亏损上限（%，0表示不限制）
+	PeakEquity     float64   `json:"peak_equity"`
+	DrawdownPct    float64   `json:"drawdown_pct"`
+	MaxDrawdown    float64   `json:"max_drawdown"` // 回撤上限（%，0表示不限制）
+	MarginUsedPct  float64   `json:"margin_used_pct"`
+	OpenRiskUSD    float64   `json:"open_risk_usd"`     // 组合开放风险（止损距离×数量之和）
+	OpenRiskPct    float64   `json:"open_risk_pct"`     // 开放风险占净值（%）
+	MaxOpenRiskPct float64   `json:"max_open_risk_pct"` // 开放风险预算（%，0表示不限制）
+	StopUntil      time.Time `json:"stop_until"`        // 风控暂停截止时间（零值表示未暂停）
+	BreakerTripped bool      `json:"breaker_tripped"`
+	BreakerReason  string    `json:"breaker_reason,omitempty"`
+}
+
+// InspectReport trader当前状态（持仓、挂单、最近行情快照、最近决策、风控使用情况）
+type InspectReport struct {
+	TraderID     string                   `json:"trader_id"`
+	Name         string                   `json:"name"`
+	Exchange     string                   `json:"exchange"`
+	Running      bool                     `json:"running"`
+	CallCount    int                      `json:"call_count"`
+	Positions    []map[string]interface{} `json:"positions"`
+	OpenOrders   []map[string]interface{} `json:"open_orders"`
+	Snapshots    []SymbolSnapshot         `json:"snapshots"`
+	LastDecision *logger.DecisionRecord   `json:"last_decision,omitempty"` // 不含输入prompt
+	Risk         RiskUtilization          `json:"risk"`
+}
+
+// Inspect 汇总trader当前状态（供inspect命令查看）
+func (at *AutoTrader) Inspect() (*InspectReport, error) {
+	report := &InspectReport{
+		TraderID:  at.id,
+		Name:      at.name,
+		Exchange:  at.exchange,
+		Running:   at.isRunning,
+		CallCount: at.callCount,
+	}
+
+	account, err := at.GetAccountInfo()
+	if err != nil {
+		return nil, err
+	}
+	if report.Positions, err = at.GetPositions(); err != nil {
+		return nil, err
+	}
+	if report.OpenOrders, err = at.trader.GetOpenOrders(""); err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	for symbol, data := range at.lastMarketData {
+		snapshot := SymbolSnapshot{
+			Symbol:        symbol,
+			Price:         data.CurrentPrice,
+			PriceChange1h: data.PriceChange1h,
+			PriceChange4h: data.PriceChange4h,
+			FundingRate:   data.FundingRate,
+			StrengthScore: data.StrengthScore,
+		}
+		if price, ok := market.Hub.MarkPrice(symbol, livePriceMaxAge); ok {
+			snapshot.LivePrice = price
+		}
+		if ltd := data.LongerTermContext; ltd != nil && len(ltd.RSI14Values) > 0 {
+			snapshot.RSI14 = ltd.RSI14Values[len(ltd.RSI14Values)-1]
+		}
+		report.Snapshots = append(report.Snapshots, snapshot)
+	}
+	sort.Slice(report.Snapshots, func(i, j int) bool { return report.Snapshots[i].Symbol < report.Snapshots[j].Symbol })
+
+	if records, err := at.decisionLogger.GetLatestRecords(1); err == nil && len(records) > 0 {
+		report.LastDecision = records[len(records)-1]
+		report.LastDecision.InputPrompt = ""
+	}
+
+	equity, _ := account["total_equity"].(float64)
+	marginUsedPct, _ := account["margin_used_pct"].(float64)
+	utilization := RiskUtilization{
+		Equity:         equity,
+		DayStartEquity: at.dayStartEquity,
+		MaxDailyLoss:   at.config.MaxDailyLoss,
+		PeakEquity:     at.peakEquity,
+		MaxDrawdown:    at.config.MaxDrawdown,
+		MarginUsedPct:  marginUsedPct,
+		MaxOpenRiskPct: at.config.MaxOpenRiskPct,
+	}
+	openRisk := at.OpenRisk()
+	utilization.OpenRiskUSD, utilization.OpenRiskPct = openRisk.TotalUSD, openRisk.Pct
+	if at.dayStartEquity > 0 && equity < at.dayStartEquity {
+		utilization.DailyLossPct = (at.dayStartEquity - equity) / at.dayStartEquity * 100
+	}
+	if at.peakEquity > 0 && equity < at.peakEquity {
+		utilization.DrawdownPct = (at.peakEquity - equity) / at.peakEquity * 100
+	}
+	if at.stopUntil.After(market.Clock.Now()) {
+		utilization.StopUntil = at.stopUntil
+	}
+	utilization.BreakerTripped, utilization.BreakerReason = risk.Breaker.Tripped()
+	report.Risk = utilization
+
+	return report, nil
+}
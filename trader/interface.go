@@ -1,5 +1,50 @@
 package trader
 
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"nofx/market"
+)
+
+// 限价单有效期类型
+const (
+	TimeInForceGTC = "GTC" // 一直有效直到取消
+	TimeInForceIOC = "IOC" // 立即成交，未成交部分取消
+	TimeInForceFOK = "FOK" // 全部成交，否则全部取消
+	TimeInForceGTX = "GTX" // 只做Maker（post-only），会立即成交时拒绝
+)
+
+// LimitOrder 限价单参数
+type LimitOrder struct {
+	Symbol        string
+	Side          string // 持仓方向: "long" 或 "short"
+	Quantity      float64
+	Price         float64
+	Leverage      int    // 开仓时设置的杠杆（ReduceOnly时忽略）
+	TimeInForce   string // GTC（默认）、IOC、FOK 或 GTX
+	PostOnly      bool   // 只做Maker，等价于 TimeInForce=GTX
+	ReduceOnly    bool   // 平仓单（只减少Side方向的持仓）
+	ClientOrderID string // 客户端订单ID（可选，用于幂等下单）
+}
+
+// EffectiveTimeInForce 返回标准化后的有效期类型（PostOnly优先）
+func (o LimitOrder) EffectiveTimeInForce() (string, error) {
+	if o.PostOnly {
+		return TimeInForceGTX, nil
+	}
+	tif := strings.ToUpper(o.TimeInForce)
+	switch tif {
+	case "":
+		return TimeInForceGTC, nil
+	case TimeInForceGTC, TimeInForceIOC, TimeInForceFOK, TimeInForceGTX:
+		return tif, nil
+	default:
+		return "", fmt.Errorf("不支持的time_in_force: %s（可选GTC/IOC/FOK/GTX）", o.TimeInForce)
+	}
+}
+
 // Trader 交易器统一接口
 // 支持多个交易平台（币安、Hyperliquid等）
 type Trader interface {
@@ -9,11 +54,14 @@ type Trader interface {
 	// GetPositions 获取所有持仓
 	GetPositions() ([]map[string]interface{}, error)
 
-	// OpenLong 开多仓
-	OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
+	// OpenLong 开多仓（clientOrderID非空时作为客户端订单ID，用于幂等下单）
+	OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error)
 
-	// OpenShort 开空仓
-	OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
+	// OpenShort 开空仓（clientOrderID非空时作为客户端订单ID，用于幂等下单）
+	OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error)
+
+	// PlaceLimitOrder 下限价单（支持GTC/IOC/FOK/GTX有效期和post-only）
+	PlaceLimitOrder(order LimitOrder) (map[string]interface{}, error)
 
 	// CloseLong 平多仓（quantity=0表示全部平仓）
 	CloseLong(symbol string, quantity float64) (map[string]interface{}, error)
@@ -27,6 +75,9 @@ type Trader interface {
 	// GetMarketPrice 获取市场价格
 	GetMarketPrice(symbol string) (float64, error)
 
+	// GetOrderBook 获取本交易所的盘口深度（数量统一为币的数量，limit为每侧至少的档数，交易所档数有限时可能更少）
+	GetOrderBook(symbol string, limit int) (*market.OrderBook, error)
+
 	// SetStopLoss 设置止损单
 	SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error
 
@@ -36,6 +87,34 @@ type Trader interface {
 	// CancelAllOrders 取消该币种的所有挂单
 	CancelAllOrders(symbol string) error
 
+	// CancelOrder 按订单ID取消单个挂单
+	CancelOrder(symbol string, orderID int64) error
+
+	// GetOrderByClientID 按客户端订单ID查询订单（订单不存在时返回nil, nil）
+	GetOrderByClientID(symbol, clientOrderID string) (map[string]interface{}, error)
+
+	// GetOpenOrders 获取挂单（symbol为空表示所有币种，用于对账）
+	GetOpenOrders(symbol string) ([]map[string]interface{}, error)
+
+	// GetIncomeHistory 获取[startTime, endTime]期间的收益流水（手续费、返佣、资金费、已实现盈亏）
+	GetIncomeHistory(startTime, endTime time.Time) ([]IncomeRecord, error)
+
 	// FormatQuantity 格式化数量到正确的精度
 	FormatQuantity(symbol string, quantity float64) (string, error)
 }
+
+// SpotTrader 现货交易接口（资金费率套利的现货腿）
+// 只有支持现货的交易平台实现（币安和模拟交易）
+type SpotTrader interface {
+	// GetSpotBalance 获取现货资产的可用余额（如 BTC、USDT）
+	GetSpotBalance(asset string) (float64, error)
+
+	// GetSpotPrice 获取现货最新成交价
+	GetSpotPrice(symbol string) (float64, error)
+
+	// SpotBuy 市价买入现货，返回avgPrice、executedQty（已扣除以该币种支付的手续费）和fee（USDT计）
+	SpotBuy(symbol string, quantity float64) (map[string]interface{}, error)
+
+	// SpotSell 市价卖出现货，返回avgPrice、executedQty和fee（USDT计）
+	SpotSell(symbol string, quantity float64) (map[string]interface{}, error)
+}
@@ -0,0 +1,126 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"nofx/decision"
+	"nofx/market"
+)
+
+// invalidationCheckInterval 检查失效条件的间隔（只在条件所用周期有新K线收盘后才获取K线计算）
+const invalidationCheckInterval = time.Minute
+
+// invalidationSettle K线收盘后等待交易所生成最终K线的时间
+const invalidationSettle = 5 * time.Second
+
+// checkInvalidations 失效条件监控：对仍有未成交开仓单的交易计划（pending的限价开仓单、scaling的网格层），
+// 在条件周期的每根K线收盘后检查一次，任一条件满足时取消未成交的开仓单
+func (at *AutoTrader) checkInvalidations() {
+	for _, plan := range at.plans.openPlans() {
+		if plan.State != PlanPending && plan.State != PlanScaling {
+			continue
+		}
+		for _, inv := range plan.Signal.Invalidations {
+			triggered, detail, err := at.evaluateInvalidation(plan, inv)
+			if err != nil {
+				log.Printf("⚠️  [%s] %s %s 检查失效条件（%s）失败: %v", at.name, plan.Symbol, plan.Side, inv, err)
+				continue
+			}
+			if triggered {
+				at.cancelPlanEntries(plan, "信号失效: "+detail)
+				break
+			}
+		}
+	}
+}
+
+// evaluateInvalidation 条件周期有新收盘的K线时计算失效条件（计划创建前收盘的K线不计，信号发出时已考虑）
+func (at *AutoTrader) evaluateInvalidation(plan *TradePlan, inv decision.Invalidation) (bool, string, error) {
+	duration, err := market.IntervalDuration(inv.Interval)
+	if err != nil {
+		return false, "", err
+	}
+	lastClose := market.Clock.LastClose(duration)
+	key := inv.String()
+	if !lastClose.After(plan.CreatedAt) || !lastClose.After(plan.checked[key]) || !market.Clock.CandleCompleted(duration, invalidationSettle) {
+		return false, "", nil
+	}
+
+	klines, err := market.GetProvider().GetKlines(plan.Symbol, inv.Interval, inv.MA+2)
+	if err != nil {
+		return false, "", err
+	}
+	now := market.Clock.Now().UnixMilli()
+	for len(klines) > 0 && klines[len(klines)-1].CloseTime >= now {
+		klines = klines[:len(klines)-1] // 未走完的K线
+	}
+	if len(klines) == 0 || len(klines) < inv.MA {
+		return false, "", fmt.Errorf("已完成K线不足（%d根）", len(klines))
+	}
+
+	reference := inv.Price
+	if inv.MA > 0 {
+		sum := 0.0
+		for _, k := range klines[len(klines)-inv.MA:] {
+			sum += k.Close
+		}
+		reference = sum / float64(inv.MA)
+	}
+	if plan.checked == nil {
+		plan.checked = make(map[string]time.Time)
+	}
+	plan.checked[key] = lastClose
+
+	price := klines[len(klines)-1].Close
+	if math.IsNaN(reference) || !inv.Triggered(price, reference) {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("%s（收盘 %.4f，参考值 %.4f）", inv, price, reference), nil
+}
+
+// cancelPlanEntries 取消计划未成交的开仓单：pending的计划在没有成交时失效，已部分成交则按成交数量转为active；
+// scaling的计划取消剩余网格层后转为active，已有持仓按原止损/止盈继续管理
+func (at *AutoTrader) cancelPlanEntries(plan *TradePlan, reason string) {
+	key := plan.Symbol + "_" + plan.Side
+	log.Printf("🚫 [%s] %s %s %s，取消未成交的开仓单", at.name, plan.Symbol, plan.Side, reason)
+
+	// 取消该币种全部挂单后，为仍在跟踪的持仓重挂止损/止盈和其他网格
+	delete(at.grids, key)
+	if err := at.trader.CancelAllOrders(plan.Symbol); err != nil {
+		log.Printf("  ⚠ 取消开仓挂单失败: %v", err)
+		return
+	}
+
+	filled := 0.0
+	if positions, err := at.trader.GetPositions(); err == nil {
+		for _, pos := range positions {
+			if pos["symbol"] == plan.Symbol && pos["side"] == plan.Side {
+				amount, _ := pos["positionAmt"].(float64)
+				filled = math.Abs(amount)
+			}
+		}
+	} else {
+		log.Printf("  ⚠ 获取持仓失败（按计划记录的成交数量处理）: %v", err)
+		filled = plan.Filled
+	}
+
+	if filled == 0 {
+		at.finishPlan(plan, PlanInvalidated, reason)
+	} else {
+		if _, ok := at.trackedPositions[key]; !ok {
+			at.trackPosition(plan.Symbol, plan.Side, filled, plan.StopLoss, plan.TakeProfit, plan.StrategyID)
+		}
+		at.trackedPositions[key].Quantity = filled
+		plan.Filled = filled
+		if err := plan.transition(PlanActive, ""); err != nil {
+			log.Printf("⚠️  [%s] %v", at.name, err)
+		} else {
+			at.publishPlan(plan, reason+"，保留已成交部分")
+		}
+	}
+	at.restoreStops(plan.Symbol)
+	at.plans.save()
+}
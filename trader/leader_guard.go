@@ -0,0 +1,118 @@
+package trader
+
+import (
+	"fmt"
+
+	"nofx/leader"
+)
+
+// leaderGuard 主备部署时的下单闸门：包装交易器，所有下单/撤单/改杠杆操作前检查本实例是否为主节点
+// 对账、网格、加仓、超时平仓、配对、资金费率套利等所有下单路径都经过交易器，统一在这一层拦截；查询操作不受影响
+type leaderGuard struct {
+	Trader
+}
+
+// allowed 本实例是否允许下单
+func allowed() error {
+	if ok, reason := leader.Allowed(); !ok {
+		return fmt.Errorf("拒绝下单: %s", reason)
+	}
+	return nil
+}
+
+// unwrapTrader 去掉下单闸门，返回实际的交易器（用于按具体类型判断，如回放只支持模拟交易）
+func unwrapTrader(t Trader) Trader {
+	if guard, ok := t.(*leaderGuard); ok {
+		return guard.Trader
+	}
+	return t
+}
+
+func (g *leaderGuard) OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	if err := allowed(); err != nil {
+		return nil, err
+	}
+	return g.Trader.OpenLong(symbol, quantity, leverage, clientOrderID)
+}
+
+func (g *leaderGuard) OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	if err := allowed(); err != nil {
+		return nil, err
+	}
+	return g.Trader.OpenShort(symbol, quantity, leverage, clientOrderID)
+}
+
+func (g *leaderGuard) PlaceLimitOrder(order LimitOrder) (map[string]interface{}, error) {
+	if err := allowed(); err != nil {
+		return nil, err
+	}
+	return g.Trader.PlaceLimitOrder(order)
+}
+
+func (g *leaderGuard) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	if err := allowed(); err != nil {
+		return nil, err
+	}
+	return g.Trader.CloseLong(symbol, quantity)
+}
+
+func (g *leaderGuard) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	if err := allowed(); err != nil {
+		return nil, err
+	}
+	return g.Trader.CloseShort(symbol, quantity)
+}
+
+func (g *leaderGuard) SetLeverage(symbol string, leverage int) error {
+	if err := allowed(); err != nil {
+		return err
+	}
+	return g.Trader.SetLeverage(symbol, leverage)
+}
+
+func (g *leaderGuard) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := allowed(); err != nil {
+		return err
+	}
+	return g.Trader.SetStopLoss(symbol, positionSide, quantity, stopPrice)
+}
+
+func (g *leaderGuard) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := allowed(); err != nil {
+		return err
+	}
+	return g.Trader.SetTakeProfit(symbol, positionSide, quantity, takeProfitPrice)
+}
+
+func (g *leaderGuard) CancelAllOrders(symbol string) error {
+	if err := allowed(); err != nil {
+		return err
+	}
+	return g.Trader.CancelAllOrders(symbol)
+}
+
+func (g *leaderGuard) CancelOrder(symbol string, orderID int64) error {
+	if err := allowed(); err != nil {
+		return err
+	}
+	return g.Trader.CancelOrder(symbol, orderID)
+}
+
+// leaderSpotGuard 现货交易（资金费率套利的现货腿）的下单闸门
+type leaderSpotGuard struct {
+	SpotTrader
+}
+
+func (g *leaderSpotGuard) SpotBuy(symbol string, quantity float64) (map[string]interface{}, error) {
+	if err := allowed(); err != nil {
+		return nil, err
+	}
+	return g.SpotTrader.SpotBuy(symbol, quantity)
+}
+
+func (g *leaderSpotGuard) SpotSell(symbol string, quantity float64) (map[string]interface{}, error) {
+	if err := allowed(); err != nil {
+		return nil, err
+	}
+	return g.SpotTrader.SpotSell(symbol, quantity)
+}
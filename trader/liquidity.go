@@ -0,0 +1,57 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+
+	"nofx/decision"
+)
+
+// liquidityDepthLevels 流动性检查获取的盘口档数
+const liquidityDepthLevels = 100
+
+// LiquidityGuardConfig 开仓前的盘口流动性检查：开仓名义价值不超过开仓方向可见深度的一定比例
+type LiquidityGuardConfig struct {
+	MaxDepthPct float64 // 开仓名义价值占中间价WithinBps以内可见深度的上限（%，0表示不检查）
+	WithinBps   float64 // 统计深度的价格范围（中间价上下bps）
+	Reject      bool    // 超过上限时拒绝开仓（否则缩小到上限）
+	MinScalePct float64 // 缩小后不足原仓位该百分比时拒绝开仓
+}
+
+// liquidityLimit 开仓方向（开多为卖盘、开空为买盘）中间价WithinBps以内的可见深度及允许的最大开仓名义价值（USDT）
+// 盘口来自本trader的交易所（币本位、Hyperliquid、Aster各自的盘口，而不是币安USDT永续）
+func (at *AutoTrader) liquidityLimit(symbol, side string) (limit, depth float64, err error) {
+	book, err := at.trader.GetOrderBook(symbol, liquidityDepthLevels)
+	if err != nil {
+		return 0, 0, fmt.Errorf("获取%s盘口深度失败: %w", symbol, err)
+	}
+	if book.Mid() <= 0 {
+		return 0, 0, fmt.Errorf("%s盘口为空", symbol)
+	}
+	config := at.config.LiquidityGuard
+	depth = book.DepthWithin(side == "long", config.WithinBps)
+	return depth * config.MaxDepthPct / 100, depth, nil
+}
+
+// applyLiquidityGuard 开仓前按盘口深度检查仓位：超过上限时按配置缩小仓位或拒绝开仓（获取不到盘口时拒绝开仓）
+func (at *AutoTrader) applyLiquidityGuard(d *decision.Decision, side string) error {
+	config := at.config.LiquidityGuard
+	if config.MaxDepthPct <= 0 {
+		return nil
+	}
+	limit, depth, err := at.liquidityLimit(d.Symbol, side)
+	if err != nil {
+		return fmt.Errorf("❌ %s 无法检查盘口流动性，拒绝开仓: %w", d.Symbol, err)
+	}
+	if d.PositionSizeUSD <= limit {
+		return nil
+	}
+
+	detail := fmt.Sprintf("仓位 %.2f USDT 超过%.0f bps内可见深度 %.2f USDT 的%g%%", d.PositionSizeUSD, config.WithinBps, depth, config.MaxDepthPct)
+	if config.Reject || limit < d.PositionSizeUSD*config.MinScalePct/100 {
+		return fmt.Errorf("❌ %s 流动性不足，拒绝开仓: %s", d.Symbol, detail)
+	}
+	log.Printf("  💧 %s 流动性不足（%s），仓位 %.2f → %.2f USDT", d.Symbol, detail, d.PositionSizeUSD, limit)
+	d.PositionSizeUSD = limit
+	return nil
+}
@@ -0,0 +1,124 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"nofx/logger"
+	"nofx/market"
+	"nofx/monitor"
+)
+
+// maxHoldingCheckInterval 检查最长持仓时间的间隔（独立于交易周期，风控暂停期间也照常平掉超时持仓）
+const maxHoldingCheckInterval = time.Minute
+
+// MaxHoldingConfig 最长持仓时间（Candles与Hours二选一）：持仓超过该时间仍未触发止损/止盈时按市价平仓
+type MaxHoldingConfig struct {
+	Candles  int     // 最多持有的K线根数（时长为Candles个Interval）
+	Interval string  // K线周期（如"4h"）
+	Hours    float64 // 最多持有的小时数
+}
+
+// duration 最长持仓时长
+func (c MaxHoldingConfig) duration() (time.Duration, error) {
+	if (c.Candles > 0) == (c.Hours > 0) {
+		return 0, fmt.Errorf("max_holding必须且只能配置candles或hours之一")
+	}
+	if c.Hours > 0 {
+		return time.Duration(c.Hours * float64(time.Hour)), nil
+	}
+	interval, err := market.IntervalDuration(c.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("max_holding.interval无效: %w", err)
+	}
+	return time.Duration(c.Candles) * interval, nil
+}
+
+// String 最长持仓时间的描述（如"12根4h K线"、"48小时"）
+func (c MaxHoldingConfig) String() string {
+	if c.Candles > 0 {
+		return fmt.Sprintf("%d根%s K线", c.Candles, c.Interval)
+	}
+	return fmt.Sprintf("%g小时", c.Hours)
+}
+
+// validateMaxHolding 检查各策略的最长持仓时间配置
+func validateMaxHolding(configs map[string]MaxHoldingConfig) error {
+	for id, c := range configs {
+		if _, err := c.duration(); err != nil {
+			return fmt.Errorf("策略 %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// checkMaxHolding 定时检查超时持仓（不受风控暂停、全局熔断影响，交易所不可用时跳过），有平仓时保存决策记录
+func (at *AutoTrader) checkMaxHolding() {
+	if len(at.config.MaxHolding) == 0 {
+		return
+	}
+	if available, _ := monitor.ExchangeAvailable(at.exchange); !available {
+		return
+	}
+	record := &logger.DecisionRecord{ExecutionLog: []string{}, Success: true}
+	at.enforceMaxHolding(record)
+	if len(record.Decisions) > 0 {
+		at.decisionLogger.LogDecision(record)
+	}
+}
+
+// enforceMaxHolding 持仓管理：按开仓策略的最长持仓时间平掉超时的持仓（止损/止盈仍未触发）
+// 开仓时间来自本地跟踪的持仓，重启后由交易计划恢复；对账接管的未知来源持仓、配对和资金费率套利的持仓不受影响
+func (at *AutoTrader) enforceMaxHolding(record *logger.DecisionRecord) {
+	if len(at.config.MaxHolding) == 0 {
+		return
+	}
+
+	var expired []*trackedPosition
+	for _, tracked := range at.trackedPositions {
+		config, ok := at.config.MaxHolding[tracked.StrategyID]
+		if !ok || at.isPairLeg(tracked.Symbol, tracked.Side) || at.isHarvestLeg(tracked.Symbol, tracked.Side) {
+			continue
+		}
+		limit, err := config.duration()
+		if err != nil || time.Since(tracked.OpenedAt) < limit {
+			continue
+		}
+		expired = append(expired, tracked)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].OpenedAt.Before(expired[j].OpenedAt) })
+
+	for _, tracked := range expired {
+		config := at.config.MaxHolding[tracked.StrategyID]
+		held := time.Since(tracked.OpenedAt).Round(time.Minute)
+		reason := fmt.Sprintf("持仓%v超过最长持仓时间（%s）", held, config)
+		log.Printf("⏳ [%s] %s %s %s，按市价平仓", at.name, tracked.Symbol, tracked.Side, reason)
+
+		action := logger.DecisionAction{
+			Action:     "close_" + tracked.Side,
+			Symbol:     tracked.Symbol,
+			Quantity:   tracked.Quantity,
+			StrategyID: tracked.StrategyID,
+			OrderType:  "market",
+			Timestamp:  time.Now(),
+		}
+		if data, err := market.Get(tracked.Symbol); err == nil {
+			action.Price = data.CurrentPrice
+			action.DecisionPrice = data.CurrentPrice
+		}
+		order, err := at.closeSide(tracked.Symbol, tracked.Side, tracked.Quantity, 1, reason)
+		if err != nil {
+			log.Printf("  ⚠ 超时平仓失败: %v", err)
+			action.Error = err.Error()
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 超时平仓失败: %v", tracked.Symbol, tracked.Side, err))
+		} else {
+			action.Success = true
+			action.OrderID = orderID(order)
+			at.recordFill(&action, tracked.Symbol, tracked.Side, order, false)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏳ %s %s 超时平仓: %s", tracked.Symbol, tracked.Side, reason))
+		}
+		record.Decisions = append(record.Decisions, action)
+	}
+}
@@ -0,0 +1,90 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"nofx/market"
+	"nofx/risk"
+)
+
+// openRiskInterval 两个交易周期之间刷新开放风险的间隔（按实时标记价格）
+const openRiskInterval = 30 * time.Second
+
+// openRiskMonitor 组合开放风险：只在交易主循环中计算，API通过快照读取
+type openRiskMonitor struct {
+	mu       sync.Mutex
+	snapshot risk.OpenRisk
+}
+
+// refreshOpenRisk 按当前价格重新计算跟踪中持仓的开放风险（equity为0时沿用上次的净值）
+// 价格优先使用实时标记价格，其次向交易所查询，再次为最近周期的行情价格和交易计划的成交均价，
+// 都没有时该持仓的风险记为未知（禁止新开仓）；配对和资金费率套利的持仓有对冲腿，不计入
+func (at *AutoTrader) refreshOpenRisk(equity float64) risk.OpenRisk {
+	at.openRisk.mu.Lock()
+	previous := at.openRisk.snapshot
+	at.openRisk.mu.Unlock()
+	if equity <= 0 {
+		equity = previous.Equity
+	}
+
+	positions := make([]risk.PositionRisk, 0, len(at.trackedPositions))
+	for _, tracked := range at.trackedPositions {
+		if at.isPairLeg(tracked.Symbol, tracked.Side) || at.isHarvestLeg(tracked.Symbol, tracked.Side) {
+			continue
+		}
+		price, ok := market.Hub.MarkPrice(tracked.Symbol, livePriceMaxAge)
+		if !ok {
+			var err error
+			if price, err = at.trader.GetMarketPrice(tracked.Symbol); err != nil {
+				log.Printf("⚠️  [%s] 获取 %s 价格失败（计算开放风险）: %v", at.name, tracked.Symbol, err)
+				price = 0
+			}
+		}
+		if price <= 0 {
+			if data, exists := at.lastMarketData[tracked.Symbol]; exists {
+				price = data.CurrentPrice
+			} else if plan := at.plans.open(tracked.Symbol, tracked.Side); plan != nil {
+				price = plan.EntryPrice
+			}
+		}
+		positions = append(positions, risk.NewPositionRisk(tracked.Symbol, tracked.Side, tracked.Quantity, price, tracked.StopLoss))
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].RiskUSD > positions[j].RiskUSD })
+
+	snapshot := risk.NewOpenRisk(positions, equity, at.config.MaxOpenRiskPct, market.Clock.Now())
+	at.openRisk.mu.Lock()
+	at.openRisk.snapshot = snapshot
+	at.openRisk.mu.Unlock()
+	return snapshot
+}
+
+// checkOpenRisk 开仓前检查：按当前价格重新计算开放风险，加上新开仓增加的风险后超过预算时拒绝开仓
+func (at *AutoTrader) checkOpenRisk(symbol string, added float64) error {
+	if at.config.MaxOpenRiskPct <= 0 {
+		return nil
+	}
+	if err := at.refreshOpenRisk(0).Check(added); err != nil {
+		return fmt.Errorf("❌ %s 拒绝开仓: %w", symbol, err)
+	}
+	return nil
+}
+
+// entryRisk 新开仓的风险：名义价值×止损距离/价格（没有止损时为全部名义价值）
+func entryRisk(notional, price, stopLoss float64) float64 {
+	if stopLoss <= 0 || price <= 0 {
+		return notional
+	}
+	return notional * math.Abs(price-stopLoss) / price
+}
+
+// OpenRisk 组合开放风险的最近快照（每个交易周期和每openRiskInterval刷新）
+func (at *AutoTrader) OpenRisk() risk.OpenRisk {
+	at.openRisk.mu.Lock()
+	defer at.openRisk.mu.Unlock()
+	return at.openRisk.snapshot
+}
@@ -0,0 +1,341 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+// PairConfig 配对交易配置（价格比 Base/Quote 的均值回归）
+type PairConfig struct {
+	Base     string
+	Quote    string
+	Interval string  // 计算价差的K线周期
+	Lookback int     // 计算均值/标准差的K线数
+	ExitZ    float64 // |z|回归到该值以内时平仓（止盈）
+	StopZ    float64 // 继续偏离超过该值时平仓（止损）
+}
+
+// pairPosition 已开仓的配对
+type pairPosition struct {
+	Symbol     string // BASE/QUOTE
+	Base       string
+	Quote      string
+	Direction  string // "long"（多base空quote）或 "short"（空base多quote）
+	EntryZ     float64
+	StrategyID string
+	OpenedAt   time.Time
+}
+
+// legs 两条腿的币种和方向
+func (p *pairPosition) legs() [2][2]string {
+	if p.Direction == "long" {
+		return [2][2]string{{p.Base, "long"}, {p.Quote, "short"}}
+	}
+	return [2][2]string{{p.Base, "short"}, {p.Quote, "long"}}
+}
+
+// pairConfig 查找配对配置
+func (at *AutoTrader) pairConfig(symbol string) (PairConfig, bool) {
+	for _, pair := range at.config.Pairs {
+		if market.PairSymbol(pair.Base, pair.Quote) == symbol {
+			return pair, true
+		}
+	}
+	return PairConfig{}, false
+}
+
+// isPairLeg 持仓是否属于某个已开仓的配对
+func (at *AutoTrader) isPairLeg(symbol, side string) bool {
+	for _, pair := range at.pairs {
+		for _, leg := range pair.legs() {
+			if leg[0] == symbol && leg[1] == side {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pairContext 获取各配对的价差数据（附当前持仓），供AI决策和自动平仓使用
+func (at *AutoTrader) pairContext() []decision.PairInfo {
+	var pairs []decision.PairInfo
+	for _, config := range at.config.Pairs {
+		spread, err := market.GetSpread(config.Base, config.Quote, config.Interval, config.Lookback)
+		if err != nil {
+			log.Printf("⚠️  [%s] 获取 %s/%s 价差失败: %v", at.name, config.Base, config.Quote, err)
+			continue
+		}
+		info := decision.PairInfo{Spread: spread}
+		if position, ok := at.pairs[spread.Symbol()]; ok {
+			info.Position = position.Direction
+			info.EntryZ = position.EntryZ
+		}
+		pairs = append(pairs, info)
+	}
+	return pairs
+}
+
+// isPairAction 是否为配对交易动作（两条腿同时执行）
+func isPairAction(action string) bool {
+	return decision.IsPairAction(action)
+}
+
+// executePair 执行配对交易决策，返回各条腿的执行记录
+func (at *AutoTrader) executePair(d *decision.Decision, ctx *decision.Context) ([]logger.DecisionAction, error) {
+	switch d.Action {
+	case decision.ActionOpenPairLong:
+		return at.openPair(d, "long", ctx)
+	case decision.ActionOpenPairShort:
+		return at.openPair(d, "short", ctx)
+	default:
+		return at.closePair(d.Symbol)
+	}
+}
+
+// pairLegAction 配对中一条腿的执行记录（按普通开平仓动作记录，便于统计盈亏）
+func pairLegAction(action, symbol, pair, strategyID string, leverage int) logger.DecisionAction {
+	return logger.DecisionAction{
+		Action:     action,
+		Symbol:     symbol,
+		Leverage:   leverage,
+		StrategyID: strategyID,
+		Tags:       []string{"pair:" + pair},
+		OrderType:  "market",
+		Timestamp:  time.Now(),
+	}
+}
+
+// openPair 同时开两条腿（每条腿名义价值为position_size_usd）；一条腿失败时平掉另一条，避免留下单边敞口
+func (at *AutoTrader) openPair(d *decision.Decision, direction string, ctx *decision.Context) ([]logger.DecisionAction, error) {
+	if _, ok := at.pairConfig(d.Symbol); !ok {
+		return nil, fmt.Errorf("%s 不是已配置的配对", d.Symbol)
+	}
+	if _, open := at.pairs[d.Symbol]; open {
+		return nil, fmt.Errorf("❌ %s 已有配对持仓，如需换向请先给出 close_pair 决策", d.Symbol)
+	}
+	base, quote, _ := market.SplitPair(d.Symbol)
+	position := &pairPosition{Symbol: d.Symbol, Base: base, Quote: quote, Direction: direction, StrategyID: d.StrategyID}
+	for _, pair := range ctx.Pairs {
+		if pair.Spread.Symbol() == d.Symbol {
+			position.EntryZ = pair.Spread.ZScore
+		}
+	}
+	log.Printf("  📐 开配对: %s %s价差（z-score %+.2f）", d.Symbol, direction, position.EntryZ)
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	for _, leg := range position.legs() {
+		for _, pos := range positions {
+			if pos["symbol"] == leg[0] && pos["side"] == leg[1] {
+				return nil, fmt.Errorf("❌ %s 已有%s仓，拒绝开配对", leg[0], leg[1])
+			}
+		}
+	}
+
+	// 资金预算按两条腿合计计算
+	combined := *d
+	combined.PositionSizeUSD = d.PositionSizeUSD * 2
+	if err := at.applyAllocation(&combined); err != nil {
+		return nil, err
+	}
+	size := combined.PositionSizeUSD / 2
+
+	type legOrder struct {
+		symbol, side string
+		price        float64
+		record       logger.DecisionAction
+		order        map[string]interface{}
+		err          error
+	}
+	var orders [2]*legOrder
+	for i, leg := range position.legs() {
+		if err := at.checkSymbolTier(leg[0], d.Leverage, size); err != nil {
+			return nil, err
+		}
+		if err := at.checkSpread(leg[0]); err != nil {
+			return nil, err
+		}
+		marketData, err := market.Get(leg[0])
+		if err != nil {
+			return nil, err
+		}
+		price := livePrice(leg[0], leg[1], marketData.CurrentPrice)
+		record := pairLegAction("open_"+leg[1], leg[0], d.Symbol, d.StrategyID, d.Leverage)
+		record.Quantity = size / price
+		record.Price = price
+		record.DecisionPrice = marketData.CurrentPrice
+		orders[i] = &legOrder{symbol: leg[0], side: leg[1], price: price, record: record}
+	}
+
+	// 两条腿同时下单
+	var wg sync.WaitGroup
+	for _, leg := range orders {
+		wg.Add(1)
+		go func(leg *legOrder) {
+			defer wg.Done()
+			clientOrderID := at.clientOrderID(leg.symbol, "open_"+leg.side, d.StrategyID)
+			if leg.side == "long" {
+				leg.order, leg.err = at.trader.OpenLong(leg.symbol, leg.record.Quantity, d.Leverage, clientOrderID)
+			} else {
+				leg.order, leg.err = at.trader.OpenShort(leg.symbol, leg.record.Quantity, d.Leverage, clientOrderID)
+			}
+		}(leg)
+	}
+	wg.Wait()
+
+	var actions []logger.DecisionAction
+	var failed []string
+	for _, leg := range orders {
+		if leg.err != nil {
+			leg.record.Error = leg.err.Error()
+			failed = append(failed, fmt.Sprintf("%s %s: %v", leg.symbol, leg.side, leg.err))
+		} else {
+			leg.record.Success = true
+			at.recordFill(&leg.record, leg.symbol, leg.side, leg.order, true)
+		}
+		actions = append(actions, leg.record)
+	}
+
+	if len(failed) > 0 {
+		// 单腿成交：立即平掉已成交的一条腿
+		for _, leg := range orders {
+			if leg.err != nil {
+				continue
+			}
+			log.Printf("  ⚠ %s 另一条腿开仓失败，平掉已成交的 %s %s", d.Symbol, leg.symbol, leg.side)
+			actions = append(actions, at.closePairLeg(leg.symbol, leg.side, d.Symbol, d.StrategyID))
+		}
+		return actions, fmt.Errorf("配对开仓失败: %s", strings.Join(failed, "; "))
+	}
+
+	now := market.Clock.Now()
+	for _, leg := range orders {
+		at.positionFirstSeenTime[leg.symbol+"_"+leg.side] = now.UnixMilli()
+		at.trackPosition(leg.symbol, leg.side, leg.record.Quantity, 0, 0, d.StrategyID)
+	}
+	position.OpenedAt = now
+	at.pairs[d.Symbol] = position
+	log.Printf("  ✓ 配对开仓成功: %s 每条腿 %.2f USDT", d.Symbol, size)
+	return actions, nil
+}
+
+// closePairLeg 平掉配对的一条腿
+func (at *AutoTrader) closePairLeg(symbol, side, pair, strategyID string) logger.DecisionAction {
+	record := pairLegAction("close_"+side, symbol, pair, strategyID, 0)
+	if marketData, err := market.Get(symbol); err == nil {
+		record.Price = marketData.CurrentPrice
+		record.DecisionPrice = marketData.CurrentPrice
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	for _, pos := range positions {
+		if pos["symbol"] != symbol || pos["side"] != side {
+			continue
+		}
+		amount, _ := pos["positionAmt"].(float64)
+		record.Quantity = math.Abs(amount)
+		order, err := at.closeSide(symbol, side, record.Quantity, 1, "配对平仓")
+		if err != nil {
+			record.Error = err.Error()
+			return record
+		}
+		record.Success = true
+		at.recordFill(&record, symbol, side, order, false)
+		return record
+	}
+	record.Error = fmt.Sprintf("没有找到 %s 的%s仓", symbol, side)
+	return record
+}
+
+// closePair 同时平掉配对的两条腿
+func (at *AutoTrader) closePair(symbol string) ([]logger.DecisionAction, error) {
+	position, ok := at.pairs[symbol]
+	if !ok {
+		return nil, fmt.Errorf("%s 没有配对持仓", symbol)
+	}
+	log.Printf("  📐 平配对: %s %s价差", symbol, position.Direction)
+
+	var actions []logger.DecisionAction
+	var failed []string
+	for _, leg := range position.legs() {
+		action := at.closePairLeg(leg[0], leg[1], symbol, position.StrategyID)
+		if !action.Success {
+			failed = append(failed, fmt.Sprintf("%s %s: %s", leg[0], leg[1], action.Error))
+		}
+		actions = append(actions, action)
+	}
+	delete(at.pairs, symbol)
+	if len(failed) > 0 {
+		return actions, fmt.Errorf("配对平仓失败: %s", strings.Join(failed, "; "))
+	}
+	return actions, nil
+}
+
+// managePairs 管理配对持仓：z-score回归到exit_z以内（止盈）或偏离超过stop_z（止损）时平仓；
+// 一条腿已被平掉（强平或手动）时平掉另一条腿
+func (at *AutoTrader) managePairs(ctx *decision.Context, record *logger.DecisionRecord) {
+	if len(at.pairs) == 0 {
+		return
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️  [%s] 配对检查失败: %v", at.name, err)
+		return
+	}
+	open := make(map[string]bool)
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		open[symbol+"_"+side] = true
+	}
+
+	for i := range ctx.Pairs {
+		pair := &ctx.Pairs[i]
+		symbol := pair.Spread.Symbol()
+		position, ok := at.pairs[symbol]
+		if !ok {
+			continue
+		}
+		config, _ := at.pairConfig(symbol)
+		z := pair.Spread.ZScore
+
+		reason := ""
+		legs := position.legs()
+		switch {
+		case !open[legs[0][0]+"_"+legs[0][1]] || !open[legs[1][0]+"_"+legs[1][1]]:
+			reason = "一条腿已平仓"
+		case position.Direction == "long" && z >= -config.ExitZ, position.Direction == "short" && z <= config.ExitZ:
+			reason = fmt.Sprintf("z-score回归至 %+.2f（止盈）", z)
+		case position.Direction == "long" && z <= -config.StopZ, position.Direction == "short" && z >= config.StopZ:
+			reason = fmt.Sprintf("z-score偏离至 %+.2f（止损）", z)
+		}
+		if reason == "" {
+			continue
+		}
+
+		log.Printf("📐 [%s] %s %s，平掉配对", at.name, symbol, reason)
+		var actions []logger.DecisionAction
+		for _, leg := range legs {
+			if open[leg[0]+"_"+leg[1]] {
+				actions = append(actions, at.closePairLeg(leg[0], leg[1], symbol, position.StrategyID))
+			}
+		}
+		delete(at.pairs, symbol)
+		pair.Position = ""
+		record.Decisions = append(record.Decisions, actions...)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("📐 %s 自动平配对: %s", symbol, reason))
+	}
+}
@@ -0,0 +1,793 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/market"
+	"nofx/symbols"
+)
+
+// paperFeeRate 模拟成交手续费率（吃单）
+const paperFeeRate = shadowFeeRate
+
+// PaperConfig 模拟成交的执行模型（零值为理想成交：市价单立即按最新价全部成交）
+type PaperConfig struct {
+	Latency        time.Duration // 市价单从下单到成交的延迟（按延迟后的最新价成交；回放按K线撮合，不等待）
+	SlippageBps    float64       // 市价单和止损/止盈触发后的不利滑点（基点）
+	PartialFillPct float64       // 市价单每次成交的数量占比（%，0或100表示一次全部成交），剩余部分每隔一个延迟继续成交，滑点逐次递增
+}
+
+// PaperTrader 模拟交易器：按当前行情数据源的价格撮合，不向任何交易所下单
+// 止损/止盈按K线高低价触发（OnBar），未驱动K线时按最新价检查
+type PaperTrader struct {
+	mu        sync.Mutex
+	config    PaperConfig
+	balance   float64                           // 钱包余额（已计入已实现盈亏和手续费）
+	positions map[string]*paperPosition         // symbol_side -> 持仓
+	pending   []*paperOrder                     // 未成交的限价单
+	orders    map[string]map[string]interface{} // clientOrderID -> 订单结果（幂等查询）
+	spot      map[string]*paperSpot             // symbol -> 现货持仓（资金费率套利的现货腿）
+	nextID    int64
+
+	fees   float64
+	trades int
+	wins   int
+	fills  []PaperFill // 成交记录（按时间顺序）
+}
+
+// paperSpot 模拟现货持仓（买入成本从钱包余额中占用，与1倍杠杆多单相同）
+type paperSpot struct {
+	Quantity  float64
+	Cost      float64 // 持仓成本（USDT）
+	MarkPrice float64
+}
+
+// paperOrder 模拟限价挂单
+type paperOrder struct {
+	id    int64
+	order LimitOrder
+}
+
+// PaperStats 模拟账户统计
+type PaperStats struct {
+	Balance float64 `json:"balance"`
+	Fees    float64 `json:"fees"`
+	Trades  int     `json:"trades"`
+	Wins    int     `json:"wins"`
+}
+
+// PaperFill 模拟成交记录
+type PaperFill struct {
+	Time     time.Time `json:"time"`
+	Symbol   string    `json:"symbol"`
+	Side     string    `json:"side"`   // 持仓方向: "long" 或 "short"
+	Action   string    `json:"action"` // "open" 或 "close"
+	Price    float64   `json:"price"`
+	Quantity float64   `json:"quantity"`
+	Fee      float64   `json:"fee"`
+	PnL      float64   `json:"pnl"` // 平仓已实现盈亏（已扣除平仓手续费）
+}
+
+// NewPaperTrader 创建模拟交易器
+func NewPaperTrader(initialBalance float64, config PaperConfig) *PaperTrader {
+	return &PaperTrader{
+		config:    config,
+		balance:   initialBalance,
+		positions: make(map[string]*paperPosition),
+		orders:    make(map[string]map[string]interface{}),
+		spot:      make(map[string]*paperSpot),
+	}
+}
+
+// GetBalance 获取模拟账户余额
+func (t *PaperTrader) GetBalance() (map[string]interface{}, error) {
+	t.markToMarket()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	unrealized, margin := 0.0, 0.0
+	for _, pos := range t.positions {
+		unrealized += pos.pnl()
+		margin += pos.margin()
+	}
+	for _, p := range t.pending {
+		if !p.order.ReduceOnly {
+			margin += p.order.Price * p.order.Quantity / float64(maxInt(p.order.Leverage, 1))
+		}
+	}
+	for _, s := range t.spot {
+		unrealized += s.Quantity*s.MarkPrice - s.Cost
+		margin += s.Cost
+	}
+
+	return map[string]interface{}{
+		"totalWalletBalance":    t.balance,
+		"availableBalance":      t.balance + unrealized - margin,
+		"totalUnrealizedProfit": unrealized,
+	}, nil
+}
+
+// GetPositions 获取模拟持仓（空仓positionAmt为负数，与币安一致）
+func (t *PaperTrader) GetPositions() ([]map[string]interface{}, error) {
+	t.markToMarket()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var result []map[string]interface{}
+	for _, pos := range t.sortedPositions() {
+		amount := pos.Quantity
+		liquidation := pos.EntryPrice * (1 - 1/float64(pos.Leverage))
+		if pos.Side == "short" {
+			amount = -amount
+			liquidation = pos.EntryPrice * (1 + 1/float64(pos.Leverage))
+		}
+		result = append(result, map[string]interface{}{
+			"symbol":           pos.Symbol,
+			"side":             pos.Side,
+			"positionAmt":      amount,
+			"entryPrice":       pos.EntryPrice,
+			"markPrice":        pos.MarkPrice,
+			"unRealizedProfit": pos.pnl(),
+			"leverage":         float64(pos.Leverage),
+			"liquidationPrice": liquidation,
+		})
+	}
+	return result, nil
+}
+
+// OpenLong 模拟市价开多
+func (t *PaperTrader) OpenLong(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	return t.marketOpen(symbol, "long", quantity, leverage, clientOrderID)
+}
+
+// OpenShort 模拟市价开空
+func (t *PaperTrader) OpenShort(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	return t.marketOpen(symbol, "short", quantity, leverage, clientOrderID)
+}
+
+// marketOpen 按执行模型成交开仓
+func (t *PaperTrader) marketOpen(symbol, side string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	price, filled, err := t.executeMarket(symbol, side == "long", quantity, func(quantity, price float64) (float64, error) {
+		return quantity, t.open(symbol, side, quantity, leverage, price)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	log.Printf("✓ [模拟] 开%s成功: %s 数量: %.4f @ %.4f", sideName(side), symbol, filled, price)
+	return t.marketResult(symbol, clientOrderID, price, filled, quantity), nil
+}
+
+// PlaceLimitOrder 模拟限价单：可立即成交的按最新价成交（post-only时拒绝），否则按有效期挂单或过期
+func (t *PaperTrader) PlaceLimitOrder(order LimitOrder) (map[string]interface{}, error) {
+	tif, err := order.EffectiveTimeInForce()
+	if err != nil {
+		return nil, err
+	}
+	price, err := t.GetMarketPrice(order.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	buy := (order.Side == "long") != order.ReduceOnly
+	marketable := (buy && price <= order.Price) || (!buy && price >= order.Price)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := "NEW"
+	switch {
+	case marketable && tif == TimeInForceGTX:
+		status = "EXPIRED" // post-only单会吃单，交易所拒绝
+	case marketable:
+		if err := t.execute(order, price); err != nil {
+			return nil, err
+		}
+		return t.fill(order.Symbol, order.ClientOrderID, price, order.Quantity), nil
+	case tif == "IOC" || tif == "FOK":
+		status = "EXPIRED"
+	default:
+		t.nextID++
+		t.pending = append(t.pending, &paperOrder{id: t.nextID, order: order})
+	}
+
+	log.Printf("✓ [模拟] 限价单: %s %s %.4f @ %.4f (%s) 状态: %s", order.Symbol, order.Side, order.Quantity, order.Price, tif, status)
+	result := map[string]interface{}{
+		"orderId":     t.nextID,
+		"symbol":      order.Symbol,
+		"status":      status,
+		"timeInForce": tif,
+	}
+	if order.ClientOrderID != "" {
+		t.orders[order.ClientOrderID] = result
+	}
+	return result, nil
+}
+
+// CloseLong 模拟市价平多（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.marketClose(symbol, "long", quantity)
+}
+
+// CloseShort 模拟市价平空（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.marketClose(symbol, "short", quantity)
+}
+
+// marketClose 按执行模型成交平仓
+func (t *PaperTrader) marketClose(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	t.mu.Lock()
+	pos, ok := t.positions[symbol+"_"+side]
+	if !ok {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("%s 没有%s持仓", symbol, sideName(side))
+	}
+	if quantity <= 0 || quantity > pos.Quantity {
+		quantity = pos.Quantity
+	}
+	t.mu.Unlock()
+
+	price, closed, err := t.executeMarket(symbol, side == "short", quantity, func(quantity, price float64) (float64, error) {
+		return t.close(symbol, side, quantity, price)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	log.Printf("✓ [模拟] 平%s成功: %s 数量: %.4f @ %.4f", sideName(side), symbol, closed, price)
+	return t.marketResult(symbol, "", price, closed, quantity), nil
+}
+
+// executeMarket 按执行模型成交市价单：每次等待延迟后按最新价加滑点成交一部分，直到全部成交或无法继续成交
+// apply在持锁状态下成交指定数量并返回实际成交数量；返回成交均价和总成交数量（一笔都未成交时返回错误）
+func (t *PaperTrader) executeMarket(symbol string, buy bool, quantity float64, apply func(quantity, price float64) (float64, error)) (float64, float64, error) {
+	chunk := quantity
+	if pct := t.config.PartialFillPct; pct > 0 && pct < 100 {
+		chunk = quantity * pct / 100
+	}
+
+	value, filled := 0.0, 0.0
+	for step := 1; quantity-filled > quantity*1e-9; step++ {
+		t.waitLatency()
+		price, err := t.GetMarketPrice(symbol)
+		if err == nil {
+			// 剩余部分吃更深的盘口，滑点逐次递增
+			price = slippedPrice(price, buy, t.config.SlippageBps*float64(step))
+
+			requested := math.Min(chunk, quantity-filled)
+			t.mu.Lock()
+			var executed float64
+			executed, err = apply(requested, price)
+			t.mu.Unlock()
+			if err == nil {
+				value += executed * price
+				filled += executed
+				if executed < requested*(1-1e-9) {
+					break // 持仓已不足（如期间被止损平仓）
+				}
+				continue
+			}
+		}
+		if filled == 0 {
+			return 0, 0, err
+		}
+		log.Printf("⚠️  [模拟] %s 市价单部分成交 %.4f/%.4f，剩余部分取消: %v", symbol, filled, quantity, err)
+		break
+	}
+	return value / filled, filled, nil
+}
+
+// waitLatency 模拟下单延迟（回放时按K线撮合，不等待）
+func (t *PaperTrader) waitLatency() {
+	if t.config.Latency <= 0 {
+		return
+	}
+	if _, replay := market.GetProvider().(*market.ReplayProvider); replay {
+		return
+	}
+	time.Sleep(t.config.Latency)
+}
+
+// marketResult 生成市价单结果（未全部成交时状态为PARTIALLY_FILLED，调用方持有锁）
+func (t *PaperTrader) marketResult(symbol, clientOrderID string, price, filled, quantity float64) map[string]interface{} {
+	result := t.fill(symbol, clientOrderID, price, filled)
+	if filled < quantity*(1-1e-9) {
+		result["status"] = "PARTIALLY_FILLED"
+	}
+	return result
+}
+
+// slippedPrice 按不利方向加滑点（买入价格上移，卖出价格下移）
+func slippedPrice(price float64, buy bool, bps float64) float64 {
+	if buy {
+		return price * (1 + bps/10000)
+	}
+	return price * (1 - bps/10000)
+}
+
+// SetLeverage 模拟账户按开仓时的杠杆计算保证金，无需设置
+func (t *PaperTrader) SetLeverage(symbol string, leverage int) error {
+	return nil
+}
+
+// GetMarketPrice 当前行情数据源的最新价格（回放时为模拟时间点的价格）
+func (t *PaperTrader) GetMarketPrice(symbol string) (float64, error) {
+	price, err := market.GetProvider().GetPrice(symbol)
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+	return price, nil
+}
+
+// SetStopLoss 设置模拟止损（整仓）
+func (t *PaperTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pos, ok := t.positions[symbol+"_"+strings.ToLower(positionSide)]
+	if !ok {
+		return fmt.Errorf("设置止损失败: %s 没有%s持仓", symbol, positionSide)
+	}
+	pos.StopLoss = stopPrice
+	t.nextID++
+	pos.stopLossID = t.nextID
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置模拟止盈（整仓）
+func (t *PaperTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pos, ok := t.positions[symbol+"_"+strings.ToLower(positionSide)]
+	if !ok {
+		return fmt.Errorf("设置止盈失败: %s 没有%s持仓", symbol, positionSide)
+	}
+	pos.TakeProfit = takeProfitPrice
+	t.nextID++
+	pos.takeProfitID = t.nextID
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// CancelAllOrders 取消该币种的止损/止盈和限价挂单
+func (t *PaperTrader) CancelAllOrders(symbol string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, pos := range t.positions {
+		if pos.Symbol == symbol {
+			pos.StopLoss, pos.TakeProfit = 0, 0
+		}
+	}
+	pending := t.pending[:0]
+	for _, p := range t.pending {
+		if p.order.Symbol != symbol {
+			pending = append(pending, p)
+		}
+	}
+	t.pending = pending
+	return nil
+}
+
+// GetOrderBook 获取盘口深度（模拟交易按币安USDT永续盘口）
+func (t *PaperTrader) GetOrderBook(symbol string, limit int) (*market.OrderBook, error) {
+	return market.GetDepth(symbol, limit)
+}
+
+// CancelOrder 按订单ID取消模拟的止损/止盈或限价挂单
+func (t *PaperTrader) CancelOrder(symbol string, orderID int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, pos := range t.positions {
+		if pos.Symbol != symbol {
+			continue
+		}
+		if pos.stopLossID == orderID {
+			pos.StopLoss = 0
+			return nil
+		}
+		if pos.takeProfitID == orderID {
+			pos.TakeProfit = 0
+			return nil
+		}
+	}
+	for i, p := range t.pending {
+		if p.id == orderID {
+			t.pending = append(t.pending[:i], t.pending[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("订单不存在 (orderId=%d)", orderID)
+}
+
+// GetOrderByClientID 按客户端订单ID查询模拟订单
+func (t *PaperTrader) GetOrderByClientID(symbol, clientOrderID string) (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.orders[clientOrderID], nil
+}
+
+// GetOpenOrders 获取模拟挂单（止损/止盈按币安订单格式返回，供对账使用）
+func (t *PaperTrader) GetOpenOrders(symbol string) ([]map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var result []map[string]interface{}
+	for _, pos := range t.sortedPositions() {
+		if symbol != "" && pos.Symbol != symbol {
+			continue
+		}
+		closeSide := "SELL"
+		if pos.Side == "short" {
+			closeSide = "BUY"
+		}
+		positionSide := strings.ToUpper(pos.Side)
+		if pos.StopLoss > 0 {
+			result = append(result, openOrderMap(pos.stopLossID, pos.Symbol, "STOP_MARKET", closeSide, positionSide, pos.StopLoss, pos.Quantity))
+		}
+		if pos.TakeProfit > 0 {
+			result = append(result, openOrderMap(pos.takeProfitID, pos.Symbol, "TAKE_PROFIT_MARKET", closeSide, positionSide, pos.TakeProfit, pos.Quantity))
+		}
+	}
+	for _, p := range t.pending {
+		if symbol != "" && p.order.Symbol != symbol {
+			continue
+		}
+		side := "BUY"
+		if (p.order.Side == "long") == p.order.ReduceOnly {
+			side = "SELL"
+		}
+		result = append(result, openOrderMap(p.id, p.order.Symbol, "LIMIT", side, strings.ToUpper(p.order.Side), p.order.Price, p.order.Quantity))
+	}
+	return result, nil
+}
+
+// FormatQuantity 模拟账户不限制精度
+func (t *PaperTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	return fmt.Sprintf("%.8f", quantity), nil
+}
+
+// OnBar 用一根已完成K线撮合：先检查止损（同一根K线同时触及止损和止盈时按止损处理），再检查止盈和限价挂单
+// 跳空越过触发价时按开盘价成交；限价单只有价格穿过挂单价（不只是触及）才成交
+func (t *PaperTrader) OnBar(symbol string, bar market.Kline) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.match(symbol, bar.Open, bar.High, bar.Low, bar.Close)
+}
+
+// Stats 模拟账户统计
+func (t *PaperTrader) Stats() PaperStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return PaperStats{Balance: t.balance, Fees: t.fees, Trades: t.trades, Wins: t.wins}
+}
+
+// Fills 全部成交记录
+func (t *PaperTrader) Fills() []PaperFill {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]PaperFill(nil), t.fills...)
+}
+
+// GetIncomeHistory 由模拟成交生成手续费和已实现盈亏流水
+func (t *PaperTrader) GetIncomeHistory(startTime, endTime time.Time) ([]IncomeRecord, error) {
+	return incomeRecordsFromFills(t.Fills(), "USDT", startTime, endTime), nil
+}
+
+// markToMarket 按最新价更新持仓标记价并检查触发（未由OnBar驱动时使用）
+func (t *PaperTrader) markToMarket() {
+	t.mu.Lock()
+	symbolSet := make(map[string]bool)
+	for _, pos := range t.positions {
+		symbolSet[pos.Symbol] = true
+	}
+	for _, p := range t.pending {
+		symbolSet[p.order.Symbol] = true
+	}
+	for symbol := range t.spot {
+		symbolSet[symbol] = true
+	}
+	t.mu.Unlock()
+
+	for symbol := range symbolSet {
+		price, err := t.GetMarketPrice(symbol)
+		if err != nil {
+			continue
+		}
+		t.mu.Lock()
+		if s, ok := t.spot[symbol]; ok {
+			s.MarkPrice = price
+		}
+		t.match(symbol, price, price, price, price)
+		t.mu.Unlock()
+	}
+}
+
+// match 按价格区间撮合止损/止盈/限价单（调用方持有锁）
+func (t *PaperTrader) match(symbol string, open, high, low, last float64) {
+	for _, side := range []string{"long", "short"} {
+		pos, ok := t.positions[symbol+"_"+side]
+		if !ok {
+			continue
+		}
+		pos.MarkPrice = last
+
+		long := side == "long"
+		var fill float64
+		var reason string
+		switch {
+		case pos.StopLoss > 0 && long && low <= pos.StopLoss:
+			fill, reason = math.Min(open, pos.StopLoss), "止损"
+		case pos.StopLoss > 0 && !long && high >= pos.StopLoss:
+			fill, reason = math.Max(open, pos.StopLoss), "止损"
+		case pos.TakeProfit > 0 && long && high >= pos.TakeProfit:
+			fill, reason = math.Max(open, pos.TakeProfit), "止盈"
+		case pos.TakeProfit > 0 && !long && low <= pos.TakeProfit:
+			fill, reason = math.Min(open, pos.TakeProfit), "止盈"
+		default:
+			continue
+		}
+		// 止损/止盈触发后以市价单成交
+		fill = slippedPrice(fill, !long, t.config.SlippageBps)
+		if quantity, err := t.close(symbol, side, 0, fill); err == nil {
+			log.Printf("🎯 [模拟] %s %s 触发%s @ %.4f（数量 %.4f）", symbol, side, reason, fill, quantity)
+		}
+	}
+
+	pending := t.pending[:0]
+	for _, p := range t.pending {
+		// 只触及挂单价时排在前面的挂单可能吃完了成交量，价格穿过挂单价才视为成交
+		buy := (p.order.Side == "long") != p.order.ReduceOnly
+		if p.order.Symbol != symbol || (buy && low >= p.order.Price) || (!buy && high <= p.order.Price) {
+			pending = append(pending, p)
+			continue
+		}
+		fill := p.order.Price
+		if (buy && open < fill) || (!buy && open > fill) {
+			fill = open
+		}
+		if err := t.execute(p.order, fill); err != nil {
+			log.Printf("⚠️  [模拟] 限价单成交失败: %v", err)
+			continue
+		}
+		log.Printf("🎯 [模拟] %s 限价单成交 @ %.4f", symbol, fill)
+		if p.order.ClientOrderID != "" {
+			t.orders[p.order.ClientOrderID] = t.fill(symbol, "", fill, p.order.Quantity)
+		}
+	}
+	t.pending = pending
+}
+
+// execute 执行限价单成交（开仓或只减仓，调用方持有锁）
+func (t *PaperTrader) execute(order LimitOrder, price float64) error {
+	if order.ReduceOnly {
+		_, err := t.close(order.Symbol, order.Side, order.Quantity, price)
+		return err
+	}
+	return t.open(order.Symbol, order.Side, order.Quantity, order.Leverage, price)
+}
+
+// open 开仓或加仓（按成交量加权计算开仓均价，调用方持有锁）
+func (t *PaperTrader) open(symbol, side string, quantity float64, leverage int, price float64) error {
+	if quantity <= 0 || price <= 0 {
+		return fmt.Errorf("开仓数量或价格无效: %.8f @ %.8f", quantity, price)
+	}
+	leverage = maxInt(leverage, 1)
+
+	available := t.available()
+	if margin := price * quantity / float64(leverage); margin > available {
+		return fmt.Errorf("模拟账户可用保证金不足: 需要 %.2f，可用 %.2f", margin, available)
+	}
+
+	fee := price * quantity * paperFeeRate
+	t.balance -= fee
+	t.fees += fee
+	t.fills = append(t.fills, PaperFill{
+		Time: market.Clock.Now(), Symbol: symbol, Side: side, Action: "open",
+		Price: price, Quantity: quantity, Fee: fee,
+	})
+
+	key := symbol + "_" + side
+	if pos, ok := t.positions[key]; ok {
+		total := pos.Quantity + quantity
+		pos.EntryPrice = (pos.EntryPrice*pos.Quantity + price*quantity) / total
+		pos.Quantity = total
+		pos.Leverage = leverage
+		pos.MarkPrice = price
+		return nil
+	}
+	t.positions[key] = &paperPosition{
+		Symbol:     symbol,
+		Side:       side,
+		EntryPrice: price,
+		MarkPrice:  price,
+		Quantity:   quantity,
+		Leverage:   leverage,
+		OpenedAt:   market.Clock.Now(),
+	}
+	return nil
+}
+
+// close 平仓（quantity=0或超过持仓时全部平仓），返回平仓数量（调用方持有锁）
+func (t *PaperTrader) close(symbol, side string, quantity, price float64) (float64, error) {
+	key := symbol + "_" + side
+	pos, ok := t.positions[key]
+	if !ok {
+		return 0, fmt.Errorf("%s 没有%s持仓", symbol, sideName(side))
+	}
+	if quantity <= 0 || quantity > pos.Quantity {
+		quantity = pos.Quantity
+	}
+
+	pnl := (price - pos.EntryPrice) * quantity
+	if side == "short" {
+		pnl = -pnl
+	}
+	fee := price * quantity * paperFeeRate
+	t.balance += pnl - fee
+	t.fees += fee
+	t.trades++
+	if pnl-fee > 0 {
+		t.wins++
+	}
+	t.fills = append(t.fills, PaperFill{
+		Time: market.Clock.Now(), Symbol: symbol, Side: side, Action: "close",
+		Price: price, Quantity: quantity, Fee: fee, PnL: pnl - fee,
+	})
+
+	pos.Quantity -= quantity
+	if pos.Quantity < 1e-12 {
+		delete(t.positions, key)
+	}
+	return quantity, nil
+}
+
+// available 可用余额（钱包余额 + 未实现盈亏 - 持仓保证金 - 现货持仓成本，调用方持有锁）
+func (t *PaperTrader) available() float64 {
+	available := t.balance
+	for _, pos := range t.positions {
+		available += pos.pnl() - pos.margin()
+	}
+	for _, s := range t.spot {
+		available -= s.Cost
+	}
+	return available
+}
+
+// GetSpotBalance 模拟现货余额（USDT为可用余额）
+func (t *PaperTrader) GetSpotBalance(asset string) (float64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if asset == "USDT" {
+		return t.available(), nil
+	}
+	for symbol, s := range t.spot {
+		if symbols.Canonical(symbol) == asset {
+			return s.Quantity, nil
+		}
+	}
+	return 0, nil
+}
+
+// GetSpotPrice 模拟现货价格（与合约共用行情数据源的价格）
+func (t *PaperTrader) GetSpotPrice(symbol string) (float64, error) {
+	return t.GetMarketPrice(symbol)
+}
+
+// SpotBuy 模拟现货市价买入（按滑点成交，手续费从钱包余额扣除）
+func (t *PaperTrader) SpotBuy(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.spotOrder(symbol, true, quantity)
+}
+
+// SpotSell 模拟现货市价卖出（只能卖出已持有的数量，不支持借币卖空）
+func (t *PaperTrader) SpotSell(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.spotOrder(symbol, false, quantity)
+}
+
+// spotOrder 模拟现货市价成交
+func (t *PaperTrader) spotOrder(symbol string, buy bool, quantity float64) (map[string]interface{}, error) {
+	t.waitLatency()
+	price, err := t.GetMarketPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+	price = slippedPrice(price, buy, t.config.SlippageBps)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.spot[symbol]
+	if buy {
+		if cost := price * quantity; quantity <= 0 || cost > t.available() {
+			return nil, fmt.Errorf("模拟现货可用余额不足: 需要 %.2f，可用 %.2f", cost, t.available())
+		}
+		if s == nil {
+			s = &paperSpot{}
+			t.spot[symbol] = s
+		}
+		s.Quantity += quantity
+		s.Cost += price * quantity
+		s.MarkPrice = price
+	} else {
+		if s == nil || quantity <= 0 || quantity > s.Quantity*(1+1e-9) {
+			return nil, fmt.Errorf("模拟现货 %s 持仓不足", symbol)
+		}
+		quantity = math.Min(quantity, s.Quantity)
+		cost := s.Cost * quantity / s.Quantity
+		t.balance += price*quantity - cost
+		s.Quantity -= quantity
+		s.Cost -= cost
+		if s.Quantity < 1e-12 {
+			delete(t.spot, symbol)
+		}
+	}
+
+	fee := price * quantity * paperFeeRate
+	t.balance -= fee
+	t.fees += fee
+	t.nextID++
+	action := "卖出"
+	if buy {
+		action = "买入"
+	}
+	log.Printf("✓ [模拟] 现货%s成功: %s 数量: %.4f @ %.4f", action, symbol, quantity, price)
+	return map[string]interface{}{
+		"orderId":     t.nextID,
+		"symbol":      symbol,
+		"status":      "FILLED",
+		"avgPrice":    price,
+		"executedQty": quantity,
+		"fee":         fee,
+	}, nil
+}
+
+// fill 生成已成交订单结果（clientOrderID非空时保存用于幂等查询，调用方持有锁）
+func (t *PaperTrader) fill(symbol, clientOrderID string, price, quantity float64) map[string]interface{} {
+	t.nextID++
+	result := map[string]interface{}{
+		"orderId":     t.nextID,
+		"symbol":      symbol,
+		"status":      "FILLED",
+		"avgPrice":    price,
+		"executedQty": quantity,
+	}
+	if clientOrderID != "" {
+		t.orders[clientOrderID] = result
+	}
+	return result
+}
+
+// sortedPositions 按币种排序的持仓（调用方持有锁）
+func (t *PaperTrader) sortedPositions() []*paperPosition {
+	return sortPaperPositions(t.positions)
+}
+
+// sideName 方向中文名
+func sideName(side string) string {
+	if side == "short" {
+		return "空"
+	}
+	return "多"
+}
+
+// maxInt 返回较大值
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
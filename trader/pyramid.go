@@ -0,0 +1,193 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"nofx/logger"
+	"nofx/market"
+	"nofx/risk"
+)
+
+// PyramidConfig 金字塔加仓规则：持仓浮盈达到各R倍数阈值时按递减的数量加仓，并收紧止损
+// R为初始风险距离（初始开仓价到信号止损价的距离）
+type PyramidConfig struct {
+	ThresholdsR []float64        // 加仓阈值（R倍数，递增），第n次加仓在浮盈达到ThresholdsR[n-1]时触发
+	SizeScale   float64          // 第n次加仓数量为初始数量×SizeScale^n
+	StopLagR    float64          // 加仓后止损收紧到阈值之下StopLagR个R（如阈值1R、滞后1R即保本），只收紧不放宽
+	Caps        risk.PyramidCaps // 风控硬性上限（加仓次数、总数量倍数，且加仓后的风险不超过初始风险）
+}
+
+// pyramidOrderID 第n次加仓的客户端订单ID（由计划ID派生，重试或重启后不变；首层序号0不用于网格挂单）
+func pyramidOrderID(planID string, n int) string {
+	return gridOrderID(planID, 0, n)
+}
+
+// managePyramids 持仓管理：对配置了加仓规则的策略，active的交易计划浮盈达到下一个R倍数阈值时市价加仓
+// 加仓基准（初始开仓价、初始数量）在计划首次进入检查时确定，网格计划为全部层成交后的均价和数量；
+// 配对、资金费率套利的持仓和禁止开仓时段不加仓
+func (at *AutoTrader) managePyramids(record *logger.DecisionRecord) {
+	if len(at.config.Pyramiding) == 0 || at.entryBlock() != "" {
+		return
+	}
+
+	for _, plan := range at.plans.openPlans() {
+		config, ok := at.config.Pyramiding[plan.StrategyID]
+		if !ok || plan.State != PlanActive || plan.Adds >= len(config.ThresholdsR) || plan.Signal.StopLoss <= 0 {
+			continue
+		}
+		tracked, ok := at.trackedPositions[plan.Symbol+"_"+plan.Side]
+		if !ok || at.isPairLeg(plan.Symbol, plan.Side) || at.isHarvestLeg(plan.Symbol, plan.Side) {
+			continue
+		}
+		if plan.InitialQuantity == 0 {
+			plan.InitialEntry, plan.InitialQuantity = plan.EntryPrice, tracked.Quantity
+			at.plans.save()
+		}
+		riskPerUnit := math.Abs(plan.InitialEntry - plan.Signal.StopLoss)
+		if riskPerUnit == 0 || plan.InitialQuantity == 0 {
+			continue
+		}
+
+		data, err := market.Get(plan.Symbol)
+		if err != nil {
+			continue
+		}
+		price := livePrice(plan.Symbol, plan.Side, data.CurrentPrice)
+		direction := 1.0
+		if plan.Side == "short" {
+			direction = -1
+		}
+		threshold := config.ThresholdsR[plan.Adds]
+		if multiple := direction * (price - plan.InitialEntry) / riskPerUnit; multiple < threshold {
+			continue
+		}
+		at.addToWinner(plan, tracked, config, price, riskPerUnit, record)
+	}
+}
+
+// addToWinner 按第plan.Adds+1个阈值加仓：风控检查通过后市价加仓，按新的总数量重挂止损/止盈
+func (at *AutoTrader) addToWinner(plan *TradePlan, tracked *trackedPosition, config PyramidConfig, price, riskPerUnit float64, record *logger.DecisionRecord) {
+	n := plan.Adds + 1
+	threshold := config.ThresholdsR[plan.Adds]
+	direction := 1.0
+	if plan.Side == "short" {
+		direction = -1
+	}
+
+	quantity := plan.InitialQuantity * math.Pow(config.SizeScale, float64(n))
+	stopLoss := plan.InitialEntry + direction*(threshold-config.StopLagR)*riskPerUnit
+	if tracked.StopLoss > 0 && direction*(tracked.StopLoss-stopLoss) > 0 {
+		stopLoss = tracked.StopLoss // 当前止损（如已被移动止损收紧）更有利时保持不变
+	}
+	total := tracked.Quantity + quantity
+	average := (plan.EntryPrice*tracked.Quantity + price*quantity) / total
+	check := risk.PyramidAdd{
+		Adds:            plan.Adds,
+		InitialQuantity: plan.InitialQuantity,
+		Quantity:        tracked.Quantity,
+		AddQuantity:     quantity,
+		InitialRisk:     plan.InitialQuantity * riskPerUnit,
+		Risk:            direction * (average - stopLoss) * total,
+	}
+	err := config.Caps.Check(check)
+	if err == nil {
+		err = at.checkSymbolTier(plan.Symbol, plan.Leverage, total*price)
+	}
+	if err == nil {
+		current := risk.NewPositionRisk(plan.Symbol, plan.Side, tracked.Quantity, price, tracked.StopLoss)
+		after := risk.NewPositionRisk(plan.Symbol, plan.Side, total, price, stopLoss)
+		err = at.checkOpenRisk(plan.Symbol, after.RiskUSD-current.RiskUSD)
+	}
+	if err == nil {
+		err = at.checkSpread(plan.Symbol)
+	}
+	if err == nil && at.config.LiquidityGuard.MaxDepthPct > 0 {
+		if limit, _, depthErr := at.liquidityLimit(plan.Symbol, plan.Side); depthErr != nil {
+			err = fmt.Errorf("无法检查盘口流动性: %w", depthErr)
+		} else if quantity*price > limit {
+			err = fmt.Errorf("加仓名义价值%.2f USDT超过盘口流动性上限%.2f USDT", quantity*price, limit)
+		}
+	}
+	if err != nil {
+		if plan.rejectedAdd != n {
+			plan.rejectedAdd = n // 同一次加仓只记录一次拒绝原因
+			log.Printf("🚫 [%s] %s %s 达到%gR，风控拒绝第%d次加仓: %v", at.name, plan.Symbol, plan.Side, threshold, n, err)
+		}
+		return
+	}
+
+	reason := fmt.Sprintf("浮盈达到%gR，第%d次加仓", threshold, n)
+	log.Printf("🔺 [%s] %s %s %s: %.4f @ %.4f，止损收紧至 %.4f", at.name, plan.Symbol, plan.Side, reason, quantity, price, stopLoss)
+	action := logger.DecisionAction{
+		Action:        "add_" + plan.Side,
+		Symbol:        plan.Symbol,
+		Quantity:      quantity,
+		Leverage:      plan.Leverage,
+		Price:         price,
+		DecisionPrice: price,
+		StrategyID:    plan.StrategyID,
+		OrderType:     "market",
+		Timestamp:     time.Now(),
+	}
+
+	// 开仓接口下单前会取消该币种的全部挂单：加仓成功或失败都要按跟踪的数量和止损重挂止损/止盈
+	defer at.replaceStops(plan.Symbol)
+
+	clientOrderID := pyramidOrderID(plan.ID, n)
+	order, err := at.trader.GetOrderByClientID(plan.Symbol, clientOrderID)
+	if err != nil {
+		log.Printf("  ⚠ 查询历史订单失败（继续下单）: %v", err)
+	}
+	if order == nil {
+		if plan.Side == "long" {
+			order, err = at.trader.OpenLong(plan.Symbol, quantity, plan.Leverage, clientOrderID)
+		} else {
+			order, err = at.trader.OpenShort(plan.Symbol, quantity, plan.Leverage, clientOrderID)
+		}
+	}
+	if err != nil {
+		log.Printf("  ⚠ 加仓失败: %v", err)
+		action.Error = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 加仓失败: %v", plan.Symbol, plan.Side, err))
+		record.Decisions = append(record.Decisions, action)
+		return
+	}
+	action.Success = true
+	action.OrderID = orderID(order)
+	at.recordFill(&action, plan.Symbol, plan.Side, order, false)
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔺 %s %s %s", plan.Symbol, plan.Side, reason))
+	record.Decisions = append(record.Decisions, action)
+
+	if fill := orderAvgPrice(order); fill > 0 {
+		average = (plan.EntryPrice*tracked.Quantity + fill*quantity) / total
+	}
+	tracked.Quantity = total
+	tracked.StopLoss = stopLoss
+
+	plan.Orders = append(plan.Orders, PlanOrder{
+		Kind:          PlanOrderPyramid,
+		OrderID:       action.OrderID,
+		ClientOrderID: clientOrderID,
+		Price:         action.Price,
+		Quantity:      quantity,
+		Time:          market.Clock.Now(),
+	})
+	plan.Quantity += quantity
+	plan.Adds = n
+	at.fillPlan(plan, total, average, false)
+	plan.setStops(tracked.StopLoss, tracked.TakeProfit, reason)
+	at.publishPlan(plan, reason)
+	at.plans.save()
+}
+
+// replaceStops 取消该币种的全部挂单，按跟踪的数量和止损/止盈重新挂单（取消失败时保留原挂单，避免重复挂单）
+func (at *AutoTrader) replaceStops(symbol string) {
+	if err := at.trader.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧止损单失败（止损/止盈仍按原数量）: %v", err)
+		return
+	}
+	at.restoreStops(symbol)
+}
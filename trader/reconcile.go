@@ -0,0 +1,257 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"nofx/events"
+)
+
+// 挂单类别
+const (
+	OrderKindStopLoss   = "stop_loss"
+	OrderKindTakeProfit = "take_profit"
+	OrderKindOther      = "other"
+)
+
+// 对账差异类型
+const (
+	DiscrepancyPositionClosed    = "position_closed"    // 本地记录的持仓在交易所已不存在（止损/止盈/强平/手动平仓）
+	DiscrepancyUntrackedPosition = "untracked_position" // 交易所有持仓但本地未记录（超时后实际成交、手动下单）
+	DiscrepancyQuantityMismatch  = "quantity_mismatch"  // 持仓数量不一致（部分成交/部分平仓）
+	DiscrepancyOrphanedOrder     = "orphaned_order"     // 没有对应持仓的止损/止盈单
+	DiscrepancyMissingStop       = "missing_stop"       // 持仓缺少止损/止盈单
+)
+
+// defaultReconcileInterval 默认对账间隔
+const defaultReconcileInterval = 5 * time.Minute
+
+// quantityTolerance 数量比较的相对容差（精度取整误差）
+const quantityTolerance = 0.01
+
+// trackedPosition 本地跟踪的持仓（开仓时写入，对账时与交易所核对）
+type trackedPosition struct {
+	Symbol     string
+	Side       string // "long" 或 "short"
+	Quantity   float64
+	StopLoss   float64 // 0表示未知（如接管的未记录持仓）
+	TakeProfit float64
+	OpenedAt   time.Time
+	StrategyID string // 开仓策略（接管的未记录持仓为空）
+}
+
+// positionSide 交易所持仓方向（"LONG" / "SHORT"）
+func (p *trackedPosition) positionSide() string {
+	return strings.ToUpper(p.Side)
+}
+
+// openOrderMap 构建统一格式的挂单信息
+// 单向持仓模式下positionSide为"BOTH"，按平仓方向推断：卖出平多，买入平空
+func openOrderMap(orderID int64, symbol, orderType, side, positionSide string, stopPrice, quantity float64) map[string]interface{} {
+	if positionSide == "" || positionSide == "BOTH" {
+		positionSide = "SHORT"
+		if side == "SELL" {
+			positionSide = "LONG"
+		}
+	}
+
+	return map[string]interface{}{
+		"orderId":      orderID,
+		"symbol":       symbol,
+		"type":         orderType,
+		"kind":         orderKind(orderType),
+		"side":         side,
+		"positionSide": positionSide,
+		"stopPrice":    stopPrice,
+		"quantity":     quantity,
+	}
+}
+
+// orderKind 根据订单类型判断挂单类别
+func orderKind(orderType string) string {
+	switch strings.ToUpper(orderType) {
+	case "STOP", "STOP_MARKET":
+		return OrderKindStopLoss
+	case "TAKE_PROFIT", "TAKE_PROFIT_MARKET":
+		return OrderKindTakeProfit
+	default:
+		return OrderKindOther
+	}
+}
+
+// trackPosition 开仓成功后记录本地持仓
+func (at *AutoTrader) trackPosition(symbol, side string, quantity, stopLoss, takeProfit float64, strategyID string) {
+	at.trackedPositions[symbol+"_"+side] = &trackedPosition{
+		Symbol:     symbol,
+		Side:       side,
+		Quantity:   quantity,
+		StopLoss:   stopLoss,
+		TakeProfit: takeProfit,
+		OpenedAt:   time.Now(),
+		StrategyID: strategyID,
+	}
+}
+
+// untrackPosition 平仓后删除本地持仓记录
+func (at *AutoTrader) untrackPosition(symbol, side string) {
+	delete(at.trackedPositions, symbol+"_"+side)
+}
+
+// reconcile 对比本地跟踪的持仓与交易所的持仓/挂单，修复差异并发布事件
+func (at *AutoTrader) reconcile() {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️  [%s] 对账失败: 获取持仓失败: %v", at.name, err)
+		return
+	}
+	orders, err := at.trader.GetOpenOrders("")
+	if err != nil {
+		log.Printf("⚠️  [%s] 对账失败: %v", at.name, err)
+		return
+	}
+
+	// 交易所持仓 (symbol_side -> 数量)
+	exchangePositions := make(map[string]float64)
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		quantity, _ := pos["positionAmt"].(float64)
+		exchangePositions[symbol+"_"+side] = math.Abs(quantity)
+	}
+
+	// 交易所止损/止盈挂单 (symbol_side -> 类别 -> 是否存在)
+	protective := make(map[string]map[string]bool)
+	orphaned := make(map[string][]int64)    // 没有对应持仓的止损/止盈单 (symbol -> 订单ID)
+	pendingEntries := make(map[string]bool) // 有未成交开仓限价单的币种
+	for _, order := range orders {
+		kind, _ := order["kind"].(string)
+		symbol, _ := order["symbol"].(string)
+		if kind == OrderKindOther {
+			pendingEntries[symbol] = true
+			continue
+		}
+		side := strings.ToLower(order["positionSide"].(string))
+		key := symbol + "_" + side
+
+		if _, ok := exchangePositions[key]; !ok {
+			orphaned[symbol] = append(orphaned[symbol], orderID(order))
+			continue
+		}
+		if protective[key] == nil {
+			protective[key] = make(map[string]bool)
+		}
+		protective[key][kind] = true
+	}
+
+	// 1. 本地有、交易所无：持仓已被平掉，删除本地记录
+	for key, tracked := range at.trackedPositions {
+		if _, ok := exchangePositions[key]; !ok {
+			at.reportDiscrepancy(DiscrepancyPositionClosed, tracked.Symbol, events.SeverityInfo, true,
+				fmt.Sprintf("%s %s 持仓已在交易所平仓（止损/止盈/强平或手动），移除本地记录", tracked.Symbol, tracked.Side))
+			delete(at.trackedPositions, key)
+		}
+	}
+
+	// 2. 交易所有、本地无：未记录的成交，纳入跟踪；数量不一致时以交易所为准
+	for key, quantity := range exchangePositions {
+		tracked, ok := at.trackedPositions[key]
+		if !ok {
+			parts := strings.SplitN(key, "_", 2)
+			if resumed, ok := at.resumePlan(parts[0], parts[1], quantity); ok {
+				// 有未结束的交易计划：按计划恢复止损/止盈和策略归属（重启后或限价开仓单离线成交）
+				at.trackedPositions[key] = resumed
+				log.Printf("📋 [%s] %s %s 按交易计划恢复持仓跟踪（数量 %.4f，止损 %.4f，止盈 %.4f）",
+					at.name, parts[0], parts[1], quantity, resumed.StopLoss, resumed.TakeProfit)
+				continue
+			}
+			severity := events.SeverityWarning
+			message := fmt.Sprintf("%s %s 存在未记录的持仓（数量 %.4f），已纳入跟踪", parts[0], parts[1], quantity)
+			if !at.reconciledOnce {
+				severity = events.SeverityInfo
+				message = fmt.Sprintf("%s %s 启动时接管已有持仓（数量 %.4f）", parts[0], parts[1], quantity)
+			}
+			at.trackedPositions[key] = &trackedPosition{Symbol: parts[0], Side: parts[1], Quantity: quantity, OpenedAt: time.Now()}
+			at.reportDiscrepancy(DiscrepancyUntrackedPosition, parts[0], severity, true, message)
+			continue
+		}
+		if tracked.Quantity > 0 && math.Abs(quantity-tracked.Quantity)/tracked.Quantity > quantityTolerance {
+			at.reportDiscrepancy(DiscrepancyQuantityMismatch, tracked.Symbol, events.SeverityWarning, true,
+				fmt.Sprintf("%s %s 持仓数量不一致（本地 %.4f，交易所 %.4f），以交易所为准", tracked.Symbol, tracked.Side, tracked.Quantity, quantity))
+		}
+		tracked.Quantity = quantity
+	}
+
+	// 未结束的交易计划在交易所没有持仓：已持仓的计划在离线期间被平仓；
+	// pending的计划开仓单已不在交易所，未成交即失效（IOC/FOK未成交、被取消或过期）
+	for _, plan := range at.plans.openPlans() {
+		if _, ok := exchangePositions[plan.Symbol+"_"+plan.Side]; ok {
+			continue
+		}
+		switch {
+		case plan.State != PlanPending:
+			at.exitPlan(plan.Symbol, plan.Side, nil, 0, "交易所已平仓（止损/止盈/强平或手动）")
+		case !pendingEntries[plan.Symbol]:
+			at.invalidatePlan(plan.Symbol, plan.Side, "开仓单未成交且已不在交易所")
+		}
+	}
+
+	// 3. 孤儿挂单：没有对应持仓的止损/止盈单，只按订单ID取消这些挂单（同币种其他持仓的止损/止盈保留）
+	for symbol, orderIDs := range orphaned {
+		if pendingEntries[symbol] {
+			continue // 限价开仓单未成交，止损/止盈单等待持仓建立
+		}
+		var err error
+		for _, id := range orderIDs {
+			if cancelErr := at.trader.CancelOrder(symbol, id); cancelErr != nil {
+				log.Printf("  ⚠ 取消孤儿挂单失败: %v", cancelErr)
+				err = cancelErr
+			}
+		}
+		at.reportDiscrepancy(DiscrepancyOrphanedOrder, symbol, events.SeverityWarning, err == nil,
+			fmt.Sprintf("%s 存在%d个没有对应持仓的止损/止盈单，已取消", symbol, len(orderIDs)))
+	}
+
+	// 4. 缺少止损/止盈单：按本地记录的价格补挂
+	for key, tracked := range at.trackedPositions {
+		existing := protective[key]
+		if tracked.StopLoss > 0 && !existing[OrderKindStopLoss] {
+			err := at.trader.SetStopLoss(tracked.Symbol, tracked.positionSide(), tracked.Quantity, tracked.StopLoss)
+			at.reportDiscrepancy(DiscrepancyMissingStop, tracked.Symbol, events.SeverityWarning, err == nil,
+				fmt.Sprintf("%s %s 缺少止损单，按 %.4f 补挂", tracked.Symbol, tracked.Side, tracked.StopLoss))
+		}
+		if tracked.TakeProfit > 0 && !existing[OrderKindTakeProfit] {
+			err := at.trader.SetTakeProfit(tracked.Symbol, tracked.positionSide(), tracked.Quantity, tracked.TakeProfit)
+			at.reportDiscrepancy(DiscrepancyMissingStop, tracked.Symbol, events.SeverityWarning, err == nil,
+				fmt.Sprintf("%s %s 缺少止盈单，按 %.4f 补挂", tracked.Symbol, tracked.Side, tracked.TakeProfit))
+		}
+		if tracked.StopLoss == 0 && !existing[OrderKindStopLoss] && at.reconciledOnce && !at.isPairLeg(tracked.Symbol, tracked.Side) && !at.isHarvestLeg(tracked.Symbol, tracked.Side) {
+			// 价格未知无法补挂，只提示
+			log.Printf("⚠️  [%s] %s %s 没有止损保护", at.name, tracked.Symbol, tracked.Side)
+		}
+	}
+
+	at.reconciledOnce = true
+}
+
+// reportDiscrepancy 记录并发布对账差异事件
+func (at *AutoTrader) reportDiscrepancy(kind, symbol, severity string, repaired bool, message string) {
+	if !repaired {
+		message += "（修复失败）"
+	}
+	log.Printf("🔍 [%s] 对账: %s", at.name, message)
+
+	events.Publish(events.Event{
+		Type:     events.TypeReconcileDiscrepancy,
+		Severity: severity,
+		Message:  fmt.Sprintf("[%s] %s", at.name, message),
+		Data: map[string]interface{}{
+			"trader_id": at.id,
+			"symbol":    symbol,
+			"kind":      kind,
+			"repaired":  repaired,
+		},
+	})
+}
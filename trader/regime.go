@@ -0,0 +1,242 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/decision"
+	"nofx/events"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/strategy"
+)
+
+// 市场状态
+const (
+	RegimeTrending = "trending" // 趋势：启用趋势策略（如breakout）
+	RegimeRanging  = "ranging"  // 震荡：启用均值回归策略（如mean_reversion）
+)
+
+// RegimeConfig 按市场状态切换用户策略的配置（Trending和Ranging都为空时不启用）
+type RegimeConfig struct {
+	Symbols       []string // 判断市场状态的参考币种（多数币种的状态为整体状态）
+	Trending      []string // 趋势状态下启用的策略ID
+	Ranging       []string // 震荡状态下启用的策略ID
+	ConfirmCycles int      // 新状态连续出现的周期数达到该值才切换（防止来回切换）
+}
+
+func (c RegimeConfig) enabled() bool {
+	return len(c.Trending) > 0 || len(c.Ranging) > 0
+}
+
+// regimeController 市场状态切换器：状态确认后启用对应状态的策略，其余受管策略转为只管理模式
+type regimeController struct {
+	mu           sync.Mutex
+	current      string            // 当前状态（确认前为空，受管策略全部停用）
+	since        time.Time         // 当前状态开始时间
+	pending      string            // 待确认的新状态
+	pendingCount int               // 待确认状态已连续出现的周期数
+	symbols      map[string]string // 各参考币种最近一次的状态
+	disabled     map[string]bool   // 由切换器停用的策略（切换时只重新启用这些策略，不恢复手动或因失败停用的策略）
+	initialized  bool
+}
+
+// RegimeStatus 市场状态切换器的状态
+type RegimeStatus struct {
+	Regime        string            `json:"regime"` // trending、ranging，未确认时为空
+	Since         time.Time         `json:"since"`
+	Pending       string            `json:"pending,omitempty"` // 待确认的新状态
+	PendingCycles int               `json:"pending_cycles,omitempty"`
+	Symbols       map[string]string `json:"symbols"` // 各参考币种的状态（trending、ranging或unclear）
+	Active        []string          `json:"active"`  // 当前状态启用的策略ID
+}
+
+// classifyRegime 结合趋势状态（Hurst指数）和波动率状态判断单个币种的市场状态，无法判断时返回空字符串
+// 趋势性强或波动率扩张时为趋势状态；均值回归特征明显，或随机游走且波动率收缩时为震荡状态
+func classifyRegime(data *market.LongerTermData) string {
+	if data == nil {
+		return ""
+	}
+	trend, volatility := data.Regime(), data.VolatilityRegime()
+	switch {
+	case trend == "trending" || volatility == "expanding":
+		return RegimeTrending
+	case trend == "mean-reverting" || (trend == "random-walk" && volatility == "contracting"):
+		return RegimeRanging
+	}
+	return ""
+}
+
+// updateRegime 每个周期在运行用户策略前调用：按参考币种判断市场状态，状态确认切换后启用/停用受管策略
+func (at *AutoTrader) updateRegime(ctx *decision.Context, record *logger.DecisionRecord) {
+	config := at.config.Regime
+	counts := make(map[string]int)
+	symbols := make(map[string]string, len(config.Symbols))
+	for _, symbol := range config.Symbols {
+		data := ctx.MarketDataMap[symbol]
+		if data == nil {
+			var err error
+			if data, err = market.Get(symbol); err != nil {
+				log.Printf("⚠️  [%s] 获取 %s 市场数据失败，跳过市场状态判断: %v", at.name, symbol, err)
+				continue
+			}
+		}
+		regime := classifyRegime(data.LongerTermContext)
+		if regime == "" {
+			symbols[symbol] = "unclear"
+			continue
+		}
+		symbols[symbol] = regime
+		counts[regime]++
+	}
+
+	// 多数参考币种的状态为整体状态，平票或无法判断时保持当前状态
+	observed := ""
+	switch {
+	case counts[RegimeTrending] > counts[RegimeRanging]:
+		observed = RegimeTrending
+	case counts[RegimeRanging] > counts[RegimeTrending]:
+		observed = RegimeRanging
+	}
+
+	c := &at.regime
+	c.mu.Lock()
+	c.symbols = symbols
+	first := !c.initialized
+	c.initialized = true
+	switched := false
+	previous := c.current
+	switch {
+	case observed == "" || observed == c.current:
+		c.pending, c.pendingCount = "", 0
+	case observed == c.pending:
+		c.pendingCount++
+	default:
+		c.pending, c.pendingCount = observed, 1
+	}
+	if c.pending != "" && (c.pendingCount >= config.ConfirmCycles || c.current == "") {
+		c.current, c.since = c.pending, time.Now()
+		c.pending, c.pendingCount = "", 0
+		switched = true
+	}
+	current := c.current
+	c.mu.Unlock()
+
+	if !first && !switched {
+		return
+	}
+	at.applyRegime(current)
+	if !switched {
+		return
+	}
+
+	message := fmt.Sprintf("[%s] 市场状态切换: %s → %s（%s），启用策略 %v",
+		at.name, regimeLabel(previous), current, describeRegimeSymbols(symbols), at.regimeStrategies(current))
+	log.Printf("🔀 %s", message)
+	record.ExecutionLog = append(record.ExecutionLog, "🔀 "+message)
+	events.Publish(events.Event{
+		Type:     events.TypeRegimeSwitch,
+		Severity: events.SeverityInfo,
+		Message:  message,
+		Data: map[string]interface{}{
+			"trader_id": at.id,
+			"from":      previous,
+			"to":        current,
+			"symbols":   symbols,
+			"active":    at.regimeStrategies(current),
+		},
+	})
+}
+
+// applyRegime 启用当前状态的策略，停用其他受管策略（已有持仓转为只管理模式，由该策略继续平仓）
+func (at *AutoTrader) applyRegime(regime string) {
+	active := make(map[string]bool)
+	for _, id := range at.regimeStrategies(regime) {
+		active[id] = true
+	}
+
+	c := &at.regime
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.disabled == nil {
+		c.disabled = make(map[string]bool)
+	}
+	for _, id := range append(append([]string{}, at.config.Regime.Trending...), at.config.Regime.Ranging...) {
+		sandbox, err := at.findStrategy(id)
+		if err != nil {
+			continue
+		}
+		if active[id] {
+			if c.disabled[id] && sandbox.Mode() != strategy.ModeActive {
+				sandbox.Enable()
+			}
+			delete(c.disabled, id)
+			continue
+		}
+		if sandbox.Mode() == strategy.ModeActive {
+			sandbox.Disable("市场状态: " + regimeLabel(regime))
+			c.disabled[id] = true
+		}
+	}
+}
+
+// regimeStrategies 指定市场状态下启用的策略ID
+func (at *AutoTrader) regimeStrategies(regime string) []string {
+	switch regime {
+	case RegimeTrending:
+		return at.config.Regime.Trending
+	case RegimeRanging:
+		return at.config.Regime.Ranging
+	}
+	return nil
+}
+
+// regimeLabel 市场状态的显示名称（未确认时为unknown）
+func regimeLabel(regime string) string {
+	if regime == "" {
+		return "unknown"
+	}
+	return regime
+}
+
+// RegimeStatus 市场状态切换器的当前状态（未配置regime时返回错误）
+func (at *AutoTrader) RegimeStatus() (RegimeStatus, error) {
+	if !at.config.Regime.enabled() {
+		return RegimeStatus{}, fmt.Errorf("trader %s 未配置regime", at.id)
+	}
+	c := &at.regime
+	c.mu.Lock()
+	status := RegimeStatus{
+		Regime:        c.current,
+		Since:         c.since,
+		Pending:       c.pending,
+		PendingCycles: c.pendingCount,
+		Symbols:       make(map[string]string, len(c.symbols)),
+	}
+	for symbol, regime := range c.symbols {
+		status.Symbols[symbol] = regime
+	}
+	c.mu.Unlock()
+
+	for _, id := range at.regimeStrategies(status.Regime) {
+		if sandbox, err := at.findStrategy(id); err == nil && sandbox.Mode() == strategy.ModeActive {
+			status.Active = append(status.Active, id)
+		}
+	}
+	sort.Strings(status.Active)
+	return status, nil
+}
+
+// describeRegimeSymbols 参考币种状态的简短描述（用于日志）
+func describeRegimeSymbols(symbols map[string]string) string {
+	parts := make([]string, 0, len(symbols))
+	for symbol, regime := range symbols {
+		parts = append(parts, symbol+"="+regime)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,146 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"nofx/market"
+)
+
+// replaySettle 回放时每个时间点相对K线收盘的延迟（模拟实盘在收盘后几秒执行）
+const replaySettle = 5 * time.Second
+
+// ReplayConfig 历史回放配置
+type ReplayConfig struct {
+	Symbols []string  // 按基础周期K线撮合止损/止盈的币种（需已同步历史K线）
+	From    time.Time // 回放开始时间
+	To      time.Time // 回放结束时间
+}
+
+// ReplayPoint 回放净值曲线上的一个点
+type ReplayPoint struct {
+	Time   time.Time `json:"time"`
+	Equity float64   `json:"equity"`
+}
+
+// ReplayResult 回放结果
+type ReplayResult struct {
+	TraderID       string        `json:"trader_id"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	Cycles         int           `json:"cycles"`
+	InitialBalance float64       `json:"initial_balance"`
+	FinalEquity    float64       `json:"final_equity"`
+	PnL            float64       `json:"pnl"`
+	PnLPct         float64       `json:"pnl_pct"`
+	MaxDrawdownPct float64       `json:"max_drawdown_pct"`
+	Stats          PaperStats    `json:"stats"`
+	EquityCurve    []ReplayPoint `json:"equity_curve"`
+	Fills          []PaperFill   `json:"fills"`
+}
+
+// Replay 用历史K线驱动与实盘完全相同的交易周期（行情→指标→AI决策→风控→下单→对账）
+// 模拟时间通过market.Clock注入，行情来自provider，下单由PaperTrader按基础周期K线撮合；
+// 每个基础周期推进一次时间（不等待真实时间），到达扫描间隔时执行runCycle，到达对账间隔时执行reconcile
+func (at *AutoTrader) Replay(provider *market.ReplayProvider, cfg ReplayConfig) (*ReplayResult, error) {
+	paper, ok := unwrapTrader(at.trader).(*PaperTrader)
+	if !ok {
+		return nil, fmt.Errorf("回放只能使用模拟交易（exchange=paper），当前为 %s", at.exchange)
+	}
+	step, err := market.IntervalDuration(provider.BaseInterval())
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.To.After(cfg.From) {
+		return nil, fmt.Errorf("回放结束时间必须晚于开始时间")
+	}
+
+	// 切换到回放数据源和模拟时钟，结束后恢复
+	previous := market.GetProvider()
+	market.RegisterProvider(provider)
+	if err := market.SetProvider(provider.Name()); err != nil {
+		return nil, err
+	}
+	defer market.SetProvider(previous.Name())
+
+	var now atomic.Int64
+	market.Clock.SetNow(func() time.Time { return time.UnixMilli(now.Load()) })
+	defer market.Clock.SetNow(nil)
+
+	start := market.Clock.CandleStart(cfg.From, step)
+	now.Store(start.Add(replaySettle).UnixMilli())
+	at.startTime = start
+	at.lastResetTime = start
+	at.isRunning = true
+
+	result := &ReplayResult{
+		TraderID:       at.id,
+		From:           start,
+		To:             cfg.To,
+		InitialBalance: at.initialBalance,
+	}
+	log.Printf("⏪ [%s] 开始回放 %s ~ %s（基础周期 %s，扫描间隔 %v）", at.name,
+		start.Format("2006-01-02 15:04"), cfg.To.Format("2006-01-02 15:04"), provider.BaseInterval(), at.config.ScanInterval)
+
+	lastBar := make(map[string]int64)
+	nextCycle, nextReconcile := start, start
+	peak := at.initialBalance
+	for t := start; at.isRunning && t.Before(cfg.To); t = t.Add(step) {
+		now.Store(t.Add(replaySettle).UnixMilli())
+
+		// 用刚收盘的基础周期K线撮合止损/止盈/限价单
+		for _, symbol := range cfg.Symbols {
+			symbol = market.Normalize(symbol)
+			if bar, ok := provider.LastBar(symbol); ok && bar.OpenTime > lastBar[symbol] {
+				lastBar[symbol] = bar.OpenTime
+				paper.OnBar(symbol, bar)
+			}
+		}
+
+		if !t.Before(nextReconcile) {
+			at.reconcile()
+			nextReconcile = t.Add(at.config.ReconcileInterval)
+		}
+		if t.Before(nextCycle) {
+			continue
+		}
+		nextCycle = t.Add(at.config.ScanInterval)
+
+		if err := at.runCycle(); err != nil {
+			log.Printf("❌ [%s] 回放周期失败 @ %s: %v", at.name, t.Format("2006-01-02 15:04"), err)
+		}
+		result.Cycles++
+
+		equity := paperEquity(paper)
+		result.EquityCurve = append(result.EquityCurve, ReplayPoint{Time: t, Equity: equity})
+		if equity > peak {
+			peak = equity
+		}
+		if peak > 0 && (peak-equity)/peak*100 > result.MaxDrawdownPct {
+			result.MaxDrawdownPct = (peak - equity) / peak * 100
+		}
+	}
+	at.isRunning = false
+
+	result.FinalEquity = paperEquity(paper)
+	result.PnL = result.FinalEquity - at.initialBalance
+	if at.initialBalance > 0 {
+		result.PnLPct = result.PnL / at.initialBalance * 100
+	}
+	result.Stats = paper.Stats()
+	result.Fills = paper.Fills()
+
+	log.Printf("⏹ [%s] 回放结束: %d个周期 | 净值 %.2f (%+.2f%%) | 最大回撤 %.2f%% | %d笔交易 | 手续费 %.2f",
+		at.name, result.Cycles, result.FinalEquity, result.PnLPct, result.MaxDrawdownPct, result.Stats.Trades, result.Stats.Fees)
+	return result, nil
+}
+
+// paperEquity 模拟账户净值（钱包余额 + 未实现盈亏）
+func paperEquity(paper *PaperTrader) float64 {
+	balance, _ := paper.GetBalance()
+	wallet, _ := balance["totalWalletBalance"].(float64)
+	unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+	return wallet + unrealized
+}
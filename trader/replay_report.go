@@ -0,0 +1,400 @@
+package trader
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"nofx/market"
+)
+
+// reportChartWidth/reportChartHeight 回放报告中图表的尺寸（SVG坐标）
+const (
+	reportChartWidth  = 1000
+	reportChartHeight = 260
+	reportChartPad    = 50
+)
+
+// reportMaxCandles 回放报告每个币种K线图的最大K线数量（据此选择K线周期）
+const reportMaxCandles = 600
+
+// reportIntervals 回放报告K线图可选的周期（从小到大）
+var reportIntervals = []string{"15m", "1h", "4h", "1d"}
+
+// ReportInterval 选择回放报告K线图的周期：K线数量不超过reportMaxCandles的最小周期
+func ReportInterval(from, to time.Time) string {
+	for _, interval := range reportIntervals {
+		d, _ := market.IntervalDuration(interval)
+		if to.Sub(from)/d <= reportMaxCandles {
+			return interval
+		}
+	}
+	return reportIntervals[len(reportIntervals)-1]
+}
+
+// reportLabel 坐标轴刻度
+type reportLabel struct {
+	X, Y float64
+	Text string
+}
+
+// reportLine 折线/面积图
+type reportLine struct {
+	Path    string // 折线
+	Area    string // 填充区域（为空时不填充）
+	YLabels []reportLabel
+	XLabels []reportLabel
+}
+
+// reportCandle 一根K线
+type reportCandle struct {
+	X, High, Low               float64 // 影线
+	BodyX, BodyY, BodyW, BodyH float64 // 实体
+	Up                         bool
+}
+
+// reportMarker 成交标记
+type reportMarker struct {
+	X, Y  float64
+	Buy   bool
+	Title string
+}
+
+// reportCandleChart 单个币种的K线图
+type reportCandleChart struct {
+	Symbol   string
+	Interval string
+	Candles  []reportCandle
+	Markers  []reportMarker
+	YLabels  []reportLabel
+	XLabels  []reportLabel
+}
+
+// reportMonth 月度收益
+type reportMonth struct {
+	Month     string
+	Start     float64
+	End       float64
+	ReturnPct float64
+}
+
+// replayReportData 回放报告模板数据
+type replayReportData struct {
+	Result      *ReplayResult
+	Width       int
+	Height      int
+	Pad         int
+	Equity      reportLine
+	Drawdown    reportLine
+	CandleChart []reportCandleChart
+	Months      []reportMonth
+	WinRate     float64
+	GeneratedAt time.Time
+}
+
+// WriteReplayReport 生成回放HTML报告（净值曲线、回撤图、带成交标记的K线图、月度收益表），
+// candles为每个币种在回放区间内的K线（可为空，缺少的币种不画K线图）；报告为单个自包含文件，无外部依赖
+func WriteReplayReport(path string, result *ReplayResult, candles map[string][]market.Kline, interval string) error {
+	data := replayReportData{
+		Result:      result,
+		Width:       reportChartWidth,
+		Height:      reportChartHeight,
+		Pad:         reportChartPad,
+		Months:      monthlyReturns(result),
+		GeneratedAt: time.Now(),
+	}
+	if result.Stats.Trades > 0 {
+		data.WinRate = float64(result.Stats.Wins) / float64(result.Stats.Trades) * 100
+	}
+	data.Equity, data.Drawdown = equityCharts(result)
+
+	symbols := make([]string, 0, len(candles))
+	for symbol := range candles {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		if len(candles[symbol]) == 0 {
+			continue
+		}
+		data.CandleChart = append(data.CandleChart, candleChart(symbol, interval, candles[symbol], result))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建报告目录失败: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建回放报告失败: %w", err)
+	}
+	defer f.Close()
+	if err := replayReportTemplate.Execute(f, data); err != nil {
+		return fmt.Errorf("生成回放报告失败: %w", err)
+	}
+	return nil
+}
+
+// equityCharts 净值曲线和回撤图
+func equityCharts(result *ReplayResult) (equity, drawdown reportLine) {
+	curve := result.EquityCurve
+	if len(curve) == 0 {
+		return
+	}
+	from, to := curve[0].Time, curve[len(curve)-1].Time
+	minEquity, maxEquity := curve[0].Equity, curve[0].Equity
+	for _, p := range curve {
+		minEquity = math.Min(minEquity, p.Equity)
+		maxEquity = math.Max(maxEquity, p.Equity)
+	}
+
+	drawdowns := make([]float64, len(curve))
+	peak, maxDrawdown := 0.0, 0.0
+	for i, p := range curve {
+		peak = math.Max(peak, p.Equity)
+		if peak > 0 {
+			drawdowns[i] = (peak - p.Equity) / peak * 100
+		}
+		maxDrawdown = math.Max(maxDrawdown, drawdowns[i])
+	}
+	if maxDrawdown == 0 {
+		maxDrawdown = 1
+	}
+
+	var eq, dd strings.Builder
+	for i, p := range curve {
+		x := scaleTime(p.Time, from, to)
+		cmd := "L"
+		if i == 0 {
+			cmd = "M"
+		}
+		fmt.Fprintf(&eq, "%s%.1f,%.1f ", cmd, x, scaleValue(p.Equity, minEquity, maxEquity))
+		fmt.Fprintf(&dd, "%s%.1f,%.1f ", cmd, x, scaleValue(-drawdowns[i], -maxDrawdown, 0))
+	}
+	top := float64(reportChartPad / 2)
+	area := fmt.Sprintf("M%.1f,%.1f %sL%.1f,%.1f Z", scaleTime(from, from, to), top, dd.String(), scaleTime(to, from, to), top)
+
+	equity = reportLine{
+		Path:    eq.String(),
+		YLabels: valueLabels(minEquity, maxEquity, "%.2f"),
+		XLabels: timeLabels(from, to),
+	}
+	drawdown = reportLine{
+		Path:    dd.String(),
+		Area:    area,
+		YLabels: valueLabels(-maxDrawdown, 0, "%.1f%%"),
+		XLabels: timeLabels(from, to),
+	}
+	return
+}
+
+// candleChart 币种K线图（成交标记画在成交价位置）
+func candleChart(symbol, interval string, klines []market.Kline, result *ReplayResult) reportCandleChart {
+	from := time.UnixMilli(klines[0].OpenTime)
+	to := time.UnixMilli(klines[len(klines)-1].CloseTime)
+	low, high := klines[0].Low, klines[0].High
+	for _, k := range klines {
+		low = math.Min(low, k.Low)
+		high = math.Max(high, k.High)
+	}
+
+	var fills []PaperFill
+	for _, fill := range result.Fills {
+		if fill.Symbol == symbol {
+			fills = append(fills, fill)
+			low = math.Min(low, fill.Price)
+			high = math.Max(high, fill.Price)
+		}
+	}
+
+	chart := reportCandleChart{
+		Symbol:   symbol,
+		Interval: interval,
+		YLabels:  valueLabels(low, high, "%.4g"),
+		XLabels:  timeLabels(from, to),
+	}
+	width := math.Max(float64(reportChartWidth-2*reportChartPad)/float64(len(klines))*0.7, 1)
+	for _, k := range klines {
+		x := scaleTime(time.UnixMilli((k.OpenTime+k.CloseTime)/2), from, to)
+		top := scaleValue(math.Max(k.Open, k.Close), low, high)
+		bottom := scaleValue(math.Min(k.Open, k.Close), low, high)
+		chart.Candles = append(chart.Candles, reportCandle{
+			X:     x,
+			High:  scaleValue(k.High, low, high),
+			Low:   scaleValue(k.Low, low, high),
+			BodyX: x - width/2,
+			BodyY: top,
+			BodyW: width,
+			BodyH: math.Max(bottom-top, 0.5),
+			Up:    k.Close >= k.Open,
+		})
+	}
+	for _, fill := range fills {
+		// 开多/平空为买入，开空/平多为卖出
+		buy := (fill.Action == "open") == (fill.Side == "long")
+		title := fmt.Sprintf("%s %s %s %.4f @ %.4f", fill.Time.UTC().Format("2006-01-02 15:04"), fill.Action, fill.Side, fill.Quantity, fill.Price)
+		if fill.Action == "close" {
+			title += fmt.Sprintf(" 盈亏 %+.2f", fill.PnL)
+		}
+		chart.Markers = append(chart.Markers, reportMarker{
+			X:     scaleTime(fill.Time, from, to),
+			Y:     scaleValue(fill.Price, low, high),
+			Buy:   buy,
+			Title: title,
+		})
+	}
+	return chart
+}
+
+// monthlyReturns 按净值曲线每月最后一个点计算月度收益（首月相对初始资金）
+func monthlyReturns(result *ReplayResult) []reportMonth {
+	var months []reportMonth
+	start := result.InitialBalance
+	for _, p := range result.EquityCurve {
+		month := p.Time.UTC().Format("2006-01")
+		if len(months) == 0 || months[len(months)-1].Month != month {
+			if len(months) > 0 {
+				start = months[len(months)-1].End
+			}
+			months = append(months, reportMonth{Month: month, Start: start})
+		}
+		current := &months[len(months)-1]
+		current.End = p.Equity
+		if current.Start > 0 {
+			current.ReturnPct = (current.End - current.Start) / current.Start * 100
+		}
+	}
+	return months
+}
+
+// scaleTime 时间映射到X坐标
+func scaleTime(t, from, to time.Time) float64 {
+	span := to.Sub(from).Seconds()
+	if span <= 0 {
+		return reportChartPad
+	}
+	return reportChartPad + t.Sub(from).Seconds()/span*float64(reportChartWidth-2*reportChartPad)
+}
+
+// scaleValue 数值映射到Y坐标（上下各留半个边距）
+func scaleValue(v, low, high float64) float64 {
+	top, bottom := float64(reportChartPad/2), float64(reportChartHeight-reportChartPad)
+	if high <= low {
+		return (top + bottom) / 2
+	}
+	return bottom - (v-low)/(high-low)*(bottom-top)
+}
+
+// valueLabels Y轴刻度（5个）
+func valueLabels(low, high float64, format string) []reportLabel {
+	labels := make([]reportLabel, 0, 5)
+	for i := 0; i <= 4; i++ {
+		v := low + (high-low)*float64(i)/4
+		labels = append(labels, reportLabel{X: reportChartPad - 6, Y: scaleValue(v, low, high), Text: fmt.Sprintf(format, v)})
+	}
+	return labels
+}
+
+// timeLabels X轴刻度（5个）
+func timeLabels(from, to time.Time) []reportLabel {
+	layout := "01-02 15:04"
+	if to.Sub(from) > 7*24*time.Hour {
+		layout = "2006-01-02"
+	}
+	labels := make([]reportLabel, 0, 5)
+	for i := 0; i <= 4; i++ {
+		t := from.Add(to.Sub(from) * time.Duration(i) / 4)
+		labels = append(labels, reportLabel{X: scaleTime(t, from, to), Y: reportChartHeight - reportChartPad + 18, Text: t.UTC().Format(layout)})
+	}
+	return labels
+}
+
+var replayReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"signed": func(v float64) string { return fmt.Sprintf("%+.2f", v) },
+	"utc":    func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04") },
+	"gain": func(v float64) string {
+		if v > 0 {
+			return "gain"
+		}
+		if v < 0 {
+			return "loss"
+		}
+		return ""
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>NOFX 回放报告 - {{.Result.TraderID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", sans-serif; margin: 24px auto; max-width: 1040px; color: #222; }
+h1 { font-size: 22px; } h2 { font-size: 17px; margin-top: 32px; border-bottom: 1px solid #ddd; padding-bottom: 4px; }
+table { border-collapse: collapse; font-size: 13px; }
+td, th { padding: 4px 10px; border-bottom: 1px solid #eee; text-align: right; }
+th { background: #f6f6f6; } td:first-child, th:first-child { text-align: left; }
+.gain { color: #0a8a3a; } .loss { color: #c62828; } .dim { color: #888; font-size: 12px; }
+svg { background: #fcfcfc; border: 1px solid #eee; width: 100%; height: auto; }
+svg text { font-size: 11px; fill: #666; } .axis { stroke: #ddd; }
+.equity { fill: none; stroke: #1565c0; stroke-width: 1.5; }
+.drawdown { fill: rgba(198, 40, 40, 0.25); stroke: #c62828; stroke-width: 1; }
+.up { fill: #26a69a; stroke: #26a69a; } .down { fill: #ef5350; stroke: #ef5350; }
+.buy { fill: #0a8a3a; } .sell { fill: #c62828; }
+</style>
+</head>
+<body>
+<h1>回放报告 · {{.Result.TraderID}}</h1>
+<p class="dim">{{utc .Result.From}} ~ {{utc .Result.To}} (UTC) · 生成于 {{utc .GeneratedAt}}</p>
+
+<table>
+<tr><th>指标</th><th>数值</th></tr>
+<tr><td>初始资金</td><td>{{printf "%.2f" .Result.InitialBalance}}</td></tr>
+<tr><td>最终净值</td><td>{{printf "%.2f" .Result.FinalEquity}}</td></tr>
+<tr><td>盈亏</td><td class="{{gain .Result.PnL}}">{{signed .Result.PnL}} ({{signed .Result.PnLPct}}%)</td></tr>
+<tr><td>最大回撤</td><td>{{printf "%.2f" .Result.MaxDrawdownPct}}%</td></tr>
+<tr><td>周期数</td><td>{{.Result.Cycles}}</td></tr>
+<tr><td>交易笔数（胜）</td><td>{{.Result.Stats.Trades}} ({{.Result.Stats.Wins}}，胜率 {{printf "%.1f" .WinRate}}%)</td></tr>
+<tr><td>手续费</td><td>{{printf "%.2f" .Result.Stats.Fees}}</td></tr>
+</table>
+
+<h2>净值曲线</h2>
+<svg viewBox="0 0 {{.Width}} {{.Height}}">
+{{range .Equity.YLabels}}<line class="axis" x1="{{$.Pad}}" x2="{{$.Width}}" y1="{{.Y}}" y2="{{.Y}}"/><text x="{{.X}}" y="{{.Y}}" text-anchor="end" dominant-baseline="middle">{{.Text}}</text>
+{{end}}{{range .Equity.XLabels}}<text x="{{.X}}" y="{{.Y}}" text-anchor="middle">{{.Text}}</text>
+{{end}}<path class="equity" d="{{.Equity.Path}}"/>
+</svg>
+
+<h2>回撤</h2>
+<svg viewBox="0 0 {{.Width}} {{.Height}}">
+{{range .Drawdown.YLabels}}<line class="axis" x1="{{$.Pad}}" x2="{{$.Width}}" y1="{{.Y}}" y2="{{.Y}}"/><text x="{{.X}}" y="{{.Y}}" text-anchor="end" dominant-baseline="middle">{{.Text}}</text>
+{{end}}{{range .Drawdown.XLabels}}<text x="{{.X}}" y="{{.Y}}" text-anchor="middle">{{.Text}}</text>
+{{end}}<path class="drawdown" d="{{.Drawdown.Area}}"/>
+</svg>
+
+<h2>月度收益</h2>
+<table>
+<tr><th>月份</th><th>月初净值</th><th>月末净值</th><th>收益</th></tr>
+{{range .Months}}<tr><td>{{.Month}}</td><td>{{printf "%.2f" .Start}}</td><td>{{printf "%.2f" .End}}</td><td class="{{gain .ReturnPct}}">{{signed .ReturnPct}}%</td></tr>
+{{end}}</table>
+
+{{range .CandleChart}}
+<h2>{{.Symbol}} · {{.Interval}}</h2>
+<svg viewBox="0 0 {{$.Width}} {{$.Height}}">
+{{range .YLabels}}<line class="axis" x1="{{$.Pad}}" x2="{{$.Width}}" y1="{{.Y}}" y2="{{.Y}}"/><text x="{{.X}}" y="{{.Y}}" text-anchor="end" dominant-baseline="middle">{{.Text}}</text>
+{{end}}{{range .XLabels}}<text x="{{.X}}" y="{{.Y}}" text-anchor="middle">{{.Text}}</text>
+{{end}}{{range .Candles}}<g class="{{if .Up}}up{{else}}down{{end}}"><line x1="{{.X}}" x2="{{.X}}" y1="{{.High}}" y2="{{.Low}}"/><rect x="{{.BodyX}}" y="{{.BodyY}}" width="{{.BodyW}}" height="{{.BodyH}}"/></g>
+{{end}}{{range .Markers}}<path class="{{if .Buy}}buy{{else}}sell{{end}}" d="{{if .Buy}}M{{.X}},{{.Y}} l-5,9 h10 z{{else}}M{{.X}},{{.Y}} l-5,-9 h10 z{{end}}"><title>{{.Title}}</title></path>
+{{end}}</svg>
+{{end}}
+
+<h2>成交记录</h2>
+<table>
+<tr><th>时间 (UTC)</th><th>币种</th><th>操作</th><th>方向</th><th>价格</th><th>数量</th><th>手续费</th><th>盈亏</th></tr>
+{{range .Result.Fills}}<tr><td>{{utc .Time}}</td><td>{{.Symbol}}</td><td>{{.Action}}</td><td>{{.Side}}</td><td>{{printf "%.4f" .Price}}</td><td>{{printf "%.4f" .Quantity}}</td><td>{{printf "%.4f" .Fee}}</td><td class="{{gain .PnL}}">{{if eq .Action "close"}}{{signed .PnL}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
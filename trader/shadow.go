@@ -0,0 +1,383 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"nofx/decision"
+	"nofx/market"
+	"nofx/mcp"
+)
+
+// shadowFeeRate 影子策略模拟成交的手续费率（按吃单费率估算）
+const shadowFeeRate = 0.0004
+
+// ShadowConfig 影子策略配置：替代模型/prompt与实盘使用同一份行情并行决策，只做模拟成交
+type ShadowConfig struct {
+	Enabled         bool
+	AIModel         string // 为空时与实盘相同（"qwen"、"deepseek"或"custom"）
+	CustomAPIURL    string
+	CustomAPIKey    string
+	CustomModelName string
+	ExtraPrompt     string // 追加到系统prompt的替代策略说明
+}
+
+// paperPosition 模拟持仓
+type paperPosition struct {
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"`
+	EntryPrice float64   `json:"entry_price"`
+	MarkPrice  float64   `json:"mark_price"`
+	Quantity   float64   `json:"quantity"`
+	Leverage   int       `json:"leverage"`
+	StopLoss   float64   `json:"stop_loss"`
+	TakeProfit float64   `json:"take_profit"`
+	OpenedAt   time.Time `json:"opened_at"`
+
+	stopLossID   int64 // 模拟止损/止盈单ID（PaperTrader使用）
+	takeProfitID int64
+}
+
+// pnl 按标记价计算的未实现盈亏
+func (p *paperPosition) pnl() float64 {
+	if p.Side == "long" {
+		return (p.MarkPrice - p.EntryPrice) * p.Quantity
+	}
+	return (p.EntryPrice - p.MarkPrice) * p.Quantity
+}
+
+// margin 占用保证金
+func (p *paperPosition) margin() float64 {
+	return p.EntryPrice * p.Quantity / float64(p.Leverage)
+}
+
+// shadowRunner 影子策略执行器
+type shadowRunner struct {
+	mu          sync.Mutex
+	client      *mcp.Client
+	extraPrompt string
+	running     bool // 上一次影子决策是否仍在进行（AI调用较慢时跳过本周期）
+
+	initialBalance float64
+	realizedPnL    float64
+	fees           float64
+	trades         int
+	wins           int
+	positions      map[string]*paperPosition // symbol_side -> 模拟持仓
+	lastRunAt      time.Time
+	lastError      string
+}
+
+// ShadowReport 影子策略与实盘的收益对比
+type ShadowReport struct {
+	Model          string           `json:"model"`
+	Equity         float64          `json:"equity"`
+	PnL            float64          `json:"pnl"`
+	PnLPct         float64          `json:"pnl_pct"`
+	RealizedPnL    float64          `json:"realized_pnl"`
+	UnrealizedPnL  float64          `json:"unrealized_pnl"`
+	Fees           float64          `json:"fees"`
+	Trades         int              `json:"trades"`
+	WinRate        float64          `json:"win_rate"`
+	Positions      []*paperPosition `json:"positions"`
+	LiveEquity     float64          `json:"live_equity"`
+	LivePnL        float64          `json:"live_pnl"`
+	LivePnLPct     float64          `json:"live_pnl_pct"`
+	PnLPctVsLive   float64          `json:"pnl_pct_vs_live"` // 影子收益率 - 实盘收益率
+	LastRunAt      time.Time        `json:"last_run_at"`
+	LastError      string           `json:"last_error,omitempty"`
+	InitialBalance float64          `json:"initial_balance"`
+}
+
+// newShadowRunner 创建影子策略执行器（模型配置在实盘配置基础上覆盖）
+func newShadowRunner(config AutoTraderConfig) *shadowRunner {
+	shadow := config.Shadow
+	aiConfig := config
+	aiConfig.Name = config.Name + " (shadow)"
+	if shadow.AIModel != "" {
+		aiConfig.AIModel = shadow.AIModel
+		aiConfig.UseQwen = shadow.AIModel == "qwen"
+	}
+	if shadow.CustomAPIURL != "" {
+		aiConfig.CustomAPIURL = shadow.CustomAPIURL
+		aiConfig.CustomAPIKey = shadow.CustomAPIKey
+		aiConfig.CustomModelName = shadow.CustomModelName
+	}
+
+	return &shadowRunner{
+		client:         newMCPClient(aiConfig),
+		extraPrompt:    shadow.ExtraPrompt,
+		initialBalance: config.InitialBalance,
+		positions:      make(map[string]*paperPosition),
+	}
+}
+
+// runShadow 在后台用影子策略对同一份行情做决策并模拟成交（不阻塞实盘周期）
+func (at *AutoTrader) runShadow(ctx *decision.Context) {
+	if at.shadow == nil || len(ctx.MarketDataMap) == 0 {
+		return
+	}
+
+	s := at.shadow
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		log.Printf("👻 [%s] 上一次影子决策尚未完成，跳过本周期", at.name)
+		return
+	}
+	s.running = true
+	s.markToMarket(ctx.MarketDataMap)
+	shadowCtx := s.context(ctx)
+	s.mu.Unlock()
+
+	go func() {
+		fd, err := decision.GetFullDecision(shadowCtx, s.client)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running = false
+		s.lastRunAt = time.Now()
+		if err != nil {
+			s.lastError = err.Error()
+			log.Printf("👻 [%s] 影子策略决策失败: %v", at.name, err)
+			return
+		}
+		s.lastError = ""
+		for _, d := range sortDecisionsByPriority(fd.Decisions) {
+			s.apply(d, ctx.MarketDataMap[d.Symbol])
+		}
+
+		report := s.report(ctx.Account.TotalEquity)
+		log.Printf("👻 [%s] 影子策略: 净值 %.2f (%+.2f%%) | 实盘 %.2f (%+.2f%%) | 差异 %+.2f%%",
+			at.name, report.Equity, report.PnLPct, report.LiveEquity, report.LivePnLPct, report.PnLPctVsLive)
+	}()
+}
+
+// GetShadowReport 获取影子策略对比报告（未启用影子策略时返回false）
+func (at *AutoTrader) GetShadowReport() (*ShadowReport, bool) {
+	if at.shadow == nil {
+		return nil, false
+	}
+
+	liveEquity := at.initialBalance
+	if account, err := at.GetAccountInfo(); err == nil {
+		if equity, ok := account["total_equity"].(float64); ok {
+			liveEquity = equity
+		}
+	}
+
+	at.shadow.mu.Lock()
+	defer at.shadow.mu.Unlock()
+	report := at.shadow.report(liveEquity)
+	report.Model = at.config.Shadow.AIModel
+	if report.Model == "" {
+		report.Model = at.aiModel
+	}
+	return report, true
+}
+
+// context 构建影子策略的决策上下文（复用实盘行情，账户和持仓替换为模拟盘）
+func (s *shadowRunner) context(live *decision.Context) *decision.Context {
+	ctx := *live
+	ctx.ExtraPrompt = s.extraPrompt
+	ctx.Performance = nil // 实盘交易记录不适用于影子策略
+
+	equity := s.equity()
+	marginUsed := 0.0
+	ctx.Positions = nil
+	for _, pos := range s.sortedPositions() {
+		marginUsed += pos.margin()
+		pnlPct := 0.0
+		if pos.margin() > 0 {
+			pnlPct = pos.pnl() / pos.margin() * 100
+		}
+		ctx.Positions = append(ctx.Positions, decision.PositionInfo{
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        pos.MarkPrice,
+			Quantity:         pos.Quantity,
+			Leverage:         pos.Leverage,
+			UnrealizedPnL:    pos.pnl(),
+			UnrealizedPnLPct: pnlPct,
+			MarginUsed:       pos.margin(),
+			UpdateTime:       pos.OpenedAt.UnixMilli(),
+			StopLoss:         pos.StopLoss,
+			TakeProfit:       pos.TakeProfit,
+		})
+	}
+
+	ctx.Account = decision.AccountInfo{
+		TotalEquity:      equity,
+		AvailableBalance: equity - marginUsed,
+		TotalPnL:         equity - s.initialBalance,
+		TotalPnLPct:      (equity - s.initialBalance) / s.initialBalance * 100,
+		MarginUsed:       marginUsed,
+		PositionCount:    len(s.positions),
+	}
+	if equity > 0 {
+		ctx.Account.MarginUsedPct = marginUsed / equity * 100
+	}
+	return &ctx
+}
+
+// markToMarket 按最新价格更新模拟持仓，触及止损/止盈的持仓按止损/止盈价平仓
+func (s *shadowRunner) markToMarket(marketData map[string]*market.Data) {
+	for key, pos := range s.positions {
+		data, ok := marketData[pos.Symbol]
+		if !ok || data.CurrentPrice <= 0 {
+			continue
+		}
+		pos.MarkPrice = data.CurrentPrice
+
+		long := pos.Side == "long"
+		switch {
+		case pos.StopLoss > 0 && ((long && pos.MarkPrice <= pos.StopLoss) || (!long && pos.MarkPrice >= pos.StopLoss)):
+			pos.MarkPrice = pos.StopLoss
+		case pos.TakeProfit > 0 && ((long && pos.MarkPrice >= pos.TakeProfit) || (!long && pos.MarkPrice <= pos.TakeProfit)):
+			pos.MarkPrice = pos.TakeProfit
+		default:
+			continue
+		}
+		s.close(key)
+	}
+}
+
+// apply 模拟执行一条决策（按决策时的当前价格成交）
+func (s *shadowRunner) apply(d decision.Decision, data *market.Data) {
+	if data == nil || data.CurrentPrice <= 0 {
+		return
+	}
+	price := data.CurrentPrice
+
+	switch d.Action {
+	case "open_long", "open_short":
+		side := "long"
+		if d.Action == "open_short" {
+			side = "short"
+		}
+		key := d.Symbol + "_" + side
+		if _, exists := s.positions[key]; exists || d.PositionSizeUSD <= 0 {
+			return
+		}
+		leverage := d.Leverage
+		if leverage <= 0 {
+			leverage = 1
+		}
+		pos := &paperPosition{
+			Symbol:     d.Symbol,
+			Side:       side,
+			EntryPrice: price,
+			MarkPrice:  price,
+			Quantity:   d.PositionSizeUSD / price,
+			Leverage:   leverage,
+			StopLoss:   d.StopLoss,
+			TakeProfit: d.TakeProfit,
+			OpenedAt:   time.Now(),
+		}
+		if pos.margin() > s.equity()-s.marginUsed() {
+			return // 模拟盘可用保证金不足
+		}
+		s.fees += d.PositionSizeUSD * shadowFeeRate
+		s.positions[key] = pos
+	case "close_long", "close_short":
+		side := "long"
+		if d.Action == "close_short" {
+			side = "short"
+		}
+		key := d.Symbol + "_" + side
+		if pos, ok := s.positions[key]; ok {
+			pos.MarkPrice = price
+			s.close(key)
+		}
+	}
+}
+
+// close 按当前标记价平掉模拟持仓
+func (s *shadowRunner) close(key string) {
+	pos := s.positions[key]
+	pnl := pos.pnl()
+	s.fees += pos.MarkPrice * pos.Quantity * shadowFeeRate
+	s.realizedPnL += pnl
+	s.trades++
+	if pnl > 0 {
+		s.wins++
+	}
+	delete(s.positions, key)
+}
+
+// equity 模拟盘净值（初始资金 + 已实现盈亏 - 手续费 + 未实现盈亏）
+func (s *shadowRunner) equity() float64 {
+	equity := s.initialBalance + s.realizedPnL - s.fees
+	for _, pos := range s.positions {
+		equity += pos.pnl()
+	}
+	return equity
+}
+
+// marginUsed 模拟持仓占用保证金
+func (s *shadowRunner) marginUsed() float64 {
+	total := 0.0
+	for _, pos := range s.positions {
+		total += pos.margin()
+	}
+	return total
+}
+
+// sortedPositions 按币种排序的模拟持仓
+func (s *shadowRunner) sortedPositions() []*paperPosition {
+	return sortPaperPositions(s.positions)
+}
+
+// sortPaperPositions 按币种、方向排序模拟持仓
+func sortPaperPositions(m map[string]*paperPosition) []*paperPosition {
+	positions := make([]*paperPosition, 0, len(m))
+	for _, pos := range m {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].Symbol+positions[i].Side < positions[j].Symbol+positions[j].Side
+	})
+	return positions
+}
+
+// report 生成对比报告（调用方持有锁）
+func (s *shadowRunner) report(liveEquity float64) *ShadowReport {
+	equity := s.equity()
+	report := &ShadowReport{
+		Equity:         equity,
+		PnL:            equity - s.initialBalance,
+		RealizedPnL:    s.realizedPnL,
+		UnrealizedPnL:  equity - s.initialBalance - s.realizedPnL + s.fees,
+		Fees:           s.fees,
+		Trades:         s.trades,
+		LiveEquity:     liveEquity,
+		LivePnL:        liveEquity - s.initialBalance,
+		LastRunAt:      s.lastRunAt,
+		LastError:      s.lastError,
+		InitialBalance: s.initialBalance,
+	}
+	for _, pos := range s.sortedPositions() {
+		copied := *pos
+		report.Positions = append(report.Positions, &copied)
+	}
+	if s.trades > 0 {
+		report.WinRate = float64(s.wins) / float64(s.trades) * 100
+	}
+	if s.initialBalance > 0 {
+		report.PnLPct = report.PnL / s.initialBalance * 100
+		report.LivePnLPct = report.LivePnL / s.initialBalance * 100
+		report.PnLPctVsLive = report.PnLPct - report.LivePnLPct
+	}
+	report.UnrealizedPnL = math.Round(report.UnrealizedPnL*1e8) / 1e8
+	return report
+}
+
+// String 单行摘要
+func (r *ShadowReport) String() string {
+	return fmt.Sprintf("影子 %+.2f%% vs 实盘 %+.2f%%（%d笔，胜率%.0f%%）", r.PnLPct, r.LivePnLPct, r.Trades, r.WinRate)
+}
@@ -0,0 +1,131 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/monitor"
+)
+
+// signalQueueSize 外部信号队列长度
+const signalQueueSize = 16
+
+// ExternalSignal 外部信号（如TradingView告警），与AI决策经过相同的校验、风控和执行流程
+type ExternalSignal struct {
+	Decision decision.Decision
+	Source   string // 信号来源（如"tradingview"），记录在决策日志中；决策未指定策略ID时作为策略ID
+}
+
+// SubmitSignal 提交外部信号（异步执行，结果写入决策日志并发布信号事件）
+// 信号在交易主循环中执行，与交易周期、对账串行，避免并发修改本地持仓记录
+func (at *AutoTrader) SubmitSignal(signal ExternalSignal) error {
+	if !at.isRunning {
+		return fmt.Errorf("trader %s 未运行", at.id)
+	}
+	select {
+	case at.signals <- signal:
+		return nil
+	default:
+		return fmt.Errorf("外部信号队列已满（%d）", signalQueueSize)
+	}
+}
+
+// executeSignal 执行外部信号
+func (at *AutoTrader) executeSignal(signal ExternalSignal) {
+	d := signal.Decision
+	at.attributeDecision(&d, signal.Source)
+	log.Printf("📨 [%s] 收到外部信号（%s）: %s %s", at.name, signal.Source, d.Symbol, d.Action)
+
+	record := &logger.DecisionRecord{
+		Source:       signal.Source,
+		CoTTrace:     d.Reasoning,
+		ExecutionLog: []string{},
+		Success:      true,
+	}
+	decisionJSON, _ := json.MarshalIndent([]decision.Decision{d}, "", "  ")
+	record.DecisionJSON = string(decisionJSON)
+	reject := func(reason string) {
+		log.Printf("🚫 [%s] 外部信号被拒绝（%s %s）: %s", at.name, d.Symbol, d.Action, reason)
+		record.Success = false
+		record.ErrorMessage = reason
+		at.decisionLogger.LogDecision(record)
+	}
+
+	// 与交易周期相同的风控检查：风控暂停和熔断一样通过EntryBlocked只拒绝开仓，平仓信号照常执行
+	if available, reason := monitor.ExchangeAvailable(at.exchange); !available {
+		reject(fmt.Sprintf("交易所暂不可用: %s", reason))
+		return
+	}
+
+	ctx, err := at.buildTradingContext()
+	if err != nil {
+		reject(fmt.Sprintf("构建交易上下文失败: %v", err))
+		return
+	}
+	if reason, now := at.checkRiskLimits(ctx.Account.TotalEquity), market.Clock.Now(); reason != "" && !now.Before(at.stopUntil) {
+		at.stopUntil = now.Add(at.config.StopTradingTime)
+		log.Printf("🛑 [%s] 触发风控: %s，暂停开仓 %.0f 分钟（平仓照常执行）", at.name, reason, at.config.StopTradingTime.Minutes())
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛑 触发风控: %s，暂停开仓 %.0f 分钟", reason, at.config.StopTradingTime.Minutes()))
+		ctx.EntryBlocked = at.entryBlock()
+	}
+	record.AccountState = logger.AccountSnapshot{
+		TotalBalance:          ctx.Account.TotalEquity,
+		AvailableBalance:      ctx.Account.AvailableBalance,
+		TotalUnrealizedProfit: ctx.Account.TotalPnL,
+		PositionCount:         ctx.Account.PositionCount,
+		MarginUsedPct:         ctx.Account.MarginUsedPct,
+	}
+
+	// 行情数据用于下架检查和信号依据
+	if data, err := market.Get(d.Symbol); err == nil {
+		ctx.MarketDataMap = map[string]*market.Data{d.Symbol: data}
+		switch d.Action {
+		case "open_long":
+			d.Explanation = market.Explain(data, "long")
+		case "open_short":
+			d.Explanation = market.Explain(data, "short")
+		default:
+			d.Explanation = market.Explain(data, "")
+		}
+	}
+	if err := decision.ValidateDecisions([]decision.Decision{d}, ctx); err != nil {
+		reject(err.Error())
+		return
+	}
+
+	actionRecord := logger.DecisionAction{
+		Action:     d.Action,
+		Symbol:     d.Symbol,
+		Leverage:   d.Leverage,
+		StrategyID: d.StrategyID,
+		Tags:       d.Tags,
+		Timestamp:  time.Now(),
+	}
+	if d.Explanation != nil {
+		actionRecord.Explanation = d.Explanation.Summary
+		actionRecord.Readings = d.Explanation.Values()
+	}
+	if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
+		log.Printf("❌ 执行外部信号失败 (%s %s): %v", d.Symbol, d.Action, err)
+		actionRecord.Error = err.Error()
+		record.Success = false
+		record.ErrorMessage = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+		at.deferEntry(d, signal.Source, err)
+	} else {
+		actionRecord.Success = true
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+		at.publishSignal(&d, &actionRecord)
+		at.attachPlanSource(&d, signal.Source)
+	}
+	record.Decisions = append(record.Decisions, actionRecord)
+
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+}
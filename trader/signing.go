@@ -0,0 +1,235 @@
+package trader
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/market"
+	"nofx/monitor"
+	"nofx/utils"
+)
+
+// timeSyncInterval 服务器时间重新同步间隔
+const timeSyncInterval = 30 * time.Minute
+
+// signedRequestTimeout 私有接口请求的整体超时（包含限速排队和-1021重试），避免交易循环卡在无响应的连接上
+const signedRequestTimeout = 30 * time.Second
+
+// recvWindowMs 全局recvWindow配置（毫秒，0表示使用各交易所默认值）
+var recvWindowMs int64
+
+// SetRecvWindow 设置私有接口的recvWindow（毫秒，币安最大60000）
+func SetRecvWindow(ms int64) {
+	recvWindowMs = ms
+	log.Printf("✓ 私有接口recvWindow: %dms", ms)
+}
+
+// RequestSigner 私有接口签名助手
+// 统一生成timestamp（按服务器时间校正本地时钟偏差）、recvWindow和HMAC-SHA256签名
+type RequestSigner struct {
+	secretKey     string
+	serverTimeURL string
+	recvWindow    int64
+	httpClient    *http.Client
+
+	mu       sync.Mutex
+	offset   int64 // 本地时间 - 服务器时间（毫秒）
+	syncedAt time.Time
+}
+
+// NewRequestSigner 创建签名助手
+// serverTimeURL: 返回{"serverTime": 毫秒}的接口；defaultRecvWindow: 未配置recv_window_ms时使用的值
+func NewRequestSigner(secretKey, serverTimeURL string, defaultRecvWindow int64, httpClient *http.Client) *RequestSigner {
+	recvWindow := defaultRecvWindow
+	if recvWindowMs > 0 {
+		recvWindow = recvWindowMs
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RequestSigner{
+		secretKey:     secretKey,
+		serverTimeURL: serverTimeURL,
+		recvWindow:    recvWindow,
+		httpClient:    httpClient,
+	}
+}
+
+// RecvWindow 获取recvWindow（毫秒）
+func (s *RequestSigner) RecvWindow() int64 {
+	return s.recvWindow
+}
+
+// Timestamp 获取校正后的时间戳（毫秒），超过同步间隔时先同步服务器时间
+func (s *RequestSigner) Timestamp() int64 {
+	s.mu.Lock()
+	due := time.Since(s.syncedAt) > timeSyncInterval
+	s.mu.Unlock()
+
+	if due {
+		if err := s.SyncTime(); err != nil {
+			log.Printf("⚠️  同步服务器时间失败: %v（使用上次的时钟偏差）", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().UnixMilli() - s.offset
+}
+
+// SyncTime 同步服务器时间，计算本地时钟偏差
+func (s *RequestSigner) SyncTime() error {
+	start := time.Now().UnixMilli()
+	resp, err := s.httpClient.Get(s.serverTimeURL)
+	if err != nil {
+		s.markSynced() // 避免每个请求都重试同步
+		return err
+	}
+	defer resp.Body.Close()
+	end := time.Now().UnixMilli()
+
+	var result struct {
+		ServerTime int64 `json:"serverTime"`
+	}
+	body, err := utils.ReadBody(resp.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &result)
+	}
+	if err != nil || result.ServerTime == 0 {
+		s.markSynced()
+		return fmt.Errorf("解析服务器时间失败: %s", string(body))
+	}
+
+	// 以请求往返的中点估算本地时间
+	offset := (start+end)/2 - result.ServerTime
+
+	s.mu.Lock()
+	previous := s.offset
+	s.offset = offset
+	s.syncedAt = time.Now()
+	s.mu.Unlock()
+
+	// K线边界计算同样使用交易所时间
+	market.Clock.SetOffset(-time.Duration(offset) * time.Millisecond)
+
+	if abs64(offset-previous) > 1000 {
+		log.Printf("🕒 本地时钟与服务器偏差 %dms，已自动校正", offset)
+	}
+	return nil
+}
+
+// Resync 标记需要重新同步（收到时间戳错误后调用，下次签名前同步）
+func (s *RequestSigner) Resync() {
+	s.mu.Lock()
+	s.syncedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+func (s *RequestSigner) markSynced() {
+	s.mu.Lock()
+	s.syncedAt = time.Now()
+	s.mu.Unlock()
+}
+
+// Sign 为参数设置timestamp和recvWindow，并返回带signature的查询字符串
+// body为表单请求体（参与签名，不包含在返回值中）
+func (s *RequestSigner) Sign(params url.Values, body string) string {
+	params.Del("signature")
+	params.Set("timestamp", strconv.FormatInt(s.Timestamp(), 10))
+	params.Set("recvWindow", strconv.FormatInt(s.recvWindow, 10))
+
+	query := params.Encode()
+	mac := hmac.New(sha256.New, []byte(s.secretKey))
+	mac.Write([]byte(query + body))
+	return query + "&signature=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Transport 返回签名中间件：带signature参数的请求统一重新签名（校正时间戳、设置recvWindow），
+// 收到时间戳错误(-1021)时同步服务器时间并重试一次。base为nil时使用http.DefaultTransport
+func (s *RequestSigner) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &signingTransport{signer: s, base: base}
+}
+
+// signingTransport 签名中间件
+type signingTransport struct {
+	signer *RequestSigner
+	base   http.RoundTripper
+}
+
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !req.URL.Query().Has("signature") {
+		return t.base.RoundTrip(req)
+	}
+	if err := checkClockDrift(); err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		signed := req.Clone(req.Context())
+		signed.URL.RawQuery = t.signer.Sign(req.URL.Query(), string(body))
+		if body != nil {
+			signed.Body = io.NopCloser(bytes.NewReader(body))
+			signed.ContentLength = int64(len(body))
+		}
+
+		resp, err := t.base.RoundTrip(signed)
+		if err != nil || resp.StatusCode != http.StatusBadRequest || attempt > 1 {
+			return resp, err
+		}
+
+		// 时间戳超出recvWindow：请求未被执行，同步时间后可安全重试
+		respBody, _ := utils.ReadBody(resp.Body)
+		resp.Body.Close()
+		if !isTimestampError(string(respBody)) {
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+			return resp, nil
+		}
+		log.Printf("🕒 请求时间戳超出recvWindow，同步服务器时间后重试")
+		t.signer.Resync()
+	}
+}
+
+// checkClockDrift 时钟偏差监控开启暂停签名请求且当前偏差超限时返回错误
+func checkClockDrift() error {
+	if ok, reason := monitor.SignedRequestsAllowed(); !ok {
+		return fmt.Errorf("已暂停签名请求: %s", reason)
+	}
+	return nil
+}
+
+// isTimestampError 是否为时间戳错误（币安/Aster错误码-1021）
+func isTimestampError(message string) bool {
+	return strings.Contains(message, "-1021")
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
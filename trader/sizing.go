@@ -0,0 +1,208 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+// 仓位计算模式
+const (
+	SizingRisk      = "risk"       // 按止损距离使每笔亏损为净值的固定百分比
+	SizingKelly     = "kelly"      // 按历史胜率/盈亏比的凯利公式（乘以系数）确定风险百分比
+	SizingVolTarget = "vol_target" // 按币种日波动率使仓位的日波动为净值的目标百分比
+)
+
+const (
+	sizingStatsTTL = time.Hour // 凯利公式所用交易统计的缓存时间
+	volTargetBars  = 168       // 计算日波动率的1小时K线数（7天）
+)
+
+// SizingConfig 单个策略的仓位计算配置
+type SizingConfig struct {
+	Mode          string        // risk、kelly 或 vol_target
+	RiskPct       float64       // 每笔风险（占净值%）；kelly模式下交易数不足时使用
+	KellyFraction float64       // 凯利系数（如0.5为半凯利）
+	MaxRiskPct    float64       // kelly模式的每笔风险上限（占净值%）
+	MinTrades     int           // kelly模式所需的最少已平仓交易数
+	Lookback      time.Duration // kelly模式统计胜率/盈亏比的回看期
+	TargetVolPct  float64       // vol_target模式的目标日波动（占净值%）
+}
+
+// kellyStats 单个策略的胜率和盈亏比
+type kellyStats struct {
+	trades  int
+	winRate float64 // 0-1
+	payoff  float64 // 平均盈利 / 平均亏损
+}
+
+// sizingStats 按策略缓存的交易统计（避免每次开仓都扫描全部决策日志）
+type sizingStats struct {
+	mu       sync.Mutex
+	stats    map[string]*kellyStats
+	loadedAt time.Time
+}
+
+// kellyStats 读取策略在回看期内的胜率和盈亏比（缓存sizingStatsTTL）
+func (at *AutoTrader) kellyStats(strategyID string, lookback time.Duration) (*kellyStats, error) {
+	cache := &at.sizingStats
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := market.Clock.Now()
+	if cache.stats == nil || now.Sub(cache.loadedAt) >= sizingStatsTTL || now.Before(cache.loadedAt) {
+		summary, err := at.decisionLogger.TaxLots(now.Add(-lookback), now, 0)
+		if err != nil {
+			return nil, err
+		}
+		cache.stats = computeKellyStats(summary.Lots)
+		cache.loadedAt = now
+	}
+	if stats, ok := cache.stats[strategyID]; ok {
+		return stats, nil
+	}
+	return &kellyStats{}, nil
+}
+
+// computeKellyStats 按策略统计胜率和盈亏比
+func computeKellyStats(lots []logger.TaxLot) map[string]*kellyStats {
+	type totals struct {
+		wins, losses    int
+		winSum, lossSum float64
+	}
+	byStrategy := make(map[string]*totals)
+	for _, lot := range lots {
+		t := byStrategy[lot.StrategyID]
+		if t == nil {
+			t = &totals{}
+			byStrategy[lot.StrategyID] = t
+		}
+		if gain := lot.Gain(); gain > 0 {
+			t.wins++
+			t.winSum += gain
+		} else {
+			t.losses++
+			t.lossSum -= gain
+		}
+	}
+
+	stats := make(map[string]*kellyStats)
+	for id, t := range byStrategy {
+		s := &kellyStats{trades: t.wins + t.losses}
+		s.winRate = float64(t.wins) / float64(s.trades)
+		if t.wins > 0 && t.losses > 0 && t.lossSum > 0 {
+			s.payoff = (t.winSum / float64(t.wins)) / (t.lossSum / float64(t.losses))
+		}
+		stats[id] = s
+	}
+	return stats
+}
+
+// kellyRiskPct 凯利比例 f* = W - (1-W)/R，乘以系数并限制上限后作为每笔风险百分比
+func kellyRiskPct(stats *kellyStats, config SizingConfig) (float64, error) {
+	if stats.payoff <= 0 {
+		// 只有盈利或只有亏损，无法估计盈亏比
+		if stats.winRate == 0 {
+			return 0, fmt.Errorf("凯利公式：最近%d笔交易全部亏损，拒绝开仓", stats.trades)
+		}
+		return config.MaxRiskPct, nil
+	}
+	kelly := stats.winRate - (1-stats.winRate)/stats.payoff
+	if kelly <= 0 {
+		return 0, fmt.Errorf("凯利比例≤0（胜率%.0f%%，盈亏比%.2f），策略没有正期望，拒绝开仓", stats.winRate*100, stats.payoff)
+	}
+	return math.Min(kelly*config.KellyFraction*100, config.MaxRiskPct), nil
+}
+
+// dailyVolatility 币种日波动率（1小时对数收益率标准差 × √24，百分比）
+func dailyVolatility(symbol string) (float64, error) {
+	klines, err := market.GetProvider().GetKlines(symbol, "1h", volTargetBars+1)
+	if err != nil {
+		return 0, fmt.Errorf("获取K线失败: %w", err)
+	}
+	var returns []float64
+	for i := 1; i < len(klines); i++ {
+		if klines[i-1].Close > 0 && klines[i].Close > 0 {
+			returns = append(returns, math.Log(klines[i].Close/klines[i-1].Close))
+		}
+	}
+	if len(returns) < 24 {
+		return 0, fmt.Errorf("K线数量不足（%d根）", len(returns))
+	}
+	_, std := meanStd(returns)
+	return std * math.Sqrt(24) * 100, nil
+}
+
+// accountEquity 账户净值（钱包余额 + 未实现盈亏）
+func (at *AutoTrader) accountEquity() (float64, error) {
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		return 0, fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	wallet, _ := balance["totalWalletBalance"].(float64)
+	unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+	return wallet + unrealized, nil
+}
+
+// applySizing 按策略配置的仓位模式重新计算开仓仓位（未配置的策略使用决策给出的仓位），结果不超过单币种仓位上限
+func (at *AutoTrader) applySizing(d *decision.Decision, price float64) error {
+	config, ok := at.config.Sizing[d.StrategyID]
+	if !ok {
+		return nil
+	}
+	equity, err := at.accountEquity()
+	if err != nil {
+		return err
+	}
+
+	var size float64
+	var detail string
+	switch config.Mode {
+	case SizingRisk, SizingKelly:
+		riskPct := config.RiskPct
+		detail = fmt.Sprintf("风险%.2f%%", riskPct)
+		if config.Mode == SizingKelly {
+			stats, err := at.kellyStats(d.StrategyID, config.Lookback)
+			if err != nil {
+				return fmt.Errorf("读取交易统计失败: %w", err)
+			}
+			if stats.trades >= config.MinTrades {
+				if riskPct, err = kellyRiskPct(stats, config); err != nil {
+					return err
+				}
+				detail = fmt.Sprintf("凯利风险%.2f%%（%d笔，胜率%.0f%%，盈亏比%.2f）", riskPct, stats.trades, stats.winRate*100, stats.payoff)
+			} else {
+				detail = fmt.Sprintf("风险%.2f%%（交易数%d<%d，暂不使用凯利公式）", riskPct, stats.trades, config.MinTrades)
+			}
+		}
+		stopDistance := math.Abs(price-d.StopLoss) / price
+		if stopDistance <= 0 {
+			return fmt.Errorf("止损价与当前价相同，无法按风险计算仓位")
+		}
+		size = equity * riskPct / 100 / stopDistance
+		d.RiskUSD = equity * riskPct / 100
+	case SizingVolTarget:
+		vol, err := dailyVolatility(d.Symbol)
+		if err != nil {
+			return fmt.Errorf("计算%s日波动率失败: %w", d.Symbol, err)
+		}
+		size = equity * config.TargetVolPct / vol
+		detail = fmt.Sprintf("目标日波动%.2f%%，%s日波动%.2f%%", config.TargetVolPct, d.Symbol, vol)
+	default:
+		return nil
+	}
+
+	if maxSize := decision.MaxPositionValue(d.Symbol, equity, at.config.SymbolOverrides[d.Symbol]); size > maxSize {
+		size = maxSize
+		detail += "，已限制为单币种上限"
+	}
+	log.Printf("  📐 策略 %s 仓位模式 %s：%s，仓位 %.2f → %.2f USDT", d.StrategyID, config.Mode, detail, d.PositionSizeUSD, size)
+	d.PositionSizeUSD = size
+	return nil
+}
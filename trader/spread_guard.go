@@ -0,0 +1,185 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/monitor"
+)
+
+// spreadPollInterval 等待价差收窄时的检查间隔（交易主循环的定时器，不阻塞主循环）
+const spreadPollInterval = 500 * time.Millisecond
+
+// SpreadGuardConfig 市价开仓前的买卖价差检查（使用bookTicker实时盘口），避免在流动性差的山寨币上支付过大的价差
+type SpreadGuardConfig struct {
+	MaxSpreadBps float64            // 价差上限（bps，0表示不检查）
+	Symbols      map[string]float64 // 币种单独的价差上限（覆盖MaxSpreadBps）
+	MaxDelay     time.Duration      // 价差过大时等待收窄的最长时间（0表示直接拒绝）
+}
+
+// limit 币种的价差上限
+func (c SpreadGuardConfig) limit(symbol string) float64 {
+	if limit, ok := c.Symbols[symbol]; ok {
+		return limit
+	}
+	return c.MaxSpreadBps
+}
+
+// spreadRejection 价差检查未通过（价差过大或没有实时盘口），配置了MaxDelay时AI决策和外部信号的开仓会等待后重新检查
+type spreadRejection struct {
+	reason string
+}
+
+func (e *spreadRejection) Error() string {
+	return e.reason
+}
+
+// spreadWait 等待价差收窄的开仓决策
+type spreadWait struct {
+	decision decision.Decision
+	source   string // 外部信号来源（AI决策为空）
+	deadline time.Time
+}
+
+// checkSpread 市价开仓前检查买卖价差：超过上限或没有实时盘口（推送过期）时拒绝开仓
+func (at *AutoTrader) checkSpread(symbol string) error {
+	limit := at.config.SpreadGuard.limit(symbol)
+	if limit <= 0 {
+		return nil
+	}
+	spread, ok := market.Hub.SpreadBps(symbol, livePriceMaxAge)
+	if !ok {
+		return &spreadRejection{reason: fmt.Sprintf("❌ %s 没有实时盘口（推送过期或尚未订阅），拒绝市价开仓", symbol)}
+	}
+	if spread > limit {
+		return &spreadRejection{reason: fmt.Sprintf("❌ %s 买卖价差 %.1f bps 超过上限 %.1f bps，拒绝市价开仓", symbol, spread, limit)}
+	}
+	return nil
+}
+
+// deferEntry 开仓因价差检查被拒绝且配置了MaxDelay时加入等待队列，由交易主循环每spreadPollInterval重新检查
+func (at *AutoTrader) deferEntry(d decision.Decision, source string, err error) {
+	var rejection *spreadRejection
+	delay := at.config.SpreadGuard.MaxDelay
+	if delay <= 0 || !errors.As(err, &rejection) {
+		return
+	}
+	for _, w := range at.spreadWaits {
+		if w.decision.Symbol == d.Symbol && w.decision.Action == d.Action {
+			return
+		}
+	}
+	log.Printf("  ⏳ %s %s 等待价差收窄，最多%v（收窄后自动开仓）", d.Symbol, d.Action, delay)
+	at.spreadWaits = append(at.spreadWaits, spreadWait{decision: d, source: source, deadline: time.Now().Add(delay)})
+	at.watchSpreadWaits()
+}
+
+// spreadWatcher 等待价差收窄的开仓在实时行情中心的关注方名称
+func (at *AutoTrader) spreadWatcher() string {
+	return at.id + "/spread"
+}
+
+// watchSpreadWaits 订阅等待中开仓币种的盘口（外部信号的币种可能不在候选池中），没有等待时取消关注
+func (at *AutoTrader) watchSpreadWaits() {
+	symbols := make([]string, 0, len(at.spreadWaits))
+	for _, w := range at.spreadWaits {
+		symbols = append(symbols, w.decision.Symbol)
+	}
+	market.Hub.Watch(at.spreadWatcher(), symbols...)
+}
+
+// checkSpreadWaits 重新检查等待中的开仓：价差收窄时执行，超时仍未收窄（或一直没有新的盘口推送）时放弃
+func (at *AutoTrader) checkSpreadWaits() {
+	if len(at.spreadWaits) == 0 {
+		return
+	}
+	waits := at.spreadWaits
+	at.spreadWaits = nil
+
+	var pending []spreadWait
+	for _, w := range waits {
+		limit := at.config.SpreadGuard.limit(w.decision.Symbol)
+		spread, ok := market.Hub.SpreadBps(w.decision.Symbol, livePriceMaxAge)
+		switch {
+		case ok && spread <= limit:
+			log.Printf("  ✓ %s 买卖价差已收窄至 %.1f bps，执行 %s", w.decision.Symbol, spread, w.decision.Action)
+			at.executeDeferredEntry(w)
+		case time.Now().Before(w.deadline):
+			pending = append(pending, w)
+		case ok:
+			log.Printf("🚫 [%s] %s %s 等待超时，买卖价差 %.1f bps 仍超过上限 %.1f bps，放弃开仓", at.name, w.decision.Symbol, w.decision.Action, spread, limit)
+		default:
+			log.Printf("🚫 [%s] %s %s 等待超时，仍没有实时盘口，放弃开仓", at.name, w.decision.Symbol, w.decision.Action)
+		}
+	}
+	at.spreadWaits = append(pending, at.spreadWaits...)
+	at.watchSpreadWaits()
+}
+
+// executeDeferredEntry 执行价差收窄后的开仓（重新检查暂停、熔断和交易所状态），结果写入决策日志
+func (at *AutoTrader) executeDeferredEntry(w spreadWait) {
+	d := w.decision
+	record := &logger.DecisionRecord{
+		Source:       w.source,
+		CoTTrace:     d.Reasoning,
+		ExecutionLog: []string{},
+		Success:      true,
+	}
+	decisionJSON, _ := json.MarshalIndent([]decision.Decision{d}, "", "  ")
+	record.DecisionJSON = string(decisionJSON)
+
+	var blocked string
+	if now := market.Clock.Now(); now.Before(at.stopUntil) {
+		blocked = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", at.stopUntil.Sub(now).Minutes())
+	} else if reason := at.entryBlock(); reason != "" {
+		blocked = reason
+	} else if available, reason := monitor.ExchangeAvailable(at.exchange); !available {
+		blocked = fmt.Sprintf("交易所暂不可用: %s", reason)
+	}
+	if blocked != "" {
+		log.Printf("🚫 [%s] %s %s 放弃开仓: %s", at.name, d.Symbol, d.Action, blocked)
+		record.Success = false
+		record.ErrorMessage = blocked
+		at.decisionLogger.LogDecision(record)
+		return
+	}
+
+	actionRecord := logger.DecisionAction{
+		Action:     d.Action,
+		Symbol:     d.Symbol,
+		Leverage:   d.Leverage,
+		StrategyID: d.StrategyID,
+		Tags:       d.Tags,
+		Timestamp:  time.Now(),
+	}
+	if d.Explanation != nil {
+		actionRecord.Explanation = d.Explanation.Summary
+		actionRecord.Readings = d.Explanation.Values()
+	}
+	if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
+		log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
+		actionRecord.Error = err.Error()
+		record.Success = false
+		record.ErrorMessage = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+	} else {
+		actionRecord.Success = true
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功（等待价差收窄后）", d.Symbol, d.Action))
+		at.publishSignal(&d, &actionRecord)
+		if w.source != "" {
+			at.attachPlanSource(&d, w.source)
+		}
+	}
+	record.Decisions = append(record.Decisions, actionRecord)
+	at.refreshOpenRisk(0)
+
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package trader
+
+import (
+	"log"
+
+	"nofx/market"
+)
+
+// 移动止损模式
+const (
+	TrailingStopNone = ""    // 不移动止损（使用开仓时AI给出的固定止损）
+	TrailingStopSAR  = "sar" // 按趋势周期Parabolic SAR上移（多）/下移（空）止损
+)
+
+// trailingStopMinMove 止损移动的最小幅度（相对当前止损），避免频繁撤单重挂
+const trailingStopMinMove = 0.001
+
+// updateTrailingStops 按移动止损模式更新跟踪中持仓的止损价
+// 止损只向有利方向移动；移动时取消该币种挂单并为该币种所有持仓重新挂止损/止盈
+func (at *AutoTrader) updateTrailingStops(marketData map[string]*market.Data) {
+	if at.config.TrailingStopMode != TrailingStopSAR {
+		return
+	}
+
+	moved := make(map[string]bool)
+	for _, tracked := range at.trackedPositions {
+		data, ok := marketData[tracked.Symbol]
+		if !ok || data.LongerTermContext == nil || !data.LongerTermContext.Available("parabolic_sar") {
+			continue
+		}
+		newStop, ok := sarStop(tracked, data)
+		if !ok {
+			continue
+		}
+
+		log.Printf("  📐 [%s] %s %s 移动止损(SAR): %.4f -> %.4f", at.name, tracked.Symbol, tracked.Side, tracked.StopLoss, newStop)
+		tracked.StopLoss = newStop
+		at.updatePlan(tracked.Symbol, tracked.Side, 0, 0, false, "移动止损(SAR)")
+		moved[tracked.Symbol] = true
+	}
+
+	for symbol := range moved {
+		if err := at.trader.CancelAllOrders(symbol); err != nil {
+			log.Printf("  ⚠ 取消旧止损单失败（保留原止损）: %v", err)
+			continue
+		}
+		at.restoreStops(symbol)
+	}
+}
+
+// sarStop 根据SAR计算新的止损价，SAR方向与持仓一致且比当前止损更有利时返回true
+func sarStop(tracked *trackedPosition, data *market.Data) (float64, bool) {
+	sar := data.LongerTermContext.ParabolicSAR
+	uptrend := data.LongerTermContext.SARUptrend
+
+	if tracked.Side == "long" {
+		if !uptrend || sar >= data.CurrentPrice || sar <= tracked.StopLoss*(1+trailingStopMinMove) {
+			return 0, false
+		}
+		return sar, true
+	}
+
+	if uptrend || sar <= data.CurrentPrice {
+		return 0, false
+	}
+	if tracked.StopLoss > 0 && sar >= tracked.StopLoss*(1-trailingStopMinMove) {
+		return 0, false
+	}
+	return sar, true
+}
@@ -0,0 +1,179 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/strategy"
+)
+
+// StrategyConfig 用户策略配置（在沙箱中运行，决策与AI决策一起校验和执行；Plugin、Script和Builtin三选一）
+type StrategyConfig struct {
+	ID           string          // 策略ID（用于归因成交和统计表现）
+	Plugin       string          // Go插件路径（go build -buildmode=plugin 生成的.so）
+	Script       string          // Starlark脚本路径（修改后自动重新加载）
+	Builtin      string          // 内置参考策略名称（如 breakout）
+	Params       json.RawMessage // 内置策略参数（JSON）
+	Timeout      time.Duration   // 单次决策超时（0使用默认5秒）
+	MaxDecisions int             // 单次最多返回的决策数（0使用默认10）
+	MaxSteps     uint64          // 脚本单次执行的最大步数（0使用默认值）
+}
+
+// loadStrategies 加载用户策略并放入沙箱
+func loadStrategies(configs []StrategyConfig) ([]*strategy.Sandbox, error) {
+	var sandboxes []*strategy.Sandbox
+	for _, cfg := range configs {
+		var s strategy.Strategy
+		var err error
+		source := cfg.Plugin
+		switch {
+		case cfg.Script != "":
+			s, err = strategy.LoadScript(cfg.Script, cfg.MaxSteps)
+			source = cfg.Script
+		case cfg.Builtin != "":
+			s, err = strategy.LoadBuiltin(cfg.Builtin, cfg.Params)
+			source = "内置 " + cfg.Builtin
+		default:
+			s, err = strategy.LoadPlugin(cfg.Plugin)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("策略 %s: %w", cfg.ID, err)
+		}
+		sandboxes = append(sandboxes, strategy.NewSandbox(cfg.ID, s, strategy.Limits{
+			Timeout:      cfg.Timeout,
+			MaxDecisions: cfg.MaxDecisions,
+		}))
+		log.Printf("🧩 已加载用户策略 %s（%s）", cfg.ID, source)
+	}
+	return sandboxes, nil
+}
+
+// runStrategies 在沙箱中依次执行用户策略，返回通过校验的决策
+// 策略报错、panic或超时只影响该策略本身，不影响AI决策和其他策略；
+// 只管理模式的策略只执行其持仓的平仓决策，持仓全部平仓后完全停用
+func (at *AutoTrader) runStrategies(ctx *decision.Context, record *logger.DecisionRecord) []decision.Decision {
+	var result []decision.Decision
+	for _, sandbox := range at.strategies {
+		mode := sandbox.Mode()
+		if mode == strategy.ModeDisabled {
+			continue
+		}
+		owned := at.strategyPositions(sandbox.ID())
+		if mode == strategy.ModeManageOnly && len(owned) == 0 {
+			sandbox.Retire("已停用，持仓已全部平仓")
+			continue
+		}
+
+		view := *ctx // 浅拷贝，避免策略修改周期上下文的字段
+		decisions, err := sandbox.Decide(&view)
+		if err != nil {
+			log.Printf("⚠️  [%s] %v", at.name, err)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ %v", err))
+			continue
+		}
+		for _, d := range decisions {
+			if mode == strategy.ModeManageOnly && !owned[d.Symbol+"_"+closeSide(d.Action)] {
+				log.Printf("⏸ [%s] 策略 %s 处于只管理模式，忽略决策 %s %s", at.name, sandbox.ID(), d.Symbol, d.Action)
+				continue
+			}
+			if err := decision.ValidateDecisions([]decision.Decision{d}, ctx); err != nil {
+				log.Printf("⚠️  [%s] 策略 %s 的决策 %s %s 未通过校验: %v", at.name, sandbox.ID(), d.Symbol, d.Action, err)
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ 策略 %s 的决策 %s %s 未通过校验: %v", sandbox.ID(), d.Symbol, d.Action, err))
+				continue
+			}
+			result = append(result, d)
+		}
+		if len(decisions) > 0 {
+			log.Printf("🧩 [%s] 策略 %s 给出 %d 个决策", at.name, sandbox.ID(), len(decisions))
+		}
+	}
+	return result
+}
+
+// closeSide 平仓决策对应的持仓方向（非平仓决策返回空字符串）
+func closeSide(action string) string {
+	switch action {
+	case "close_long":
+		return "long"
+	case "close_short":
+		return "short"
+	}
+	return ""
+}
+
+// openSide 开仓决策对应的持仓方向（非开仓决策返回空字符串）
+func openSide(action string) string {
+	switch action {
+	case "open_long":
+		return "long"
+	case "open_short":
+		return "short"
+	}
+	return ""
+}
+
+// strategyPositions 由指定策略开仓、仍在持有的持仓（symbol_side集合）
+func (at *AutoTrader) strategyPositions(strategyID string) map[string]bool {
+	owned := make(map[string]bool)
+	for key, pos := range at.trackedPositions {
+		if pos.StrategyID == strategyID {
+			owned[key] = true
+		}
+	}
+	return owned
+}
+
+// findStrategy 按ID查找用户策略
+func (at *AutoTrader) findStrategy(id string) (*strategy.Sandbox, error) {
+	for _, sandbox := range at.strategies {
+		if sandbox.ID() == id {
+			return sandbox, nil
+		}
+	}
+	return nil, fmt.Errorf("策略 %s 不存在", id)
+}
+
+// StrategyStatuses 各用户策略的运行状态
+func (at *AutoTrader) StrategyStatuses() []strategy.Status {
+	statuses := make([]strategy.Status, 0, len(at.strategies))
+	for _, sandbox := range at.strategies {
+		statuses = append(statuses, sandbox.Status())
+	}
+	return statuses
+}
+
+// EnableStrategy 启用用户策略（无需重启）
+func (at *AutoTrader) EnableStrategy(id string) (strategy.Status, error) {
+	sandbox, err := at.findStrategy(id)
+	if err != nil {
+		return strategy.Status{}, err
+	}
+	sandbox.Enable()
+	return sandbox.Status(), nil
+}
+
+// DisableStrategy 停用用户策略：不再开新仓，已有持仓由该策略继续管理（只执行平仓决策）直到全部平仓
+func (at *AutoTrader) DisableStrategy(id string) (strategy.Status, error) {
+	sandbox, err := at.findStrategy(id)
+	if err != nil {
+		return strategy.Status{}, err
+	}
+	sandbox.Disable("手动停用")
+	return sandbox.Status(), nil
+}
+
+// ReloadStrategy 重新加载用户策略（脚本策略），运行模式不变
+func (at *AutoTrader) ReloadStrategy(id string) (strategy.Status, error) {
+	sandbox, err := at.findStrategy(id)
+	if err != nil {
+		return strategy.Status{}, err
+	}
+	if err := sandbox.Reload(); err != nil {
+		return sandbox.Status(), err
+	}
+	return sandbox.Status(), nil
+}
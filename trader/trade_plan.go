@@ -0,0 +1,484 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"nofx/decision"
+	"nofx/events"
+	"nofx/market"
+	"nofx/secret"
+)
+
+// 交易计划状态
+const (
+	PlanPending     = "pending"     // 开仓单已提交，尚未成交
+	PlanActive      = "active"      // 持仓已建立
+	PlanScaling     = "scaling"     // 持仓已建立，仍有加仓单（网格层）等待成交
+	PlanClosed      = "closed"      // 持仓已全部平仓
+	PlanInvalidated = "invalidated" // 开仓单未成交即失效（未成交的IOC/FOK单、被取消或过期）
+)
+
+// planTransitions 允许的状态转换（closed、invalidated为终态）
+var planTransitions = map[string][]string{
+	PlanPending: {PlanActive, PlanScaling, PlanInvalidated},
+	PlanActive:  {PlanScaling, PlanClosed},
+	PlanScaling: {PlanActive, PlanClosed},
+}
+
+// 交易计划订单类别
+const (
+	PlanOrderEntry   = "entry"   // 开仓单
+	PlanOrderGrid    = "grid"    // 网格加仓层
+	PlanOrderPyramid = "pyramid" // 金字塔加仓单
+	PlanOrderExit    = "exit"    // 平仓单
+)
+
+// maxFinishedPlans 保留的已结束（closed/invalidated）交易计划数
+const maxFinishedPlans = 200
+
+// tradePlansFile 交易计划的持久化文件（位于决策日志目录下）
+const tradePlansFile = "trade_plans.json"
+
+// PlanSignal 交易计划的来源信号（开仓决策的快照）
+type PlanSignal struct {
+	Source     string    `json:"source,omitempty"` // 空表示AI决策，外部信号为来源名称（如"tradingview"）
+	Action     string    `json:"action"`
+	Confidence int       `json:"confidence,omitempty"`
+	Reasoning  string    `json:"reasoning,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	StopLoss   float64   `json:"stop_loss"`
+	TakeProfit float64   `json:"take_profit"`
+	ReceivedAt time.Time `json:"received_at"`
+
+	Invalidations []decision.Invalidation `json:"invalidations,omitempty"` // 失效条件（开仓单成交前满足时取消）
+}
+
+// PlanOrder 交易计划下的订单
+type PlanOrder struct {
+	Kind          string    `json:"kind"` // entry、grid、pyramid、exit
+	OrderID       int64     `json:"order_id,omitempty"`
+	ClientOrderID string    `json:"client_order_id,omitempty"`
+	Price         float64   `json:"price"`
+	Quantity      float64   `json:"quantity"` // 0表示全部平仓
+	Time          time.Time `json:"time"`
+}
+
+// PlanStop 止损/止盈价的一次设置（开仓、移动止损、网格按均价调整止盈、重启后恢复）
+type PlanStop struct {
+	StopLoss   float64   `json:"stop_loss"`
+	TakeProfit float64   `json:"take_profit"`
+	Reason     string    `json:"reason"`
+	Time       time.Time `json:"time"`
+}
+
+// PlanExit 交易计划的一次平仓（部分或全部）
+type PlanExit struct {
+	Reason   string    `json:"reason"`
+	Price    float64   `json:"price,omitempty"`    // 0表示成交价未知（如交易所触发的止损/止盈）
+	Quantity float64   `json:"quantity,omitempty"` // 0表示全部平仓
+	Time     time.Time `json:"time"`
+}
+
+// TradePlan 交易计划：执行子系统的核心对象，把一个开仓信号与其订单、止损/止盈和平仓关联起来
+// 状态 pending → active/scaling → closed，未成交即失效时 pending → invalidated；
+// 每次变化都写入 decision_logs/<trader_id>/trade_plans.json，重启后未结束的计划继续管理对应持仓
+type TradePlan struct {
+	ID         string      `json:"id"` // 开仓单的客户端订单ID（同一信号重复下单时对应同一个计划）
+	Symbol     string      `json:"symbol"`
+	Side       string      `json:"side"` // "long" 或 "short"
+	StrategyID string      `json:"strategy_id"`
+	State      string      `json:"state"`
+	Signal     PlanSignal  `json:"signal"`
+	Leverage   int         `json:"leverage"`
+	EntryPrice float64     `json:"entry_price"` // 成交均价（未成交时为下单参考价）
+	Quantity   float64     `json:"quantity"`    // 计划数量（网格为各层合计）
+	Filled     float64     `json:"filled"`      // 已成交的持仓数量
+	StopLoss   float64     `json:"stop_loss"`
+	TakeProfit float64     `json:"take_profit"`
+	Orders     []PlanOrder `json:"orders"`
+	Stops      []PlanStop  `json:"stops"`
+	Exits      []PlanExit  `json:"exits,omitempty"`
+	Reason     string      `json:"reason,omitempty"` // 结束原因（closed/invalidated）
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	ClosedAt   *time.Time  `json:"closed_at,omitempty"`
+
+	// 金字塔加仓的基准和已加仓次数（未配置加仓规则的计划为0）
+	InitialEntry    float64 `json:"initial_entry,omitempty"`
+	InitialQuantity float64 `json:"initial_quantity,omitempty"`
+	Adds            int     `json:"adds,omitempty"`
+
+	checked     map[string]time.Time // 各失效条件最近检查的K线收盘时间（不持久化，重启后重新检查最近一根）
+	rejectedAdd int                  // 最近一次被风控拒绝的加仓序号（不持久化，避免每个周期重复记录）
+}
+
+// Finished 计划是否已结束（closed 或 invalidated）
+func (p *TradePlan) Finished() bool {
+	return p.State == PlanClosed || p.State == PlanInvalidated
+}
+
+// transition 切换状态（不允许的转换返回错误，状态不变）
+func (p *TradePlan) transition(state, reason string) error {
+	if p.State == state {
+		return nil
+	}
+	if !slices.Contains(planTransitions[p.State], state) {
+		return fmt.Errorf("交易计划 %s 不能从 %s 变为 %s", p.ID, p.State, state)
+	}
+	now := market.Clock.Now()
+	p.State = state
+	p.UpdatedAt = now
+	if p.Finished() {
+		p.Reason = reason
+		p.ClosedAt = &now
+	}
+	return nil
+}
+
+// setStops 记录新的止损/止盈价（与当前相同时不记录）
+func (p *TradePlan) setStops(stopLoss, takeProfit float64, reason string) {
+	if len(p.Stops) > 0 && stopLoss == p.StopLoss && takeProfit == p.TakeProfit {
+		return
+	}
+	now := market.Clock.Now()
+	p.StopLoss, p.TakeProfit = stopLoss, takeProfit
+	p.Stops = append(p.Stops, PlanStop{StopLoss: stopLoss, TakeProfit: takeProfit, Reason: reason, Time: now})
+	p.UpdatedAt = now
+}
+
+// planBook 交易计划的内存记录与持久化
+// plans只在交易主循环中访问；API通过每次保存时的快照读取，不与主循环竞争
+type planBook struct {
+	path     string
+	plans    []*TradePlan // 按创建时间排序
+	mu       sync.Mutex
+	snapshot []byte // 最近一次保存的JSON
+}
+
+// loadPlanBook 从决策日志目录加载交易计划（文件不存在时为空）
+func loadPlanBook(dir string) *planBook {
+	book := &planBook{path: filepath.Join(dir, tradePlansFile)}
+	data, err := secret.ReadFile(book.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("⚠️  读取交易计划失败（从空记录开始）: %v", err)
+		}
+		return book
+	}
+	if err := json.Unmarshal(data, &book.plans); err != nil {
+		log.Printf("⚠️  解析交易计划失败（从空记录开始）: %v", err)
+		book.plans = nil
+		return book
+	}
+	book.snapshot = data
+	if open := len(book.openPlans()); open > 0 {
+		log.Printf("📋 恢复 %d 个未结束的交易计划", open)
+	}
+	return book
+}
+
+// save 写入持久化文件：保留全部未结束的计划和最近 maxFinishedPlans 个已结束的计划
+func (b *planBook) save() {
+	finished := 0
+	for i := len(b.plans) - 1; i >= 0; i-- {
+		if !b.plans[i].Finished() {
+			continue
+		}
+		if finished++; finished > maxFinishedPlans {
+			b.plans = slices.Delete(b.plans, i, i+1)
+		}
+	}
+
+	data, err := json.MarshalIndent(b.plans, "", "  ")
+	if err != nil {
+		log.Printf("⚠ 序列化交易计划失败: %v", err)
+		return
+	}
+	b.mu.Lock()
+	b.snapshot = data
+	b.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		log.Printf("⚠ 创建交易计划目录失败: %v", err)
+		return
+	}
+	if err := secret.WriteFile(b.path, data, 0644); err != nil {
+		log.Printf("⚠ 保存交易计划失败: %v", err)
+	}
+}
+
+// open 币种/方向上未结束的计划（同一方向最多一个，开仓时已拒绝叠加持仓）
+func (b *planBook) open(symbol, side string) *TradePlan {
+	for i := len(b.plans) - 1; i >= 0; i-- {
+		p := b.plans[i]
+		if p.Symbol == symbol && p.Side == side && !p.Finished() {
+			return p
+		}
+	}
+	return nil
+}
+
+// openPlans 全部未结束的计划
+func (b *planBook) openPlans() []*TradePlan {
+	var plans []*TradePlan
+	for _, p := range b.plans {
+		if !p.Finished() {
+			plans = append(plans, p)
+		}
+	}
+	return plans
+}
+
+// get 按ID查找计划
+func (b *planBook) get(id string) *TradePlan {
+	for _, p := range b.plans {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// startPlan 开仓单提交后创建（或在同一信号重复下单时复用）交易计划
+// filled 开仓单是否已成交，grid 为网格开仓（未启用网格时为nil），其余各层作为加仓单
+func (at *AutoTrader) startPlan(d *decision.Decision, side string, order map[string]interface{}, quantity, price float64, filled bool, grid *gridEntry) {
+	now := market.Clock.Now()
+	id := at.clientOrderID(d.Symbol, "open_"+side, d.StrategyID)
+	scaling := grid != nil && len(grid.Levels) > 1
+	if plan := at.plans.get(id); plan != nil {
+		// 同一信号重复提交时placeOpenOrder返回已有订单，沿用原计划
+		if filled && plan.State == PlanPending {
+			at.fillPlan(plan, quantity, orderAvgPrice(order), scaling)
+			at.plans.save()
+		}
+		return
+	}
+
+	plan := &TradePlan{
+		ID:         id,
+		Symbol:     d.Symbol,
+		Side:       side,
+		StrategyID: d.StrategyID,
+		State:      PlanPending,
+		Signal: PlanSignal{
+			Action:     d.Action,
+			Confidence: d.Confidence,
+			Reasoning:  d.Reasoning,
+			Tags:       d.Tags,
+			StopLoss:   d.StopLoss,
+			TakeProfit: d.TakeProfit,
+			ReceivedAt: now,
+
+			Invalidations: d.Invalidations,
+		},
+		Leverage:   d.Leverage,
+		EntryPrice: price,
+		Quantity:   quantity,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	plan.setStops(d.StopLoss, d.TakeProfit, "开仓")
+	at.plans.plans = append(at.plans.plans, plan)
+	at.publishPlan(plan, "")
+
+	entry := PlanOrder{Kind: PlanOrderEntry, ClientOrderID: id, Price: price, Quantity: quantity, Time: now}
+	entry.OrderID = orderID(order)
+	plan.Orders = append(plan.Orders, entry)
+	if scaling {
+		for i, level := range grid.Levels[1:] {
+			plan.Orders = append(plan.Orders, PlanOrder{Kind: PlanOrderGrid, ClientOrderID: gridOrderID(id, i+1, 0), Price: level.Price, Quantity: level.Quantity, Time: now})
+			plan.Quantity += level.Quantity
+		}
+	}
+
+	if filled {
+		if fill := orderAvgPrice(order); fill > 0 {
+			plan.EntryPrice = fill
+		}
+		at.fillPlan(plan, quantity, plan.EntryPrice, scaling)
+	}
+	at.plans.save()
+}
+
+// attachPlanSource 外部信号开仓后记录计划的信号来源
+func (at *AutoTrader) attachPlanSource(d *decision.Decision, source string) {
+	side := openSide(d.Action)
+	if side == "" {
+		return
+	}
+	if plan := at.plans.open(d.Symbol, side); plan != nil && plan.Signal.Source == "" {
+		plan.Signal.Source = source
+		at.plans.save()
+	}
+}
+
+// fillPlan 开仓/加仓成交后更新计划的持仓数量和状态
+func (at *AutoTrader) fillPlan(plan *TradePlan, filled, entryPrice float64, scaling bool) {
+	state := PlanActive
+	if scaling {
+		state = PlanScaling
+	}
+	plan.Filled = filled
+	if entryPrice > 0 {
+		plan.EntryPrice = entryPrice
+	}
+	plan.UpdatedAt = market.Clock.Now()
+	if plan.State == state {
+		return
+	}
+	if err := plan.transition(state, ""); err != nil {
+		log.Printf("⚠️  [%s] %v", at.name, err)
+		return
+	}
+	at.publishPlan(plan, "")
+}
+
+// updatePlan 持仓数量/均价或止损止盈变化时更新未结束的计划（网格加仓、移动止损）
+func (at *AutoTrader) updatePlan(symbol, side string, filled, entryPrice float64, scaling bool, stopReason string) {
+	plan := at.plans.open(symbol, side)
+	if plan == nil {
+		return
+	}
+	if filled > 0 {
+		at.fillPlan(plan, filled, entryPrice, scaling)
+	}
+	if tracked, ok := at.trackedPositions[symbol+"_"+side]; ok {
+		plan.setStops(tracked.StopLoss, tracked.TakeProfit, stopReason)
+	}
+	at.plans.save()
+}
+
+// exitPlan 记录计划的平仓：quantity为0表示全部平仓，计划结束；pending的计划全部平仓时视为失效
+func (at *AutoTrader) exitPlan(symbol, side string, order map[string]interface{}, quantity float64, reason string) {
+	plan := at.plans.open(symbol, side)
+	if plan == nil {
+		return
+	}
+	now := market.Clock.Now()
+	price := orderAvgPrice(order)
+	if order != nil {
+		exitOrder := PlanOrder{Kind: PlanOrderExit, Price: price, Quantity: quantity, Time: now}
+		exitOrder.OrderID = orderID(order)
+		plan.Orders = append(plan.Orders, exitOrder)
+	}
+	plan.Exits = append(plan.Exits, PlanExit{Reason: reason, Price: price, Quantity: quantity, Time: now})
+	plan.UpdatedAt = now
+
+	if quantity > 0 {
+		plan.Filled = max(plan.Filled-quantity, 0)
+	} else {
+		plan.Filled = 0
+		state := PlanClosed
+		if plan.State == PlanPending {
+			state = PlanInvalidated
+		}
+		at.finishPlan(plan, state, reason)
+	}
+	at.plans.save()
+}
+
+// invalidatePlan 开仓单未成交即失效，结束pending的计划
+func (at *AutoTrader) invalidatePlan(symbol, side, reason string) {
+	plan := at.plans.open(symbol, side)
+	if plan == nil || plan.State != PlanPending {
+		return
+	}
+	at.finishPlan(plan, PlanInvalidated, reason)
+	at.plans.save()
+}
+
+// finishPlan 结束计划（closed/invalidated）
+func (at *AutoTrader) finishPlan(plan *TradePlan, state, reason string) {
+	if err := plan.transition(state, reason); err != nil {
+		log.Printf("⚠️  [%s] %v", at.name, err)
+		return
+	}
+	at.publishPlan(plan, reason)
+}
+
+// publishPlan 记录并发布交易计划状态变化事件
+func (at *AutoTrader) publishPlan(plan *TradePlan, reason string) {
+	message := fmt.Sprintf("[%s] %s %s 交易计划 %s", at.name, plan.Symbol, plan.Side, plan.State)
+	if reason != "" {
+		message += "：" + reason
+	}
+	log.Printf("📋 %s", message)
+	events.Publish(events.Event{
+		Type:     events.TypeTradePlan,
+		Severity: events.SeverityInfo,
+		Message:  message,
+		Data: map[string]interface{}{
+			"trader_id":   at.id,
+			"plan_id":     plan.ID,
+			"symbol":      plan.Symbol,
+			"side":        plan.Side,
+			"state":       plan.State,
+			"strategy_id": plan.StrategyID,
+			"filled":      plan.Filled,
+			"reason":      reason,
+		},
+	})
+}
+
+// resumePlan 对账发现本地未跟踪的持仓时，按未结束的交易计划恢复止损/止盈和开仓时间（重启后继续管理）
+// 计划仍为pending时说明开仓单在离线期间成交，切换为active
+func (at *AutoTrader) resumePlan(symbol, side string, quantity float64) (*trackedPosition, bool) {
+	plan := at.plans.open(symbol, side)
+	if plan == nil {
+		return nil, false
+	}
+	if plan.State == PlanPending {
+		at.fillPlan(plan, quantity, 0, false)
+	} else {
+		plan.Filled = quantity
+	}
+	at.plans.save()
+
+	openedAt := plan.CreatedAt
+	if len(plan.Orders) > 0 {
+		openedAt = plan.Orders[0].Time
+	}
+	at.positionFirstSeenTime[symbol+"_"+side] = openedAt.UnixMilli()
+	return &trackedPosition{
+		Symbol:     symbol,
+		Side:       side,
+		Quantity:   quantity,
+		StopLoss:   plan.StopLoss,
+		TakeProfit: plan.TakeProfit,
+		OpenedAt:   openedAt,
+		StrategyID: plan.StrategyID,
+	}, true
+}
+
+// TradePlans 交易计划（未结束的在前，其余按创建时间倒序）
+func (at *AutoTrader) TradePlans() []TradePlan {
+	at.plans.mu.Lock()
+	data := at.plans.snapshot
+	at.plans.mu.Unlock()
+
+	var plans []TradePlan
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &plans); err != nil {
+			log.Printf("⚠ 解析交易计划快照失败: %v", err)
+		}
+	}
+	slices.Reverse(plans)
+	slices.SortStableFunc(plans, func(a, b TradePlan) int {
+		switch {
+		case !a.Finished() && b.Finished():
+			return -1
+		case a.Finished() && !b.Finished():
+			return 1
+		}
+		return 0
+	})
+	return plans
+}
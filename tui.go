@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"nofx/events"
+	"nofx/trader"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// tuiPollInterval 终端监控刷新trader状态的间隔
+const tuiPollInterval = 3 * time.Second
+
+// tuiMaxEvents 终端监控保留的事件日志条数
+const tuiMaxEvents = 500
+
+var (
+	tuiTitleStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14"))
+	tuiHeaderStyle  = lipgloss.NewStyle().Bold(true).Underline(true)
+	tuiDimStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	tuiGainStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	tuiLossStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	tuiWarningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+)
+
+// tuiReportMsg trader状态刷新结果
+type tuiReportMsg struct {
+	traderID string
+	report   *trader.InspectReport
+	err      error
+}
+
+// tuiEventMsg 事件流推送的事件
+type tuiEventMsg events.Event
+
+// tuiTickMsg 定时刷新
+type tuiTickMsg time.Time
+
+// tuiModel 终端监控界面状态
+type tuiModel struct {
+	client    *http.Client
+	baseURL   string
+	traderIDs []string
+	selected  int
+	reports   map[string]*trader.InspectReport
+	err       error
+	updatedAt time.Time
+
+	eventCh <-chan events.Event
+	events  []events.Event
+	scroll  int // 事件日志从底部向上滚动的行数
+
+	width, height int
+}
+
+// runTUI 终端监控（适合通过SSH查看无界面服务器上的实例）: nofx tui [API地址] [trader_id...]
+// 显示实时价格和指标、持仓、风控状态和滚动事件日志；Tab切换trader，↑/↓翻阅事件，q退出
+func runTUI(args []string) {
+	baseURL := "http://localhost:8080"
+	if len(args) > 0 {
+		baseURL = strings.TrimRight(args[0], "/")
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	traderIDs := args[min(len(args), 1):]
+	if len(traderIDs) == 0 {
+		var traders []struct {
+			TraderID string `json:"trader_id"`
+		}
+		if err := getJSON(client, baseURL+"/api/traders", &traders); err != nil {
+			log.Fatalf("❌ 连接 %s 失败: %v", baseURL, err)
+		}
+		for _, t := range traders {
+			traderIDs = append(traderIDs, t.TraderID)
+		}
+	}
+	if len(traderIDs) == 0 {
+		log.Fatalf("❌ %s 没有运行中的trader", baseURL)
+	}
+
+	model := &tuiModel{
+		client:    client,
+		baseURL:   baseURL,
+		traderIDs: traderIDs,
+		reports:   make(map[string]*trader.InspectReport),
+		eventCh:   streamEvents(baseURL),
+	}
+	if _, err := tea.NewProgram(model, tea.WithAltScreen()).Run(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+// streamEvents 订阅实例的事件流（SSE，断开后5秒重连）
+func streamEvents(baseURL string) <-chan events.Event {
+	ch := make(chan events.Event, 64)
+	go func() {
+		for {
+			resp, err := apiGet(http.DefaultClient, baseURL+"/api/events/stream")
+			if err == nil {
+				scanner := bufio.NewScanner(resp.Body)
+				scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+				for scanner.Scan() {
+					data, ok := strings.CutPrefix(scanner.Text(), "data:")
+					if !ok {
+						continue
+					}
+					var event events.Event
+					if json.Unmarshal([]byte(data), &event) == nil {
+						ch <- event
+					}
+				}
+				resp.Body.Close()
+			}
+			time.Sleep(5 * time.Second)
+		}
+	}()
+	return ch
+}
+
+// Init 启动时立即刷新并开始接收事件
+func (m *tuiModel) Init() tea.Cmd {
+	return tea.Batch(m.fetch(), m.waitEvent(), tuiTick())
+}
+
+// fetch 刷新当前选中trader的状态
+func (m *tuiModel) fetch() tea.Cmd {
+	traderID := m.traderIDs[m.selected]
+	return func() tea.Msg {
+		var report trader.InspectReport
+		err := getJSON(m.client, m.baseURL+"/api/inspect?trader_id="+url.QueryEscape(traderID), &report)
+		if err != nil {
+			return tuiReportMsg{traderID: traderID, err: err}
+		}
+		return tuiReportMsg{traderID: traderID, report: &report}
+	}
+}
+
+// waitEvent 等待下一个事件
+func (m *tuiModel) waitEvent() tea.Cmd {
+	return func() tea.Msg {
+		return tuiEventMsg(<-m.eventCh)
+	}
+}
+
+// tuiTick 定时刷新
+func tuiTick() tea.Cmd {
+	return tea.Tick(tuiPollInterval, func(t time.Time) tea.Msg { return tuiTickMsg(t) })
+}
+
+// Update 处理按键、刷新结果和事件
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return m, tea.Quit
+		case "tab", "right", "l":
+			m.selected = (m.selected + 1) % len(m.traderIDs)
+			return m, m.fetch()
+		case "shift+tab", "left", "h":
+			m.selected = (m.selected + len(m.traderIDs) - 1) % len(m.traderIDs)
+			return m, m.fetch()
+		case "up", "k":
+			m.scroll = min(m.scroll+1, max(len(m.events)-1, 0))
+		case "down", "j":
+			m.scroll = max(m.scroll-1, 0)
+		case "pgup":
+			m.scroll = min(m.scroll+10, max(len(m.events)-1, 0))
+		case "pgdown":
+			m.scroll = max(m.scroll-10, 0)
+		case "end", "G":
+			m.scroll = 0
+		}
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tuiTickMsg:
+		return m, tea.Batch(m.fetch(), tuiTick())
+	case tuiReportMsg:
+		m.err = msg.err
+		if msg.report != nil {
+			m.reports[msg.traderID] = msg.report
+			m.updatedAt = time.Now()
+		}
+	case tuiEventMsg:
+		m.events = append(m.events, events.Event(msg))
+		if len(m.events) > tuiMaxEvents {
+			m.events = m.events[len(m.events)-tuiMaxEvents:]
+		}
+		if m.scroll > 0 {
+			m.scroll++ // 翻阅历史时保持位置不动
+		}
+		return m, m.waitEvent()
+	}
+	return m, nil
+}
+
+// View 渲染界面
+func (m *tuiModel) View() string {
+	var b strings.Builder
+	traderID := m.traderIDs[m.selected]
+	report := m.reports[traderID]
+
+	// 标题和风控
+	title := fmt.Sprintf("NOFX %s  [%d/%d] %s", m.baseURL, m.selected+1, len(m.traderIDs), traderID)
+	b.WriteString(tuiTitleStyle.Render(title))
+	if !m.updatedAt.IsZero() {
+		b.WriteString(tuiDimStyle.Render("  更新于 " + m.updatedAt.Format("15:04:05")))
+	}
+	b.WriteString("\n")
+	if m.err != nil {
+		b.WriteString(tuiLossStyle.Render("⚠ " + m.err.Error()))
+		b.WriteString("\n")
+	}
+	lines := 2
+	if m.err != nil {
+		lines++
+	}
+	if report != nil {
+		risk := report.Risk
+		status := tuiGainStyle.Render("运行中")
+		if !report.Running {
+			status = tuiLossStyle.Render("已停止")
+		}
+		fmt.Fprintf(&b, "%s | %s | 周期 #%d | 净值 %.2f | 日亏损 %.2f%%/%s | 回撤 %.2f%%/%s | 保证金 %.1f%%\n",
+			report.Name, status, report.CallCount, risk.Equity,
+			risk.DailyLossPct, limitText(risk.MaxDailyLoss), risk.DrawdownPct, limitText(risk.MaxDrawdown), risk.MarginUsedPct)
+		if risk.BreakerTripped {
+			b.WriteString(tuiLossStyle.Render("🚨 全局熔断: "+risk.BreakerReason) + "\n")
+			lines++
+		}
+		if !risk.StopUntil.IsZero() {
+			b.WriteString(tuiWarningStyle.Render("⏸ 风控暂停至 "+risk.StopUntil.Local().Format("15:04:05")) + "\n")
+			lines++
+		}
+
+		// 行情和指标
+		b.WriteString("\n" + tuiHeaderStyle.Render(tuiColumns([]string{"币种", "价格", "1h", "4h", "资金费率", "RSI14", "强度"}, []int{12, 12, 8, 8, 10, 6, 5}, 1)) + "\n")
+		for _, s := range report.Snapshots {
+			price := s.Price
+			if s.LivePrice > 0 {
+				price = s.LivePrice
+			}
+			fmt.Fprintf(&b, "%-12s %12.4f %s %s %9.4f%% %6.1f %5.0f\n",
+				s.Symbol, price, tuiSigned(s.PriceChange1h, "%+7.2f%%"), tuiSigned(s.PriceChange4h, "%+7.2f%%"), s.FundingRate*100, s.RSI14, s.StrengthScore)
+		}
+		lines += 3 + len(report.Snapshots)
+
+		// 持仓
+		b.WriteString("\n" + tuiHeaderStyle.Render(tuiColumns([]string{"持仓", "方向", "数量", "开仓价", "标记价", "杠杆", "未实现盈亏"}, []int{12, 5, 12, 12, 12, 4, 20}, 2)) + "\n")
+		if len(report.Positions) == 0 {
+			b.WriteString(tuiDimStyle.Render("（无持仓）") + "\n")
+			lines++
+		}
+		for _, pos := range report.Positions {
+			pnl, _ := pos["unrealized_pnl"].(float64)
+			pnlPct, _ := pos["unrealized_pnl_pct"].(float64)
+			fmt.Fprintf(&b, "%-12v %-5v %12.4f %12.4f %12.4f %3vx %s\n",
+				pos["symbol"], pos["side"], pos["quantity"], pos["entry_price"], pos["mark_price"], pos["leverage"],
+				tuiSigned(pnl, fmt.Sprintf("%%+10.2f (%+.2f%%%%)", pnlPct)))
+		}
+		lines += 2 + len(report.Positions)
+	} else {
+		b.WriteString(tuiDimStyle.Render("正在获取trader状态...") + "\n")
+		lines++
+	}
+
+	// 事件日志（占满剩余高度）
+	b.WriteString("\n" + tuiHeaderStyle.Render("事件") + tuiDimStyle.Render("  ↑/↓ 翻阅  Tab 切换trader  q 退出") + "\n")
+	lines += 3
+	visible := max(m.height-lines, 3)
+	end := len(m.events) - m.scroll
+	for _, event := range m.events[max(end-visible, 0):max(end, 0)] {
+		line := fmt.Sprintf("%s %-24s %s", event.Time.Local().Format("15:04:05"), event.Type, event.Message)
+		if m.width > 0 {
+			line = ansi.Truncate(line, m.width, "…")
+		}
+		switch event.Severity {
+		case events.SeverityCritical:
+			line = tuiLossStyle.Render(line)
+		case events.SeverityWarning:
+			line = tuiWarningStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// tuiColumns 按显示宽度对齐表头（中文字符占两列；前left列左对齐，其余右对齐）
+func tuiColumns(titles []string, widths []int, left int) string {
+	cells := make([]string, len(titles))
+	for i, title := range titles {
+		padding := strings.Repeat(" ", max(widths[i]-ansi.StringWidth(title), 0))
+		if i < left {
+			cells[i] = title + padding
+		} else {
+			cells[i] = padding + title
+		}
+	}
+	return strings.Join(cells, " ")
+}
+
+// tuiSigned 按正负着色数值
+func tuiSigned(value float64, format string) string {
+	text := fmt.Sprintf(format, value)
+	if value > 0 {
+		return tuiGainStyle.Render(text)
+	}
+	if value < 0 {
+		return tuiLossStyle.Render(text)
+	}
+	return text
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxResponseBody 单个HTTP响应体最多读取的字节数
+// 交易所最大的正常响应（币安exchangeInfo、期权全市场mark）只有几MB，
+// 基础URL被覆盖指向异常/恶意服务时，超大响应会被拒绝而不是整个读入内存
+var MaxResponseBody int64 = 32 << 20
+
+// ErrBodyTooLarge 响应体超过 MaxResponseBody
+var ErrBodyTooLarge = errors.New("响应体超过大小限制")
+
+// ReadBody 读取完整响应体，超过 MaxResponseBody 时返回 ErrBodyTooLarge
+func ReadBody(r io.Reader) ([]byte, error) {
+	body, err := io.ReadAll(LimitBody(r))
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// LimitBody 限制流式读取的字节数：超过 MaxResponseBody 后读取返回 ErrBodyTooLarge（而不是io.EOF，避免把截断的响应当作完整响应）
+func LimitBody(r io.Reader) io.Reader {
+	return &limitedBody{r: r, remaining: MaxResponseBody}
+}
+
+// DecodeJSON 边读边解析JSON响应，不先把整个响应读入内存，最多读取 MaxResponseBody 字节
+func DecodeJSON(r io.Reader, v interface{}) error {
+	if err := json.NewDecoder(LimitBody(r)).Decode(v); err != nil {
+		if errors.Is(err, ErrBodyTooLarge) {
+			return err
+		}
+		return fmt.Errorf("解析JSON响应失败: %w", err)
+	}
+	return nil
+}
+
+type limitedBody struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// 已读满限额：再探测一个字节，区分恰好读完和超出限制
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, fmt.Errorf("%w (%d字节)", ErrBodyTooLarge, MaxResponseBody)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}